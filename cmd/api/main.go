@@ -3,19 +3,34 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	nethttp "net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
+	"helloworld-ai/internal/cache"
 	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/digest"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/handlers"
 	"helloworld-ai/internal/http"
 	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/jobs"
+	"helloworld-ai/internal/judge"
 	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/logging"
+	"helloworld-ai/internal/monitor"
+	"helloworld-ai/internal/pii"
 	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/snapshot"
 	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/tracing"
 	"helloworld-ai/internal/vault"
 	"helloworld-ai/internal/vectorstore"
 )
@@ -52,21 +67,49 @@ func main() {
 	}
 
 	// Configure structured logging with configurable level and format
-	opts := &slog.HandlerOptions{
-		Level: cfg.LogLevel,
-	}
-	var handler slog.Handler
-	if cfg.LogFormat == "json" {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
-	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
+	// (both can be changed at runtime via PUT /api/v1/admin/loglevel)
+	logSettings, err := logging.NewSettings(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
 	}
-	logger := slog.New(handler)
+	logger := slog.New(logSettings.Handler(os.Stdout))
 	slog.SetDefault(logger)
 	slog.Debug("Logging configured", "level", cfg.LogLevel.String(), "format", cfg.LogFormat)
 
+	// SIGINT/SIGTERM cancel ctx, which stops background work and starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// background tracks the goroutines that must stop before the database is closed
+	var background sync.WaitGroup
+
+	// Export traces over OTLP when an endpoint is configured (spans are no-ops otherwise)
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.TracingEnabled {
+		shutdownTracing, err = tracing.Setup(ctx, cfg.OTelServiceName)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		slog.Info("OpenTelemetry tracing enabled", "service_name", cfg.OTelServiceName)
+	}
+
+	// Test mode swaps llama.cpp for an in-process fake so the API runs without external services
+	if cfg.Mode == config.ModeTest {
+		fakeLLM := llm.NewFakeServer(cfg.QdrantVectorSize)
+		defer fakeLLM.Close()
+		cfg.LLMBaseURL = fakeLLM.URL
+		cfg.EmbeddingBaseURL = fakeLLM.URL
+		slog.Warn("Running in test mode with fake LLM", "llm_url", fakeLLM.URL, "vector_store", cfg.VectorStoreBackend)
+	}
+
+	// Replicas answer questions from the primary's database and collections and never write them
+	replica := cfg.Role == config.RoleReplica
+
 	// Initialize database
-	db, err := storage.New(cfg.DBPath)
+	openDB := storage.New
+	if replica {
+		openDB = storage.NewReadOnly
+	}
+	db, err := openDB(cfg.DBPath)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -74,127 +117,134 @@ func main() {
 		_ = db.Close()
 	}()
 
-	if err := storage.Migrate(db); err != nil {
+	if replica {
+		slog.Info("Running as read-only replica: indexing and write endpoints are disabled", "db_path", cfg.DBPath)
+	} else if err := storage.Migrate(db); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
+	} else {
+		slog.Info("Database initialized", "path", cfg.DBPath)
+	}
+	if cfg.SQLiteWAL && !replica {
+		if err := storage.EnableWAL(db); err != nil {
+			log.Fatalf("Failed to configure database: %v", err)
+		}
+		slog.Info("Database WAL mode enabled")
+	}
+	// Keep a warm standby copy so the index metadata survives a disk failure
+	if cfg.SQLiteReplicaPath != "" && !replica {
+		slog.Info("Database replica enabled", "path", cfg.SQLiteReplicaPath, "interval_minutes", cfg.SQLiteReplicaIntervalMinutes)
+		replicator := storage.NewReplicator(db, cfg.SQLiteReplicaPath)
+		background.Go(func() {
+			replicator.Run(ctx, time.Duration(cfg.SQLiteReplicaIntervalMinutes)*time.Minute)
+		})
 	}
-	slog.Info("Database initialized", "path", cfg.DBPath)
 
 	// Create repository instances
 	vaultRepo := storage.NewVaultRepo(db)
 	noteRepo := storage.NewNoteRepo(db)
 	chunkRepo := storage.NewChunkRepo(db)
+	indexTimingRepo := storage.NewIndexTimingRepo(db)
+	indexChecksumRepo := storage.NewIndexChecksumRepo(db)
+	chunkClickRepo := storage.NewChunkClickRepo(db)
+	indexEventRepo := storage.NewIndexEventRepo(db)
+	indexFailureRepo := storage.NewIndexFailureRepo(db)
+	answerCacheRepo := storage.NewAnswerCacheRepo(db)
+	jobRepo := storage.NewJobRepo(db)
 
-	// Initialize Qdrant vector store
-	ctx := context.Background()
-
-	// Initialize vault manager
-	vaultManager, err := vault.NewManager(ctx, vaultRepo, cfg.VaultPersonalPath, cfg.VaultWorkPath)
+	// Initialize vault manager (replicas look up the vaults the primary created)
+	newVaultManager := vault.NewManager
+	if replica {
+		newVaultManager = vault.NewReadOnlyManager
+	}
+	vaultManager, err := newVaultManager(ctx, vaultRepo, cfg.Vaults, cfg.VaultSymlinks)
 	if err != nil {
 		log.Fatalf("Failed to initialize vault manager: %v", err)
 	}
-	slog.Info("Vault manager initialized", "personal", cfg.VaultPersonalPath, "work", cfg.VaultWorkPath)
-	vectorStore, err := vectorstore.NewQdrantStore(cfg.QdrantURL)
+	for _, v := range cfg.Vaults {
+		slog.Info("Vault configured", "name", v.Name, "path", v.Path)
+	}
+	vectorStore, err := vectorstore.Open(vectorstore.BackendOptions{
+		Backend:         cfg.VectorStoreBackend,
+		QdrantURL:       cfg.QdrantURL,
+		PgvectorDSN:     cfg.PgvectorDSN,
+		UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+		Distance:        cfg.QdrantDistance,
+	})
 	if err != nil {
-		log.Fatalf("Failed to create Qdrant client: %v", err)
+		log.Fatalf("Failed to open %s vector store: %v", cfg.VectorStoreBackend, err)
 	}
+	slog.Info("Vector store opened", "backend", cfg.VectorStoreBackend)
+	defer func() {
+		if err := vectorStore.Close(); err != nil {
+			slog.Warn("Failed to close vector store", "error", err)
+		}
+	}()
 
 	// Ensure collection exists with correct vector size
-	if err := vectorStore.EnsureCollection(ctx, cfg.QdrantCollection, cfg.QdrantVectorSize); err != nil {
+	if err := prepareCollection(ctx, vectorStore, cfg.QdrantCollection, cfg.QdrantVectorSize, replica); err != nil {
 		log.Fatalf("Failed to ensure Qdrant collection: %v", err)
 	}
 	slog.Info("Qdrant collection ready", "collection", cfg.QdrantCollection, "vector_size", cfg.QdrantVectorSize)
 
-	// Load models into llama.cpp server (router mode)
-	// This ensures models are available before we try to use them
-	modelLoader := llm.NewModelLoader(cfg.LLMBaseURL)
-
-	// Get absolute path to models directory (relative to project root)
-	// This helps avoid relative path resolution issues when llama.cpp spawns subprocesses
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Failed to get working directory: %v", err)
-	}
-	modelsDir := filepath.Join(wd, "..", "llama.cpp", "models")
-	absModelsDir, err := filepath.Abs(modelsDir)
-	if err != nil {
-		slog.Warn("Failed to resolve absolute models directory, using relative path",
-			"models_dir", modelsDir,
-			"error", err)
-		absModelsDir = modelsDir
+	// Per-vault chunk collections; the shared collection above stays for health checks
+	var vaultCollections []string
+	if cfg.QdrantCollectionPerVault {
+		for _, v := range vaultManager.ListVaults() {
+			collection := vectorstore.VaultCollection(cfg.QdrantCollection, v.ID)
+			if err := prepareCollection(ctx, vectorStore, collection, cfg.QdrantVectorSize, replica); err != nil {
+				log.Fatalf("Failed to ensure Qdrant collection for vault %s: %v", v.Name, err)
+			}
+			vaultCollections = append(vaultCollections, collection)
+		}
+		slog.Info("Qdrant vault collections ready", "collections", vaultCollections)
 	}
 
-	// Load chat model
-	chatModelPath := filepath.Join(absModelsDir, cfg.LLMModelName+".gguf")
-	chatModelArgs := []string{
-		"--ctx-size", "8192",
-		"--threads", "8",
-		"--batch-size", "384",
-		"--ubatch-size", "96",
-		"--model", chatModelPath, // Use absolute path to avoid relative path resolution issues
-	}
-	// Check if already loaded before attempting to load
-	chatLoaded, err := modelLoader.IsModelLoaded(ctx, cfg.LLMModelName)
-	if err != nil {
-		slog.Warn("Failed to check if chat model is loaded, attempting to load",
-			"model", cfg.LLMModelName,
-			"error", err)
-		// Attempt to load even if check failed
-		if err := modelLoader.LoadModel(ctx, cfg.LLMModelName, chatModelArgs); err != nil {
-			slog.Warn("Failed to load chat model (will be loaded on first use)",
-				"model", cfg.LLMModelName,
-				"error", err)
-		} else {
-			slog.Info("Chat model loaded", "model", cfg.LLMModelName)
+	// Cold storage collection is only created when the policy is enabled
+	coldCollection := ""
+	if cfg.ColdStorageAfterMonths > 0 {
+		coldCollection = cfg.QdrantColdCollection
+		if err := prepareCollection(ctx, vectorStore, coldCollection, cfg.QdrantVectorSize, replica); err != nil {
+			log.Fatalf("Failed to ensure Qdrant cold collection: %v", err)
 		}
-		// Wait 10 seconds before loading next model (after any load attempt)
-		slog.Info("Waiting 10 seconds before loading next model...")
-		time.Sleep(10 * time.Second)
-	} else if chatLoaded {
-		slog.Info("Chat model already loaded", "model", cfg.LLMModelName)
-		// No delay needed if already loaded
-	} else {
-		// Model not loaded, attempt to load it
-		if err := modelLoader.LoadModel(ctx, cfg.LLMModelName, chatModelArgs); err != nil {
-			slog.Warn("Failed to load chat model (will be loaded on first use)",
-				"model", cfg.LLMModelName,
-				"error", err)
-		} else {
-			slog.Info("Chat model loaded", "model", cfg.LLMModelName)
+		slog.Info("Qdrant cold collection ready", "collection", coldCollection, "after_months", cfg.ColdStorageAfterMonths)
+	}
+
+	// Note centroid collection is only created when two-stage retrieval is enabled
+	noteCollection := ""
+	if cfg.NotePrefilterTopM > 0 {
+		noteCollection = cfg.QdrantNoteCollection
+		if err := prepareCollection(ctx, vectorStore, noteCollection, cfg.QdrantVectorSize, replica); err != nil {
+			log.Fatalf("Failed to ensure Qdrant note collection: %v", err)
 		}
-		// Wait 10 seconds before loading next model (after any load attempt)
-		slog.Info("Waiting 10 seconds before loading next model...")
-		time.Sleep(10 * time.Second)
-	}
-
-	// Load embeddings model
-	embeddingModelPath := filepath.Join(absModelsDir, cfg.EmbeddingModelName+".gguf")
-	embeddingModelArgs := []string{
-		"--embeddings",
-		"--pooling", "mean",
-		"--ctx-size", "2048",
-		"--ubatch-size", "2048",
-		"--model", embeddingModelPath, // Use absolute path to avoid relative path resolution issues
-	}
-	// Check if already loaded before attempting to load
-	embeddingLoaded, err := modelLoader.IsModelLoaded(ctx, cfg.EmbeddingModelName)
-	if err != nil {
-		slog.Warn("Failed to check if embedding model is loaded, attempting to load",
-			"model", cfg.EmbeddingModelName,
-			"error", err)
-	} else if embeddingLoaded {
-		slog.Info("Embedding model already loaded", "model", cfg.EmbeddingModelName)
-	} else {
-		if err := modelLoader.LoadModel(ctx, cfg.EmbeddingModelName, embeddingModelArgs); err != nil {
-			slog.Warn("Failed to load embedding model (will be loaded on first use)",
-				"model", cfg.EmbeddingModelName,
-				"error", err)
-		} else {
-			slog.Info("Embedding model loaded", "model", cfg.EmbeddingModelName)
+		slog.Info("Qdrant note collection ready", "collection", noteCollection, "top_m", cfg.NotePrefilterTopM)
+	}
+
+	// Create LLM clients (external service layer)
+	llmClient := llm.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModelName)
+	embedder := llm.NewEmbeddingsClient(cfg.EmbeddingBaseURL, cfg.LLMAPIKey, cfg.EmbeddingModelName, cfg.QdrantVectorSize)
+
+	// Load models into llama.cpp server (router mode) concurrently, waiting until they are
+	// ready; the fake LLM needs no loading. The servers' slot counts size generation and
+	// embedding concurrency.
+	var chatSlots, embeddingSlots int
+	var models *llm.ModelManager
+	if cfg.Mode != config.ModeTest {
+		modelLoader := llm.NewModelLoader(cfg.LLMBaseURL)
+		models = newModelManager(cfg, modelLoader, llmClient, embedder)
+		_ = models.LoadAll(ctx)
+		chatSlots, embeddingSlots = fetchServerSlots(ctx, cfg, modelLoader)
+		if cfg.ModelIdleTTLMinutes > 0 {
+			slog.Info("Idle model unloading enabled", "idle_ttl_minutes", cfg.ModelIdleTTLMinutes)
+			background.Go(func() {
+				models.Run(ctx, time.Minute)
+			})
 		}
 	}
+	// Indexing and question embeddings share the embedding server's slots
+	embeddingParallelism := concurrencyLimit(cfg.EmbeddingParallelism, embeddingSlots)
+	embedder.SetMaxConcurrency(embeddingParallelism)
 
 	// Validate embedding client vector size (fail-fast)
-	embedder := llm.NewEmbeddingsClient(cfg.EmbeddingBaseURL, cfg.LLMAPIKey, cfg.EmbeddingModelName, cfg.QdrantVectorSize)
 	testEmbeddings, err := embedder.EmbedTexts(ctx, []string{"test"})
 	if err != nil {
 		// Check if error is due to model not being loaded (router mode)
@@ -219,55 +269,466 @@ func main() {
 		vaultManager,
 		noteRepo,
 		chunkRepo,
+		indexTimingRepo,
+		indexChecksumRepo,
 		embedder,
 		vectorStore,
 		cfg.QdrantCollection,
+		coldCollection,
+		noteCollection,
+		storage.NewShadowIndex(db, cfg.DBPath+".rebuild"),
 	)
+	indexerPipeline.SetEmbeddingParallelism(embeddingParallelism)
+	indexerPipeline.SetChunkOverlap(cfg.ChunkOverlapRunes)
+	indexerPipeline.SetEventStore(indexEventRepo)
+	indexerPipeline.SetFailureStore(indexFailureRepo)
+	indexerPipeline.SetExcludedFolderStore(storage.NewExcludedFolderRepo(db))
+	indexerPipeline.SetCollectionPerVault(cfg.QdrantCollectionPerVault)
 
-	// Create LLM client (external service layer)
-	llmClient := llm.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModelName)
+	llmConcurrency := concurrencyLimit(cfg.LLMMaxConcurrency, chatSlots)
+	llmClient.SetMaxConcurrency(llmConcurrency)
+	slog.Info("LLM concurrency configured",
+		"generation_slots", llmConcurrency,
+		"embedding_parallelism", embeddingParallelism,
+		"server_chat_slots", chatSlots,
+		"server_embedding_slots", embeddingSlots,
+	)
 
-	// Create RAG engine
-	ragEngine := rag.NewEngine(
-		embedder,
+	// Feature flags gate experimental behaviors; runtime overrides go through /api/v1/features
+	featureFlags, err := features.New(cfg.FeatureFlags)
+	if err != nil {
+		log.Fatalf("Failed to initialize feature flags: %v", err)
+	}
+	slog.Info("Feature flags loaded", "flags", featureFlags.Snapshot())
+
+	// An external reranker replaces the lexical scores in the rerank stage
+	var reranker rag.Reranker
+	if cfg.RerankerURL != "" {
+		reranker = llm.NewRerankerClient(cfg.RerankerURL, cfg.RerankerAPIKey, cfg.RerankerBatchSize)
+		slog.Info("External reranker configured", "url", cfg.RerankerURL, "batch_size", cfg.RerankerBatchSize)
+	}
+
+	// The answer judge scores answers in the judge stage with the chat model
+	answerRubric, err := judge.ConfiguredRubric(cfg.AnswerJudgeRubricPath, cfg.AnswerJudgeFaithfulnessThreshold)
+	if err != nil {
+		log.Fatalf("Failed to load answer judge rubric: %v", err)
+	}
+	var answerJudge rag.AnswerJudge
+	if cfg.AnswerJudge {
+		answerJudge = judge.New(llmClient, answerRubric)
+		slog.Info("Answer judge enabled", "rubric", answerRubric.Version, "regenerate_below", answerRubric.RegenerateBelow(), "abstain_below", answerRubric.AbstainBelow(), "max_regenerations", cfg.AnswerJudgeMaxRegenerations)
+	}
+
+	// A replica's pipeline never indexes, so its epoch would never invalidate the cached
+	// vaults and folders; replicas read them from SQLite on every question instead
+	var indexEpoch rag.IndexEpochSource = indexerPipeline
+	if replica {
+		indexEpoch = nil
+	}
+
+	// Caches register here so the admin API can report and flush them
+	caches := cache.NewRegistry()
+
+	// Create RAG engine (implementation selected by RAG_ENGINE)
+	ragEngine, err := rag.NewEngineByName(cfg.RAGEngine, rag.EngineDeps{
+		Embedder:        embedder,
+		VectorStore:     vectorStore,
+		Collection:      cfg.QdrantCollection,
+		ColdCollection:  coldCollection,
+		ChunkRepo:       chunkRepo,
+		VaultRepo:       vaultRepo,
+		NoteRepo:        noteRepo,
+		Chat:            llmClient,
+		QuestionLogMode: cfg.LogQuestions,
+		FolderSelection: rag.FolderSelectionOptions{
+			MaxDepth:        cfg.FolderSelectionMaxDepth,
+			MaxFolders:      cfg.FolderSelectionMaxFolders,
+			Timeout:         time.Duration(cfg.FolderSelectionTimeoutSeconds) * time.Second,
+			Mode:            cfg.FolderSelectionMode,
+			HeuristicMargin: cfg.FolderSelectionHeuristicMargin,
+			CacheSize:       cfg.FolderSelectionCacheSize,
+			CacheTTL:        time.Duration(cfg.FolderSelectionCacheTTLSeconds) * time.Second,
+		},
+		NotePrefilter: rag.NotePrefilterOptions{
+			Collection: noteCollection,
+			TopM:       cfg.NotePrefilterTopM,
+		},
+		Flags:              featureFlags,
+		CollectionPerVault: cfg.QdrantCollectionPerVault,
+		QuestionCache: rag.QuestionCacheOptions{
+			Size: cfg.QuestionEmbeddingCacheSize,
+			TTL:  time.Duration(cfg.QuestionEmbeddingCacheTTLSeconds) * time.Second,
+		},
+		Generation: rag.GenerationOptions{
+			Stop:                cfg.LLMStopSequences,
+			RepeatPenalty:       cfg.LLMRepeatPenalty,
+			TopP:                cfg.LLMTopP,
+			TopK:                cfg.LLMTopK,
+			Model:               cfg.LLMModelName,
+			CurrentModel:        llmClient.ModelName,
+			StructuredCitations: cfg.LLMStructuredCitations,
+		},
+		Hydrator:   indexerPipeline,
+		IndexEpoch: indexEpoch,
+		ScoreFloor: rag.ScoreThresholds{
+			Vector: cfg.MinVectorScoreFloor,
+			Final:  cfg.MinFinalScoreFloor,
+		},
+		Retrieval: rag.RetrievalOptions{
+			MinScore: rag.ScoreThresholds{
+				Vector: cfg.MinVectorScore,
+				Final:  cfg.MinFinalScore,
+			},
+			VectorWeight:       cfg.VectorScoreWeight,
+			LexicalWeight:      cfg.LexicalScoreWeight,
+			CandidateKPerScope: cfg.CandidateKPerScope,
+			MaxCandidates:      cfg.MaxCandidates,
+			TagBoost:           cfg.TagBoost,
+		},
+		ClickStore: chunkClickRepo,
+		ReadOnly:   replica,
+		Stages:     cfg.RAGStages,
+		Reranker:   reranker,
+		Tokenizer:  llmClient,
+		Answerability: rag.AnswerabilityOptions{
+			Threshold: cfg.AnswerabilityThreshold,
+		},
+		Neighbors: rag.NeighborOptions{
+			TokenBudget: cfg.ContextNeighborTokens,
+		},
+		Judging: rag.JudgeOptions{
+			Judge:                 answerJudge,
+			FaithfulnessThreshold: answerRubric.RegenerateBelow(),
+			MaxRegenerations:      cfg.AnswerJudgeMaxRegenerations,
+			AbstainThresholds:     answerRubric.AbstainBelow(),
+		},
+		Timeouts: rag.TimeoutOptions{
+			Embed:      cfg.EmbedTimeout,
+			Search:     cfg.SearchTimeout,
+			Generation: cfg.GenerationTimeout,
+		},
+		Caches: caches,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create RAG engine: %v", err)
+	}
+	// Shared deployments filter sensitive categories out of answers before they are returned
+	if cfg.SafetyFilterRules != "" {
+		safetyRules, err := rag.LoadSafetyRules(cfg.SafetyFilterRules)
+		if err != nil {
+			log.Fatalf("Failed to load safety filter rules: %v", err)
+		}
+		safetyOptions := rag.SafetyFilterOptions{Rules: safetyRules, Action: cfg.SafetyFilterAction}
+		if cfg.SafetyFilterClassify {
+			safetyOptions.Classifier = llmClient
+		}
+		safetyFilter, err := rag.NewSafetyFilter(safetyOptions)
+		if err != nil {
+			log.Fatalf("Failed to create safety filter: %v", err)
+		}
+		ragEngine = rag.NewSafetyEngine(ragEngine, safetyFilter)
+		slog.Info("Answer safety filter enabled", "categories", len(safetyRules.Categories), "action", cfg.SafetyFilterAction, "classify", cfg.SafetyFilterClassify)
+	}
+	// Repeated questions are answered from SQLite until indexing changes a note or the TTL passes
+	if cfg.AnswerCacheTTLSeconds > 0 && !replica {
+		ragEngine = rag.NewAnswerCacheEngine(ragEngine, answerCacheRepo, indexEventRepo, rag.AnswerCacheOptions{
+			TTL:    time.Duration(cfg.AnswerCacheTTLSeconds) * time.Second,
+			Config: fmt.Sprintf("%s|%s|%v|%g|%t|%s|%g|%v", cfg.RAGEngine, cfg.LLMModelName, cfg.RAGStages, cfg.AnswerabilityThreshold, cfg.AnswerJudge, answerRubric.Version, answerRubric.RegenerateBelow(), answerRubric.AbstainBelow()),
+			Flags:  featureFlags,
+			Caches: caches,
+		})
+		slog.Info("Answer cache enabled", "ttl_seconds", cfg.AnswerCacheTTLSeconds)
+	}
+	// Retries of a slow question join the in-flight answer instead of starting another
+	ragEngine = rag.NewCoalescingEngine(ragEngine)
+	slog.Info("RAG engine initialized", "engine", cfg.RAGEngine, "stages", cfg.RAGStages)
+
+	// Monitor storage usage against soft limits so runaway growth is noticed on small servers
+	monitoredCollections := append([]string{cfg.QdrantCollection}, vaultCollections...)
+	if coldCollection != "" {
+		monitoredCollections = append(monitoredCollections, coldCollection)
+	}
+	if noteCollection != "" {
+		monitoredCollections = append(monitoredCollections, noteCollection)
+	}
+	storageMonitor := monitor.NewStorageMonitor(
+		cfg.DBPath,
 		vectorStore,
-		cfg.QdrantCollection,
-		chunkRepo,
-		vaultRepo,
-		noteRepo,
-		llmClient,
+		monitoredCollections,
+		monitor.StorageLimits{
+			SQLiteBytes: int64(cfg.StorageSQLiteSoftLimitMB) * 1024 * 1024,
+			QdrantBytes: int64(cfg.StorageQdrantSoftLimitMB) * 1024 * 1024,
+		},
+		cfg.StorageAlertWebhookURL,
 	)
-	slog.Info("RAG engine initialized")
+	background.Go(func() {
+		storageMonitor.Run(ctx, time.Duration(cfg.StorageCheckIntervalMinutes)*time.Minute)
+	})
+
+	// The admin PII scan reports personal data per folder before the API is shared
+	piiPatterns := pii.DefaultPatterns
+	if cfg.PIIScanPatterns != "" {
+		if piiPatterns, err = pii.LoadPatterns(cfg.PIIScanPatterns); err != nil {
+			log.Fatalf("Failed to load PII scan patterns: %v", err)
+		}
+	}
+	piiScanner, err := pii.NewScanner(chunkRepo, piiPatterns)
+	if err != nil {
+		log.Fatalf("Failed to create PII scanner: %v", err)
+	}
+
+	// Admin snapshots copy the database and every collection the index writes to
+	snapshotter := snapshot.NewSnapshotter(db, vectorStore, snapshot.Collections{
+		Chunks:   cfg.QdrantCollection,
+		PerVault: cfg.QdrantCollectionPerVault,
+		Cold:     coldCollection,
+		Notes:    noteCollection,
+	})
+
+	// Long-running index operations run as jobs on one background worker
+	jobQueue := jobs.NewQueue(jobRepo)
+	jobs.RegisterIndexJobs(jobQueue, indexerPipeline, vaultManager)
 
 	// Create router with dependencies
 	deps := &http.Deps{
 		RAGEngine:          ragEngine,
 		VaultRepo:          vaultRepo,
+		ChunkRepo:          chunkRepo,
+		NoteRepo:           noteRepo,
+		ClickStore:         chunkClickRepo,
+		EventStore:         indexEventRepo,
 		IndexerPipeline:    indexerPipeline,
 		VaultManager:       vaultManager,
 		VectorStore:        vectorStore,
 		LLMClient:          llmClient,
 		CollectionName:     cfg.QdrantCollection,
 		EmbeddingModelName: cfg.EmbeddingModelName,
+		StorageMonitor:     storageMonitor,
+		FeatureFlags:       featureFlags,
+		RequestLimits: handlers.RequestLimits{
+			MaxBodyBytes:      int64(cfg.MaxRequestBodyKB) << 10,
+			MaxQuestionLength: cfg.MaxQuestionLength,
+			MaxDocumentBytes:  int64(cfg.MaxDocumentKB) << 10,
+			MaxVaults:         cfg.MaxRequestVaults,
+			MaxFolders:        cfg.MaxRequestFolders,
+			Generation: rag.GenerationLimits{
+				DefaultModel:      cfg.LLMModelName,
+				AllowedModels:     cfg.LLMAllowedModels,
+				MaxTokens:         cfg.AskMaxTokens,
+				AllowSystemPrompt: cfg.AskAllowSystemPrompt,
+			},
+		},
+		LogSettings:  logSettings,
+		Caches:       caches,
+		PIIScanner:   piiScanner,
+		Snapshotter:  snapshotter,
+		Digest:       digest.NewSummarizer(noteRepo, chunkRepo, vaultManager, llmClient),
+		Models:       models,
+		AdminToken:   cfg.AdminToken,
+		Users:        storage.NewUserRepo(db),
+		AuthRequired: cfg.AuthRequired,
+		ReadOnly:     replica,
+		JobQueue:     jobQueue,
 	}
 	router := http.NewRouter(deps)
 
-	// Start indexing in background after router is ready
-	go func() {
-		indexCtx := context.Background()
-		slog.Info("Starting background indexing of vaults")
-		if err := indexerPipeline.IndexAll(indexCtx); err != nil {
-			slog.Error("Indexing completed with errors", "error", err)
-		} else {
-			slog.Info("Indexing completed successfully")
-		}
-	}()
+	// Start indexing in background after router is ready; shutdown cancels it.
+	// Replicas never index: they read the primary's index as it is written.
+	if !replica {
+		background.Go(func() {
+			jobQueue.Run(ctx)
+		})
+		background.Go(func() {
+			indexCtx := ctx
+			// The in-memory vector store starts empty, so notes indexed by earlier runs are embedded again
+			if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+				if err := indexerPipeline.ClearAll(indexCtx); err != nil {
+					slog.Error("Failed to reset index for the in-memory vector store", "error", err)
+				}
+			}
+			slog.Info("Starting background indexing of vaults")
+			// Recent notes and priority folders are queryable before older notes are backfilled
+			priority := indexer.IndexPriority{
+				Order:        cfg.IndexStartupOrder,
+				RecentWindow: time.Duration(cfg.IndexRecentDays) * 24 * time.Hour,
+				Folders:      cfg.IndexPriorityFolders,
+			}
+			if err := indexerPipeline.IndexAllPrioritized(indexCtx, priority); err != nil {
+				slog.Error("Indexing completed with errors", "error", err)
+			} else {
+				slog.Info("Indexing completed successfully")
+			}
+
+			// Move stale notes to cold storage once indexing has settled
+			if indexerPipeline.ColdStorageEnabled() {
+				cutoff := time.Now().AddDate(0, -cfg.ColdStorageAfterMonths, 0)
+				if _, err := indexerPipeline.MoveToCold(indexCtx, cutoff); err != nil {
+					slog.Error("Cold storage policy failed", "error", err)
+				}
+			}
+
+			// Write last week's digest note into the vault once indexing has settled
+			if cfg.DigestVault != "" {
+				slog.Info("Weekly digest enabled", "vault", cfg.DigestVault, "folder", cfg.DigestFolder)
+				digestGenerator := digest.NewGenerator(noteRepo, vaultManager, indexerPipeline, cfg.DigestVault, cfg.DigestFolder)
+				background.Go(func() {
+					digestGenerator.Run(indexCtx, time.Hour)
+				})
+			}
+		})
+	}
 
 	// Start API server
 	addr := ":" + cfg.APIPort
 	slog.Info("Starting API server", "addr", addr)
 	slog.Debug("LLM configuration", "base_url", cfg.LLMBaseURL, "model", cfg.LLMModelName)
-	if err := nethttp.ListenAndServe(addr, router); err != nil {
+	// Serve HTTP/2 without TLS (h2c) alongside HTTP/1.1 for clients and proxies that support it
+	protocols := new(nethttp.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &nethttp.Server{
+		Addr:      addr,
+		Handler:   router,
+		Protocols: protocols,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
 		log.Fatalf("API server failed to start: %v", err)
+	case <-ctx.Done():
+	}
+	// Restore default signal handling so a second signal kills the process
+	stop()
+
+	slog.Info("Shutting down API server", "timeout_seconds", cfg.ShutdownTimeoutSeconds)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("In-flight requests did not finish before the shutdown timeout, closing connections", "error", err)
+		_ = server.Close()
+	}
+	// Background work sees the cancelled context; wait for it so the database is not closed under it
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
+	select {
+	case <-backgroundDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("Background indexing did not stop before the shutdown timeout")
+	}
+	// Flush spans still waiting in the batcher
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("API server stopped")
+}
+
+// newModelManager registers the chat model, and the embedding model when the chat server
+// also serves it, with a ModelManager and points the clients at it, so models unloaded for
+// being idle are reloaded with the right arguments on their next request. Swapping the chat
+// model updates llmClient's default model.
+func newModelManager(cfg *config.Config, modelLoader *llm.ModelLoader, llmClient *llm.Client, embedder *llm.EmbeddingsClient) *llm.ModelManager {
+	// Get absolute path to models directory (relative to project root)
+	// This helps avoid relative path resolution issues when llama.cpp spawns subprocesses
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("Failed to get working directory: %v", err)
+	}
+	modelsDir := filepath.Join(wd, "..", "llama.cpp", "models")
+	absModelsDir, err := filepath.Abs(modelsDir)
+	if err != nil {
+		slog.Warn("Failed to resolve absolute models directory, using relative path",
+			"models_dir", modelsDir,
+			"error", err)
+		absModelsDir = modelsDir
+	}
+
+	models := llm.NewModelManager(modelLoader, llm.ModelManagerOptions{
+		LoadTimeout: time.Duration(cfg.ModelLoadTimeoutSeconds) * time.Second,
+		IdleTTL:     time.Duration(cfg.ModelIdleTTLMinutes) * time.Minute,
+	})
+	models.Register(llm.RoleChat, llm.ModelRole{
+		Model: cfg.LLMModelName,
+		Args: func(model string) []string {
+			return []string{
+				"--ctx-size", "8192",
+				"--threads", "8",
+				"--batch-size", "384",
+				"--ubatch-size", "96",
+				"--model", filepath.Join(absModelsDir, model+".gguf"), // Use absolute path to avoid relative path resolution issues
+			}
+		},
+		OnSwap: llmClient.SetModel,
+	})
+	llmClient.SetModelManager(models)
+
+	// A separate embedding server (EMBEDDING_BASE_URL) manages its own model
+	if cfg.EmbeddingBaseURL == cfg.LLMBaseURL {
+		models.Register(llm.RoleEmbedding, llm.ModelRole{
+			Model: cfg.EmbeddingModelName,
+			Args: func(model string) []string {
+				return []string{
+					"--embeddings",
+					"--pooling", "mean",
+					"--ctx-size", "2048",
+					"--ubatch-size", "2048",
+					"--model", filepath.Join(absModelsDir, model+".gguf"),
+				}
+			},
+		})
+		embedder.SetModelManager(models)
+	}
+	return models
+}
+
+// fetchServerSlots returns the parallel slot counts of the chat and embedding models from
+// llama.cpp's /props, storing them in modelLoader. A count is 0 when it cannot be fetched.
+func fetchServerSlots(ctx context.Context, cfg *config.Config, modelLoader *llm.ModelLoader) (chatSlots, embeddingSlots int) {
+	if props, err := modelLoader.FetchProps(ctx, cfg.LLMModelName); err != nil {
+		slog.Warn("Failed to get chat server props", "model", cfg.LLMModelName, "error", err)
+	} else {
+		chatSlots = props.TotalSlots
+	}
+
+	// The embedding model may be served separately (EMBEDDING_BASE_URL)
+	embeddingLoader := modelLoader
+	if cfg.EmbeddingBaseURL != cfg.LLMBaseURL {
+		embeddingLoader = llm.NewModelLoader(cfg.EmbeddingBaseURL)
+	}
+	if props, err := embeddingLoader.FetchProps(ctx, cfg.EmbeddingModelName); err != nil {
+		slog.Warn("Failed to get embedding server props", "model", cfg.EmbeddingModelName, "error", err)
+	} else {
+		embeddingSlots = props.TotalSlots
+	}
+	return chatSlots, embeddingSlots
+}
+
+// concurrencyLimit returns configured when it is set, otherwise the server's slot count
+// (0 when unknown, which leaves the concurrency at its default).
+func concurrencyLimit(configured, serverSlots int) int {
+	if configured > 0 {
+		return configured
+	}
+	return serverSlots
+}
+
+// prepareCollection creates a collection with the given vector size. A replica only checks
+// that the primary created it with that size, since it must not write the vector store.
+func prepareCollection(ctx context.Context, store vectorstore.Store, collection string, vectorSize int, replica bool) error {
+	if !replica {
+		return store.EnsureCollection(ctx, collection, vectorSize)
+	}
+	info, err := store.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("collection %s not found (start the primary instance first): %w", collection, err)
+	}
+	if info.VectorSize != vectorSize {
+		return fmt.Errorf("collection %s has vector size %d, expected %d", collection, info.VectorSize, vectorSize)
 	}
+	return nil
 }
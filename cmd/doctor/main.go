@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/doctor"
+)
+
+// Doctor checks a first-time setup end to end and prints a pass/fail report: configuration,
+// vault paths, SQLite, Qdrant, llama.cpp models, a test embedding and generation, and a test
+// question answered from a tiny scratch index. It reads the same environment configuration
+// as the API and never modifies the real index. The exit status is 1 when a check fails.
+//
+// Usage:
+//
+//	go run ./cmd/doctor [-v]
+func main() {
+	verbose := flag.Bool("v", false, "also print the service logs (to stderr) while checking")
+	flag.Parse()
+
+	// Logs would drown the report, so they are only shown on request
+	handler := slog.DiscardHandler
+	cfg, err := config.Load()
+	if *verbose {
+		level := slog.LevelInfo
+		if err == nil {
+			level = cfg.LogLevel
+		}
+		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	}
+	slog.SetDefault(slog.New(handler))
+
+	var report *doctor.Report
+	if err != nil {
+		report = doctor.ConfigFailure(err)
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		report = doctor.Run(ctx, cfg)
+		stop()
+	}
+
+	if err := report.Write(os.Stdout); err != nil {
+		slog.Error("Failed to write report", "error", err)
+		os.Exit(1)
+	}
+	if report.Failed() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/eval"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/judge"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Eval runs a golden dataset of questions with expected source notes through the RAG engine
+// and reports recall@K, MRR, citation precision, and abstention accuracy, so retrieval
+// tuning (score thresholds, weights, auto-K) can be measured. It reads the same environment
+// configuration as the API and answers from the existing index without modifying it; every
+// question is answered afresh, bypassing the answer cache.
+//
+// With -rejudge it instead scores the answers of an earlier run's results.jsonl again with
+// the answer judge's rubric (ANSWER_JUDGE_RUBRIC), without asking the questions again, and
+// reports the mean scores and the answers the rubric's thresholds would regenerate or
+// abstain. Only the chat model must be running.
+//
+// Usage:
+//
+//	go run ./cmd/eval [-dataset eval/eval_set.jsonl] [-k 5] [-out report.json] [-v]
+//	go run ./cmd/eval -rejudge eval/results/<run_id>/results.jsonl [-out rejudged.json] [-v]
+func main() {
+	datasetPath := flag.String("dataset", "eval/eval_set.jsonl", "golden dataset (.jsonl or .json)")
+	k := flag.Int("k", 0, "chunks to retrieve and score per question (0 = the engine's auto-K, scoring every retrieved chunk)")
+	outPath := flag.String("out", "", "also write the full report, with per-case results, as JSON to this file")
+	rejudgePath := flag.String("rejudge", "", "re-judge the answers of this results.jsonl with the judge rubric instead of running the dataset")
+	verbose := flag.Bool("v", false, "also print the service logs (to stderr) while evaluating")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Logs would drown the report, so they are only shown on request
+	handler := slog.DiscardHandler
+	if *verbose {
+		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.LogLevel})
+	}
+	slog.SetDefault(slog.New(handler))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	answerRubric, err := judge.ConfiguredRubric(cfg.AnswerJudgeRubricPath, cfg.AnswerJudgeFaithfulnessThreshold)
+	if err != nil {
+		log.Fatalf("Failed to load answer judge rubric: %v", err)
+	}
+	llmClient := llm.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModelName)
+	if *rejudgePath != "" {
+		traces, err := eval.LoadTraces(*rejudgePath)
+		if err != nil {
+			log.Fatalf("Failed to load traces: %v", err)
+		}
+		report, err := eval.Rejudge(ctx, judge.New(llmClient, answerRubric), traces)
+		if err != nil {
+			log.Fatalf("Re-judging interrupted: %v", err)
+		}
+		writeReport(report, *outPath)
+		return
+	}
+
+	cases, err := eval.LoadCases(*datasetPath)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+
+	db, err := storage.New(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// The in-memory backend would start empty here; evaluation needs the API's index
+	if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+		log.Fatalf("Evaluation needs a persistent vector store; VECTOR_STORE_BACKEND=memory keeps the index inside the API process")
+	}
+	vectorStore, err := vectorstore.Open(vectorstore.BackendOptions{
+		Backend:         cfg.VectorStoreBackend,
+		QdrantURL:       cfg.QdrantURL,
+		PgvectorDSN:     cfg.PgvectorDSN,
+		UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+		Distance:        cfg.QdrantDistance,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open %s vector store: %v", cfg.VectorStoreBackend, err)
+	}
+	defer func() {
+		_ = vectorStore.Close()
+	}()
+
+	coldCollection := ""
+	if cfg.ColdStorageAfterMonths > 0 {
+		coldCollection = cfg.QdrantColdCollection
+	}
+	noteCollection := ""
+	if cfg.NotePrefilterTopM > 0 {
+		noteCollection = cfg.QdrantNoteCollection
+	}
+
+	featureFlags, err := features.New(cfg.FeatureFlags)
+	if err != nil {
+		log.Fatalf("Failed to initialize feature flags: %v", err)
+	}
+	var reranker rag.Reranker
+	if cfg.RerankerURL != "" {
+		reranker = llm.NewRerankerClient(cfg.RerankerURL, cfg.RerankerAPIKey, cfg.RerankerBatchSize)
+	}
+
+	var answerJudge rag.AnswerJudge
+	if cfg.AnswerJudge {
+		answerJudge = judge.New(llmClient, answerRubric)
+	}
+	engine, err := rag.NewEngineByName(cfg.RAGEngine, rag.EngineDeps{
+		Embedder:       llm.NewEmbeddingsClient(cfg.EmbeddingBaseURL, cfg.LLMAPIKey, cfg.EmbeddingModelName, cfg.QdrantVectorSize),
+		VectorStore:    vectorStore,
+		Collection:     cfg.QdrantCollection,
+		ColdCollection: coldCollection,
+		ChunkRepo:      storage.NewChunkRepo(db),
+		VaultRepo:      storage.NewVaultRepo(db),
+		NoteRepo:       storage.NewNoteRepo(db),
+		Chat:           llmClient,
+		FolderSelection: rag.FolderSelectionOptions{
+			MaxDepth:        cfg.FolderSelectionMaxDepth,
+			MaxFolders:      cfg.FolderSelectionMaxFolders,
+			Timeout:         time.Duration(cfg.FolderSelectionTimeoutSeconds) * time.Second,
+			Mode:            cfg.FolderSelectionMode,
+			HeuristicMargin: cfg.FolderSelectionHeuristicMargin,
+			CacheSize:       cfg.FolderSelectionCacheSize,
+			CacheTTL:        time.Duration(cfg.FolderSelectionCacheTTLSeconds) * time.Second,
+		},
+		NotePrefilter: rag.NotePrefilterOptions{
+			Collection: noteCollection,
+			TopM:       cfg.NotePrefilterTopM,
+		},
+		Flags:              featureFlags,
+		CollectionPerVault: cfg.QdrantCollectionPerVault,
+		QuestionCache: rag.QuestionCacheOptions{
+			Size: cfg.QuestionEmbeddingCacheSize,
+			TTL:  time.Duration(cfg.QuestionEmbeddingCacheTTLSeconds) * time.Second,
+		},
+		Generation: rag.GenerationOptions{
+			Stop:                cfg.LLMStopSequences,
+			RepeatPenalty:       cfg.LLMRepeatPenalty,
+			TopP:                cfg.LLMTopP,
+			TopK:                cfg.LLMTopK,
+			Model:               cfg.LLMModelName,
+			StructuredCitations: cfg.LLMStructuredCitations,
+		},
+		ScoreFloor: rag.ScoreThresholds{
+			Vector: cfg.MinVectorScoreFloor,
+			Final:  cfg.MinFinalScoreFloor,
+		},
+		Retrieval: rag.RetrievalOptions{
+			MinScore: rag.ScoreThresholds{
+				Vector: cfg.MinVectorScore,
+				Final:  cfg.MinFinalScore,
+			},
+			VectorWeight:       cfg.VectorScoreWeight,
+			LexicalWeight:      cfg.LexicalScoreWeight,
+			CandidateKPerScope: cfg.CandidateKPerScope,
+			MaxCandidates:      cfg.MaxCandidates,
+			TagBoost:           cfg.TagBoost,
+		},
+		Stages:    cfg.RAGStages,
+		Reranker:  reranker,
+		Tokenizer: llmClient,
+		Answerability: rag.AnswerabilityOptions{
+			Threshold: cfg.AnswerabilityThreshold,
+		},
+		Neighbors: rag.NeighborOptions{
+			TokenBudget: cfg.ContextNeighborTokens,
+		},
+		Judging: rag.JudgeOptions{
+			Judge:                 answerJudge,
+			FaithfulnessThreshold: answerRubric.RegenerateBelow(),
+			MaxRegenerations:      cfg.AnswerJudgeMaxRegenerations,
+			AbstainThresholds:     answerRubric.AbstainBelow(),
+		},
+		Timeouts: rag.TimeoutOptions{
+			Embed:      cfg.EmbedTimeout,
+			Search:     cfg.SearchTimeout,
+			Generation: cfg.GenerationTimeout,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to create RAG engine: %v", err)
+	}
+
+	report, err := eval.NewRunner(engine, eval.Options{K: *k}).Run(ctx, cases)
+	if err != nil {
+		log.Fatalf("Evaluation interrupted: %v", err)
+	}
+
+	writeReport(report, *outPath)
+}
+
+// writeReport prints report and, with an outPath, also writes it as JSON.
+func writeReport(report interface{ WriteText(io.Writer) error }, outPath string) {
+	if outPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/export"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Export writes the indexed chunk corpus as JSONL (one chunk per line) for use outside the API,
+// e.g. fine-tuning or offline evaluation. It reads the same environment configuration as the API.
+//
+// Usage:
+//
+//	go run ./cmd/export [-vault personal] [-folder projects] [-embeddings] [-out corpus.jsonl]
+func main() {
+	vaultName := flag.String("vault", "", "only export chunks from this vault (e.g. personal)")
+	folder := flag.String("folder", "", "only export chunks from this folder and its subfolders")
+	includeEmbeddings := flag.Bool("embeddings", false, "include each chunk's embedding vector (requires Qdrant)")
+	outPath := flag.String("out", "", "output file (default stdout)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Log to stderr so the corpus can be piped from stdout
+	opts := &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	db, err := storage.New(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := storage.Migrate(db); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	var vectorStore vectorstore.VectorStore
+	if *includeEmbeddings {
+		if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+			log.Fatalf("Exporting embeddings needs a persistent vector store; VECTOR_STORE_BACKEND=memory keeps the index inside the API process")
+		}
+		store, err := vectorstore.Open(vectorstore.BackendOptions{
+			Backend:         cfg.VectorStoreBackend,
+			QdrantURL:       cfg.QdrantURL,
+			PgvectorDSN:     cfg.PgvectorDSN,
+			UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+			Distance:        cfg.QdrantDistance,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open %s vector store: %v", cfg.VectorStoreBackend, err)
+		}
+		vectorStore = store
+	}
+
+	coldCollection := ""
+	if cfg.ColdStorageAfterMonths > 0 {
+		coldCollection = cfg.QdrantColdCollection
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+
+	exporter := export.NewExporter(storage.NewChunkRepo(db), vectorStore, cfg.QdrantCollection, coldCollection)
+	exporter.SetCollectionPerVault(cfg.QdrantCollectionPerVault)
+	count, err := exporter.Export(context.Background(), writer, export.Options{
+		Filter: storage.ChunkExportFilter{
+			VaultName: *vaultName,
+			Folder:    *folder,
+		},
+		IncludeEmbeddings: *includeEmbeddings,
+	})
+	if err != nil {
+		log.Fatalf("Failed to export corpus: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		log.Fatalf("Failed to write output: %v", err)
+	}
+
+	slog.Info("Export complete", "chunks", count, "out", *outPath)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"helloworld-ai/internal/rag"
+)
+
+// runAsk answers a question from the index and prints the answer with its references, or
+// the full response as JSON.
+func runAsk(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	req, asJSON, err := parseQuery(flags, args, "chunks to use as context (0 = auto)")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	engine, err := svc.newEngine()
+	if err != nil {
+		return err
+	}
+
+	resp, err := engine.Ask(ctx, req)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return writeJSON(os.Stdout, resp)
+	}
+	return writeAnswer(os.Stdout, resp)
+}
+
+// parseQuery parses the flags shared by ask and search and the question.
+func parseQuery(flags *flag.FlagSet, args []string, kUsage string) (rag.AskRequest, bool, error) {
+	vaults := flags.String("vault", "", "comma-separated vaults to search (default all)")
+	folders := flags.String("folder", "", "comma-separated folder prefixes to search (default all)")
+	tags := flags.String("tag", "", "comma-separated tags the notes must carry")
+	k := flags.Int("k", 0, kUsage)
+	includeCold := flags.Bool("cold", false, "also search notes moved to cold storage")
+	asJSON := flags.Bool("json", false, "print the full response as JSON")
+	if err := flags.Parse(args); err != nil {
+		return rag.AskRequest{}, false, err
+	}
+	question, err := questionArg(flags)
+	if err != nil {
+		return rag.AskRequest{}, false, err
+	}
+	return rag.AskRequest{
+		Question:    question,
+		Vaults:      splitList(*vaults),
+		Folders:     splitList(*folders),
+		Tags:        splitList(*tags),
+		K:           *k,
+		IncludeCold: *includeCold,
+	}, *asJSON, nil
+}
+
+// writeAnswer writes an answer followed by its references.
+func writeAnswer(w io.Writer, resp rag.AskResponse) error {
+	if _, err := fmt.Fprintln(w, resp.Answer); err != nil {
+		return err
+	}
+	if resp.Abstained && resp.AbstainReason != "" {
+		if _, err := fmt.Fprintf(w, "\n(abstained: %s)\n", resp.AbstainReason); err != nil {
+			return err
+		}
+	}
+	if len(resp.References) > 0 {
+		if _, err := fmt.Fprintln(w, "\nReferences:"); err != nil {
+			return err
+		}
+		for i, ref := range resp.References {
+			if _, err := fmt.Fprintf(w, "  [%d] %s\n", i+1, referenceLabel(ref)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// referenceLabel formats a reference as vault/path > heading.
+func referenceLabel(ref rag.Reference) string {
+	label := ref.Vault + "/" + ref.RelPath
+	if ref.HeadingPath != "" {
+		label += " > " + ref.HeadingPath
+	}
+	return label
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
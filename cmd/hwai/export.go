@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"helloworld-ai/internal/export"
+	"helloworld-ai/internal/storage"
+)
+
+// runExport writes the indexed chunk corpus as JSONL, like cmd/export.
+func runExport(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	vaultName := flags.String("vault", "", "only export chunks from this vault (e.g. personal)")
+	folder := flags.String("folder", "", "only export chunks from this folder and its subfolders")
+	includeEmbeddings := flags.Bool("embeddings", false, "include each chunk's embedding vector")
+	outPath := flags.String("out", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usageError(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+
+	exporter := export.NewExporter(storage.NewChunkRepo(svc.db), svc.vectorStore, cfg.QdrantCollection, svc.coldCollection)
+	exporter.SetCollectionPerVault(cfg.QdrantCollectionPerVault)
+	count, err := exporter.Export(ctx, writer, export.Options{
+		Filter: storage.ChunkExportFilter{
+			VaultName: *vaultName,
+			Folder:    *folder,
+		},
+		IncludeEmbeddings: *includeEmbeddings,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export corpus: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	slog.Info("Export complete", "chunks", count, "out", *outPath)
+	return nil
+}
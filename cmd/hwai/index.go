@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runIndex indexes every vault, or one, like the API's startup pass: unchanged notes are
+// skipped and notes whose files are gone are removed. -clear re-embeds everything.
+func runIndex(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	vaultName := flags.String("vault", "", "only index this vault (e.g. personal)")
+	clearFirst := flags.Bool("clear", false, "clear the index first and re-embed every note (all vaults only)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usageError(fmt.Sprintf("unexpected argument %q", flags.Arg(0)))
+	}
+	if *clearFirst && *vaultName != "" {
+		return usageError("-clear applies to all vaults and cannot be combined with -vault")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	if err := svc.ensureCollections(ctx); err != nil {
+		return err
+	}
+	pipeline := svc.newPipeline()
+
+	if *clearFirst {
+		if err := pipeline.ClearAll(ctx); err != nil {
+			return fmt.Errorf("failed to clear index: %w", err)
+		}
+	}
+
+	if *vaultName != "" {
+		v, lookupErr := svc.vaults.VaultByName(*vaultName)
+		if lookupErr != nil {
+			return lookupErr
+		}
+		err = pipeline.IndexVault(ctx, v.ID)
+	} else {
+		err = pipeline.IndexAll(ctx)
+	}
+
+	progress := pipeline.IndexProgress()
+	fmt.Fprintf(os.Stdout, "indexed %d of %d files, %d failed, %d removed notes\n",
+		progress.FilesIndexed, progress.FilesScanned, progress.FilesFailed, progress.NotesRemoved)
+	return err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"helloworld-ai/internal/config"
+)
+
+// Hwai runs the API's operations from the command line against the same database, vector
+// store, and llama.cpp server, without starting the HTTP server, so scripts and cron jobs
+// need no running API. It reads the same environment configuration as the API.
+//
+// Usage:
+//
+//	go run ./cmd/hwai index [-vault personal] [-clear]
+//	go run ./cmd/hwai ask [-vault personal] [-folder projects] [-k 5] [-json] "question"
+//	go run ./cmd/hwai search [-vault personal] [-folder projects] [-k 10] [-json] "query"
+//	go run ./cmd/hwai export [-vault personal] [-folder projects] [-embeddings] [-out corpus.jsonl]
+func main() {
+	flag.Usage = func() {
+		usage(flag.CommandLine.Output())
+	}
+	flag.BoolVar(&verbose, "v", false, "also print the service logs (to stderr)")
+	flag.Parse()
+
+	name := flag.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "" {
+			fmt.Fprintf(os.Stderr, "hwai: unknown command %q\n\n", name)
+		}
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Flag errors exit with status 2 and -h with 0, as for the other commands
+	flags := flag.NewFlagSet("hwai "+name, flag.ExitOnError)
+	if err := cmd.run(ctx, flags, flag.Args()[1:]); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "hwai %s: %v\n", name, err)
+			flags.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "hwai %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// verbose shows the service logs below warnings (-v).
+var verbose bool
+
+// loadConfig loads the configuration and sets up logging to stderr, so output can be piped.
+// Without -v only warnings and errors are logged.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	level := slog.LevelWarn
+	if verbose {
+		level = cfg.LogLevel
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	return cfg, nil
+}
+
+// command is a hwai subcommand.
+type command struct {
+	// summary is the one-line description shown by hwai -h.
+	summary string
+	// run parses args with flags, loads the configuration, and runs the command.
+	run func(ctx context.Context, flags *flag.FlagSet, args []string) error
+}
+
+// commands lists the subcommands by name.
+var commands = map[string]command{
+	"index":  {summary: "index the configured vaults (changed notes only)", run: runIndex},
+	"ask":    {summary: "answer a question from the index", run: runAsk},
+	"search": {summary: "list the chunks retrieved for a query, without an answer", run: runSearch},
+	"export": {summary: "write the indexed chunks as JSONL", run: runExport},
+}
+
+// usageError is a command line mistake, reported with the command's usage.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// usage writes the top-level help.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hwai [-v] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range []string{"index", "ask", "search", "export"} {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'hwai <command> -h' for the command's flags. Configuration is read from the")
+	fmt.Fprintln(w, "same environment variables as the API.")
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// questionArg joins the positional arguments into the question, so it may be quoted or not.
+func questionArg(flags *flag.FlagSet) (string, error) {
+	question := strings.TrimSpace(strings.Join(flags.Args(), " "))
+	if question == "" {
+		return "", usageError("missing question")
+	}
+	return question, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"helloworld-ai/internal/rag"
+)
+
+// snippetRunes bounds the chunk text printed per search result.
+const snippetRunes = 200
+
+// runSearch prints the chunks retrieved and ranked for a query, without calling the chat
+// model, or the full response as JSON.
+func runSearch(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	req, asJSON, err := parseQuery(flags, args, "chunks to return (0 = auto)")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	svc, err := openServices(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+	engine, err := svc.newEngine()
+	if err != nil {
+		return err
+	}
+
+	resp, err := engine.Search(ctx, req)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return writeJSON(os.Stdout, resp)
+	}
+	return writeResults(os.Stdout, resp)
+}
+
+// writeResults writes one block per result: rank, score, source, and a text snippet.
+func writeResults(w io.Writer, resp rag.SearchResponse) error {
+	if len(resp.Results) == 0 {
+		reason := resp.AbstainReason
+		if reason == "" {
+			reason = "no results"
+		}
+		_, err := fmt.Fprintf(w, "(%s)\n", reason)
+		return err
+	}
+	for _, result := range resp.Results {
+		if _, err := fmt.Fprintf(w, "%d. [%.3f] %s\n   %s\n", result.Rank, result.ScoreFinal, referenceLabel(result.Reference), snippet(result.Text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snippet returns text on one line, cut to snippetRunes.
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > snippetRunes {
+		return string(runes[:snippetRunes]) + "…"
+	}
+	return text
+}
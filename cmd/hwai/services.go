@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/judge"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// services are the database and vector store shared by the commands, wired as in cmd/api.
+type services struct {
+	cfg         *config.Config
+	db          *sql.DB
+	vectorStore vectorstore.Store
+	vaults      *vault.Manager
+	embedder    *llm.EmbeddingsClient
+	// coldCollection and noteCollection are empty when their policies are disabled.
+	coldCollection string
+	noteCollection string
+}
+
+// openServices opens the database and the vector store. The in-memory backend is rejected,
+// since its index lives inside the API process.
+func openServices(ctx context.Context, cfg *config.Config) (*services, error) {
+	if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+		return nil, fmt.Errorf("VECTOR_STORE_BACKEND=memory keeps the index inside the API process; use qdrant or pgvector")
+	}
+
+	db, err := storage.New(cfg.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := storage.Migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	vaults, err := vault.NewManager(ctx, storage.NewVaultRepo(db), cfg.Vaults, cfg.VaultSymlinks)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize vault manager: %w", err)
+	}
+
+	vectorStore, err := vectorstore.Open(vectorstore.BackendOptions{
+		Backend:         cfg.VectorStoreBackend,
+		QdrantURL:       cfg.QdrantURL,
+		PgvectorDSN:     cfg.PgvectorDSN,
+		UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+		Distance:        cfg.QdrantDistance,
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to open %s vector store: %w", cfg.VectorStoreBackend, err)
+	}
+
+	svc := &services{
+		cfg:         cfg,
+		db:          db,
+		vectorStore: vectorStore,
+		vaults:      vaults,
+		embedder:    llm.NewEmbeddingsClient(cfg.EmbeddingBaseURL, cfg.LLMAPIKey, cfg.EmbeddingModelName, cfg.QdrantVectorSize),
+	}
+	svc.embedder.SetMaxConcurrency(cfg.EmbeddingParallelism)
+	if cfg.ColdStorageAfterMonths > 0 {
+		svc.coldCollection = cfg.QdrantColdCollection
+	}
+	if cfg.NotePrefilterTopM > 0 {
+		svc.noteCollection = cfg.QdrantNoteCollection
+	}
+	return svc, nil
+}
+
+// Close closes the vector store and the database.
+func (s *services) Close() {
+	_ = s.vectorStore.Close()
+	_ = s.db.Close()
+}
+
+// ensureCollections creates the collections indexing writes to, as the API does at startup.
+func (s *services) ensureCollections(ctx context.Context) error {
+	collections := []string{s.cfg.QdrantCollection}
+	if s.cfg.QdrantCollectionPerVault {
+		for _, v := range s.vaults.ListVaults() {
+			collections = append(collections, vectorstore.VaultCollection(s.cfg.QdrantCollection, v.ID))
+		}
+	}
+	for _, collection := range []string{s.coldCollection, s.noteCollection} {
+		if collection != "" {
+			collections = append(collections, collection)
+		}
+	}
+	for _, collection := range collections {
+		if err := s.vectorStore.EnsureCollection(ctx, collection, s.cfg.QdrantVectorSize); err != nil {
+			return fmt.Errorf("failed to ensure collection %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// newPipeline creates the indexing pipeline.
+func (s *services) newPipeline() *indexer.Pipeline {
+	cfg := s.cfg
+	pipeline := indexer.NewPipeline(
+		s.vaults,
+		storage.NewNoteRepo(s.db),
+		storage.NewChunkRepo(s.db),
+		storage.NewIndexTimingRepo(s.db),
+		storage.NewIndexChecksumRepo(s.db),
+		s.embedder,
+		s.vectorStore,
+		cfg.QdrantCollection,
+		s.coldCollection,
+		s.noteCollection,
+		storage.NewShadowIndex(s.db, cfg.DBPath+".rebuild"),
+	)
+	if cfg.EmbeddingParallelism > 0 {
+		pipeline.SetEmbeddingParallelism(cfg.EmbeddingParallelism)
+	}
+	pipeline.SetChunkOverlap(cfg.ChunkOverlapRunes)
+	pipeline.SetEventStore(storage.NewIndexEventRepo(s.db))
+	pipeline.SetFailureStore(storage.NewIndexFailureRepo(s.db))
+	pipeline.SetExcludedFolderStore(storage.NewExcludedFolderRepo(s.db))
+	pipeline.SetCollectionPerVault(cfg.QdrantCollectionPerVault)
+	return pipeline
+}
+
+// newEngine creates the RAG engine selected by RAG_ENGINE. It answers every question afresh:
+// the answer cache belongs to the API, whose in-memory caches would not see new entries.
+func (s *services) newEngine() (rag.Engine, error) {
+	cfg := s.cfg
+	featureFlags, err := features.New(cfg.FeatureFlags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize feature flags: %w", err)
+	}
+	var reranker rag.Reranker
+	if cfg.RerankerURL != "" {
+		reranker = llm.NewRerankerClient(cfg.RerankerURL, cfg.RerankerAPIKey, cfg.RerankerBatchSize)
+	}
+
+	llmClient := llm.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModelName)
+	answerRubric, err := judge.ConfiguredRubric(cfg.AnswerJudgeRubricPath, cfg.AnswerJudgeFaithfulnessThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load answer judge rubric: %w", err)
+	}
+	var answerJudge rag.AnswerJudge
+	if cfg.AnswerJudge {
+		answerJudge = judge.New(llmClient, answerRubric)
+	}
+	engine, err := rag.NewEngineByName(cfg.RAGEngine, rag.EngineDeps{
+		Embedder:        s.embedder,
+		VectorStore:     s.vectorStore,
+		Collection:      cfg.QdrantCollection,
+		ColdCollection:  s.coldCollection,
+		ChunkRepo:       storage.NewChunkRepo(s.db),
+		VaultRepo:       storage.NewVaultRepo(s.db),
+		NoteRepo:        storage.NewNoteRepo(s.db),
+		Chat:            llmClient,
+		QuestionLogMode: cfg.LogQuestions,
+		FolderSelection: rag.FolderSelectionOptions{
+			MaxDepth:        cfg.FolderSelectionMaxDepth,
+			MaxFolders:      cfg.FolderSelectionMaxFolders,
+			Timeout:         time.Duration(cfg.FolderSelectionTimeoutSeconds) * time.Second,
+			Mode:            cfg.FolderSelectionMode,
+			HeuristicMargin: cfg.FolderSelectionHeuristicMargin,
+			CacheSize:       cfg.FolderSelectionCacheSize,
+			CacheTTL:        time.Duration(cfg.FolderSelectionCacheTTLSeconds) * time.Second,
+		},
+		NotePrefilter: rag.NotePrefilterOptions{
+			Collection: s.noteCollection,
+			TopM:       cfg.NotePrefilterTopM,
+		},
+		Flags:              featureFlags,
+		CollectionPerVault: cfg.QdrantCollectionPerVault,
+		Generation: rag.GenerationOptions{
+			Stop:                cfg.LLMStopSequences,
+			RepeatPenalty:       cfg.LLMRepeatPenalty,
+			TopP:                cfg.LLMTopP,
+			TopK:                cfg.LLMTopK,
+			Model:               cfg.LLMModelName,
+			StructuredCitations: cfg.LLMStructuredCitations,
+		},
+		Hydrator: s.newPipeline(),
+		ScoreFloor: rag.ScoreThresholds{
+			Vector: cfg.MinVectorScoreFloor,
+			Final:  cfg.MinFinalScoreFloor,
+		},
+		Retrieval: rag.RetrievalOptions{
+			MinScore: rag.ScoreThresholds{
+				Vector: cfg.MinVectorScore,
+				Final:  cfg.MinFinalScore,
+			},
+			VectorWeight:       cfg.VectorScoreWeight,
+			LexicalWeight:      cfg.LexicalScoreWeight,
+			CandidateKPerScope: cfg.CandidateKPerScope,
+			MaxCandidates:      cfg.MaxCandidates,
+			TagBoost:           cfg.TagBoost,
+		},
+		Stages:    cfg.RAGStages,
+		Reranker:  reranker,
+		Tokenizer: llmClient,
+		Answerability: rag.AnswerabilityOptions{
+			Threshold: cfg.AnswerabilityThreshold,
+		},
+		Neighbors: rag.NeighborOptions{
+			TokenBudget: cfg.ContextNeighborTokens,
+		},
+		Judging: rag.JudgeOptions{
+			Judge:                 answerJudge,
+			FaithfulnessThreshold: answerRubric.RegenerateBelow(),
+			MaxRegenerations:      cfg.AnswerJudgeMaxRegenerations,
+			AbstainThresholds:     answerRubric.AbstainBelow(),
+		},
+		Timeouts: rag.TimeoutOptions{
+			Embed:      cfg.EmbedTimeout,
+			Search:     cfg.SearchTimeout,
+			Generation: cfg.GenerationTimeout,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RAG engine: %w", err)
+	}
+
+	// Answers are filtered as the API would filter them
+	if cfg.SafetyFilterRules != "" {
+		safetyRules, err := rag.LoadSafetyRules(cfg.SafetyFilterRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load safety filter rules: %w", err)
+		}
+		safetyOptions := rag.SafetyFilterOptions{Rules: safetyRules, Action: cfg.SafetyFilterAction}
+		if cfg.SafetyFilterClassify {
+			safetyOptions.Classifier = llmClient
+		}
+		safetyFilter, err := rag.NewSafetyFilter(safetyOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create safety filter: %w", err)
+		}
+		engine = rag.NewSafetyEngine(engine, safetyFilter)
+	}
+	return engine, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/snapshot"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Restore loads a snapshot downloaded from POST /api/v1/admin/snapshot into the database at
+// DB_PATH and the configured vector store, e.g. after moving to another machine. It reads the
+// same environment configuration as the API, which must be stopped while restoring.
+//
+// Usage:
+//
+//	go run ./cmd/restore [-force] -in index-snapshot.tar.gz
+func main() {
+	inPath := flag.String("in", "", "snapshot file (default stdin)")
+	force := flag.Bool("force", false, "replace an existing database and the snapshot's collections")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: cfg.LogLevel,
+	}
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+		log.Fatalf("Restoring needs a persistent vector store; VECTOR_STORE_BACKEND=memory keeps the index inside the API process")
+	}
+	store, err := vectorstore.Open(vectorstore.BackendOptions{
+		Backend:         cfg.VectorStoreBackend,
+		QdrantURL:       cfg.QdrantURL,
+		PgvectorDSN:     cfg.PgvectorDSN,
+		UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+		Distance:        cfg.QdrantDistance,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open %s vector store: %v", cfg.VectorStoreBackend, err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	var in io.Reader = os.Stdin
+	if *inPath != "" {
+		file, err := os.Open(*inPath)
+		if err != nil {
+			log.Fatalf("Failed to open snapshot: %v", err)
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		in = file
+	}
+
+	manifest, err := snapshot.Restore(context.Background(), bufio.NewReader(in), store, snapshot.RestoreOptions{
+		DBPath: cfg.DBPath,
+		Force:  *force,
+	})
+	if err != nil {
+		log.Fatalf("Failed to restore snapshot: %v", err)
+	}
+
+	slog.Info("Restore complete", "db_path", cfg.DBPath, "chunks", manifest.Chunks, "collections", len(manifest.Collections), "created_at", manifest.CreatedAt)
+}
@@ -0,0 +1,234 @@
+// Package cache provides the size-bounded, expiring in-memory caches shared by the service
+// (question embeddings, vault and folder lists, prompt token counts) and a registry that
+// reports their hit rates and flushes them from the admin API.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Options bounds a cache.
+type Options struct {
+	// Size is the maximum number of entries; the least recently used entry is evicted
+	// beyond it (0: unbounded).
+	Size int
+	// TTL is how long an entry stays cached after it was set (0: until evicted or flushed).
+	TTL time.Duration
+}
+
+// Stats is a snapshot of a cache's size and counters since start (or the last flush).
+type Stats struct {
+	Name string `json:"name"`
+	// Storage is where entries live: "memory" or, for the answer cache, "sqlite".
+	Storage string `json:"storage"`
+	// Entries and MaxEntries are only reported by in-memory caches (MaxEntries 0: unbounded).
+	Entries    int   `json:"entries"`
+	MaxEntries int   `json:"max_entries"`
+	TTLSeconds int64 `json:"ttl_seconds"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Evictions  int64 `json:"evictions"`
+	// HitRate is Hits / (Hits + Misses), 0 before the first lookup.
+	HitRate float64 `json:"hit_rate"`
+}
+
+// WithHitRate returns s with HitRate computed from its counters.
+func (s Stats) WithHitRate() Stats {
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	} else {
+		s.HitRate = 0
+	}
+	return s
+}
+
+// Cache is a concurrency-safe LRU cache with an optional TTL that counts hits, misses,
+// and evictions. A nil *Cache never hits and ignores writes, so callers can leave a
+// disabled cache nil.
+type Cache[K comparable, V any] struct {
+	name string
+	opts Options
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	order   *list.List // front = most recently used
+
+	hits, misses, evictions int64
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time // zero: never
+}
+
+// New creates a cache reported as name.
+func New[K comparable, V any](name string, opts Options) *Cache[K, V] {
+	return &Cache[K, V]{
+		name:    name,
+		opts:    opts,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Name returns the name the cache is reported as.
+func (c *Cache[K, V]) Name() string {
+	if c == nil {
+		return ""
+	}
+	return c.name
+}
+
+// Get returns the value cached for key, if present and not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	return c.GetValid(key, nil)
+}
+
+// GetValid is Get for values that can go stale before their TTL (e.g. snapshots of an
+// index epoch): a value for which valid returns false counts as a miss but stays cached,
+// so Peek can still serve it when a refresh fails. A nil valid accepts every value.
+func (c *Cache[K, V]) GetValid(key K, valid func(V) bool) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.lookup(key)
+	if !ok {
+		c.misses++
+		return zero, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if valid != nil && !valid(e.value) {
+		c.misses++
+		return zero, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Peek returns the value cached for key without counting a lookup or refreshing its
+// recency, for fallbacks such as serving a stale snapshot.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.lookup(key)
+	if !ok {
+		return zero, false
+	}
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// lookup finds key's element, dropping it when expired. c.mu must be held.
+func (c *Cache[K, V]) lookup(key K) (*list.Element, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if expires := elem.Value.(*entry[K, V]).expires; !expires.IsZero() && !c.now().Before(expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	return elem, true
+}
+
+// Set caches value for key, evicting the least recently used entries beyond the size bound.
+func (c *Cache[K, V]) Set(key K, value V) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.opts.TTL > 0 {
+		expires = c.now().Add(c.opts.TTL)
+	}
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*entry[K, V])
+		e.value = value
+		e.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	for c.opts.Size > 0 && c.order.Len() > c.opts.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+		c.evictions++
+	}
+}
+
+// Delete removes key from the cache.
+func (c *Cache[K, V]) Delete(key K) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet dropped.
+func (c *Cache[K, V]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Flush drops every entry and resets the counters. It never fails; the error satisfies Flusher.
+func (c *Cache[K, V]) Flush(context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+	c.hits, c.misses, c.evictions = 0, 0, 0
+	return nil
+}
+
+// Stats returns the cache's size and counters.
+func (c *Cache[K, V]) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Name:       c.name,
+		Storage:    "memory",
+		Entries:    c.order.Len(),
+		MaxEntries: c.opts.Size,
+		TTLSeconds: int64(c.opts.TTL / time.Second),
+		Hits:       c.hits,
+		Misses:     c.misses,
+		Evictions:  c.evictions,
+	}.WithHitRate()
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCache_LRUAndTTL(t *testing.T) {
+	c := New[string, int]("test", Options{Size: 2, TTL: time.Minute})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get(a) missed right after Set")
+	}
+
+	// "b" is now least recently used and is evicted by a third entry
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) hit after eviction")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("c"); ok {
+		t.Error("Get(c) hit after TTL expired")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 after expiry", c.Len())
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Evictions != 1 || stats.HitRate != 0.5 {
+		t.Errorf("Stats() = %+v, want 2 hits, 2 misses, 1 eviction, 0.5 hit rate", stats)
+	}
+	if stats.Name != "test" || stats.MaxEntries != 2 || stats.TTLSeconds != 60 || stats.Storage != "memory" {
+		t.Errorf("Stats() = %+v, want the cache's name and bounds", stats)
+	}
+}
+
+func TestCache_GetValidKeepsStaleEntries(t *testing.T) {
+	c := New[string, int]("epochs", Options{})
+	c.Set("vaults", 1)
+
+	if _, ok := c.GetValid("vaults", func(epoch int) bool { return epoch == 2 }); ok {
+		t.Error("GetValid() hit on a stale value")
+	}
+	if v, ok := c.Peek("vaults"); !ok || v != 1 {
+		t.Errorf("Peek() = %v, %v, want the stale value", v, ok)
+	}
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want one miss and Peek uncounted", stats)
+	}
+}
+
+func TestCache_Flush(t *testing.T) {
+	c := New[int, string]("test", Options{Size: 10})
+	c.Set(1, "one")
+	c.Get(1)
+
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if _, ok := c.Peek(1); ok || c.Len() != 0 {
+		t.Error("Flush() left entries behind")
+	}
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Flush() kept counters: %+v", stats)
+	}
+}
+
+func TestCache_Nil(t *testing.T) {
+	var c *Cache[string, int]
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("nil cache reported a hit")
+	}
+	if err := c.Flush(context.Background()); err != nil || c.Len() != 0 {
+		t.Errorf("nil cache Flush() = %v, Len() = %d", err, c.Len())
+	}
+}
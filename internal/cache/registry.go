@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownCache is returned when flushing a cache that was never registered.
+var ErrUnknownCache = errors.New("unknown cache")
+
+// Flusher is a cache the registry can report on and flush. *Cache implements it.
+type Flusher interface {
+	Stats() Stats
+	Flush(ctx context.Context) error
+}
+
+// Registry tracks the service's caches by name for the admin API.
+// A nil *Registry ignores registrations and reports no caches.
+type Registry struct {
+	mu     sync.Mutex
+	caches map[string]Flusher
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]Flusher)}
+}
+
+// Register adds cache under name, replacing any cache registered under the same name.
+// A nil cache is ignored, so disabled caches need no special casing.
+func (r *Registry) Register(name string, cache Flusher) {
+	if r == nil || cache == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caches[name] = cache
+}
+
+// Stats returns the stats of every registered cache, sorted by name.
+func (r *Registry) Stats() []Stats {
+	if r == nil {
+		return []Stats{}
+	}
+	caches := r.snapshot()
+	stats := make([]Stats, 0, len(caches))
+	for name, cache := range caches {
+		s := cache.Stats()
+		s.Name = name
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Flush empties the cache registered under name.
+func (r *Registry) Flush(ctx context.Context, name string) error {
+	cache, ok := r.snapshot()[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCache, name)
+	}
+	if err := cache.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush cache %q: %w", name, err)
+	}
+	return nil
+}
+
+// FlushAll empties every registered cache and returns their names, sorted. It keeps going
+// past failures and returns them joined.
+func (r *Registry) FlushAll(ctx context.Context) ([]string, error) {
+	var names []string
+	var errs []error
+	for name, cache := range r.snapshot() {
+		if err := cache.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush cache %q: %w", name, err))
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, errors.Join(errs...)
+}
+
+// snapshot copies the registered caches so they are used without holding the lock.
+func (r *Registry) snapshot() map[string]Flusher {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	caches := make(map[string]Flusher, len(r.caches))
+	for name, cache := range r.caches {
+		caches[name] = cache
+	}
+	return caches
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingCache is a Flusher whose flush always fails.
+type failingCache struct{}
+
+func (failingCache) Stats() Stats                { return Stats{Storage: "sqlite"} }
+func (failingCache) Flush(context.Context) error { return errors.New("database is locked") }
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry()
+	vaults := New[string, int]("vaults", Options{})
+	folders := New[string, int]("folders", Options{})
+	r.Register("vaults", vaults)
+	r.Register("folders", folders)
+	r.Register("disabled", nil)
+	vaults.Set("all", 1)
+	folders.Set("1", 2)
+
+	stats := r.Stats()
+	if len(stats) != 2 || stats[0].Name != "folders" || stats[1].Name != "vaults" || stats[1].Entries != 1 {
+		t.Fatalf("Stats() = %+v, want folders and vaults, sorted", stats)
+	}
+
+	if err := r.Flush(ctx, "vaults"); err != nil {
+		t.Fatalf("Flush(vaults) error = %v", err)
+	}
+	if vaults.Len() != 0 || folders.Len() != 1 {
+		t.Errorf("Flush(vaults) should only empty vaults: vaults=%d folders=%d", vaults.Len(), folders.Len())
+	}
+	if err := r.Flush(ctx, "answers"); !errors.Is(err, ErrUnknownCache) {
+		t.Errorf("Flush(unknown) error = %v, want ErrUnknownCache", err)
+	}
+
+	r.Register("answers", failingCache{})
+	names, err := r.FlushAll(ctx)
+	if err == nil {
+		t.Error("FlushAll() should report the failing cache")
+	}
+	if len(names) != 2 || names[0] != "folders" || names[1] != "vaults" || folders.Len() != 0 {
+		t.Errorf("FlushAll() = %v, want the other caches flushed", names)
+	}
+}
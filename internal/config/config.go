@@ -1,32 +1,243 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/logging"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+const (
+	// ModeProduction uses llama.cpp and Qdrant.
+	ModeProduction = "production"
+	// ModeTest runs with an in-process fake LLM and in-memory vector store (no external services).
+	ModeTest = "test"
+)
+
+const (
+	// RolePrimary runs the single instance that migrates the database, indexes the vaults,
+	// and serves every endpoint.
+	RolePrimary = "primary"
+	// RoleReplica runs an additional read-only instance that answers questions from the
+	// primary's SQLite database and Qdrant collections, to scale Ask throughput.
+	RoleReplica = "replica"
 )
 
 // Config holds all configuration for the application.
 type Config struct {
+	Mode               string
 	LLMBaseURL         string
 	LLMModelName       string
 	LLMAPIKey          string
 	EmbeddingBaseURL   string
 	EmbeddingModelName string
 	DBPath             string
-	VaultPersonalPath  string
-	VaultWorkPath      string
+	Vaults             []vault.Config
 	QdrantURL          string
 	QdrantCollection   string
 	QdrantVectorSize   int
 	APIPort            string
 	LogLevel           slog.Level
 	LogFormat          string
+	// LogQuestions controls how question text appears in logs: full, truncate, or hash.
+	LogQuestions string
+	// Role is RolePrimary (the indexing writer) or RoleReplica (read-only, serving questions).
+	Role string
+	// ColdStorageAfterMonths moves notes not updated or retrieved within this many months
+	// into the cold collection. Zero disables the policy.
+	ColdStorageAfterMonths int
+	QdrantColdCollection   string
+	// NotePrefilterTopM enables two-stage retrieval: chunks are only searched within the
+	// M notes whose centroid embeddings best match the question. Zero disables it.
+	NotePrefilterTopM    int
+	QdrantNoteCollection string
+	// FolderSelectionMaxDepth collapses folders deeper than this in the folder-selection prompt (0 = unlimited).
+	FolderSelectionMaxDepth int
+	// FolderSelectionMaxFolders caps the folders offered in the folder-selection prompt (0 = unlimited).
+	FolderSelectionMaxFolders int
+	// FolderSelectionTimeoutSeconds bounds the folder-selection LLM call before falling back to
+	// ranking folders by path tokens (0 = no limit).
+	FolderSelectionTimeoutSeconds int
+	// FolderSelectionMode ranks folders with the LLM ("llm") or by chunk similarity, asking the
+	// LLM only when the ranking is inconclusive ("heuristic").
+	FolderSelectionMode string
+	// FolderSelectionHeuristicMargin is how far the best folder's similarity must lead the
+	// runner-up for the heuristic ranking to be used.
+	FolderSelectionHeuristicMargin float64
+	// FolderSelectionCacheSize caps the cached folder rankings (0 = no cache).
+	FolderSelectionCacheSize int
+	// FolderSelectionCacheTTLSeconds is how long a cached folder ranking is reused (0 = no cache).
+	FolderSelectionCacheTTLSeconds int
+	// StorageSQLiteSoftLimitMB warns when the SQLite database files exceed this size (0 = no limit).
+	StorageSQLiteSoftLimitMB int
+	// StorageQdrantSoftLimitMB warns when the estimated Qdrant vector data exceeds this size (0 = no limit).
+	StorageQdrantSoftLimitMB int
+	// StorageAlertWebhookURL receives a JSON POST when a storage soft limit is first exceeded (empty = log only).
+	StorageAlertWebhookURL string
+	// StorageCheckIntervalMinutes is how often storage usage is measured against the soft limits.
+	StorageCheckIntervalMinutes int
+	// FeatureFlags holds per-deployment feature flag values (flags not listed use built-in defaults).
+	FeatureFlags map[string]bool
+	// MaxRequestBodyKB caps JSON request bodies (ask, search, jobs, and admin updates).
+	MaxRequestBodyKB int
+	// MaxQuestionLength caps the question in characters (longer text goes to the document endpoint).
+	MaxQuestionLength int
+	// MaxDocumentKB caps documents sent to the document question endpoint.
+	MaxDocumentKB int
+	// MaxRequestVaults caps the vaults one ask or search request may name.
+	MaxRequestVaults int
+	// MaxRequestFolders caps the folders one ask or search request may name.
+	MaxRequestFolders int
+	// VaultSymlinks controls how vault scanning treats symlinks: follow (with loop detection) or skip.
+	VaultSymlinks string
+	// AdminToken is the bearer token required by /api/v1/admin endpoints (empty = admin endpoints disabled).
+	AdminToken string
+	// AuthRequired rejects /api/v1 requests without a user or admin token (false = anonymous requests see every vault).
+	AuthRequired bool
+	// QuestionEmbeddingCacheSize caps how many recent question embeddings are cached (0 = disabled).
+	QuestionEmbeddingCacheSize int
+	// QuestionEmbeddingCacheTTLSeconds is how long a cached question embedding is reused.
+	QuestionEmbeddingCacheTTLSeconds int
+	// AnswerCacheTTLSeconds is how long answers to repeated questions are cached in SQLite (0 = disabled).
+	AnswerCacheTTLSeconds int
+	// RAGEngine selects the answer engine implementation (see rag.EngineNames).
+	RAGEngine string
+	// RerankerURL is the base URL of an external reranker serving POST /rerank (empty = lexical rerank).
+	RerankerURL string
+	// RerankerAPIKey is sent as a bearer token to the external reranker (empty = none).
+	RerankerAPIKey string
+	// RerankerBatchSize caps the texts sent per rerank request.
+	RerankerBatchSize int
+	// TracingEnabled exports OpenTelemetry traces over OTLP (set when an OTLP endpoint is configured).
+	TracingEnabled bool
+	// OTelServiceName is the service name reported on exported traces.
+	OTelServiceName string
+	// RAGStages is the ordered ask pipeline (see rag.DefaultStages; empty = default order).
+	RAGStages []string
+	// DigestVault is the configured vault that weekly digest notes are written to (empty = disabled).
+	DigestVault string
+	// DigestFolder is the folder, relative to the digest vault root, that holds digest notes.
+	DigestFolder string
+	// LLMStopSequences end answer generation when the model produces one of them.
+	LLMStopSequences []string
+	// LLMRepeatPenalty penalizes repeated tokens during answer generation (0 = server default).
+	LLMRepeatPenalty float32
+	// LLMTopP is the nucleus sampling threshold for answer generation (0 = server default).
+	LLMTopP float32
+	// LLMTopK limits answer sampling to the K most likely tokens (0 = server default).
+	LLMTopK int
+	// LLMAllowedModels are the chat models besides LLM_MODEL that ask requests may select.
+	LLMAllowedModels []string
+	// AskMaxTokens caps the max_tokens an ask request may set (0 = no cap).
+	AskMaxTokens int
+	// AskAllowSystemPrompt lets ask requests replace the answer system prompt.
+	AskAllowSystemPrompt bool
+	// LLMStructuredCitations requests answers as JSON with cited chunk IDs. Disable it for
+	// chat servers without response_format support; citations are then parsed from the text.
+	LLMStructuredCitations bool
+	// QdrantUpsertBatchSize caps the number of points sent per Qdrant upsert request.
+	QdrantUpsertBatchSize int
+	// QdrantCollectionPerVault stores each vault's chunks in its own collection, named
+	// QdrantCollection plus "_vault_<id>", instead of the shared collection.
+	QdrantCollectionPerVault bool
+	// SafetyFilterRules is the JSON file of sensitive categories checked in answers (empty = filter disabled).
+	SafetyFilterRules string
+	// SafetyFilterAction is what happens to answers matching a category: redact or block.
+	SafetyFilterAction string
+	// SafetyFilterClassify also asks the LLM whether answers fall into a described category.
+	SafetyFilterClassify bool
+	// PIIScanPatterns is a JSON file of extra or replacement patterns for the admin PII scan
+	// (empty = built-in email, phone, and credit card patterns only).
+	PIIScanPatterns string
+	// QdrantDistance is the distance metric of the Qdrant collections: cosine, dot, or euclid.
+	QdrantDistance string
+	// VectorStoreBackend is where vectors are stored: qdrant, memory, or pgvector.
+	VectorStoreBackend string
+	// PgvectorDSN is the PostgreSQL connection string for the pgvector backend.
+	PgvectorDSN string
+	// SQLiteWAL switches the database to WAL mode for external replication (e.g. Litestream).
+	SQLiteWAL bool
+	// SQLiteReplicaPath is where a standby copy of the database is kept (empty disables it).
+	SQLiteReplicaPath string
+	// SQLiteReplicaIntervalMinutes is how often the standby copy is refreshed.
+	SQLiteReplicaIntervalMinutes int
+	// IndexStartupOrder orders the startup index run (indexer.IndexOrderScan or indexer.IndexOrderRecent).
+	IndexStartupOrder string
+	// IndexRecentDays indexes notes modified within this many days first at startup (0 disables it).
+	IndexRecentDays int
+	// IndexPriorityFolders are "vault/folder" prefixes indexed first at startup.
+	IndexPriorityFolders []string
+	// LLMMaxConcurrency bounds concurrent chat requests (0 = the llama.cpp server's slot count).
+	LLMMaxConcurrency int
+	// EmbeddingParallelism is how many embedding batches of a note are requested at once, and
+	// bounds embedding requests in flight overall (0 = the embedding server's slot count).
+	EmbeddingParallelism int
+	// ChunkOverlapRunes is how many runes of trailing sentences each chunk repeats from the
+	// previous chunk of its note (0 = no overlap, at most 350).
+	ChunkOverlapRunes int
+	// EmbedTimeout bounds embedding a question (0 = bounded by the request only).
+	EmbedTimeout time.Duration
+	// SearchTimeout bounds each vector store search made for a question (0 = bounded by the request only).
+	SearchTimeout time.Duration
+	// GenerationTimeout bounds each chat model call made for an answer (0 = bounded by the request only).
+	GenerationTimeout time.Duration
+	// ShutdownTimeoutSeconds is how long shutdown waits for in-flight requests and
+	// background indexing to finish.
+	ShutdownTimeoutSeconds int
+	// MinVectorScore is the default vector score threshold for retrieved chunks.
+	MinVectorScore float32
+	// MinFinalScore is the default final (reranked) score threshold for retrieved chunks.
+	MinFinalScore float32
+	// VectorScoreWeight and LexicalScoreWeight weight the vector and lexical scores in the
+	// final score.
+	VectorScoreWeight  float32
+	LexicalScoreWeight float32
+	// CandidateKPerScope is how many chunks each vault or folder search retrieves.
+	CandidateKPerScope int
+	// MaxCandidates caps the deduplicated candidates that are reranked per question.
+	MaxCandidates int
+	// TagBoost is added to the final score of chunks whose note tags match the question's keywords.
+	TagBoost float32
+	// MinVectorScoreFloor is the lowest vector score threshold a request may ask for.
+	MinVectorScoreFloor float32
+	// MinFinalScoreFloor is the lowest final (reranked) score threshold a request may ask for.
+	MinFinalScoreFloor float32
+	// AnswerabilityThreshold is the lowest answerability judge score (0-1) a question may get
+	// before the engine abstains instead of generating (0 = check disabled).
+	AnswerabilityThreshold float32
+	// AnswerJudge scores each generated answer's faithfulness and relevance with the chat model.
+	AnswerJudge bool
+	// AnswerJudgeFaithfulnessThreshold regenerates judged answers less faithful than this (0 = scores only).
+	AnswerJudgeFaithfulnessThreshold float32
+	// AnswerJudgeMaxRegenerations caps how many times an unfaithful answer is regenerated.
+	AnswerJudgeMaxRegenerations int
+	// AnswerJudgeRubricPath is a JSON file with the judge's criteria, scale, and thresholds
+	// (empty: faithfulness and relevance from 0 to 1).
+	AnswerJudgeRubricPath string
+	// ContextNeighborTokens bounds the estimated tokens of neighboring chunk text merged into
+	// the answer context around the selected chunks (0 = disabled).
+	ContextNeighborTokens int
+	// ModelLoadTimeoutSeconds is how long startup and model swaps wait for a model to load.
+	ModelLoadTimeoutSeconds int
+	// ModelIdleTTLMinutes unloads models unused for this long from the llama.cpp server,
+	// reloading them on their next request (0 = models stay loaded).
+	ModelIdleTTLMinutes int
 }
 
 // Load reads configuration from environment variables and returns a Config struct.
@@ -64,17 +275,8 @@ func Load() (*Config, error) {
 
 	// Parse log level (case-insensitive)
 	logLevelStr := strings.ToUpper(getEnv("LOG_LEVEL", "INFO"))
-	var logLevel slog.Level
-	switch logLevelStr {
-	case "DEBUG":
-		logLevel = slog.LevelDebug
-	case "INFO":
-		logLevel = slog.LevelInfo
-	case "WARN":
-		logLevel = slog.LevelWarn
-	case "ERROR":
-		logLevel = slog.LevelError
-	default:
+	logLevel, err := logging.ParseLevel(logLevelStr)
+	if err != nil {
 		return nil, fmt.Errorf("invalid LOG_LEVEL: %s (must be DEBUG, INFO, WARN, or ERROR)", logLevelStr)
 	}
 
@@ -84,7 +286,42 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid LOG_FORMAT: %s (must be text or json)", logFormat)
 	}
 
+	// Parse question log mode (privacy mode for question text in logs)
+	logQuestions := strings.ToLower(getEnv("LOG_QUESTIONS", contextutil.QuestionLogFull))
+	switch logQuestions {
+	case contextutil.QuestionLogFull, contextutil.QuestionLogTruncate, contextutil.QuestionLogHash:
+	default:
+		return nil, fmt.Errorf("invalid LOG_QUESTIONS: %s (must be full, truncate, or hash)", logQuestions)
+	}
+
+	// Parse vault symlink handling
+	vaultSymlinks := strings.ToLower(getEnv("VAULT_SYMLINKS", vault.SymlinksFollow))
+	if vaultSymlinks != vault.SymlinksFollow && vaultSymlinks != vault.SymlinksSkip {
+		return nil, fmt.Errorf("invalid VAULT_SYMLINKS: %s (must be follow or skip)", vaultSymlinks)
+	}
+	vaults, err := parseVaults()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse run mode
+	mode := strings.ToLower(getEnv("MODE", ModeProduction))
+	if mode != ModeProduction && mode != ModeTest {
+		return nil, fmt.Errorf("invalid MODE: %s (must be production or test)", mode)
+	}
+
+	// Parse instance role (replicas share the primary's database and Qdrant, read-only)
+	role := strings.ToLower(getEnv("API_ROLE", RolePrimary))
+	if role != RolePrimary && role != RoleReplica {
+		return nil, fmt.Errorf("invalid API_ROLE: %s (must be primary or replica)", role)
+	}
+	if role == RoleReplica && mode == ModeTest {
+		return nil, fmt.Errorf("API_ROLE=replica requires MODE=production (test mode has no shared vector store)")
+	}
+
 	cfg := &Config{
+		Mode:         mode,
+		Role:         role,
 		LLMBaseURL:   llmBaseURL,
 		LLMModelName: llmModelName,
 		LLMAPIKey:    getEnv("LLM_API_KEY", "dummy-key"),
@@ -96,23 +333,37 @@ func Load() (*Config, error) {
 		EmbeddingModelName: getEnv("EMBEDDING_MODEL_NAME", "ggml-org_embeddinggemma-300M-GGUF_embeddinggemma-300M-Q8_0"),
 		// Note: EmbeddingGemma-300M supports a 2048-token context window.
 		// For granite-embedding-278m-multilingual, n_ctx=512 tokens (hard limit enforced by model).
-		DBPath:            getEnv("DB_PATH", "./data/helloworld-ai.db"),
-		VaultPersonalPath: getEnv("VAULT_PERSONAL_PATH", ""),
-		VaultWorkPath:     getEnv("VAULT_WORK_PATH", ""),
-		QdrantURL:         getEnv("QDRANT_URL", "http://127.0.0.1:6333"),
-		QdrantCollection:  getEnv("QDRANT_COLLECTION", "notes"),
-		APIPort:           getEnv("API_PORT", "9000"),
-		LogLevel:          logLevel,
-		LogFormat:         logFormat,
+		DBPath:           getEnv("DB_PATH", "./data/helloworld-ai.db"),
+		Vaults:           vaults,
+		VaultSymlinks:    vaultSymlinks,
+		QdrantURL:        getEnv("QDRANT_URL", "http://127.0.0.1:6333"),
+		QdrantCollection: getEnv("QDRANT_COLLECTION", "notes"),
+		APIPort:          getEnv("API_PORT", "9000"),
+		LogLevel:         logLevel,
+		LogFormat:        logFormat,
+		LogQuestions:     logQuestions,
+		AdminToken:       getEnv("ADMIN_TOKEN", ""),
 	}
 
+	// Parse AUTH_REQUIRED (off by default: a single-user install needs no tokens)
+	authRequired, err := strconv.ParseBool(getEnv("AUTH_REQUIRED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("AUTH_REQUIRED must be true or false")
+	}
+	cfg.AuthRequired = authRequired
+
 	// Parse QDRANT_VECTOR_SIZE
 	// Note: This must match the output vector size of the embeddings model.
 	// For granite-embedding-278m-multilingual, this is typically 1024 dimensions.
 	// Verify the actual output size by testing the model and update QDRANT_VECTOR_SIZE
 	// in your .env file accordingly. If the vector size changes, the Qdrant collection
 	// must be recreated.
-	vectorSizeStr := getEnv("QDRANT_VECTOR_SIZE", "")
+	// Test mode uses the fake embedder, so any size works and a small default is provided.
+	defaultVectorSize := ""
+	if mode == ModeTest {
+		defaultVectorSize = "64"
+	}
+	vectorSizeStr := getEnv("QDRANT_VECTOR_SIZE", defaultVectorSize)
 	if vectorSizeStr == "" {
 		return nil, fmt.Errorf("QDRANT_VECTOR_SIZE is required")
 	}
@@ -125,13 +376,425 @@ func Load() (*Config, error) {
 	}
 	cfg.QdrantVectorSize = vectorSize
 
-	// Validate required fields
-	if cfg.VaultPersonalPath == "" {
-		return nil, fmt.Errorf("VAULT_PERSONAL_PATH is required")
+	// Parse COLD_STORAGE_AFTER_MONTHS (0 disables cold storage)
+	coldAfterMonths, err := strconv.Atoi(getEnv("COLD_STORAGE_AFTER_MONTHS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("COLD_STORAGE_AFTER_MONTHS must be a valid integer: %w", err)
+	}
+	if coldAfterMonths < 0 {
+		return nil, fmt.Errorf("COLD_STORAGE_AFTER_MONTHS must be 0 or greater")
+	}
+	cfg.ColdStorageAfterMonths = coldAfterMonths
+	cfg.QdrantColdCollection = getEnv("QDRANT_COLD_COLLECTION", cfg.QdrantCollection+"_cold")
+
+	// Parse QDRANT_UPSERT_BATCH_SIZE (large notes are upserted in several requests)
+	upsertBatchSize, err := strconv.Atoi(getEnv("QDRANT_UPSERT_BATCH_SIZE", "64"))
+	if err != nil || upsertBatchSize <= 0 {
+		return nil, fmt.Errorf("QDRANT_UPSERT_BATCH_SIZE must be an integer > 0")
+	}
+	cfg.QdrantUpsertBatchSize = upsertBatchSize
+
+	// Parse QDRANT_COLLECTION_PER_VAULT (vaults get isolated chunk collections)
+	collectionPerVault, err := strconv.ParseBool(getEnv("QDRANT_COLLECTION_PER_VAULT", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("QDRANT_COLLECTION_PER_VAULT must be true or false")
+	}
+	cfg.QdrantCollectionPerVault = collectionPerVault
+
+	// Parse QDRANT_DISTANCE (some embedding models expect dot-product scoring)
+	distance, err := vectorstore.ParseDistance(getEnv("QDRANT_DISTANCE", vectorstore.DistanceCosine))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QDRANT_DISTANCE: %w", err)
+	}
+	cfg.QdrantDistance = distance
+
+	// Parse VECTOR_STORE_BACKEND (test mode keeps vectors in memory unless told otherwise)
+	defaultBackend := vectorstore.BackendQdrant
+	if mode == ModeTest {
+		defaultBackend = vectorstore.BackendMemory
+	}
+	backend, err := vectorstore.ParseBackend(getEnv("VECTOR_STORE_BACKEND", defaultBackend))
+	if err != nil {
+		return nil, fmt.Errorf("invalid VECTOR_STORE_BACKEND: %w", err)
+	}
+	cfg.VectorStoreBackend = backend
+	cfg.PgvectorDSN = getEnv("PGVECTOR_DSN", "")
+	if backend == vectorstore.BackendPgvector && cfg.PgvectorDSN == "" {
+		return nil, fmt.Errorf("PGVECTOR_DSN is required when VECTOR_STORE_BACKEND=pgvector")
+	}
+	if backend == vectorstore.BackendMemory && role == RoleReplica {
+		return nil, fmt.Errorf("API_ROLE=replica cannot use VECTOR_STORE_BACKEND=memory (the in-memory index is not shared)")
+	}
+
+	// Parse database replication settings (the standby should live on another disk)
+	sqliteWAL, err := strconv.ParseBool(getEnv("SQLITE_WAL", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("SQLITE_WAL must be true or false")
+	}
+	cfg.SQLiteWAL = sqliteWAL
+	cfg.SQLiteReplicaPath = getEnv("SQLITE_REPLICA_PATH", "")
+	if cfg.SQLiteReplicaPath != "" && filepath.Clean(cfg.SQLiteReplicaPath) == filepath.Clean(cfg.DBPath) {
+		return nil, fmt.Errorf("SQLITE_REPLICA_PATH must differ from DB_PATH")
+	}
+	replicaInterval, err := strconv.Atoi(getEnv("SQLITE_REPLICA_INTERVAL_MINUTES", "15"))
+	if err != nil || replicaInterval <= 0 {
+		return nil, fmt.Errorf("SQLITE_REPLICA_INTERVAL_MINUTES must be an integer > 0")
+	}
+	cfg.SQLiteReplicaIntervalMinutes = replicaInterval
+
+	// Parse startup index priority (recent notes and listed folders first, then the backfill)
+	startupOrder, err := indexer.ParseIndexOrder(getEnv("INDEX_STARTUP_ORDER", indexer.IndexOrderScan))
+	if err != nil {
+		return nil, fmt.Errorf("INDEX_STARTUP_ORDER is invalid: %w", err)
+	}
+	cfg.IndexStartupOrder = startupOrder
+	recentDays, err := strconv.Atoi(getEnv("INDEX_RECENT_DAYS", "0"))
+	if err != nil || recentDays < 0 {
+		return nil, fmt.Errorf("INDEX_RECENT_DAYS must be an integer >= 0")
+	}
+	cfg.IndexRecentDays = recentDays
+	for _, folder := range strings.Split(getEnv("INDEX_PRIORITY_FOLDERS", ""), ",") {
+		folder = strings.Trim(strings.TrimSpace(folder), "/")
+		if folder == "" {
+			continue
+		}
+		if !strings.Contains(folder, "/") {
+			return nil, fmt.Errorf("INDEX_PRIORITY_FOLDERS entries must be vault/folder, got %q", folder)
+		}
+		cfg.IndexPriorityFolders = append(cfg.IndexPriorityFolders, folder)
+	}
+
+	// Parse concurrency overrides; by default they follow the llama.cpp slot counts from /props
+	llmMaxConcurrency, err := strconv.Atoi(getEnv("LLM_MAX_CONCURRENCY", "0"))
+	if err != nil || llmMaxConcurrency < 0 {
+		return nil, fmt.Errorf("LLM_MAX_CONCURRENCY must be an integer >= 0")
+	}
+	cfg.LLMMaxConcurrency = llmMaxConcurrency
+	embeddingParallelism, err := strconv.Atoi(getEnv("EMBEDDING_PARALLELISM", "0"))
+	if err != nil || embeddingParallelism < 0 {
+		return nil, fmt.Errorf("EMBEDDING_PARALLELISM must be an integer >= 0")
+	}
+	cfg.EmbeddingParallelism = embeddingParallelism
+	chunkOverlap, err := strconv.Atoi(getEnv("CHUNK_OVERLAP_RUNES", "0"))
+	if err != nil || chunkOverlap < 0 || chunkOverlap > 350 {
+		return nil, fmt.Errorf("CHUNK_OVERLAP_RUNES must be an integer between 0 and 350")
+	}
+	cfg.ChunkOverlapRunes = chunkOverlap
+
+	// Parse the per-call timeouts of question answering (0 means bounded by the request only)
+	if cfg.EmbedTimeout, err = parseTimeout("EMBED_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+	if cfg.SearchTimeout, err = parseTimeout("SEARCH_TIMEOUT", "10s"); err != nil {
+		return nil, err
+	}
+	if cfg.GenerationTimeout, err = parseTimeout("GENERATION_TIMEOUT", "5m"); err != nil {
+		return nil, err
+	}
+
+	// Parse SHUTDOWN_TIMEOUT_SECONDS (how long SIGINT/SIGTERM waits for requests to drain)
+	shutdownTimeout, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT_SECONDS", "30"))
+	if err != nil || shutdownTimeout <= 0 {
+		return nil, fmt.Errorf("SHUTDOWN_TIMEOUT_SECONDS must be an integer > 0")
+	}
+	cfg.ShutdownTimeoutSeconds = shutdownTimeout
+
+	// Parse model lifecycle settings (load readiness timeout and idle unloading)
+	modelLoadTimeout, err := strconv.Atoi(getEnv("MODEL_LOAD_TIMEOUT_SECONDS", "60"))
+	if err != nil || modelLoadTimeout <= 0 {
+		return nil, fmt.Errorf("MODEL_LOAD_TIMEOUT_SECONDS must be an integer > 0")
+	}
+	cfg.ModelLoadTimeoutSeconds = modelLoadTimeout
+	modelIdleTTL, err := strconv.Atoi(getEnv("MODEL_IDLE_TTL_MINUTES", "0"))
+	if err != nil || modelIdleTTL < 0 {
+		return nil, fmt.Errorf("MODEL_IDLE_TTL_MINUTES must be an integer >= 0")
+	}
+	cfg.ModelIdleTTLMinutes = modelIdleTTL
+
+	// Parse retrieval tuning (score thresholds, score weights, and candidate counts)
+	minVectorScore, err := strconv.ParseFloat(getEnv("MIN_VECTOR_SCORE", "0.3"), 32)
+	if err != nil || minVectorScore <= 0 || minVectorScore > 1 {
+		return nil, fmt.Errorf("MIN_VECTOR_SCORE must be a number > 0 and <= 1")
+	}
+	cfg.MinVectorScore = float32(minVectorScore)
+	minFinalScore, err := strconv.ParseFloat(getEnv("MIN_FINAL_SCORE", "0.4"), 32)
+	if err != nil || minFinalScore <= 0 || minFinalScore > 1 {
+		return nil, fmt.Errorf("MIN_FINAL_SCORE must be a number > 0 and <= 1")
+	}
+	cfg.MinFinalScore = float32(minFinalScore)
+	vectorWeight, err := strconv.ParseFloat(getEnv("RETRIEVAL_VECTOR_WEIGHT", "0.7"), 32)
+	if err != nil || vectorWeight < 0 || vectorWeight > 1 {
+		return nil, fmt.Errorf("RETRIEVAL_VECTOR_WEIGHT must be a number between 0 and 1")
+	}
+	lexicalWeight, err := strconv.ParseFloat(getEnv("RETRIEVAL_LEXICAL_WEIGHT", "0.3"), 32)
+	if err != nil || lexicalWeight < 0 || lexicalWeight > 1 {
+		return nil, fmt.Errorf("RETRIEVAL_LEXICAL_WEIGHT must be a number between 0 and 1")
+	}
+	if vectorWeight == 0 && lexicalWeight == 0 {
+		return nil, fmt.Errorf("RETRIEVAL_VECTOR_WEIGHT and RETRIEVAL_LEXICAL_WEIGHT must not both be 0")
+	}
+	cfg.VectorScoreWeight = float32(vectorWeight)
+	cfg.LexicalScoreWeight = float32(lexicalWeight)
+	candidateK, err := strconv.Atoi(getEnv("RETRIEVAL_CANDIDATE_K", "15"))
+	if err != nil || candidateK <= 0 {
+		return nil, fmt.Errorf("RETRIEVAL_CANDIDATE_K must be an integer > 0")
+	}
+	cfg.CandidateKPerScope = candidateK
+	maxCandidates, err := strconv.Atoi(getEnv("RETRIEVAL_MAX_CANDIDATES", "200"))
+	if err != nil || maxCandidates <= 0 {
+		return nil, fmt.Errorf("RETRIEVAL_MAX_CANDIDATES must be an integer > 0")
+	}
+	cfg.MaxCandidates = maxCandidates
+	tagBoost, err := strconv.ParseFloat(getEnv("RETRIEVAL_TAG_BOOST", "0.05"), 32)
+	if err != nil || tagBoost < 0 || tagBoost > 1 {
+		return nil, fmt.Errorf("RETRIEVAL_TAG_BOOST must be a number between 0 and 1")
+	}
+	cfg.TagBoost = float32(tagBoost)
+
+	// Parse score floors (how far a request's min_score may relax the retrieval thresholds)
+	minVectorScoreFloor, err := strconv.ParseFloat(getEnv("MIN_VECTOR_SCORE_FLOOR", "0.2"), 32)
+	if err != nil || minVectorScoreFloor < 0 || minVectorScoreFloor > 1 {
+		return nil, fmt.Errorf("MIN_VECTOR_SCORE_FLOOR must be a number between 0 and 1")
+	}
+	cfg.MinVectorScoreFloor = float32(minVectorScoreFloor)
+	minFinalScoreFloor, err := strconv.ParseFloat(getEnv("MIN_FINAL_SCORE_FLOOR", "0.25"), 32)
+	if err != nil || minFinalScoreFloor < 0 || minFinalScoreFloor > 1 {
+		return nil, fmt.Errorf("MIN_FINAL_SCORE_FLOOR must be a number between 0 and 1")
+	}
+	cfg.MinFinalScoreFloor = float32(minFinalScoreFloor)
+	// Parse ANSWERABILITY_THRESHOLD (0 skips the LLM answerability check)
+	answerabilityThreshold, err := strconv.ParseFloat(getEnv("ANSWERABILITY_THRESHOLD", "0"), 32)
+	if err != nil || answerabilityThreshold < 0 || answerabilityThreshold > 1 {
+		return nil, fmt.Errorf("ANSWERABILITY_THRESHOLD must be a number between 0 and 1")
+	}
+	cfg.AnswerabilityThreshold = float32(answerabilityThreshold)
+	// Parse the answer judge settings (off by default: one or more extra LLM calls per answer)
+	answerJudge, err := strconv.ParseBool(getEnv("ANSWER_JUDGE", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("ANSWER_JUDGE must be true or false")
+	}
+	cfg.AnswerJudge = answerJudge
+	faithfulnessThreshold, err := strconv.ParseFloat(getEnv("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD", "0"), 32)
+	if err != nil || faithfulnessThreshold < 0 || faithfulnessThreshold > 1 {
+		return nil, fmt.Errorf("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD must be a number between 0 and 1")
+	}
+	cfg.AnswerJudgeFaithfulnessThreshold = float32(faithfulnessThreshold)
+	maxRegenerations, err := strconv.Atoi(getEnv("ANSWER_JUDGE_MAX_REGENERATIONS", "1"))
+	if err != nil || maxRegenerations < 0 || maxRegenerations > 3 {
+		return nil, fmt.Errorf("ANSWER_JUDGE_MAX_REGENERATIONS must be an integer between 0 and 3")
+	}
+	cfg.AnswerJudgeMaxRegenerations = maxRegenerations
+	// A rubric file sets its own regenerate threshold, so the two cannot disagree
+	cfg.AnswerJudgeRubricPath = getEnv("ANSWER_JUDGE_RUBRIC", "")
+	if cfg.AnswerJudgeRubricPath != "" && os.Getenv("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD") != "" {
+		return nil, fmt.Errorf("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD cannot be set with ANSWER_JUDGE_RUBRIC; set thresholds.regenerate in the rubric instead")
+	}
+	// Parse CONTEXT_NEIGHBOR_TOKENS (0 sends the selected chunks without their neighbors)
+	contextNeighborTokens, err := strconv.Atoi(getEnv("CONTEXT_NEIGHBOR_TOKENS", "512"))
+	if err != nil || contextNeighborTokens < 0 {
+		return nil, fmt.Errorf("CONTEXT_NEIGHBOR_TOKENS must be an integer >= 0")
+	}
+	cfg.ContextNeighborTokens = contextNeighborTokens
+
+	// Parse NOTE_PREFILTER_TOP_M (0 disables the note-level prefilter)
+	notePrefilterTopM, err := strconv.Atoi(getEnv("NOTE_PREFILTER_TOP_M", "0"))
+	if err != nil || notePrefilterTopM < 0 {
+		return nil, fmt.Errorf("NOTE_PREFILTER_TOP_M must be an integer >= 0")
+	}
+	cfg.NotePrefilterTopM = notePrefilterTopM
+	cfg.QdrantNoteCollection = getEnv("QDRANT_NOTE_COLLECTION", cfg.QdrantCollection+"_notes")
+
+	// Parse question embedding cache bounds (size 0 disables the cache)
+	questionCacheSize, err := strconv.Atoi(getEnv("QUESTION_EMBEDDING_CACHE_SIZE", "256"))
+	if err != nil || questionCacheSize < 0 {
+		return nil, fmt.Errorf("QUESTION_EMBEDDING_CACHE_SIZE must be an integer >= 0")
+	}
+	cfg.QuestionEmbeddingCacheSize = questionCacheSize
+	questionCacheTTL, err := strconv.Atoi(getEnv("QUESTION_EMBEDDING_CACHE_TTL_SECONDS", "600"))
+	if err != nil || questionCacheTTL <= 0 {
+		return nil, fmt.Errorf("QUESTION_EMBEDDING_CACHE_TTL_SECONDS must be an integer > 0")
+	}
+	cfg.QuestionEmbeddingCacheTTLSeconds = questionCacheTTL
+	// Parse the answer cache TTL (0 disables the cache)
+	answerCacheTTL, err := strconv.Atoi(getEnv("ANSWER_CACHE_TTL_SECONDS", "3600"))
+	if err != nil || answerCacheTTL < 0 {
+		return nil, fmt.Errorf("ANSWER_CACHE_TTL_SECONDS must be an integer >= 0")
+	}
+	cfg.AnswerCacheTTLSeconds = answerCacheTTL
+	// Engine names are validated by rag.NewEngineByName at startup
+	cfg.RAGEngine = strings.ToLower(getEnv("RAG_ENGINE", "llm"))
+	// Parse the external reranker (an empty URL keeps the built-in lexical rerank)
+	cfg.RerankerURL = strings.TrimRight(getEnv("RERANKER_URL", ""), "/")
+	cfg.RerankerAPIKey = getEnv("RERANKER_API_KEY", "")
+	rerankerBatchSize, err := strconv.Atoi(getEnv("RERANKER_BATCH_SIZE", "32"))
+	if err != nil || rerankerBatchSize <= 0 {
+		return nil, fmt.Errorf("RERANKER_BATCH_SIZE must be an integer > 0")
+	}
+	cfg.RerankerBatchSize = rerankerBatchSize
+	// Tracing is on when an OTLP endpoint is set; the exporter reads the OTEL_EXPORTER_OTLP_* variables itself
+	cfg.TracingEnabled = getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "") != "" || getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "") != ""
+	cfg.OTelServiceName = getEnv("OTEL_SERVICE_NAME", "helloworld-ai")
+	// Stage names and order are validated by rag.NewEngineByName at startup
+	for _, stage := range strings.Split(getEnv("RAG_STAGES", ""), ",") {
+		if stage = strings.ToLower(strings.TrimSpace(stage)); stage != "" {
+			cfg.RAGStages = append(cfg.RAGStages, stage)
+		}
+	}
+
+	// Parse weekly digest settings (the vault must be writable by the server)
+	digestVault := strings.ToLower(getEnv("DIGEST_VAULT", ""))
+	if digestVault != "" && !slices.ContainsFunc(vaults, func(v vault.Config) bool { return v.Name == digestVault }) {
+		return nil, fmt.Errorf("invalid DIGEST_VAULT: %s (must be a configured vault)", digestVault)
+	}
+	cfg.DigestVault = digestVault
+	digestFolder := filepath.ToSlash(filepath.Clean(getEnv("DIGEST_FOLDER", "Digests")))
+	if filepath.IsAbs(digestFolder) || digestFolder == "." || digestFolder == ".." || strings.HasPrefix(digestFolder, "../") {
+		return nil, fmt.Errorf("DIGEST_FOLDER must be a folder inside the vault")
+	}
+	cfg.DigestFolder = digestFolder
+
+	// Parse generation controls (zero values keep the llama.cpp server defaults)
+	cfg.LLMStopSequences = parseStopSequences(getEnv("LLM_STOP_SEQUENCES", ""))
+	repeatPenalty, err := strconv.ParseFloat(getEnv("LLM_REPEAT_PENALTY", "0"), 32)
+	if err != nil || repeatPenalty < 0 {
+		return nil, fmt.Errorf("LLM_REPEAT_PENALTY must be a number >= 0")
+	}
+	cfg.LLMRepeatPenalty = float32(repeatPenalty)
+	topP, err := strconv.ParseFloat(getEnv("LLM_TOP_P", "0"), 32)
+	if err != nil || topP < 0 || topP > 1 {
+		return nil, fmt.Errorf("LLM_TOP_P must be a number between 0 and 1")
+	}
+	cfg.LLMTopP = float32(topP)
+	topK, err := strconv.Atoi(getEnv("LLM_TOP_K", "0"))
+	if err != nil || topK < 0 {
+		return nil, fmt.Errorf("LLM_TOP_K must be an integer >= 0")
+	}
+	cfg.LLMTopK = topK
+
+	// Parse per-request generation override limits (the default model is always allowed)
+	for _, model := range strings.Split(getEnv("LLM_ALLOWED_MODELS", ""), ",") {
+		if model = strings.TrimSpace(model); model != "" && !slices.Contains(cfg.LLMAllowedModels, model) {
+			cfg.LLMAllowedModels = append(cfg.LLMAllowedModels, model)
+		}
+	}
+	askMaxTokens, err := strconv.Atoi(getEnv("ASK_MAX_TOKENS", "2048"))
+	if err != nil || askMaxTokens < 0 {
+		return nil, fmt.Errorf("ASK_MAX_TOKENS must be an integer >= 0")
+	}
+	cfg.AskMaxTokens = askMaxTokens
+	askAllowSystemPrompt, err := strconv.ParseBool(getEnv("ASK_ALLOW_SYSTEM_PROMPT", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("ASK_ALLOW_SYSTEM_PROMPT must be true or false")
+	}
+	cfg.AskAllowSystemPrompt = askAllowSystemPrompt
+	structuredCitations, err := strconv.ParseBool(getEnv("LLM_STRUCTURED_CITATIONS", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("LLM_STRUCTURED_CITATIONS must be true or false")
+	}
+	cfg.LLMStructuredCitations = structuredCitations
+
+	// Parse answer safety filter settings (the rules file is loaded at startup)
+	cfg.SafetyFilterRules = getEnv("SAFETY_FILTER_RULES", "")
+	cfg.PIIScanPatterns = getEnv("PII_SCAN_PATTERNS", "")
+	safetyAction := strings.ToLower(getEnv("SAFETY_FILTER_ACTION", "redact"))
+	if safetyAction != "redact" && safetyAction != "block" {
+		return nil, fmt.Errorf("invalid SAFETY_FILTER_ACTION: %s (must be redact or block)", safetyAction)
+	}
+	cfg.SafetyFilterAction = safetyAction
+	safetyClassify, err := strconv.ParseBool(getEnv("SAFETY_FILTER_CLASSIFY", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("SAFETY_FILTER_CLASSIFY must be true or false")
+	}
+	cfg.SafetyFilterClassify = safetyClassify
+
+	// Parse folder selection bounds (0 means unlimited)
+	folderMaxDepth, err := strconv.Atoi(getEnv("FOLDER_SELECTION_MAX_DEPTH", "2"))
+	if err != nil || folderMaxDepth < 0 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_MAX_DEPTH must be an integer >= 0")
+	}
+	cfg.FolderSelectionMaxDepth = folderMaxDepth
+	folderMaxFolders, err := strconv.Atoi(getEnv("FOLDER_SELECTION_MAX_FOLDERS", "200"))
+	if err != nil || folderMaxFolders < 0 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_MAX_FOLDERS must be an integer >= 0")
+	}
+	cfg.FolderSelectionMaxFolders = folderMaxFolders
+	folderTimeout, err := strconv.Atoi(getEnv("FOLDER_SELECTION_TIMEOUT_SECONDS", "10"))
+	if err != nil || folderTimeout < 0 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_TIMEOUT_SECONDS must be an integer >= 0")
+	}
+	cfg.FolderSelectionTimeoutSeconds = folderTimeout
+	folderMode := getEnv("FOLDER_SELECTION_MODE", "llm")
+	if folderMode != "llm" && folderMode != "heuristic" {
+		return nil, fmt.Errorf("invalid FOLDER_SELECTION_MODE: %s (must be llm or heuristic)", folderMode)
+	}
+	cfg.FolderSelectionMode = folderMode
+	folderMargin, err := strconv.ParseFloat(getEnv("FOLDER_SELECTION_HEURISTIC_MARGIN", "0.05"), 64)
+	if err != nil || folderMargin < 0 || folderMargin > 1 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_HEURISTIC_MARGIN must be a number between 0 and 1")
+	}
+	cfg.FolderSelectionHeuristicMargin = folderMargin
+	folderCacheSize, err := strconv.Atoi(getEnv("FOLDER_SELECTION_CACHE_SIZE", "256"))
+	if err != nil || folderCacheSize < 0 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_CACHE_SIZE must be an integer >= 0")
+	}
+	cfg.FolderSelectionCacheSize = folderCacheSize
+	folderCacheTTL, err := strconv.Atoi(getEnv("FOLDER_SELECTION_CACHE_TTL_SECONDS", "600"))
+	if err != nil || folderCacheTTL < 0 {
+		return nil, fmt.Errorf("FOLDER_SELECTION_CACHE_TTL_SECONDS must be an integer >= 0")
+	}
+	cfg.FolderSelectionCacheTTLSeconds = folderCacheTTL
+
+	// Parse storage soft limits (0 means no limit)
+	sqliteLimitMB, err := strconv.Atoi(getEnv("STORAGE_SQLITE_SOFT_LIMIT_MB", "0"))
+	if err != nil || sqliteLimitMB < 0 {
+		return nil, fmt.Errorf("STORAGE_SQLITE_SOFT_LIMIT_MB must be an integer >= 0")
+	}
+	cfg.StorageSQLiteSoftLimitMB = sqliteLimitMB
+	qdrantLimitMB, err := strconv.Atoi(getEnv("STORAGE_QDRANT_SOFT_LIMIT_MB", "0"))
+	if err != nil || qdrantLimitMB < 0 {
+		return nil, fmt.Errorf("STORAGE_QDRANT_SOFT_LIMIT_MB must be an integer >= 0")
 	}
-	if cfg.VaultWorkPath == "" {
-		return nil, fmt.Errorf("VAULT_WORK_PATH is required")
+	cfg.StorageQdrantSoftLimitMB = qdrantLimitMB
+	checkInterval, err := strconv.Atoi(getEnv("STORAGE_CHECK_INTERVAL_MINUTES", "15"))
+	if err != nil || checkInterval <= 0 {
+		return nil, fmt.Errorf("STORAGE_CHECK_INTERVAL_MINUTES must be an integer > 0")
 	}
+	cfg.StorageCheckIntervalMinutes = checkInterval
+	cfg.StorageAlertWebhookURL = getEnv("STORAGE_ALERT_WEBHOOK_URL", "")
+
+	// Parse request payload limits
+	maxRequestBodyKB, err := strconv.Atoi(getEnv("MAX_REQUEST_BODY_KB", "64"))
+	if err != nil || maxRequestBodyKB <= 0 {
+		return nil, fmt.Errorf("MAX_REQUEST_BODY_KB must be an integer > 0")
+	}
+	cfg.MaxRequestBodyKB = maxRequestBodyKB
+	maxQuestionLength, err := strconv.Atoi(getEnv("MAX_QUESTION_LENGTH", "4000"))
+	if err != nil || maxQuestionLength <= 0 {
+		return nil, fmt.Errorf("MAX_QUESTION_LENGTH must be an integer > 0")
+	}
+	cfg.MaxQuestionLength = maxQuestionLength
+	maxDocumentKB, err := strconv.Atoi(getEnv("MAX_DOCUMENT_KB", "256"))
+	if err != nil || maxDocumentKB <= 0 {
+		return nil, fmt.Errorf("MAX_DOCUMENT_KB must be an integer > 0")
+	}
+	cfg.MaxDocumentKB = maxDocumentKB
+	maxRequestVaults, err := strconv.Atoi(getEnv("MAX_REQUEST_VAULTS", "20"))
+	if err != nil || maxRequestVaults <= 0 {
+		return nil, fmt.Errorf("MAX_REQUEST_VAULTS must be an integer > 0")
+	}
+	cfg.MaxRequestVaults = maxRequestVaults
+	maxRequestFolders, err := strconv.Atoi(getEnv("MAX_REQUEST_FOLDERS", "100"))
+	if err != nil || maxRequestFolders <= 0 {
+		return nil, fmt.Errorf("MAX_REQUEST_FOLDERS must be an integer > 0")
+	}
+	cfg.MaxRequestFolders = maxRequestFolders
+
+	// Parse FEATURE_FLAGS ("name=true,name=false"); unlisted flags keep their built-in defaults
+	featureFlags, err := features.Parse(getEnv("FEATURE_FLAGS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("FEATURE_FLAGS is invalid: %w", err)
+	}
+	cfg.FeatureFlags = featureFlags
 
 	// Create ./data directory if it doesn't exist (for future DB file)
 	dataDir := filepath.Dir(cfg.DBPath)
@@ -142,6 +805,76 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// vaultNamePattern is the form of vault names: lowercase letters, digits, '_' and '-'.
+var vaultNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// parseVaults reads the vaults to index from VAULTS_JSON, a JSON array of
+// {"name": ..., "path": ...} objects, or else from VAULT_<NAME>_PATH variables ordered by
+// name (VAULT_PERSONAL_PATH configures the vault "personal"). At least one is required.
+func parseVaults() ([]vault.Config, error) {
+	var vaults []vault.Config
+	if raw := getEnv("VAULTS_JSON", ""); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &vaults); err != nil {
+			return nil, fmt.Errorf("VAULTS_JSON is invalid: %w", err)
+		}
+		for i := range vaults {
+			vaults[i].Name = strings.ToLower(strings.TrimSpace(vaults[i].Name))
+		}
+	} else {
+		for _, entry := range os.Environ() {
+			key, value, _ := strings.Cut(entry, "=")
+			if !strings.HasPrefix(key, "VAULT_") || !strings.HasSuffix(key, "_PATH") || len(key) <= len("VAULT__PATH") || value == "" {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(key, "VAULT_"), "_PATH")
+			vaults = append(vaults, vault.Config{Name: strings.ToLower(name), Path: value})
+		}
+		sort.Slice(vaults, func(i, j int) bool {
+			return vaults[i].Name < vaults[j].Name
+		})
+	}
+	if len(vaults) == 0 {
+		return nil, fmt.Errorf("no vaults configured: set VAULTS_JSON or VAULT_<NAME>_PATH (e.g. VAULT_PERSONAL_PATH)")
+	}
+
+	seen := make(map[string]bool, len(vaults))
+	for _, v := range vaults {
+		if !vaultNamePattern.MatchString(v.Name) {
+			return nil, fmt.Errorf("invalid vault name: %q (use letters, digits, '_' and '-')", v.Name)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("duplicate vault name: %s", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Path == "" {
+			return nil, fmt.Errorf("vault %s has no path", v.Name)
+		}
+	}
+	return vaults, nil
+}
+
+// parseStopSequences splits a comma-separated list of stop sequences. The escapes \n and
+// \t stand for a newline and a tab, since stop sequences often start with a line break.
+func parseStopSequences(value string) []string {
+	unescape := strings.NewReplacer(`\n`, "\n", `\t`, "\t")
+	var sequences []string
+	for _, part := range strings.Split(value, ",") {
+		if part = unescape.Replace(strings.TrimSpace(part)); part != "" {
+			sequences = append(sequences, part)
+		}
+	}
+	return sequences
+}
+
+// parseTimeout reads a duration such as "30s" or "2m" from key, or 0 to disable the timeout.
+func parseTimeout(key, defaultValue string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(getEnv(key, defaultValue))
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("%s must be a duration >= 0 (e.g. 30s or 2m)", key)
+	}
+	return timeout, nil
+}
+
 // getEnv gets an environment variable or returns a default value.
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -4,7 +4,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"helloworld-ai/internal/vault"
 )
 
 // setEnv sets an environment variable, ignoring errors (for test setup)
@@ -26,6 +30,41 @@ func TestLoad(t *testing.T) {
 		"EMBEDDING_BASE_URL", "EMBEDDING_MODEL_NAME",
 		"DB_PATH", "QDRANT_URL", "QDRANT_COLLECTION", "API_PORT",
 		"LOG_LEVEL", "LOG_FORMAT",
+		"COLD_STORAGE_AFTER_MONTHS", "QDRANT_COLD_COLLECTION",
+		"MODE", "API_ROLE", "LOG_QUESTIONS",
+		"FOLDER_SELECTION_MAX_DEPTH", "FOLDER_SELECTION_MAX_FOLDERS", "FOLDER_SELECTION_TIMEOUT_SECONDS",
+		"FOLDER_SELECTION_MODE", "FOLDER_SELECTION_HEURISTIC_MARGIN",
+		"FOLDER_SELECTION_CACHE_SIZE", "FOLDER_SELECTION_CACHE_TTL_SECONDS",
+		"STORAGE_SQLITE_SOFT_LIMIT_MB", "STORAGE_QDRANT_SOFT_LIMIT_MB",
+		"STORAGE_ALERT_WEBHOOK_URL", "STORAGE_CHECK_INTERVAL_MINUTES",
+		"NOTE_PREFILTER_TOP_M", "QDRANT_NOTE_COLLECTION",
+		"FEATURE_FLAGS",
+		"VAULT_SYMLINKS",
+		"MAX_REQUEST_BODY_KB",
+		"MAX_QUESTION_LENGTH",
+		"MAX_DOCUMENT_KB",
+		"MAX_REQUEST_VAULTS", "MAX_REQUEST_FOLDERS",
+		"ADMIN_TOKEN", "AUTH_REQUIRED",
+		"QUESTION_EMBEDDING_CACHE_SIZE", "QUESTION_EMBEDDING_CACHE_TTL_SECONDS",
+		"ANSWER_CACHE_TTL_SECONDS",
+		"RAG_ENGINE", "RAG_STAGES",
+		"RERANKER_URL", "RERANKER_API_KEY", "RERANKER_BATCH_SIZE",
+		"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_SERVICE_NAME",
+		"DIGEST_VAULT", "DIGEST_FOLDER",
+		"LLM_STOP_SEQUENCES", "LLM_REPEAT_PENALTY", "LLM_TOP_P", "LLM_TOP_K",
+		"LLM_ALLOWED_MODELS", "ASK_MAX_TOKENS", "ASK_ALLOW_SYSTEM_PROMPT", "LLM_STRUCTURED_CITATIONS",
+		"QDRANT_UPSERT_BATCH_SIZE", "QDRANT_COLLECTION_PER_VAULT",
+		"SAFETY_FILTER_RULES", "SAFETY_FILTER_ACTION", "SAFETY_FILTER_CLASSIFY",
+		"QDRANT_DISTANCE", "VECTOR_STORE_BACKEND", "PGVECTOR_DSN", "SQLITE_WAL", "SQLITE_REPLICA_PATH", "SQLITE_REPLICA_INTERVAL_MINUTES",
+		"INDEX_STARTUP_ORDER", "INDEX_RECENT_DAYS", "INDEX_PRIORITY_FOLDERS",
+		"LLM_MAX_CONCURRENCY", "EMBEDDING_PARALLELISM", "CHUNK_OVERLAP_RUNES",
+		"SHUTDOWN_TIMEOUT_SECONDS", "MODEL_LOAD_TIMEOUT_SECONDS", "MODEL_IDLE_TTL_MINUTES",
+		"EMBED_TIMEOUT", "SEARCH_TIMEOUT", "GENERATION_TIMEOUT",
+		"ANSWER_JUDGE", "ANSWER_JUDGE_FAITHFULNESS_THRESHOLD", "ANSWER_JUDGE_MAX_REGENERATIONS", "ANSWER_JUDGE_RUBRIC",
+		"MIN_VECTOR_SCORE", "MIN_FINAL_SCORE", "RETRIEVAL_VECTOR_WEIGHT", "RETRIEVAL_LEXICAL_WEIGHT",
+		"RETRIEVAL_CANDIDATE_K", "RETRIEVAL_MAX_CANDIDATES", "RETRIEVAL_TAG_BOOST",
+		"MIN_VECTOR_SCORE_FLOOR", "MIN_FINAL_SCORE_FLOOR", "ANSWERABILITY_THRESHOLD", "CONTEXT_NEIGHBOR_TOKENS",
+		"VAULTS_JSON", "VAULT_NOTES_PATH", "VAULT_TEAM_WIKI_PATH",
 	}
 	for _, key := range envVars {
 		originalEnv[key] = os.Getenv(key)
@@ -58,24 +97,89 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: false,
 			checkConfig: func(cfg *Config) bool {
-				return cfg.VaultPersonalPath != "" &&
-					cfg.VaultWorkPath != "" &&
+				return len(cfg.Vaults) == 2 &&
+					cfg.Vaults[0].Name == "personal" && cfg.Vaults[0].Path != "" &&
+					cfg.Vaults[1].Name == "work" && cfg.Vaults[1].Path != "" &&
 					cfg.QdrantVectorSize == 768
 			},
 		},
 		{
-			name: "missing VAULT_PERSONAL_PATH",
+			name: "no vaults",
 			setupEnv: func(t *testing.T) {
-				setEnv("VAULT_WORK_PATH", t.TempDir())
 				setEnv("QDRANT_VECTOR_SIZE", "768")
 			},
 			wantErr: true,
 		},
 		{
-			name: "missing VAULT_WORK_PATH",
+			name: "vaults from VAULT_<NAME>_PATH",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_WORK_PATH", "/vaults/work")
+				setEnv("VAULT_TEAM_WIKI_PATH", "/vaults/wiki")
+				setEnv("VAULT_NOTES_PATH", "/vaults/notes")
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return len(cfg.Vaults) == 3 &&
+					cfg.Vaults[0] == vault.Config{Name: "notes", Path: "/vaults/notes"} &&
+					cfg.Vaults[1] == vault.Config{Name: "team_wiki", Path: "/vaults/wiki"} &&
+					cfg.Vaults[2] == vault.Config{Name: "work", Path: "/vaults/work"}
+			},
+		},
+		{
+			name: "vaults from VAULTS_JSON",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULTS_JSON", `[{"name": "Work", "path": "/vaults/work"}, {"name": "archive", "path": "/vaults/archive"}]`)
+				setEnv("VAULT_PERSONAL_PATH", "/vaults/personal")
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("DIGEST_VAULT", "archive")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return len(cfg.Vaults) == 2 &&
+					cfg.Vaults[0] == vault.Config{Name: "work", Path: "/vaults/work"} &&
+					cfg.Vaults[1] == vault.Config{Name: "archive", Path: "/vaults/archive"} &&
+					cfg.DigestVault == "archive"
+			},
+		},
+		{
+			name: "invalid VAULTS_JSON",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULTS_JSON", `{"work": "/vaults/work"}`)
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate vault names",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULTS_JSON", `[{"name": "work", "path": "/a"}, {"name": "WORK", "path": "/b"}]`)
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid vault name",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULTS_JSON", `[{"name": "my notes", "path": "/a"}]`)
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: true,
+		},
+		{
+			name: "vault without path",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULTS_JSON", `[{"name": "work"}]`)
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest vault not configured",
 			setupEnv: func(t *testing.T) {
 				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
 				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("DIGEST_VAULT", "work")
 			},
 			wantErr: true,
 		},
@@ -131,9 +235,11 @@ func TestLoad(t *testing.T) {
 					cfg.DBPath == "./data/helloworld-ai.db" &&
 					cfg.QdrantURL == "http://127.0.0.1:6333" &&
 					cfg.QdrantCollection == "notes" &&
+					cfg.QdrantDistance == "cosine" &&
 					cfg.APIPort == "9000" &&
 					cfg.LogLevel == slog.LevelInfo &&
-					cfg.LogFormat == "text"
+					cfg.LogFormat == "text" &&
+					cfg.LLMStructuredCitations
 			},
 		},
 		{
@@ -232,6 +338,1117 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "test mode defaults vector size",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("MODE", "test")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.Mode == ModeTest && cfg.QdrantVectorSize == 64 && cfg.VectorStoreBackend == "memory"
+			},
+		},
+		{
+			name: "invalid MODE",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MODE", "staging")
+			},
+			wantErr: true,
+		},
+		{
+			name: "replica role",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("API_ROLE", "Replica")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.Role == RoleReplica
+			},
+		},
+		{
+			name: "invalid API_ROLE",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("API_ROLE", "secondary")
+			},
+			wantErr: true,
+		},
+		{
+			name: "replica role in test mode",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("MODE", "test")
+				setEnv("API_ROLE", "replica")
+			},
+			wantErr: true,
+		},
+		{
+			name: "LOG_QUESTIONS hash mode",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LOG_QUESTIONS", "HASH")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.LogQuestions == "hash"
+			},
+		},
+		{
+			name: "invalid LOG_QUESTIONS",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LOG_QUESTIONS", "redact")
+			},
+			wantErr: true,
+		},
+		{
+			name: "folder selection bounds",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FOLDER_SELECTION_MAX_DEPTH", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.FolderSelectionMaxDepth == 0 && cfg.FolderSelectionMaxFolders == 200 &&
+					cfg.FolderSelectionTimeoutSeconds == 10 && cfg.FolderSelectionMode == "llm" &&
+					cfg.FolderSelectionHeuristicMargin == 0.05 && cfg.FolderSelectionCacheSize == 256 &&
+					cfg.FolderSelectionCacheTTLSeconds == 600
+			},
+		},
+		{
+			name: "heuristic folder selection",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FOLDER_SELECTION_MODE", "heuristic")
+				setEnv("FOLDER_SELECTION_HEURISTIC_MARGIN", "0.1")
+				setEnv("FOLDER_SELECTION_CACHE_SIZE", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.FolderSelectionMode == "heuristic" && cfg.FolderSelectionHeuristicMargin == 0.1 &&
+					cfg.FolderSelectionCacheSize == 0
+			},
+		},
+		{
+			name: "invalid FOLDER_SELECTION_MODE",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FOLDER_SELECTION_MODE", "centroid")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid FOLDER_SELECTION_TIMEOUT_SECONDS",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FOLDER_SELECTION_TIMEOUT_SECONDS", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid FOLDER_SELECTION_MAX_FOLDERS",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FOLDER_SELECTION_MAX_FOLDERS", "lots")
+			},
+			wantErr: true,
+		},
+		{
+			name: "note prefilter",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("NOTE_PREFILTER_TOP_M", "40")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.NotePrefilterTopM == 40 &&
+					cfg.QdrantNoteCollection == "notes_notes"
+			},
+		},
+		{
+			name: "negative NOTE_PREFILTER_TOP_M",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("NOTE_PREFILTER_TOP_M", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "feature flags",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FEATURE_FLAGS", "reranker=false, answer_tools=true")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return len(cfg.FeatureFlags) == 2 &&
+					!cfg.FeatureFlags["reranker"] &&
+					cfg.FeatureFlags["answer_tools"]
+			},
+		},
+		{
+			name: "unknown feature flag",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("FEATURE_FLAGS", "warp_drive=true")
+			},
+			wantErr: true,
+		},
+		{
+			name: "vault symlinks skip",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("VAULT_SYMLINKS", "SKIP")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.VaultSymlinks == "skip"
+			},
+		},
+		{
+			name: "invalid vault symlinks",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("VAULT_SYMLINKS", "sometimes")
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin token",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ADMIN_TOKEN", "s3cret")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AdminToken == "s3cret" && !cfg.AuthRequired
+			},
+		},
+		{
+			name: "auth required",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("AUTH_REQUIRED", "true")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AuthRequired
+			},
+		},
+		{
+			name: "invalid auth required",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("AUTH_REQUIRED", "sometimes")
+			},
+			wantErr: true,
+		},
+		{
+			name: "question embedding cache",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QUESTION_EMBEDDING_CACHE_SIZE", "0")
+				setEnv("QUESTION_EMBEDDING_CACHE_TTL_SECONDS", "30")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.QuestionEmbeddingCacheSize == 0 && cfg.QuestionEmbeddingCacheTTLSeconds == 30
+			},
+		},
+		{
+			name: "invalid question embedding cache TTL",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QUESTION_EMBEDDING_CACHE_TTL_SECONDS", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "answer cache disabled",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_CACHE_TTL_SECONDS", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AnswerCacheTTLSeconds == 0
+			},
+		},
+		{
+			name: "invalid answer cache TTL",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_CACHE_TTL_SECONDS", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "RAG engine",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RAG_ENGINE", "LLM")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.RAGEngine == "llm"
+			},
+		},
+		{
+			name: "RAG stages",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RAG_STAGES", "Scope, retrieve,,select,generate")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return strings.Join(cfg.RAGStages, ",") == "scope,retrieve,select,generate"
+			},
+		},
+		{
+			name: "external reranker",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RERANKER_URL", "http://127.0.0.1:8083/")
+				setEnv("RERANKER_BATCH_SIZE", "16")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.RerankerURL == "http://127.0.0.1:8083" && cfg.RerankerBatchSize == 16 && cfg.RerankerAPIKey == ""
+			},
+		},
+		{
+			name: "tracing enabled by OTLP endpoint",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://127.0.0.1:4318/v1/traces")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.TracingEnabled && cfg.OTelServiceName == "helloworld-ai"
+			},
+		},
+		{
+			name: "invalid reranker batch size",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RERANKER_BATCH_SIZE", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "weekly digest",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("DIGEST_VAULT", "Personal")
+				setEnv("DIGEST_FOLDER", "Journal/Digests/")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.DigestVault == "personal" && cfg.DigestFolder == "Journal/Digests"
+			},
+		},
+		{
+			name: "digest folder outside vault",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("DIGEST_VAULT", "personal")
+				setEnv("DIGEST_FOLDER", "../elsewhere")
+			},
+			wantErr: true,
+		},
+		{
+			name: "generation controls",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_STOP_SEQUENCES", `<|im_end|>, \n\nQuestion:,`)
+				setEnv("LLM_REPEAT_PENALTY", "1.1")
+				setEnv("LLM_TOP_P", "0.9")
+				setEnv("LLM_TOP_K", "40")
+				setEnv("LLM_STRUCTURED_CITATIONS", "false")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return len(cfg.LLMStopSequences) == 2 &&
+					cfg.LLMStopSequences[0] == "<|im_end|>" &&
+					cfg.LLMStopSequences[1] == "\n\nQuestion:" &&
+					cfg.LLMRepeatPenalty == float32(1.1) &&
+					cfg.LLMTopP == float32(0.9) &&
+					cfg.LLMTopK == 40 &&
+					!cfg.LLMStructuredCitations
+			},
+		},
+		{
+			name: "generation override limits",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_ALLOWED_MODELS", "Llama-3.1-8B-Instruct-Q4_K_M, Phi-3-mini-Q4_K_M,,Phi-3-mini-Q4_K_M")
+				setEnv("ASK_MAX_TOKENS", "512")
+				setEnv("ASK_ALLOW_SYSTEM_PROMPT", "true")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return len(cfg.LLMAllowedModels) == 2 &&
+					cfg.LLMAllowedModels[0] == "Llama-3.1-8B-Instruct-Q4_K_M" &&
+					cfg.LLMAllowedModels[1] == "Phi-3-mini-Q4_K_M" &&
+					cfg.AskMaxTokens == 512 &&
+					cfg.AskAllowSystemPrompt
+			},
+		},
+		{
+			name: "invalid ask max tokens",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ASK_MAX_TOKENS", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid structured citations",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_STRUCTURED_CITATIONS", "json")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid top p",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_TOP_P", "1.5")
+			},
+			wantErr: true,
+		},
+		{
+			name: "retrieval tuning defaults",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.MinVectorScore == float32(0.3) && cfg.MinFinalScore == float32(0.4) &&
+					cfg.VectorScoreWeight == float32(0.7) && cfg.LexicalScoreWeight == float32(0.3) &&
+					cfg.CandidateKPerScope == 15 && cfg.MaxCandidates == 200 && cfg.TagBoost == float32(0.05)
+			},
+		},
+		{
+			name: "retrieval tuning",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MIN_VECTOR_SCORE", "0.25")
+				setEnv("MIN_FINAL_SCORE", "0.5")
+				setEnv("RETRIEVAL_VECTOR_WEIGHT", "1")
+				setEnv("RETRIEVAL_LEXICAL_WEIGHT", "0")
+				setEnv("RETRIEVAL_CANDIDATE_K", "30")
+				setEnv("RETRIEVAL_MAX_CANDIDATES", "400")
+				setEnv("RETRIEVAL_TAG_BOOST", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.MinVectorScore == float32(0.25) && cfg.MinFinalScore == float32(0.5) &&
+					cfg.VectorScoreWeight == 1 && cfg.LexicalScoreWeight == 0 &&
+					cfg.CandidateKPerScope == 30 && cfg.MaxCandidates == 400 && cfg.TagBoost == 0
+			},
+		},
+		{
+			name: "zero retrieval weights",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RETRIEVAL_VECTOR_WEIGHT", "0")
+				setEnv("RETRIEVAL_LEXICAL_WEIGHT", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid candidate K",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RETRIEVAL_CANDIDATE_K", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tag boost",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("RETRIEVAL_TAG_BOOST", "1.5")
+			},
+			wantErr: true,
+		},
+		{
+			name: "score floors",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MIN_VECTOR_SCORE_FLOOR", "0.1")
+				setEnv("MIN_FINAL_SCORE_FLOOR", "0.15")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.MinVectorScoreFloor == float32(0.1) && cfg.MinFinalScoreFloor == float32(0.15)
+			},
+		},
+		{
+			name: "invalid score floor",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MIN_FINAL_SCORE_FLOOR", "1.5")
+			},
+			wantErr: true,
+		},
+		{
+			name: "answerability threshold",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWERABILITY_THRESHOLD", "0.5")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AnswerabilityThreshold == float32(0.5)
+			},
+		},
+		{
+			name: "invalid answerability threshold",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWERABILITY_THRESHOLD", "-0.1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "context neighbor tokens default",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ContextNeighborTokens == 512
+			},
+		},
+		{
+			name: "context neighbor tokens disabled",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("CONTEXT_NEIGHBOR_TOKENS", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ContextNeighborTokens == 0
+			},
+		},
+		{
+			name: "invalid context neighbor tokens",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("CONTEXT_NEIGHBOR_TOKENS", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "qdrant upsert batch size",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_UPSERT_BATCH_SIZE", "16")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.QdrantUpsertBatchSize == 16 && !cfg.QdrantCollectionPerVault
+			},
+		},
+		{
+			name: "qdrant collection per vault",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_COLLECTION_PER_VAULT", "true")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.QdrantCollectionPerVault
+			},
+		},
+		{
+			name: "invalid qdrant collection per vault",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_COLLECTION_PER_VAULT", "sometimes")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid qdrant upsert batch size",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_UPSERT_BATCH_SIZE", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "safety filter",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SAFETY_FILTER_RULES", "/etc/helloworld-ai/safety.json")
+				setEnv("SAFETY_FILTER_ACTION", "Block")
+				setEnv("SAFETY_FILTER_CLASSIFY", "true")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.SafetyFilterRules == "/etc/helloworld-ai/safety.json" &&
+					cfg.SafetyFilterAction == "block" &&
+					cfg.SafetyFilterClassify
+			},
+		},
+		{
+			name: "invalid safety filter action",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SAFETY_FILTER_ACTION", "censor")
+			},
+			wantErr: true,
+		},
+		{
+			name: "qdrant distance",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_DISTANCE", "Dot")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.QdrantDistance == "dot"
+			},
+		},
+		{
+			name: "invalid qdrant distance",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("QDRANT_DISTANCE", "manhattan")
+			},
+			wantErr: true,
+		},
+		{
+			name: "default vector store backend",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.VectorStoreBackend == "qdrant"
+			},
+		},
+		{
+			name: "pgvector backend",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("VECTOR_STORE_BACKEND", "PGVector")
+				setEnv("PGVECTOR_DSN", "postgres://notes@localhost/notes")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.VectorStoreBackend == "pgvector" && cfg.PgvectorDSN == "postgres://notes@localhost/notes"
+			},
+		},
+		{
+			name: "pgvector backend without DSN",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("VECTOR_STORE_BACKEND", "pgvector")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid vector store backend",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("VECTOR_STORE_BACKEND", "milvus")
+			},
+			wantErr: true,
+		},
+		{
+			name: "replica with memory backend",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("API_ROLE", "replica")
+				setEnv("VECTOR_STORE_BACKEND", "memory")
+			},
+			wantErr: true,
+		},
+		{
+			name: "sqlite replication",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SQLITE_WAL", "true")
+				setEnv("SQLITE_REPLICA_PATH", "/mnt/backup/helloworld-ai.db")
+				setEnv("SQLITE_REPLICA_INTERVAL_MINUTES", "5")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.SQLiteWAL &&
+					cfg.SQLiteReplicaPath == "/mnt/backup/helloworld-ai.db" &&
+					cfg.SQLiteReplicaIntervalMinutes == 5
+			},
+		},
+		{
+			name: "sqlite replica on the live database",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SQLITE_REPLICA_PATH", "./data/../data/helloworld-ai.db")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid sqlite replica interval",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SQLITE_REPLICA_INTERVAL_MINUTES", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "startup index priority",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("INDEX_STARTUP_ORDER", "Recent")
+				setEnv("INDEX_RECENT_DAYS", "14")
+				setEnv("INDEX_PRIORITY_FOLDERS", "work/projects/, personal/journal")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.IndexStartupOrder == "recent" &&
+					cfg.IndexRecentDays == 14 &&
+					len(cfg.IndexPriorityFolders) == 2 &&
+					cfg.IndexPriorityFolders[0] == "work/projects" &&
+					cfg.IndexPriorityFolders[1] == "personal/journal"
+			},
+		},
+		{
+			name: "invalid startup index order",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("INDEX_STARTUP_ORDER", "alphabetical")
+			},
+			wantErr: true,
+		},
+		{
+			name: "priority folder without vault",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("INDEX_PRIORITY_FOLDERS", "projects")
+			},
+			wantErr: true,
+		},
+		{
+			name: "concurrency overrides",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_MAX_CONCURRENCY", "2")
+				setEnv("EMBEDDING_PARALLELISM", "4")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.LLMMaxConcurrency == 2 && cfg.EmbeddingParallelism == 4
+			},
+		},
+		{
+			name: "chunk overlap",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("CHUNK_OVERLAP_RUNES", "120")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ChunkOverlapRunes == 120
+			},
+		},
+		{
+			name: "chunk overlap too large",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("CHUNK_OVERLAP_RUNES", "500")
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative llm concurrency",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("LLM_MAX_CONCURRENCY", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "shutdown timeout",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SHUTDOWN_TIMEOUT_SECONDS", "5")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ShutdownTimeoutSeconds == 5
+			},
+		},
+		{
+			name: "zero shutdown timeout",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SHUTDOWN_TIMEOUT_SECONDS", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "request timeouts",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("SEARCH_TIMEOUT", "1500ms")
+				setEnv("GENERATION_TIMEOUT", "0")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.EmbedTimeout == 30*time.Second && cfg.SearchTimeout == 1500*time.Millisecond && cfg.GenerationTimeout == 0
+			},
+		},
+		{
+			name: "request timeout without unit",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("EMBED_TIMEOUT", "30")
+			},
+			wantErr: true,
+		},
+		{
+			name: "answer judge",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_JUDGE", "true")
+				setEnv("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD", "0.7")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AnswerJudge && cfg.AnswerJudgeFaithfulnessThreshold == 0.7 && cfg.AnswerJudgeMaxRegenerations == 1
+			},
+		},
+		{
+			name: "answer judge rubric",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_JUDGE_RUBRIC", "/etc/hwai/rubric.json")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.AnswerJudgeRubricPath == "/etc/hwai/rubric.json"
+			},
+		},
+		{
+			name: "answer judge rubric with a faithfulness threshold",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_JUDGE_RUBRIC", "/etc/hwai/rubric.json")
+				setEnv("ANSWER_JUDGE_FAITHFULNESS_THRESHOLD", "0.7")
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many answer regenerations",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("ANSWER_JUDGE_MAX_REGENERATIONS", "5")
+			},
+			wantErr: true,
+		},
+		{
+			name: "model lifecycle defaults",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ModelLoadTimeoutSeconds == 60 && cfg.ModelIdleTTLMinutes == 0
+			},
+		},
+		{
+			name: "model lifecycle settings",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MODEL_LOAD_TIMEOUT_SECONDS", "120")
+				setEnv("MODEL_IDLE_TTL_MINUTES", "30")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ModelLoadTimeoutSeconds == 120 && cfg.ModelIdleTTLMinutes == 30
+			},
+		},
+		{
+			name: "negative model idle ttl",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MODEL_IDLE_TTL_MINUTES", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "request payload limits",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MAX_REQUEST_BODY_KB", "16")
+				setEnv("MAX_QUESTION_LENGTH", "500")
+				setEnv("MAX_REQUEST_FOLDERS", "10")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.MaxRequestBodyKB == 16 &&
+					cfg.MaxQuestionLength == 500 &&
+					cfg.MaxDocumentKB == 256 &&
+					cfg.MaxRequestVaults == 20 &&
+					cfg.MaxRequestFolders == 10
+			},
+		},
+		{
+			name: "invalid max request vaults",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MAX_REQUEST_VAULTS", "-1")
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid max document size",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("MAX_DOCUMENT_KB", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "storage soft limits",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("STORAGE_SQLITE_SOFT_LIMIT_MB", "512")
+				setEnv("STORAGE_QDRANT_SOFT_LIMIT_MB", "2048")
+				setEnv("STORAGE_ALERT_WEBHOOK_URL", "http://hooks.local/storage")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.StorageSQLiteSoftLimitMB == 512 &&
+					cfg.StorageQdrantSoftLimitMB == 2048 &&
+					cfg.StorageAlertWebhookURL == "http://hooks.local/storage" &&
+					cfg.StorageCheckIntervalMinutes == 15
+			},
+		},
+		{
+			name: "negative STORAGE_SQLITE_SOFT_LIMIT_MB",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("STORAGE_SQLITE_SOFT_LIMIT_MB", "-5")
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero STORAGE_CHECK_INTERVAL_MINUTES",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("STORAGE_CHECK_INTERVAL_MINUTES", "0")
+			},
+			wantErr: true,
+		},
+		{
+			name: "cold storage settings",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("COLD_STORAGE_AFTER_MONTHS", "6")
+			},
+			wantErr: false,
+			checkConfig: func(cfg *Config) bool {
+				return cfg.ColdStorageAfterMonths == 6 &&
+					cfg.QdrantColdCollection == "notes_cold"
+			},
+		},
+		{
+			name: "negative COLD_STORAGE_AFTER_MONTHS",
+			setupEnv: func(t *testing.T) {
+				setEnv("VAULT_PERSONAL_PATH", t.TempDir())
+				setEnv("VAULT_WORK_PATH", t.TempDir())
+				setEnv("QDRANT_VECTOR_SIZE", "768")
+				setEnv("COLD_STORAGE_AFTER_MONTHS", "-1")
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,28 @@
+package contextutil
+
+import (
+	"context"
+	"slices"
+)
+
+const allowedVaultsKey contextKey = "allowed_vaults"
+
+// WithAllowedVaults limits the request in ctx to the vaults with the given IDs. Requests
+// without a limit (no user, or the admin token) may read every vault.
+func WithAllowedVaults(ctx context.Context, vaultIDs []int) context.Context {
+	// A non-nil slice marks the request as limited even when the user has no vaults
+	return context.WithValue(ctx, allowedVaultsKey, append([]int{}, vaultIDs...))
+}
+
+// AllowedVaults returns the IDs of the vaults the request in ctx may read. ok is false when
+// the request may read every vault.
+func AllowedVaults(ctx context.Context) (vaultIDs []int, ok bool) {
+	vaultIDs, ok = ctx.Value(allowedVaultsKey).([]int)
+	return vaultIDs, ok
+}
+
+// VaultAllowed reports whether the request in ctx may read the vault with the given ID.
+func VaultAllowed(ctx context.Context, vaultID int) bool {
+	vaultIDs, limited := AllowedVaults(ctx)
+	return !limited || slices.Contains(vaultIDs, vaultID)
+}
@@ -0,0 +1,30 @@
+package contextutil
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAllowedVaults(t *testing.T) {
+	ctx := context.Background()
+	if _, limited := AllowedVaults(ctx); limited {
+		t.Error("AllowedVaults() limited a request without a user")
+	}
+	if !VaultAllowed(ctx, 7) {
+		t.Error("VaultAllowed() = false without a user, want every vault allowed")
+	}
+
+	limited := WithAllowedVaults(ctx, []int{1, 3})
+	if vaultIDs, ok := AllowedVaults(limited); !ok || len(vaultIDs) != 2 {
+		t.Errorf("AllowedVaults() = %v, %v; want [1 3], true", vaultIDs, ok)
+	}
+	if !VaultAllowed(limited, 3) || VaultAllowed(limited, 2) {
+		t.Error("VaultAllowed() does not follow the allowed vaults")
+	}
+
+	// A user without vaults is limited to none, not to all
+	none := WithAllowedVaults(ctx, nil)
+	if _, ok := AllowedVaults(none); !ok || VaultAllowed(none, 1) {
+		t.Error("a user without vaults may read a vault")
+	}
+}
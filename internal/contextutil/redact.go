@@ -0,0 +1,39 @@
+package contextutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unicode/utf8"
+)
+
+// Question log modes control how user question text appears in logs.
+const (
+	// QuestionLogFull logs questions verbatim.
+	QuestionLogFull = "full"
+	// QuestionLogTruncate logs only the first QuestionTruncateRunes runes of a question.
+	QuestionLogTruncate = "truncate"
+	// QuestionLogHash logs a short SHA256 digest instead of the question.
+	QuestionLogHash = "hash"
+)
+
+// QuestionTruncateRunes is the number of runes kept in QuestionLogTruncate mode.
+const QuestionTruncateRunes = 32
+
+// RedactQuestion returns question in the form allowed by mode for logging.
+// Hashes are stable, so repeated questions can still be correlated without exposing text.
+// Unknown or empty modes log the question verbatim.
+func RedactQuestion(mode, question string) string {
+	switch mode {
+	case QuestionLogHash:
+		sum := sha256.Sum256([]byte(question))
+		return "sha256:" + hex.EncodeToString(sum[:])[:16]
+	case QuestionLogTruncate:
+		if utf8.RuneCountInString(question) <= QuestionTruncateRunes {
+			return question
+		}
+		runes := []rune(question)
+		return string(runes[:QuestionTruncateRunes]) + "..."
+	default:
+		return question
+	}
+}
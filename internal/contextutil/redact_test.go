@@ -0,0 +1,33 @@
+package contextutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactQuestion(t *testing.T) {
+	question := "What did I write about my doctor's appointment last Tuesday?"
+
+	if got := RedactQuestion(QuestionLogFull, question); got != question {
+		t.Errorf("RedactQuestion(full) = %q, want question unchanged", got)
+	}
+	if got := RedactQuestion("", question); got != question {
+		t.Errorf("RedactQuestion(\"\") = %q, want question unchanged", got)
+	}
+
+	truncated := RedactQuestion(QuestionLogTruncate, question)
+	if truncated != question[:QuestionTruncateRunes]+"..." {
+		t.Errorf("RedactQuestion(truncate) = %q", truncated)
+	}
+	if got := RedactQuestion(QuestionLogTruncate, "short?"); got != "short?" {
+		t.Errorf("RedactQuestion(truncate) on short question = %q, want unchanged", got)
+	}
+
+	hashed := RedactQuestion(QuestionLogHash, question)
+	if !strings.HasPrefix(hashed, "sha256:") || strings.Contains(hashed, "doctor") {
+		t.Errorf("RedactQuestion(hash) = %q, want sha256 digest without question text", hashed)
+	}
+	if hashed != RedactQuestion(QuestionLogHash, question) {
+		t.Error("RedactQuestion(hash) should be stable for the same question")
+	}
+}
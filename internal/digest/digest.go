@@ -0,0 +1,188 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// maxTopics caps the most-asked topics listed in a digest.
+const maxTopics = 10
+
+// NoteIndexer indexes a single note. It is implemented by indexer.Pipeline.
+type NoteIndexer interface {
+	IndexNote(ctx context.Context, vaultID int, relPath, folder string) error
+	// AutoIndexingPaused reports whether automatic indexing is paused for bulk edits.
+	AutoIndexingPaused() bool
+}
+
+// Generator writes weekly digest notes into a vault folder and indexes them, so the
+// digest becomes part of the knowledge base. A digest covers one ISO week (Monday to
+// Sunday, UTC) and lists notes created or changed that week and the notes that
+// contributed to the most answers.
+type Generator struct {
+	noteRepo     storage.NoteStore
+	vaultManager *vault.Manager
+	indexer      NoteIndexer
+	vaultName    string
+	folder       string
+	now          func() time.Time
+}
+
+// NewGenerator creates a new Generator that writes digests to folder (relative to the
+// root of the vault named vaultName).
+func NewGenerator(noteRepo storage.NoteStore, vaultManager *vault.Manager, indexer NoteIndexer, vaultName, folder string) *Generator {
+	return &Generator{
+		noteRepo:     noteRepo,
+		vaultManager: vaultManager,
+		indexer:      indexer,
+		vaultName:    vaultName,
+		folder:       strings.Trim(filepath.ToSlash(folder), "/"),
+		now:          time.Now,
+	}
+}
+
+// Run writes the digest for the last completed week whenever it does not exist yet,
+// checking every interval until ctx is cancelled. Restarts never produce duplicates.
+// Checks are skipped while automatic indexing is paused.
+func (g *Generator) Run(ctx context.Context, interval time.Duration) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		weekStart := startOfWeek(g.now()).AddDate(0, 0, -7)
+		if g.indexer.AutoIndexingPaused() {
+			// Retried on the next tick once the pause has ended
+			logger.InfoContext(ctx, "digest generation deferred, automatic indexing is paused", "week_start", weekStart.Format(time.DateOnly))
+		} else if _, err := g.Generate(ctx, weekStart); err != nil && !errors.Is(err, os.ErrExist) {
+			logger.WarnContext(ctx, "digest generation failed", "week_start", weekStart.Format(time.DateOnly), "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Generate writes and indexes the digest for the week starting at weekStart and returns
+// its path relative to the vault root. If the digest already exists it is left alone
+// and an error wrapping os.ErrExist is returned.
+func (g *Generator) Generate(ctx context.Context, weekStart time.Time) (string, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	digestVault, err := g.vaultManager.VaultByName(g.vaultName)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest vault: %w", err)
+	}
+
+	weekStart = startOfWeek(weekStart)
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	year, week := weekStart.ISOWeek()
+	name := fmt.Sprintf("%d-W%02d", year, week)
+	relPath := path.Join(g.folder, name+".md")
+	absPath := g.vaultManager.AbsPath(digestVault.ID, relPath)
+	if _, err := os.Stat(absPath); err == nil {
+		return relPath, fmt.Errorf("digest %s: %w", relPath, os.ErrExist)
+	}
+
+	updated, err := g.noteRepo.ListUpdatedBetween(ctx, weekStart, weekEnd)
+	if err != nil {
+		return "", fmt.Errorf("failed to list changed notes: %w", err)
+	}
+	// Fetch extra rows since digest notes are skipped below
+	topics, err := g.noteRepo.ListMostRetrieved(ctx, weekStart, weekEnd, maxTopics*2)
+	if err != nil {
+		return "", fmt.Errorf("failed to list most retrieved notes: %w", err)
+	}
+
+	vaultNames := make(map[int]string)
+	for _, v := range g.vaultManager.ListVaults() {
+		vaultNames[v.ID] = v.Name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Weekly Digest %s\n\n", name)
+	fmt.Fprintf(&b, "Notes from %s to %s.\n\n", weekStart.Format(time.DateOnly), weekEnd.AddDate(0, 0, -1).Format(time.DateOnly))
+
+	b.WriteString("## New and Changed Notes\n\n")
+	changed := 0
+	for _, note := range updated {
+		if g.isDigest(digestVault.ID, note) {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s\n", g.link(digestVault.ID, vaultNames, note))
+		changed++
+	}
+	if changed == 0 {
+		b.WriteString("No notes were created or changed.\n")
+	}
+
+	b.WriteString("\n## Most-Asked Topics\n\n")
+	listed := 0
+	for _, topic := range topics {
+		if listed == maxTopics {
+			break
+		}
+		if g.isDigest(digestVault.ID, topic.Note) {
+			continue
+		}
+		answers := "answers"
+		if topic.Count == 1 {
+			answers = "answer"
+		}
+		fmt.Fprintf(&b, "- %s (%d %s)\n", g.link(digestVault.ID, vaultNames, topic.Note), topic.Count, answers)
+		listed++
+	}
+	if listed == 0 {
+		b.WriteString("No questions were answered from the notes.\n")
+	}
+
+	if err := vault.WriteFileAtomic(absPath, []byte(b.String())); err != nil {
+		return "", fmt.Errorf("failed to write digest: %w", err)
+	}
+	if err := g.indexer.IndexNote(ctx, digestVault.ID, relPath, g.folder); err != nil {
+		return relPath, fmt.Errorf("failed to index digest: %w", err)
+	}
+
+	logger.InfoContext(ctx, "digest written",
+		"vault", g.vaultName,
+		"rel_path", relPath,
+		"changed_notes", changed,
+		"topics", listed,
+	)
+	return relPath, nil
+}
+
+// isDigest reports whether note is a digest, which digests never list.
+func (g *Generator) isDigest(digestVaultID int, note storage.NoteRecord) bool {
+	return note.VaultID == digestVaultID && (note.Folder == g.folder || strings.HasPrefix(note.Folder, g.folder+"/"))
+}
+
+// link formats a note as a wikilink when it lives in the digest vault, and as plain
+// text with its vault otherwise (wikilinks cannot cross vaults).
+func (g *Generator) link(digestVaultID int, vaultNames map[int]string, note storage.NoteRecord) string {
+	title := noteTitle(note)
+	if note.VaultID == digestVaultID {
+		return fmt.Sprintf("[[%s|%s]]", strings.TrimSuffix(note.RelPath, path.Ext(note.RelPath)), title)
+	}
+	return fmt.Sprintf("%s (%s: %s)", title, vaultNames[note.VaultID], note.RelPath)
+}
+
+// startOfWeek returns Monday 00:00 UTC of the ISO week containing t.
+func startOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+}
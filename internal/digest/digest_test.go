@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+type recordingIndexer struct {
+	indexed []string
+	paused  bool
+}
+
+func (r *recordingIndexer) IndexNote(_ context.Context, _ int, relPath, folder string) error {
+	r.indexed = append(r.indexed, folder+"|"+relPath)
+	return nil
+}
+
+func (r *recordingIndexer) AutoIndexingPaused() bool {
+	return r.paused
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: personalDir}, {Name: "work", Path: workDir}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+
+	noteRepo := storage.NewNoteRepo(db)
+	notes := []*storage.NoteRecord{
+		{VaultID: personal.ID, RelPath: "projects/garden.md", Folder: "projects", Title: "Garden", Hash: "a"},
+		{VaultID: work.ID, RelPath: "roadmap.md", Title: "Roadmap", Hash: "b"},
+		{VaultID: personal.ID, RelPath: "Digests/2020-W01.md", Folder: "Digests", Title: "Weekly Digest 2020-W01", Hash: "c"},
+	}
+	for _, note := range notes {
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+	for _, ids := range [][]string{{notes[0].ID, notes[2].ID}, {notes[0].ID}, {notes[1].ID}} {
+		if err := noteRepo.MarkRetrieved(ctx, ids); err != nil {
+			t.Fatalf("MarkRetrieved() error = %v", err)
+		}
+	}
+
+	indexer := &recordingIndexer{}
+	generator := NewGenerator(noteRepo, vaultManager, indexer, "personal", "Digests/")
+	weekStart := startOfWeek(time.Now())
+	year, week := weekStart.ISOWeek()
+
+	relPath, err := generator.Generate(ctx, weekStart)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if want := fmt.Sprintf("Digests/%d-W%02d.md", year, week); relPath != want {
+		t.Errorf("Generate() path = %q, want %q", relPath, want)
+	}
+	if len(indexer.indexed) != 1 || indexer.indexed[0] != "Digests|"+relPath {
+		t.Errorf("indexed = %v, want the digest in folder Digests", indexer.indexed)
+	}
+
+	content, err := os.ReadFile(filepath.Join(personalDir, relPath))
+	if err != nil {
+		t.Fatalf("failed to read digest: %v", err)
+	}
+	digest := string(content)
+	for _, want := range []string{
+		"- [[projects/garden|Garden]]\n",
+		"- Roadmap (work: roadmap.md)\n",
+		"- [[projects/garden|Garden]] (2 answers)\n- Roadmap (work: roadmap.md) (1 answer)\n",
+	} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest missing %q:\n%s", want, digest)
+		}
+	}
+	if strings.Contains(digest, "2020-W01") {
+		t.Errorf("digest lists an earlier digest:\n%s", digest)
+	}
+
+	// Existing digests are never rewritten
+	if _, err := generator.Generate(ctx, weekStart); !errors.Is(err, os.ErrExist) {
+		t.Errorf("second Generate() error = %v, want os.ErrExist", err)
+	}
+	if len(indexer.indexed) != 1 {
+		t.Errorf("indexed %d times, want 1", len(indexer.indexed))
+	}
+}
+
+func TestGenerator_Run_Paused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Run must not touch the vaults while paused, so none are configured
+	indexer := &recordingIndexer{paused: true}
+	NewGenerator(nil, nil, indexer, "personal", "Digests").Run(ctx, time.Hour)
+
+	if len(indexer.indexed) != 0 {
+		t.Errorf("indexed = %v, want nothing while paused", indexer.indexed)
+	}
+}
+
+func TestStartOfWeek(t *testing.T) {
+	sunday := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+	if got, want := startOfWeek(sunday), time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("startOfWeek(Sunday) = %v, want %v", got, want)
+	}
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	if got := startOfWeek(monday); !got.Equal(monday) {
+		t.Errorf("startOfWeek(Monday) = %v, want %v", got, monday)
+	}
+}
@@ -0,0 +1,240 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+const (
+	// maxSummaryNotes caps the notes a summary covers; the most recently changed are kept.
+	maxSummaryNotes = 30
+	// summaryNoteRunes bounds how much of each note's text is shown to the model.
+	summaryNoteRunes = 1200
+)
+
+var (
+	// ErrUnknownVault is returned when a summary request names a vault that is not configured.
+	ErrUnknownVault = errors.New("unknown vault")
+	// ErrSummaryFailed is returned when the chat model fails to write the summary.
+	ErrSummaryFailed = errors.New("failed to generate digest summary")
+)
+
+// citationPattern matches note citations such as [2] and [1, 3].
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// ChatBackend generates chat completions. *llm.Client implements it.
+type ChatBackend interface {
+	ChatWithMessages(ctx context.Context, messages []llm.Message, params llm.ChatParams) (string, error)
+}
+
+// SummaryRequest selects the notes a digest summary covers.
+type SummaryRequest struct {
+	// From and To bound the period: notes last changed in [From, To) are summarized.
+	From time.Time
+	To   time.Time
+	// Vaults restricts the summary to these vault names (empty: all vaults).
+	Vaults []string
+}
+
+// Summary is an LLM-written digest of the notes changed in a period.
+type Summary struct {
+	// Text is the summary, citing notes by their number in brackets ([1]).
+	Text string
+	// Citations are the numbered notes the summary cites. When the model cites nothing,
+	// every summarized note is listed.
+	Citations []Citation
+	// Notes is the number of notes summarized.
+	Notes int
+	// Truncated is set when more notes changed than a summary covers (maxSummaryNotes);
+	// the most recently changed notes were summarized.
+	Truncated bool
+}
+
+// Citation is a summarized note and the number the summary cites it by.
+type Citation struct {
+	Number     int
+	Vault      string
+	RelPath    string
+	Title      string
+	ModifiedAt time.Time
+}
+
+// Summarizer writes digests of the notes changed in a period with the chat model. Unlike
+// Generator, it retrieves notes by recency rather than similarity and returns the digest
+// instead of writing it to the vault.
+type Summarizer struct {
+	noteRepo     storage.NoteStore
+	chunkRepo    storage.ChunkStore
+	vaultManager *vault.Manager
+	chat         ChatBackend
+}
+
+// NewSummarizer creates a new Summarizer.
+func NewSummarizer(noteRepo storage.NoteStore, chunkRepo storage.ChunkStore, vaultManager *vault.Manager, chat ChatBackend) *Summarizer {
+	return &Summarizer{
+		noteRepo:     noteRepo,
+		chunkRepo:    chunkRepo,
+		vaultManager: vaultManager,
+		chat:         chat,
+	}
+}
+
+// Summarize summarizes the notes changed in the requested period, most recently changed
+// first. A period without changed notes is reported without calling the chat model.
+// Returns an error wrapping ErrUnknownVault for unknown vault names and ErrSummaryFailed
+// when the chat model fails.
+func (s *Summarizer) Summarize(ctx context.Context, req SummaryRequest) (Summary, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// Vaults the caller's user may not read are unknown to them; no vault IDs mean all vaults
+	var vaultIDs []int
+	for _, name := range req.Vaults {
+		v, err := s.vaultManager.VaultByName(name)
+		if err != nil || !contextutil.VaultAllowed(ctx, v.ID) {
+			return Summary{}, fmt.Errorf("%w: %s", ErrUnknownVault, name)
+		}
+		vaultIDs = append(vaultIDs, v.ID)
+	}
+	if allowed, limited := contextutil.AllowedVaults(ctx); limited && len(req.Vaults) == 0 {
+		vaultIDs = allowed
+		if len(vaultIDs) == 0 {
+			return Summary{Text: "No notes were created or changed in this period.", Citations: []Citation{}}, nil
+		}
+	}
+	vaultNames := make(map[int]string)
+	for _, v := range s.vaultManager.ListVaults() {
+		vaultNames[v.ID] = v.Name
+	}
+
+	// One extra row tells whether the period had more notes than a summary covers
+	modified, err := s.noteRepo.ListModifiedBetween(ctx, vaultIDs, req.From, req.To, maxSummaryNotes+1)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to list changed notes: %w", err)
+	}
+	summary := Summary{Truncated: len(modified) > maxSummaryNotes}
+	modified = modified[:min(len(modified), maxSummaryNotes)]
+	if len(modified) == 0 {
+		summary.Text = "No notes were created or changed in this period."
+		summary.Citations = []Citation{}
+		return summary, nil
+	}
+
+	citations := make([]Citation, 0, len(modified))
+	var notesBuilder strings.Builder
+	for i, m := range modified {
+		citation := Citation{
+			Number:     i + 1,
+			Vault:      vaultNames[m.Note.VaultID],
+			RelPath:    m.Note.RelPath,
+			Title:      noteTitle(m.Note),
+			ModifiedAt: m.ModifiedAt,
+		}
+		citations = append(citations, citation)
+
+		excerpt, err := s.noteExcerpt(ctx, m.Note.ID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to read note for digest, listing it by title", "rel_path", m.Note.RelPath, "error", err)
+		}
+		fmt.Fprintf(&notesBuilder, "[%d] %s (%s: %s, changed %s)\n%s\n\n",
+			citation.Number, citation.Title, citation.Vault, citation.RelPath, citation.ModifiedAt.UTC().Format(time.DateOnly), excerpt)
+	}
+
+	prompt := fmt.Sprintf(`Write a digest of the notes below, which were created or changed between %s and %s.
+
+Notes:
+%s
+Instructions:
+- Summarize what changed or was written about, grouping related notes into short themed sections
+- Cite the notes behind each statement with their numbers in brackets, like [1] or [2, 3]
+- Use only what the notes say; do not add outside knowledge
+- Keep it brief: a few sentences per theme`,
+		req.From.UTC().Format(time.DateOnly), req.To.UTC().Add(-time.Second).Format(time.DateOnly), notesBuilder.String())
+
+	reply, err := s.chat.ChatWithMessages(ctx, []llm.Message{
+		{Role: "system", Content: "You are a helpful assistant that writes concise digests of a user's notes, citing the notes you draw on."},
+		{Role: "user", Content: prompt},
+	}, llm.ChatParams{Temperature: 0.3})
+	if err != nil {
+		return Summary{}, fmt.Errorf("%w: %w", ErrSummaryFailed, err)
+	}
+
+	summary.Text = strings.TrimSpace(reply)
+	summary.Notes = len(citations)
+	summary.Citations = citedNotes(summary.Text, citations)
+	logger.InfoContext(ctx, "digest summary generated",
+		"from", req.From,
+		"to", req.To,
+		"vaults", req.Vaults,
+		"notes", summary.Notes,
+		"cited", len(summary.Citations),
+		"truncated", summary.Truncated,
+	)
+	return summary, nil
+}
+
+// noteExcerpt returns the start of a note's indexed text, up to summaryNoteRunes, with the
+// text chunks repeat from their previous chunk left out.
+func (s *Summarizer) noteExcerpt(ctx context.Context, noteID string) (string, error) {
+	chunks, err := s.chunkRepo.ListByNote(ctx, noteID)
+	if err != nil {
+		return "", err
+	}
+	var text []rune
+	for _, chunk := range chunks {
+		runes := []rune(chunk.Text)
+		if chunk.OverlapRunes > 0 && chunk.OverlapRunes < len(runes) {
+			runes = runes[chunk.OverlapRunes:]
+		}
+		if len(text) > 0 {
+			text = append(text, '\n')
+		}
+		text = append(text, runes...)
+		if len(text) > summaryNoteRunes {
+			return strings.TrimSpace(string(text[:summaryNoteRunes])) + "…", nil
+		}
+	}
+	return strings.TrimSpace(string(text)), nil
+}
+
+// citedNotes returns the notes the summary cites, in citation number order. A summary
+// without valid citations cites every note.
+func citedNotes(text string, citations []Citation) []Citation {
+	cited := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(text, -1) {
+		for number := range strings.SplitSeq(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(number))
+			if err == nil && n >= 1 && n <= len(citations) {
+				cited[n] = true
+			}
+		}
+	}
+	if len(cited) == 0 {
+		return citations
+	}
+	result := make([]Citation, 0, len(cited))
+	for _, citation := range citations {
+		if cited[citation.Number] {
+			result = append(result, citation)
+		}
+	}
+	return result
+}
+
+// noteTitle returns a note's title, or its file name without the extension.
+func noteTitle(note storage.NoteRecord) string {
+	if note.Title != "" {
+		return note.Title
+	}
+	return strings.TrimSuffix(path.Base(note.RelPath), path.Ext(note.RelPath))
+}
@@ -0,0 +1,169 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+type fakeChat struct {
+	reply  string
+	err    error
+	prompt string
+	calls  int
+}
+
+func (f *fakeChat) ChatWithMessages(_ context.Context, messages []llm.Message, _ llm.ChatParams) (string, error) {
+	f.calls++
+	f.prompt = messages[len(messages)-1].Content
+	return f.reply, f.err
+}
+
+func TestSummarizer_Summarize(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: filepath.Join(tmpDir, "personal")}, {Name: "work", Path: filepath.Join(tmpDir, "work")}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+	notes := []struct {
+		note  *storage.NoteRecord
+		texts []string
+	}{
+		{&storage.NoteRecord{VaultID: personal.ID, RelPath: "garden.md", Title: "Garden", Hash: "a", FileModified: day.Add(9 * time.Hour)}, []string{"Planted tomatoes.", "Watered daily."}},
+		{&storage.NoteRecord{VaultID: work.ID, RelPath: "standup.md", Hash: "b", FileModified: day.Add(12 * time.Hour)}, []string{"Shipped the search page."}},
+		{&storage.NoteRecord{VaultID: personal.ID, RelPath: "old.md", Title: "Old", Hash: "c", FileModified: day.AddDate(0, -1, 0)}, []string{"Last month."}},
+	}
+	for _, n := range notes {
+		if err := noteRepo.Upsert(ctx, n.note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		for i, text := range n.texts {
+			chunk := &storage.ChunkRecord{ID: n.note.RelPath + "-" + string(rune('0'+i)), NoteID: n.note.ID, ChunkIndex: i, Text: text}
+			if err := chunkRepo.Insert(ctx, chunk); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+		}
+	}
+	period := SummaryRequest{From: day, To: day.AddDate(0, 0, 1)}
+
+	t.Run("cited notes", func(t *testing.T) {
+		chat := &fakeChat{reply: "Work: the search page shipped [1].\nGarden: tomatoes went in [2, 9]."}
+		summary, err := NewSummarizer(noteRepo, chunkRepo, vaultManager, chat).Summarize(ctx, period)
+		if err != nil {
+			t.Fatalf("Summarize() error = %v", err)
+		}
+		// Most recently changed first, so standup.md is note 1
+		for _, want := range []string{"[1] standup (work: standup.md, changed 2026-03-10)\nShipped the search page.", "[2] Garden (personal: garden.md, changed 2026-03-10)\nPlanted tomatoes.\nWatered daily."} {
+			if !strings.Contains(chat.prompt, want) {
+				t.Errorf("prompt missing %q:\n%s", want, chat.prompt)
+			}
+		}
+		if strings.Contains(chat.prompt, "Last month.") {
+			t.Errorf("prompt includes a note changed outside the period:\n%s", chat.prompt)
+		}
+		if summary.Notes != 2 || summary.Truncated || len(summary.Citations) != 2 {
+			t.Fatalf("Summarize() = %+v, want 2 notes, both cited", summary)
+		}
+		if got := summary.Citations[0]; got.Number != 1 || got.Vault != "work" || got.RelPath != "standup.md" || !got.ModifiedAt.Equal(day.Add(12*time.Hour)) {
+			t.Errorf("Citations[0] = %+v, want standup.md as note 1", got)
+		}
+	})
+
+	t.Run("vault filter and uncited summary", func(t *testing.T) {
+		chat := &fakeChat{reply: "Tomatoes were planted."}
+		summary, err := NewSummarizer(noteRepo, chunkRepo, vaultManager, chat).Summarize(ctx, SummaryRequest{From: period.From, To: period.To, Vaults: []string{"personal"}})
+		if err != nil {
+			t.Fatalf("Summarize() error = %v", err)
+		}
+		if len(summary.Citations) != 1 || summary.Citations[0].RelPath != "garden.md" {
+			t.Errorf("Citations = %+v, want every summarized note (garden.md)", summary.Citations)
+		}
+	})
+
+	t.Run("user vaults", func(t *testing.T) {
+		chat := &fakeChat{reply: "Tomatoes were planted."}
+		summarizer := NewSummarizer(noteRepo, chunkRepo, vaultManager, chat)
+		limited := contextutil.WithAllowedVaults(ctx, []int{personal.ID})
+		summary, err := summarizer.Summarize(limited, period)
+		if err != nil {
+			t.Fatalf("Summarize() error = %v", err)
+		}
+		if len(summary.Citations) != 1 || summary.Citations[0].Vault != "personal" || strings.Contains(chat.prompt, "standup") {
+			t.Errorf("Summarize() for a personal-only user = %+v, want only personal notes", summary.Citations)
+		}
+		if _, err := summarizer.Summarize(limited, SummaryRequest{From: period.From, To: period.To, Vaults: []string{"work"}}); !errors.Is(err, ErrUnknownVault) {
+			t.Errorf("Summarize(work) for a personal-only user error = %v, want ErrUnknownVault", err)
+		}
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		chat := &fakeChat{}
+		summary, err := NewSummarizer(noteRepo, chunkRepo, vaultManager, chat).Summarize(ctx, SummaryRequest{From: day.AddDate(1, 0, 0), To: day.AddDate(1, 0, 1)})
+		if err != nil {
+			t.Fatalf("Summarize() error = %v", err)
+		}
+		if chat.calls != 0 || summary.Notes != 0 || len(summary.Citations) != 0 {
+			t.Errorf("Summarize() = %+v with %d chat calls, want no notes and no call", summary, chat.calls)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		summarizer := NewSummarizer(noteRepo, chunkRepo, vaultManager, &fakeChat{err: errors.New("llm down")})
+		if _, err := summarizer.Summarize(ctx, SummaryRequest{From: period.From, To: period.To, Vaults: []string{"missing"}}); !errors.Is(err, ErrUnknownVault) {
+			t.Errorf("Summarize(unknown vault) error = %v, want ErrUnknownVault", err)
+		}
+		if _, err := summarizer.Summarize(ctx, period); !errors.Is(err, ErrSummaryFailed) {
+			t.Errorf("Summarize(chat failure) error = %v, want ErrSummaryFailed", err)
+		}
+	})
+}
+
+func TestCitedNotes(t *testing.T) {
+	citations := []Citation{{Number: 1}, {Number: 2}, {Number: 3}}
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{text: "A [3] and B [1,2].", want: []int{1, 2, 3}},
+		{text: "Only [2].", want: []int{2}},
+		{text: "Out of range [7].", want: []int{1, 2, 3}},
+		{text: "No citations.", want: []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		got := citedNotes(tt.text, citations)
+		numbers := make([]int, 0, len(got))
+		for _, c := range got {
+			numbers = append(numbers, c.Number)
+		}
+		if !slices.Equal(numbers, tt.want) {
+			t.Errorf("citedNotes(%q) = %v, want %v", tt.text, numbers, tt.want)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusPass means the check succeeded.
+	StatusPass Status = "PASS"
+	// StatusWarn means the check found something that works but may surprise (e.g. an empty vault).
+	StatusWarn Status = "WARN"
+	// StatusFail means the check found a setup problem the API will trip over.
+	StatusFail Status = "FAIL"
+	// StatusSkip means the check did not run because a check it depends on failed.
+	StatusSkip Status = "SKIP"
+)
+
+const (
+	// scratchVaultName is the vault of the tiny index built by the index and ask checks.
+	scratchVaultName = "doctor"
+	// scratchNote is the note indexed by the index check and asked about by the ask check.
+	scratchNote = "# Doctor\n\nThe helloworld doctor plants blue tomatoes along the north fence every spring.\n"
+	// scratchQuestion is the question of the ask check; the answer must cite scratchNote.
+	scratchQuestion = "Where does the helloworld doctor plant blue tomatoes?"
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	// Hint suggests a fix for failed checks; warnings explain themselves in Detail.
+	Hint     string
+	Duration time.Duration
+}
+
+// Report is the outcome of a doctor run, one result per check in the order they ran.
+type Report struct {
+	Results []Result
+}
+
+// Failed reports whether any check failed.
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints the report as a readable table, the hints of failed checks, and a summary line.
+func (r *Report) Write(w io.Writer) error {
+	counts := make(map[Status]int)
+	var hints []string
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, result := range r.Results {
+		counts[result.Status]++
+		duration := ""
+		if rounded := result.Duration.Round(time.Millisecond); rounded > 0 {
+			duration = rounded.String()
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Status, result.Name, result.Detail, duration)
+		if result.Hint != "" {
+			hints = append(hints, fmt.Sprintf("  %s: %s", result.Name, result.Hint))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if len(hints) > 0 {
+		_, _ = fmt.Fprintf(w, "\nHints:\n%s\n", strings.Join(hints, "\n"))
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[StatusPass], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+	return err
+}
+
+// ConfigFailure returns the report of a run that could not load its configuration.
+func ConfigFailure(err error) *Report {
+	return &Report{Results: []Result{{
+		Name:   "configuration",
+		Status: StatusFail,
+		Detail: err.Error(),
+		Hint:   "check the environment and .env file (see the README for required variables)",
+	}}}
+}
+
+// warning is a check error that is reported as StatusWarn instead of StatusFail.
+type warning struct {
+	msg string
+}
+
+func (w *warning) Error() string {
+	return w.msg
+}
+
+// warnf returns a warning error.
+func warnf(format string, args ...any) error {
+	return &warning{msg: fmt.Sprintf(format, args...)}
+}
+
+// VectorStore is the vector store the doctor checks and builds its scratch collection in.
+type VectorStore interface {
+	vectorstore.VectorStore
+	CollectionExists(ctx context.Context, collection string) (bool, error)
+	EnsureCollection(ctx context.Context, collection string, vectorSize int) error
+	GetCollectionInfo(ctx context.Context, collection string) (*vectorstore.CollectionInfo, error)
+	DeleteCollection(ctx context.Context, collection string) error
+	AliasTarget(ctx context.Context, alias string) (string, error)
+}
+
+// runner runs the checks of one doctor run and records their results.
+type runner struct {
+	cfg    *config.Config
+	report *Report
+	// passed holds the names of checks that passed or warned, for dependent checks.
+	passed map[string]bool
+}
+
+// check runs fn as the check name unless one of needs did not pass, and records the result.
+// fn returns a detail line; a *warning error is a warning, any other error a failure.
+func (r *runner) check(name, hint string, needs []string, fn func() (string, error)) {
+	for _, need := range needs {
+		if !r.passed[need] {
+			r.report.Results = append(r.report.Results, Result{Name: name, Status: StatusSkip, Detail: fmt.Sprintf("needs %s", need)})
+			return
+		}
+	}
+
+	start := time.Now()
+	detail, err := fn()
+	result := Result{Name: name, Status: StatusPass, Detail: detail, Duration: time.Since(start)}
+	var warn *warning
+	switch {
+	case errors.As(err, &warn):
+		result.Status = StatusWarn
+		result.Detail = warn.msg
+		r.passed[name] = true
+	case err != nil:
+		result.Status = StatusFail
+		result.Detail = err.Error()
+		result.Hint = hint
+	default:
+		r.passed[name] = true
+	}
+	r.report.Results = append(r.report.Results, result)
+}
+
+// Run checks the setup described by cfg end to end: vault paths, SQLite writability, Qdrant
+// connectivity and collection shape, llama.cpp model availability, a test embedding and
+// generation, and a tiny index of a scratch note answered by a test question. The index
+// and ask checks use a temporary database and a scratch collection that are removed
+// afterwards, so the real index is never modified. In test mode the fake LLM and in-memory
+// vector store are used, as in the API. Run never stops early; checks whose dependencies
+// failed are skipped.
+func Run(ctx context.Context, cfg *config.Config) *Report {
+	r := &runner{cfg: cfg, report: &Report{}, passed: make(map[string]bool)}
+
+	r.check("configuration", "", nil, func() (string, error) {
+		return fmt.Sprintf("%s mode, %d vaults, engine %s", cfg.Mode, len(cfg.Vaults), cfg.RAGEngine), nil
+	})
+
+	for _, v := range cfg.Vaults {
+		r.check("vault "+v.Name, "fix the path in VAULTS_JSON", nil, func() (string, error) {
+			return checkVaultPath(v.Path)
+		})
+	}
+
+	r.check("sqlite", "make sure the directory of DB_PATH exists and is writable", nil, func() (string, error) {
+		return checkSQLite(ctx, cfg.DBPath)
+	})
+
+	// Test mode swaps llama.cpp for an in-process fake, like the API
+	chatBaseURL, embeddingBaseURL := cfg.LLMBaseURL, cfg.EmbeddingBaseURL
+	var vectorStore VectorStore
+	if cfg.Mode == config.ModeTest {
+		fakeLLM := llm.NewFakeServer(cfg.QdrantVectorSize)
+		defer fakeLLM.Close()
+		chatBaseURL, embeddingBaseURL = fakeLLM.URL, fakeLLM.URL
+	}
+	if cfg.VectorStoreBackend == vectorstore.BackendMemory {
+		vectorStore = vectorstore.NewMemoryStore()
+	}
+
+	r.check("qdrant connection", "start the vector store and check QDRANT_URL (or PGVECTOR_DSN with VECTOR_STORE_BACKEND=pgvector)", nil, func() (string, error) {
+		if vectorStore != nil {
+			return "in-memory vector store", nil
+		}
+		store, err := vectorstore.Open(vectorstore.BackendOptions{
+			Backend:         cfg.VectorStoreBackend,
+			QdrantURL:       cfg.QdrantURL,
+			PgvectorDSN:     cfg.PgvectorDSN,
+			UpsertBatchSize: cfg.QdrantUpsertBatchSize,
+			Distance:        cfg.QdrantDistance,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s vector store: %w", cfg.VectorStoreBackend, err)
+		}
+		if _, err := store.CollectionExists(ctx, cfg.QdrantCollection); err != nil {
+			_ = store.Close()
+			return "", fmt.Errorf("failed to reach the %s vector store: %w", cfg.VectorStoreBackend, err)
+		}
+		vectorStore = store
+		if cfg.VectorStoreBackend == vectorstore.BackendPgvector {
+			return "pgvector", nil
+		}
+		return cfg.QdrantURL, nil
+	})
+	if closer, ok := vectorStore.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+
+	r.check("qdrant collection", "QDRANT_VECTOR_SIZE and QDRANT_DISTANCE must match the collection; recreate it or fix the settings", []string{"qdrant connection"}, func() (string, error) {
+		return checkCollection(ctx, vectorStore, cfg.QdrantCollection, cfg.QdrantVectorSize)
+	})
+
+	modelHint := "start llama.cpp and check LLM_BASE_URL, EMBEDDING_BASE_URL, and the model names"
+	if cfg.Mode == config.ModeTest {
+		r.report.Results = append(r.report.Results,
+			Result{Name: "chat model", Status: StatusSkip, Detail: "test mode uses the fake LLM"},
+			Result{Name: "embedding model", Status: StatusSkip, Detail: "test mode uses the fake LLM"},
+		)
+	} else {
+		r.check("chat model", modelHint, nil, func() (string, error) {
+			return checkModelLoaded(ctx, llm.NewModelLoader(chatBaseURL), cfg.LLMModelName)
+		})
+		r.check("embedding model", modelHint, nil, func() (string, error) {
+			return checkModelLoaded(ctx, llm.NewModelLoader(embeddingBaseURL), cfg.EmbeddingModelName)
+		})
+	}
+
+	embedder := llm.NewEmbeddingsClient(embeddingBaseURL, cfg.LLMAPIKey, cfg.EmbeddingModelName, cfg.QdrantVectorSize)
+	r.check("test embedding", "check the embedding server and that QDRANT_VECTOR_SIZE matches the embedding model", nil, func() (string, error) {
+		embeddings, err := embedder.EmbedTexts(ctx, []string{scratchQuestion})
+		if err != nil {
+			return "", fmt.Errorf("failed to embed text: %w", err)
+		}
+		if len(embeddings) != 1 || len(embeddings[0]) != cfg.QdrantVectorSize {
+			return "", fmt.Errorf("embedding vector size mismatch: expected %d, got %d", cfg.QdrantVectorSize, len(embeddings[0]))
+		}
+		return fmt.Sprintf("%s, %d dimensions", cfg.EmbeddingModelName, cfg.QdrantVectorSize), nil
+	})
+
+	chat := llm.NewClient(chatBaseURL, cfg.LLMAPIKey, cfg.LLMModelName)
+	r.check("test generation", "check the chat server and LLM_MODEL", nil, func() (string, error) {
+		reply, err := chat.Chat(ctx, "Reply with the single word OK.")
+		if err != nil {
+			return "", fmt.Errorf("failed to generate: %w", err)
+		}
+		if strings.TrimSpace(reply) == "" {
+			return "", fmt.Errorf("model returned an empty reply")
+		}
+		return fmt.Sprintf("%s replied %q", cfg.LLMModelName, truncate(strings.TrimSpace(reply), 40)), nil
+	})
+
+	r.scratchIndex(ctx, vectorStore, embedder, chat)
+
+	return r.report
+}
+
+// scratchIndex runs the index and ask checks against a temporary vault, database, and
+// collection, and removes them afterwards.
+func (r *runner) scratchIndex(ctx context.Context, vectorStore VectorStore, embedder *llm.EmbeddingsClient, chat *llm.Client) {
+	dir, err := os.MkdirTemp("", "helloworld-doctor-")
+	if err != nil {
+		r.report.Results = append(r.report.Results, Result{Name: "test index", Status: StatusFail, Detail: fmt.Sprintf("failed to create temporary directory: %v", err)})
+		return
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	collection := r.cfg.QdrantCollection + "_doctor"
+
+	var db *sql.DB
+	var engine rag.Engine
+	collectionCreated := false
+	r.check("test index", "see the failing step; the test embedding and qdrant checks narrow it down", []string{"sqlite", "qdrant connection", "test embedding"}, func() (string, error) {
+		vaultPath := filepath.Join(dir, "vault")
+		if err := os.MkdirAll(vaultPath, 0755); err != nil {
+			return "", fmt.Errorf("failed to create scratch vault: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(vaultPath, "doctor.md"), []byte(scratchNote), 0644); err != nil {
+			return "", fmt.Errorf("failed to write scratch note: %w", err)
+		}
+
+		dbPath := filepath.Join(dir, "doctor.db")
+		db, err = storage.New(dbPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to open scratch database: %w", err)
+		}
+		if err := storage.Migrate(db); err != nil {
+			return "", fmt.Errorf("failed to run migrations: %w", err)
+		}
+		vaultRepo := storage.NewVaultRepo(db)
+		noteRepo := storage.NewNoteRepo(db)
+		chunkRepo := storage.NewChunkRepo(db)
+		vaultManager, err := vault.NewManager(ctx, vaultRepo, []vault.Config{{Name: scratchVaultName, Path: vaultPath}}, r.cfg.VaultSymlinks)
+		if err != nil {
+			return "", fmt.Errorf("failed to initialize scratch vault: %w", err)
+		}
+
+		// A run that was killed may have left its scratch collection behind
+		if exists, err := vectorStore.CollectionExists(ctx, collection); err != nil {
+			return "", fmt.Errorf("failed to check scratch collection: %w", err)
+		} else if exists {
+			if err := vectorStore.DeleteCollection(ctx, collection); err != nil {
+				return "", fmt.Errorf("failed to remove stale scratch collection: %w", err)
+			}
+		}
+		if err := vectorStore.EnsureCollection(ctx, collection, r.cfg.QdrantVectorSize); err != nil {
+			return "", fmt.Errorf("failed to create scratch collection: %w", err)
+		}
+		collectionCreated = true
+		pipeline := indexer.NewPipeline(vaultManager, noteRepo, chunkRepo, storage.NewIndexTimingRepo(db), storage.NewIndexChecksumRepo(db), embedder, vectorStore, collection, "", "", storage.NewShadowIndex(db, dbPath+".rebuild"))
+		pipeline.SetChunkOverlap(r.cfg.ChunkOverlapRunes)
+		if err := pipeline.IndexAll(ctx); err != nil {
+			return "", fmt.Errorf("failed to index scratch note: %w", err)
+		}
+		chunkIDs, err := chunkRepo.GetAllIDs(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list scratch chunks: %w", err)
+		}
+		if len(chunkIDs) == 0 {
+			return "", fmt.Errorf("scratch note produced no chunks")
+		}
+
+		flags, err := features.New(r.cfg.FeatureFlags)
+		if err != nil {
+			return "", fmt.Errorf("failed to initialize feature flags: %w", err)
+		}
+		engine, err = rag.NewEngineByName(r.cfg.RAGEngine, rag.EngineDeps{
+			Embedder:        embedder,
+			VectorStore:     vectorStore,
+			Collection:      collection,
+			ChunkRepo:       chunkRepo,
+			VaultRepo:       vaultRepo,
+			NoteRepo:        noteRepo,
+			Chat:            chat,
+			FolderSelection: rag.DefaultFolderSelectionOptions,
+			Flags:           flags,
+			QuestionCache:   rag.DefaultQuestionCacheOptions,
+			Generation:      rag.GenerationOptions{Model: r.cfg.LLMModelName},
+			Stages:          r.cfg.RAGStages,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to create RAG engine: %w", err)
+		}
+		return fmt.Sprintf("1 note, %d chunks in scratch collection %s", len(chunkIDs), collection), nil
+	})
+	if db != nil {
+		defer func() {
+			_ = db.Close()
+		}()
+	}
+	if collectionCreated {
+		defer func() {
+			// Detach from ctx so an interrupted run still removes the scratch collection
+			if err := vectorStore.DeleteCollection(context.WithoutCancel(ctx), collection); err != nil {
+				r.report.Results = append(r.report.Results, Result{Name: "cleanup", Status: StatusWarn, Detail: err.Error(), Hint: fmt.Sprintf("delete the Qdrant collection %s by hand", collection)})
+			}
+		}()
+	}
+
+	r.check("test ask", "check RAG_ENGINE and RAG_STAGES; with retrieval working, a missing reference points at the score thresholds", []string{"test index", "test generation"}, func() (string, error) {
+		resp, err := engine.Ask(ctx, rag.AskRequest{Question: scratchQuestion})
+		if err != nil {
+			return "", fmt.Errorf("failed to answer test question: %w", err)
+		}
+		for _, ref := range resp.References {
+			if ref.RelPath == "doctor.md" {
+				return fmt.Sprintf("answered citing %s: %q", ref.RelPath, truncate(strings.TrimSpace(resp.Answer), 60)), nil
+			}
+		}
+		if resp.Abstained {
+			return "", fmt.Errorf("engine abstained (%s) instead of answering from the scratch note", resp.AbstainReason)
+		}
+		return "", fmt.Errorf("answer does not reference the scratch note")
+	})
+}
+
+// checkVaultPath checks that a vault path is a readable directory and counts its notes.
+func checkVaultPath(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault path: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	notes := 0
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != path && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(d.Name()), ".md") {
+			notes++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan vault: %w", err)
+	}
+	if notes == 0 {
+		return "", warnf("%s has no markdown notes", path)
+	}
+	return fmt.Sprintf("%s, %d notes", path, notes), nil
+}
+
+// checkSQLite opens and migrates the database and makes sure it can be written to. The
+// probe write is rolled back.
+func checkSQLite(ctx context.Context, path string) (string, error) {
+	db, err := storage.New(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		return "", fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE doctor_write_check (id INTEGER)"); err != nil {
+		return "", fmt.Errorf("database is not writable: %w", err)
+	}
+
+	var notes int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Scan(&notes); err != nil {
+		return "", fmt.Errorf("failed to count notes: %w", err)
+	}
+	return fmt.Sprintf("%s, %d notes indexed", path, notes), nil
+}
+
+// checkCollection checks that the collection exists with the configured vector size and
+// distance metric. A missing collection is a warning, since the API creates it on startup.
+func checkCollection(ctx context.Context, vectorStore VectorStore, collection string, vectorSize int) (string, error) {
+	exists, err := vectorStore.CollectionExists(ctx, collection)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		if target, err := vectorStore.AliasTarget(ctx, collection); err != nil || target == "" {
+			return "", warnf("collection %s does not exist yet; the API creates it on startup", collection)
+		}
+	}
+	info, err := vectorStore.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return "", err
+	}
+	// EnsureCollection validates an existing collection's vector size and distance metric
+	if err := vectorStore.EnsureCollection(ctx, collection, vectorSize); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s, %d points, vector size %d", collection, info.PointsCount, info.VectorSize), nil
+}
+
+// checkModelLoaded checks whether llama.cpp (router mode) has the model loaded. Both an
+// unanswered check and a model that is not loaded yet are warnings: servers outside router
+// mode have no model list, and router mode loads models on first use. The test embedding
+// and generation checks tell whether the models actually work.
+func checkModelLoaded(ctx context.Context, loader *llm.ModelLoader, model string) (string, error) {
+	loaded, err := loader.IsModelLoaded(ctx, model)
+	if err != nil {
+		return "", warnf("could not check model %s: %v", model, err)
+	}
+	if !loaded {
+		return "", warnf("%s is not loaded yet; it is loaded on first use, which makes the first request slow", model)
+	}
+	return fmt.Sprintf("%s loaded", model), nil
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
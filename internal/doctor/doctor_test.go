@@ -0,0 +1,109 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/config"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// testConfig returns a test-mode configuration with one vault holding a note.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	vaultPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(vaultPath, "garden.md"), []byte("# Garden\n\nTomatoes."), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+	return &config.Config{
+		Mode:               config.ModeTest,
+		VectorStoreBackend: vectorstore.BackendMemory,
+		Vaults:             []vault.Config{{Name: "personal", Path: vaultPath}},
+		VaultSymlinks:      vault.SymlinksFollow,
+		DBPath:             filepath.Join(t.TempDir(), "test.db"),
+		QdrantCollection:   "notes",
+		QdrantVectorSize:   64,
+		LLMModelName:       "fake-chat",
+		EmbeddingModelName: "fake-embedding",
+		RAGEngine:          rag.EngineLLM,
+	}
+}
+
+// statuses returns the status of each check by name.
+func statuses(report *Report) map[string]Status {
+	out := make(map[string]Status, len(report.Results))
+	for _, result := range report.Results {
+		out[result.Name] = result.Status
+	}
+	return out
+}
+
+func TestRun_TestMode(t *testing.T) {
+	report := Run(context.Background(), testConfig(t))
+
+	want := map[string]Status{
+		"configuration":     StatusPass,
+		"vault personal":    StatusPass,
+		"sqlite":            StatusPass,
+		"qdrant connection": StatusPass,
+		// The in-memory store starts empty; the API creates the collection on startup
+		"qdrant collection": StatusWarn,
+		"chat model":        StatusSkip,
+		"embedding model":   StatusSkip,
+		"test embedding":    StatusPass,
+		"test generation":   StatusPass,
+		"test index":        StatusPass,
+		"test ask":          StatusPass,
+	}
+	got := statuses(report)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("check %q = %s, want %s (report: %+v)", name, got[name], status, report.Results)
+		}
+	}
+	if report.Failed() {
+		t.Errorf("Failed() = true, want false")
+	}
+}
+
+func TestRun_SkipsDependentChecks(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Vaults = append(cfg.Vaults, vault.Config{Name: "work", Path: filepath.Join(t.TempDir(), "missing")})
+	cfg.DBPath = filepath.Join(t.TempDir(), "missing", "test.db")
+
+	report := Run(context.Background(), cfg)
+	got := statuses(report)
+	if got["vault work"] != StatusFail || got["sqlite"] != StatusFail {
+		t.Errorf("vault and sqlite checks = %s, %s, want FAIL", got["vault work"], got["sqlite"])
+	}
+	// The tiny index needs a writable database, and the ask needs the index
+	if got["test index"] != StatusSkip || got["test ask"] != StatusSkip {
+		t.Errorf("index and ask checks = %s, %s, want SKIP", got["test index"], got["test ask"])
+	}
+	if !report.Failed() {
+		t.Errorf("Failed() = false, want true")
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	report := ConfigFailure(errors.New("VAULTS_JSON is required"))
+	report.Results = append(report.Results, Result{Name: "sqlite", Status: StatusSkip, Detail: "needs configuration"})
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"FAIL  configuration  VAULTS_JSON is required", "configuration: check the environment", "0 passed, 0 warnings, 1 failed, 1 skipped"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Write() output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Case is one question of a golden dataset, in the format of eval/eval_set.jsonl.
+type Case struct {
+	// ID identifies the case in reports (e.g. "test_001").
+	ID string `json:"id"`
+	// Question is asked as written.
+	Question string `json:"question"`
+	// Answerable is false for questions the notes cannot answer, where abstaining is correct.
+	Answerable bool `json:"answerable"`
+	// GoldSupports are the note sections that answer the question.
+	GoldSupports []GoldSupport `json:"gold_supports"`
+	// RequiredSupportGroups lists groups of GoldSupports indices for multi-hop questions:
+	// recall_all is met when every support of at least one group is retrieved.
+	RequiredSupportGroups [][]int `json:"required_support_groups,omitempty"`
+	// Vaults and Folders scope the question like the ask request fields.
+	Vaults  []string `json:"vaults,omitempty"`
+	Folders []string `json:"folders,omitempty"`
+	// Category groups cases in reports (e.g. "factual", "multi_hop").
+	Category string `json:"category,omitempty"`
+}
+
+// GoldSupport anchors an expected source by location rather than chunk ID, so it survives
+// chunking changes.
+type GoldSupport struct {
+	// RelPath is the note path relative to its vault.
+	RelPath string `json:"rel_path"`
+	// HeadingPath is matched as a prefix of the chunk's heading path (heading levels ignored).
+	HeadingPath string `json:"heading_path"`
+	// Snippets, when set, require the chunk text to contain at least one of them.
+	Snippets []string `json:"snippets,omitempty"`
+}
+
+// LoadCases reads a golden dataset: JSON Lines (one case per line, .jsonl) or a JSON array
+// (.json). Blank lines are skipped. YAML datasets must be converted to JSON first.
+func LoadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var cases []Case
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &cases); err != nil {
+			return nil, fmt.Errorf("failed to parse dataset: %w", err)
+		}
+	case ".jsonl":
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var c Case
+			if err := json.Unmarshal([]byte(text), &c); err != nil {
+				return nil, fmt.Errorf("failed to parse dataset line %d: %w", line, err)
+			}
+			cases = append(cases, c)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read dataset: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dataset format %q: use .jsonl or .json", filepath.Ext(path))
+	}
+
+	for i, c := range cases {
+		if strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("case %d (%s) has no question", i+1, c.ID)
+		}
+		if cases[i].ID == "" {
+			cases[i].ID = fmt.Sprintf("case_%03d", i+1)
+		}
+	}
+	return cases, nil
+}
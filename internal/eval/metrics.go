@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"regexp"
+	"strings"
+
+	"helloworld-ai/internal/rag"
+)
+
+var (
+	// headingDelimiter matches " > " with any spacing.
+	headingDelimiter = regexp.MustCompile(`\s*>\s*`)
+	// headingLevel matches the leading #s of a heading.
+	headingLevel = regexp.MustCompile(`^#+\s*`)
+	// spaceRun matches runs of whitespace.
+	spaceRun = regexp.MustCompile(`\s+`)
+)
+
+// normalizeHeadingPath strips heading levels and extra spaces so "# A >  ## B" and "A > B"
+// compare equal. It matches normalize_heading_path in eval/scripts/score_retrieval.py, so
+// both harnesses score the same dataset the same way.
+func normalizeHeadingPath(headingPath string) string {
+	var parts []string
+	for _, part := range headingDelimiter.Split(strings.TrimSpace(headingPath), -1) {
+		part = headingLevel.ReplaceAllString(strings.TrimSpace(part), "")
+		part = strings.TrimSpace(spaceRun.ReplaceAllString(part, " "))
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// matchesSupport reports whether a chunk at relPath/headingPath with text matches support:
+// the same path, a heading path starting with the support's, and (when the support has
+// snippets and text is known) a snippet in the text, case-insensitively.
+func matchesSupport(relPath, headingPath, text string, support GoldSupport) bool {
+	if relPath != support.RelPath {
+		return false
+	}
+	if !strings.HasPrefix(normalizeHeadingPath(headingPath), normalizeHeadingPath(support.HeadingPath)) {
+		return false
+	}
+	if len(support.Snippets) == 0 || text == "" {
+		return true
+	}
+	lower := strings.ToLower(text)
+	for _, snippet := range support.Snippets {
+		if strings.Contains(lower, strings.ToLower(snippet)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstMatchRank returns the 1-based rank of the first chunk matching any support, or 0.
+func firstMatchRank(chunks []rag.RetrievedChunk, supports []GoldSupport) int {
+	for i, chunk := range chunks {
+		for _, support := range supports {
+			if matchesSupport(chunk.RelPath, chunk.HeadingPath, chunk.Text, support) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// supportRetrieved reports whether any chunk matches support.
+func supportRetrieved(chunks []rag.RetrievedChunk, support GoldSupport) bool {
+	for _, chunk := range chunks {
+		if matchesSupport(chunk.RelPath, chunk.HeadingPath, chunk.Text, support) {
+			return true
+		}
+	}
+	return false
+}
+
+// recallAll reports whether every support of at least one group was retrieved. Without
+// groups it is recall_any.
+func recallAll(chunks []rag.RetrievedChunk, supports []GoldSupport, groups [][]int) bool {
+	if len(groups) == 0 {
+		return firstMatchRank(chunks, supports) > 0
+	}
+	for _, group := range groups {
+		satisfied := len(group) > 0
+		for _, index := range group {
+			if index < 0 || index >= len(supports) || !supportRetrieved(chunks, supports[index]) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+// citationPrecision returns the fraction of references that match a support (by path and
+// heading; references carry no text) and false when there are no references.
+func citationPrecision(refs []rag.Reference, supports []GoldSupport) (float64, bool) {
+	if len(refs) == 0 {
+		return 0, false
+	}
+	hits := 0
+	for _, ref := range refs {
+		for _, support := range supports {
+			if matchesSupport(ref.RelPath, ref.HeadingPath, "", support) {
+				hits++
+				break
+			}
+		}
+	}
+	return float64(hits) / float64(len(refs)), true
+}
@@ -0,0 +1,89 @@
+package eval
+
+import (
+	"testing"
+
+	"helloworld-ai/internal/rag"
+)
+
+func TestNormalizeHeadingPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "# Summary > ## Column Reference", want: "Summary > Column Reference"},
+		{in: "  #  Summary>##   Column   Reference ", want: "Summary > Column Reference"},
+		{in: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeHeadingPath(tt.in); got != tt.want {
+			t.Errorf("normalizeHeadingPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesSupport(t *testing.T) {
+	support := GoldSupport{RelPath: "docs/a.md", HeadingPath: "# Summary", Snippets: []string{"Primary Key"}}
+
+	tests := []struct {
+		name        string
+		relPath     string
+		headingPath string
+		text        string
+		want        bool
+	}{
+		{name: "deeper heading with snippet", relPath: "docs/a.md", headingPath: "## Summary > ### Columns", text: "the primary key is id", want: true},
+		{name: "no text skips snippets", relPath: "docs/a.md", headingPath: "# Summary", want: true},
+		{name: "snippet missing", relPath: "docs/a.md", headingPath: "# Summary", text: "unrelated", want: false},
+		{name: "other note", relPath: "docs/b.md", headingPath: "# Summary", text: "primary key", want: false},
+		{name: "other section", relPath: "docs/a.md", headingPath: "# Overview", text: "primary key", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesSupport(tt.relPath, tt.headingPath, tt.text, support); got != tt.want {
+				t.Errorf("matchesSupport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecallAll(t *testing.T) {
+	supports := []GoldSupport{
+		{RelPath: "a.md", HeadingPath: "# A"},
+		{RelPath: "b.md", HeadingPath: "# B"},
+		{RelPath: "c.md", HeadingPath: "# C"},
+	}
+	chunks := []rag.RetrievedChunk{
+		{RelPath: "a.md", HeadingPath: "# A"},
+		{RelPath: "c.md", HeadingPath: "# C > ## Details"},
+	}
+
+	if recallAll(chunks, supports, [][]int{{0, 1}}) {
+		t.Error("recallAll() = true, want false when support 1 of the only group is missing")
+	}
+	if !recallAll(chunks, supports, [][]int{{0, 1}, {0, 2}}) {
+		t.Error("recallAll() = false, want true when the second group is complete")
+	}
+	if recallAll(chunks, supports, [][]int{{0, 7}}) {
+		t.Error("recallAll() = true, want false for an out-of-range support index")
+	}
+	if !recallAll(chunks, supports, nil) {
+		t.Error("recallAll() without groups should fall back to recall_any")
+	}
+}
+
+func TestCitationPrecision(t *testing.T) {
+	supports := []GoldSupport{{RelPath: "a.md", HeadingPath: "# A", Snippets: []string{"only in text"}}}
+	refs := []rag.Reference{
+		{RelPath: "a.md", HeadingPath: "# A > ## Sub"},
+		{RelPath: "b.md", HeadingPath: "# B"},
+	}
+
+	precision, ok := citationPrecision(refs, supports)
+	if !ok || precision != 0.5 {
+		t.Errorf("citationPrecision() = %v, %v, want 0.5, true", precision, ok)
+	}
+	if _, ok := citationPrecision(nil, supports); ok {
+		t.Error("citationPrecision() without references should report no value")
+	}
+}
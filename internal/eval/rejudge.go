@@ -0,0 +1,244 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/judge"
+)
+
+// Trace is one answered question of an earlier evaluation run: a line of the Python
+// harness's eval/results/<run_id>/results.jsonl. Chunk texts are cut to 200 characters
+// unless the run used --store-full-text.
+type Trace struct {
+	ID              string       `json:"test_case_id"`
+	Question        string       `json:"question"`
+	Answer          string       `json:"answer"`
+	RetrievedChunks []TraceChunk `json:"retrieved_chunks"`
+	Abstention      *struct {
+		Abstained bool `json:"abstained"`
+	} `json:"abstention,omitempty"`
+}
+
+// TraceChunk is a chunk the traced answer was generated from.
+type TraceChunk struct {
+	RelPath     string `json:"rel_path"`
+	HeadingPath string `json:"heading_path"`
+	Text        string `json:"text"`
+}
+
+// LoadTraces reads the traces of a results.jsonl file.
+func LoadTraces(path string) ([]Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traces: %w", err)
+	}
+	var traces []Trace
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// Full-text runs carry every retrieved chunk on one line
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var trace Trace
+		if err := json.Unmarshal([]byte(text), &trace); err != nil {
+			return nil, fmt.Errorf("failed to parse trace on line %d: %w", line, err)
+		}
+		if trace.ID == "" {
+			trace.ID = fmt.Sprintf("line-%d", line)
+		}
+		traces = append(traces, trace)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read traces: %w", err)
+	}
+	return traces, nil
+}
+
+// RejudgeResult is the new verdict on one trace.
+type RejudgeResult struct {
+	ID       string `json:"id"`
+	Question string `json:"question"`
+	// Skipped says why the trace was not judged: abstained, no_answer, or no_context.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Scores holds every criterion of the rubric, normalized to 0-1.
+	Scores map[string]float32 `json:"scores,omitempty"`
+	Reason string             `json:"reason,omitempty"`
+	// BelowRegenerate is true when the API would regenerate the answer.
+	BelowRegenerate bool `json:"below_regenerate,omitempty"`
+	// BelowAbstain lists the criteria the answer scores below their abstain threshold.
+	BelowAbstain []string `json:"below_abstain,omitempty"`
+}
+
+// RejudgeSummary aggregates a re-judged run.
+type RejudgeSummary struct {
+	Traces  int `json:"traces"`
+	Judged  int `json:"judged"`
+	Skipped int `json:"skipped"`
+	Errors  int `json:"errors"`
+	// MeanScores averages each criterion over the judged traces (0-1).
+	MeanScores map[string]float64 `json:"mean_scores"`
+	// BelowRegenerate counts answers the API would regenerate under the rubric.
+	BelowRegenerate int `json:"below_regenerate"`
+	// BelowAbstain counts answers the API would replace with an abstention under the rubric.
+	BelowAbstain int `json:"below_abstain"`
+}
+
+// RejudgeReport is the outcome of re-judging a run's traces with a rubric.
+type RejudgeReport struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Rubric    string          `json:"rubric"`
+	Summary   RejudgeSummary  `json:"summary"`
+	Results   []RejudgeResult `json:"results"`
+}
+
+// Rejudge scores the answer of every trace with j, so earlier runs can be recalibrated after
+// a rubric change without asking the questions again. Judge errors are recorded on the
+// result; only cancellation of ctx stops the run.
+func Rejudge(ctx context.Context, j *judge.Judge, traces []Trace) (*RejudgeReport, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+	rubric := j.Rubric()
+	report := &RejudgeReport{CreatedAt: time.Now().UTC(), Rubric: rubric.Version, Results: make([]RejudgeResult, 0, len(traces))}
+
+	for i, trace := range traces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := rejudgeTrace(ctx, j, rubric, trace)
+		logger.InfoContext(ctx, "trace rejudged",
+			"trace", trace.ID,
+			"progress", fmt.Sprintf("%d/%d", i+1, len(traces)),
+			"scores", result.Scores,
+			"skipped", result.Skipped,
+			"error", result.Error,
+		)
+		report.Results = append(report.Results, result)
+	}
+	report.Summary = summarizeRejudged(report.Results)
+	return report, nil
+}
+
+// rejudgeTrace judges one trace and compares its scores with the rubric's thresholds.
+func rejudgeTrace(ctx context.Context, j *judge.Judge, rubric judge.Rubric, trace Trace) RejudgeResult {
+	result := RejudgeResult{ID: trace.ID, Question: trace.Question}
+	sources := make([]judge.Source, 0, len(trace.RetrievedChunks))
+	for _, chunk := range trace.RetrievedChunks {
+		if chunk.Text != "" {
+			sources = append(sources, judge.Source{Label: fmt.Sprintf("%s (%s)", chunk.RelPath, chunk.HeadingPath), Text: chunk.Text})
+		}
+	}
+	switch {
+	case trace.Abstention != nil && trace.Abstention.Abstained:
+		result.Skipped = "abstained"
+		return result
+	case strings.TrimSpace(trace.Answer) == "":
+		result.Skipped = "no_answer"
+		return result
+	case len(sources) == 0:
+		result.Skipped = "no_context"
+		return result
+	}
+
+	verdict, err := j.Score(ctx, trace.Question, trace.Answer, sources)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Scores = verdict.Scores
+	result.Reason = verdict.Reason
+	if threshold := rubric.RegenerateBelow(); threshold > 0 {
+		result.BelowRegenerate = verdict.Faithfulness < threshold
+	}
+	abstainBelow := rubric.AbstainBelow()
+	for _, name := range slices.Sorted(maps.Keys(abstainBelow)) {
+		if verdict.Scores[name] < abstainBelow[name] {
+			result.BelowAbstain = append(result.BelowAbstain, name)
+		}
+	}
+	return result
+}
+
+// summarizeRejudged averages the re-judged results.
+func summarizeRejudged(results []RejudgeResult) RejudgeSummary {
+	summary := RejudgeSummary{Traces: len(results), MeanScores: map[string]float64{}}
+	for _, result := range results {
+		switch {
+		case result.Skipped != "":
+			summary.Skipped++
+			continue
+		case result.Error != "":
+			summary.Errors++
+			continue
+		}
+		summary.Judged++
+		for name, score := range result.Scores {
+			summary.MeanScores[name] += float64(score)
+		}
+		if result.BelowRegenerate {
+			summary.BelowRegenerate++
+		}
+		if len(result.BelowAbstain) > 0 {
+			summary.BelowAbstain++
+		}
+	}
+	for name := range summary.MeanScores {
+		summary.MeanScores[name] /= float64(summary.Judged)
+	}
+	return summary
+}
+
+// WriteText prints the summary and the answers the rubric's thresholds would act on.
+func (r *RejudgeReport) WriteText(w io.Writer) error {
+	s := r.Summary
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "rubric\t%s\n", r.Rubric)
+	fmt.Fprintf(tw, "traces\t%d (%d judged, %d skipped, %d errors)\n", s.Traces, s.Judged, s.Skipped, s.Errors)
+	for _, name := range slices.Sorted(maps.Keys(s.MeanScores)) {
+		fmt.Fprintf(tw, "mean_%s\t%.3f\n", name, s.MeanScores[name])
+	}
+	fmt.Fprintf(tw, "below_regenerate\t%d\n", s.BelowRegenerate)
+	fmt.Fprintf(tw, "below_abstain\t%d\n", s.BelowAbstain)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var flagged []string
+	for _, result := range r.Results {
+		switch {
+		case result.Error != "":
+			flagged = append(flagged, fmt.Sprintf("%s: error: %s", result.ID, result.Error))
+		case len(result.BelowAbstain) > 0:
+			flagged = append(flagged, fmt.Sprintf("%s: would abstain (%s): %s", result.ID, strings.Join(result.BelowAbstain, ", "), result.Reason))
+		case result.BelowRegenerate:
+			flagged = append(flagged, fmt.Sprintf("%s: would regenerate: %s", result.ID, result.Reason))
+		}
+	}
+	if len(flagged) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nflagged answers (%d):\n", len(flagged)); err != nil {
+		return err
+	}
+	for _, line := range flagged {
+		if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
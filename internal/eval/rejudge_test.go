@@ -0,0 +1,105 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/judge"
+	"helloworld-ai/internal/llm"
+)
+
+// fakeJudgeChat replies to judge prompts by answer: the reply for the first key the prompt
+// contains, or an error.
+type fakeJudgeChat struct {
+	replies map[string]string
+	calls   int
+}
+
+func (f *fakeJudgeChat) ChatWithMessages(_ context.Context, messages []llm.Message, _ llm.ChatParams) (string, error) {
+	f.calls++
+	prompt := messages[len(messages)-1].Content
+	for answer, reply := range f.replies {
+		if strings.Contains(prompt, answer) {
+			return reply, nil
+		}
+	}
+	return "I cannot grade this.", nil
+}
+
+func TestRejudge(t *testing.T) {
+	results := strings.Join([]string{
+		`{"test_case_id": "good", "question": "Where do tomatoes grow?", "answer": "In the north bed.", "retrieved_chunks": [{"rel_path": "garden.md", "heading_path": "# Tomatoes", "text": "Tomatoes grow in the north bed."}]}`,
+		`{"test_case_id": "weak", "question": "When do I water?", "answer": "Every morning at six.", "retrieved_chunks": [{"rel_path": "garden.md", "heading_path": "# Tomatoes", "text": "Tomatoes grow in the north bed."}]}`,
+		`{"test_case_id": "abstained", "question": "Who is the mayor?", "answer": "I couldn't find it.", "retrieved_chunks": [], "abstention": {"abstained": true, "hallucinated": false}}`,
+		`{"test_case_id": "garbled", "question": "What is planted?", "answer": "Beans.", "retrieved_chunks": [{"rel_path": "garden.md", "heading_path": "# Beans", "text": "Beans by the fence."}]}`,
+		``,
+		`{"question": "What is in the shed?", "answer": "A rake.", "retrieved_chunks": [{"rel_path": "shed.md", "heading_path": "# Shed"}]}`,
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	if err := os.WriteFile(path, []byte(results), 0644); err != nil {
+		t.Fatalf("failed to write results: %v", err)
+	}
+	traces, err := LoadTraces(path)
+	if err != nil {
+		t.Fatalf("LoadTraces() error = %v", err)
+	}
+	if len(traces) != 5 || traces[4].ID != "line-6" {
+		t.Fatalf("LoadTraces() = %+v, want 5 traces with the unnamed one as line-6", traces)
+	}
+
+	rubric := judge.Rubric{
+		Version:  "garden-rubric-v2",
+		Scale:    judge.Scale{Min: 1, Max: 5},
+		Criteria: []judge.Criterion{{Name: judge.CriterionFaithfulness, Guide: "5 if supported"}, {Name: "completeness", Guide: "5 if complete"}},
+		Thresholds: judge.Thresholds{
+			Regenerate: 4,
+			Abstain:    map[string]float32{judge.CriterionFaithfulness: 2},
+		},
+	}
+	chat := &fakeJudgeChat{replies: map[string]string{
+		"In the north bed.":     `{"faithfulness": 5, "completeness": 4, "reason": "Supported."}`,
+		"Every morning at six.": `{"faithfulness": 1, "completeness": 3, "reason": "The schedule is not in the notes."}`,
+	}}
+	report, err := Rejudge(context.Background(), judge.New(chat, rubric), traces)
+	if err != nil {
+		t.Fatalf("Rejudge() error = %v", err)
+	}
+	if chat.calls != 3 {
+		t.Errorf("judge calls = %d, want 3 (abstained and context-free traces skipped)", chat.calls)
+	}
+
+	s := report.Summary
+	if report.Rubric != "garden-rubric-v2" || s.Traces != 5 || s.Judged != 2 || s.Skipped != 2 || s.Errors != 1 || s.BelowRegenerate != 1 || s.BelowAbstain != 1 {
+		t.Errorf("summary = %+v, want 2 judged, 2 skipped, 1 error, and the weak answer flagged", s)
+	}
+	if s.MeanScores[judge.CriterionFaithfulness] != 0.5 || s.MeanScores["completeness"] != 0.625 {
+		t.Errorf("mean scores = %v, want faithfulness 0.5 and completeness 0.625", s.MeanScores)
+	}
+	weak := report.Results[1]
+	if !weak.BelowRegenerate || len(weak.BelowAbstain) != 1 || weak.BelowAbstain[0] != judge.CriterionFaithfulness {
+		t.Errorf("weak result = %+v, want it below the regenerate and faithfulness abstain thresholds", weak)
+	}
+	if report.Results[2].Skipped != "abstained" || report.Results[4].Skipped != "no_context" || report.Results[3].Error == "" {
+		t.Errorf("results = %+v, want skipped and failed traces recorded", report.Results)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteText(&out); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"garden-rubric-v2", "mean_completeness", "weak: would abstain (faithfulness): The schedule is not in the notes.", "garbled: error:"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Rejudge(ctx, judge.New(chat, rubric), traces); err == nil {
+		t.Error("Rejudge() error = nil after cancellation")
+	}
+}
@@ -0,0 +1,258 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+)
+
+// Asker answers questions; rag.Engine satisfies it.
+type Asker interface {
+	Ask(ctx context.Context, req rag.AskRequest) (rag.AskResponse, error)
+}
+
+// Options controls an evaluation run.
+type Options struct {
+	// K is sent as the request's chunk count and cuts off the retrieval metrics (recall@K,
+	// MRR). Zero leaves K to the engine's auto-selection and scores every retrieved chunk.
+	K int
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	ID         string `json:"id"`
+	Category   string `json:"category,omitempty"`
+	Question   string `json:"question"`
+	Answerable bool   `json:"answerable"`
+	// Error is set when the engine failed; the case is left out of every metric.
+	Error string `json:"error,omitempty"`
+	// Abstained is the engine's explicit abstention flag.
+	Abstained bool `json:"abstained"`
+	// AbstentionCorrect is true when the engine abstained exactly on unanswerable questions.
+	AbstentionCorrect bool `json:"abstention_correct"`
+	// Scored is true for answerable cases with gold supports, the only ones with retrieval metrics.
+	Scored bool `json:"scored"`
+	// RetrievedChunks is the number of chunks scored (at most K).
+	RetrievedChunks int `json:"retrieved_chunks"`
+	// FirstMatchRank is the rank of the first chunk matching a gold support (0: none).
+	FirstMatchRank int  `json:"first_match_rank"`
+	RecallAny      bool `json:"recall_any"`
+	// RecallAll requires every support of a required support group (recall_any without groups).
+	RecallAll      bool    `json:"recall_all"`
+	ReciprocalRank float64 `json:"reciprocal_rank"`
+	// CitationPrecision is the fraction of references matching a gold support (nil without references).
+	CitationPrecision *float64 `json:"citation_precision,omitempty"`
+	LatencyMs         int64    `json:"latency_ms"`
+}
+
+// Summary aggregates a run's metrics. Averages are over the cases each metric applies to.
+type Summary struct {
+	Cases  int `json:"cases"`
+	Errors int `json:"errors"`
+	// RetrievalCases is the number of scored cases behind RecallAny, RecallAll, and MRR.
+	RetrievalCases int     `json:"retrieval_cases"`
+	RecallAny      float64 `json:"recall_any"`
+	RecallAll      float64 `json:"recall_all"`
+	MRR            float64 `json:"mrr"`
+	// CitationCases is the number of scored cases with references behind CitationPrecision.
+	CitationCases     int     `json:"citation_cases"`
+	CitationPrecision float64 `json:"citation_precision"`
+	// AbstentionAccuracy is the fraction of cases (answerable or not) with the correct abstention decision.
+	AbstentionAccuracy float64 `json:"abstention_accuracy"`
+	// UnanswerableCases is the number of unanswerable cases behind UnanswerableAbstained.
+	UnanswerableCases     int     `json:"unanswerable_cases"`
+	UnanswerableAbstained float64 `json:"unanswerable_abstained"`
+}
+
+// Report is the result of an evaluation run.
+type Report struct {
+	CreatedAt time.Time    `json:"created_at"`
+	K         int          `json:"k"`
+	Summary   Summary      `json:"summary"`
+	Cases     []CaseResult `json:"cases"`
+}
+
+// Runner runs golden dataset cases through a RAG engine and scores them.
+type Runner struct {
+	engine Asker
+	opts   Options
+}
+
+// NewRunner creates a runner asking engine.
+func NewRunner(engine Asker, opts Options) *Runner {
+	return &Runner{engine: engine, opts: opts}
+}
+
+// Run asks every case in debug mode, bypassing the answer cache, and scores the answers.
+// Engine errors are recorded on the case; only cancellation of ctx stops the run.
+func (r *Runner) Run(ctx context.Context, cases []Case) (*Report, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+	report := &Report{CreatedAt: time.Now().UTC(), K: r.opts.K, Cases: make([]CaseResult, 0, len(cases))}
+
+	for i, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := r.runCase(ctx, c)
+		logger.InfoContext(ctx, "eval case finished",
+			"case", c.ID,
+			"progress", fmt.Sprintf("%d/%d", i+1, len(cases)),
+			"recall_any", result.RecallAny,
+			"abstention_correct", result.AbstentionCorrect,
+			"error", result.Error,
+		)
+		report.Cases = append(report.Cases, result)
+	}
+	report.Summary = summarize(report.Cases)
+	return report, nil
+}
+
+// runCase asks one case and scores the response.
+func (r *Runner) runCase(ctx context.Context, c Case) CaseResult {
+	result := CaseResult{ID: c.ID, Category: c.Category, Question: c.Question, Answerable: c.Answerable}
+
+	start := time.Now()
+	resp, err := r.engine.Ask(ctx, rag.AskRequest{
+		Question: c.Question,
+		Vaults:   c.Vaults,
+		Folders:  c.Folders,
+		K:        r.opts.K,
+		Debug:    true,
+		NoCache:  true,
+	})
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Abstained = resp.Abstained
+	result.AbstentionCorrect = resp.Abstained != c.Answerable
+	if !c.Answerable || len(c.GoldSupports) == 0 {
+		return result
+	}
+
+	var chunks []rag.RetrievedChunk
+	if resp.Debug != nil {
+		chunks = resp.Debug.RetrievedChunks
+	}
+	if r.opts.K > 0 && len(chunks) > r.opts.K {
+		chunks = chunks[:r.opts.K]
+	}
+	result.Scored = true
+	result.RetrievedChunks = len(chunks)
+	result.FirstMatchRank = firstMatchRank(chunks, c.GoldSupports)
+	result.RecallAny = result.FirstMatchRank > 0
+	if result.RecallAny {
+		result.ReciprocalRank = 1 / float64(result.FirstMatchRank)
+	}
+	result.RecallAll = recallAll(chunks, c.GoldSupports, c.RequiredSupportGroups)
+	if precision, ok := citationPrecision(resp.References, c.GoldSupports); ok {
+		result.CitationPrecision = &precision
+	}
+	return result
+}
+
+// summarize averages the case results.
+func summarize(results []CaseResult) Summary {
+	summary := Summary{Cases: len(results)}
+	var recallAny, recallAll, rr, precision, abstentionCorrect, unanswerableAbstained float64
+	for _, result := range results {
+		if result.Error != "" {
+			summary.Errors++
+			continue
+		}
+		if result.AbstentionCorrect {
+			abstentionCorrect++
+		}
+		if !result.Answerable {
+			summary.UnanswerableCases++
+			if result.Abstained {
+				unanswerableAbstained++
+			}
+		}
+		if !result.Scored {
+			continue
+		}
+		summary.RetrievalCases++
+		if result.RecallAny {
+			recallAny++
+		}
+		if result.RecallAll {
+			recallAll++
+		}
+		rr += result.ReciprocalRank
+		if result.CitationPrecision != nil {
+			summary.CitationCases++
+			precision += *result.CitationPrecision
+		}
+	}
+
+	if answered := summary.Cases - summary.Errors; answered > 0 {
+		summary.AbstentionAccuracy = abstentionCorrect / float64(answered)
+	}
+	if summary.UnanswerableCases > 0 {
+		summary.UnanswerableAbstained = unanswerableAbstained / float64(summary.UnanswerableCases)
+	}
+	if summary.RetrievalCases > 0 {
+		n := float64(summary.RetrievalCases)
+		summary.RecallAny = recallAny / n
+		summary.RecallAll = recallAll / n
+		summary.MRR = rr / n
+	}
+	if summary.CitationCases > 0 {
+		summary.CitationPrecision = precision / float64(summary.CitationCases)
+	}
+	return summary
+}
+
+// WriteText writes the summary and the cases that missed, abstained wrongly, or failed.
+func (r *Report) WriteText(w io.Writer) error {
+	k := "auto"
+	if r.K > 0 {
+		k = fmt.Sprint(r.K)
+	}
+	s := r.Summary
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "cases\t%d (%d errors)\n", s.Cases, s.Errors)
+	fmt.Fprintf(tw, "recall_any@%s\t%.3f\t(%d cases)\n", k, s.RecallAny, s.RetrievalCases)
+	fmt.Fprintf(tw, "recall_all@%s\t%.3f\n", k, s.RecallAll)
+	fmt.Fprintf(tw, "mrr@%s\t%.3f\n", k, s.MRR)
+	fmt.Fprintf(tw, "citation_precision\t%.3f\t(%d cases)\n", s.CitationPrecision, s.CitationCases)
+	fmt.Fprintf(tw, "abstention_accuracy\t%.3f\n", s.AbstentionAccuracy)
+	fmt.Fprintf(tw, "unanswerable_abstained\t%.3f\t(%d cases)\n", s.UnanswerableAbstained, s.UnanswerableCases)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, result := range r.Cases {
+		switch {
+		case result.Error != "":
+			failures = append(failures, fmt.Sprintf("%s: error: %s", result.ID, result.Error))
+		case !result.AbstentionCorrect && result.Abstained:
+			failures = append(failures, fmt.Sprintf("%s: abstained on an answerable question", result.ID))
+		case !result.AbstentionCorrect:
+			failures = append(failures, fmt.Sprintf("%s: answered an unanswerable question", result.ID))
+		case result.Scored && !result.RecallAny:
+			failures = append(failures, fmt.Sprintf("%s: no gold support in %d retrieved chunks", result.ID, result.RetrievedChunks))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\nfailing cases (%d):\n", len(failures)); err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		if _, err := fmt.Fprintf(w, "  %s\n", failure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
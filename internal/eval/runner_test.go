@@ -0,0 +1,159 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/rag"
+)
+
+// fakeAsker answers each question with a fixed response, or fails questions it has none for.
+type fakeAsker struct {
+	responses map[string]rag.AskResponse
+	requests  []rag.AskRequest
+}
+
+func (f *fakeAsker) Ask(_ context.Context, req rag.AskRequest) (rag.AskResponse, error) {
+	f.requests = append(f.requests, req)
+	resp, ok := f.responses[req.Question]
+	if !ok {
+		return rag.AskResponse{}, errors.New("llm unavailable")
+	}
+	return resp, nil
+}
+
+func TestRunner_Run(t *testing.T) {
+	cases := []Case{
+		{ID: "hit", Question: "q1", Answerable: true, GoldSupports: []GoldSupport{{RelPath: "a.md", HeadingPath: "# A"}}},
+		{ID: "second", Question: "q2", Answerable: true, GoldSupports: []GoldSupport{{RelPath: "b.md", HeadingPath: "# B"}}},
+		{ID: "unanswerable", Question: "q3", Answerable: false},
+		{ID: "broken", Question: "q4", Answerable: true},
+	}
+	asker := &fakeAsker{responses: map[string]rag.AskResponse{
+		"q1": {
+			References: []rag.Reference{{RelPath: "a.md", HeadingPath: "# A"}},
+			Debug:      &rag.DebugInfo{RetrievedChunks: []rag.RetrievedChunk{{RelPath: "a.md", HeadingPath: "# A"}, {RelPath: "x.md"}}},
+		},
+		"q2": {
+			References: []rag.Reference{{RelPath: "x.md"}, {RelPath: "b.md", HeadingPath: "# B"}},
+			Debug:      &rag.DebugInfo{RetrievedChunks: []rag.RetrievedChunk{{RelPath: "x.md"}, {RelPath: "b.md", HeadingPath: "# B"}, {RelPath: "y.md"}}},
+		},
+		"q3": {Abstained: true},
+	}}
+
+	report, err := NewRunner(asker, Options{K: 2}).Run(context.Background(), cases)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for _, req := range asker.requests {
+		if !req.Debug || !req.NoCache || req.K != 2 {
+			t.Errorf("request = %+v, want debug, no_cache, and k=2", req)
+		}
+	}
+
+	s := report.Summary
+	if s.Cases != 4 || s.Errors != 1 || s.RetrievalCases != 2 {
+		t.Errorf("summary counts = %+v, want 4 cases, 1 error, 2 retrieval cases", s)
+	}
+	if s.RecallAny != 1 || s.RecallAll != 1 {
+		t.Errorf("recall = %v/%v, want 1/1", s.RecallAny, s.RecallAll)
+	}
+	if math.Abs(s.MRR-0.75) > 1e-9 {
+		t.Errorf("MRR = %v, want 0.75", s.MRR)
+	}
+	if math.Abs(s.CitationPrecision-0.75) > 1e-9 || s.CitationCases != 2 {
+		t.Errorf("citation precision = %v over %d cases, want 0.75 over 2", s.CitationPrecision, s.CitationCases)
+	}
+	if s.AbstentionAccuracy != 1 || s.UnanswerableCases != 1 || s.UnanswerableAbstained != 1 {
+		t.Errorf("abstention = %+v, want all correct", s)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "recall_any@2") || !strings.Contains(buf.String(), "broken: error: llm unavailable") {
+		t.Errorf("WriteText() = %q, want the summary and the failing case", buf.String())
+	}
+}
+
+func TestRunner_K(t *testing.T) {
+	cases := []Case{{ID: "deep", Question: "q", Answerable: true, GoldSupports: []GoldSupport{{RelPath: "b.md"}}}}
+	asker := &fakeAsker{responses: map[string]rag.AskResponse{
+		"q": {Debug: &rag.DebugInfo{RetrievedChunks: []rag.RetrievedChunk{{RelPath: "a.md"}, {RelPath: "b.md"}}}},
+	}}
+
+	report, err := NewRunner(asker, Options{K: 1}).Run(context.Background(), cases)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Cases[0].RecallAny {
+		t.Error("a gold support below K should not count toward recall@K")
+	}
+
+	report, err = NewRunner(asker, Options{}).Run(context.Background(), cases)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := report.Cases[0].FirstMatchRank; got != 2 {
+		t.Errorf("FirstMatchRank = %d with auto-K, want 2", got)
+	}
+}
+
+func TestLoadCases(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonl := filepath.Join(dir, "set.jsonl")
+	content := `{"id": "a", "question": "Q1?", "answerable": true, "gold_supports": [{"rel_path": "x.md", "heading_path": "# X"}], "required_support_groups": null}
+
+{"question": "Q2?", "answerable": false}
+`
+	if err := os.WriteFile(jsonl, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write dataset: %v", err)
+	}
+	cases, err := LoadCases(jsonl)
+	if err != nil {
+		t.Fatalf("LoadCases(jsonl) error = %v", err)
+	}
+	if len(cases) != 2 || cases[0].GoldSupports[0].RelPath != "x.md" || cases[1].ID != "case_002" {
+		t.Errorf("LoadCases(jsonl) = %+v, want 2 cases with a generated ID for the second", cases)
+	}
+
+	jsonPath := filepath.Join(dir, "set.json")
+	if err := os.WriteFile(jsonPath, []byte(`[{"id": "a", "question": "Q?"}]`), 0644); err != nil {
+		t.Fatalf("failed to write dataset: %v", err)
+	}
+	if cases, err := LoadCases(jsonPath); err != nil || len(cases) != 1 {
+		t.Errorf("LoadCases(json) = %+v, %v, want 1 case", cases, err)
+	}
+
+	for name, content := range map[string]string{
+		"set.yaml":    "- question: Q?",
+		"empty.jsonl": `{"id": "a", "question": " "}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write dataset: %v", err)
+		}
+		if _, err := LoadCases(path); err == nil {
+			t.Errorf("LoadCases(%s) error = nil, want an error", name)
+		}
+	}
+}
+
+func TestLoadCases_RepoDataset(t *testing.T) {
+	cases, err := LoadCases(filepath.Join("..", "..", "eval", "eval_set.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadCases() error = %v", err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("LoadCases() returned no cases from eval/eval_set.jsonl")
+	}
+}
@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"unicode/utf8"
+
+	"helloworld-ai/internal/storage"
+)
+
+// Sampling strategies for SampleChunks.
+const (
+	// SampleRandom draws chunks uniformly, so large folders dominate the sample like they
+	// dominate retrieval.
+	SampleRandom = "random"
+	// SampleStratified draws chunks round-robin over vault/folder/length strata, so small
+	// folders and short or long chunks are labeled too.
+	SampleStratified = "stratified"
+)
+
+// Chunk length buckets used as strata, in runes.
+const (
+	LengthShort  = "short"
+	LengthMedium = "medium"
+	LengthLong   = "long"
+
+	shortChunkRunes  = 300
+	mediumChunkRunes = 700
+)
+
+// Sample is a chunk drawn for relevance labeling.
+type Sample struct {
+	storage.ChunkExportRecord
+	// Runes is the chunk text length in runes.
+	Runes int
+	// Length is the length bucket: LengthShort, LengthMedium, or LengthLong.
+	Length string
+	// Stratum is "<vault>/<folder>/<length>", the group the chunk was drawn from.
+	Stratum string
+}
+
+// Case returns a golden dataset case whose gold support is the sampled chunk's section, for
+// the labeler to complete with a question the chunk answers.
+func (s Sample) Case() Case {
+	return Case{
+		ID:         "sample_" + s.ID,
+		Answerable: true,
+		GoldSupports: []GoldSupport{{
+			RelPath:     s.RelPath,
+			HeadingPath: s.HeadingPath,
+		}},
+		Vaults: []string{s.VaultName},
+	}
+}
+
+// ValidSampleStrategy reports whether strategy is SampleRandom or SampleStratified.
+func ValidSampleStrategy(strategy string) bool {
+	return strategy == SampleRandom || strategy == SampleStratified
+}
+
+// SampleChunks draws up to n chunks with the given strategy. The same chunks, seed, and
+// strategy always give the same sample, so a labeling session can be reproduced and
+// extended; samples are ordered by vault, path, and chunk index for reading.
+func SampleChunks(chunks []storage.ChunkExportRecord, n int, strategy string, seed uint64) ([]Sample, error) {
+	if !ValidSampleStrategy(strategy) {
+		return nil, fmt.Errorf("unknown sample strategy %q: use %s or %s", strategy, SampleRandom, SampleStratified)
+	}
+	if n <= 0 || len(chunks) == 0 {
+		return []Sample{}, nil
+	}
+
+	samples := make([]Sample, 0, len(chunks))
+	for _, chunk := range chunks {
+		samples = append(samples, newSample(chunk))
+	}
+	// The draw must not depend on the order the chunks were listed in
+	sort.Slice(samples, func(i, j int) bool { return samples[i].ID < samples[j].ID })
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	var drawn []Sample
+	if strategy == SampleRandom {
+		rng.Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
+		drawn = samples[:min(n, len(samples))]
+	} else {
+		drawn = drawStratified(samples, n, rng)
+	}
+
+	sort.Slice(drawn, func(i, j int) bool {
+		if drawn[i].VaultName != drawn[j].VaultName {
+			return drawn[i].VaultName < drawn[j].VaultName
+		}
+		if drawn[i].RelPath != drawn[j].RelPath {
+			return drawn[i].RelPath < drawn[j].RelPath
+		}
+		return drawn[i].ChunkIndex < drawn[j].ChunkIndex
+	})
+	return drawn, nil
+}
+
+// drawStratified shuffles each stratum and the order of the strata, then takes one chunk
+// from each stratum in turn until n are drawn or every stratum is exhausted.
+func drawStratified(samples []Sample, n int, rng *rand.Rand) []Sample {
+	strata := make(map[string][]Sample)
+	var keys []string
+	for _, sample := range samples {
+		if _, ok := strata[sample.Stratum]; !ok {
+			keys = append(keys, sample.Stratum)
+		}
+		strata[sample.Stratum] = append(strata[sample.Stratum], sample)
+	}
+	sort.Strings(keys)
+	rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	for _, key := range keys {
+		stratum := strata[key]
+		rng.Shuffle(len(stratum), func(i, j int) { stratum[i], stratum[j] = stratum[j], stratum[i] })
+	}
+
+	drawn := make([]Sample, 0, min(n, len(samples)))
+	for round := 0; len(drawn) < n; round++ {
+		added := false
+		for _, key := range keys {
+			if round < len(strata[key]) && len(drawn) < n {
+				drawn = append(drawn, strata[key][round])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return drawn
+}
+
+// newSample measures a chunk and assigns its stratum.
+func newSample(chunk storage.ChunkExportRecord) Sample {
+	runes := utf8.RuneCountInString(chunk.Text)
+	length := LengthLong
+	switch {
+	case runes < shortChunkRunes:
+		length = LengthShort
+	case runes < mediumChunkRunes:
+		length = LengthMedium
+	}
+	return Sample{
+		ChunkExportRecord: chunk,
+		Runes:             runes,
+		Length:            length,
+		Stratum:           chunk.VaultName + "/" + chunk.Folder + "/" + length,
+	}
+}
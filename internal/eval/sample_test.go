@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+// sampleCorpus returns 40 short chunks in a large folder and one chunk each in a small
+// folder and a long note, listed in the given order.
+func sampleCorpus(reverse bool) []storage.ChunkExportRecord {
+	var chunks []storage.ChunkExportRecord
+	for i := range 40 {
+		chunks = append(chunks, sampleChunk("personal", "journal", fmt.Sprintf("journal/day%02d.md", i), "Short entry."))
+	}
+	chunks = append(chunks,
+		sampleChunk("personal", "recipes", "recipes/bread.md", "Knead for ten minutes."),
+		sampleChunk("work", "", "plan.md", strings.Repeat("long plan text ", 60)),
+	)
+	if reverse {
+		for i, j := 0, len(chunks)-1; i < j; i, j = i+1, j-1 {
+			chunks[i], chunks[j] = chunks[j], chunks[i]
+		}
+	}
+	return chunks
+}
+
+func sampleChunk(vaultName, folder, relPath, text string) storage.ChunkExportRecord {
+	return storage.ChunkExportRecord{
+		ChunkRecord: storage.ChunkRecord{ID: "chunk-" + relPath, HeadingPath: "# " + relPath, Text: text},
+		VaultName:   vaultName,
+		Folder:      folder,
+		RelPath:     relPath,
+	}
+}
+
+func sampleIDs(samples []Sample) []string {
+	ids := make([]string, 0, len(samples))
+	for _, sample := range samples {
+		ids = append(ids, sample.ID)
+	}
+	return ids
+}
+
+func TestSampleChunks_Stratified(t *testing.T) {
+	samples, err := SampleChunks(sampleCorpus(false), 3, SampleStratified, 7)
+	if err != nil {
+		t.Fatalf("SampleChunks() error = %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("SampleChunks() returned %d samples, want 3", len(samples))
+	}
+
+	// One chunk from each stratum, however small
+	strata := map[string]bool{}
+	for _, sample := range samples {
+		strata[sample.Stratum] = true
+	}
+	want := map[string]bool{"personal/journal/short": true, "personal/recipes/short": true, "work//long": true}
+	if !reflect.DeepEqual(strata, want) {
+		t.Errorf("SampleChunks() strata = %v, want %v", strata, want)
+	}
+	if last := samples[2]; last.VaultName != "work" || last.Length != LengthLong || last.Runes != 900 {
+		t.Errorf("samples[2] = %s %s (%d runes), want the long work chunk last", last.VaultName, last.Length, last.Runes)
+	}
+}
+
+func TestSampleChunks_Reproducible(t *testing.T) {
+	for _, strategy := range []string{SampleRandom, SampleStratified} {
+		first, err := SampleChunks(sampleCorpus(false), 10, strategy, 42)
+		if err != nil {
+			t.Fatalf("SampleChunks(%s) error = %v", strategy, err)
+		}
+		again, _ := SampleChunks(sampleCorpus(true), 10, strategy, 42)
+		if !reflect.DeepEqual(sampleIDs(first), sampleIDs(again)) {
+			t.Errorf("SampleChunks(%s) with the same seed = %v, then %v", strategy, sampleIDs(first), sampleIDs(again))
+		}
+		other, _ := SampleChunks(sampleCorpus(false), 10, strategy, 43)
+		if reflect.DeepEqual(sampleIDs(first), sampleIDs(other)) {
+			t.Errorf("SampleChunks(%s) gave the same sample for different seeds", strategy)
+		}
+	}
+}
+
+func TestSampleChunks_Limits(t *testing.T) {
+	samples, err := SampleChunks(sampleCorpus(false), 100, SampleStratified, 1)
+	if err != nil {
+		t.Fatalf("SampleChunks() error = %v", err)
+	}
+	if len(samples) != 42 {
+		t.Errorf("SampleChunks(100) returned %d samples, want all 42 chunks", len(samples))
+	}
+
+	if samples, _ := SampleChunks(nil, 5, SampleRandom, 1); samples == nil || len(samples) != 0 {
+		t.Errorf("SampleChunks(no chunks) = %v, want an empty sample", samples)
+	}
+	if _, err := SampleChunks(sampleCorpus(false), 5, "weighted", 1); err == nil {
+		t.Error("SampleChunks() expected error for an unknown strategy")
+	}
+}
+
+func TestSample_Case(t *testing.T) {
+	sample := newSample(sampleChunk("work", "", "plan.md", "Ship in May."))
+	want := Case{
+		ID:           "sample_chunk-plan.md",
+		Answerable:   true,
+		GoldSupports: []GoldSupport{{RelPath: "plan.md", HeadingPath: "# plan.md"}},
+		Vaults:       []string{"work"},
+	}
+	if got := sample.Case(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Case() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// embeddingBatchSize is the number of points fetched from the vector store per request.
+const embeddingBatchSize = 256
+
+// Options controls what an export contains.
+type Options struct {
+	// Filter limits the export to a vault and/or folder.
+	Filter storage.ChunkExportFilter
+	// IncludeEmbeddings adds each chunk's vector from the vector store.
+	IncludeEmbeddings bool
+}
+
+// Record is one line of the JSONL corpus.
+type Record struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Metadata  Metadata  `json:"metadata"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Metadata describes where a chunk came from.
+type Metadata struct {
+	VaultID     int    `json:"vault_id"`
+	VaultName   string `json:"vault_name"`
+	NoteID      string `json:"note_id"`
+	RelPath     string `json:"rel_path"`
+	Folder      string `json:"folder"`
+	NoteTitle   string `json:"note_title,omitempty"`
+	HeadingPath string `json:"heading_path"`
+	ChunkIndex  int    `json:"chunk_index"`
+	Tier        string `json:"tier"`
+}
+
+// Exporter writes chunks from SQLite (and optionally their vectors) as JSONL.
+type Exporter struct {
+	chunkRepo      storage.ChunkStore
+	vectorStore    vectorstore.VectorStore
+	collection     string
+	coldCollection string
+	// collectionPerVault reads hot vectors from each vault's own collection (see SetCollectionPerVault).
+	collectionPerVault bool
+}
+
+// NewExporter creates a new Exporter.
+// vectorStore is only used when embeddings are requested and may be nil otherwise.
+// coldCollection holds the vectors of cold-tier notes (empty if cold storage is disabled).
+func NewExporter(chunkRepo storage.ChunkStore, vectorStore vectorstore.VectorStore, collection, coldCollection string) *Exporter {
+	return &Exporter{
+		chunkRepo:      chunkRepo,
+		vectorStore:    vectorStore,
+		collection:     collection,
+		coldCollection: coldCollection,
+	}
+}
+
+// SetCollectionPerVault reads the vectors of hot chunks from their vault's own collection
+// (vectorstore.VaultCollection) instead of the shared one, matching QDRANT_COLLECTION_PER_VAULT.
+func (e *Exporter) SetCollectionPerVault(enabled bool) {
+	e.collectionPerVault = enabled
+}
+
+// Export writes one JSON record per chunk to w and returns the number of records written.
+// Chunks whose vector cannot be found are still exported, without an embedding.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, opts Options) (int, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	chunks, err := e.chunkRepo.ListForExport(ctx, opts.Filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	var embeddings map[string][]float32
+	if opts.IncludeEmbeddings {
+		if e.vectorStore == nil {
+			return 0, fmt.Errorf("embeddings requested but no vector store configured")
+		}
+		embeddings, err = e.fetchEmbeddings(ctx, chunks)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	missing := 0
+	for _, chunk := range chunks {
+		record := Record{
+			ID:   chunk.ID,
+			Text: chunk.Text,
+			Metadata: Metadata{
+				VaultID:     chunk.VaultID,
+				VaultName:   chunk.VaultName,
+				NoteID:      chunk.NoteID,
+				RelPath:     chunk.RelPath,
+				Folder:      chunk.Folder,
+				NoteTitle:   chunk.NoteTitle,
+				HeadingPath: chunk.HeadingPath,
+				ChunkIndex:  chunk.ChunkIndex,
+				Tier:        chunk.Tier,
+			},
+		}
+		if opts.IncludeEmbeddings {
+			record.Embedding = embeddings[chunk.ID]
+			if record.Embedding == nil {
+				missing++
+			}
+		}
+		if err := encoder.Encode(record); err != nil {
+			return 0, fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	if missing > 0 {
+		logger.WarnContext(ctx, "some chunks have no vector in the vector store", "missing", missing)
+	}
+	logger.InfoContext(ctx, "exported corpus",
+		"chunks", len(chunks),
+		"vault", opts.Filter.VaultName,
+		"folder", opts.Filter.Folder,
+		"embeddings", opts.IncludeEmbeddings,
+	)
+
+	return len(chunks), nil
+}
+
+// fetchEmbeddings retrieves vectors for chunks in batches, reading cold-tier chunks from the cold collection.
+func (e *Exporter) fetchEmbeddings(ctx context.Context, chunks []storage.ChunkExportRecord) (map[string][]float32, error) {
+	idsByCollection := make(map[string][]string)
+	for _, chunk := range chunks {
+		collection := e.collection
+		switch {
+		case chunk.Tier == storage.TierCold && e.coldCollection != "":
+			collection = e.coldCollection
+		case e.collectionPerVault:
+			collection = vectorstore.VaultCollection(e.collection, chunk.VaultID)
+		}
+		idsByCollection[collection] = append(idsByCollection[collection], chunk.ID)
+	}
+
+	embeddings := make(map[string][]float32, len(chunks))
+	for collection, ids := range idsByCollection {
+		for start := 0; start < len(ids); start += embeddingBatchSize {
+			end := start + embeddingBatchSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			points, err := e.vectorStore.Retrieve(ctx, collection, ids[start:end])
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve embeddings from %s: %w", collection, err)
+			}
+			for _, point := range points {
+				embeddings[point.ID] = point.Vec
+			}
+		}
+	}
+	return embeddings, nil
+}
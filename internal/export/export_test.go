@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestExporter_Export(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultRepo := storage.NewVaultRepo(db)
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+
+	personal, err := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	work, err := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	notes := []struct {
+		vaultID int
+		relPath string
+		folder  string
+		chunkID string
+	}{
+		{personal.ID, "projects/go/plan.md", "projects/go", "c1"},
+		{personal.ID, "projectsarchive/old.md", "projectsarchive", "c2"},
+		{personal.ID, "journal.md", "", "c3"},
+		{work.ID, "projects/roadmap.md", "projects", "c4"},
+	}
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	for _, n := range notes {
+		note := &storage.NoteRecord{VaultID: n.vaultID, RelPath: n.relPath, Folder: n.folder, Title: "Title", Hash: "hash"}
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		if err := chunkRepo.Insert(ctx, &storage.ChunkRecord{ID: n.chunkID, NoteID: note.ID, HeadingPath: "# Title", Text: "text " + n.chunkID}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+	// c3 has no vector, so its embedding is omitted
+	points := []vectorstore.Point{
+		{ID: "c1", Vec: []float32{1, 0}},
+		{ID: "c2", Vec: []float32{0, 1}},
+		{ID: "c4", Vec: []float32{1, 1}},
+	}
+	if err := store.Upsert(ctx, "notes", points); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	exporter := NewExporter(chunkRepo, store, "notes", "")
+
+	tests := []struct {
+		name           string
+		opts           Options
+		wantIDs        []string
+		wantEmbeddings map[string]bool
+	}{
+		{
+			name:    "all chunks",
+			opts:    Options{},
+			wantIDs: []string{"c3", "c1", "c2", "c4"},
+		},
+		{
+			name:    "vault filter",
+			opts:    Options{Filter: storage.ChunkExportFilter{VaultName: "work"}},
+			wantIDs: []string{"c4"},
+		},
+		{
+			name:    "folder filter matches subfolders but not siblings",
+			opts:    Options{Filter: storage.ChunkExportFilter{VaultName: "personal", Folder: "projects/"}},
+			wantIDs: []string{"c1"},
+		},
+		{
+			name:           "embeddings included",
+			opts:           Options{Filter: storage.ChunkExportFilter{VaultName: "personal"}, IncludeEmbeddings: true},
+			wantIDs:        []string{"c3", "c1", "c2"},
+			wantEmbeddings: map[string]bool{"c1": true, "c2": true, "c3": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			count, err := exporter.Export(ctx, &buf, tt.opts)
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if count != len(tt.wantIDs) {
+				t.Errorf("Export() count = %d, want %d", count, len(tt.wantIDs))
+			}
+
+			var records []Record
+			scanner := bufio.NewScanner(&buf)
+			for scanner.Scan() {
+				var record Record
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatalf("failed to decode line %q: %v", scanner.Text(), err)
+				}
+				records = append(records, record)
+			}
+			if len(records) != len(tt.wantIDs) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if records[i].ID != id {
+					t.Errorf("record[%d].ID = %s, want %s", i, records[i].ID, id)
+				}
+				if records[i].Text != "text "+id {
+					t.Errorf("record[%d].Text = %q, want %q", i, records[i].Text, "text "+id)
+				}
+				if want := tt.wantEmbeddings[id]; want != (records[i].Embedding != nil) {
+					t.Errorf("record[%d] has embedding = %v, want %v", i, records[i].Embedding != nil, want)
+				}
+			}
+		})
+	}
+
+	// With per-vault collections, vectors are read from the chunk's vault collection
+	workCollection := vectorstore.VaultCollection("notes", work.ID)
+	if err := store.EnsureCollection(ctx, workCollection, 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	if err := store.Upsert(ctx, workCollection, []vectorstore.Point{{ID: "c4", Vec: []float32{0, 2}}}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	exporter.SetCollectionPerVault(true)
+	var buf bytes.Buffer
+	if _, err := exporter.Export(ctx, &buf, Options{Filter: storage.ChunkExportFilter{VaultName: "work"}, IncludeEmbeddings: true}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var record Record
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record); err != nil {
+		t.Fatalf("failed to decode %q: %v", buf.String(), err)
+	}
+	if len(record.Embedding) != 2 || record.Embedding[1] != 2 {
+		t.Errorf("per-vault export embedding = %v, want the vector from %s", record.Embedding, workCollection)
+	}
+}
@@ -0,0 +1,219 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Flag names for experimental behaviors that can be toggled per deployment.
+const (
+	// Reranker blends lexical scores into vector scores before selecting chunks.
+	// When off, chunks are ranked by vector score alone.
+	Reranker = "reranker"
+	// RetrievalExpansion runs a second, broader retrieval pass after a weak first pass.
+	RetrievalExpansion = "retrieval_expansion"
+	// NotePrefilter restricts the chunk search to the top notes by centroid similarity
+	// (only takes effect when NOTE_PREFILTER_TOP_M is set).
+	NotePrefilter = "note_prefilter"
+	// AnswerTools injects date, calculator, and unit conversion results into the prompt.
+	AnswerTools = "answer_tools"
+	// HeadingMatch ranks all chunks under a heading that the question names first.
+	HeadingMatch = "heading_match"
+	// FollowUps suggests follow-up questions with each answer (one extra LLM call per question).
+	FollowUps = "follow_ups"
+	// ConflictDetection flags dates and numbers that differ across notes in the prompt.
+	ConflictDetection = "conflict_detection"
+	// RetrievalOptions lets ask requests override retrieval weights and candidate counts.
+	RetrievalOptions = "retrieval_options"
+)
+
+// ErrUnknownFlag is returned for flag names that are not registered.
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// definition describes a known flag and its built-in default.
+type definition struct {
+	name        string
+	description string
+	enabled     bool
+}
+
+// definitions lists every known flag. New experimental behaviors register here.
+var definitions = []definition{
+	{name: Reranker, description: "Blend lexical scores into vector scores when ranking chunks", enabled: true},
+	{name: RetrievalExpansion, description: "Retry weak retrievals with a larger K and relaxed folder scope", enabled: true},
+	{name: NotePrefilter, description: "Search chunks only within the top notes by centroid similarity", enabled: true},
+	{name: AnswerTools, description: "Inject date, calculator, and unit conversion results into the prompt", enabled: true},
+	{name: HeadingMatch, description: "Rank all chunks under a heading the question names first", enabled: true},
+	{name: FollowUps, description: "Suggest follow-up questions grounded in the retrieved notes", enabled: false},
+	{name: ConflictDetection, description: "Ask the model to report dates and numbers that differ across notes", enabled: true},
+	{name: RetrievalOptions, description: "Let ask requests override retrieval weights and candidate counts", enabled: false},
+}
+
+// Sources of a flag's current value.
+const (
+	SourceDefault  = "default"
+	SourceConfig   = "config"
+	SourceOverride = "override"
+)
+
+// State is the current value of a flag and where it came from.
+type State struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Source      string `json:"source"` // default, config, or override
+}
+
+// Flags holds deployment flag values (built-in defaults plus config) and runtime overrides.
+// Overrides live in memory and are lost on restart. A nil *Flags reports built-in defaults.
+type Flags struct {
+	mu        sync.RWMutex
+	config    map[string]bool
+	overrides map[string]bool
+}
+
+// New creates Flags from the deployment configuration (flag name to enabled).
+// Returns an error if config names an unknown flag.
+func New(config map[string]bool) (*Flags, error) {
+	for name := range config {
+		if !IsKnown(name) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+	}
+
+	configCopy := make(map[string]bool, len(config))
+	for name, enabled := range config {
+		configCopy[name] = enabled
+	}
+	return &Flags{
+		config:    configCopy,
+		overrides: make(map[string]bool),
+	}, nil
+}
+
+// IsKnown reports whether name is a known flag.
+func IsKnown(name string) bool {
+	_, ok := lookup(name)
+	return ok
+}
+
+// Parse parses a comma-separated list of name=bool pairs, e.g. "reranker=false,answer_tools=true".
+func Parse(value string) (map[string]bool, error) {
+	result := make(map[string]bool)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, rawEnabled, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid feature flag %q: expected name=true|false", pair)
+		}
+		name = strings.TrimSpace(name)
+		if !IsKnown(name) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(rawEnabled))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature flag %q: %w", name, err)
+		}
+		result[name] = enabled
+	}
+	return result, nil
+}
+
+// Enabled reports whether a flag is on: runtime override first, then config, then the built-in default.
+// Unknown flags are off.
+func (f *Flags) Enabled(name string) bool {
+	return f.state(name).Enabled
+}
+
+// Set overrides a flag at runtime.
+func (f *Flags) Set(name string, enabled bool) error {
+	if !IsKnown(name) {
+		return fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+	if f == nil {
+		return fmt.Errorf("feature flags are not configured")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.overrides[name] = enabled
+	return nil
+}
+
+// Reset removes a runtime override so the flag returns to its deployment value.
+func (f *Flags) Reset(name string) error {
+	if !IsKnown(name) {
+		return fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+	if f == nil {
+		return fmt.Errorf("feature flags are not configured")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.overrides, name)
+	return nil
+}
+
+// States returns the current state of every known flag, ordered by name.
+func (f *Flags) States() []State {
+	states := make([]State, 0, len(definitions))
+	for _, def := range definitions {
+		states = append(states, f.state(def.name))
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// Snapshot returns the enabled value of every known flag.
+func (f *Flags) Snapshot() map[string]bool {
+	snapshot := make(map[string]bool, len(definitions))
+	for _, def := range definitions {
+		snapshot[def.name] = f.Enabled(def.name)
+	}
+	return snapshot
+}
+
+// state resolves the value and source of a flag.
+func (f *Flags) state(name string) State {
+	def, ok := lookup(name)
+	if !ok {
+		return State{Name: name}
+	}
+	state := State{
+		Name:        def.name,
+		Description: def.description,
+		Enabled:     def.enabled,
+		Source:      SourceDefault,
+	}
+	if f == nil {
+		return state
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if enabled, ok := f.overrides[name]; ok {
+		state.Enabled = enabled
+		state.Source = SourceOverride
+	} else if enabled, ok := f.config[name]; ok {
+		state.Enabled = enabled
+		state.Source = SourceConfig
+	}
+	return state
+}
+
+func lookup(name string) (definition, bool) {
+	for _, def := range definitions {
+		if def.name == name {
+			return def, true
+		}
+	}
+	return definition{}, false
+}
@@ -0,0 +1,97 @@
+package features
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFlags_Precedence(t *testing.T) {
+	flags, err := New(map[string]bool{Reranker: false})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if flags.Enabled(Reranker) {
+		t.Error("Enabled(reranker) = true, want false from config")
+	}
+	if !flags.Enabled(AnswerTools) {
+		t.Error("Enabled(answer_tools) = false, want built-in default true")
+	}
+	if flags.Enabled("warp_drive") {
+		t.Error("Enabled(unknown) = true, want false")
+	}
+
+	// Runtime overrides win over config until reset
+	if err := flags.Set(Reranker, true); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !flags.Enabled(Reranker) {
+		t.Error("Enabled(reranker) = false after override, want true")
+	}
+	for _, state := range flags.States() {
+		if state.Name == Reranker && state.Source != SourceOverride {
+			t.Errorf("reranker source = %q, want %q", state.Source, SourceOverride)
+		}
+	}
+	if err := flags.Reset(Reranker); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if flags.Enabled(Reranker) {
+		t.Error("Enabled(reranker) = true after reset, want config value false")
+	}
+
+	if err := flags.Set("warp_drive", true); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Set(unknown) error = %v, want ErrUnknownFlag", err)
+	}
+	if _, err := New(map[string]bool{"warp_drive": true}); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("New(unknown) error = %v, want ErrUnknownFlag", err)
+	}
+}
+
+func TestFlags_NilUsesDefaults(t *testing.T) {
+	var flags *Flags
+	if !flags.Enabled(Reranker) {
+		t.Error("nil Flags Enabled(reranker) = false, want built-in default true")
+	}
+	if len(flags.Snapshot()) != len(definitions) {
+		t.Errorf("nil Flags Snapshot() has %d flags, want %d", len(flags.Snapshot()), len(definitions))
+	}
+	if err := flags.Set(Reranker, false); err == nil {
+		t.Error("nil Flags Set() expected error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[string]bool{}},
+		{name: "pairs", value: "reranker=false, answer_tools=1", want: map[string]bool{Reranker: false, AnswerTools: true}},
+		{name: "missing value", value: "reranker", wantErr: true},
+		{name: "invalid bool", value: "reranker=maybe", wantErr: true},
+		{name: "unknown flag", value: "warp_drive=true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			for name, enabled := range tt.want {
+				if got[name] != enabled {
+					t.Errorf("Parse(%q)[%s] = %v, want %v", tt.value, name, got[name], enabled)
+				}
+			}
+		})
+	}
+}
@@ -3,10 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"helloworld-ai/internal/cache"
 	"helloworld-ai/internal/contextutil"
 	"helloworld-ai/internal/indexer"
 	"helloworld-ai/internal/rag"
@@ -19,15 +24,66 @@ type AskHandler struct {
 	vaultRepo        storage.VaultStore
 	indexerPipeline  *indexer.Pipeline
 	embeddingModelName string
+	limits           RequestLimits
+	// searchHandles keeps the results of recent searches for paging and re-sorting (see ServeSearchPage).
+	searchHandles *cache.Cache[string, searchHandle]
+}
+
+// RequestLimits bounds the size of ask requests.
+type RequestLimits struct {
+	// MaxBodyBytes caps the JSON body of POST /api/v1/ask.
+	MaxBodyBytes int64
+	// MaxQuestionLength caps the question in characters; longer text belongs in POST /api/v1/ask/document.
+	MaxQuestionLength int
+	// MaxDocumentBytes caps the document body of POST /api/v1/ask/document.
+	MaxDocumentBytes int64
+	// MaxVaults caps the vaults one request may name (body and vault: operators together).
+	MaxVaults int
+	// MaxFolders caps the folders one request may name (body and folder: operators together).
+	MaxFolders int
+	// Generation bounds the model and sampling overrides of ask requests (zero value: only
+	// temperature and max_tokens may be overridden).
+	Generation rag.GenerationLimits
+}
+
+// DefaultRequestLimits are used for any limit left at zero.
+var DefaultRequestLimits = RequestLimits{
+	MaxBodyBytes:      64 << 10,
+	MaxQuestionLength: 4000,
+	MaxDocumentBytes:  256 << 10,
+	MaxVaults:         20,
+	MaxFolders:        100,
+}
+
+// withDefaults fills zero limits from DefaultRequestLimits.
+func (l RequestLimits) withDefaults() RequestLimits {
+	if l.MaxBodyBytes <= 0 {
+		l.MaxBodyBytes = DefaultRequestLimits.MaxBodyBytes
+	}
+	if l.MaxQuestionLength <= 0 {
+		l.MaxQuestionLength = DefaultRequestLimits.MaxQuestionLength
+	}
+	if l.MaxDocumentBytes <= 0 {
+		l.MaxDocumentBytes = DefaultRequestLimits.MaxDocumentBytes
+	}
+	if l.MaxVaults <= 0 {
+		l.MaxVaults = DefaultRequestLimits.MaxVaults
+	}
+	if l.MaxFolders <= 0 {
+		l.MaxFolders = DefaultRequestLimits.MaxFolders
+	}
+	return l
 }
 
 // NewAskHandler creates a new AskHandler.
-func NewAskHandler(ragEngine rag.Engine, vaultRepo storage.VaultStore, indexerPipeline *indexer.Pipeline, embeddingModelName string) *AskHandler {
+func NewAskHandler(ragEngine rag.Engine, vaultRepo storage.VaultStore, indexerPipeline *indexer.Pipeline, embeddingModelName string, limits RequestLimits) *AskHandler {
 	return &AskHandler{
 		ragEngine:        ragEngine,
 		vaultRepo:        vaultRepo,
 		indexerPipeline:  indexerPipeline,
 		embeddingModelName: embeddingModelName,
+		limits:           limits.withDefaults(),
+		searchHandles:    cache.New[string, searchHandle]("search_handles", cache.Options{Size: searchHandleCacheSize, TTL: searchHandleTTL}),
 	}
 }
 
@@ -41,6 +97,103 @@ type AskRequest struct {
 	Folders  []string `json:"folders,omitempty"`
 	K        int      `json:"k,omitempty"`
 	Detail   string   `json:"detail,omitempty"`
+	// IncludeCold also searches notes that were moved to cold storage.
+	IncludeCold bool `json:"include_cold,omitempty"`
+	// MinScore overrides the retrieval score thresholds, e.g. lower for exploratory questions
+	// where missing context is worse than noisy context. Values below the server's floor are raised to it.
+	MinScore *ScoreThresholds `json:"min_score,omitempty"`
+	// RetrievalOptions overrides the retrieval weights and candidate counts, to tune retrieval
+	// without a rebuild. Ignored unless the retrieval_options feature flag is on.
+	RetrievalOptions *RetrievalOptions `json:"retrieval_options,omitempty"`
+	// Tags restricts the search to notes carrying all of these tags (nested tags match).
+	Tags []string `json:"tags,omitempty"`
+	// ModifiedAfter keeps notes last changed on or after this YYYY-MM-DD date (frontmatter
+	// `modified` when set, otherwise the file's modification time when it was indexed).
+	ModifiedAfter string `json:"modified_after,omitempty"`
+	// ModifiedBefore keeps notes last changed before this YYYY-MM-DD date.
+	ModifiedBefore string `json:"modified_before,omitempty"`
+	// CreatedAfter keeps notes whose frontmatter `created` date is on or after this YYYY-MM-DD date.
+	CreatedAfter string `json:"created_after,omitempty"`
+	// CreatedBefore keeps notes whose frontmatter `created` date is before this YYYY-MM-DD date.
+	CreatedBefore string `json:"created_before,omitempty"`
+	// NoCache answers the question afresh instead of from the answer cache, and does not cache the answer.
+	NoCache bool `json:"no_cache,omitempty"`
+	// Model selects the chat model for this answer: LLM_MODEL or one of LLM_ALLOWED_MODELS.
+	Model string `json:"model,omitempty"`
+	// Temperature overrides the answer temperature (0 < t <= 2; omit for the default 0.3).
+	Temperature float32 `json:"temperature,omitempty"`
+	// MaxTokens caps the answer length in tokens (at most ASK_MAX_TOKENS).
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// SystemPromptOverride replaces the answer system prompt (requires ASK_ALLOW_SYSTEM_PROMPT).
+	SystemPromptOverride string `json:"system_prompt_override,omitempty"`
+}
+
+// filterDateLayout is the date format of the date filters in AskRequest.
+const filterDateLayout = "2006-01-02"
+
+// tightenDateBound parses the YYYY-MM-DD value of a date filter field and narrows bound to
+// it: the earliest date wins for before bounds and the latest for after bounds. An empty
+// value leaves bound unchanged.
+func tightenDateBound(bound *time.Time, field, value string, before bool) error {
+	if value == "" {
+		return nil
+	}
+	date, err := time.Parse(filterDateLayout, value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: date %q (must be YYYY-MM-DD)", field, value)
+	}
+	if bound.IsZero() || (before && date.Before(*bound)) || (!before && date.After(*bound)) {
+		*bound = date
+	}
+	return nil
+}
+
+// ScoreThresholds are the minimum scores a retrieved chunk needs to be used as context.
+//
+// swagger:model ScoreThresholds
+type ScoreThresholds struct {
+	// Minimum vector similarity, between 0 and 1 (0 keeps the default)
+	Vector float32 `json:"vector,omitempty"`
+	// Minimum combined vector and lexical score, between 0 and 1 (0 keeps the default)
+	Final float32 `json:"final,omitempty"`
+}
+
+// scoreThresholds converts engine score thresholds to their HTTP form.
+func scoreThresholds(thresholds *rag.ScoreThresholds) *ScoreThresholds {
+	if thresholds == nil {
+		return nil
+	}
+	return &ScoreThresholds{Vector: thresholds.Vector, Final: thresholds.Final}
+}
+
+// RetrievalOptions tune how candidates are retrieved and scored for one request.
+//
+// swagger:model RetrievalOptions
+type RetrievalOptions struct {
+	// Weight of the vector score in the final score, between 0 and 1 (set with lexical_weight; 0 for both keeps the defaults)
+	VectorWeight float32 `json:"vector_weight,omitempty"`
+	// Weight of the lexical score in the final score, between 0 and 1
+	LexicalWeight float32 `json:"lexical_weight,omitempty"`
+	// Chunks retrieved per vault or folder search, at most 100 (0 keeps the default)
+	CandidateKPerScope int `json:"candidate_k_per_scope,omitempty"`
+	// Cap on the candidates reranked, at most 1000 (0 keeps the default)
+	MaxCandidates int `json:"max_candidates,omitempty"`
+	// Boost added to the final score of chunks whose note tags match the question, between 0 and 1 (0 keeps the default)
+	TagBoost float32 `json:"tag_boost,omitempty"`
+}
+
+// retrievalOptions converts engine retrieval options to their HTTP form.
+func retrievalOptions(opts *rag.RetrievalOptions) *RetrievalOptions {
+	if opts == nil {
+		return nil
+	}
+	return &RetrievalOptions{
+		VectorWeight:       opts.VectorWeight,
+		LexicalWeight:      opts.LexicalWeight,
+		CandidateKPerScope: opts.CandidateKPerScope,
+		MaxCandidates:      opts.MaxCandidates,
+		TagBoost:           opts.TagBoost,
+	}
 }
 
 // AskResponse represents the HTTP response payload for RAG queries.
@@ -57,11 +210,81 @@ type AskResponse struct {
 	// Abstained indicates whether the system abstained from answering (explicit abstention flag).
 	Abstained bool `json:"abstained,omitempty"`
 
-	// AbstainReason provides the reason for abstention (e.g., "no_relevant_context", "ambiguous_question", "insufficient_information").
+	// AbstainReason provides the reason for abstention (e.g., "no_relevant_context", "ambiguous_question", "insufficient_information", "unsupported_answer").
 	AbstainReason string `json:"abstain_reason,omitempty"`
 
 	// Debug contains debug information when debug mode is enabled (via ?debug=true query parameter).
 	Debug *DebugInfo `json:"debug,omitempty"`
+
+	// Meta records the model, prompt version, and retrieval configuration behind the answer.
+	Meta *AnswerMeta `json:"meta,omitempty"`
+
+	// Safety reports how the answer safety filter changed the answer (omitted when it did not act).
+	Safety *SafetyResponse `json:"safety,omitempty"`
+
+	// Suggestions are follow-up questions the retrieved notes can answer (omitted when disabled).
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	// Cached is true when the answer was served from the answer cache.
+	Cached bool `json:"cached,omitempty"`
+
+	// Truncated is true when the streamed answer was cut off by the generation timeout (GENERATION_TIMEOUT).
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SafetyResponse reports an answer safety filter action.
+//
+// swagger:model SafetyResponse
+type SafetyResponse struct {
+	// Action is redact (matched text replaced) or block (answer withheld)
+	Action string `json:"action"`
+	// Categories lists the sensitive categories found in the answer
+	Categories []string `json:"categories"`
+}
+
+// safetyResponse converts an engine safety result to its HTTP form.
+func safetyResponse(result *rag.SafetyResult) *SafetyResponse {
+	if result == nil {
+		return nil
+	}
+	return &SafetyResponse{
+		Action:     result.Action,
+		Categories: result.Categories,
+	}
+}
+
+// AnswerMeta identifies what produced an answer, so regressions after a model or prompt
+// change can be traced.
+//
+// swagger:model AnswerMeta
+type AnswerMeta struct {
+	// Model is the chat model that generated the answer (omitted without generation).
+	Model string `json:"model,omitempty"`
+	// Quantization is the quantization tag in the model name, e.g. Q4_K_M.
+	Quantization string `json:"quantization,omitempty"`
+	// PromptVersion is the prompt template version (omitted without generation).
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// RetrievalConfigHash identifies the retrieval configuration (omitted without retrieval).
+	RetrievalConfigHash string `json:"retrieval_config_hash,omitempty"`
+	// ScoreThresholds are the thresholds applied when the request set min_score (omitted otherwise).
+	ScoreThresholds *ScoreThresholds `json:"score_thresholds,omitempty"`
+	// Retrieval is the retrieval configuration applied when the request's retrieval_options took effect (omitted otherwise).
+	Retrieval *RetrievalOptions `json:"retrieval,omitempty"`
+}
+
+// answerMeta converts engine response metadata to its HTTP form.
+func answerMeta(meta *rag.ResponseMeta) *AnswerMeta {
+	if meta == nil {
+		return nil
+	}
+	return &AnswerMeta{
+		Model:               meta.Model,
+		Quantization:        meta.Quantization,
+		PromptVersion:       meta.PromptVersion,
+		RetrievalConfigHash: meta.RetrievalConfigHash,
+		ScoreThresholds:     scoreThresholds(meta.ScoreThresholds),
+		Retrieval:           retrievalOptions(meta.Retrieval),
+	}
 }
 
 // DebugInfo contains debug information when debug mode is enabled.
@@ -76,12 +299,88 @@ type DebugInfo struct {
 	Latency *LatencyBreakdown `json:"latency,omitempty"`
 	// IndexingCoverage contains indexing coverage statistics.
 	IndexingCoverage *IndexingCoverage `json:"indexing_coverage,omitempty"`
+	// RetrievalExpansion describes the second, broader retrieval pass (omitted if the first pass was strong enough).
+	RetrievalExpansion *DebugRetrievalExpansion `json:"retrieval_expansion,omitempty"`
+	// NotePrefilter describes the note-level first stage of two-stage retrieval (omitted when disabled).
+	NotePrefilter *DebugNotePrefilter `json:"note_prefilter,omitempty"`
+	// ToolResults lists the deterministic tool outputs (dates, calculations, unit conversions) injected into the prompt.
+	ToolResults []DebugToolResult `json:"tool_results,omitempty"`
+	// Conflicts lists dates and numbers that differ across notes, flagged to the model so it reports both.
+	Conflicts []DebugConflict `json:"conflicts,omitempty"`
+	// Features holds the feature flag values in effect for the request.
+	Features map[string]bool `json:"features,omitempty"`
+	// QuestionEmbeddingCached is true when the question embedding came from the recent-question cache.
+	QuestionEmbeddingCached bool `json:"question_embedding_cached,omitempty"`
+	// ScoreThresholds are the score thresholds applied to the retrieved chunks.
+	ScoreThresholds *ScoreThresholds `json:"score_thresholds,omitempty"`
+	// PromptTokens is the size of the answer prompt in chat model tokens (omitted without a generated answer or tokenizer).
+	PromptTokens *DebugPromptTokens `json:"prompt_tokens,omitempty"`
+	// Answerability is the answerability judge's verdict (omitted when ANSWERABILITY_THRESHOLD is 0 or the check failed).
+	Answerability *DebugAnswerability `json:"answerability,omitempty"`
+	// Judgement is the answer judge's verdict on the returned answer (omitted when ANSWER_JUDGE is off or judging failed).
+	Judgement *DebugJudgement `json:"judgement,omitempty"`
+}
+
+// DebugAnswerability is the answerability judge's verdict on the selected chunks.
+//
+// swagger:model DebugAnswerability
+type DebugAnswerability struct {
+	// Score is how well the judge thinks the chunks answer the question (0-1).
+	Score float32 `json:"score"`
+	// Threshold is the configured score below which the question is abstained as insufficient_information.
+	Threshold float32 `json:"threshold"`
+	// Reason is the judge's one-line explanation, if it gave one.
+	Reason string `json:"reason,omitempty"`
+	// LatencyMs is the time spent in the judge call (milliseconds).
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// DebugJudgement is the answer judge's verdict on the returned answer.
+//
+// swagger:model DebugJudgement
+type DebugJudgement struct {
+	// Faithfulness is how fully the answer's claims are supported by the retrieved chunks (0-1).
+	Faithfulness float32 `json:"faithfulness"`
+	// Relevance is how directly the answer addresses the question (0-1).
+	Relevance float32 `json:"relevance"`
+	// Scores holds every criterion of the judge's rubric, normalized to 0-1.
+	Scores map[string]float32 `json:"scores,omitempty"`
+	// Threshold is the faithfulness below which answers are regenerated (0: scores only).
+	Threshold float32 `json:"threshold"`
+	// Reason is the judge's one-line explanation, if it gave one.
+	Reason string `json:"reason,omitempty"`
+	// Attempts is the number of answers generated, the first included.
+	Attempts int `json:"attempts"`
+	// Regenerated is true when a regenerated answer was returned in place of the first.
+	Regenerated bool `json:"regenerated,omitempty"`
+	// Abstained is true when the answer scored below an abstain threshold of the rubric and
+	// was replaced with an unsupported_answer abstention.
+	Abstained bool `json:"abstained,omitempty"`
+	// PromptVersion identifies the judge rubric.
+	PromptVersion string `json:"prompt_version"`
+}
+
+// DebugPromptTokens is the size of the answer prompt's parts in tokens of the chat model.
+//
+// swagger:model DebugPromptTokens
+type DebugPromptTokens struct {
+	// SystemPrompt is the token count of the system prompt.
+	SystemPrompt int `json:"system_prompt"`
+	// Context is the token count of the context block (chunks, citation instructions, tool results).
+	Context int `json:"context"`
+	// Question is the token count of the question.
+	Question int `json:"question"`
+	// Total is the sum of the parts; the chat template adds a few tokens on top.
+	Total int `json:"total"`
 }
 
 // ReferenceResponse represents a reference in the HTTP response.
 //
 // swagger:model ReferenceResponse
 type ReferenceResponse struct {
+	// Stable chunk ID, for reporting clicks with POST /api/v1/references/{chunk_id}/click
+	ChunkID string `json:"chunk_id,omitempty"`
+
 	// Name of the vault containing the source
 	Vault string `json:"vault"`
 
@@ -93,6 +392,12 @@ type ReferenceResponse struct {
 
 	// Index of the chunk within the document
 	ChunkIndex int `json:"chunk_index"`
+
+	// 1-based position of the chunk among the document's chunks, for "section 3 of 12" (omitted when unknown)
+	Position int `json:"position,omitempty"`
+
+	// Number of chunks in the document (omitted when unknown)
+	TotalChunks int `json:"total_chunks,omitempty"`
 }
 
 // LatencyBreakdown contains timing information for each phase of the RAG pipeline.
@@ -105,7 +410,7 @@ type LatencyBreakdown struct {
 	RetrievalMs int64 `json:"retrieval_ms"`
 	// GenerationMs is the time spent in LLM generation (milliseconds).
 	GenerationMs int64 `json:"generation_ms"`
-	// JudgeMs is the time spent in answer judging (milliseconds). Always 0 in Go API (judging happens in Python).
+	// JudgeMs is the time spent in answer judging (milliseconds, 0 when ANSWER_JUDGE is off).
 	JudgeMs int64 `json:"judge_ms"`
 	// TotalMs is the total time for the entire RAG query (milliseconds).
 	TotalMs int64 `json:"total_ms"`
@@ -131,6 +436,68 @@ type DebugRetrievedChunk struct {
 	Text string `json:"text"`
 	// Rank is the rank of this chunk in the retrieval results (1-based).
 	Rank int `json:"rank"`
+	// Explanation breaks down how the scores were computed (omitted when the chunk was not reranked).
+	Explanation *DebugScoreExplanation `json:"explanation,omitempty"`
+	// Provenance identifies the indexing run that produced the chunk (omitted for chunks indexed before it was recorded).
+	Provenance *DebugChunkProvenance `json:"provenance,omitempty"`
+	// ClickThrough reports how often the chunk's reference was opened (omitted when never shown or clicked).
+	ClickThrough *DebugClickThrough `json:"click_through,omitempty"`
+}
+
+// DebugClickThrough counts how often a chunk was returned as a reference and opened.
+//
+// swagger:model DebugClickThrough
+type DebugClickThrough struct {
+	// Shown is the number of answers that returned the chunk as a reference.
+	Shown int `json:"shown"`
+	// Clicks is the number of times the reference was opened.
+	Clicks int `json:"clicks"`
+	// Rate is clicks / shown (0 when never shown).
+	Rate float64 `json:"rate"`
+}
+
+// DebugChunkProvenance identifies the indexing run that produced a chunk.
+//
+// swagger:model DebugChunkProvenance
+type DebugChunkProvenance struct {
+	// ChunkerVersion is the chunker version that split the note.
+	ChunkerVersion string `json:"chunker_version,omitempty"`
+	// EmbeddingModel is the model that embedded the chunk.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// EmbeddedAt is when the chunk was embedded (RFC 3339, UTC).
+	EmbeddedAt string `json:"embedded_at,omitempty"`
+	// RunID is the indexing run that wrote the chunk.
+	RunID string `json:"run_id,omitempty"`
+}
+
+// DebugScoreExplanation breaks a chunk's scores down into the components that produced them.
+//
+// swagger:model DebugScoreExplanation
+type DebugScoreExplanation struct {
+	// MatchedTerms are the query terms (stopwords removed) found in the chunk text.
+	MatchedTerms []string `json:"matched_terms"`
+	// TermFrequencies is how often each matched term occurs in the chunk text.
+	TermFrequencies map[string]int `json:"term_frequencies,omitempty"`
+	// ChunkTokenCount is the number of tokens in the chunk text, used to normalize term matches.
+	ChunkTokenCount int `json:"chunk_token_count"`
+	// TermScore is the length-normalized term match component of the lexical score.
+	TermScore float64 `json:"term_score"`
+	// HeadingMatches are the query terms found in the chunk's heading path.
+	HeadingMatches []string `json:"heading_matches,omitempty"`
+	// HeadingBonus is the lexical bonus added for heading matches.
+	HeadingBonus float64 `json:"heading_bonus"`
+	// LexicalCapped is true when the lexical score was clamped to its maximum.
+	LexicalCapped bool `json:"lexical_capped"`
+	// FolderWeight is the multiplier applied to the vector score for the folder the chunk was found in.
+	FolderWeight float64 `json:"folder_weight"`
+	// HeadingExactMatch is true when the question names the chunk's heading, which ranks the chunk first.
+	HeadingExactMatch bool `json:"heading_exact_match,omitempty"`
+	// RerankerScore is the external reranker's relevance score, used in place of the lexical score.
+	RerankerScore *float64 `json:"reranker_score,omitempty"`
+	// TagMatches are the chunk's note tags that share a word with the question.
+	TagMatches []string `json:"tag_matches,omitempty"`
+	// TagBoost is the boost added to the final score for TagMatches.
+	TagBoost float64 `json:"tag_boost,omitempty"`
 }
 
 // DebugFolderSelection contains information about folder selection.
@@ -141,6 +508,94 @@ type DebugFolderSelection struct {
 	SelectedFolders []string `json:"selected_folders"`
 	// AvailableFolders is the list of all available folders.
 	AvailableFolders []string `json:"available_folders,omitempty"`
+	// Method is how the folders were chosen: user, cache, heuristic, llm, or fallback.
+	Method string `json:"method,omitempty"`
+}
+
+// DebugRetrievalExpansion describes an automatic second retrieval pass after a weak first pass.
+//
+// swagger:model DebugRetrievalExpansion
+type DebugRetrievalExpansion struct {
+	// Reason is why the first pass was considered weak ("no_results", "below_threshold", "marginal_scores").
+	Reason string `json:"reason"`
+	// InitialCandidateK is the per-scope candidate K of the first pass.
+	InitialCandidateK int `json:"initial_candidate_k"`
+	// ExpandedCandidateK is the per-scope candidate K of the second pass.
+	ExpandedCandidateK int `json:"expanded_candidate_k"`
+	// FolderScopeRelaxed is true when the second pass searched all folders instead of the LLM-selected ones.
+	FolderScopeRelaxed bool `json:"folder_scope_relaxed"`
+	// NoteScopeRelaxed is true when the second pass searched all notes instead of the prefiltered ones.
+	NoteScopeRelaxed bool `json:"note_scope_relaxed"`
+	// InitialTopScore is the best final score of the first pass.
+	InitialTopScore float64 `json:"initial_top_score"`
+	// ExpandedTopScore is the best final score of the second pass.
+	ExpandedTopScore float64 `json:"expanded_top_score"`
+	// Used is true when the second pass scored higher and its results were used.
+	Used bool `json:"used"`
+}
+
+// DebugNotePrefilter describes the note-level first stage of two-stage retrieval.
+//
+// swagger:model DebugNotePrefilter
+type DebugNotePrefilter struct {
+	// TopM is the configured number of notes whose chunks are searched.
+	TopM int `json:"top_m"`
+	// Notes are the notes selected by centroid similarity, best first.
+	Notes []DebugPrefilterNote `json:"notes"`
+	// ChunkResults is the number of chunk hits the second stage returned within the selected notes.
+	ChunkResults int `json:"chunk_results"`
+	// FallbackReason is set when the chunk search ran unrestricted ("no_note_centroids", "search_failed").
+	FallbackReason string `json:"fallback_reason,omitempty"`
+}
+
+// DebugPrefilterNote is a note selected by the note-level prefilter.
+//
+// swagger:model DebugPrefilterNote
+type DebugPrefilterNote struct {
+	// Vault is the vault name.
+	Vault string `json:"vault"`
+	// RelPath is the relative path to the note file.
+	RelPath string `json:"rel_path"`
+	// Score is the cosine similarity between the question and the note centroid.
+	Score float64 `json:"score"`
+}
+
+// DebugToolResult is the output of a deterministic tool run for the question.
+//
+// swagger:model DebugToolResult
+type DebugToolResult struct {
+	// Tool is the tool that produced the result ("date", "calculator", "unit_conversion").
+	Tool string `json:"tool"`
+	// Input is the part of the question (or context) the tool ran on.
+	Input string `json:"input"`
+	// Output is the computed result as shown to the LLM.
+	Output string `json:"output"`
+}
+
+// DebugConflict is a pair of statements from different notes giving a different date or number for the same fact.
+//
+// swagger:model DebugConflict
+type DebugConflict struct {
+	// Kind is the kind of value that differs ("date" or "number").
+	Kind string `json:"kind"`
+	// Claims are the conflicting statements, one per note.
+	Claims []DebugConflictClaim `json:"claims"`
+}
+
+// DebugConflictClaim is one side of a conflict.
+//
+// swagger:model DebugConflictClaim
+type DebugConflictClaim struct {
+	// Value is the date or number the statement gives.
+	Value string `json:"value"`
+	// VaultName is the vault of the note making the statement.
+	VaultName string `json:"vault_name"`
+	// RelPath is the note's path within the vault.
+	RelPath string `json:"rel_path"`
+	// HeadingPath is the section of the statement.
+	HeadingPath string `json:"heading_path"`
+	// Text is the statement itself.
+	Text string `json:"text"`
 }
 
 // IndexingCoverage contains indexing coverage statistics.
@@ -203,12 +658,44 @@ type ErrorResponse struct {
 //
 // Use the `debug=true` query parameter to include detailed retrieval information
 // (retrieved chunks with scores, folder selection) in the response.
+// Use the `include_cold=true` query parameter (or `include_cold` in the body) to also
+// search notes that were moved to cold storage.
+//
+// Use `format=md` (or `Accept: text/markdown`) to receive the answer as a markdown
+// note with a Sources section of wikilinks to the cited notes.
+//
+// Use `stream=true` (or `Accept: text/event-stream`) to receive the answer as Server-Sent
+// Events: `token` events (StreamToken) carry the answer as it is generated, and a final
+// `done` event carries the AskResponse with references and debug information. Errors after
+// streaming started end the stream with an `error` event (ErrorResponse).
+//
+// Questions may carry inline filter operators, which are removed before answering:
+// `vault:work`, `folder:Projects/` (quote values with spaces), `tag:#golang` (nested tags
+// match), and `before:2024-01-01` / `after:2023-06-01` (UTC dates of the note's last change).
+// An invalid date is a 400.
+//
+// The body filters `tags`, `modified_after`, `modified_before`, `created_after`, and
+// `created_before` (YYYY-MM-DD) restrict the search by note frontmatter, e.g.
+// {"tags": ["project-x"], "modified_after": "2024-01-01"}. A note's frontmatter `modified`
+// date takes precedence over the file's modification time; created filters only match notes with a
+// `created` date. They combine with the inline operators, keeping the tightest date bounds.
+//
+// Set `min_score` ({"vector": 0.2, "final": 0.25}) to lower the retrieval score thresholds for
+// exploratory questions where missing context is worse than noisy context (or raise them).
+// Values below the server's floor (MIN_VECTOR_SCORE_FLOOR, MIN_FINAL_SCORE_FLOOR) are raised to
+// it; the applied thresholds are reported in `meta.score_thresholds` and the debug information.
+//
+// Repeated questions (same normalized text and options) are answered from the answer cache
+// until indexing changes a note or ANSWER_CACHE_TTL_SECONDS passes; cached answers have
+// `cached` set. Set `no_cache` (body or query) to answer afresh. Debug requests are never cached.
 //
 // ---
 // consumes:
 // - application/json
 // produces:
 // - application/json
+// - text/markdown
+// - text/event-stream
 // parameters:
 //   - in: body
 //     name: body
@@ -220,6 +707,27 @@ type ErrorResponse struct {
 //     type: boolean
 //     description: Enable debug mode to include detailed retrieval information
 //     required: false
+//   - in: query
+//     name: include_cold
+//     type: boolean
+//     description: Also search notes that were moved to cold storage
+//     required: false
+//   - in: query
+//     name: no_cache
+//     type: boolean
+//     description: Answer afresh instead of from the answer cache
+//     required: false
+//   - in: query
+//     name: format
+//     type: string
+//     enum: md
+//     description: Set to md to return the answer as a markdown note with wikilinked sources
+//     required: false
+//   - in: query
+//     name: stream
+//     type: boolean
+//     description: Stream the answer as Server-Sent Events
+//     required: false
 //
 // responses:
 //
@@ -228,7 +736,11 @@ type ErrorResponse struct {
 //	  schema:
 //	    "$ref": "#/definitions/AskResponse"
 //	'400':
-//	  description: Bad request (invalid question or vault name)
+//	  description: Bad request (invalid question, question too long, or invalid vault name)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Request body too large
 //	  schema:
 //	    "$ref": "#/definitions/ErrorResponse"
 //	'502':
@@ -239,6 +751,10 @@ type ErrorResponse struct {
 //	  description: Vector store unavailable
 //	  schema:
 //	    "$ref": "#/definitions/ErrorResponse"
+//	'504':
+//	  description: Embedding, vector search, or LLM call timed out (EMBED_TIMEOUT, SEARCH_TIMEOUT, or GENERATION_TIMEOUT)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
 //	'500':
 //	  description: Internal server error
 //	  schema:
@@ -253,18 +769,139 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, ragReq, ok := h.decodeAskRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Stream the answer over Server-Sent Events when requested
+	if wantsStream(r) {
+		h.serveStream(w, ctx, ragReq)
+		return
+	}
+
+	// Call RAG engine
+	ragResp, err := h.ragEngine.Ask(ctx, ragReq)
+	if err != nil {
+		h.handleRAGError(w, ctx, err, "Failed to process RAG query")
+		return
+	}
+
+	resp := h.askResponse(ctx, ragResp)
+
+	// Markdown export returns the answer as a note ready to paste into Obsidian
+	if wantsMarkdown(r) {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(formatAnswerMarkdown(req.Question, resp)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.ErrorContext(ctx, "failed to encode response", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+}
+
+// decodeAskRequest decodes an ask request body and validates it with prepareAskRequest.
+// On failure it writes the error response and returns false.
+func (h *AskHandler) decodeAskRequest(w http.ResponseWriter, r *http.Request) (AskRequest, rag.AskRequest, bool) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// Decode straight from the body, failing as soon as it exceeds the size limit
 	var req AskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.limits.MaxBodyBytes)).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			logger.WarnContext(ctx, "request body too large", "limit_bytes", maxBytesErr.Limit)
+			h.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+			return AskRequest{}, rag.AskRequest{}, false
+		}
 		logger.WarnContext(ctx, "invalid request body", "error", err)
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
-		return
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+	return h.prepareAskRequest(w, r, req)
+}
+
+// prepareAskRequest validates an ask request, applying inline question operators and the
+// debug, include_cold, and no_cache query parameters, and converts it to a RAG request.
+// On failure it writes the error response and returns false.
+func (h *AskHandler) prepareAskRequest(w http.ResponseWriter, r *http.Request, req AskRequest) (AskRequest, rag.AskRequest, bool) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// Inline operators (vault:work folder:Projects/ tag:#golang before:2024-01-01) add to the body filters
+	question, operators, err := rag.ParseQuestionOperators(req.Question)
+	if err != nil {
+		logger.WarnContext(ctx, "invalid question operator", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+	req.Question = question
+	for _, vaultName := range operators.Vaults {
+		if !slices.Contains(req.Vaults, vaultName) {
+			req.Vaults = append(req.Vaults, vaultName)
+		}
+	}
+	for _, folder := range operators.Folders {
+		if !slices.Contains(req.Folders, folder) {
+			req.Folders = append(req.Folders, folder)
+		}
+	}
+
+	// Body tag and date filters combine with the operators: tags add up, dates keep the tightest bound
+	tags := operators.Tags
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "#"), "/"))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	modifiedBefore, modifiedAfter := operators.Before, operators.After
+	var createdBefore, createdAfter time.Time
+	for _, bound := range []struct {
+		target *time.Time
+		field  string
+		value  string
+		before bool
+	}{
+		{&modifiedBefore, "modified_before", req.ModifiedBefore, true},
+		{&modifiedAfter, "modified_after", req.ModifiedAfter, false},
+		{&createdBefore, "created_before", req.CreatedBefore, true},
+		{&createdAfter, "created_after", req.CreatedAfter, false},
+	} {
+		if err := tightenDateBound(bound.target, bound.field, bound.value, bound.before); err != nil {
+			logger.WarnContext(ctx, "invalid date filter", "error", err)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return AskRequest{}, rag.AskRequest{}, false
+		}
 	}
 
 	// Validate request
 	if req.Question == "" {
 		logger.WarnContext(ctx, "empty question in request")
 		h.writeError(w, http.StatusBadRequest, "Question is required")
-		return
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+	if length := utf8.RuneCountInString(req.Question); length > h.limits.MaxQuestionLength {
+		logger.WarnContext(ctx, "question too long", "question_length", length, "limit", h.limits.MaxQuestionLength)
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("Question exceeds %d characters; send long text to /api/v1/ask/document instead", h.limits.MaxQuestionLength))
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+
+	if len(req.Vaults) > h.limits.MaxVaults {
+		logger.WarnContext(ctx, "too many vaults", "vaults", len(req.Vaults), "limit", h.limits.MaxVaults)
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("At most %d vaults may be requested", h.limits.MaxVaults))
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+	if len(req.Folders) > h.limits.MaxFolders {
+		logger.WarnContext(ctx, "too many folders", "folders", len(req.Folders), "limit", h.limits.MaxFolders)
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("At most %d folders may be requested", h.limits.MaxFolders))
+		return AskRequest{}, rag.AskRequest{}, false
 	}
 
 	// Enforce bounds for user-provided K (legacy clients). Zero means "auto".
@@ -275,13 +912,50 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		req.K = 20
 	}
 
+	var minScore rag.ScoreThresholds
+	if req.MinScore != nil {
+		if req.MinScore.Vector < 0 || req.MinScore.Vector > 1 || req.MinScore.Final < 0 || req.MinScore.Final > 1 {
+			h.writeError(w, http.StatusBadRequest, "min_score values must be between 0 and 1")
+			return AskRequest{}, rag.AskRequest{}, false
+		}
+		minScore = rag.ScoreThresholds{Vector: req.MinScore.Vector, Final: req.MinScore.Final}
+	}
+
+	var retrieval rag.RetrievalOverrides
+	if req.RetrievalOptions != nil {
+		retrieval = rag.RetrievalOverrides{
+			VectorWeight:       req.RetrievalOptions.VectorWeight,
+			LexicalWeight:      req.RetrievalOptions.LexicalWeight,
+			CandidateKPerScope: req.RetrievalOptions.CandidateKPerScope,
+			MaxCandidates:      req.RetrievalOptions.MaxCandidates,
+			TagBoost:           req.RetrievalOptions.TagBoost,
+		}
+		if err := retrieval.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid retrieval options", "error", err)
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return AskRequest{}, rag.AskRequest{}, false
+		}
+	}
+
+	generation := rag.GenerationOverrides{
+		Model:        strings.TrimSpace(req.Model),
+		Temperature:  req.Temperature,
+		MaxTokens:    req.MaxTokens,
+		SystemPrompt: strings.TrimSpace(req.SystemPromptOverride),
+	}
+	if err := h.limits.Generation.Validate(generation); err != nil {
+		logger.WarnContext(ctx, "invalid generation override", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return AskRequest{}, rag.AskRequest{}, false
+	}
+
 	// Validate vault names if provided
 	if len(req.Vaults) > 0 {
 		allVaults, err := h.vaultRepo.ListAll(ctx)
 		if err != nil {
 			logger.ErrorContext(ctx, "failed to list vaults for validation", "error", err)
 			h.writeError(w, http.StatusInternalServerError, "Failed to validate vaults")
-			return
+			return AskRequest{}, rag.AskRequest{}, false
 		}
 
 		// Build set of valid vault names
@@ -295,7 +969,7 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if !validVaults[vaultName] {
 				logger.WarnContext(ctx, "invalid vault name", "vault", vaultName)
 				h.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid vault name: %s", vaultName))
-				return
+				return AskRequest{}, rag.AskRequest{}, false
 			}
 		}
 	}
@@ -306,6 +980,18 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		debug = strings.ToLower(debugParam) == "true" || debugParam == "1"
 	}
 
+	// Parse include_cold query parameter (overrides the body field when set)
+	includeCold := req.IncludeCold
+	if coldParam := r.URL.Query().Get("include_cold"); coldParam != "" {
+		includeCold = strings.ToLower(coldParam) == "true" || coldParam == "1"
+	}
+
+	// Parse no_cache query parameter (overrides the body field when set)
+	noCache := req.NoCache
+	if noCacheParam := r.URL.Query().Get("no_cache"); noCacheParam != "" {
+		noCache = strings.ToLower(noCacheParam) == "true" || noCacheParam == "1"
+	}
+
 	// Convert HTTP request to RAG request
 	detail := strings.ToLower(strings.TrimSpace(req.Detail))
 	switch detail {
@@ -315,30 +1001,33 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ragReq := rag.AskRequest{
-		Question: req.Question,
-		Vaults:   req.Vaults,
-		Folders:  req.Folders,
-		K:        req.K,
-		Detail:   detail,
-		Debug:    debug,
-	}
-
-	// Call RAG engine
-	ragResp, err := h.ragEngine.Ask(ctx, ragReq)
-	if err != nil {
-		h.handleRAGError(w, ctx, err, "Failed to process RAG query")
-		return
+		Question:      req.Question,
+		Vaults:        req.Vaults,
+		Folders:       req.Folders,
+		K:             req.K,
+		Detail:        detail,
+		Debug:         debug,
+		IncludeCold:   includeCold,
+		Tags:          tags,
+		UpdatedBefore: modifiedBefore,
+		UpdatedAfter:  modifiedAfter,
+		CreatedBefore: createdBefore,
+		CreatedAfter:  createdAfter,
+		MinScore:      minScore,
+		Retrieval:     retrieval,
+		NoCache:       noCache,
+		Generation:    generation,
 	}
+	return req, ragReq, true
+}
 
+// askResponse converts a RAG engine response to its HTTP form, fetching indexing coverage
+// for debug output.
+func (h *AskHandler) askResponse(ctx context.Context, ragResp rag.AskResponse) AskResponse {
 	// Convert RAG response to HTTP response
 	references := make([]ReferenceResponse, len(ragResp.References))
 	for i, ref := range ragResp.References {
-		references[i] = ReferenceResponse{
-			Vault:       ref.Vault,
-			RelPath:     ref.RelPath,
-			HeadingPath: ref.HeadingPath,
-			ChunkIndex:  ref.ChunkIndex,
-		}
+		references[i] = referenceResponse(ref)
 	}
 
 	resp := AskResponse{
@@ -346,87 +1035,246 @@ func (h *AskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		References:    references,
 		Abstained:     ragResp.Abstained,
 		AbstainReason: ragResp.AbstainReason,
+		Meta:          answerMeta(ragResp.Meta),
+		Safety:        safetyResponse(ragResp.Safety),
+		Suggestions:   ragResp.Suggestions,
+		Cached:        ragResp.Cached,
+		Truncated:     ragResp.Truncated,
 	}
 
-	// Include debug information if present
-	if ragResp.Debug != nil {
-		debugChunks := make([]DebugRetrievedChunk, 0, len(ragResp.Debug.RetrievedChunks))
-		for _, chunk := range ragResp.Debug.RetrievedChunks {
-			debugChunks = append(debugChunks, DebugRetrievedChunk{
-				ChunkID:      chunk.ChunkID,
-				RelPath:      chunk.RelPath,
-				HeadingPath:  chunk.HeadingPath,
-				ScoreVector:  chunk.ScoreVector,
-				ScoreLexical: chunk.ScoreLexical,
-				ScoreFinal:   chunk.ScoreFinal,
-				Text:         chunk.Text,
-				Rank:         chunk.Rank,
-			})
-		}
+	resp.Debug = h.debugInfo(ctx, ragResp.Debug)
+	return resp
+}
+
+// referenceResponse converts a RAG reference to its HTTP form.
+func referenceResponse(ref rag.Reference) ReferenceResponse {
+	return ReferenceResponse{
+		ChunkID:     ref.ChunkID,
+		Vault:       ref.Vault,
+		RelPath:     ref.RelPath,
+		HeadingPath: ref.HeadingPath,
+		ChunkIndex:  ref.ChunkIndex,
+		Position:    ref.Position,
+		TotalChunks: ref.TotalChunks,
+	}
+}
 
-		var folderSelection *DebugFolderSelection
-		if ragResp.Debug.FolderSelection != nil {
-			folderSelection = &DebugFolderSelection{
-				SelectedFolders:  ragResp.Debug.FolderSelection.SelectedFolders,
-				AvailableFolders: ragResp.Debug.FolderSelection.AvailableFolders,
+// debugInfo converts RAG debug information to its HTTP form, fetching indexing coverage
+// (nil for nil debug).
+func (h *AskHandler) debugInfo(ctx context.Context, debug *rag.DebugInfo) *DebugInfo {
+	if debug == nil {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	debugChunks := make([]DebugRetrievedChunk, 0, len(debug.RetrievedChunks))
+	for _, chunk := range debug.RetrievedChunks {
+		var explanation *DebugScoreExplanation
+		if chunk.Explanation != nil {
+			explanation = &DebugScoreExplanation{
+				MatchedTerms:      chunk.Explanation.MatchedTerms,
+				TermFrequencies:   chunk.Explanation.TermFrequencies,
+				ChunkTokenCount:   chunk.Explanation.ChunkTokenCount,
+				TermScore:         chunk.Explanation.TermScore,
+				HeadingMatches:    chunk.Explanation.HeadingMatches,
+				HeadingBonus:      chunk.Explanation.HeadingBonus,
+				LexicalCapped:     chunk.Explanation.LexicalCapped,
+				FolderWeight:      chunk.Explanation.FolderWeight,
+				HeadingExactMatch: chunk.Explanation.HeadingExactMatch,
+				RerankerScore:     chunk.Explanation.RerankerScore,
+				TagMatches:        chunk.Explanation.TagMatches,
+				TagBoost:          chunk.Explanation.TagBoost,
 			}
 		}
-
-		var latency *LatencyBreakdown
-		if ragResp.Debug.Latency != nil {
-			latency = &LatencyBreakdown{
-				FolderSelectionMs: ragResp.Debug.Latency.FolderSelectionMs,
-				RetrievalMs:       ragResp.Debug.Latency.RetrievalMs,
-				GenerationMs:      ragResp.Debug.Latency.GenerationMs,
-				JudgeMs:           ragResp.Debug.Latency.JudgeMs,
-				TotalMs:           ragResp.Debug.Latency.TotalMs,
+		var provenance *DebugChunkProvenance
+		if chunk.Provenance != nil {
+			provenance = &DebugChunkProvenance{
+				ChunkerVersion: chunk.Provenance.ChunkerVersion,
+				EmbeddingModel: chunk.Provenance.EmbeddingModel,
+				EmbeddedAt:     chunk.Provenance.EmbeddedAt,
+				RunID:          chunk.Provenance.RunID,
 			}
 		}
+		var clickThrough *DebugClickThrough
+		if chunk.ClickThrough != nil {
+			clickThrough = &DebugClickThrough{
+				Shown:  chunk.ClickThrough.Shown,
+				Clicks: chunk.ClickThrough.Clicks,
+				Rate:   chunk.ClickThrough.Rate,
+			}
+		}
+		debugChunks = append(debugChunks, DebugRetrievedChunk{
+			ChunkID:      chunk.ChunkID,
+			RelPath:      chunk.RelPath,
+			HeadingPath:  chunk.HeadingPath,
+			ScoreVector:  chunk.ScoreVector,
+			ScoreLexical: chunk.ScoreLexical,
+			ScoreFinal:   chunk.ScoreFinal,
+			Text:         chunk.Text,
+			Rank:         chunk.Rank,
+			Explanation:  explanation,
+			Provenance:   provenance,
+			ClickThrough: clickThrough,
+		})
+	}
 
-		// Fetch indexing coverage stats if debug mode is enabled
-		var indexingCoverage *IndexingCoverage
-		if h.indexerPipeline != nil && h.embeddingModelName != "" {
-			stats, err := h.indexerPipeline.GetIndexingCoverageStats(ctx, h.embeddingModelName)
-			if err != nil {
-				logger.WarnContext(ctx, "failed to get indexing coverage stats", "error", err)
-			} else if stats != nil {
-				// Convert indexer.IndexingCoverageStats to handlers.IndexingCoverage
-				var tokenStats *ChunkTokenStats
-				if stats.ChunkTokenStats.Min > 0 || stats.ChunkTokenStats.Max > 0 {
-					tokenStats = &ChunkTokenStats{
-						Min:  stats.ChunkTokenStats.Min,
-						Max:  stats.ChunkTokenStats.Max,
-						Mean: stats.ChunkTokenStats.Mean,
-						P95:  stats.ChunkTokenStats.P95,
-					}
-				}
-				indexingCoverage = &IndexingCoverage{
-					DocsProcessed:        stats.DocsProcessed,
-					DocsWith0Chunks:      stats.DocsWith0Chunks,
-					ChunksAttempted:      stats.ChunksAttempted,
-					ChunksEmbedded:       stats.ChunksEmbedded,
-					ChunksSkipped:        stats.ChunksSkipped,
-					ChunksSkippedReasons: stats.ChunksSkippedReasons,
-					ChunkTokenStats:      tokenStats,
-					ChunkerVersion:       stats.ChunkerVersion,
-					IndexVersion:         stats.IndexVersion,
+	var folderSelection *DebugFolderSelection
+	if debug.FolderSelection != nil {
+		folderSelection = &DebugFolderSelection{
+			SelectedFolders:  debug.FolderSelection.SelectedFolders,
+			AvailableFolders: debug.FolderSelection.AvailableFolders,
+			Method:           debug.FolderSelection.Method,
+		}
+	}
+
+	var latency *LatencyBreakdown
+	if debug.Latency != nil {
+		latency = &LatencyBreakdown{
+			FolderSelectionMs: debug.Latency.FolderSelectionMs,
+			RetrievalMs:       debug.Latency.RetrievalMs,
+			GenerationMs:      debug.Latency.GenerationMs,
+			JudgeMs:           debug.Latency.JudgeMs,
+			TotalMs:           debug.Latency.TotalMs,
+		}
+	}
+
+	// Fetch indexing coverage stats if debug mode is enabled
+	var indexingCoverage *IndexingCoverage
+	if h.indexerPipeline != nil && h.embeddingModelName != "" {
+		stats, err := h.indexerPipeline.GetIndexingCoverageStats(ctx, h.embeddingModelName)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to get indexing coverage stats", "error", err)
+		} else if stats != nil {
+			// Convert indexer.IndexingCoverageStats to handlers.IndexingCoverage
+			var tokenStats *ChunkTokenStats
+			if stats.ChunkTokenStats.Min > 0 || stats.ChunkTokenStats.Max > 0 {
+				tokenStats = &ChunkTokenStats{
+					Min:  stats.ChunkTokenStats.Min,
+					Max:  stats.ChunkTokenStats.Max,
+					Mean: stats.ChunkTokenStats.Mean,
+					P95:  stats.ChunkTokenStats.P95,
 				}
 			}
+			indexingCoverage = &IndexingCoverage{
+				DocsProcessed:        stats.DocsProcessed,
+				DocsWith0Chunks:      stats.DocsWith0Chunks,
+				ChunksAttempted:      stats.ChunksAttempted,
+				ChunksEmbedded:       stats.ChunksEmbedded,
+				ChunksSkipped:        stats.ChunksSkipped,
+				ChunksSkippedReasons: stats.ChunksSkippedReasons,
+				ChunkTokenStats:      tokenStats,
+				ChunkerVersion:       stats.ChunkerVersion,
+				IndexVersion:         stats.IndexVersion,
+			}
 		}
+	}
 
-		resp.Debug = &DebugInfo{
-			RetrievedChunks:  debugChunks,
-			FolderSelection:  folderSelection,
-			Latency:          latency,
-			IndexingCoverage: indexingCoverage,
+	var retrievalExpansion *DebugRetrievalExpansion
+	if expansion := debug.RetrievalExpansion; expansion != nil {
+		retrievalExpansion = &DebugRetrievalExpansion{
+			Reason:             expansion.Reason,
+			InitialCandidateK:  expansion.InitialCandidateK,
+			ExpandedCandidateK: expansion.ExpandedCandidateK,
+			FolderScopeRelaxed: expansion.FolderScopeRelaxed,
+			NoteScopeRelaxed:   expansion.NoteScopeRelaxed,
+			InitialTopScore:    expansion.InitialTopScore,
+			ExpandedTopScore:   expansion.ExpandedTopScore,
+			Used:               expansion.Used,
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logger.ErrorContext(ctx, "failed to encode response", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "Failed to encode response")
-		return
+	var notePrefilter *DebugNotePrefilter
+	if prefilter := debug.NotePrefilter; prefilter != nil {
+		notes := make([]DebugPrefilterNote, 0, len(prefilter.Notes))
+		for _, note := range prefilter.Notes {
+			notes = append(notes, DebugPrefilterNote{
+				Vault:   note.Vault,
+				RelPath: note.RelPath,
+				Score:   note.Score,
+			})
+		}
+		notePrefilter = &DebugNotePrefilter{
+			TopM:           prefilter.TopM,
+			Notes:          notes,
+			ChunkResults:   prefilter.ChunkResults,
+			FallbackReason: prefilter.FallbackReason,
+		}
+	}
+
+	var toolResults []DebugToolResult
+	for _, result := range debug.ToolResults {
+		toolResults = append(toolResults, DebugToolResult{
+			Tool:   result.Tool,
+			Input:  result.Input,
+			Output: result.Output,
+		})
+	}
+
+	var conflicts []DebugConflict
+	for _, conflict := range debug.Conflicts {
+		claims := make([]DebugConflictClaim, 0, len(conflict.Claims))
+		for _, claim := range conflict.Claims {
+			claims = append(claims, DebugConflictClaim{
+				Value:       claim.Value,
+				VaultName:   claim.VaultName,
+				RelPath:     claim.RelPath,
+				HeadingPath: claim.HeadingPath,
+				Text:        claim.Text,
+			})
+		}
+		conflicts = append(conflicts, DebugConflict{Kind: conflict.Kind, Claims: claims})
+	}
+
+	var promptTokens *DebugPromptTokens
+	if tokens := debug.PromptTokens; tokens != nil {
+		promptTokens = &DebugPromptTokens{
+			SystemPrompt: tokens.SystemPrompt,
+			Context:      tokens.Context,
+			Question:     tokens.Question,
+			Total:        tokens.Total,
+		}
+	}
+
+	var answerability *DebugAnswerability
+	if check := debug.Answerability; check != nil {
+		answerability = &DebugAnswerability{
+			Score:     check.Score,
+			Threshold: check.Threshold,
+			Reason:    check.Reason,
+			LatencyMs: check.LatencyMs,
+		}
+	}
+
+	var judgement *DebugJudgement
+	if verdict := debug.Judgement; verdict != nil {
+		judgement = &DebugJudgement{
+			Faithfulness:  verdict.Faithfulness,
+			Relevance:     verdict.Relevance,
+			Scores:        verdict.Scores,
+			Threshold:     verdict.Threshold,
+			Reason:        verdict.Reason,
+			Attempts:      verdict.Attempts,
+			Regenerated:   verdict.Regenerated,
+			Abstained:     verdict.Abstained,
+			PromptVersion: verdict.PromptVersion,
+		}
+	}
+
+	return &DebugInfo{
+		RetrievedChunks:         debugChunks,
+		FolderSelection:         folderSelection,
+		Latency:                 latency,
+		IndexingCoverage:        indexingCoverage,
+		RetrievalExpansion:      retrievalExpansion,
+		NotePrefilter:           notePrefilter,
+		ToolResults:             toolResults,
+		Conflicts:               conflicts,
+		Features:                debug.Features,
+		QuestionEmbeddingCached: debug.QuestionEmbeddingCached,
+		ScoreThresholds:         scoreThresholds(debug.ScoreThresholds),
+		PromptTokens:            promptTokens,
+		Answerability:           answerability,
+		Judgement:               judgement,
 	}
 }
 
@@ -440,28 +1288,24 @@ func (h *AskHandler) handleRAGError(w http.ResponseWriter, ctx context.Context,
 		return
 	}
 
-	// Check error message for specific error types
-	errMsg := strings.ToLower(err.Error())
-
-	// Vector store errors -> 503
-	if strings.Contains(errMsg, "vector store") ||
-		strings.Contains(errMsg, "vectorstore") ||
-		strings.Contains(errMsg, "qdrant") ||
-		strings.Contains(errMsg, "failed to search") {
+	switch {
+	case errors.Is(err, rag.ErrGenerationDisabled):
+		// Generation disabled by the extractive engine -> 501
+		h.writeError(w, http.StatusNotImplemented, "Not available with the extractive engine (RAG_ENGINE=extractive)")
+	case errors.Is(err, rag.ErrNoContext):
+		h.writeError(w, http.StatusBadRequest, "Nothing to answer from")
+	case errors.Is(err, context.DeadlineExceeded):
+		// An embedding, search, or generation call ran out of its timeout
+		h.writeError(w, http.StatusGatewayTimeout, "Timed out waiting for an external service")
+	case errors.Is(err, rag.ErrVectorStoreUnavailable):
 		h.writeError(w, http.StatusServiceUnavailable, "Vector store unavailable")
-		return
-	}
-
-	// LLM/embedding errors -> 502
-	if strings.Contains(errMsg, "embed") ||
-		strings.Contains(errMsg, "llm") ||
-		strings.Contains(errMsg, "failed to get llm") {
-		h.writeError(w, http.StatusBadGateway, "External service error")
-		return
+	case errors.Is(err, rag.ErrEmbeddingUnavailable):
+		h.writeError(w, http.StatusBadGateway, "Embedding service unavailable")
+	case errors.Is(err, rag.ErrLLMFailure):
+		h.writeError(w, http.StatusBadGateway, "LLM request failed")
+	default:
+		h.writeError(w, http.StatusInternalServerError, defaultMsg)
 	}
-
-	// Default to 500
-	h.writeError(w, http.StatusInternalServerError, defaultMsg)
 }
 
 // writeError writes an error response.
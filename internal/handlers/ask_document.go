@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+)
+
+// errDocumentTooLarge is returned when a document exceeds MaxDocumentBytes.
+var errDocumentTooLarge = errors.New("document too large")
+
+// ServeDocument handles questions about a document supplied with the request.
+//
+// swagger:route POST /api/v1/ask/document askDocument
+//
+// # Ask a question about a document
+//
+// Answers a question about long text supplied with the request (e.g. "summarize this")
+// instead of searching the indexed notes. Send the document either as the raw request
+// body (`text/plain` or `text/markdown`) with the question in the `question` query
+// parameter, or as `multipart/form-data` with a `file` part and an optional `question`
+// field. The body is read as a stream and rejected once it exceeds the document limit.
+// Without a question the document is summarized. Documents too long for one LLM request
+// are condensed section by section before answering.
+//
+// ---
+// consumes:
+// - text/plain
+// - text/markdown
+// - multipart/form-data
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: question
+//     type: string
+//     description: Question or instruction about the document (defaults to a summary)
+//     required: false
+//
+// responses:
+//
+//	'200':
+//	  description: Answer about the document (references are always empty)
+//	  schema:
+//	    "$ref": "#/definitions/AskResponse"
+//	'400':
+//	  description: Bad request (empty document, question too long, or malformed multipart body)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Document too large
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'501':
+//	  description: Document questions are unavailable because RAG_ENGINE=extractive disables generation
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: External service error (LLM unavailable)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'504':
+//	  description: LLM call timed out (GENERATION_TIMEOUT)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *AskHandler) ServeDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if r.Method != http.MethodPost {
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Leave room for multipart headers and the question field around the document itself
+	r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxDocumentBytes+int64(h.limits.MaxQuestionLength)*utf8.UTFMax+64<<10)
+
+	req := rag.DocumentRequest{Question: r.URL.Query().Get("question")}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var err error
+	if mediaType == "multipart/form-data" {
+		err = h.readMultipartDocument(r, &req)
+	} else {
+		req.Document, err = h.readDocument(r.Body)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.Is(err, errDocumentTooLarge) || errors.As(err, &maxBytesErr) {
+			logger.WarnContext(ctx, "document too large", "limit_bytes", h.limits.MaxDocumentBytes)
+			h.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Document exceeds %d bytes", h.limits.MaxDocumentBytes))
+			return
+		}
+		logger.WarnContext(ctx, "invalid document request", "error", err)
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.Document) == "" {
+		logger.WarnContext(ctx, "empty document in request")
+		h.writeError(w, http.StatusBadRequest, "Document is required")
+		return
+	}
+	if !utf8.ValidString(req.Document) {
+		logger.WarnContext(ctx, "document is not valid UTF-8 text", "document_name", req.Name)
+		h.writeError(w, http.StatusBadRequest, "Document must be UTF-8 text")
+		return
+	}
+	if length := utf8.RuneCountInString(req.Question); length > h.limits.MaxQuestionLength {
+		logger.WarnContext(ctx, "question too long", "question_length", length, "limit", h.limits.MaxQuestionLength)
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("Question exceeds %d characters", h.limits.MaxQuestionLength))
+		return
+	}
+
+	ragResp, err := h.ragEngine.AskDocument(ctx, req)
+	if err != nil {
+		h.handleRAGError(w, ctx, err, "Failed to answer question about document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(AskResponse{
+		Answer:     ragResp.Answer,
+		References: []ReferenceResponse{},
+		Meta:       answerMeta(ragResp.Meta),
+		Safety:     safetyResponse(ragResp.Safety),
+	}); err != nil {
+		logger.ErrorContext(ctx, "failed to encode response", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+}
+
+// readMultipartDocument streams the parts of a multipart body: the "file" part is the
+// document and the "question" field overrides the query parameter. Other parts are skipped.
+func (h *AskHandler) readMultipartDocument(r *http.Request, req *rag.DocumentRequest) error {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("failed to read multipart body: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read multipart body: %w", err)
+		}
+
+		switch part.FormName() {
+		case "file":
+			req.Name = part.FileName()
+			req.Document, err = h.readDocument(part)
+		case "question":
+			req.Question, err = readPart(part, int64(h.limits.MaxQuestionLength)*utf8.UTFMax)
+		}
+		_ = part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readDocument reads a document, failing with errDocumentTooLarge past MaxDocumentBytes.
+func (h *AskHandler) readDocument(body io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, h.limits.MaxDocumentBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if int64(len(data)) > h.limits.MaxDocumentBytes {
+		return "", errDocumentTooLarge
+	}
+	return string(data), nil
+}
+
+// readPart reads a small multipart form field. At most limit+1 bytes are read, so an
+// oversized value still fails the length check that follows.
+func readPart(part *multipart.Part, limit int64) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(part, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read form field %s: %w", part.FormName(), err)
+	}
+	return string(data), nil
+}
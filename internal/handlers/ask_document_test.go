@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/rag"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestAskHandler_ServeDocument(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{
+		MaxQuestionLength: 100,
+		MaxDocumentBytes:  1024,
+	})
+
+	multipartBody := func(question, document string) (*bytes.Buffer, string) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if question != "" {
+			_ = writer.WriteField("question", question)
+		}
+		part, _ := writer.CreateFormFile("file", "meeting.md")
+		_, _ = part.Write([]byte(document))
+		_ = writer.Close()
+		return &body, writer.FormDataContentType()
+	}
+
+	tests := []struct {
+		name           string
+		buildRequest   func() *http.Request
+		expectedStatus int
+		wantDocument   string
+		wantQuestion   string
+		wantName       string
+		engineErr      error
+	}{
+		{
+			name: "raw body with question parameter",
+			buildRequest: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/ask/document?question=Who+attended%3F", strings.NewReader("Alice and Bob met."))
+				req.Header.Set("Content-Type", "text/plain")
+				return req
+			},
+			expectedStatus: http.StatusOK,
+			wantDocument:   "Alice and Bob met.",
+			wantQuestion:   "Who attended?",
+		},
+		{
+			name: "multipart upload",
+			buildRequest: func() *http.Request {
+				body, contentType := multipartBody("Summarize the decisions", "We decided to ship.")
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/ask/document", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			expectedStatus: http.StatusOK,
+			wantDocument:   "We decided to ship.",
+			wantQuestion:   "Summarize the decisions",
+			wantName:       "meeting.md",
+		},
+		{
+			name: "empty document",
+			buildRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/v1/ask/document", strings.NewReader("  "))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "document too large",
+			buildRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/v1/ask/document", strings.NewReader(strings.Repeat("a", 2048)))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "multipart document too large",
+			buildRequest: func() *http.Request {
+				body, contentType := multipartBody("", strings.Repeat("a", 2048))
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/ask/document", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "question too long",
+			buildRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/v1/ask/document?question="+strings.Repeat("a", 101), strings.NewReader("text"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "extractive engine",
+			buildRequest: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/v1/ask/document", strings.NewReader("text"))
+			},
+			engineErr:      rag.ErrGenerationDisabled,
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			mockRAGEngine.response = rag.AskResponse{Answer: "An answer."}
+			mockRAGEngine.err = tt.engineErr
+
+			w := httptest.NewRecorder()
+			handler.ServeDocument(w, tt.buildRequest())
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("ServeDocument() status = %d, want %d: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			got := mockRAGEngine.lastDocumentRequest
+			if got.Document != tt.wantDocument || got.Question != tt.wantQuestion || got.Name != tt.wantName {
+				t.Errorf("AskDocument() request = %+v, want document %q, question %q, name %q", got, tt.wantDocument, tt.wantQuestion, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// wantsMarkdown reports whether the client asked for the answer as a markdown note,
+// either via ?format=md or an Accept header preferring text/markdown.
+func wantsMarkdown(r *http.Request) bool {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "md", "markdown":
+		return true
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "text/markdown")
+}
+
+// formatAnswerMarkdown renders an answer as an Obsidian-ready markdown note.
+// The question becomes the title and cited notes are listed as wikilinks in a Sources section.
+func formatAnswerMarkdown(question string, resp AskResponse) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", strings.TrimSpace(question))
+	b.WriteString(strings.TrimSpace(resp.Answer))
+	b.WriteString("\n")
+
+	seen := make(map[string]bool)
+	var links []string
+	for _, ref := range resp.References {
+		link := wikilink(ref)
+		if link == "" {
+			continue
+		}
+		if ref.Vault != "" {
+			link += " (" + ref.Vault + ")"
+		}
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+
+	if len(links) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		for _, link := range links {
+			fmt.Fprintf(&b, "- %s\n", link)
+		}
+	}
+
+	return b.String()
+}
+
+// wikilink builds an Obsidian wikilink for a reference, pointing at the deepest heading when known.
+// Example: rel_path "projects/main.md" with heading path "# Overview > ## Goals" yields [[projects/main#Goals]].
+func wikilink(ref ReferenceResponse) string {
+	target := strings.TrimSuffix(ref.RelPath, ".md")
+	if target == "" {
+		return ""
+	}
+
+	if ref.HeadingPath != "" {
+		parts := strings.Split(ref.HeadingPath, ">")
+		heading := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(parts[len(parts)-1]), "#"))
+		if heading != "" {
+			target += "#" + heading
+		}
+	}
+
+	return "[[" + target + "]]"
+}
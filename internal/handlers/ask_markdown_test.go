@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/rag"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestFormatAnswerMarkdown(t *testing.T) {
+	resp := AskResponse{
+		Answer: "The project is about RAG systems.\n",
+		References: []ReferenceResponse{
+			{Vault: "personal", RelPath: "projects/main.md", HeadingPath: "# Overview > ## Goals", ChunkIndex: 0},
+			{Vault: "personal", RelPath: "projects/main.md", HeadingPath: "# Overview > ## Goals", ChunkIndex: 1},
+			{Vault: "work", RelPath: "notes/rag.md", HeadingPath: "", ChunkIndex: 0},
+		},
+	}
+
+	got := formatAnswerMarkdown("What is the project about?", resp)
+	want := "# What is the project about?\n\n" +
+		"The project is about RAG systems.\n\n" +
+		"## Sources\n\n" +
+		"- [[projects/main#Goals]] (personal)\n" +
+		"- [[notes/rag]] (work)\n"
+	if got != want {
+		t.Errorf("formatAnswerMarkdown() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatAnswerMarkdown_NoReferences(t *testing.T) {
+	got := formatAnswerMarkdown("Question?", AskResponse{Answer: "I don't know."})
+	if strings.Contains(got, "## Sources") {
+		t.Errorf("formatAnswerMarkdown() should omit Sources without references, got %q", got)
+	}
+}
+
+func TestAskHandler_MarkdownExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+	}{
+		{name: "format query parameter", query: "format=md"},
+		{name: "accept header", accept: "text/markdown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			mockRAGEngine.response = rag.AskResponse{
+				Answer:     "RAG answer.",
+				References: []rag.Reference{{Vault: "personal", RelPath: "projects/main.md", HeadingPath: "# Overview"}},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ask", bytes.NewReader([]byte(`{"question":"What?"}`)))
+			req.URL.RawQuery = tt.query
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+				t.Errorf("expected text/markdown content type, got %q", ct)
+			}
+			if !strings.Contains(w.Body.String(), "- [[projects/main#Overview]] (personal)") {
+				t.Errorf("expected wikilinked source in body, got %q", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/tracing"
+)
+
+// AnswerReport is the report.json file of an answer report archive.
+//
+// swagger:model AnswerReport
+type AnswerReport struct {
+	// CreatedAt is when the report was generated.
+	CreatedAt time.Time `json:"created_at"`
+	// TraceID identifies the OpenTelemetry trace of the reported ask (omitted when tracing is disabled).
+	TraceID string `json:"trace_id,omitempty"`
+	// Request is the ask request as received.
+	Request AskRequest `json:"request"`
+	// Response is the answer with full debug information (retrieved chunks, scores, latency).
+	Response AskResponse `json:"response"`
+	// Redactions is the number of emails, credentials, and API keys replaced in the archive.
+	Redactions int `json:"redactions"`
+}
+
+// reportRedaction replaces one kind of sensitive text in an answer report.
+type reportRedaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// reportRedactions are applied to every file of an answer report. Values stop at quotes and
+// backslashes so redacting the encoded report.json keeps it valid JSON.
+var reportRedactions = []reportRedaction{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED:email]"},
+	{regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token|api[_-]?key)(\s*[:=]\s*)[^\s"\\]+`), "${1}${2}[REDACTED:secret]"},
+	{regexp.MustCompile(`\b(?:sk|pk|rk|ghp|gho|ghs|xox[abpr])[-_][A-Za-z0-9_-]{16,}\b`), "[REDACTED:key]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED:key]"},
+	{regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{16,}`), "Bearer [REDACTED:key]"},
+}
+
+// redactReportText replaces emails, credentials, and API keys in text and returns the
+// redacted text with the number of replacements.
+func redactReportText(text string) (string, int) {
+	count := 0
+	for _, redaction := range reportRedactions {
+		text = redaction.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			count++
+			return redaction.pattern.ReplaceAllString(match, redaction.replacement)
+		})
+	}
+	return text, count
+}
+
+// ServeReport handles HTTP requests that export an answer report.
+//
+// swagger:route POST /api/v1/ask/report reportAnswer
+//
+// # Export an answer report
+//
+// Answers the question afresh in debug mode and returns a zip archive for attaching to a bug
+// report about a wrong or missing answer, so maintainers can reproduce retrieval issues:
+// report.json (request, answer, references, retrieved chunk texts and scores, latency
+// breakdown, retrieval config hash, trace ID), prompt.txt (the answer prompt), llm_output.txt
+// (the model's unprocessed reply), and chunks.md (the retrieved chunks, readable). Emails,
+// credentials, and API keys are redacted from every file; note paths and texts are not, so
+// review the archive before sharing it. The body and query parameters are those of POST /api/v1/ask.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/zip
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/AskRequest"
+//   - in: query
+//     name: include_cold
+//     type: boolean
+//     description: Also search notes that were moved to cold storage
+//     required: false
+//
+// responses:
+//
+//	'200':
+//	  description: Zip archive with report.json, prompt.txt, llm_output.txt, and chunks.md (prompt files are omitted when no answer was generated)
+//	'400':
+//	  description: Bad request (invalid question, question too long, or invalid vault name)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Request body too large
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: External service error (LLM or embedding service unavailable)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *AskHandler) ServeReport(w http.ResponseWriter, r *http.Request) {
+	req, ragReq, ok := h.decodeAskRequest(w, r)
+	if !ok {
+		return
+	}
+	ragReq.Debug = true
+	ragReq.NoCache = true
+	ragReq.CapturePrompt = true
+
+	ctx, span := tracing.Start(r.Context(), "rag.report")
+	defer span.End()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ragResp, err := h.ragEngine.Ask(ctx, ragReq)
+	if err != nil {
+		h.handleRAGError(w, ctx, err, "Failed to process RAG query")
+		return
+	}
+
+	now := time.Now().UTC()
+	archive, redactions, err := buildAnswerReport(AnswerReport{
+		CreatedAt: now,
+		TraceID:   span.TraceID(),
+		Request:   req,
+		Response:  h.askResponse(ctx, ragResp),
+	}, ragResp.Debug)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to build answer report", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to build answer report")
+		return
+	}
+	logger.InfoContext(ctx, "answer report exported", "trace_id", span.TraceID(), "bytes", len(archive), "redactions", redactions)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="answer-report-%s.zip"`, now.Format("20060102-150405")))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(archive)
+}
+
+// reportFile is one file of an answer report archive.
+type reportFile struct {
+	name    string
+	content string
+}
+
+// buildAnswerReport writes the redacted report archive and returns it with the number of
+// redactions. debug supplies the prompt and chunk files; without it (the safety filter drops
+// debug info when it acts) the archive holds report.json only.
+func buildAnswerReport(report AnswerReport, debug *rag.DebugInfo) ([]byte, int, error) {
+	var files []reportFile
+	if debug != nil {
+		if prompt := debug.Prompt; prompt != nil {
+			files = append(files,
+				reportFile{"prompt.txt", "=== System ===\n" + prompt.System + "\n\n=== User ===\n" + prompt.User + "\n"},
+				reportFile{"llm_output.txt", prompt.Output + "\n"},
+			)
+		}
+		files = append(files, reportFile{"chunks.md", formatReportChunks(debug.RetrievedChunks)})
+	}
+
+	for i := range files {
+		content, count := redactReportText(files[i].content)
+		files[i].content = content
+		report.Redactions += count
+	}
+	// Count the report's own redactions first so report.json states the total
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode report: %w", err)
+	}
+	_, count := redactReportText(string(data))
+	report.Redactions += count
+	data, err = json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode report: %w", err)
+	}
+	reportJSON, _ := redactReportText(string(data))
+	files = append([]reportFile{{"report.json", reportJSON + "\n"}}, files...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		if _, err := fw.Write([]byte(file.content)); err != nil {
+			return nil, 0, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), report.Redactions, nil
+}
+
+// formatReportChunks renders the retrieved chunks, best first, with their scores.
+func formatReportChunks(chunks []rag.RetrievedChunk) string {
+	var b strings.Builder
+	b.WriteString("# Retrieved chunks\n")
+	if len(chunks) == 0 {
+		b.WriteString("\nNo chunks were retrieved.\n")
+	}
+	for _, chunk := range chunks {
+		fmt.Fprintf(&b, "\n## %d. %s", chunk.Rank, chunk.RelPath)
+		if chunk.HeadingPath != "" {
+			fmt.Fprintf(&b, " (%s)", chunk.HeadingPath)
+		}
+		fmt.Fprintf(&b, "\n\nchunk_id: %s, vector: %.3f, lexical: %.3f, final: %.3f\n\n%s\n",
+			chunk.ChunkID, chunk.ScoreVector, chunk.ScoreLexical, chunk.ScoreFinal, chunk.Text)
+	}
+	return b.String()
+}
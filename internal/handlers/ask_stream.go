@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+)
+
+// StreamToken is the payload of a "token" event in a streamed answer.
+//
+// swagger:model StreamToken
+type StreamToken struct {
+	// Text is the next piece of the answer.
+	Text string `json:"text"`
+}
+
+// wantsStream reports whether the client asked for the answer as Server-Sent Events,
+// via stream=true or an Accept header of text/event-stream.
+func wantsStream(r *http.Request) bool {
+	if streamParam := r.URL.Query().Get("stream"); streamParam != "" {
+		return strings.ToLower(streamParam) == "true" || streamParam == "1"
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "text/event-stream")
+}
+
+// serveStream answers ragReq over Server-Sent Events. "token" events carry the answer as
+// it is generated and a final "done" event carries the full AskResponse, with references
+// and debug information. Errors before the first token get a regular JSON error response;
+// later errors end the stream with an "error" event.
+func (h *AskHandler) serveStream(w http.ResponseWriter, ctx context.Context, ragReq rag.AskRequest) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.ErrorContext(ctx, "response writer does not support streaming")
+		h.writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	started := false
+	send := func(event string, payload any) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := writeEvent(w, event, payload); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	ragResp, err := h.ragEngine.AskStream(ctx, ragReq, func(token string) error {
+		return send("token", StreamToken{Text: token})
+	})
+	if err != nil {
+		if !started {
+			h.handleRAGError(w, ctx, err, "Failed to process RAG query")
+			return
+		}
+		logger.ErrorContext(ctx, "RAG engine error during stream", "error", err)
+		_ = send("error", ErrorResponse{Error: "Failed to process RAG query"})
+		return
+	}
+
+	if err := send("done", h.askResponse(ctx, ragResp)); err != nil {
+		logger.WarnContext(ctx, "failed to write final stream event", "error", err)
+	}
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload.
+func writeEvent(w http.ResponseWriter, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event, err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return fmt.Errorf("failed to write %s event: %w", event, err)
+	}
+	return nil
+}
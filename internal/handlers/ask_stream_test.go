@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/rag"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+// streamEvent is one parsed Server-Sent Event.
+type streamEvent struct {
+	name string
+	data string
+}
+
+// parseEvents splits a Server-Sent Events body into events.
+func parseEvents(t *testing.T, body string) []streamEvent {
+	t.Helper()
+	var events []streamEvent
+	var current streamEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "" && current.name != "":
+			events = append(events, current)
+			current = streamEvent{}
+		}
+	}
+	return events
+}
+
+func TestAskHandler_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+	}{
+		{name: "stream query parameter", query: "stream=true"},
+		{name: "accept header", accept: "text/event-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			mockRAGEngine.response = rag.AskResponse{
+				Answer:     "RAG answer here.",
+				References: []rag.Reference{{Vault: "personal", RelPath: "projects/main.md", HeadingPath: "# Overview"}},
+				Debug:      &rag.DebugInfo{RetrievedChunks: []rag.RetrievedChunk{{ChunkID: "chunk-1", Rank: 1}}},
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ask", bytes.NewReader([]byte(`{"question":"What?"}`)))
+			req.URL.RawQuery = tt.query
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("expected text/event-stream content type, got %q", ct)
+			}
+
+			events := parseEvents(t, w.Body.String())
+			if len(events) != 4 {
+				t.Fatalf("expected 3 token events and a done event, got %+v", events)
+			}
+			var answer strings.Builder
+			for _, event := range events[:3] {
+				var token StreamToken
+				if event.name != "token" || json.Unmarshal([]byte(event.data), &token) != nil {
+					t.Fatalf("expected a token event, got %+v", event)
+				}
+				answer.WriteString(token.Text)
+			}
+			if answer.String() != "RAG answer here." {
+				t.Errorf("streamed answer = %q, want %q", answer.String(), "RAG answer here.")
+			}
+
+			done := events[3]
+			var resp AskResponse
+			if done.name != "done" || json.Unmarshal([]byte(done.data), &resp) != nil {
+				t.Fatalf("expected a done event with the response, got %+v", done)
+			}
+			if resp.Answer != "RAG answer here." || len(resp.References) != 1 || resp.Debug == nil || len(resp.Debug.RetrievedChunks) != 1 {
+				t.Errorf("done event = %+v, want the answer with references and debug info", resp)
+			}
+		})
+	}
+}
+
+func TestAskHandler_StreamErrorBeforeFirstToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{err: fmt.Errorf("%w: connection refused", rag.ErrLLMFailure)}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ask?stream=true", bytes.NewReader([]byte(`{"question":"What?"}`)))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error, got content type %q", ct)
+	}
+}
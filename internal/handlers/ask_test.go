@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
 	storage_mocks "helloworld-ai/internal/storage/mocks"
 
 	"go.uber.org/mock/gomock"
@@ -21,7 +25,7 @@ func TestAskHandler_DebugMode(t *testing.T) {
 	mockRAGEngine := &mockRAGEngine{}
 	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
 
-	handler := NewAskHandler(mockRAGEngine, mockVaultRepo, nil, "")
+	handler := NewAskHandler(mockRAGEngine, mockVaultRepo, nil, "", RequestLimits{})
 
 	tests := []struct {
 		name           string
@@ -182,14 +186,18 @@ func TestAskHandler_DebugMode(t *testing.T) {
 
 // mockRAGEngine is a simple mock for testing
 type mockRAGEngine struct {
-	lastRequest rag.AskRequest
-	response    rag.AskResponse
-	err         error
+	lastRequest         rag.AskRequest
+	lastDocumentRequest rag.DocumentRequest
+	response            rag.AskResponse
+	searchResponse      rag.SearchResponse
+	err                 error
 }
 
 func (m *mockRAGEngine) reset() {
 	m.lastRequest = rag.AskRequest{}
+	m.lastDocumentRequest = rag.DocumentRequest{}
 	m.response = rag.AskResponse{}
+	m.searchResponse = rag.SearchResponse{}
 	m.err = nil
 }
 
@@ -201,3 +209,317 @@ func (m *mockRAGEngine) Ask(ctx context.Context, req rag.AskRequest) (rag.AskRes
 	return m.response, nil
 }
 
+func (m *mockRAGEngine) AskDocument(ctx context.Context, req rag.DocumentRequest) (rag.AskResponse, error) {
+	m.lastDocumentRequest = req
+	if m.err != nil {
+		return rag.AskResponse{}, m.err
+	}
+	return m.response, nil
+}
+
+func (m *mockRAGEngine) AskStream(ctx context.Context, req rag.AskRequest, onToken func(token string) error) (rag.AskResponse, error) {
+	m.lastRequest = req
+	if m.err != nil {
+		return rag.AskResponse{}, m.err
+	}
+	for _, token := range strings.SplitAfter(m.response.Answer, " ") {
+		if err := onToken(token); err != nil {
+			return rag.AskResponse{}, err
+		}
+	}
+	return m.response, nil
+}
+
+func (m *mockRAGEngine) Search(ctx context.Context, req rag.AskRequest) (rag.SearchResponse, error) {
+	m.lastRequest = req
+	if m.err != nil {
+		return rag.SearchResponse{}, m.err
+	}
+	return m.searchResponse, nil
+}
+
+
+func TestAskHandler_RequestLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{
+		MaxBodyBytes:      256,
+		MaxQuestionLength: 20,
+		MaxVaults:         2,
+		MaxFolders:        3,
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "within limits",
+			body:           `{"question":"What is RAG?"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "question too long",
+			body:           `{"question":"` + strings.Repeat("a", 21) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "body too large",
+			body:           `{"question":"` + strings.Repeat("a", 300) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "too many vaults",
+			body:           `{"question":"What is RAG?","vaults":["a","b","c"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many folders with operators",
+			body:           `{"question":"folder:d What is RAG?","folders":["a","b","c"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "folders within limit",
+			body:           `{"question":"What is RAG?","folders":["a","b","c"]}`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.expectedStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAskHandler_QuestionOperators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return([]storage.VaultRecord{{ID: 1, Name: "personal"}, {ID: 2, Name: "work"}}, nil).AnyTimes()
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, mockVaultRepo, nil, "", RequestLimits{})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "operators fill filters", body: `{"question":"vault:work folder:Projects/ tag:#golang before:2024-01-01 How do goroutines leak?","folders":["Projects"]}`, expectedStatus: http.StatusOK},
+		{name: "invalid date", body: `{"question":"before:yesterday What happened?"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown vault", body: `{"question":"vault:school What happened?"}`, expectedStatus: http.StatusBadRequest},
+		{name: "only operators", body: `{"question":"tag:#golang"}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// The last successful request is the one with every operator
+	mockRAGEngine.reset()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tests[0].body)))
+	got := mockRAGEngine.lastRequest
+	if got.Question != "How do goroutines leak?" || strings.Join(got.Vaults, ",") != "work" || strings.Join(got.Folders, ",") != "Projects" ||
+		strings.Join(got.Tags, ",") != "golang" || got.UpdatedBefore.Format("2006-01-02") != "2024-01-01" || !got.UpdatedAfter.IsZero() {
+		t.Errorf("RAG request = %+v, want the operators moved into its filters", got)
+	}
+}
+
+func TestAskHandler_MetadataFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	body := `{"question":"tag:golang after:2023-06-01 What changed?","tags":["#Project-X","golang"],"modified_after":"2024-01-01","modified_before":"2024-06-01","created_after":"2023-01-01"}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got := mockRAGEngine.lastRequest
+	// after:2023-06-01 is looser than modified_after, so the body bound wins
+	if strings.Join(got.Tags, ",") != "golang,project-x" || got.UpdatedAfter.Format("2006-01-02") != "2024-01-01" ||
+		got.UpdatedBefore.Format("2006-01-02") != "2024-06-01" || got.CreatedAfter.Format("2006-01-02") != "2023-01-01" || !got.CreatedBefore.IsZero() {
+		t.Errorf("RAG request = %+v, want body filters merged with the operators", got)
+	}
+
+	mockRAGEngine.reset()
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(`{"question":"What changed?","created_before":"01/02/2024"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid created_before: expected status 400, got %d", w.Code)
+	}
+}
+
+func TestAskHandler_MinScore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedMin    rag.ScoreThresholds
+	}{
+		{name: "override", body: `{"question":"What did I write about gardening?","min_score":{"vector":0.2,"final":0.25}}`, expectedStatus: http.StatusOK, expectedMin: rag.ScoreThresholds{Vector: 0.2, Final: 0.25}},
+		{name: "final only", body: `{"question":"What did I write about gardening?","min_score":{"final":0.3}}`, expectedStatus: http.StatusOK, expectedMin: rag.ScoreThresholds{Final: 0.3}},
+		{name: "omitted", body: `{"question":"What did I write about gardening?"}`, expectedStatus: http.StatusOK},
+		{name: "out of range", body: `{"question":"What did I write about gardening?","min_score":{"vector":1.5}}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative", body: `{"question":"What did I write about gardening?","min_score":{"final":-0.1}}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK && mockRAGEngine.lastRequest.MinScore != tt.expectedMin {
+				t.Errorf("MinScore = %+v, want %+v", mockRAGEngine.lastRequest.MinScore, tt.expectedMin)
+			}
+		})
+	}
+}
+
+func TestAskHandler_RetrievalOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       rag.RetrievalOverrides
+	}{
+		{
+			name:           "override",
+			body:           `{"question":"What did I write about gardening?","retrieval_options":{"vector_weight":0.9,"lexical_weight":0.1,"candidate_k_per_scope":30,"max_candidates":400}}`,
+			expectedStatus: http.StatusOK,
+			expected:       rag.RetrievalOverrides{VectorWeight: 0.9, LexicalWeight: 0.1, CandidateKPerScope: 30, MaxCandidates: 400},
+		},
+		{name: "omitted", body: `{"question":"What did I write about gardening?"}`, expectedStatus: http.StatusOK},
+		{name: "weight out of range", body: `{"question":"What did I write about gardening?","retrieval_options":{"vector_weight":1.5}}`, expectedStatus: http.StatusBadRequest},
+		{name: "too many candidates", body: `{"question":"What did I write about gardening?","retrieval_options":{"max_candidates":5000}}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK && mockRAGEngine.lastRequest.Retrieval != tt.expected {
+				t.Errorf("Retrieval = %+v, want %+v", mockRAGEngine.lastRequest.Retrieval, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAskHandler_GenerationOverrides(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{
+		Generation: rag.GenerationLimits{
+			DefaultModel:  "Qwen2.5-3B-Instruct-Q4_K_M",
+			AllowedModels: []string{"Llama-3.1-8B-Instruct-Q4_K_M"},
+			MaxTokens:     1024,
+		},
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       rag.GenerationOverrides
+	}{
+		{name: "omitted", body: `{"question":"What did I plant?"}`, expectedStatus: http.StatusOK},
+		{
+			name:           "allowed overrides",
+			body:           `{"question":"What did I plant?","model":"Llama-3.1-8B-Instruct-Q4_K_M","temperature":0.9,"max_tokens":512}`,
+			expectedStatus: http.StatusOK,
+			expected:       rag.GenerationOverrides{Model: "Llama-3.1-8B-Instruct-Q4_K_M", Temperature: 0.9, MaxTokens: 512},
+		},
+		{name: "model not allowed", body: `{"question":"What did I plant?","model":"gpt-4"}`, expectedStatus: http.StatusBadRequest},
+		{name: "temperature out of range", body: `{"question":"What did I plant?","temperature":3}`, expectedStatus: http.StatusBadRequest},
+		{name: "too many tokens", body: `{"question":"What did I plant?","max_tokens":4096}`, expectedStatus: http.StatusBadRequest},
+		{name: "system prompt disabled", body: `{"question":"What did I plant?","system_prompt_override":"Answer in French."}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK && mockRAGEngine.lastRequest.Generation != tt.expected {
+				t.Errorf("Generation = %+v, want %+v", mockRAGEngine.lastRequest.Generation, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAskHandler_EngineErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "vector store down", err: fmt.Errorf("%w: connection refused", rag.ErrVectorStoreUnavailable), expectedStatus: http.StatusServiceUnavailable},
+		{name: "embedding service down", err: fmt.Errorf("%w: bad status 500", rag.ErrEmbeddingUnavailable), expectedStatus: http.StatusBadGateway},
+		{name: "LLM failure", err: fmt.Errorf("%w: no choices returned", rag.ErrLLMFailure), expectedStatus: http.StatusBadGateway},
+		{name: "generation timeout", err: fmt.Errorf("%w: %w", rag.ErrLLMFailure, context.DeadlineExceeded), expectedStatus: http.StatusGatewayTimeout},
+		{name: "no context", err: rag.ErrNoContext, expectedStatus: http.StatusBadRequest},
+		{name: "generation disabled", err: rag.ErrGenerationDisabled, expectedStatus: http.StatusNotImplemented},
+		// Messages naming a dependency no longer decide the status
+		{name: "untyped error", err: errors.New("failed to list vaults: qdrant embed llm"), expectedStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			mockRAGEngine.err = tt.err
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(`{"question":"What did I plant?"}`)))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
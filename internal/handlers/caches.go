@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+)
+
+// CachesHandler handles HTTP requests for cache statistics and flushing.
+type CachesHandler struct {
+	registry *cache.Registry
+}
+
+// NewCachesHandler creates a new CachesHandler.
+func NewCachesHandler(registry *cache.Registry) *CachesHandler {
+	return &CachesHandler{
+		registry: registry,
+	}
+}
+
+// CachesResponse represents the response from the cache endpoints.
+//
+// swagger:model CachesResponse
+type CachesResponse struct {
+	// Caches lists every registered cache, ordered by name
+	Caches []CacheStatsResponse `json:"caches"`
+	// Flushed lists the caches emptied by a DELETE request
+	Flushed []string `json:"flushed,omitempty"`
+}
+
+// CacheStatsResponse describes a cache's size and counters since start or its last flush.
+//
+// swagger:model CacheStatsResponse
+type CacheStatsResponse struct {
+	// Name of the cache (question_embeddings, vaults, folders, prompt_tokens, answers)
+	Name string `json:"name"`
+	// Storage is memory or sqlite
+	Storage string `json:"storage"`
+	// Entries currently cached (in-memory caches only)
+	Entries int `json:"entries"`
+	// MaxEntries is the size bound (0: unbounded)
+	MaxEntries int `json:"max_entries"`
+	// TTLSeconds is how long entries stay cached (0: until evicted or invalidated)
+	TTLSeconds int64 `json:"ttl_seconds"`
+	// Hits counts lookups served from the cache
+	Hits int64 `json:"hits"`
+	// Misses counts lookups that were not
+	Misses int64 `json:"misses"`
+	// Evictions counts entries dropped to stay within MaxEntries
+	Evictions int64 `json:"evictions"`
+	// HitRate is hits / (hits + misses)
+	HitRate float64 `json:"hit_rate"`
+}
+
+// ServeHTTP handles HTTP requests for the caches.
+//
+// swagger:route GET /api/v1/admin/caches getCaches
+//
+// # Get cache statistics
+//
+// Returns the size and hit/miss counters of every cache. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Cache statistics retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/CachesResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/admin/caches flushCaches
+//
+// # Flush caches
+//
+// Empties every cache, or only the one named by the name query parameter, and resets its
+// counters, e.g. after editing the database by hand. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: query
+//     name: name
+//     type: string
+//     required: false
+//     description: Cache to flush (omit to flush all)
+//
+// responses:
+//
+//	'200':
+//	  description: Caches flushed; returns the flushed names and the statistics after flushing
+//	  schema:
+//	    "$ref": "#/definitions/CachesResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown cache
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: A cache could not be flushed
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *CachesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeCaches(w, nil)
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if name != "" {
+			if err := h.registry.Flush(ctx, name); err != nil {
+				if errors.Is(err, cache.ErrUnknownCache) {
+					logger.WarnContext(ctx, "unknown cache", "cache", name)
+					h.writeError(w, http.StatusNotFound, "Unknown cache: "+name)
+					return
+				}
+				logger.ErrorContext(ctx, "failed to flush cache", "cache", name, "error", err)
+				h.writeError(w, http.StatusInternalServerError, "Failed to flush cache")
+				return
+			}
+			logger.InfoContext(ctx, "cache flushed", "cache", name)
+			h.writeCaches(w, []string{name})
+			return
+		}
+
+		flushed, err := h.registry.FlushAll(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to flush caches", "flushed", flushed, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to flush caches")
+			return
+		}
+		logger.InfoContext(ctx, "caches flushed", "caches", flushed)
+		h.writeCaches(w, flushed)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeCaches writes the stats of every cache and the names of the flushed ones.
+func (h *CachesHandler) writeCaches(w http.ResponseWriter, flushed []string) {
+	stats := h.registry.Stats()
+	caches := make([]CacheStatsResponse, 0, len(stats))
+	for _, s := range stats {
+		caches = append(caches, CacheStatsResponse{
+			Name:       s.Name,
+			Storage:    s.Storage,
+			Entries:    s.Entries,
+			MaxEntries: s.MaxEntries,
+			TTLSeconds: s.TTLSeconds,
+			Hits:       s.Hits,
+			Misses:     s.Misses,
+			Evictions:  s.Evictions,
+			HitRate:    s.HitRate,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(CachesResponse{Caches: caches, Flushed: flushed})
+}
+
+// writeError writes an error response.
+func (h *CachesHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
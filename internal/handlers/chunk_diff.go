@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// ChunkDiffHandler handles HTTP requests for chunk-level diffs between index generations.
+type ChunkDiffHandler struct {
+	indexerPipeline *indexer.Pipeline
+	vaultManager    *vault.Manager
+}
+
+// NewChunkDiffHandler creates a new ChunkDiffHandler.
+func NewChunkDiffHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager) *ChunkDiffHandler {
+	return &ChunkDiffHandler{
+		indexerPipeline: indexerPipeline,
+		vaultManager:    vaultManager,
+	}
+}
+
+// ChunkDiffResponse compares a note's chunks before and after its last re-index.
+//
+// swagger:model ChunkDiffResponse
+type ChunkDiffResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Path is the note path relative to the vault root
+	Path string `json:"path"`
+	// HasPrevious is false when the note was never re-indexed (every chunk shows as added)
+	HasPrevious bool `json:"has_previous"`
+	// PreviousChunkerVersion is the chunker version that split the previous generation
+	PreviousChunkerVersion string `json:"previous_chunker_version,omitempty"`
+	// CurrentChunkerVersion is the chunker version that split the current chunks
+	CurrentChunkerVersion string `json:"current_chunker_version,omitempty"`
+	// Added is the number of new chunks
+	Added int `json:"added"`
+	// Removed is the number of chunks that no longer exist
+	Removed int `json:"removed"`
+	// Changed is the number of chunks under the same heading with different text
+	Changed int `json:"changed"`
+	// Unchanged is the number of chunks with identical text
+	Unchanged int `json:"unchanged"`
+	// Chunks lists the current chunks in order, followed by the removed ones
+	Chunks []ChunkDiffEntryResponse `json:"chunks"`
+}
+
+// ChunkDiffEntryResponse describes one chunk of a diff.
+//
+// swagger:model ChunkDiffEntryResponse
+type ChunkDiffEntryResponse struct {
+	// Change is added, removed, changed, or unchanged
+	Change string `json:"change"`
+	// HeadingPath is the chunk's heading path
+	HeadingPath string `json:"heading_path"`
+	// PreviousHeadingPath is set when an unchanged chunk moved under another heading
+	PreviousHeadingPath string `json:"previous_heading_path,omitempty"`
+	// PreviousIndex is the chunk index in the previous generation (-1 for added chunks)
+	PreviousIndex int `json:"previous_index"`
+	// CurrentIndex is the chunk index now (-1 for removed chunks)
+	CurrentIndex int `json:"current_index"`
+	// PreviousText is the old text of changed and removed chunks
+	PreviousText string `json:"previous_text,omitempty"`
+	// CurrentText is the new text of changed and added chunks
+	CurrentText string `json:"current_text,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for chunk diffs.
+//
+// swagger:route GET /api/v1/vaults/{name}/chunks/diff getChunkDiff
+//
+// # Diff a note's chunks against the previous index
+//
+// Compares the chunks a note had before its last re-index with its current chunks,
+// listing chunks added, removed, and changed (matched by heading path). Use it after
+// changing chunker settings to see what actually changed. One previous generation is kept
+// per note; texts of unchanged chunks are omitted.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: query
+//     name: path
+//     type: string
+//     required: true
+//     description: Note path relative to the vault root (e.g. projects/plan.md)
+//
+// responses:
+//
+//	'200':
+//	  description: Chunk diff computed successfully
+//	  schema:
+//	    "$ref": "#/definitions/ChunkDiffResponse"
+//	'400':
+//	  description: Missing or invalid path
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault or note not indexed
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *ChunkDiffHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+	relPath := strings.TrimPrefix(r.URL.Query().Get("path"), "/")
+	if relPath == "" || path.Clean(relPath) != relPath || strings.HasPrefix(relPath, "../") {
+		h.writeError(w, http.StatusBadRequest, "path must be a note path relative to the vault root")
+		return
+	}
+
+	diff, err := h.indexerPipeline.ChunkDiff(ctx, vaultRecord.ID, relPath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "Note not indexed")
+			return
+		}
+		logger.ErrorContext(ctx, "failed to diff chunks", "vault", vaultName, "rel_path", relPath, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to diff chunks")
+		return
+	}
+
+	resp := ChunkDiffResponse{
+		Vault:                  vaultName,
+		Path:                   diff.RelPath,
+		HasPrevious:            diff.HasPrevious,
+		PreviousChunkerVersion: diff.PreviousChunkerVersion,
+		CurrentChunkerVersion:  diff.CurrentChunkerVersion,
+		Added:                  diff.Added,
+		Removed:                diff.Removed,
+		Changed:                diff.Changed,
+		Unchanged:              diff.Unchanged,
+		Chunks:                 make([]ChunkDiffEntryResponse, 0, len(diff.Entries)),
+	}
+	for _, entry := range diff.Entries {
+		resp.Chunks = append(resp.Chunks, ChunkDiffEntryResponse{
+			Change:              string(entry.Change),
+			HeadingPath:         entry.HeadingPath,
+			PreviousHeadingPath: entry.PreviousHeadingPath,
+			PreviousIndex:       entry.PreviousIndex,
+			CurrentIndex:        entry.CurrentIndex,
+			PreviousText:        entry.PreviousText,
+			CurrentText:         entry.CurrentText,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *ChunkDiffHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
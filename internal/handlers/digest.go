@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/digest"
+)
+
+// Digest periods: the day or the seven days ending on the `to` date.
+const (
+	digestPeriodDaily  = "daily"
+	digestPeriodWeekly = "weekly"
+)
+
+// maxDigestDays bounds the period a digest may cover.
+const maxDigestDays = 31
+
+// DigestHandler handles HTTP requests for digests of recently changed notes.
+type DigestHandler struct {
+	summarizer *digest.Summarizer
+	now        func() time.Time
+}
+
+// NewDigestHandler creates a new DigestHandler. summarizer is nil when digests are unavailable.
+func NewDigestHandler(summarizer *digest.Summarizer) *DigestHandler {
+	return &DigestHandler{
+		summarizer: summarizer,
+		now:        time.Now,
+	}
+}
+
+// DigestRequest selects the period and vaults a digest covers.
+//
+// swagger:model DigestRequest
+type DigestRequest struct {
+	// Period is daily or weekly: the day, or the seven days, ending on `to`. Used when
+	// `from` is not set (default daily).
+	Period string `json:"period,omitempty"`
+	// From is the first day (YYYY-MM-DD, UTC) of the period. Leave out `period` when set.
+	From string `json:"from,omitempty"`
+	// To is the last day (YYYY-MM-DD, UTC) of the period, included (default today).
+	To string `json:"to,omitempty"`
+	// Vaults restricts the digest to these vaults (default all).
+	Vaults []string `json:"vaults,omitempty"`
+}
+
+// DigestResponse is a summary of the notes changed in a period.
+//
+// swagger:model DigestResponse
+type DigestResponse struct {
+	// From is the first day of the period
+	From string `json:"from"`
+	// To is the last day of the period (included)
+	To string `json:"to"`
+	// Summary of the changed notes, citing them by number in brackets ([1])
+	Summary string `json:"summary"`
+	// Citations are the notes the summary cites (every summarized note when it cites none)
+	Citations []DigestCitation `json:"citations"`
+	// Notes is the number of changed notes summarized
+	Notes int `json:"notes"`
+	// Truncated is set when more notes changed than a digest covers; the most recently
+	// changed notes were summarized
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DigestCitation is a note cited by a digest.
+//
+// swagger:model DigestCitation
+type DigestCitation struct {
+	// Number the summary cites the note by
+	Number int `json:"number"`
+	// Vault name
+	Vault string `json:"vault"`
+	// RelPath is the note path relative to the vault root
+	RelPath string `json:"rel_path"`
+	// Title of the note (its file name when it has none)
+	Title string `json:"title"`
+	// ModifiedAt is when the note was last changed (frontmatter modified date, else file modification time)
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// ServeHTTP handles HTTP requests for digests.
+//
+// swagger:route POST /api/v1/digest createDigest
+//
+// # Summarize recently changed notes
+//
+// Lists the notes changed in a period, newest first, and asks the chat model for a short
+// themed summary citing them by number. Notes are retrieved by when they last changed
+// (frontmatter `modified` date, else the file modification time recorded at indexing),
+// not by similarity to a question. At most 30 notes are summarized. A period without
+// changed notes returns an empty digest without calling the model.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/DigestRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Digest generated
+//	  schema:
+//	    "$ref": "#/definitions/DigestResponse"
+//	'400':
+//	  description: Invalid period, dates, or vault name
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Request body too large
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: The chat model failed to write the digest
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'503':
+//	  description: Digests are unavailable
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *DigestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if r.Method != http.MethodPost {
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if h.summarizer == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "Digests are unavailable")
+		return
+	}
+
+	var req DigestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		logger.WarnContext(ctx, "invalid digest request", "error", err)
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	from, to, err := digestPeriod(req, h.now())
+	if err != nil {
+		logger.WarnContext(ctx, "invalid digest period", "error", err)
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.summarizer.Summarize(ctx, digest.SummaryRequest{From: from, To: to, Vaults: req.Vaults})
+	if err != nil {
+		switch {
+		case errors.Is(err, digest.ErrUnknownVault):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, digest.ErrSummaryFailed):
+			logger.ErrorContext(ctx, "failed to generate digest", "error", err)
+			h.writeError(w, http.StatusBadGateway, "Failed to generate digest")
+		default:
+			logger.ErrorContext(ctx, "failed to generate digest", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to generate digest")
+		}
+		return
+	}
+
+	resp := DigestResponse{
+		From:      from.Format(filterDateLayout),
+		To:        to.AddDate(0, 0, -1).Format(filterDateLayout),
+		Summary:   summary.Text,
+		Citations: make([]DigestCitation, 0, len(summary.Citations)),
+		Notes:     summary.Notes,
+		Truncated: summary.Truncated,
+	}
+	for _, citation := range summary.Citations {
+		resp.Citations = append(resp.Citations, DigestCitation{
+			Number:     citation.Number,
+			Vault:      citation.Vault,
+			RelPath:    citation.RelPath,
+			Title:      citation.Title,
+			ModifiedAt: citation.ModifiedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.ErrorContext(ctx, "failed to encode response", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+}
+
+// digestPeriod resolves a digest request to the half-open period [from, to) in UTC days.
+func digestPeriod(req DigestRequest, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.To != "" {
+		date, err := time.Parse(filterDateLayout, req.To)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: date %q (must be YYYY-MM-DD)", req.To)
+		}
+		last = date
+	}
+	to := last.AddDate(0, 0, 1)
+
+	var from time.Time
+	switch {
+	case req.From != "" && req.Period != "":
+		return time.Time{}, time.Time{}, fmt.Errorf("set either period or from, not both")
+	case req.From != "":
+		date, err := time.Parse(filterDateLayout, req.From)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: date %q (must be YYYY-MM-DD)", req.From)
+		}
+		from = date
+	case req.Period == "" || req.Period == digestPeriodDaily:
+		from = last
+	case req.Period == digestPeriodWeekly:
+		from = last.AddDate(0, 0, -6)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("period must be %s or %s", digestPeriodDaily, digestPeriodWeekly)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > maxDigestDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("a digest covers at most %d days", maxDigestDays)
+	}
+	return from, to, nil
+}
+
+// writeError writes an error response.
+func (h *DigestHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/digest"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vault"
+
+	"go.uber.org/mock/gomock"
+)
+
+type digestChat struct {
+	reply string
+	err   error
+}
+
+func (c *digestChat) ChatWithMessages(context.Context, []llm.Message, llm.ChatParams) (string, error) {
+	return c.reply, c.err
+}
+
+func TestDigestPeriod(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		req      DigestRequest
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  string
+	}{
+		{name: "default is today", wantFrom: day(10), wantTo: day(11)},
+		{name: "weekly ending today", req: DigestRequest{Period: "weekly"}, wantFrom: day(4), wantTo: day(11)},
+		{name: "daily on a date", req: DigestRequest{Period: "daily", To: "2026-03-02"}, wantFrom: day(2), wantTo: day(3)},
+		{name: "date range", req: DigestRequest{From: "2026-03-01", To: "2026-03-05"}, wantFrom: day(1), wantTo: day(6)},
+		{name: "period and from", req: DigestRequest{Period: "weekly", From: "2026-03-01"}, wantErr: "either period or from"},
+		{name: "unknown period", req: DigestRequest{Period: "monthly"}, wantErr: "period must be"},
+		{name: "invalid date", req: DigestRequest{From: "March 1"}, wantErr: "invalid from"},
+		{name: "reversed range", req: DigestRequest{From: "2026-03-05", To: "2026-03-01"}, wantErr: "must not be after"},
+		{name: "too long", req: DigestRequest{From: "2026-01-01"}, wantErr: "at most 31 days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := digestPeriod(tt.req, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("digestPeriod() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("digestPeriod() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("digestPeriod() = [%v, %v), want [%v, %v)", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestDigestHandler_ServeHTTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	vaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	vaultRepo.EXPECT().GetOrCreateByName(gomock.Any(), "personal", "/vaults/personal").Return(storage.VaultRecord{ID: 1, Name: "personal", RootPath: "/vaults/personal"}, nil)
+	manager, err := vault.NewManager(context.Background(), vaultRepo, []vault.Config{{Name: "personal", Path: "/vaults/personal"}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	modifiedAt := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	noteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	noteRepo.EXPECT().ListModifiedBetween(gomock.Any(), []int{1}, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), gomock.Any()).
+		Return([]storage.ModifiedNote{{Note: storage.NoteRecord{ID: "n1", VaultID: 1, RelPath: "garden.md", Title: "Garden"}, ModifiedAt: modifiedAt}}, nil).AnyTimes()
+	chunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	chunkRepo.EXPECT().ListByNote(gomock.Any(), "n1").Return([]storage.ChunkRecord{{Text: "Planted tomatoes."}}, nil).AnyTimes()
+
+	tests := []struct {
+		name       string
+		chat       *digestChat
+		body       string
+		wantStatus int
+	}{
+		{name: "digest", chat: &digestChat{reply: "Tomatoes were planted [1]."}, body: `{"period":"weekly","to":"2026-03-10","vaults":["personal"]}`, wantStatus: http.StatusOK},
+		{name: "unknown vault", chat: &digestChat{}, body: `{"vaults":["missing"]}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid period", chat: &digestChat{}, body: `{"period":"hourly"}`, wantStatus: http.StatusBadRequest},
+		{name: "chat failure", chat: &digestChat{err: errors.New("llm down")}, body: `{"period":"weekly","to":"2026-03-10","vaults":["personal"]}`, wantStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewDigestHandler(digest.NewSummarizer(noteRepo, chunkRepo, manager, tt.chat))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/digest", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DigestResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.From != "2026-03-04" || resp.To != "2026-03-10" || resp.Notes != 1 || len(resp.Citations) != 1 {
+				t.Fatalf("response = %+v, want one cited note from 2026-03-04 to 2026-03-10", resp)
+			}
+			if got := resp.Citations[0]; got.Number != 1 || got.Vault != "personal" || got.RelPath != "garden.md" || !got.ModifiedAt.Equal(modifiedAt) {
+				t.Errorf("Citations[0] = %+v, want garden.md", got)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	NewDigestHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/digest", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without summarizer = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
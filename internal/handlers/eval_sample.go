@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/eval"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+const (
+	defaultSampleSize = 20
+	maxSampleSize     = 200
+)
+
+// EvalSampleHandler handles HTTP requests for chunk samples to label for evaluation.
+type EvalSampleHandler struct {
+	chunkRepo    storage.ChunkStore
+	vaultManager *vault.Manager
+}
+
+// NewEvalSampleHandler creates a new EvalSampleHandler.
+func NewEvalSampleHandler(chunkRepo storage.ChunkStore, vaultManager *vault.Manager) *EvalSampleHandler {
+	return &EvalSampleHandler{
+		chunkRepo:    chunkRepo,
+		vaultManager: vaultManager,
+	}
+}
+
+// EvalSampleResponse is a sample of indexed chunks for relevance labeling.
+//
+// swagger:model EvalSampleResponse
+type EvalSampleResponse struct {
+	// Strategy is the sampling strategy used (random or stratified)
+	Strategy string `json:"strategy"`
+	// Seed reproduces this sample when passed back with the same strategy and filters
+	Seed uint64 `json:"seed"`
+	// Chunks is the number of chunks sampled from
+	Chunks int `json:"chunks"`
+	// Samples are the drawn chunks, ordered by vault, path, and chunk index
+	Samples []EvalSampleChunk `json:"samples"`
+}
+
+// EvalSampleChunk is one chunk to label.
+//
+// swagger:model EvalSampleChunk
+type EvalSampleChunk struct {
+	// ChunkID is the stable chunk ID (same as the Qdrant point ID and debug chunk IDs)
+	ChunkID string `json:"chunk_id"`
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Folder is the folder path within the vault (empty for the vault root)
+	Folder string `json:"folder"`
+	// RelPath is the note path relative to the vault root
+	RelPath string `json:"rel_path"`
+	// NoteTitle is the note title, if any
+	NoteTitle string `json:"note_title,omitempty"`
+	// HeadingPath is the chunk's heading hierarchy
+	HeadingPath string `json:"heading_path"`
+	// ChunkIndex is the chunk's position within the note
+	ChunkIndex int `json:"chunk_index"`
+	// Text is the chunk text
+	Text string `json:"text"`
+	// Runes is the chunk length in runes
+	Runes int `json:"runes"`
+	// Length is the length bucket (short, medium, or long)
+	Length string `json:"length"`
+	// Stratum is the vault/folder/length group the chunk was drawn from
+	Stratum string `json:"stratum"`
+	// Case is a golden dataset case with this chunk as its gold support; add a question
+	// the chunk answers and append it to the eval set
+	Case eval.Case `json:"case"`
+}
+
+// ServeHTTP handles HTTP requests for evaluation samples.
+//
+// swagger:route GET /api/v1/eval/sample getEvalSample
+//
+// # Sample chunks for relevance labeling
+//
+// Draws a reproducible sample of indexed chunks for building labeled evaluation sets.
+// Stratified sampling (the default) takes chunks round-robin from each vault/folder/length
+// group so small folders and unusual chunk lengths are covered; random sampling draws
+// uniformly. Each sample includes an eval set case to complete with a question.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: n
+//     type: integer
+//     default: 20
+//     description: Number of chunks to sample (1-200)
+//   - in: query
+//     name: strategy
+//     type: string
+//     enum: [random, stratified]
+//     default: stratified
+//     description: Sampling strategy
+//   - in: query
+//     name: seed
+//     type: integer
+//     required: false
+//     description: Seed from a previous response to reproduce its sample (omit for a new sample)
+//   - in: query
+//     name: vault
+//     type: string
+//     required: false
+//     description: Vault to sample from (omit to sample all vaults)
+//   - in: query
+//     name: folder
+//     type: string
+//     required: false
+//     description: Folder to sample from, with its subfolders (omit to sample all folders)
+//
+// responses:
+//
+//	'200':
+//	  description: Sample drawn successfully
+//	  schema:
+//	    "$ref": "#/definitions/EvalSampleResponse"
+//	'400':
+//	  description: Invalid n, strategy, or seed parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *EvalSampleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+	query := r.URL.Query()
+
+	n := defaultSampleSize
+	if nParam := query.Get("n"); nParam != "" {
+		parsed, err := strconv.Atoi(nParam)
+		if err != nil || parsed <= 0 {
+			logger.WarnContext(ctx, "invalid n parameter", "n", nParam)
+			h.writeError(w, http.StatusBadRequest, "n must be a positive integer")
+			return
+		}
+		n = min(parsed, maxSampleSize)
+	}
+
+	strategy := strings.ToLower(query.Get("strategy"))
+	if strategy == "" {
+		strategy = eval.SampleStratified
+	}
+	if !eval.ValidSampleStrategy(strategy) {
+		h.writeError(w, http.StatusBadRequest, "strategy must be random or stratified")
+		return
+	}
+
+	seed := rand.Uint64()
+	if seedParam := query.Get("seed"); seedParam != "" {
+		parsed, err := strconv.ParseUint(seedParam, 10, 64)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "seed must be a non-negative integer")
+			return
+		}
+		seed = parsed
+	}
+
+	filter := storage.ChunkExportFilter{
+		VaultName: query.Get("vault"),
+		Folder:    strings.Trim(query.Get("folder"), "/"),
+	}
+	if filter.VaultName != "" {
+		if _, err := h.vaultManager.VaultByName(filter.VaultName); err != nil {
+			h.writeError(w, http.StatusNotFound, "Vault not found")
+			return
+		}
+	}
+
+	chunks, err := h.chunkRepo.ListForExport(ctx, filter)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list chunks for sampling", "vault", filter.VaultName, "folder", filter.Folder, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list chunks")
+		return
+	}
+	samples, err := eval.SampleChunks(chunks, n, strategy, seed)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to sample chunks", "strategy", strategy, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to sample chunks")
+		return
+	}
+
+	resp := EvalSampleResponse{
+		Strategy: strategy,
+		Seed:     seed,
+		Chunks:   len(chunks),
+		Samples:  make([]EvalSampleChunk, 0, len(samples)),
+	}
+	for _, sample := range samples {
+		resp.Samples = append(resp.Samples, EvalSampleChunk{
+			ChunkID:     sample.ID,
+			Vault:       sample.VaultName,
+			Folder:      sample.Folder,
+			RelPath:     sample.RelPath,
+			NoteTitle:   sample.NoteTitle,
+			HeadingPath: sample.HeadingPath,
+			ChunkIndex:  sample.ChunkIndex,
+			Text:        sample.Text,
+			Runes:       sample.Runes,
+			Length:      sample.Length,
+			Stratum:     sample.Stratum,
+			Case:        sample.Case(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *EvalSampleHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+const (
+	defaultEventsLimit = 500
+	maxEventsLimit     = 1000
+)
+
+// EventsHandler handles HTTP requests for the index change event log.
+type EventsHandler struct {
+	eventStore storage.IndexEventStore
+}
+
+// NewEventsHandler creates a new EventsHandler.
+func NewEventsHandler(eventStore storage.IndexEventStore) *EventsHandler {
+	return &EventsHandler{
+		eventStore: eventStore,
+	}
+}
+
+// IndexEventsResponse represents the response from the index events endpoint.
+//
+// swagger:model IndexEventsResponse
+type IndexEventsResponse struct {
+	// Events after the requested sequence number, oldest first
+	Events []IndexEvent `json:"events"`
+	// Sequence number to pass as since on the next request
+	NextSince int64 `json:"next_since"`
+	// HasMore is true when more events follow; request again with next_since
+	HasMore bool `json:"has_more"`
+	// Reset is true when events after since are no longer available (pruned, or the
+	// database was replaced). Clients should drop their caches, re-fetch, and continue
+	// from next_since.
+	Reset bool `json:"reset"`
+}
+
+// IndexEvent describes one change to the index.
+//
+// swagger:model IndexEvent
+type IndexEvent struct {
+	// Sequence number, increasing with every event
+	Seq int64 `json:"seq"`
+	// Event type: note_added, note_updated, note_moved, note_deleted, index_cleared, or reindex_completed
+	Type string `json:"type"`
+	// ID of the vault (omitted for events covering all vaults)
+	VaultID int `json:"vault_id,omitempty"`
+	// ID of the note (note events only)
+	NoteID string `json:"note_id,omitempty"`
+	// Relative path of the note within the vault (note events only)
+	RelPath string `json:"rel_path,omitempty"`
+	// Path the note was moved from (note_moved only)
+	PreviousRelPath string `json:"previous_rel_path,omitempty"`
+	// When the event was recorded (RFC3339)
+	CreatedAt string `json:"created_at"`
+}
+
+// ServeHTTP handles HTTP requests for index change events.
+//
+// swagger:route GET /api/v1/events listIndexEvents
+//
+// # List index changes since a sequence number
+//
+// Returns the index changes (notes added, updated, moved, or deleted, the index cleared,
+// and index passes completed) recorded after since, so client-side caches such as folder
+// trees can be updated incrementally instead of re-fetched. Start with since=0, then pass
+// next_since back. When reset is true the requested events are gone and the client should
+// re-fetch everything. reindex_completed and index_cleared events cover many notes at once
+// and also call for a re-fetch.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: since
+//     type: integer
+//     default: 0
+//     description: Return events with a sequence number above this one
+//   - in: query
+//     name: limit
+//     type: integer
+//     default: 500
+//     description: Maximum number of events to return (1-1000)
+//
+// responses:
+//
+//	'200':
+//	  description: Events retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/IndexEventsResponse"
+//	'400':
+//	  description: Invalid since or limit parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	var since int64
+	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		parsed, err := strconv.ParseInt(sinceParam, 10, 64)
+		if err != nil || parsed < 0 {
+			logger.WarnContext(ctx, "invalid since parameter", "since", sinceParam)
+			h.writeError(w, http.StatusBadRequest, "since must be a non-negative integer")
+			return
+		}
+		since = parsed
+	}
+	limit := defaultEventsLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			logger.WarnContext(ctx, "invalid limit parameter", "limit", limitParam)
+			h.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxEventsLimit)
+	}
+
+	oldest, latest, err := h.eventStore.Bounds(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to read index event bounds", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list index events")
+		return
+	}
+
+	resp := IndexEventsResponse{Events: []IndexEvent{}, NextSince: since}
+	// Events after since were pruned, or since comes from a different database
+	if since > latest || (latest > since && (oldest == 0 || oldest > since+1)) {
+		resp.Reset = true
+		resp.NextSince = latest
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	events, err := h.eventStore.ListSince(ctx, since, limit)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list index events", "since", since, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list index events")
+		return
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, IndexEvent{
+			Seq:             event.Seq,
+			Type:            event.Type,
+			VaultID:         event.VaultID,
+			NoteID:          event.NoteID,
+			RelPath:         event.RelPath,
+			PreviousRelPath: event.PreviousRelPath,
+			CreatedAt:       event.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		resp.NextSince = event.Seq
+	}
+	resp.HasMore = resp.NextSince < latest
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *EventsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+)
+
+// FeaturesHandler handles HTTP requests for listing and overriding feature flags.
+type FeaturesHandler struct {
+	flags *features.Flags
+}
+
+// NewFeaturesHandler creates a new FeaturesHandler.
+func NewFeaturesHandler(flags *features.Flags) *FeaturesHandler {
+	return &FeaturesHandler{
+		flags: flags,
+	}
+}
+
+// FeatureFlagsResponse represents the response from the feature flags endpoints.
+//
+// swagger:model FeatureFlagsResponse
+type FeatureFlagsResponse struct {
+	// Flags lists every known feature flag, ordered by name
+	Flags []FeatureFlagResponse `json:"flags"`
+}
+
+// FeatureFlagResponse describes the current state of a feature flag.
+//
+// swagger:model FeatureFlagResponse
+type FeatureFlagResponse struct {
+	// Name of the flag
+	Name string `json:"name"`
+	// Description of the behavior the flag gates
+	Description string `json:"description"`
+	// Enabled is the value currently in effect
+	Enabled bool `json:"enabled"`
+	// Source of the value: default (built in), config (FEATURE_FLAGS), or override (set at runtime)
+	Source string `json:"source"`
+}
+
+// FeatureFlagRequest represents a runtime override of a feature flag.
+//
+// swagger:model FeatureFlagRequest
+type FeatureFlagRequest struct {
+	// Enabled turns the flag on or off until it is reset or the server restarts
+	// required: true
+	Enabled *bool `json:"enabled"`
+}
+
+// ServeHTTP handles HTTP requests for feature flags.
+//
+// swagger:route GET /api/v1/features getFeatureFlags
+//
+// # List feature flags
+//
+// Returns every known feature flag with its current value and where the value came from.
+//
+// ---
+// produces:
+// - application/json
+// responses:
+//
+//	'200':
+//	  description: Feature flags retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/FeatureFlagsResponse"
+//
+// swagger:route PUT /api/v1/admin/features/{name} setFeatureFlag
+//
+// # Override a feature flag
+//
+// Turns a flag on or off at runtime without a redeploy. Overrides are kept in memory
+// and are lost on restart. Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Flag name
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/FeatureFlagRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Flag overridden; returns all flags
+//	  schema:
+//	    "$ref": "#/definitions/FeatureFlagsResponse"
+//	'400':
+//	  description: Invalid request body
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown flag
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/admin/features/{name} resetFeatureFlag
+//
+// # Reset a feature flag
+//
+// Removes the runtime override so the flag returns to its deployment value. Requires the
+// admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Flag name
+//
+// responses:
+//
+//	'200':
+//	  description: Override removed; returns all flags
+//	  schema:
+//	    "$ref": "#/definitions/FeatureFlagsResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown flag
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *FeaturesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+	name := chi.URLParam(r, "name")
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeFlags(w)
+	case http.MethodPut:
+		var req FeatureFlagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			logger.WarnContext(ctx, "invalid feature flag request", "flag", name, "error", err)
+			h.writeError(w, http.StatusBadRequest, "Request body must be {\"enabled\": true|false}")
+			return
+		}
+		if err := h.flags.Set(name, *req.Enabled); err != nil {
+			h.writeFlagError(w, r, name, err)
+			return
+		}
+		logger.InfoContext(ctx, "feature flag overridden", "flag", name, "enabled", *req.Enabled)
+		h.writeFlags(w)
+	case http.MethodDelete:
+		if err := h.flags.Reset(name); err != nil {
+			h.writeFlagError(w, r, name, err)
+			return
+		}
+		logger.InfoContext(ctx, "feature flag override removed", "flag", name, "enabled", h.flags.Enabled(name))
+		h.writeFlags(w)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeFlags writes the state of every flag.
+func (h *FeaturesHandler) writeFlags(w http.ResponseWriter) {
+	states := h.flags.States()
+	flags := make([]FeatureFlagResponse, 0, len(states))
+	for _, state := range states {
+		flags = append(flags, FeatureFlagResponse{
+			Name:        state.Name,
+			Description: state.Description,
+			Enabled:     state.Enabled,
+			Source:      state.Source,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(FeatureFlagsResponse{Flags: flags})
+}
+
+// writeFlagError maps a Set/Reset error to a response.
+func (h *FeaturesHandler) writeFlagError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if errors.Is(err, features.ErrUnknownFlag) {
+		logger.WarnContext(ctx, "unknown feature flag", "flag", name)
+		h.writeError(w, http.StatusNotFound, "Unknown feature flag: "+name)
+		return
+	}
+	logger.ErrorContext(ctx, "failed to update feature flag", "flag", name, "error", err)
+	h.writeError(w, http.StatusInternalServerError, "Failed to update feature flag")
+}
+
+// writeError writes an error response.
+func (h *FeaturesHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// FolderDeleteHandler handles HTTP requests for removing a folder from the index.
+type FolderDeleteHandler struct {
+	indexerPipeline *indexer.Pipeline
+	vaultManager    *vault.Manager
+}
+
+// NewFolderDeleteHandler creates a new FolderDeleteHandler.
+func NewFolderDeleteHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager) *FolderDeleteHandler {
+	return &FolderDeleteHandler{
+		indexerPipeline: indexerPipeline,
+		vaultManager:    vaultManager,
+	}
+}
+
+// FolderDeleteResponse represents the result of removing a folder from the index.
+//
+// swagger:model FolderDeleteResponse
+type FolderDeleteResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Prefix is the folder prefix that was removed
+	Prefix string `json:"prefix"`
+	// NotesDeleted is the number of notes removed from the index
+	NotesDeleted int `json:"notes_deleted"`
+	// ChunksDeleted is the number of chunks (and vector points) removed
+	ChunksDeleted int `json:"chunks_deleted"`
+	// NotesFailed is the number of notes that could not be removed and are still indexed
+	NotesFailed int `json:"notes_failed"`
+}
+
+// ServeHTTP handles HTTP requests for removing a folder from the index.
+//
+// swagger:route DELETE /api/v1/vaults/{name}/folders deleteFolder
+//
+// # Remove a folder from the index
+//
+// Removes every indexed note under a folder prefix, with its chunks and vector points,
+// without reindexing from scratch. Files in the vault are not touched. The prefix is
+// excluded from later index runs until it is included again through
+// DELETE /api/v1/vaults/{name}/folders/excluded; use this to prune content that should
+// never have been indexed.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: query
+//     name: prefix
+//     type: string
+//     required: true
+//     description: Folder prefix relative to the vault root; matches whole folder names
+//
+// responses:
+//
+//	'200':
+//	  description: Folder removed from the index
+//	  schema:
+//	    "$ref": "#/definitions/FolderDeleteResponse"
+//	'400':
+//	  description: Missing prefix
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *FolderDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+	prefix := strings.Trim(r.URL.Query().Get("prefix"), "/")
+	if prefix == "" {
+		h.writeError(w, http.StatusBadRequest, "Query parameter \"prefix\" is required")
+		return
+	}
+
+	result, err := h.indexerPipeline.DeleteFolder(ctx, vaultRecord.ID, prefix)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to delete folder from index", "vault", vaultName, "prefix", prefix, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to delete folder from index")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(FolderDeleteResponse{
+		Vault:         vaultName,
+		Prefix:        prefix,
+		NotesDeleted:  result.Notes,
+		ChunksDeleted: result.Chunks,
+		NotesFailed:   result.Failed,
+	})
+}
+
+// writeError writes an error response.
+func (h *FolderDeleteHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
+
+// FolderExclusionsHandler handles HTTP requests for the folders excluded from the index.
+type FolderExclusionsHandler struct {
+	indexerPipeline *indexer.Pipeline
+	vaultManager    *vault.Manager
+}
+
+// NewFolderExclusionsHandler creates a new FolderExclusionsHandler.
+func NewFolderExclusionsHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager) *FolderExclusionsHandler {
+	return &FolderExclusionsHandler{
+		indexerPipeline: indexerPipeline,
+		vaultManager:    vaultManager,
+	}
+}
+
+// ExcludedFolder is a folder prefix that index runs skip.
+//
+// swagger:model ExcludedFolder
+type ExcludedFolder struct {
+	// Prefix is the folder prefix, including its subfolders
+	Prefix string `json:"prefix"`
+	// ExcludedAt is when the folder was removed from the index (RFC3339)
+	ExcludedAt string `json:"excluded_at"`
+}
+
+// FolderExclusionsResponse lists the folders of a vault excluded from the index.
+//
+// swagger:model FolderExclusionsResponse
+type FolderExclusionsResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Folders are the excluded folder prefixes, ordered by prefix
+	Folders []ExcludedFolder `json:"folders"`
+}
+
+// ServeHTTP handles HTTP requests for the folders excluded from the index.
+//
+// swagger:route GET /api/v1/vaults/{name}/folders/excluded listExcludedFolders
+//
+// # List folders excluded from the index
+//
+// Returns the folder prefixes removed with DELETE /api/v1/vaults/{name}/folders, which
+// index runs skip.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//
+// responses:
+//
+//	'200':
+//	  description: Excluded folders retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/FolderExclusionsResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/vaults/{name}/folders/excluded includeFolder
+//
+// # Include an excluded folder again
+//
+// Lifts the exclusion of a folder prefix, so the next index run indexes its notes again.
+//
+// ---
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: query
+//     name: prefix
+//     type: string
+//     required: true
+//     description: Excluded folder prefix, as listed
+//
+// responses:
+//
+//	'204':
+//	  description: Folder included again
+//	'400':
+//	  description: Missing prefix
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault or folder not excluded
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *FolderExclusionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		folders, err := h.indexerPipeline.ExcludedFolders(ctx, vaultRecord.ID)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list excluded folders", "vault", vaultName, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to list excluded folders")
+			return
+		}
+		resp := FolderExclusionsResponse{Vault: vaultName, Folders: make([]ExcludedFolder, 0, len(folders))}
+		for _, f := range folders {
+			resp.Folders = append(resp.Folders, ExcludedFolder{
+				Prefix:     f.Prefix,
+				ExcludedAt: f.CreatedAt.UTC().Format(time.RFC3339),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	case http.MethodDelete:
+		prefix := strings.Trim(r.URL.Query().Get("prefix"), "/")
+		if prefix == "" {
+			h.writeError(w, http.StatusBadRequest, "Query parameter \"prefix\" is required")
+			return
+		}
+		if err := h.indexerPipeline.IncludeFolder(ctx, vaultRecord.ID, prefix); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				h.writeError(w, http.StatusNotFound, "Folder is not excluded")
+				return
+			}
+			logger.ErrorContext(ctx, "failed to include folder", "vault", vaultName, "prefix", prefix, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to include folder")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeError writes an error response.
+func (h *FolderExclusionsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
+
+// defaultFolderStatsWindowDays is the retrieval window used when the request does not set one.
+const defaultFolderStatsWindowDays = 30
+
+// FolderStatsHandler handles HTTP requests for per-folder chunk statistics.
+type FolderStatsHandler struct {
+	indexerPipeline *indexer.Pipeline
+	vaultManager    *vault.Manager
+}
+
+// NewFolderStatsHandler creates a new FolderStatsHandler.
+func NewFolderStatsHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager) *FolderStatsHandler {
+	return &FolderStatsHandler{
+		indexerPipeline: indexerPipeline,
+		vaultManager:    vaultManager,
+	}
+}
+
+// FolderStatsResponse describes the indexed chunks of a folder.
+//
+// swagger:model FolderStatsResponse
+type FolderStatsResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Prefix is the folder prefix, including its subfolders
+	Prefix string `json:"prefix"`
+	// NoteCount is the number of indexed notes under the prefix
+	NoteCount int `json:"note_count"`
+	// ChunkCount is the number of chunks of those notes
+	ChunkCount int `json:"chunk_count"`
+	// AvgChunkTokens is the mean estimated token count per chunk
+	AvgChunkTokens float64 `json:"avg_chunk_tokens"`
+	// LastIndexedAt is when a note under the prefix was last indexed (RFC3339, omitted without notes)
+	LastIndexedAt string `json:"last_indexed_at,omitempty"`
+	// WindowDays is the period retrievals are counted over
+	WindowDays int `json:"window_days"`
+	// Retrievals is the number of answers the folder's notes contributed to within the window
+	Retrievals int `json:"retrievals"`
+	// RetrievalsPerDay is the average number of retrievals per day within the window
+	RetrievalsPerDay float64 `json:"retrievals_per_day"`
+}
+
+// ServeHTTP handles HTTP requests for per-folder chunk statistics.
+//
+// swagger:route GET /api/v1/vaults/{name}/folders/{prefix}/stats getFolderStats
+//
+// # Get chunk statistics for a folder
+//
+// Returns the chunk count, average chunk size, last index time, and retrieval frequency
+// of the notes under a folder prefix. Use it to find folders whose chunks are too large
+// or too small, or that are rarely retrieved, when tuning chunking.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: path
+//     name: prefix
+//     type: string
+//     required: true
+//     description: Folder prefix relative to the vault root; URL-encode nested folders (e.g. Projects%2F2024)
+//   - in: query
+//     name: days
+//     type: integer
+//     default: 30
+//     description: Number of days retrievals are counted over
+//
+// responses:
+//
+//	'200':
+//	  description: Folder statistics retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/FolderStatsResponse"
+//	'400':
+//	  description: Invalid prefix or days parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *FolderStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+	// Nested folders arrive URL-encoded so the prefix stays a single path segment
+	prefix, err := url.PathUnescape(chi.URLParam(r, "prefix"))
+	if err != nil || strings.Trim(prefix, "/") == "" {
+		h.writeError(w, http.StatusBadRequest, "Invalid folder prefix")
+		return
+	}
+	windowDays := defaultFolderStatsWindowDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed <= 0 {
+			h.writeError(w, http.StatusBadRequest, "days must be a positive integer")
+			return
+		}
+		windowDays = parsed
+	}
+
+	stats, err := h.indexerPipeline.FolderStats(ctx, vaultRecord.ID, prefix, windowDays)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get folder stats", "vault", vaultName, "prefix", prefix, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to get folder stats")
+		return
+	}
+
+	resp := FolderStatsResponse{
+		Vault:            vaultName,
+		Prefix:           stats.Prefix,
+		NoteCount:        stats.Notes,
+		ChunkCount:       stats.Chunks,
+		AvgChunkTokens:   stats.AvgChunkTokens,
+		WindowDays:       stats.WindowDays,
+		Retrievals:       stats.Retrievals,
+		RetrievalsPerDay: stats.RetrievalsPerDay,
+	}
+	if stats.LastIndexedAt != nil {
+		resp.LastIndexedAt = stats.LastIndexedAt.UTC().Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *FolderStatsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
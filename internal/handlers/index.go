@@ -1,26 +1,32 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
-	"sync/atomic"
+	"time"
 
 	"helloworld-ai/internal/contextutil"
 	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/jobs"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
 )
 
-// IndexHandler handles HTTP requests for triggering re-indexing.
+// IndexHandler handles HTTP requests for triggering re-indexing, reporting its progress,
+// and clearing the index.
 type IndexHandler struct {
 	indexerPipeline *indexer.Pipeline
-	isIndexing      *atomic.Bool
+	vaultManager    *vault.Manager
+	jobQueue        *jobs.Queue
 }
 
-// NewIndexHandler creates a new IndexHandler.
-func NewIndexHandler(indexerPipeline *indexer.Pipeline) *IndexHandler {
+// NewIndexHandler creates a new IndexHandler. Re-indexing it triggers runs as a
+// reindex job on jobQueue.
+func NewIndexHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager, jobQueue *jobs.Queue) *IndexHandler {
 	return &IndexHandler{
 		indexerPipeline: indexerPipeline,
-		isIndexing:      &atomic.Bool{},
+		vaultManager:    vaultManager,
+		jobQueue:        jobQueue,
 	}
 }
 
@@ -30,6 +36,8 @@ func NewIndexHandler(indexerPipeline *indexer.Pipeline) *IndexHandler {
 type IndexResponse struct {
 	Message string `json:"message"`
 	Status  string `json:"status"`
+	// JobID identifies the reindex job; poll GET /api/v1/jobs/{id} for its outcome
+	JobID string `json:"job_id,omitempty"`
 }
 
 // IndexStatusResponse represents the response from the index status endpoint.
@@ -38,31 +46,72 @@ type IndexResponse struct {
 type IndexStatusResponse struct {
 	IsIndexing bool   `json:"is_indexing"`
 	Status     string `json:"status"`
+	// Vault is the vault of the running or last pass (omitted for all vaults)
+	Vault string `json:"vault,omitempty"`
+	// StartedAt is when the running or last pass started (omitted if none ran since startup)
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// FinishedAt is when the last pass ended (omitted while running)
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// FilesScanned is the number of markdown files found in the vaults
+	FilesScanned int `json:"files_scanned"`
+	// FilesIndexed is the number of files indexed (or found unchanged) so far
+	FilesIndexed int `json:"files_indexed"`
+	// FilesFailed is the number of files that could not be indexed
+	FilesFailed int `json:"files_failed"`
+	// NotesRemoved is the number of indexed notes removed because their files are gone
+	NotesRemoved int `json:"notes_removed"`
+	// ChunksRemoved is the number of chunks removed with them
+	ChunksRemoved int `json:"chunks_removed"`
+	// ETASeconds estimates the seconds left in the running pass (omitted when unknown)
+	ETASeconds int `json:"eta_seconds,omitempty"`
+	// Error is the error the last pass ended with
+	Error string `json:"error,omitempty"`
 }
 
-// ServeHTTP handles HTTP requests for triggering re-indexing and checking status.
+// IndexClearResponse represents the result of clearing the index.
 //
-// Trigger re-indexing of all markdown files in configured vaults.
+// swagger:model IndexClearResponse
+type IndexClearResponse struct {
+	Status string `json:"status"`
+	// Vault is the vault that was cleared (omitted when all vaults were)
+	Vault string `json:"vault,omitempty"`
+	// NotesDeleted is the number of notes removed (only reported for a single vault)
+	NotesDeleted int `json:"notes_deleted,omitempty"`
+	// NotesFailed is the number of notes that could not be removed and are still indexed
+	NotesFailed int `json:"notes_failed,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for triggering re-indexing, checking status, and
+// clearing the index.
+//
+// Trigger re-indexing of all markdown files in configured vaults, or of one vault.
 // By default, only changed files are re-indexed. Use the force query parameter
-// to clear all existing data and rebuild the index from scratch.
+// to rebuild the index from scratch. When supported, a full rebuild runs beside the
+// current index, which keeps answering questions until the new one is swapped in.
 //
-// swagger:route POST /api/index triggerIndex
+// swagger:route POST /api/v1/index/reindex reindex
 //
 // # Trigger re-indexing of vaults
 //
-// Starts an asynchronous re-indexing process that scans all markdown files
-// in the configured vaults and updates the search index. The operation runs
-// in the background and returns immediately with an accepted status.
+// Queues a reindex job that scans the markdown files in the configured vaults (or in
+// the vault given by the vault parameter) and updates the search index. The job runs
+// in the background and the request returns immediately with an accepted status and
+// the job ID; follow it with GET /api/v1/jobs/{id} or GET /api/v1/index/status.
+// POST /api/index is the same operation, kept for existing scripts.
 //
 // ---
 // produces:
 // - application/json
 // parameters:
 //   - in: query
+//     name: vault
+//     type: string
+//     description: Re-index only this vault (default all vaults)
+//   - in: query
 //     name: force
 //     type: boolean
 //     default: false
-//     description: If true, clears all existing indexed data before re-indexing
+//     description: If true, rebuilds the index from scratch (blue/green for all vaults when supported, otherwise clears existing data of the vaults first)
 //
 // responses:
 //
@@ -70,20 +119,30 @@ type IndexStatusResponse struct {
 //	  description: Indexing started successfully
 //	  schema:
 //	    "$ref": "#/definitions/IndexResponse"
+//	'400':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
 //	'405':
 //	  description: Method not allowed
 //	  schema:
 //	    "$ref": "#/definitions/ErrorResponse"
+//	'409':
+//	  description: Indexing is already in progress
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
 //	'500':
 //	  description: Internal server error
 //	  schema:
 //	    "$ref": "#/definitions/ErrorResponse"
 //
-// swagger:route GET /api/index/status getIndexStatus
+// swagger:route GET /api/v1/index/status getIndexStatus
 //
 // # Get indexing status
 //
-// Returns the current status of the indexing process.
+// Returns the progress of the running indexing pass (startup indexing included), or the
+// outcome of the last one: files scanned, indexed, and failed, and an ETA while running.
+// GET /api/index/status is the same operation.
 //
 // ---
 // produces:
@@ -94,90 +153,210 @@ type IndexStatusResponse struct {
 //	  description: Indexing status retrieved successfully
 //	  schema:
 //	    "$ref": "#/definitions/IndexStatusResponse"
+//
+// swagger:route DELETE /api/v1/index clearIndex
+//
+// # Clear the index
+//
+// Removes all indexed notes, chunks, and vector points, or only those of the vault given
+// by the vault parameter. Files are not touched; the next index run indexes them again.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: vault
+//     type: string
+//     description: Clear only this vault (default all vaults)
+//
+// responses:
+//
+//	'200':
+//	  description: Index cleared
+//	  schema:
+//	    "$ref": "#/definitions/IndexClearResponse"
+//	'400':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'409':
+//	  description: Indexing is in progress
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
 func (h *IndexHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := contextutil.LoggerFromContext(ctx)
 
-	// Handle GET request for status
-	if r.Method == http.MethodGet {
+	switch r.Method {
+	case http.MethodGet:
 		h.handleStatus(w, r)
 		return
-	}
-
-	if r.Method != http.MethodPost {
+	case http.MethodDelete:
+		h.handleClear(w, r)
+		return
+	case http.MethodPost:
+	default:
 		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
 		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Check if indexing is already in progress
-	if h.isIndexing.Load() {
-		logger.WarnContext(ctx, "indexing already in progress")
-		h.writeError(w, http.StatusConflict, "Indexing is already in progress")
+	vaultRecord, ok := h.vaultParam(w, r)
+	if !ok {
 		return
 	}
 
 	// Check for force parameter
 	force := r.URL.Query().Get("force") == "true"
+	// Only a full rebuild can run beside the live index
+	rebuild := force && vaultRecord.ID == 0 && h.indexerPipeline.RebuildSupported()
+
+	// Check if indexing is already in progress (startup indexing and queued jobs included)
+	if h.indexing(r) {
+		logger.WarnContext(ctx, "indexing already in progress")
+		h.writeError(w, http.StatusConflict, "Indexing is already in progress")
+		return
+	}
 
+	job, err := h.jobQueue.Enqueue(ctx, jobs.TypeReindex, jobs.ReindexParams{Vault: vaultRecord.Name, Force: force})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to queue reindex job", "vault", vaultRecord.Name, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to start indexing")
+		return
+	}
 	if force {
-		logger.InfoContext(ctx, "force re-indexing triggered via API")
+		logger.InfoContext(ctx, "force re-indexing triggered via API", "vault", vaultRecord.Name, "job_id", job.ID)
 	} else {
-		logger.InfoContext(ctx, "re-indexing triggered via API")
-	}
-
-	// Set indexing flag
-	h.isIndexing.Store(true)
-
-	// Trigger indexing in a goroutine so it doesn't block the HTTP response
-	// Use background context so indexing continues after HTTP request completes
-	go func() {
-		defer h.isIndexing.Store(false)
-
-		indexCtx := context.Background()
-		indexLogger := contextutil.LoggerFromContext(indexCtx)
-		if force {
-			// Clear all existing data first
-			if err := h.indexerPipeline.ClearAll(indexCtx); err != nil {
-				indexLogger.ErrorContext(indexCtx, "failed to clear existing data", "error", err)
-				return
-			}
-			indexLogger.InfoContext(indexCtx, "cleared all existing indexed data")
-		}
-		if err := h.indexerPipeline.IndexAll(indexCtx); err != nil {
-			indexLogger.ErrorContext(indexCtx, "re-indexing completed with errors", "error", err)
-		} else {
-			indexLogger.InfoContext(indexCtx, "re-indexing completed successfully")
-		}
-	}()
+		logger.InfoContext(ctx, "re-indexing triggered via API", "vault", vaultRecord.Name, "job_id", job.ID)
+	}
 
 	// Return immediately with accepted status
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
-	message := "Indexing started. Check server logs for progress."
-	if force {
-		message = "Force re-indexing started (all existing data cleared). Check server logs for progress."
+	message := "Indexing started. Check /api/v1/index/status for progress."
+	if rebuild {
+		message = "Rebuild started; the current index keeps serving until the new one is swapped in. Check /api/v1/index/status for progress."
+	} else if force {
+		message = "Force re-indexing started (existing data cleared). Check /api/v1/index/status for progress."
 	}
 	_ = json.NewEncoder(w).Encode(IndexResponse{
 		Message: message,
 		Status:  "accepted",
+		JobID:   job.ID,
 	})
 }
 
 // handleStatus handles GET requests to check indexing status.
 func (h *IndexHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
-	isIndexing := h.isIndexing.Load()
+	progress := h.indexerPipeline.IndexProgress()
+	isIndexing := progress.Running
+	if !isIndexing {
+		job, err := h.jobQueue.Active(r.Context(), jobs.IndexTypes...)
+		isIndexing = err == nil && job != nil
+	}
 	status := "idle"
 	if isIndexing {
 		status = "indexing"
 	}
 
+	response := IndexStatusResponse{
+		IsIndexing:    isIndexing,
+		Status:        status,
+		FilesScanned:  progress.FilesScanned,
+		FilesIndexed:  progress.FilesIndexed,
+		FilesFailed:   progress.FilesFailed,
+		NotesRemoved:  progress.NotesRemoved,
+		ChunksRemoved: progress.ChunksRemoved,
+		ETASeconds:    int(progress.ETA(time.Now()).Seconds()),
+		Error:         progress.Error,
+	}
+	if progress.VaultID != 0 {
+		if vaultRecord, err := h.vaultManager.VaultByID(progress.VaultID); err == nil {
+			response.Vault = vaultRecord.Name
+		}
+	}
+	if !progress.StartedAt.IsZero() {
+		response.StartedAt = &progress.StartedAt
+	}
+	if !progress.FinishedAt.IsZero() {
+		response.FinishedAt = &progress.FinishedAt
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(IndexStatusResponse{
-		IsIndexing: isIndexing,
-		Status:     status,
-	})
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// handleClear handles DELETE requests to clear the index.
+func (h *IndexHandler) handleClear(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultRecord, ok := h.vaultParam(w, r)
+	if !ok {
+		return
+	}
+	// Clearing under a running pass would leave it indexing into a half-empty index
+	if h.indexing(r) {
+		h.writeError(w, http.StatusConflict, "Indexing is in progress")
+		return
+	}
+
+	response := IndexClearResponse{Status: "cleared", Vault: vaultRecord.Name}
+	if vaultRecord.ID != 0 {
+		result, err := h.indexerPipeline.ClearVault(ctx, vaultRecord.ID)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to clear vault from index", "vault", vaultRecord.Name, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to clear index")
+			return
+		}
+		response.NotesDeleted = result.Notes
+		response.NotesFailed = result.Failed
+	} else if err := h.indexerPipeline.ClearAll(ctx); err != nil {
+		logger.ErrorContext(ctx, "failed to clear index", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to clear index")
+		return
+	}
+	logger.InfoContext(ctx, "index cleared via API", "vault", vaultRecord.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// vaultParam resolves the optional vault query parameter. The zero record means all
+// vaults. Writes a 400 and returns false for unknown vaults.
+func (h *IndexHandler) vaultParam(w http.ResponseWriter, r *http.Request) (storage.VaultRecord, bool) {
+	name := r.URL.Query().Get("vault")
+	if name == "" {
+		return storage.VaultRecord{}, true
+	}
+	vaultRecord, err := h.vaultManager.VaultByName(name)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Unknown vault: "+name)
+		return storage.VaultRecord{}, false
+	}
+	return vaultRecord, true
+}
+
+// indexing reports whether an indexing pass is running or an index job is queued or
+// running. A failure to read the job queue is logged and treated as indexing, so the
+// caller does not change the index under a job it cannot see.
+func (h *IndexHandler) indexing(r *http.Request) bool {
+	if h.indexerPipeline.IndexProgress().Running {
+		return true
+	}
+	job, err := h.jobQueue.Active(r.Context(), jobs.IndexTypes...)
+	if err != nil {
+		contextutil.LoggerFromContext(r.Context()).ErrorContext(r.Context(), "failed to list active jobs", "error", err)
+		return true
+	}
+	return job != nil
 }
 
 // writeError writes an error response.
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+)
+
+const (
+	defaultFailuresLimit = 100
+	maxFailuresLimit     = 1000
+)
+
+// IndexFailuresHandler handles HTTP requests for the chunks left out of the index.
+type IndexFailuresHandler struct {
+	indexerPipeline *indexer.Pipeline
+}
+
+// NewIndexFailuresHandler creates a new IndexFailuresHandler.
+func NewIndexFailuresHandler(indexerPipeline *indexer.Pipeline) *IndexFailuresHandler {
+	return &IndexFailuresHandler{
+		indexerPipeline: indexerPipeline,
+	}
+}
+
+// IndexFailuresResponse represents the response from the index failures endpoint.
+//
+// swagger:model IndexFailuresResponse
+type IndexFailuresResponse struct {
+	// Failures ordered newest first
+	Failures []IndexFailureResponse `json:"failures"`
+}
+
+// IndexFailureResponse describes a chunk skipped or failed during the last indexing run of its note.
+//
+// swagger:model IndexFailureResponse
+type IndexFailureResponse struct {
+	// ID of the vault containing the note
+	VaultID int `json:"vault_id"`
+	// Relative path to the note within the vault
+	RelPath string `json:"rel_path"`
+	// ID of the note
+	NoteID string `json:"note_id"`
+	// ChunkIndex is the position of the chunk within the note
+	ChunkIndex int `json:"chunk_index"`
+	// Reason is context_size (too large to embed, skipped) or embedding_error (the note failed)
+	Reason string `json:"reason"`
+	// Error describes the failure
+	Error string `json:"error"`
+	// RecordedAt is when the failure was recorded (RFC3339)
+	RecordedAt string `json:"recorded_at"`
+}
+
+// ServeHTTP handles HTTP requests for the chunks left out of the index.
+//
+// swagger:route GET /api/v1/index/failures getIndexFailures
+//
+// # List chunks left out of the index
+//
+// Returns the chunks the last indexing run of each note skipped because they exceed the
+// embedding model's context, or failed to embed, newest first. Records are replaced when
+// their note is indexed again and removed with it, so fixed notes drop off the list.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: limit
+//     type: integer
+//     default: 100
+//     description: Maximum number of failures to return (1-1000)
+//
+// responses:
+//
+//	'200':
+//	  description: Index failures retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/IndexFailuresResponse"
+//	'400':
+//	  description: Invalid limit parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *IndexFailuresHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	limit := defaultFailuresLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			logger.WarnContext(ctx, "invalid limit parameter", "limit", limitParam)
+			h.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxFailuresLimit)
+	}
+
+	records, err := h.indexerPipeline.IndexFailures(ctx, limit)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list index failures", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list index failures")
+		return
+	}
+
+	failures := make([]IndexFailureResponse, 0, len(records))
+	for _, record := range records {
+		failures = append(failures, IndexFailureResponse{
+			VaultID:    record.VaultID,
+			RelPath:    record.RelPath,
+			NoteID:     record.NoteID,
+			ChunkIndex: record.ChunkIndex,
+			Reason:     record.Reason,
+			Error:      record.Error,
+			RecordedAt: record.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(IndexFailuresResponse{Failures: failures})
+}
+
+// writeError writes an error response.
+func (h *IndexFailuresHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+)
+
+// IndexPauseHandler handles HTTP requests for pausing and resuming automatic indexing.
+type IndexPauseHandler struct {
+	pipeline *indexer.Pipeline
+}
+
+// NewIndexPauseHandler creates a new IndexPauseHandler.
+func NewIndexPauseHandler(pipeline *indexer.Pipeline) *IndexPauseHandler {
+	return &IndexPauseHandler{
+		pipeline: pipeline,
+	}
+}
+
+// IndexPauseRequest represents a request to pause automatic indexing.
+//
+// swagger:model IndexPauseRequest
+type IndexPauseRequest struct {
+	// TimeoutMinutes is how long the pause lasts before indexing resumes by itself
+	// (omit or 0 for 30 minutes, capped at 1440)
+	TimeoutMinutes int `json:"timeout_minutes,omitempty"`
+}
+
+// IndexPauseResponse represents the automatic indexing pause state.
+//
+// swagger:model IndexPauseResponse
+type IndexPauseResponse struct {
+	// Paused reports whether automatic indexing is paused
+	Paused bool `json:"paused"`
+	// PausedUntil is when indexing resumes by itself (RFC 3339, only while paused)
+	PausedUntil string `json:"paused_until,omitempty"`
+	// RemainingSeconds is the time left until indexing resumes
+	RemainingSeconds int `json:"remaining_seconds"`
+}
+
+// ServeHTTP handles HTTP requests for the automatic indexing pause.
+//
+// swagger:route GET /api/v1/admin/index/pause getIndexPause
+//
+// # Get automatic indexing pause state
+//
+// Reports whether automatic indexing is paused and when it resumes. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Pause state retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/IndexPauseResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route PUT /api/v1/admin/index/pause pauseIndexing
+//
+// # Pause automatic indexing
+//
+// Suspends automatic indexing (scheduled jobs such as the weekly digest) while a vault is
+// bulk-edited, e.g. during mass renames or plugin migrations. Indexing resumes by itself
+// after the timeout; pausing again replaces it. Explicit re-index requests still run.
+// The pause is kept in memory and ends on restart. Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: body
+//     name: body
+//     required: false
+//     schema:
+//     "$ref": "#/definitions/IndexPauseRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Automatic indexing paused; returns the new state
+//	  schema:
+//	    "$ref": "#/definitions/IndexPauseResponse"
+//	'400':
+//	  description: Invalid timeout
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/admin/index/pause resumeIndexing
+//
+// # Resume automatic indexing
+//
+// Ends a pause before its timeout. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Automatic indexing resumed; returns the new state
+//	  schema:
+//	    "$ref": "#/definitions/IndexPauseResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *IndexPauseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeState(w)
+	case http.MethodPut:
+		var req IndexPauseRequest
+		// An empty body pauses for the default timeout
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			logger.WarnContext(ctx, "invalid index pause request", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.TimeoutMinutes < 0 {
+			h.writeError(w, http.StatusBadRequest, "timeout_minutes must not be negative")
+			return
+		}
+		h.pipeline.PauseAutoIndexing(ctx, time.Duration(req.TimeoutMinutes)*time.Minute)
+		h.writeState(w)
+	case http.MethodDelete:
+		h.pipeline.ResumeAutoIndexing(ctx)
+		h.writeState(w)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeState writes the current pause state.
+func (h *IndexPauseHandler) writeState(w http.ResponseWriter) {
+	var resp IndexPauseResponse
+	if until := h.pipeline.AutoIndexingPausedUntil(); !until.IsZero() {
+		resp.Paused = true
+		resp.PausedUntil = until.UTC().Format(time.RFC3339)
+		resp.RemainingSeconds = int(time.Until(until).Round(time.Second).Seconds())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *IndexPauseHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
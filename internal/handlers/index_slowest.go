@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+)
+
+const (
+	defaultSlowestLimit = 20
+	maxSlowestLimit     = 200
+)
+
+// IndexSlowestHandler handles HTTP requests for the slow-file indexing report.
+type IndexSlowestHandler struct {
+	indexerPipeline *indexer.Pipeline
+}
+
+// NewIndexSlowestHandler creates a new IndexSlowestHandler.
+func NewIndexSlowestHandler(indexerPipeline *indexer.Pipeline) *IndexSlowestHandler {
+	return &IndexSlowestHandler{
+		indexerPipeline: indexerPipeline,
+	}
+}
+
+// SlowestFilesResponse represents the response from the slowest files endpoint.
+//
+// swagger:model SlowestFilesResponse
+type SlowestFilesResponse struct {
+	// Files ordered by total indexing time, slowest first
+	Files []SlowFileResponse `json:"files"`
+}
+
+// SlowFileResponse describes the most recent indexing durations for a single file.
+//
+// swagger:model SlowFileResponse
+type SlowFileResponse struct {
+	// ID of the vault containing the file
+	VaultID int `json:"vault_id"`
+	// Relative path to the markdown file within the vault
+	RelPath string `json:"rel_path"`
+	// Bottleneck is the phase that took the longest (read, chunk, embed, or upsert)
+	Bottleneck string `json:"bottleneck"`
+	// ReadMs is the time spent reading the file and checking its hash (milliseconds)
+	ReadMs int64 `json:"read_ms"`
+	// ChunkMs is the time spent chunking markdown (milliseconds)
+	ChunkMs int64 `json:"chunk_ms"`
+	// EmbedMs is the time spent generating embeddings (milliseconds)
+	EmbedMs int64 `json:"embed_ms"`
+	// UpsertMs is the time spent writing to SQLite and Qdrant (milliseconds)
+	UpsertMs int64 `json:"upsert_ms"`
+	// TotalMs is the total time spent indexing the file (milliseconds)
+	TotalMs int64 `json:"total_ms"`
+	// SizeBytes is the file size in bytes
+	SizeBytes int64 `json:"size_bytes"`
+	// ChunkCount is the number of chunks produced
+	ChunkCount int `json:"chunk_count"`
+	// IndexedAt is when the file was last indexed (RFC3339)
+	IndexedAt string `json:"indexed_at"`
+}
+
+// ServeHTTP handles HTTP requests for the slowest indexed files.
+//
+// swagger:route GET /api/v1/index/slowest getSlowestFiles
+//
+// # List the slowest files to index
+//
+// Returns files ordered by the duration of their most recent indexing run,
+// with a read/chunk/embed/upsert breakdown and the bottleneck phase. Useful for
+// finding pathological notes (huge tables, massive pastes) that dominate reindex time.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: limit
+//     type: integer
+//     default: 20
+//     description: Maximum number of files to return (1-200)
+//
+// responses:
+//
+//	'200':
+//	  description: Slowest files retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/SlowestFilesResponse"
+//	'400':
+//	  description: Invalid limit parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *IndexSlowestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	limit := defaultSlowestLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			logger.WarnContext(ctx, "invalid limit parameter", "limit", limitParam)
+			h.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxSlowestLimit)
+	}
+
+	timings, err := h.indexerPipeline.SlowestFiles(ctx, limit)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list slowest files", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list slowest files")
+		return
+	}
+
+	files := make([]SlowFileResponse, 0, len(timings))
+	for _, timing := range timings {
+		files = append(files, SlowFileResponse{
+			VaultID:    timing.VaultID,
+			RelPath:    timing.RelPath,
+			Bottleneck: timing.Bottleneck(),
+			ReadMs:     timing.ReadMs,
+			ChunkMs:    timing.ChunkMs,
+			EmbedMs:    timing.EmbedMs,
+			UpsertMs:   timing.UpsertMs,
+			TotalMs:    timing.TotalMs,
+			SizeBytes:  timing.SizeBytes,
+			ChunkCount: timing.ChunkCount,
+			IndexedAt:  timing.IndexedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(SlowestFilesResponse{Files: files})
+}
+
+// writeError writes an error response.
+func (h *IndexSlowestHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
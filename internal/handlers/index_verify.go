@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+)
+
+// IndexVerifyHandler handles HTTP requests for index integrity verification.
+type IndexVerifyHandler struct {
+	indexerPipeline *indexer.Pipeline
+}
+
+// NewIndexVerifyHandler creates a new IndexVerifyHandler.
+func NewIndexVerifyHandler(indexerPipeline *indexer.Pipeline) *IndexVerifyHandler {
+	return &IndexVerifyHandler{
+		indexerPipeline: indexerPipeline,
+	}
+}
+
+// IndexVerifyResponse represents the response from the index verification endpoint.
+//
+// swagger:model IndexVerifyResponse
+type IndexVerifyResponse struct {
+	// Verified is true when every vault's index matches its stored checksum
+	Verified bool `json:"verified"`
+	// Vaults holds the verification result for each vault
+	Vaults []VaultVerificationResponse `json:"vaults"`
+}
+
+// VaultVerificationResponse compares a vault's stored and current index checksums.
+//
+// swagger:model VaultVerificationResponse
+type VaultVerificationResponse struct {
+	// ID of the vault
+	VaultID int `json:"vault_id"`
+	// Name of the vault
+	VaultName string `json:"vault_name"`
+	// Status is ok, mismatch, or not_recorded
+	Status string `json:"status"`
+	// StoredChecksum is the checksum recorded after the last index run
+	StoredChecksum string `json:"stored_checksum,omitempty"`
+	// CurrentChecksum is the checksum of the index as it is now
+	CurrentChecksum string `json:"current_checksum"`
+	// StoredNoteCount is the number of notes at the last index run
+	StoredNoteCount int `json:"stored_note_count"`
+	// CurrentNoteCount is the number of notes now
+	CurrentNoteCount int `json:"current_note_count"`
+	// StoredChunkCount is the number of chunks at the last index run
+	StoredChunkCount int `json:"stored_chunk_count"`
+	// CurrentChunkCount is the number of chunks now
+	CurrentChunkCount int `json:"current_chunk_count"`
+	// RecordedAt is when the stored checksum was computed (RFC3339, omitted if not recorded)
+	RecordedAt string `json:"recorded_at,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for index integrity verification.
+//
+// swagger:route GET /api/v1/index/verify getIndexVerify
+//
+// # Verify index integrity
+//
+// Recomputes a checksum over each vault's notes (path and content hash) and chunk IDs
+// and compares it with the checksum stored after the last index run. A mismatch means
+// the index was changed outside the indexer (or an index run is in progress).
+//
+// ---
+// produces:
+// - application/json
+// responses:
+//
+//	'200':
+//	  description: Index verified
+//	  schema:
+//	    "$ref": "#/definitions/IndexVerifyResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *IndexVerifyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	results, err := h.indexerPipeline.VerifyIndex(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to verify index", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to verify index")
+		return
+	}
+
+	verified := true
+	vaults := make([]VaultVerificationResponse, 0, len(results))
+	for _, result := range results {
+		if result.Status != indexer.VerifyStatusOK {
+			verified = false
+		}
+		vault := VaultVerificationResponse{
+			VaultID:           result.VaultID,
+			VaultName:         result.VaultName,
+			Status:            result.Status,
+			StoredChecksum:    result.StoredChecksum,
+			CurrentChecksum:   result.CurrentChecksum,
+			StoredNoteCount:   result.StoredNoteCount,
+			CurrentNoteCount:  result.CurrentNoteCount,
+			StoredChunkCount:  result.StoredChunkCount,
+			CurrentChunkCount: result.CurrentChunkCount,
+		}
+		if !result.RecordedAt.IsZero() {
+			vault.RecordedAt = result.RecordedAt.UTC().Format(time.RFC3339)
+		}
+		vaults = append(vaults, vault)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(IndexVerifyResponse{
+		Verified: verified,
+		Vaults:   vaults,
+	})
+}
+
+// writeError writes an error response.
+func (h *IndexVerifyHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/jobs"
+	"helloworld-ai/internal/storage"
+)
+
+// JobsHandler handles HTTP requests for queueing background jobs and polling their status.
+type JobsHandler struct {
+	queue *jobs.Queue
+}
+
+// NewJobsHandler creates a new JobsHandler.
+func NewJobsHandler(queue *jobs.Queue) *JobsHandler {
+	return &JobsHandler{
+		queue: queue,
+	}
+}
+
+// JobRequest queues a background job.
+//
+// swagger:model JobRequest
+type JobRequest struct {
+	// Type is the job type: reindex, clear_index, or delete_folder
+	Type string `json:"type"`
+	// Params are the job parameters: {"vault", "force"} for reindex, {"vault"} for
+	// clear_index, {"vault", "prefix"} for delete_folder
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// JobResponse is the state of a background job.
+//
+// swagger:model JobResponse
+type JobResponse struct {
+	// ID identifies the job for polling
+	ID string `json:"id"`
+	// Type is the job type
+	Type string `json:"type"`
+	// State is queued, running, succeeded, or failed
+	State string `json:"state"`
+	// Params are the job parameters
+	Params json.RawMessage `json:"params"`
+	// Progress is the fraction of the job that is done, 0 to 1
+	Progress float64 `json:"progress"`
+	// Error is why the job failed
+	Error string `json:"error,omitempty"`
+	// CreatedAt is when the job was queued
+	CreatedAt time.Time `json:"created_at"`
+	// StartedAt is when the worker started the job (omitted while queued)
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// FinishedAt is when the job succeeded or failed (omitted until then)
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for background jobs.
+//
+// swagger:route POST /api/v1/jobs createJob
+//
+// # Queue a background job
+//
+// Queues a long-running operation for the background worker, which runs jobs one at a
+// time. Poll GET /api/v1/jobs/{id} for its state and progress.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/JobRequest"
+//
+// responses:
+//
+//	'202':
+//	  description: Job queued
+//	  schema:
+//	    "$ref": "#/definitions/JobResponse"
+//	'400':
+//	  description: Invalid request, unknown job type, or invalid params
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route GET /api/v1/jobs/{id} getJob
+//
+// # Get a background job
+//
+// Returns the state, progress, and error of a queued, running, or finished job.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: id
+//     type: string
+//     required: true
+//     description: Job ID returned when the job was queued
+//
+// responses:
+//
+//	'200':
+//	  description: Job found
+//	  schema:
+//	    "$ref": "#/definitions/JobResponse"
+//	'404':
+//	  description: Job not found
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.handleGet(w, r)
+		return
+	case http.MethodPost:
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if req.Type == "" {
+		h.writeError(w, http.StatusBadRequest, "type is required")
+		return
+	}
+
+	var params any
+	if len(req.Params) > 0 {
+		params = req.Params
+	}
+	job, err := h.queue.Enqueue(ctx, req.Type, params)
+	if errors.Is(err, jobs.ErrUnknownType) || errors.Is(err, jobs.ErrInvalidParams) {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to queue job", "type", req.Type, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to queue job")
+		return
+	}
+	logger.InfoContext(ctx, "job queued via API", "job_id", job.ID, "type", job.Type)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+// handleGet handles GET requests for a job's status.
+func (h *JobsHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	id := chi.URLParam(r, "id")
+	job, err := h.queue.Get(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		h.writeError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get job", "job_id", id, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to get job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(newJobResponse(job))
+}
+
+// newJobResponse converts a job record to its API representation.
+func newJobResponse(job *storage.JobRecord) JobResponse {
+	return JobResponse{
+		ID:         job.ID,
+		Type:       job.Type,
+		State:      job.State,
+		Params:     json.RawMessage(job.Params),
+		Progress:   job.Progress,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// writeError writes an error response.
+func (h *JobsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
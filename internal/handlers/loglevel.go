@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/logging"
+)
+
+// LogLevelHandler handles HTTP requests for reading and changing the log settings at runtime.
+type LogLevelHandler struct {
+	settings *logging.Settings
+}
+
+// NewLogLevelHandler creates a new LogLevelHandler.
+func NewLogLevelHandler(settings *logging.Settings) *LogLevelHandler {
+	return &LogLevelHandler{
+		settings: settings,
+	}
+}
+
+// LogLevelRequest represents a change to the log settings.
+//
+// swagger:model LogLevelRequest
+type LogLevelRequest struct {
+	// Level is DEBUG, INFO, WARN, or ERROR (case-insensitive; omit to keep the current level)
+	Level string `json:"level,omitempty"`
+	// Format is text or json (omit to keep the current format)
+	Format string `json:"format,omitempty"`
+}
+
+// LogLevelResponse represents the log settings currently in effect.
+//
+// swagger:model LogLevelResponse
+type LogLevelResponse struct {
+	// Level is the current log level
+	Level string `json:"level"`
+	// Format is the current output format
+	Format string `json:"format"`
+}
+
+// ServeHTTP handles HTTP requests for the log settings.
+//
+// swagger:route GET /api/v1/admin/loglevel getLogLevel
+//
+// # Get log settings
+//
+// Returns the log level and format currently in effect. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Log settings retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/LogLevelResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route PUT /api/v1/admin/loglevel setLogLevel
+//
+// # Change log settings
+//
+// Switches the log level and/or format without a restart, e.g. to DEBUG while reproducing
+// a retrieval issue. Changes are kept in memory; LOG_LEVEL and LOG_FORMAT apply again on restart.
+// Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/LogLevelRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Log settings changed; returns the new settings
+//	  schema:
+//	    "$ref": "#/definitions/LogLevelResponse"
+//	'400':
+//	  description: Invalid level or format
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *LogLevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeSettings(w)
+	case http.MethodPut:
+		var req LogLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Level == "" && req.Format == "") {
+			logger.WarnContext(ctx, "invalid log level request", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Request body must set \"level\" and/or \"format\"")
+			return
+		}
+
+		level := h.settings.Level()
+		if req.Level != "" {
+			parsed, err := logging.ParseLevel(req.Level)
+			if err != nil {
+				h.writeError(w, http.StatusBadRequest, "Invalid level: must be DEBUG, INFO, WARN, or ERROR")
+				return
+			}
+			level = parsed
+		}
+		format := h.settings.Format()
+		if req.Format != "" {
+			format = req.Format
+		}
+
+		previousLevel, previousFormat := h.settings.Level(), h.settings.Format()
+		if err := h.settings.Set(level, format); err != nil {
+			h.writeError(w, http.StatusBadRequest, "Invalid format: must be text or json")
+			return
+		}
+		// Logged at WARN so the change is recorded whatever the new level is
+		logger.WarnContext(ctx, "log settings changed",
+			"level", h.settings.Level().String(),
+			"format", h.settings.Format(),
+			"previous_level", previousLevel.String(),
+			"previous_format", previousFormat,
+		)
+		h.writeSettings(w)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeSettings writes the current log settings.
+func (h *LogLevelHandler) writeSettings(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(LogLevelResponse{
+		Level:  h.settings.Level().String(),
+		Format: h.settings.Format(),
+	})
+}
+
+// writeError writes an error response.
+func (h *LogLevelHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
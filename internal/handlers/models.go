@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/rag"
+)
+
+// ModelsHandler handles HTTP requests for the loaded models and runtime model swaps.
+type ModelsHandler struct {
+	models *llm.ModelManager
+	caches *cache.Registry
+}
+
+// NewModelsHandler creates a new ModelsHandler. models is nil when the API manages no
+// models (the fake LLM in test mode); caches may be nil.
+func NewModelsHandler(models *llm.ModelManager, caches *cache.Registry) *ModelsHandler {
+	return &ModelsHandler{
+		models: models,
+		caches: caches,
+	}
+}
+
+// ModelsResponse represents the response from the model endpoints.
+//
+// swagger:model ModelsResponse
+type ModelsResponse struct {
+	// Models lists every managed model, ordered by role
+	Models []ModelStateResponse `json:"models"`
+	// Previous is the model replaced by a PUT request
+	Previous string `json:"previous,omitempty"`
+}
+
+// ModelStateResponse describes a managed model.
+//
+// swagger:model ModelStateResponse
+type ModelStateResponse struct {
+	// Role of the model (chat or embedding)
+	Role string `json:"role"`
+	// Model name
+	Model string `json:"model"`
+	// Loaded is false before the first load and after the model was unloaded for being idle
+	Loaded bool `json:"loaded"`
+	// LastUsed is when the model last served a request (or was loaded)
+	LastUsed time.Time `json:"last_used"`
+	// LastError is the error of the last load or unload
+	LastError string `json:"last_error,omitempty"`
+}
+
+// ModelSwapRequest represents a runtime model swap.
+//
+// swagger:model ModelSwapRequest
+type ModelSwapRequest struct {
+	// Model to load for the role (a .gguf file name in the llama.cpp models directory, without the extension)
+	// required: true
+	Model string `json:"model"`
+}
+
+// ServeHTTP handles HTTP requests for the managed models.
+//
+// swagger:route GET /api/v1/admin/models getModels
+//
+// # List managed models
+//
+// Returns the chat and embedding models the API loaded into llama.cpp, whether each is
+// loaded, and when it was last used. Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Models retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/ModelsResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'503':
+//	  description: Models are not managed (test mode)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route PUT /api/v1/admin/models/{role} swapModel
+//
+// # Swap a model
+//
+// Loads a new model for the role, waits until it is ready, points new requests at it, and
+// unloads the previous model once its in-flight requests finish. The answer cache is
+// flushed, since cached answers came from the previous model. Only the chat model can be
+// swapped: a different embedding model would not match the indexed vectors. The swap lasts
+// until restart. Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: path
+//     name: role
+//     type: string
+//     required: true
+//     description: Model role (chat)
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/ModelSwapRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Model swapped; returns all models and the previous model
+//	  schema:
+//	    "$ref": "#/definitions/ModelsResponse"
+//	'400':
+//	  description: Invalid request body, or the role cannot be swapped
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown role
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: The new model failed to load; the previous model stays in use
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'503':
+//	  description: Models are not managed (test mode)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *ModelsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if h.models == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "Models are not managed in this mode")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeModels(w, "")
+	case http.MethodPut:
+		role := chi.URLParam(r, "role")
+		var req ModelSwapRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Model) == "" {
+			logger.WarnContext(ctx, "invalid model swap request", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Request body must be {\"model\": \"<name>\"}")
+			return
+		}
+
+		previous, err := h.models.Swap(ctx, role, strings.TrimSpace(req.Model))
+		if err != nil {
+			switch {
+			case errors.Is(err, llm.ErrUnknownRole):
+				h.writeError(w, http.StatusNotFound, "Unknown model role: "+role)
+			case errors.Is(err, llm.ErrSwapUnsupported):
+				h.writeError(w, http.StatusBadRequest, "The "+role+" model cannot be swapped at runtime")
+			default:
+				logger.ErrorContext(ctx, "failed to swap model", "role", role, "model", req.Model, "error", err)
+				h.writeError(w, http.StatusBadGateway, "Failed to load model: "+err.Error())
+			}
+			return
+		}
+
+		if h.caches != nil {
+			if err := h.caches.Flush(ctx, rag.CacheAnswers); err != nil && !errors.Is(err, cache.ErrUnknownCache) {
+				logger.WarnContext(ctx, "failed to flush answer cache after model swap", "error", err)
+			}
+		}
+		logger.InfoContext(ctx, "model swapped", "role", role, "model", req.Model, "previous", previous)
+		h.writeModels(w, previous)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeModels writes the state of every managed model.
+func (h *ModelsHandler) writeModels(w http.ResponseWriter, previous string) {
+	states := h.models.States()
+	models := make([]ModelStateResponse, 0, len(states))
+	for _, s := range states {
+		models = append(models, ModelStateResponse{
+			Role:      s.Role,
+			Model:     s.Model,
+			Loaded:    s.Loaded,
+			LastUsed:  s.LastUsed,
+			LastError: s.LastError,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(ModelsResponse{Models: models, Previous: previous})
+}
+
+// writeError writes an error response.
+func (h *ModelsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// NoteWriteHandler handles HTTP requests that create, update, or delete note files.
+type NoteWriteHandler struct {
+	indexerPipeline *indexer.Pipeline
+	vaultManager    *vault.Manager
+	maxNoteBytes    int64
+}
+
+// NewNoteWriteHandler creates a new NoteWriteHandler. Note bodies are capped at
+// limits.MaxDocumentBytes.
+func NewNoteWriteHandler(indexerPipeline *indexer.Pipeline, vaultManager *vault.Manager, limits RequestLimits) *NoteWriteHandler {
+	return &NoteWriteHandler{
+		indexerPipeline: indexerPipeline,
+		vaultManager:    vaultManager,
+		maxNoteBytes:    limits.withDefaults().MaxDocumentBytes,
+	}
+}
+
+// NoteWriteResponse represents the result of writing or deleting a note.
+//
+// swagger:model NoteWriteResponse
+type NoteWriteResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// RelPath is the note's path relative to the vault root
+	RelPath string `json:"rel_path"`
+	// Created is true when PUT created a new note
+	Created bool `json:"created,omitempty"`
+	// ChunksDeleted is the number of chunks removed from the index (DELETE only)
+	ChunksDeleted int `json:"chunks_deleted,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests that write or delete a note.
+//
+// swagger:route PUT /api/v1/vaults/{name}/notes/{path} putNote
+//
+// # Create or update a note
+//
+// Writes the request body as the markdown note at path, creating folders as needed, and
+// indexes it before responding, so the note can be asked about immediately. Lets clients
+// that cannot reach the vault's filesystem (mobile apps, automations) edit notes.
+//
+// ---
+// consumes:
+// - text/markdown
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: path
+//     name: path
+//     type: string
+//     required: true
+//     description: Note path relative to the vault root, ending in .md (e.g. Projects/plan.md)
+//   - in: body
+//     name: body
+//     required: true
+//     description: Markdown content of the note
+//     schema:
+//     type: string
+//
+// responses:
+//
+//	'200':
+//	  description: Note updated and re-indexed
+//	  schema:
+//	    "$ref": "#/definitions/NoteWriteResponse"
+//	'201':
+//	  description: Note created and indexed
+//	  schema:
+//	    "$ref": "#/definitions/NoteWriteResponse"
+//	'400':
+//	  description: Invalid path (not a .md file, hidden, or outside the vault)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Note larger than the document size limit
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Note could not be written, or was written but not indexed
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/vaults/{name}/notes/{path} deleteNote
+//
+// # Delete a note
+//
+// Deletes the note file and removes it from the index with its chunks and vector points.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//   - in: path
+//     name: path
+//     type: string
+//     required: true
+//     description: Note path relative to the vault root, ending in .md
+//
+// responses:
+//
+//	'200':
+//	  description: Note deleted
+//	  schema:
+//	    "$ref": "#/definitions/NoteWriteResponse"
+//	'400':
+//	  description: Invalid path
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault, or the note neither exists nor is indexed
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *NoteWriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+	relPath, err := writableNotePath(chi.URLParam(r, "*"))
+	if err != nil {
+		logger.WarnContext(ctx, "invalid note path", "vault", vaultName, "error", err)
+		h.writeError(w, http.StatusBadRequest, "Invalid note path: "+err.Error())
+		return
+	}
+	absPath, err := buildAbsPath(vaultRecord.RootPath, relPath)
+	if err != nil {
+		logger.WarnContext(ctx, "invalid note path", "vault", vaultName, "rel_path", relPath, "error", err)
+		h.writeError(w, http.StatusBadRequest, "Invalid note path")
+		return
+	}
+	if err := checkResolvedParent(vaultRecord.RootPath, absPath); err != nil {
+		if errors.Is(err, errSymlinkEscape) {
+			logger.WarnContext(ctx, "note path leaves the vault through a symlink", "vault", vaultName, "rel_path", relPath)
+			h.writeError(w, http.StatusBadRequest, "Invalid note path")
+			return
+		}
+		logger.ErrorContext(ctx, "failed to resolve note path", "vault", vaultName, "rel_path", relPath, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to resolve note path")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxNoteBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				h.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Note exceeds %d bytes", maxBytesErr.Limit))
+				return
+			}
+			logger.WarnContext(ctx, "failed to read note body", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+
+		_, statErr := os.Stat(absPath)
+		created := os.IsNotExist(statErr)
+		if err := vault.WriteFileAtomic(absPath, content); err != nil {
+			logger.ErrorContext(ctx, "failed to write note", "vault", vaultName, "rel_path", relPath, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to write note")
+			return
+		}
+		if err := h.indexerPipeline.ReindexNote(ctx, vaultRecord.ID, relPath); err != nil {
+			logger.ErrorContext(ctx, "failed to index written note", "vault", vaultName, "rel_path", relPath, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Note saved but not indexed; it will be indexed on the next index run")
+			return
+		}
+		logger.InfoContext(ctx, "note written", "vault", vaultName, "rel_path", relPath, "created", created, "bytes", len(content))
+
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		h.writeJSON(w, status, NoteWriteResponse{Vault: vaultName, RelPath: relPath, Created: created})
+
+	case http.MethodDelete:
+		removeErr := os.Remove(absPath)
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			logger.ErrorContext(ctx, "failed to delete note file", "vault", vaultName, "rel_path", relPath, "error", removeErr)
+			h.writeError(w, http.StatusInternalServerError, "Failed to delete note")
+			return
+		}
+		chunks, err := h.indexerPipeline.DeleteNote(ctx, vaultRecord.ID, relPath)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			logger.ErrorContext(ctx, "failed to delete note from index", "vault", vaultName, "rel_path", relPath, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Note file deleted but still indexed")
+			return
+		}
+		// Nothing to delete on disk or in the index
+		if err != nil && removeErr != nil {
+			h.writeError(w, http.StatusNotFound, "Note not found")
+			return
+		}
+		logger.InfoContext(ctx, "note deleted", "vault", vaultName, "rel_path", relPath, "chunks_deleted", chunks)
+		h.writeJSON(w, http.StatusOK, NoteWriteResponse{Vault: vaultName, RelPath: relPath, ChunksDeleted: chunks})
+
+	default:
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writableNotePath decodes and validates a note path from the URL: it must stay inside
+// the vault, end in .md, and have no hidden segments (.obsidian, temporary files), since
+// the scanner would not index such a file.
+func writableNotePath(raw string) (string, error) {
+	decoded, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", errors.New("invalid path encoding")
+	}
+	relPath, err := cleanRelPath(decoded)
+	if err != nil {
+		return "", err
+	}
+	if path.Ext(relPath) != ".md" {
+		return "", errors.New("not a .md file")
+	}
+	for _, segment := range strings.Split(relPath, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", errors.New("hidden files and folders are not allowed")
+		}
+	}
+	return relPath, nil
+}
+
+// errSymlinkEscape reports a note path whose folder is a symlink to outside the vault.
+var errSymlinkEscape = errors.New("path escapes vault root through a symlink")
+
+// checkResolvedParent resolves the symlinks of the nearest existing folder of absPath and
+// returns errSymlinkEscape when it lies outside the resolved vault root. buildAbsPath only
+// compares path strings, so without this a symlinked folder in the vault would let note
+// writes and deletes reach files elsewhere on disk. Folders that do not exist yet are
+// created by the write inside the checked one.
+func checkResolvedParent(root, absPath string) error {
+	realRoot, err := filepath.EvalSymlinks(filepath.Clean(root))
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(absPath)
+	for {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if realDir != realRoot && !strings.HasPrefix(realDir, realRoot+string(os.PathSeparator)) {
+				return errSymlinkEscape
+			}
+			return nil
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return err
+		}
+		dir = parent
+	}
+}
+
+// writeJSON writes a JSON response.
+func (h *NoteWriteHandler) writeJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// writeError writes an error response.
+func (h *NoteWriteHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	h.writeJSON(w, statusCode, ErrorResponse{
+		Error: message,
+	})
+}
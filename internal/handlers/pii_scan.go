@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/pii"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// PIIScanHandler handles HTTP requests for personal data scan reports.
+type PIIScanHandler struct {
+	scanner      *pii.Scanner
+	vaultManager *vault.Manager
+}
+
+// NewPIIScanHandler creates a new PIIScanHandler.
+func NewPIIScanHandler(scanner *pii.Scanner, vaultManager *vault.Manager) *PIIScanHandler {
+	return &PIIScanHandler{
+		scanner:      scanner,
+		vaultManager: vaultManager,
+	}
+}
+
+// PIIScanResponse reports the personal data found in indexed notes.
+//
+// swagger:model PIIScanResponse
+type PIIScanResponse struct {
+	// ScannedAt is when the scan ran (RFC 3339, UTC)
+	ScannedAt string `json:"scanned_at"`
+	// Patterns lists the pattern names scanned for
+	Patterns []string `json:"patterns"`
+	// Notes is the number of notes scanned
+	Notes int `json:"notes"`
+	// Chunks is the number of chunks scanned
+	Chunks int `json:"chunks"`
+	// Matches counts matches per pattern over all folders
+	Matches map[string]int `json:"matches"`
+	// Folders lists every scanned folder, ordered by vault and folder
+	Folders []PIIFolderResponse `json:"folders"`
+}
+
+// PIIFolderResponse is the scan result for one folder (not including subfolders).
+//
+// swagger:model PIIFolderResponse
+type PIIFolderResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Folder is the folder path within the vault (empty for the vault root)
+	Folder string `json:"folder"`
+	// Notes is the number of notes scanned in the folder
+	Notes int `json:"notes"`
+	// Chunks is the number of chunks scanned in the folder
+	Chunks int `json:"chunks"`
+	// NotesWithMatches counts notes with at least one match
+	NotesWithMatches int `json:"notes_with_matches"`
+	// Matches counts matches per pattern
+	Matches map[string]int `json:"matches"`
+	// MatchedNotes lists the notes with matches, most matches first (at most 20)
+	MatchedNotes []PIINoteResponse `json:"matched_notes,omitempty"`
+}
+
+// PIINoteResponse counts the matches in one note.
+//
+// swagger:model PIINoteResponse
+type PIINoteResponse struct {
+	// Path is the note path relative to the vault root
+	Path string `json:"path"`
+	// Matches counts matches per pattern
+	Matches map[string]int `json:"matches"`
+}
+
+// ServeHTTP handles HTTP requests for PII scans.
+//
+// swagger:route GET /api/v1/admin/pii getPIIScan
+//
+// # Scan indexed notes for personal data
+//
+// Scans the indexed chunk text for email addresses, phone numbers, credit card numbers, and
+// the patterns in PII_SCAN_PATTERNS, and reports match counts per vault and folder. Use it to
+// decide which folders to exclude before sharing the API. Matched text is never returned.
+// Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: query
+//     name: vault
+//     type: string
+//     required: false
+//     description: Vault to scan (omit to scan all vaults)
+//   - in: query
+//     name: folder
+//     type: string
+//     required: false
+//     description: Folder to scan, with its subfolders (omit to scan all folders)
+//
+// responses:
+//
+//	'200':
+//	  description: Scan completed
+//	  schema:
+//	    "$ref": "#/definitions/PIIScanResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *PIIScanHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	filter := storage.ChunkExportFilter{
+		VaultName: r.URL.Query().Get("vault"),
+		Folder:    strings.Trim(r.URL.Query().Get("folder"), "/"),
+	}
+	if filter.VaultName != "" {
+		if _, err := h.vaultManager.VaultByName(filter.VaultName); err != nil {
+			h.writeError(w, http.StatusNotFound, "Vault not found")
+			return
+		}
+	}
+
+	report, err := h.scanner.Scan(ctx, filter)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to scan for PII", "vault", filter.VaultName, "folder", filter.Folder, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to scan notes")
+		return
+	}
+
+	resp := PIIScanResponse{
+		ScannedAt: report.ScannedAt.Format(time.RFC3339),
+		Patterns:  report.Patterns,
+		Notes:     report.Notes,
+		Chunks:    report.Chunks,
+		Matches:   report.Matches,
+		Folders:   make([]PIIFolderResponse, 0, len(report.Folders)),
+	}
+	for _, folder := range report.Folders {
+		folderResp := PIIFolderResponse{
+			Vault:            folder.VaultName,
+			Folder:           folder.Folder,
+			Notes:            folder.Notes,
+			Chunks:           folder.Chunks,
+			NotesWithMatches: folder.NotesWithMatches,
+			Matches:          folder.Matches,
+		}
+		for _, note := range folder.MatchedNotes {
+			folderResp.MatchedNotes = append(folderResp.MatchedNotes, PIINoteResponse{
+				Path:    note.RelPath,
+				Matches: note.Matches,
+			})
+		}
+		resp.Folders = append(resp.Folders, folderResp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeError writes an error response.
+func (h *PIIScanHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
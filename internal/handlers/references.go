@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+const (
+	defaultClickStatsLimit = 20
+	maxClickStatsLimit     = 200
+)
+
+// ReferenceClickHandler handles HTTP requests for reference click-through tracking.
+type ReferenceClickHandler struct {
+	chunkRepo  storage.ChunkStore
+	noteRepo   storage.NoteStore
+	clickStore storage.ChunkClickStore
+}
+
+// NewReferenceClickHandler creates a new ReferenceClickHandler.
+func NewReferenceClickHandler(chunkRepo storage.ChunkStore, noteRepo storage.NoteStore, clickStore storage.ChunkClickStore) *ReferenceClickHandler {
+	return &ReferenceClickHandler{
+		chunkRepo:  chunkRepo,
+		noteRepo:   noteRepo,
+		clickStore: clickStore,
+	}
+}
+
+// ReferenceClickStatsResponse represents the response from the reference click stats endpoint.
+//
+// swagger:model ReferenceClickStatsResponse
+type ReferenceClickStatsResponse struct {
+	// Chunks ordered by clicks, most clicked first
+	Chunks []ReferenceClickStats `json:"chunks"`
+}
+
+// ReferenceClickStats describes how often a chunk was cited and opened.
+//
+// swagger:model ReferenceClickStats
+type ReferenceClickStats struct {
+	// Stable chunk ID
+	ChunkID string `json:"chunk_id"`
+	// Number of answers that returned the chunk as a reference
+	Shown int `json:"shown"`
+	// Number of times the reference was opened
+	Clicks int `json:"clicks"`
+	// Click-through rate, clicks / shown (0 when never shown)
+	ClickThrough float64 `json:"click_through"`
+	// When the reference was last opened (RFC3339)
+	LastClickedAt string `json:"last_clicked_at,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for recording a click on a reference.
+//
+// swagger:route POST /api/v1/references/{chunk_id}/click recordReferenceClick
+//
+// # Record a click on a reference
+//
+// Counts that a user opened a reference returned by /api/v1/ask. Together with the
+// number of answers that returned the chunk, clicks give a click-through rate that
+// is reported in debug mode and by GET /api/v1/references/clicks. Users limited to
+// some vaults can only click chunks in those vaults.
+//
+// ---
+// parameters:
+//   - in: path
+//     name: chunk_id
+//     type: string
+//     required: true
+//     description: Chunk ID from the reference (chunk_id)
+//
+// responses:
+//
+//	'204':
+//	  description: Click recorded
+//	'404':
+//	  description: Unknown chunk, or a chunk in a vault the user may not read
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *ReferenceClickHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+	chunkID := chi.URLParam(r, "chunk_id")
+
+	// Only chunks in the index can be clicked, so stray IDs do not pile up
+	chunk, err := h.chunkRepo.GetByID(ctx, chunkID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "Chunk not found")
+			return
+		}
+		logger.ErrorContext(ctx, "failed to look up clicked chunk", "chunk_id", chunkID, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to record click")
+		return
+	}
+
+	// Chunks in vaults the user may not read look like unknown chunks
+	if _, limited := contextutil.AllowedVaults(ctx); limited {
+		note, err := h.noteRepo.GetByID(ctx, chunk.NoteID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			logger.ErrorContext(ctx, "failed to look up note of clicked chunk", "chunk_id", chunkID, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to record click")
+			return
+		}
+		if note == nil || !contextutil.VaultAllowed(ctx, note.VaultID) {
+			h.writeError(w, http.StatusNotFound, "Chunk not found")
+			return
+		}
+	}
+
+	if err := h.clickStore.RecordClick(ctx, chunkID); err != nil {
+		logger.ErrorContext(ctx, "failed to record reference click", "chunk_id", chunkID, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to record click")
+		return
+	}
+	logger.DebugContext(ctx, "reference click recorded", "chunk_id", chunkID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeStats handles HTTP requests for the most clicked references.
+//
+// swagger:route GET /api/v1/references/clicks getReferenceClicks
+//
+// # List the most clicked references
+//
+// Returns chunks ordered by how often their references were opened, with the
+// click-through rate, for use as relevance labels by the evaluation harness.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: limit
+//     type: integer
+//     default: 20
+//     description: Maximum number of chunks to return (1-200)
+//
+// responses:
+//
+//	'200':
+//	  description: Click stats retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/ReferenceClickStatsResponse"
+//	'400':
+//	  description: Invalid limit parameter
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *ReferenceClickHandler) ServeStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	limit := defaultClickStatsLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			logger.WarnContext(ctx, "invalid limit parameter", "limit", limitParam)
+			h.writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(parsed, maxClickStatsLimit)
+	}
+
+	stats, err := h.clickStore.ListMostClicked(ctx, limit)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list reference clicks", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list reference clicks")
+		return
+	}
+
+	chunks := make([]ReferenceClickStats, 0, len(stats))
+	for _, s := range stats {
+		chunk := ReferenceClickStats{
+			ChunkID:      s.ChunkID,
+			Shown:        s.Shown,
+			Clicks:       s.Clicks,
+			ClickThrough: s.ClickThrough(),
+		}
+		if s.LastClickedAt != nil {
+			chunk.LastClickedAt = s.LastClickedAt.UTC().Format(time.RFC3339)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(ReferenceClickStatsResponse{Chunks: chunks})
+}
+
+// writeError writes an error response.
+func (h *ReferenceClickHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
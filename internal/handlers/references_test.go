@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestReferenceClickHandler_AllowedVaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantClick  bool
+		wantStatus int
+	}{
+		{name: "unrestricted", ctx: context.Background(), wantClick: true, wantStatus: http.StatusNoContent},
+		{name: "user allowed the vault", ctx: contextutil.WithAllowedVaults(context.Background(), []int{2}), wantClick: true, wantStatus: http.StatusNoContent},
+		{name: "user limited to another vault", ctx: contextutil.WithAllowedVaults(context.Background(), []int{1}), wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			chunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+			noteRepo := storage_mocks.NewMockNoteStore(ctrl)
+			clickStore := storage_mocks.NewMockChunkClickStore(ctrl)
+			chunkRepo.EXPECT().GetByID(gomock.Any(), "chunk-1").Return(&storage.ChunkRecord{ID: "chunk-1", NoteID: "note-1"}, nil)
+			noteRepo.EXPECT().GetByID(gomock.Any(), "note-1").Return(&storage.NoteRecord{ID: "note-1", VaultID: 2}, nil).AnyTimes()
+			if tt.wantClick {
+				clickStore.EXPECT().RecordClick(gomock.Any(), "chunk-1").Return(nil)
+			}
+
+			router := chi.NewRouter()
+			router.Method(http.MethodPost, "/references/{chunk_id}/click", NewReferenceClickHandler(chunkRepo, noteRepo, clickStore))
+			req := httptest.NewRequest(http.MethodPost, "/references/chunk-1/click", nil).WithContext(tt.ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
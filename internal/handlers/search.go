@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+)
+
+// Search result orders (the sort parameter).
+const (
+	// searchSortScore ranks results by their final score, best first (the default).
+	searchSortScore = "score"
+	// searchSortDate orders results by when their note was last changed, newest first.
+	searchSortDate = "date"
+	// searchSortNote groups results by note (vault, then path) in chunk order.
+	searchSortNote = "note"
+)
+
+const (
+	// searchHandleCacheSize bounds the number of searches kept for paging.
+	searchHandleCacheSize = 256
+	// searchHandleTTL is how long a search can be paged and re-sorted before it must run again.
+	searchHandleTTL = 10 * time.Minute
+	// maxSearchCandidates is the number of chunks a paged search without k retrieves (the k cap).
+	maxSearchCandidates = 20
+)
+
+// searchHandle is the result set of one search, kept for paging and re-sorting.
+type searchHandle struct {
+	results []rag.SearchResult
+	// allowedVaults and limited are the vault scope of the search; only the same scope may read it.
+	allowedVaults []int
+	limited       bool
+}
+
+// SearchResponse represents the HTTP response payload for searches.
+//
+// swagger:model SearchResponse
+type SearchResponse struct {
+	// Chunks retrieved for the question, best first (empty when nothing relevant was found)
+	Results []SearchResultResponse `json:"results"`
+
+	// AbstainReason is why no chunks were returned (e.g., "no_relevant_context").
+	AbstainReason string `json:"abstain_reason,omitempty"`
+
+	// Handle identifies the results for fetching other pages or orders from
+	// GET /api/v1/search/{handle} without searching again (omitted when nothing was found).
+	Handle string `json:"handle,omitempty"`
+
+	// Sort is the order of the results: score, date, or note.
+	Sort string `json:"sort"`
+
+	// Page is the 1-based page returned.
+	Page int `json:"page"`
+
+	// PageSize is the number of results per page.
+	PageSize int `json:"page_size"`
+
+	// Total is the number of results across all pages.
+	Total int `json:"total"`
+
+	// NextPage is the page after this one (omitted on the last page).
+	NextPage int `json:"next_page,omitempty"`
+
+	// Debug contains debug information when debug mode is enabled (via ?debug=true query parameter).
+	Debug *DebugInfo `json:"debug,omitempty"`
+}
+
+// SearchResultResponse is one retrieved chunk in a search response.
+//
+// swagger:model SearchResultResponse
+type SearchResultResponse struct {
+	// Source of the chunk, as in ask references
+	Reference ReferenceResponse `json:"reference"`
+
+	// Full chunk text
+	Text string `json:"text"`
+
+	// Vector similarity score
+	ScoreVector float64 `json:"score_vector"`
+
+	// Lexical score (omitted when the chunk was not reranked)
+	ScoreLexical float64 `json:"score_lexical,omitempty"`
+
+	// Combined final score the results are ranked by
+	ScoreFinal float64 `json:"score_final"`
+
+	// 1-based rank of the chunk by score, whatever the sort order
+	Rank int `json:"rank"`
+
+	// When the chunk's note was last changed (RFC3339, omitted when unknown)
+	ModifiedAt string `json:"modified_at,omitempty"`
+}
+
+// ServeSearch handles searches that return the retrieved chunks without an answer.
+//
+// swagger:route GET /api/v1/search searchChunks
+//
+// # Search indexed notes
+//
+// Embeds the question, retrieves and reranks chunks exactly like /api/v1/ask, and returns
+// the ranked chunks with their scores and references instead of generating an answer. The
+// chat LLM is never called, so folders are not ranked: the search covers the requested
+// folders, or every folder. Useful for browse UIs, debugging retrieval, and clients that do
+// their own generation.
+//
+// GET takes the question in `q` and filters as query parameters; POST takes an AskRequest
+// body (generation settings are ignored). Inline question operators work in both.
+//
+// The results are kept under the returned handle for 10 minutes, so later pages and other
+// orders come from GET /api/v1/search/{handle} without embedding and searching again. With
+// `page_size` and no `k`, the search retrieves the most chunks k allows (20) to page through.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: query
+//     name: q
+//     type: string
+//     description: Question to search for
+//     required: true
+//   - in: query
+//     name: vault
+//     type: string
+//     description: Vaults to search (repeat for several; default all)
+//     required: false
+//   - in: query
+//     name: folder
+//     type: string
+//     description: Folders to search, prefix matched (repeat for several; default all)
+//     required: false
+//   - in: query
+//     name: tag
+//     type: string
+//     description: Only notes carrying all of these tags
+//     required: false
+//   - in: query
+//     name: k
+//     type: integer
+//     description: Number of chunks to return (default chosen from the question, at most 20)
+//     required: false
+//   - in: query
+//     name: modified_after
+//     type: string
+//     description: Only notes last changed on or after this YYYY-MM-DD date
+//     required: false
+//   - in: query
+//     name: modified_before
+//     type: string
+//     description: Only notes last changed before this YYYY-MM-DD date
+//     required: false
+//   - in: query
+//     name: created_after
+//     type: string
+//     description: Only notes created on or after this YYYY-MM-DD date
+//     required: false
+//   - in: query
+//     name: created_before
+//     type: string
+//     description: Only notes created before this YYYY-MM-DD date
+//     required: false
+//   - in: query
+//     name: debug
+//     type: boolean
+//     description: Enable debug mode to include detailed retrieval information
+//     required: false
+//   - in: query
+//     name: include_cold
+//     type: boolean
+//     description: Also search notes that were moved to cold storage
+//     required: false
+//   - in: query
+//     name: page_size
+//     type: integer
+//     description: Number of results per page (default all)
+//     required: false
+//   - in: query
+//     name: page
+//     type: integer
+//     description: 1-based page to return (default 1)
+//     required: false
+//   - in: query
+//     name: sort
+//     type: string
+//     enum: [score, date, note]
+//     description: Result order - score (best first, default), date (note last changed, newest first), or note (grouped by note, in chunk order)
+//     required: false
+//
+// responses:
+//
+//	'200':
+//	  description: Ranked chunks
+//	  schema:
+//	    "$ref": "#/definitions/SearchResponse"
+//	'400':
+//	  description: Bad request (missing question, invalid k, paging, sort, date, or vault name)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: Embedding service unavailable
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'503':
+//	  description: Vector store unavailable
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'504':
+//	  description: Embedding or vector search timed out (EMBED_TIMEOUT or SEARCH_TIMEOUT)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route POST /api/v1/search searchChunksPost
+//
+// # Search indexed notes (JSON body)
+//
+// Same as GET /api/v1/search with an AskRequest body.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/AskRequest"
+//   - in: query
+//     name: debug
+//     type: boolean
+//     description: Enable debug mode to include detailed retrieval information
+//     required: false
+//   - in: query
+//     name: include_cold
+//     type: boolean
+//     description: Also search notes that were moved to cold storage
+//     required: false
+//   - in: query
+//     name: page_size
+//     type: integer
+//     description: Number of results per page (default all)
+//     required: false
+//   - in: query
+//     name: page
+//     type: integer
+//     description: 1-based page to return (default 1)
+//     required: false
+//   - in: query
+//     name: sort
+//     type: string
+//     enum: [score, date, note]
+//     description: Result order - score (best first, default), date (note last changed, newest first), or note (grouped by note, in chunk order)
+//     required: false
+//
+// responses:
+//
+//	'200':
+//	  description: Ranked chunks
+//	  schema:
+//	    "$ref": "#/definitions/SearchResponse"
+//	'400':
+//	  description: Bad request (missing question, invalid paging, sort, date, or vault name)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'413':
+//	  description: Request body too large
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'502':
+//	  description: Embedding service unavailable
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'503':
+//	  description: Vector store unavailable
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'504':
+//	  description: Embedding or vector search timed out (EMBED_TIMEOUT or SEARCH_TIMEOUT)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *AskHandler) ServeSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	var ragReq rag.AskRequest
+	var ok bool
+	switch r.Method {
+	case http.MethodGet:
+		ragReq, ok = h.searchQueryRequest(w, r)
+	case http.MethodPost:
+		_, ragReq, ok = h.decodeAskRequest(w, r)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !ok {
+		return
+	}
+	sortBy, page, pageSize, ok := h.searchPaging(w, r)
+	if !ok {
+		return
+	}
+	// A paged search retrieves as many chunks as k allows, unless the caller chose k
+	if pageSize > 0 && ragReq.K == 0 {
+		ragReq.K = maxSearchCandidates
+	}
+
+	ragResp, err := h.ragEngine.Search(ctx, ragReq)
+	if err != nil {
+		h.handleRAGError(w, ctx, err, "Failed to search")
+		return
+	}
+
+	handle := newSearchHandle(ctx, ragResp.Results)
+	var handleID string
+	if len(ragResp.Results) > 0 {
+		handleID = uuid.New().String()
+		h.searchHandles.Set(handleID, handle)
+	}
+	resp := searchPage(handle.results, sortBy, page, pageSize)
+	resp.Handle = handleID
+	resp.AbstainReason = ragResp.AbstainReason
+	resp.Debug = h.debugInfo(ctx, ragResp.Debug)
+	h.writeSearchResponse(w, r, resp)
+}
+
+// ServeSearchPage returns another page or order of a previous search.
+//
+// swagger:route GET /api/v1/search/{handle} searchPage
+//
+// # Page through search results
+//
+// Returns a page of the results kept under a handle from GET or POST /api/v1/search, in
+// any order, without embedding and searching again. Handles expire 10 minutes after the
+// search, and only callers with the same vault access as the search can read them.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: handle
+//     type: string
+//     required: true
+//     description: Handle returned by the search
+//   - in: query
+//     name: page_size
+//     type: integer
+//     description: Number of results per page (default all)
+//     required: false
+//   - in: query
+//     name: page
+//     type: integer
+//     description: 1-based page to return (default 1)
+//     required: false
+//   - in: query
+//     name: sort
+//     type: string
+//     enum: [score, date, note]
+//     description: Result order - score (best first, default), date (note last changed, newest first), or note (grouped by note, in chunk order)
+//     required: false
+//
+// responses:
+//
+//	'200':
+//	  description: Page of search results
+//	  schema:
+//	    "$ref": "#/definitions/SearchResponse"
+//	'400':
+//	  description: Invalid paging or sort
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown or expired handle
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *AskHandler) ServeSearchPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sortBy, page, pageSize, ok := h.searchPaging(w, r)
+	if !ok {
+		return
+	}
+	handleID := chi.URLParam(r, "handle")
+	handle, found := h.searchHandles.Get(handleID)
+	if !found || !handle.visibleTo(ctx) {
+		h.writeError(w, http.StatusNotFound, "Search handle not found or expired")
+		return
+	}
+
+	resp := searchPage(handle.results, sortBy, page, pageSize)
+	resp.Handle = handleID
+	h.writeSearchResponse(w, r, resp)
+}
+
+// writeSearchResponse writes a search response as JSON.
+func (h *AskHandler) writeSearchResponse(w http.ResponseWriter, r *http.Request, resp SearchResponse) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		contextutil.LoggerFromContext(ctx).ErrorContext(ctx, "failed to encode response", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+}
+
+// searchPaging reads the sort, page, and page_size query parameters. On failure it writes
+// the error response and returns false. A page size of 0 means one page with every result.
+func (h *AskHandler) searchPaging(w http.ResponseWriter, r *http.Request) (sortBy string, page, pageSize int, ok bool) {
+	query := r.URL.Query()
+	sortBy = cmp.Or(query.Get("sort"), searchSortScore)
+	if sortBy != searchSortScore && sortBy != searchSortDate && sortBy != searchSortNote {
+		h.writeError(w, http.StatusBadRequest, "sort must be score, date, or note")
+		return "", 0, 0, false
+	}
+	page = 1
+	if pageParam := query.Get("page"); pageParam != "" {
+		parsed, err := strconv.Atoi(pageParam)
+		if err != nil || parsed < 1 {
+			h.writeError(w, http.StatusBadRequest, "page must be a positive integer")
+			return "", 0, 0, false
+		}
+		page = parsed
+	}
+	if sizeParam := query.Get("page_size"); sizeParam != "" {
+		parsed, err := strconv.Atoi(sizeParam)
+		if err != nil || parsed < 1 {
+			h.writeError(w, http.StatusBadRequest, "page_size must be a positive integer")
+			return "", 0, 0, false
+		}
+		pageSize = parsed
+	}
+	return sortBy, page, pageSize, true
+}
+
+// newSearchHandle keeps results under the vault scope of the request that searched them.
+func newSearchHandle(ctx context.Context, results []rag.SearchResult) searchHandle {
+	allowed, limited := contextutil.AllowedVaults(ctx)
+	allowed = slices.Clone(allowed)
+	slices.Sort(allowed)
+	return searchHandle{results: results, allowedVaults: allowed, limited: limited}
+}
+
+// visibleTo reports whether the request in ctx has the vault scope the search ran with.
+func (s searchHandle) visibleTo(ctx context.Context) bool {
+	allowed, limited := contextutil.AllowedVaults(ctx)
+	allowed = slices.Clone(allowed)
+	slices.Sort(allowed)
+	return limited == s.limited && slices.Equal(allowed, s.allowedVaults)
+}
+
+// searchPage sorts results (kept in score order) and returns the requested page of them.
+// Results keep their score rank in every order.
+func searchPage(results []rag.SearchResult, sortBy string, page, pageSize int) SearchResponse {
+	if pageSize == 0 {
+		pageSize = max(len(results), 1)
+	}
+	sorted := slices.Clone(results)
+	switch sortBy {
+	case searchSortDate:
+		slices.SortStableFunc(sorted, func(a, b rag.SearchResult) int {
+			return b.ModifiedAt.Compare(a.ModifiedAt)
+		})
+	case searchSortNote:
+		slices.SortStableFunc(sorted, func(a, b rag.SearchResult) int {
+			return cmp.Or(
+				strings.Compare(a.Reference.Vault, b.Reference.Vault),
+				strings.Compare(a.Reference.RelPath, b.Reference.RelPath),
+				cmp.Compare(a.Reference.ChunkIndex, b.Reference.ChunkIndex),
+			)
+		})
+	}
+
+	resp := SearchResponse{
+		Results:  []SearchResultResponse{},
+		Sort:     sortBy,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    len(sorted),
+	}
+	// Compare page numbers first: multiplying a huge page by the page size overflows
+	if pages := (len(sorted) + pageSize - 1) / pageSize; page-1 >= pages {
+		return resp
+	}
+	start := (page - 1) * pageSize
+	end := min(start+pageSize, len(sorted))
+	if end < len(sorted) {
+		resp.NextPage = page + 1
+	}
+	for _, result := range sorted[start:end] {
+		item := SearchResultResponse{
+			Reference:    referenceResponse(result.Reference),
+			Text:         result.Text,
+			ScoreVector:  result.ScoreVector,
+			ScoreLexical: result.ScoreLexical,
+			ScoreFinal:   result.ScoreFinal,
+			Rank:         result.Rank,
+		}
+		if !result.ModifiedAt.IsZero() {
+			item.ModifiedAt = result.ModifiedAt.UTC().Format(time.RFC3339)
+		}
+		resp.Results = append(resp.Results, item)
+	}
+	return resp
+}
+
+// searchQueryRequest builds a search request from the query parameters of a GET search and
+// validates it like an ask request body. On failure it writes the error response and
+// returns false.
+func (h *AskHandler) searchQueryRequest(w http.ResponseWriter, r *http.Request) (rag.AskRequest, bool) {
+	query := r.URL.Query()
+	req := AskRequest{
+		Question:       query.Get("q"),
+		Vaults:         query["vault"],
+		Folders:        query["folder"],
+		Tags:           query["tag"],
+		ModifiedAfter:  query.Get("modified_after"),
+		ModifiedBefore: query.Get("modified_before"),
+		CreatedAfter:   query.Get("created_after"),
+		CreatedBefore:  query.Get("created_before"),
+	}
+	if kParam := query.Get("k"); kParam != "" {
+		k, err := strconv.Atoi(kParam)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "k must be an integer")
+			return rag.AskRequest{}, false
+		}
+		req.K = k
+	}
+	_, ragReq, ok := h.prepareAskRequest(w, r, req)
+	return ragReq, ok
+}
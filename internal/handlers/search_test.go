@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestAskHandler_ServeSearch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return([]storage.VaultRecord{{ID: 1, Name: "personal"}, {ID: 2, Name: "work"}}, nil).AnyTimes()
+	mockRAGEngine := &mockRAGEngine{}
+	handler := NewAskHandler(mockRAGEngine, mockVaultRepo, nil, "", RequestLimits{})
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		err        error
+		wantStatus int
+		check      func(t *testing.T, req rag.AskRequest)
+	}{
+		{
+			name:       "query parameters",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?q=tomatoes&vault=personal&folder=garden&folder=recipes&tag=%23food&k=5&modified_after=2024-01-01&debug=true", nil),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, req rag.AskRequest) {
+				if req.Question != "tomatoes" || !slices.Equal(req.Vaults, []string{"personal"}) || !slices.Equal(req.Folders, []string{"garden", "recipes"}) ||
+					!slices.Equal(req.Tags, []string{"food"}) || req.K != 5 || !req.UpdatedAfter.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !req.Debug {
+					t.Errorf("search request = %+v, want the query parameters", req)
+				}
+			},
+		},
+		{
+			name:       "inline operators in q",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?q=vault%3Awork+standup+notes", nil),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, req rag.AskRequest) {
+				if req.Question != "standup notes" || !slices.Equal(req.Vaults, []string{"work"}) {
+					t.Errorf("search request = %+v, want question %q in vault work", req, "standup notes")
+				}
+			},
+		},
+		{
+			name:       "JSON body",
+			request:    httptest.NewRequest(http.MethodPost, "/api/v1/search", strings.NewReader(`{"question":"tomatoes","folders":["garden"]}`)),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, req rag.AskRequest) {
+				if req.Question != "tomatoes" || !slices.Equal(req.Folders, []string{"garden"}) {
+					t.Errorf("search request = %+v, want the body fields", req)
+				}
+			},
+		},
+		{
+			name:       "missing question",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?vault=personal", nil),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid k",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?q=tomatoes&k=many", nil),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown vault",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?q=tomatoes&vault=archive", nil),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "vector store down",
+			request:    httptest.NewRequest(http.MethodGet, "/api/v1/search?q=tomatoes", nil),
+			err:        rag.ErrVectorStoreUnavailable,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "method not allowed",
+			request:    httptest.NewRequest(http.MethodDelete, "/api/v1/search", nil),
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRAGEngine.reset()
+			mockRAGEngine.err = tt.err
+			w := httptest.NewRecorder()
+			handler.ServeSearch(w, tt.request)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeSearch() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.check != nil {
+				tt.check(t, mockRAGEngine.lastRequest)
+			}
+		})
+	}
+}
+
+func TestAskHandler_ServeSearch_Response(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRAGEngine := &mockRAGEngine{searchResponse: rag.SearchResponse{
+		Results: []rag.SearchResult{{
+			Reference:   rag.Reference{ChunkID: "chunk-1", Vault: "personal", RelPath: "garden.md", HeadingPath: "# Garden", Position: 1, TotalChunks: 3},
+			Text:        "Tomatoes grow in the north bed.",
+			ScoreVector: 0.8,
+			ScoreFinal:  0.75,
+			Rank:        1,
+		}},
+	}}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+
+	w := httptest.NewRecorder()
+	handler.ServeSearch(w, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=tomatoes", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeSearch() status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp SearchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Fatalf("results = %+v, want one", resp.Results)
+	}
+	got := resp.Results[0]
+	if got.Reference.ChunkID != "chunk-1" || got.Reference.TotalChunks != 3 || got.Text != "Tomatoes grow in the north bed." || got.ScoreFinal != 0.75 || got.Rank != 1 {
+		t.Errorf("result = %+v, want the engine's chunk", got)
+	}
+	if resp.Debug != nil {
+		t.Errorf("debug = %+v, want none", resp.Debug)
+	}
+}
+
+func TestAskHandler_ServeSearchPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockRAGEngine := &mockRAGEngine{searchResponse: rag.SearchResponse{
+		Results: []rag.SearchResult{
+			{Reference: rag.Reference{ChunkID: "b-1", Vault: "personal", RelPath: "b.md", ChunkIndex: 1}, ScoreFinal: 0.9, Rank: 1, ModifiedAt: jan},
+			{Reference: rag.Reference{ChunkID: "a-0", Vault: "personal", RelPath: "a.md", ChunkIndex: 0}, ScoreFinal: 0.8, Rank: 2, ModifiedAt: jan.AddDate(0, 2, 0)},
+			{Reference: rag.Reference{ChunkID: "b-0", Vault: "personal", RelPath: "b.md", ChunkIndex: 0}, ScoreFinal: 0.7, Rank: 3, ModifiedAt: jan},
+		},
+	}}
+	handler := NewAskHandler(mockRAGEngine, storage_mocks.NewMockVaultStore(ctrl), nil, "", RequestLimits{})
+	router := chi.NewRouter()
+	router.Get("/api/v1/search", handler.ServeSearch)
+	router.Get("/api/v1/search/{handle}", handler.ServeSearchPage)
+
+	get := func(t *testing.T, ctx context.Context, url string, wantStatus int) SearchResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctx))
+		if w.Code != wantStatus {
+			t.Fatalf("GET %s status = %d, want %d: %s", url, w.Code, wantStatus, w.Body.String())
+		}
+		var resp SearchResponse
+		if wantStatus == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return resp
+	}
+	chunkIDs := func(resp SearchResponse) []string {
+		var ids []string
+		for _, result := range resp.Results {
+			ids = append(ids, result.Reference.ChunkID)
+		}
+		return ids
+	}
+
+	ctx := contextutil.WithAllowedVaults(context.Background(), []int{1})
+	first := get(t, ctx, "/api/v1/search?q=tomatoes&page_size=2", http.StatusOK)
+	if mockRAGEngine.lastRequest.K != maxSearchCandidates {
+		t.Errorf("search k = %d, want %d for a paged search", mockRAGEngine.lastRequest.K, maxSearchCandidates)
+	}
+	if first.Handle == "" || first.Total != 3 || first.Page != 1 || first.NextPage != 2 || !slices.Equal(chunkIDs(first), []string{"b-1", "a-0"}) {
+		t.Fatalf("first page = %+v, want b-1 and a-0 of 3 with a handle", first)
+	}
+	if first.Results[1].ModifiedAt != "2024-03-01T00:00:00Z" {
+		t.Errorf("modified_at = %q, want 2024-03-01T00:00:00Z", first.Results[1].ModifiedAt)
+	}
+
+	// Pages come from the handle; searching again would fail
+	mockRAGEngine.err = errors.New("search should not run again")
+	handleURL := "/api/v1/search/" + first.Handle
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		query      string
+		wantStatus int
+		wantIDs    []string
+		wantNext   int
+	}{
+		{name: "second page", ctx: ctx, query: "?page=2&page_size=2", wantStatus: http.StatusOK, wantIDs: []string{"b-0"}},
+		{name: "by date", ctx: ctx, query: "?sort=date", wantStatus: http.StatusOK, wantIDs: []string{"a-0", "b-1", "b-0"}},
+		{name: "by note", ctx: ctx, query: "?sort=note&page_size=2", wantStatus: http.StatusOK, wantIDs: []string{"a-0", "b-0"}, wantNext: 2},
+		{name: "past the last page", ctx: ctx, query: "?page=3&page_size=2", wantStatus: http.StatusOK},
+		{name: "page too large to multiply", ctx: ctx, query: "?page=4611686018427387905&page_size=3", wantStatus: http.StatusOK},
+		{name: "page size too large to add", ctx: ctx, query: "?page=2&page_size=9223372036854775807", wantStatus: http.StatusOK},
+		{name: "invalid sort", ctx: ctx, query: "?sort=title", wantStatus: http.StatusBadRequest},
+		{name: "invalid page", ctx: ctx, query: "?page=0", wantStatus: http.StatusBadRequest},
+		{name: "other vault access", ctx: context.Background(), wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, tt.ctx, handleURL+tt.query, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if !slices.Equal(chunkIDs(resp), tt.wantIDs) || resp.NextPage != tt.wantNext || resp.Total != 3 {
+				t.Errorf("page = %+v, want %v with next page %d", resp, tt.wantIDs, tt.wantNext)
+			}
+		})
+	}
+
+	t.Run("ranks are kept when re-sorting", func(t *testing.T) {
+		resp := get(t, ctx, handleURL+"?sort=note", http.StatusOK)
+		if resp.Results[0].Rank != 2 {
+			t.Errorf("rank of a-0 = %d, want its score rank 2", resp.Results[0].Rank)
+		}
+	})
+
+	t.Run("unknown handle", func(t *testing.T) {
+		get(t, ctx, "/api/v1/search/unknown", http.StatusNotFound)
+	})
+}
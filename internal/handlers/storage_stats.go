@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/monitor"
+)
+
+// StorageStatsHandler handles HTTP requests for storage usage statistics.
+type StorageStatsHandler struct {
+	storageMonitor *monitor.StorageMonitor
+}
+
+// NewStorageStatsHandler creates a new StorageStatsHandler.
+func NewStorageStatsHandler(storageMonitor *monitor.StorageMonitor) *StorageStatsHandler {
+	return &StorageStatsHandler{
+		storageMonitor: storageMonitor,
+	}
+}
+
+// StorageStatsResponse represents the response from the storage stats endpoint.
+//
+// swagger:model StorageStatsResponse
+type StorageStatsResponse struct {
+	// SQLiteBytes is the size of the SQLite database including WAL and shared-memory files
+	SQLiteBytes int64 `json:"sqlite_bytes"`
+	// SQLiteLimitBytes is the SQLite soft limit (omitted when no limit is set)
+	SQLiteLimitBytes int64 `json:"sqlite_limit_bytes,omitempty"`
+	// QdrantEstimatedBytes is the estimated raw vector data size across collections
+	QdrantEstimatedBytes int64 `json:"qdrant_estimated_bytes"`
+	// QdrantLimitBytes is the Qdrant soft limit (omitted when no limit is set)
+	QdrantLimitBytes int64 `json:"qdrant_limit_bytes,omitempty"`
+	// Collections lists the measured vector collections
+	Collections []CollectionStatsResponse `json:"collections"`
+	// Exceeded lists resources over their soft limit (sqlite, qdrant)
+	Exceeded []string `json:"exceeded"`
+	// CheckedAt is when usage was measured (RFC3339)
+	CheckedAt string `json:"checked_at"`
+}
+
+// CollectionStatsResponse describes the size of a vector collection.
+//
+// swagger:model CollectionStatsResponse
+type CollectionStatsResponse struct {
+	// Name of the collection
+	Name string `json:"name"`
+	// Points is the number of vectors stored
+	Points int `json:"points"`
+	// VectorSize is the vector dimension
+	VectorSize int `json:"vector_size"`
+	// EstimatedBytes is the raw vector data size (points x dimensions x 4 bytes)
+	EstimatedBytes int64 `json:"estimated_bytes"`
+}
+
+// ServeHTTP handles HTTP requests for storage usage statistics.
+//
+// swagger:route GET /api/v1/stats/storage getStorageStats
+//
+// # Get storage usage
+//
+// Measures the SQLite database size and the estimated Qdrant collection sizes,
+// and reports which configured soft limits are exceeded.
+//
+// ---
+// produces:
+// - application/json
+// responses:
+//
+//	'200':
+//	  description: Storage usage measured successfully
+//	  schema:
+//	    "$ref": "#/definitions/StorageStatsResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *StorageStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	usage, err := h.storageMonitor.Check(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to measure storage usage", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to measure storage usage")
+		return
+	}
+
+	collections := make([]CollectionStatsResponse, 0, len(usage.QdrantCollections))
+	for _, collection := range usage.QdrantCollections {
+		collections = append(collections, CollectionStatsResponse{
+			Name:           collection.Name,
+			Points:         collection.Points,
+			VectorSize:     collection.VectorSize,
+			EstimatedBytes: collection.EstimatedBytes,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(StorageStatsResponse{
+		SQLiteBytes:          usage.SQLiteBytes,
+		SQLiteLimitBytes:     usage.SQLiteLimitBytes,
+		QdrantEstimatedBytes: usage.QdrantBytes,
+		QdrantLimitBytes:     usage.QdrantLimitBytes,
+		Collections:          collections,
+		Exceeded:             usage.Exceeded,
+		CheckedAt:            usage.CheckedAt.Format(time.RFC3339),
+	})
+}
+
+// writeError writes an error response.
+func (h *StorageStatsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
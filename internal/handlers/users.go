@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// maxUserNameLength bounds user names, which appear in logs.
+const maxUserNameLength = 64
+
+// UsersHandler handles HTTP requests for managing API users and the vaults they may read.
+type UsersHandler struct {
+	users        storage.UserStore
+	vaultManager *vault.Manager
+}
+
+// NewUsersHandler creates a new UsersHandler. users may be nil, disabling the endpoints.
+func NewUsersHandler(users storage.UserStore, vaultManager *vault.Manager) *UsersHandler {
+	return &UsersHandler{
+		users:        users,
+		vaultManager: vaultManager,
+	}
+}
+
+// UserRequest creates a user or changes their vaults.
+//
+// swagger:model UserRequest
+type UserRequest struct {
+	// Name of the new user (ignored when changing vaults; the name is in the path)
+	Name string `json:"name,omitempty"`
+	// Vaults the user may read; questions, searches, and vault listings are limited to them
+	Vaults []string `json:"vaults"`
+}
+
+// UsersResponse lists the API users.
+//
+// swagger:model UsersResponse
+type UsersResponse struct {
+	// Users ordered by name
+	Users []UserResponse `json:"users"`
+}
+
+// UserResponse describes an API user.
+//
+// swagger:model UserResponse
+type UserResponse struct {
+	// Name of the user
+	Name string `json:"name"`
+	// Vaults the user may read, in the order of GET /api/v1/vaults
+	Vaults []string `json:"vaults"`
+	// CreatedAt is when the user was created
+	CreatedAt time.Time `json:"created_at"`
+	// Token is the user's API token, returned only when the user is created. Send it as
+	// "Authorization: Bearer <token>" or "X-API-Key: <token>"; only its hash is stored.
+	Token string `json:"token,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for API users.
+//
+// swagger:route GET /api/v1/admin/users listUsers
+//
+// # List API users
+//
+// Returns every API user with the vaults they may read. Tokens are never listed.
+// Requires the admin bearer token.
+//
+// ---
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// responses:
+//
+//	'200':
+//	  description: Users retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/UsersResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route POST /api/v1/admin/users createUser
+//
+// # Create an API user
+//
+// Creates a user limited to the given vaults and returns their API token, which cannot be
+// retrieved again. Requests carrying the token only retrieve from, list, and open those
+// vaults. Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/UserRequest"
+//
+// responses:
+//
+//	'201':
+//	  description: User created; the response includes the token
+//	  schema:
+//	    "$ref": "#/definitions/UserResponse"
+//	'400':
+//	  description: Invalid request body, name, or vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'401':
+//	  description: Missing or invalid admin token
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'403':
+//	  description: Admin endpoints are disabled (ADMIN_TOKEN is not set)
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'409':
+//	  description: A user with this name already exists
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route PUT /api/v1/admin/users/{name} setUserVaults
+//
+// # Change the vaults of an API user
+//
+// Replaces the vaults the user may read. Takes effect on the user's next request.
+// Requires the admin bearer token.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+// security:
+// - bearer: []
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: User name
+//   - in: body
+//     name: body
+//     required: true
+//     schema:
+//     "$ref": "#/definitions/UserRequest"
+//
+// responses:
+//
+//	'200':
+//	  description: Vaults changed
+//	  schema:
+//	    "$ref": "#/definitions/UserResponse"
+//	'400':
+//	  description: Invalid request body or vault
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'404':
+//	  description: Unknown user
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//
+// swagger:route DELETE /api/v1/admin/users/{name} deleteUser
+//
+// # Delete an API user
+//
+// Deletes the user; their token stops working immediately. Requires the admin bearer token.
+//
+// ---
+// security:
+// - bearer: []
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: User name
+//
+// responses:
+//
+//	'204':
+//	  description: User deleted
+//	'404':
+//	  description: Unknown user
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *UsersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if h.users == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "Users are not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		users, err := h.users.List(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list users", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to list users")
+			return
+		}
+		resp := UsersResponse{Users: make([]UserResponse, 0, len(users))}
+		for _, user := range users {
+			resp.Users = append(resp.Users, h.userResponse(user))
+		}
+		h.writeJSON(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var req UserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WarnContext(ctx, "invalid user request", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || len(name) > maxUserNameLength {
+			h.writeError(w, http.StatusBadRequest, "name is required and must be at most 64 characters")
+			return
+		}
+		vaultIDs, ok := h.resolveVaults(w, req.Vaults)
+		if !ok {
+			return
+		}
+
+		token := rand.Text()
+		user := storage.UserRecord{Name: name, VaultIDs: vaultIDs}
+		if err := h.users.Create(ctx, &user, storage.HashToken(token)); err != nil {
+			if errors.Is(err, storage.ErrAlreadyExists) {
+				h.writeError(w, http.StatusConflict, "User already exists: "+name)
+				return
+			}
+			logger.ErrorContext(ctx, "failed to create user", "user", name, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to create user")
+			return
+		}
+		logger.InfoContext(ctx, "user created", "user", name, "vaults", req.Vaults)
+		resp := h.userResponse(user)
+		resp.Token = token
+		h.writeJSON(w, http.StatusCreated, resp)
+	case http.MethodPut:
+		name := chi.URLParam(r, "name")
+		var req UserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WarnContext(ctx, "invalid user request", "error", err)
+			h.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		vaultIDs, ok := h.resolveVaults(w, req.Vaults)
+		if !ok {
+			return
+		}
+		if err := h.users.SetVaults(ctx, name, vaultIDs); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				h.writeError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			logger.ErrorContext(ctx, "failed to set user vaults", "user", name, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to change user vaults")
+			return
+		}
+		logger.InfoContext(ctx, "user vaults changed", "user", name, "vaults", req.Vaults)
+		users, err := h.users.List(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to list users", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to list users")
+			return
+		}
+		for _, user := range users {
+			if user.Name == name {
+				h.writeJSON(w, http.StatusOK, h.userResponse(user))
+				return
+			}
+		}
+		h.writeError(w, http.StatusNotFound, "User not found")
+	case http.MethodDelete:
+		name := chi.URLParam(r, "name")
+		if err := h.users.Delete(ctx, name); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				h.writeError(w, http.StatusNotFound, "User not found")
+				return
+			}
+			logger.ErrorContext(ctx, "failed to delete user", "user", name, "error", err)
+			h.writeError(w, http.StatusInternalServerError, "Failed to delete user")
+			return
+		}
+		logger.InfoContext(ctx, "user deleted", "user", name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		logger.WarnContext(ctx, "method not allowed", "method", r.Method)
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// resolveVaults converts vault names to IDs, writing a 400 response for unknown names.
+func (h *UsersHandler) resolveVaults(w http.ResponseWriter, names []string) ([]int, bool) {
+	vaultIDs := make([]int, 0, len(names))
+	for _, name := range names {
+		v, err := h.vaultManager.VaultByName(name)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "Unknown vault: "+name)
+			return nil, false
+		}
+		vaultIDs = append(vaultIDs, v.ID)
+	}
+	return vaultIDs, true
+}
+
+// userResponse describes user with their vault names, ordered like the configured vaults.
+func (h *UsersHandler) userResponse(user storage.UserRecord) UserResponse {
+	resp := UserResponse{Name: user.Name, Vaults: []string{}, CreatedAt: user.CreatedAt}
+	for _, v := range h.vaultManager.ListVaults() {
+		for _, id := range user.VaultIDs {
+			if id == v.ID {
+				resp.Vaults = append(resp.Vaults, v.Name)
+				break
+			}
+		}
+	}
+	return resp
+}
+
+// writeJSON writes a JSON response.
+func (h *UsersHandler) writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error response.
+func (h *UsersHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
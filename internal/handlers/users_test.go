@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vault"
+
+	"go.uber.org/mock/gomock"
+)
+
+func newTestUsersRouter(t *testing.T, ctrl *gomock.Controller) (http.Handler, *storage_mocks.MockUserStore) {
+	t.Helper()
+	vaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	vaultRepo.EXPECT().GetOrCreateByName(gomock.Any(), "personal", "/vaults/personal").Return(storage.VaultRecord{ID: 1, Name: "personal", RootPath: "/vaults/personal"}, nil)
+	vaultRepo.EXPECT().GetOrCreateByName(gomock.Any(), "work", "/vaults/work").Return(storage.VaultRecord{ID: 2, Name: "work", RootPath: "/vaults/work"}, nil)
+	manager, err := vault.NewManager(context.Background(), vaultRepo, []vault.Config{
+		{Name: "personal", Path: "/vaults/personal"},
+		{Name: "work", Path: "/vaults/work"},
+	}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	users := storage_mocks.NewMockUserStore(ctrl)
+	handler := NewUsersHandler(users, manager)
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/api/v1/admin/users", handler)
+	router.Method(http.MethodPost, "/api/v1/admin/users", handler)
+	router.Method(http.MethodPut, "/api/v1/admin/users/{name}", handler)
+	router.Method(http.MethodDelete, "/api/v1/admin/users/{name}", handler)
+	return router, users
+}
+
+func TestUsersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	router, users := newTestUsersRouter(t, ctrl)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	// Creating a user returns a token whose hash is stored
+	var storedHash string
+	users.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user *storage.UserRecord, tokenHash string) error {
+		if user.Name != "alice" || !reflect.DeepEqual(user.VaultIDs, []int{2}) {
+			t.Errorf("Create() user = %+v, want alice with the work vault", user)
+		}
+		storedHash = tokenHash
+		user.ID = 1
+		return nil
+	})
+	w := serve(http.MethodPost, "/api/v1/admin/users", `{"name": " alice ", "vaults": ["work"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want 201: %s", w.Code, w.Body.String())
+	}
+	var created UserResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Token == "" || storage.HashToken(created.Token) != storedHash || !reflect.DeepEqual(created.Vaults, []string{"work"}) {
+		t.Errorf("POST response = %+v, want the token of the stored hash and the work vault", created)
+	}
+
+	users.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(storage.ErrAlreadyExists)
+	if w := serve(http.MethodPost, "/api/v1/admin/users", `{"name": "alice"}`); w.Code != http.StatusConflict {
+		t.Errorf("POST for a taken name status = %d, want 409", w.Code)
+	}
+	if w := serve(http.MethodPost, "/api/v1/admin/users", `{"name": "bob", "vaults": ["archive"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST with an unknown vault status = %d, want 400", w.Code)
+	}
+	if w := serve(http.MethodPost, "/api/v1/admin/users", `{"vaults": ["work"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST without a name status = %d, want 400", w.Code)
+	}
+
+	// Listing never shows tokens
+	users.EXPECT().List(gomock.Any()).Return([]storage.UserRecord{{ID: 1, Name: "alice", VaultIDs: []int{1, 2}}}, nil)
+	w = serve(http.MethodGet, "/api/v1/admin/users", "")
+	var list UsersResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Users) != 1 || list.Users[0].Token != "" || !reflect.DeepEqual(list.Users[0].Vaults, []string{"personal", "work"}) {
+		t.Errorf("GET response = %+v, want alice with both vaults and no token", list)
+	}
+
+	users.EXPECT().SetVaults(gomock.Any(), "alice", []int{1}).Return(nil)
+	users.EXPECT().List(gomock.Any()).Return([]storage.UserRecord{{ID: 1, Name: "alice", VaultIDs: []int{1}}}, nil)
+	if w := serve(http.MethodPut, "/api/v1/admin/users/alice", `{"vaults": ["personal"]}`); w.Code != http.StatusOK {
+		t.Errorf("PUT status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	users.EXPECT().SetVaults(gomock.Any(), "carol", []int{}).Return(storage.ErrNotFound)
+	if w := serve(http.MethodPut, "/api/v1/admin/users/carol", `{"vaults": []}`); w.Code != http.StatusNotFound {
+		t.Errorf("PUT for an unknown user status = %d, want 404", w.Code)
+	}
+
+	users.EXPECT().Delete(gomock.Any(), "alice").Return(nil)
+	if w := serve(http.MethodDelete, "/api/v1/admin/users/alice", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want 204", w.Code)
+	}
+	users.EXPECT().Delete(gomock.Any(), "alice").Return(storage.ErrNotFound)
+	if w := serve(http.MethodDelete, "/api/v1/admin/users/alice", ""); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE twice status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// VaultsHandler handles HTTP requests for listing vaults and their folders, so clients can
+// discover valid values for the vaults and folders fields of ask requests.
+type VaultsHandler struct {
+	vaultManager *vault.Manager
+	noteRepo     storage.NoteStore
+}
+
+// NewVaultsHandler creates a new VaultsHandler.
+func NewVaultsHandler(vaultManager *vault.Manager, noteRepo storage.NoteStore) *VaultsHandler {
+	return &VaultsHandler{
+		vaultManager: vaultManager,
+		noteRepo:     noteRepo,
+	}
+}
+
+// VaultsResponse lists the configured vaults.
+//
+// swagger:model VaultsResponse
+type VaultsResponse struct {
+	// Vaults ordered by ID
+	Vaults []VaultResponse `json:"vaults"`
+}
+
+// VaultResponse describes a configured vault and its indexed content.
+//
+// swagger:model VaultResponse
+type VaultResponse struct {
+	// Name is the vault name used in requests (e.g. personal)
+	Name string `json:"name"`
+	// Path is the vault's root directory on the server
+	Path string `json:"path"`
+	// NoteCount is the number of indexed notes
+	NoteCount int `json:"note_count"`
+	// ChunkCount is the number of indexed chunks
+	ChunkCount int `json:"chunk_count"`
+}
+
+// FolderTreeResponse is the folder tree of a vault.
+//
+// swagger:model FolderTreeResponse
+type FolderTreeResponse struct {
+	// Vault is the vault name
+	Vault string `json:"vault"`
+	// Folders are the top-level folders with indexed notes, ordered by name
+	Folders []FolderNode `json:"folders"`
+}
+
+// FolderNode is a folder in a vault's folder tree.
+//
+// swagger:model FolderNode
+type FolderNode struct {
+	// Name is the last component of the folder path
+	Name string `json:"name"`
+	// Path is the folder path relative to the vault root, as used in the folders field of ask requests
+	Path string `json:"path"`
+	// Children are the subfolders, ordered by name (omitted for leaf folders)
+	Children []FolderNode `json:"children,omitempty"`
+}
+
+// ServeHTTP handles HTTP requests for listing vaults.
+//
+// swagger:route GET /api/v1/vaults listVaults
+//
+// # List vaults
+//
+// Returns every configured vault with its root path and indexed note and chunk counts.
+// Vault names are the valid values of the vaults field of ask and search requests.
+// Requests with a user's API token list only the vaults that user may read.
+//
+// ---
+// produces:
+// - application/json
+// responses:
+//
+//	'200':
+//	  description: Vaults retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/VaultsResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *VaultsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	counts, err := h.noteRepo.CountByVault(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to count notes by vault", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list vaults")
+		return
+	}
+
+	vaults := h.vaultManager.ListVaults()
+	resp := VaultsResponse{Vaults: make([]VaultResponse, 0, len(vaults))}
+	for _, v := range vaults {
+		if !contextutil.VaultAllowed(ctx, v.ID) {
+			continue
+		}
+		resp.Vaults = append(resp.Vaults, VaultResponse{
+			Name:       v.Name,
+			Path:       v.RootPath,
+			NoteCount:  counts[v.ID].NoteCount,
+			ChunkCount: counts[v.ID].ChunkCount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// ServeFolders handles HTTP requests for a vault's folder tree.
+//
+// swagger:route GET /api/v1/vaults/{name}/folders listVaultFolders
+//
+// # List the folders of a vault
+//
+// Returns the tree of folders containing indexed notes, including the folders between
+// them. Folder paths are the valid values of the folders field of ask and search requests.
+//
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - in: path
+//     name: name
+//     type: string
+//     required: true
+//     description: Vault name (e.g. personal)
+//
+// responses:
+//
+//	'200':
+//	  description: Folder tree retrieved successfully
+//	  schema:
+//	    "$ref": "#/definitions/FolderTreeResponse"
+//	'404':
+//	  description: Unknown vault, or a vault the user may not read
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+//	'500':
+//	  description: Internal server error
+//	  schema:
+//	    "$ref": "#/definitions/ErrorResponse"
+func (h *VaultsHandler) ServeFolders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := contextutil.LoggerFromContext(ctx)
+
+	vaultName := chi.URLParam(r, "name")
+	vaultRecord, err := h.vaultManager.VaultByName(vaultName)
+	if err != nil || !contextutil.VaultAllowed(ctx, vaultRecord.ID) {
+		h.writeError(w, http.StatusNotFound, "Vault not found")
+		return
+	}
+
+	folders, err := h.noteRepo.ListUniqueFolders(ctx, []int{vaultRecord.ID})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list folders", "vault", vaultName, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to list folders")
+		return
+	}
+
+	// ListUniqueFolders returns "<vaultID>/folder" paths; the tree uses paths within the vault
+	prefix := strconv.Itoa(vaultRecord.ID) + "/"
+	paths := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		paths = append(paths, strings.TrimPrefix(folder, prefix))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(FolderTreeResponse{
+		Vault:   vaultName,
+		Folders: folderTree(paths),
+	})
+}
+
+// folderTree builds the folder tree of folder paths relative to a vault root. Missing
+// ancestors are added, and the vault root ("") is left out since it is not a folder.
+func folderTree(paths []string) []FolderNode {
+	type treeNode struct {
+		name     string
+		children map[string]*treeNode
+	}
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for _, folderPath := range paths {
+		node := root
+		for _, part := range strings.Split(folderPath, "/") {
+			if part == "" {
+				continue
+			}
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: make(map[string]*treeNode)}
+				node.children[part] = child
+			}
+			node = child
+		}
+	}
+
+	var build func(node *treeNode, parent string) []FolderNode
+	build = func(node *treeNode, parent string) []FolderNode {
+		if len(node.children) == 0 {
+			return nil
+		}
+		nodes := make([]FolderNode, 0, len(node.children))
+		for _, child := range node.children {
+			childPath := child.name
+			if parent != "" {
+				childPath = parent + "/" + child.name
+			}
+			nodes = append(nodes, FolderNode{
+				Name:     child.name,
+				Path:     childPath,
+				Children: build(child, childPath),
+			})
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+		return nodes
+	}
+
+	folders := build(root, "")
+	if folders == nil {
+		folders = []FolderNode{}
+	}
+	return folders
+}
+
+// writeError writes an error response.
+func (h *VaultsHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error: message,
+	})
+}
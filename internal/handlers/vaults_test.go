@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vault"
+
+	"go.uber.org/mock/gomock"
+)
+
+func newTestVaultsHandler(t *testing.T, ctrl *gomock.Controller) (*VaultsHandler, *storage_mocks.MockNoteStore) {
+	t.Helper()
+	vaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	vaultRepo.EXPECT().GetOrCreateByName(gomock.Any(), "personal", "/vaults/personal").Return(storage.VaultRecord{ID: 1, Name: "personal", RootPath: "/vaults/personal"}, nil)
+	vaultRepo.EXPECT().GetOrCreateByName(gomock.Any(), "work", "/vaults/work").Return(storage.VaultRecord{ID: 2, Name: "work", RootPath: "/vaults/work"}, nil)
+	manager, err := vault.NewManager(context.Background(), vaultRepo, []vault.Config{
+		{Name: "personal", Path: "/vaults/personal"},
+		{Name: "work", Path: "/vaults/work"},
+	}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	noteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	return NewVaultsHandler(manager, noteRepo), noteRepo
+}
+
+func TestVaultsHandler_ServeHTTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	handler, noteRepo := newTestVaultsHandler(t, ctrl)
+
+	noteRepo.EXPECT().CountByVault(gomock.Any()).Return(map[int]storage.VaultCounts{1: {NoteCount: 12, ChunkCount: 40}}, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp VaultsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := []VaultResponse{
+		{Name: "personal", Path: "/vaults/personal", NoteCount: 12, ChunkCount: 40},
+		{Name: "work", Path: "/vaults/work"},
+	}
+	if !reflect.DeepEqual(resp.Vaults, want) {
+		t.Errorf("vaults = %+v, want %+v", resp.Vaults, want)
+	}
+
+	// A user's API token lists only that user's vaults
+	noteRepo.EXPECT().CountByVault(gomock.Any()).Return(map[int]storage.VaultCounts{1: {NoteCount: 12, ChunkCount: 40}}, nil)
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vaults", nil)
+	handler.ServeHTTP(w, req.WithContext(contextutil.WithAllowedVaults(req.Context(), []int{2})))
+	resp = VaultsResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Vaults) != 1 || resp.Vaults[0].Name != "work" {
+		t.Errorf("vaults for a work-only user = %+v, want only work", resp.Vaults)
+	}
+
+	noteRepo.EXPECT().CountByVault(gomock.Any()).Return(nil, errors.New("database is locked"))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("ServeHTTP() status = %d after a count failure, want 500", w.Code)
+	}
+}
+
+func TestVaultsHandler_ServeFolders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	handler, noteRepo := newTestVaultsHandler(t, ctrl)
+	router := chi.NewRouter()
+	router.Get("/api/v1/vaults/{name}/folders", handler.ServeFolders)
+
+	noteRepo.EXPECT().ListUniqueFolders(gomock.Any(), []int{1}).Return([]string{"1/", "1/projects", "1/projects/go", "1/daily"}, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/personal/folders", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeFolders() status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var resp FolderTreeResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := []FolderNode{
+		{Name: "daily", Path: "daily"},
+		{Name: "projects", Path: "projects", Children: []FolderNode{{Name: "go", Path: "projects/go"}}},
+	}
+	if resp.Vault != "personal" || !reflect.DeepEqual(resp.Folders, want) {
+		t.Errorf("folder tree = %+v, want personal with %+v", resp, want)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/archive/folders", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ServeFolders() status = %d for an unknown vault, want 404", w.Code)
+	}
+
+	// Vaults a user may not read are reported as unknown
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vaults/personal/folders", nil)
+	router.ServeHTTP(w, req.WithContext(contextutil.WithAllowedVaults(req.Context(), []int{2})))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("ServeFolders() status = %d for a vault outside the user's vaults, want 404", w.Code)
+	}
+}
+
+func TestFolderTree(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  []FolderNode
+	}{
+		{name: "root only", paths: []string{""}, want: []FolderNode{}},
+		{
+			name:  "missing ancestors are added",
+			paths: []string{"a/b/c", "a/d"},
+			want: []FolderNode{{Name: "a", Path: "a", Children: []FolderNode{
+				{Name: "b", Path: "a/b", Children: []FolderNode{{Name: "c", Path: "a/b/c"}}},
+				{Name: "d", Path: "a/d"},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := folderTree(tt.paths); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("folderTree(%v) = %+v, want %+v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,28 @@ package http
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
 	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/handlers"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/tracing"
+	"helloworld-ai/internal/vault"
 )
 
 // LoggerMiddleware adds a structured logger to the request context.
@@ -50,6 +67,35 @@ func RequestLogger(next http.Handler) http.Handler {
 	})
 }
 
+// Tracing starts a server span for each request, continuing a trace propagated by the
+// caller. The span is named after the matched route pattern once routing has run.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		ww := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// chi fills the route context while routing, so the pattern is known only afterwards
+		if pattern := chi.RouteContext(r.Context()).RoutePattern(); pattern != "" {
+			span.SetName(r.Method + " " + pattern)
+			span.SetAttributes(attribute.String("http.route", pattern))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", ww.statusCode))
+		if ww.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(ww.statusCode))
+		}
+	})
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code.
 type responseWriter struct {
 	http.ResponseWriter
@@ -61,7 +107,39 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// CORS adds CORS headers to allow cross-origin requests.
+// Flush forwards to the underlying writer so streamed responses are not buffered.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// compressibleContentTypes are the response types worth compressing. text/event-stream is
+// deliberately absent so server-sent events reach the client as soon as they are flushed.
+var compressibleContentTypes = []string{
+	"application/json",
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/markdown",
+	"text/javascript",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// Compress gzip/deflate-compresses responses for clients that send Accept-Encoding.
+// Debug ask responses carry the full text of every retrieved chunk and shrink considerably.
+func Compress(next http.Handler) http.Handler {
+	return middleware.Compress(5, compressibleContentTypes...)(next)
+}
+
+// CORS adds CORS headers to allow cross-origin requests. Any origin is reflected, so only
+// GET and POST are allowed: browsers must not send cross-origin requests that write notes.
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
@@ -71,7 +149,7 @@ func CORS(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight requests
@@ -83,3 +161,181 @@ func CORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminAuth requires "Authorization: Bearer <token>" on admin endpoints.
+// With an empty token the endpoints are disabled and every request is rejected.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := contextutil.LoggerFromContext(ctx)
+
+			if token == "" {
+				logger.WarnContext(ctx, "admin endpoint called but ADMIN_TOKEN is not set")
+				writeAuthError(w, http.StatusForbidden, "Admin endpoints are disabled (ADMIN_TOKEN is not set)")
+				return
+			}
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				logger.WarnContext(ctx, "admin request rejected: missing or invalid token")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeAuthError(w, http.StatusUnauthorized, "Missing or invalid admin token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserAuth identifies the caller by "Authorization: Bearer <token>" or "X-API-Key: <token>"
+// and limits the request to the vaults of the user holding that token
+// (contextutil.WithAllowedVaults). The admin token may read every vault. Requests without a
+// token may read every vault too, unless required is set (AUTH_REQUIRED), in which case they
+// are rejected. Unknown tokens are always rejected.
+func UserAuth(users storage.UserStore, adminToken string, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			logger := contextutil.LoggerFromContext(ctx)
+
+			token := requestToken(r)
+			if token == "" {
+				if required {
+					logger.WarnContext(ctx, "request rejected: missing API token")
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					writeAuthError(w, http.StatusUnauthorized, "Missing API token")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var user *storage.UserRecord
+			err := storage.ErrNotFound
+			if users != nil {
+				user, err = users.GetByTokenHash(ctx, storage.HashToken(token))
+			}
+			if errors.Is(err, storage.ErrNotFound) {
+				logger.WarnContext(ctx, "request rejected: invalid API token")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeAuthError(w, http.StatusUnauthorized, "Invalid API token")
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to look up API token", "error", err)
+				writeAuthError(w, http.StatusInternalServerError, "Failed to check API token")
+				return
+			}
+
+			logger = logger.With("user", user.Name)
+			ctx = context.WithValue(ctx, contextutil.LoggerKey(), logger)
+			ctx = contextutil.WithAllowedVaults(ctx, user.VaultIDs)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TokenRequired rejects requests without an API token on endpoints that change vault files,
+// even when AUTH_REQUIRED is off. It runs after UserAuth, which rejects unknown tokens, so
+// only users and the admin get through.
+func TokenRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestToken(r) == "" {
+			ctx := r.Context()
+			contextutil.LoggerFromContext(ctx).WarnContext(ctx, "write request rejected: missing API token", "method", r.Method, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAuthError(w, http.StatusUnauthorized, "Missing API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestToken returns the API token of "Authorization: Bearer <token>" or "X-API-Key: <token>".
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// VaultAccess answers 404 for requests naming, in the URL parameter param, a vault their user
+// may not read, as if the vault did not exist. Requests that may read every vault pass
+// through, leaving unknown vaults to the handler.
+func VaultAccess(vaultManager *vault.Manager, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if _, limited := contextutil.AllowedVaults(ctx); limited {
+				v, err := vaultManager.VaultByName(chi.URLParam(r, param))
+				if err != nil || !contextutil.VaultAllowed(ctx, v.ID) {
+					contextutil.LoggerFromContext(ctx).WarnContext(ctx, "request for a vault outside the user's vaults rejected", "vault", chi.URLParam(r, param))
+					writeAuthError(w, http.StatusNotFound, "Vault not found")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AllVaults rejects requests limited to some vaults from endpoints that read or change
+// every vault at once (indexing, jobs, events, evaluation samples).
+func AllVaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if _, limited := contextutil.AllowedVaults(ctx); limited {
+			contextutil.LoggerFromContext(ctx).WarnContext(ctx, "request needing every vault rejected", "method", r.Method, "path", r.URL.Path)
+			writeAuthError(w, http.StatusForbidden, "This endpoint needs access to every vault")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReplicaReadOnly rejects requests to write endpoints on a read-only replica (API_ROLE=replica),
+// whose database is opened read-only and which never indexes. Writes go to the primary.
+// On the primary (readOnly false) requests pass through unchanged.
+func ReplicaReadOnly(readOnly bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !readOnly {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			contextutil.LoggerFromContext(ctx).WarnContext(ctx, "write request rejected on read-only replica", "method", r.Method, "path", r.URL.Path)
+			writeAuthError(w, http.StatusForbidden, "This instance is a read-only replica; send writes to the primary instance")
+		})
+	}
+}
+
+// MaxRequestBody caps request bodies at maxBytes for endpoints that decode JSON. Requests
+// declaring a larger Content-Length get 413 before the handler runs; larger bodies sent
+// without one fail when the handler reads past the limit. Endpoints taking documents or notes
+// set their own, larger limits and are not wrapped.
+func MaxRequestBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				ctx := r.Context()
+				contextutil.LoggerFromContext(ctx).WarnContext(ctx, "request body too large", "content_length", r.ContentLength, "limit_bytes", maxBytes)
+				writeAuthError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytes))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeAuthError writes an error response in the handlers' JSON error format.
+func writeAuthError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(handlers.ErrorResponse{Error: message})
+}
@@ -1,12 +1,25 @@
 package http
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
 	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
 )
 
 func TestLoggerMiddleware(t *testing.T) {
@@ -37,6 +50,47 @@ func TestLoggerMiddleware(t *testing.T) {
 	}
 }
 
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var handlerSpan trace.SpanContext
+	r := chi.NewRouter()
+	r.Use(Tracing)
+	r.Get("/api/v1/notes/{vault}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notes/personal", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Tracing() ended %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/notes/{vault}" {
+		t.Errorf("span name = %q, want %q", span.Name(), "GET /api/v1/notes/{vault}")
+	}
+	if span.SpanContext().SpanID() != handlerSpan.SpanID() {
+		t.Error("Tracing() should pass the span to the handler in the request context")
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusNotFound {
+		t.Errorf("http.response.status_code = %d, want %d", got, http.StatusNotFound)
+	}
+	if got := attrs["http.route"].AsString(); got != "/api/v1/notes/{vault}" {
+		t.Errorf("http.route = %q, want %q", got, "/api/v1/notes/{vault}")
+	}
+}
+
 func TestRequestLogger(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -182,7 +236,7 @@ func TestCORS_Headers(t *testing.T) {
 	headers := map[string]string{
 		"Access-Control-Allow-Origin":  "http://localhost:3000",
 		"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
-		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization, X-API-Key",
 		"Access-Control-Max-Age":       "3600",
 	}
 
@@ -193,3 +247,336 @@ func TestCORS_Headers(t *testing.T) {
 		}
 	}
 }
+
+func TestResponseWriter_Flush(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+	rw.Flush()
+
+	if !w.Flushed {
+		t.Error("responseWriter.Flush() should flush the underlying writer")
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat(`{"text":"chunk text repeated for compression"}`, 100)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{name: "json gzipped", contentType: "application/json", acceptEncoding: "gzip", wantEncoding: "gzip"},
+		{name: "json deflated", contentType: "application/json", acceptEncoding: "deflate", wantEncoding: "deflate"},
+		{name: "markdown gzipped", contentType: "text/markdown; charset=utf-8", acceptEncoding: "gzip", wantEncoding: "gzip"},
+		{name: "no accept-encoding", contentType: "application/json", acceptEncoding: "", wantEncoding: ""},
+		{name: "event stream bypassed", contentType: "text/event-stream", acceptEncoding: "gzip", wantEncoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = io.WriteString(w, body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			got := w.Body.String()
+			if tt.wantEncoding == "gzip" {
+				reader, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader() error = %v", err)
+				}
+				decoded, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("failed to read gzip body: %v", err)
+				}
+				got = string(decoded)
+			}
+			if tt.wantEncoding == "" || tt.wantEncoding == "gzip" {
+				if got != body {
+					t.Errorf("body mismatch after decoding (len %d, want %d)", len(got), len(body))
+				}
+			}
+		})
+	}
+}
+
+func TestCompress_StreamingFlushes(t *testing.T) {
+	handler := RequestLogger(Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("streaming handler writer does not implement http.Flusher")
+		}
+		flusher.Flush()
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if !w.Flushed {
+		t.Error("event stream should be flushed to the client")
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("event stream Content-Encoding = %q, want none", got)
+	}
+	if got := w.Body.String(); got != "data: first\n\n" {
+		t.Errorf("event stream body = %q", got)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "disabled without token", token: "", authorization: "Bearer anything", wantStatus: http.StatusForbidden},
+		{name: "missing header", token: "s3cret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "s3cret", authorization: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "s3cret", authorization: "Basic s3cret", wantStatus: http.StatusUnauthorized},
+		{name: "valid token", token: "s3cret", authorization: "Bearer s3cret", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := AdminAuth(tt.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("AdminAuth() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// stubUserStore knows one token, "alice-token", for a user limited to vault 2.
+type stubUserStore struct {
+	storage.UserStore
+}
+
+func (stubUserStore) GetByTokenHash(_ context.Context, tokenHash string) (*storage.UserRecord, error) {
+	if tokenHash != storage.HashToken("alice-token") {
+		return nil, storage.ErrNotFound
+	}
+	return &storage.UserRecord{ID: 1, Name: "alice", VaultIDs: []int{2}}, nil
+}
+
+func TestUserAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		required    bool
+		header      string
+		value       string
+		wantStatus  int
+		wantLimited bool
+	}{
+		{name: "no token", wantStatus: http.StatusOK},
+		{name: "no token when required", required: true, wantStatus: http.StatusUnauthorized},
+		{name: "user bearer token", header: "Authorization", value: "Bearer alice-token", wantStatus: http.StatusOK, wantLimited: true},
+		{name: "user API key", required: true, header: "X-API-Key", value: "alice-token", wantStatus: http.StatusOK, wantLimited: true},
+		{name: "admin token", required: true, header: "Authorization", value: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "unknown token", header: "X-API-Key", value: "mallory", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var limited bool
+			handler := UserAuth(stubUserStore{}, "s3cret", tt.required)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var vaultIDs []int
+				vaultIDs, limited = contextutil.AllowedVaults(r.Context())
+				if limited && (len(vaultIDs) != 1 || vaultIDs[0] != 2) {
+					t.Errorf("allowed vaults = %v, want [2]", vaultIDs)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ask", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || limited != tt.wantLimited {
+				t.Errorf("UserAuth() status = %d, limited = %v; want %d, %v", w.Code, limited, tt.wantStatus, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	handler := TokenRequired(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{wantStatus: http.StatusUnauthorized},
+		{header: "Authorization", value: "Bearer alice-token", wantStatus: http.StatusOK},
+		{header: "X-API-Key", value: "alice-token", wantStatus: http.StatusOK},
+		{header: "Authorization", value: "Basic YWxpY2U6", wantStatus: http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/vaults/personal/notes/a.md", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("TokenRequired() with %s %q status = %d, want %d", tt.header, tt.value, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestVaultAccess(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	manager, err := vault.NewManager(context.Background(), storage.NewVaultRepo(db), []vault.Config{
+		{Name: "personal", Path: filepath.Join(tmpDir, "personal")},
+		{Name: "work", Path: filepath.Join(tmpDir, "work")},
+	}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	work, _ := manager.VaultByName("work")
+
+	r := chi.NewRouter()
+	r.With(VaultAccess(manager, "name")).Get("/api/v1/vaults/{name}/chunks/diff", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range []struct {
+		vault      string
+		limited    bool
+		wantStatus int
+	}{
+		{vault: "personal", wantStatus: http.StatusOK},
+		{vault: "work", limited: true, wantStatus: http.StatusOK},
+		{vault: "personal", limited: true, wantStatus: http.StatusNotFound},
+		{vault: "archive", limited: true, wantStatus: http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vaults/"+tt.vault+"/chunks/diff", nil)
+		if tt.limited {
+			req = req.WithContext(contextutil.WithAllowedVaults(req.Context(), []int{work.ID}))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("VaultAccess() %s (limited %v) status = %d, want %d", tt.vault, tt.limited, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestAllVaults(t *testing.T) {
+	handler := AllVaults(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("AllVaults() status = %d without a user, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req.WithContext(contextutil.WithAllowedVaults(req.Context(), []int{1, 2})))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("AllVaults() status = %d for a user, want 403", w.Code)
+	}
+}
+
+func TestReplicaReadOnly(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range []struct {
+		readOnly   bool
+		wantStatus int
+	}{
+		{readOnly: false, wantStatus: http.StatusOK},
+		{readOnly: true, wantStatus: http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/index/reindex", nil)
+		w := httptest.NewRecorder()
+		ReplicaReadOnly(tt.readOnly)(next).ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("ReplicaReadOnly(%v) status = %d, want %d", tt.readOnly, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+func TestMaxRequestBody(t *testing.T) {
+	handler := MaxRequestBody(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "within limit", body: `{"type":"x"}`, wantStatus: http.StatusOK},
+		{name: "declared length over limit", body: strings.Repeat("a", 17), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "undeclared length over limit", body: strings.Repeat("a", 17), chunked: true, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("MaxRequestBody() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), `"error"`) {
+				t.Errorf("MaxRequestBody() body = %q, want a JSON error", w.Body.String())
+			}
+		})
+	}
+}
@@ -10,10 +10,18 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 
 	"helloworld-ai/internal/assets"
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/digest"
+	"helloworld-ai/internal/features"
 	"helloworld-ai/internal/handlers"
 	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/jobs"
 	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/logging"
+	"helloworld-ai/internal/monitor"
+	"helloworld-ai/internal/pii"
 	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/snapshot"
 	"helloworld-ai/internal/storage"
 	"helloworld-ai/internal/vault"
 	"helloworld-ai/internal/vectorstore"
@@ -23,12 +31,40 @@ import (
 type Deps struct {
 	RAGEngine         rag.Engine
 	VaultRepo         storage.VaultStore
+	ChunkRepo         storage.ChunkStore
+	NoteRepo          storage.NoteStore
+	ClickStore        storage.ChunkClickStore
+	EventStore        storage.IndexEventStore
 	IndexerPipeline   *indexer.Pipeline
 	VaultManager      *vault.Manager
 	VectorStore       vectorstore.VectorStore
 	LLMClient         *llm.Client
 	CollectionName    string
 	EmbeddingModelName string
+	StorageMonitor     *monitor.StorageMonitor
+	FeatureFlags       *features.Flags
+	RequestLimits      handlers.RequestLimits
+	LogSettings        *logging.Settings
+	// Caches lists the caches reported and flushed by /api/v1/admin/caches (nil: none).
+	Caches     *cache.Registry
+	// PIIScanner scans indexed notes for personal data at /api/v1/admin/pii.
+	PIIScanner *pii.Scanner
+	// Snapshotter writes full index snapshots at /api/v1/admin/snapshot.
+	Snapshotter *snapshot.Snapshotter
+	// Digest summarizes recently changed notes at /api/v1/digest (nil: unavailable).
+	Digest *digest.Summarizer
+	// Models lists and swaps the loaded models at /api/v1/admin/models (nil: not managed).
+	Models *llm.ModelManager
+	AdminToken         string
+	// Users maps API tokens to the vaults their users may read (nil: only the admin token is known).
+	Users storage.UserStore
+	// AuthRequired rejects API requests without a user or admin token (AUTH_REQUIRED).
+	AuthRequired bool
+	// ReadOnly rejects the endpoints that index or write the database (read-only replicas).
+	ReadOnly bool
+	// JobQueue queues long-running operations (re-indexing, clearing, folder deletes)
+	// for the background worker.
+	JobQueue *jobs.Queue
 }
 
 // NewRouter creates a new HTTP router with the provided dependencies.
@@ -41,25 +77,120 @@ func NewRouter(deps *Deps) http.Handler {
 	// Add custom request logger (skips health checks)
 	r.Use(RequestLogger)
 
+	// Add OpenTelemetry server spans (no-ops unless tracing is enabled)
+	r.Use(Tracing)
+
 	// Add structured logging middleware
 	r.Use(LoggerMiddleware)
 
 	// Add CORS middleware
 	r.Use(CORS)
 
+	// Add response compression (skips text/event-stream)
+	r.Use(Compress)
+
 	// Create handlers
 	healthHandler := handlers.NewHealthHandler(deps.VectorStore, deps.LLMClient, deps.CollectionName)
-	askHandler := handlers.NewAskHandler(deps.RAGEngine, deps.VaultRepo, deps.IndexerPipeline, deps.EmbeddingModelName)
-	indexHandler := handlers.NewIndexHandler(deps.IndexerPipeline)
+	askHandler := handlers.NewAskHandler(deps.RAGEngine, deps.VaultRepo, deps.IndexerPipeline, deps.EmbeddingModelName, deps.RequestLimits)
+	indexHandler := handlers.NewIndexHandler(deps.IndexerPipeline, deps.VaultManager, deps.JobQueue)
+	indexSlowestHandler := handlers.NewIndexSlowestHandler(deps.IndexerPipeline)
+	indexFailuresHandler := handlers.NewIndexFailuresHandler(deps.IndexerPipeline)
+	indexVerifyHandler := handlers.NewIndexVerifyHandler(deps.IndexerPipeline)
+	folderDeleteHandler := handlers.NewFolderDeleteHandler(deps.IndexerPipeline, deps.VaultManager)
+	folderStatsHandler := handlers.NewFolderStatsHandler(deps.IndexerPipeline, deps.VaultManager)
+	folderExclusionsHandler := handlers.NewFolderExclusionsHandler(deps.IndexerPipeline, deps.VaultManager)
+	chunkDiffHandler := handlers.NewChunkDiffHandler(deps.IndexerPipeline, deps.VaultManager)
+	evalSampleHandler := handlers.NewEvalSampleHandler(deps.ChunkRepo, deps.VaultManager)
 	noteHandler := handlers.NewNoteHandler(deps.VaultManager)
+	storageStatsHandler := handlers.NewStorageStatsHandler(deps.StorageMonitor)
+	featuresHandler := handlers.NewFeaturesHandler(deps.FeatureFlags)
+	askDocumentHandler := http.HandlerFunc(askHandler.ServeDocument)
+	logLevelHandler := handlers.NewLogLevelHandler(deps.LogSettings)
+	cachesHandler := handlers.NewCachesHandler(deps.Caches)
+	piiScanHandler := handlers.NewPIIScanHandler(deps.PIIScanner, deps.VaultManager)
+	snapshotHandler := handlers.NewSnapshotHandler(deps.Snapshotter)
+	modelsHandler := handlers.NewModelsHandler(deps.Models, deps.Caches)
+	indexPauseHandler := handlers.NewIndexPauseHandler(deps.IndexerPipeline)
+	referenceClickHandler := handlers.NewReferenceClickHandler(deps.ChunkRepo, deps.NoteRepo, deps.ClickStore)
+	eventsHandler := handlers.NewEventsHandler(deps.EventStore)
+	noteWriteHandler := handlers.NewNoteWriteHandler(deps.IndexerPipeline, deps.VaultManager, deps.RequestLimits)
+	jobsHandler := handlers.NewJobsHandler(deps.JobQueue)
+	vaultsHandler := handlers.NewVaultsHandler(deps.VaultManager, deps.NoteRepo)
+	digestHandler := handlers.NewDigestHandler(deps.Digest)
+	usersHandler := handlers.NewUsersHandler(deps.Users, deps.VaultManager)
+
+	// Replicas serve questions only; indexing and database writes go to the primary
+	readOnly := ReplicaReadOnly(deps.ReadOnly)
+
+	// JSON request bodies are capped; document and note uploads enforce their own limits
+	maxBodyBytes := deps.RequestLimits.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = handlers.DefaultRequestLimits.MaxBodyBytes
+	}
+	jsonBody := MaxRequestBody(maxBodyBytes)
+
+	// API tokens limit their users to their vaults; vault routes and endpoints spanning every
+	// vault enforce the limit. Note writes need a token even without AUTH_REQUIRED
+	userAuth := UserAuth(deps.Users, deps.AdminToken, deps.AuthRequired)
+	vaultAccess := VaultAccess(deps.VaultManager, "name")
 
 	// Register API routes (health check first for monitoring systems)
 	r.Route("/api", func(r chi.Router) {
 		r.Method(http.MethodGet, "/health", healthHandler)
-		r.Method(http.MethodPost, "/index", indexHandler) // Re-index endpoint
-		r.Method(http.MethodGet, "/index/status", indexHandler) // Index status endpoint
+		r.With(userAuth, AllVaults, readOnly).Method(http.MethodPost, "/index", indexHandler) // Re-index endpoint
+		r.With(userAuth, AllVaults).Method(http.MethodGet, "/index/status", indexHandler) // Index status endpoint
 		r.Route("/v1", func(r chi.Router) {
-			r.Method(http.MethodPost, "/ask", askHandler)
+			r.Use(userAuth)
+			r.With(jsonBody).Method(http.MethodPost, "/ask", askHandler)
+			r.Method(http.MethodPost, "/ask/document", askDocumentHandler)   // Questions about a supplied document
+			r.With(jsonBody).Post("/ask/report", askHandler.ServeReport)     // Redacted answer report archive for bug reports
+			r.Get("/search", askHandler.ServeSearch)                         // Ranked chunks without generation
+			r.With(jsonBody).Post("/search", askHandler.ServeSearch)         // Ranked chunks without generation (JSON body)
+			r.Get("/search/{handle}", askHandler.ServeSearchPage)            // Another page or order of a search
+			r.With(jsonBody).Method(http.MethodPost, "/digest", digestHandler) // Summary of the notes changed in a period
+			r.With(AllVaults, readOnly).Method(http.MethodPost, "/index/reindex", indexHandler) // Re-index all vaults or one
+			r.With(AllVaults).Method(http.MethodGet, "/index/status", indexHandler) // Indexing progress
+			r.With(AllVaults, readOnly).Method(http.MethodDelete, "/index", indexHandler) // Clear all vaults or one
+			r.With(AllVaults).Method(http.MethodGet, "/index/slowest", indexSlowestHandler)  // Slow-file indexing report
+			r.With(AllVaults).Method(http.MethodGet, "/index/failures", indexFailuresHandler) // Chunks skipped or failed during indexing
+			r.With(AllVaults).Method(http.MethodGet, "/index/verify", indexVerifyHandler)    // Index checksum verification
+			r.With(AllVaults).Method(http.MethodGet, "/events", eventsHandler)               // Index changes for client cache invalidation
+			r.With(AllVaults, readOnly, jsonBody).Method(http.MethodPost, "/jobs", jobsHandler) // Queue a background job
+			r.With(AllVaults).Method(http.MethodGet, "/jobs/{id}", jobsHandler)              // Background job state and progress
+			r.Method(http.MethodGet, "/vaults", vaultsHandler)               // Vaults with note and chunk counts
+			r.Get("/vaults/{name}/folders", vaultsHandler.ServeFolders)      // Folder tree of a vault
+			r.With(vaultAccess, readOnly).Method(http.MethodDelete, "/vaults/{name}/folders", folderDeleteHandler) // Remove a folder prefix from the index
+			r.With(vaultAccess).Method(http.MethodGet, "/vaults/{name}/folders/excluded", folderExclusionsHandler)            // Folders index runs skip
+			r.With(vaultAccess, readOnly).Method(http.MethodDelete, "/vaults/{name}/folders/excluded", folderExclusionsHandler) // Include an excluded folder again
+			r.With(vaultAccess).Method(http.MethodGet, "/vaults/{name}/folders/{prefix}/stats", folderStatsHandler) // Chunk stats for retrieval tuning
+			r.With(vaultAccess).Method(http.MethodGet, "/vaults/{name}/chunks/diff", chunkDiffHandler)              // Chunk changes since the previous index
+			r.With(AllVaults).Method(http.MethodGet, "/eval/sample", evalSampleHandler)                           // Chunk sample for labeling eval sets
+			r.With(TokenRequired, vaultAccess, readOnly).Method(http.MethodPut, "/vaults/{name}/notes/*", noteWriteHandler)    // Create or update a note and index it
+			r.With(TokenRequired, vaultAccess, readOnly).Method(http.MethodDelete, "/vaults/{name}/notes/*", noteWriteHandler) // Delete a note and remove it from the index
+			r.With(readOnly).Method(http.MethodPost, "/references/{chunk_id}/click", referenceClickHandler) // Reference click-through tracking
+			r.With(AllVaults).Get("/references/clicks", referenceClickHandler.ServeStats)                      // Most clicked references
+			r.Method(http.MethodGet, "/stats/storage", storageStatsHandler)  // Storage usage and soft limits
+			r.Method(http.MethodGet, "/features", featuresHandler)           // Feature flag states
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(AdminAuth(deps.AdminToken))
+				r.Method(http.MethodGet, "/loglevel", logLevelHandler) // Current log level and format
+				r.With(jsonBody).Method(http.MethodPut, "/loglevel", logLevelHandler) // Change log level and format at runtime
+				r.With(jsonBody).Method(http.MethodPut, "/features/{name}", featuresHandler) // Runtime flag override
+				r.Method(http.MethodDelete, "/features/{name}", featuresHandler) // Remove runtime override
+				r.Method(http.MethodGet, "/index/pause", indexPauseHandler)    // Automatic indexing pause state
+				r.With(readOnly, jsonBody).Method(http.MethodPut, "/index/pause", indexPauseHandler) // Pause automatic indexing during bulk edits
+				r.With(readOnly).Method(http.MethodDelete, "/index/pause", indexPauseHandler) // Resume automatic indexing
+				r.Method(http.MethodGet, "/caches", cachesHandler)            // Cache sizes and hit rates
+				r.Method(http.MethodDelete, "/caches", cachesHandler)         // Flush all caches or one
+				r.Method(http.MethodGet, "/pii", piiScanHandler)              // Personal data found per vault and folder
+				r.Method(http.MethodPost, "/snapshot", snapshotHandler)       // Download a snapshot of the database and vectors
+				r.Method(http.MethodGet, "/models", modelsHandler)            // Loaded models and when they were last used
+				r.With(jsonBody).Method(http.MethodPut, "/models/{role}", modelsHandler) // Swap a model at runtime
+				r.Method(http.MethodGet, "/users", usersHandler)              // API users and their vaults
+				r.With(readOnly, jsonBody).Method(http.MethodPost, "/users", usersHandler) // Create a user and their token
+				r.With(readOnly, jsonBody).Method(http.MethodPut, "/users/{name}", usersHandler) // Change a user's vaults
+				r.With(readOnly).Method(http.MethodDelete, "/users/{name}", usersHandler) // Delete a user
+			})
 		})
 		// Serve Swagger spec at /api/docs/swagger.json
 		r.Route("/docs", func(r chi.Router) {
@@ -80,7 +211,7 @@ func NewRouter(deps *Deps) http.Handler {
 
 	// Serve note files from vaults
 	r.Route("/notes", func(r chi.Router) {
-		r.Get("/{vault}/*", noteHandler.ServeHTTP)
+		r.With(userAuth, VaultAccess(deps.VaultManager, "vault")).Get("/{vault}/*", noteHandler.ServeHTTP)
 	})
 
 	// Serve embedded static assets (index.html, JS, CSS) at /
@@ -7,7 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"helloworld-ai/internal/handlers"
 	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/jobs"
 	"helloworld-ai/internal/rag"
 	"helloworld-ai/internal/storage"
 )
@@ -18,21 +20,58 @@ func (stubRAGEngine) Ask(context.Context, rag.AskRequest) (rag.AskResponse, erro
 	return rag.AskResponse{}, nil
 }
 
+func (stubRAGEngine) AskDocument(context.Context, rag.DocumentRequest) (rag.AskResponse, error) {
+	return rag.AskResponse{}, nil
+}
+
+func (stubRAGEngine) AskStream(context.Context, rag.AskRequest, func(string) error) (rag.AskResponse, error) {
+	return rag.AskResponse{}, nil
+}
+
+func (stubRAGEngine) Search(context.Context, rag.AskRequest) (rag.SearchResponse, error) {
+	return rag.SearchResponse{}, nil
+}
+
 type stubVaultStore struct{}
 
 func (stubVaultStore) GetOrCreateByName(context.Context, string, string) (storage.VaultRecord, error) {
 	return storage.VaultRecord{}, nil
 }
 
+func (stubVaultStore) GetByName(context.Context, string) (storage.VaultRecord, error) {
+	return storage.VaultRecord{}, storage.ErrNotFound
+}
+
 func (stubVaultStore) ListAll(context.Context) ([]storage.VaultRecord, error) {
 	return []storage.VaultRecord{}, nil
 }
 
+type stubJobStore struct{}
+
+func (stubJobStore) Create(context.Context, *storage.JobRecord) error { return nil }
+
+func (stubJobStore) Get(context.Context, string) (*storage.JobRecord, error) {
+	return nil, storage.ErrNotFound
+}
+
+func (stubJobStore) ListActive(context.Context) ([]storage.JobRecord, error) { return nil, nil }
+
+func (stubJobStore) ClaimNext(context.Context) (*storage.JobRecord, error) {
+	return nil, storage.ErrNotFound
+}
+
+func (stubJobStore) UpdateProgress(context.Context, string, float64) error { return nil }
+
+func (stubJobStore) Finish(context.Context, string, string) error { return nil }
+
+func (stubJobStore) FailRunning(context.Context, string) (int64, error) { return 0, nil }
+
 func newTestDeps() *Deps {
 	return &Deps{
 		RAGEngine:       stubRAGEngine{},
 		VaultRepo:       stubVaultStore{},
 		IndexerPipeline: &indexer.Pipeline{},
+		JobQueue:        jobs.NewQueue(stubJobStore{}),
 	}
 }
 
@@ -65,6 +104,72 @@ func TestRouter_Routes(t *testing.T) {
 			path:       "/api/v1/ask",
 			wantStatus: http.StatusMethodNotAllowed,
 		},
+		{
+			name:       "GET /api/v1/search requires a question",
+			method:     http.MethodGet,
+			path:       "/api/v1/search",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/index/slowest rejects invalid limit",
+			method:     http.MethodGet,
+			path:       "/api/v1/index/slowest?limit=abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/index/failures rejects invalid limit",
+			method:     http.MethodGet,
+			path:       "/api/v1/index/failures?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/events rejects a negative since",
+			method:     http.MethodGet,
+			path:       "/api/v1/events?since=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/eval/sample rejects an unknown strategy",
+			method:     http.MethodGet,
+			path:       "/api/v1/eval/sample?strategy=weighted",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "POST /api/v1/ask/document requires a document",
+			method:     http.MethodPost,
+			path:       "/api/v1/ask/document",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/jobs/{id} returns 404 for an unknown job",
+			method:     http.MethodGet,
+			path:       "/api/v1/jobs/missing",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "POST /api/v1/jobs requires a body",
+			method:     http.MethodPost,
+			path:       "/api/v1/jobs",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "GET /api/v1/features lists flags",
+			method:     http.MethodGet,
+			path:       "/api/v1/features",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "PUT /api/v1/admin/features/{name} is disabled without an admin token",
+			method:     http.MethodPut,
+			path:       "/api/v1/admin/features/reranker",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "PUT /api/v1/admin/loglevel is disabled without an admin token",
+			method:     http.MethodPut,
+			path:       "/api/v1/admin/loglevel",
+			wantStatus: http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,6 +186,132 @@ func TestRouter_Routes(t *testing.T) {
 	}
 }
 
+func TestRouter_ReadOnlyReplica(t *testing.T) {
+	deps := newTestDeps()
+	deps.ReadOnly = true
+	deps.AdminToken = "s3cret"
+	router := NewRouter(deps)
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{method: http.MethodPost, path: "/api/v1/index/reindex", wantStatus: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/api/v1/index", wantStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/index", wantStatus: http.StatusForbidden},
+		{method: http.MethodDelete, path: "/api/v1/vaults/personal/folders", wantStatus: http.StatusForbidden},
+		{method: http.MethodPut, path: "/api/v1/vaults/personal/notes/a.md", wantStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/v1/references/chunk-1/click", wantStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/v1/jobs", wantStatus: http.StatusForbidden},
+		// Questions are still answered
+		{method: http.MethodPost, path: "/api/v1/ask", wantStatus: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/api/v1/features", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			// Note writes need a token before the replica check
+			req.Header.Set("Authorization", "Bearer s3cret")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Router %s %s status = %v, want %v", tt.method, tt.path, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouter_UserAuth(t *testing.T) {
+	deps := newTestDeps()
+	deps.Users = stubUserStore{}
+	deps.AdminToken = "s3cret"
+	deps.AuthRequired = true
+	router := NewRouter(deps)
+
+	tests := []struct {
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{method: http.MethodGet, path: "/api/v1/features", wantStatus: http.StatusUnauthorized},
+		{method: http.MethodPost, path: "/api/index", wantStatus: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/v1/features", token: "alice-token", wantStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/api/v1/ask", token: "alice-token", wantStatus: http.StatusBadRequest},
+		// Endpoints spanning every vault are closed to users limited to some
+		{method: http.MethodGet, path: "/api/v1/events", token: "alice-token", wantStatus: http.StatusForbidden},
+		{method: http.MethodPost, path: "/api/v1/index/reindex", token: "alice-token", wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/v1/index/status", token: "alice-token", wantStatus: http.StatusForbidden},
+		{method: http.MethodGet, path: "/api/index/status", token: "alice-token", wantStatus: http.StatusForbidden},
+		// User tokens are not admin tokens
+		{method: http.MethodGet, path: "/api/v1/admin/users", token: "alice-token", wantStatus: http.StatusUnauthorized},
+		{method: http.MethodPut, path: "/api/v1/admin/features/reranker", token: "alice-token", wantStatus: http.StatusUnauthorized},
+		{method: http.MethodPut, path: "/api/v1/admin/features/reranker", token: "s3cret", wantStatus: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/api/v1/features", token: "s3cret", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" "+tt.token, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Router %s %s status = %v, want %v", tt.method, tt.path, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRouter_NoteWritesNeedToken(t *testing.T) {
+	deps := newTestDeps()
+	deps.Users = stubUserStore{}
+	deps.AdminToken = "s3cret"
+	router := NewRouter(deps)
+
+	tests := []struct {
+		method     string
+		token      string
+		wantStatus int
+	}{
+		// AUTH_REQUIRED is off, but anonymous callers still may not change vault files
+		{method: http.MethodPut, wantStatus: http.StatusUnauthorized},
+		{method: http.MethodDelete, wantStatus: http.StatusUnauthorized},
+		{method: http.MethodPut, token: "mallory", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.token, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/vaults/personal/notes/a.md", strings.NewReader("# A"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Router %s note with token %q status = %v, want %v", tt.method, tt.token, w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// Other endpoints stay open to anonymous callers
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/features", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Router GET /api/v1/features status = %v, want %v", w.Code, http.StatusOK)
+	}
+}
+
 func TestRouter_RootServesHTML(t *testing.T) {
 	router := NewRouter(newTestDeps())
 
@@ -119,3 +350,28 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestRouter_MaxRequestBody(t *testing.T) {
+	deps := newTestDeps()
+	deps.RequestLimits = handlers.RequestLimits{MaxBodyBytes: 64}
+	deps.AdminToken = "s3cret"
+	router := NewRouter(deps)
+
+	oversized := `{"type":"reindex","params":{"vault":"` + strings.Repeat("a", 64) + `"}}`
+	for _, tt := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/v1/jobs"},
+		{http.MethodPut, "/api/v1/admin/features/reranker"},
+		{http.MethodPost, "/api/v1/search"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(oversized))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Router %s %s status = %d, want 413", tt.method, tt.path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/handlers"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/jobs"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/logging"
+	"helloworld-ai/internal/monitor"
+	"helloworld-ai/internal/rag"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// TestSystem_TestMode runs the full HTTP API end-to-end against the test-mode fakes
+// (in-process LLM and in-memory vector store), the way cmd/api wires them with MODE=test.
+func TestSystem_TestMode(t *testing.T) {
+	ctx := context.Background()
+	const vectorSize = 64
+	const collection = "notes"
+	const noteCollection = "notes_notes"
+
+	personalPath := t.TempDir()
+	workPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(personalPath, "projects"), 0755); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	note := "# Garden\n\nThe tomatoes are planted along the south fence and watered every morning. #gardening\n"
+	if err := os.WriteFile(filepath.Join(personalPath, "projects", "garden.md"), []byte(note), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultRepo := storage.NewVaultRepo(db)
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+
+	vaultManager, err := vault.NewManager(ctx, vaultRepo, []vault.Config{{Name: "personal", Path: personalPath}, {Name: "work", Path: workPath}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("vault.NewManager() error = %v", err)
+	}
+
+	fakeLLM := llm.NewFakeServer(vectorSize)
+	defer fakeLLM.Close()
+
+	vectorStore := vectorstore.NewMemoryStore()
+	for _, name := range []string{collection, noteCollection} {
+		if err := vectorStore.EnsureCollection(ctx, name, vectorSize); err != nil {
+			t.Fatalf("EnsureCollection() error = %v", err)
+		}
+	}
+
+	embedder := llm.NewEmbeddingsClient(fakeLLM.URL, "dummy-key", "fake-embedding", vectorSize)
+	llmClient := llm.NewClient(fakeLLM.URL, "dummy-key", "fake-chat")
+
+	pipeline := indexer.NewPipeline(vaultManager, noteRepo, chunkRepo, storage.NewIndexTimingRepo(db), storage.NewIndexChecksumRepo(db), embedder, vectorStore, collection, "", noteCollection, storage.NewShadowIndex(db, dbPath+".rebuild"))
+	eventRepo := storage.NewIndexEventRepo(db)
+	pipeline.SetEventStore(eventRepo)
+	pipeline.SetExcludedFolderStore(storage.NewExcludedFolderRepo(db))
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	featureFlags, err := features.New(nil)
+	if err != nil {
+		t.Fatalf("features.New() error = %v", err)
+	}
+
+	logSettings, err := logging.NewSettings(slog.LevelInfo, logging.FormatText)
+	if err != nil {
+		t.Fatalf("failed to create log settings: %v", err)
+	}
+
+	jobQueue := jobs.NewQueue(storage.NewJobRepo(db))
+	jobs.RegisterIndexJobs(jobQueue, pipeline, vaultManager)
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		jobQueue.Run(workerCtx)
+	}()
+	defer func() {
+		stopWorker()
+		<-workerDone
+	}()
+
+	router := NewRouter(&Deps{
+		RAGEngine:       rag.NewEngine(embedder, vectorStore, collection, "", chunkRepo, vaultRepo, noteRepo, llmClient, "", rag.DefaultFolderSelectionOptions, rag.NotePrefilterOptions{Collection: noteCollection, TopM: 5}, featureFlags, rag.DefaultQuestionCacheOptions, rag.GenerationOptions{Model: "Qwen2.5-3B-Instruct-Q4_K_M", StructuredCitations: true}),
+		VaultRepo:       vaultRepo,
+		ChunkRepo:       chunkRepo,
+		ClickStore:      storage.NewChunkClickRepo(db),
+		EventStore:      eventRepo,
+		IndexerPipeline: pipeline,
+		VaultManager:    vaultManager,
+		VectorStore:     vectorStore,
+		LLMClient:       llmClient,
+		CollectionName:  collection,
+		StorageMonitor:  monitor.NewStorageMonitor(dbPath, vectorStore, []string{collection}, monitor.StorageLimits{SQLiteBytes: 1}, ""),
+		FeatureFlags:    featureFlags,
+		LogSettings:     logSettings,
+		AdminToken:      "admin-secret",
+		JobQueue:        jobQueue,
+	})
+
+	// Health reports the in-memory collection
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/health status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	// The index events report the indexed note, then nothing new, and a reset for an unknown position
+	var events handlers.IndexEventsResponse
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?since=0", nil))
+	if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode index events: %v", err)
+	}
+	if len(events.Events) != 2 || events.Events[0].Type != storage.IndexEventNoteAdded || events.Events[0].RelPath != "projects/garden.md" ||
+		events.Events[1].Type != storage.IndexEventReindexCompleted || events.NextSince != 2 || events.HasMore || events.Reset {
+		t.Errorf("GET /api/v1/events?since=0 = %+v, want the added note and the completed pass", events)
+	}
+	for since, wantReset := range map[string]bool{"2": false, "10": true} {
+		events = handlers.IndexEventsResponse{}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?since="+since, nil))
+		if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+			t.Fatalf("failed to decode index events: %v", err)
+		}
+		if len(events.Events) != 0 || events.Reset != wantReset || events.NextSince != 2 {
+			t.Errorf("GET /api/v1/events?since=%s = %+v, want no events, reset %v, next_since 2", since, events, wantReset)
+		}
+	}
+
+	// Ask returns the canned answer citing the indexed note (found through the note prefilter)
+	body := []byte(`{"question":"Where are the tomatoes planted?"}`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/v1/ask status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	var resp handlers.AskResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(resp.Answer, llm.FakeAnswerPrefix) {
+		t.Errorf("answer = %q, want prefix %q", resp.Answer, llm.FakeAnswerPrefix)
+	}
+	if len(resp.References) == 0 || resp.References[0].RelPath != "projects/garden.md" {
+		t.Errorf("references = %+v, want projects/garden.md", resp.References)
+	} else if ref := resp.References[0]; ref.Position < 1 || ref.Position > ref.TotalChunks {
+		t.Errorf("reference position = %d of %d, want a position within the note", ref.Position, ref.TotalChunks)
+	}
+	if resp.Meta == nil || resp.Meta.Model != "Qwen2.5-3B-Instruct-Q4_K_M" || resp.Meta.Quantization != "Q4_K_M" || resp.Meta.PromptVersion == "" || resp.Meta.RetrievalConfigHash == "" {
+		t.Errorf("meta = %+v, want model, quantization, prompt version, and retrieval config hash", resp.Meta)
+	}
+
+	// Opening a reference counts as a click on its chunk
+	if len(resp.References) > 0 {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/references/"+resp.References[0].ChunkID+"/click", nil))
+		if w.Code != http.StatusNoContent {
+			t.Errorf("POST /api/v1/references/{chunk_id}/click status = %d, want 204: %s", w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/references/missing/click", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST /api/v1/references/missing/click status = %d, want 404", w.Code)
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/references/clicks", nil))
+		var clicks handlers.ReferenceClickStatsResponse
+		if err := json.NewDecoder(w.Body).Decode(&clicks); err != nil {
+			t.Fatalf("failed to decode reference clicks: %v", err)
+		}
+		if len(clicks.Chunks) != 1 || clicks.Chunks[0].ChunkID != resp.References[0].ChunkID || clicks.Chunks[0].Clicks != 1 {
+			t.Errorf("reference clicks = %+v, want one click on the cited chunk", clicks)
+		}
+	}
+
+	// Streamed answers arrive as token events followed by the full response, uncompressed
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ask?stream=true", bytes.NewReader(body))
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("POST /api/v1/ask?stream=true status = %d, headers = %v, want an uncompressed event stream", w.Code, w.Header())
+	}
+	var streamed strings.Builder
+	var tokenEvents int
+	var streamResp handlers.AskResponse
+	for _, event := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+		name, data, _ := strings.Cut(event, "\ndata: ")
+		switch name {
+		case "event: token":
+			var token handlers.StreamToken
+			if err := json.Unmarshal([]byte(data), &token); err != nil {
+				t.Fatalf("failed to decode token event: %v", err)
+			}
+			streamed.WriteString(token.Text)
+			tokenEvents++
+		case "event: done":
+			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
+				t.Fatalf("failed to decode done event: %v", err)
+			}
+		default:
+			t.Errorf("unexpected event %q", event)
+		}
+	}
+	if tokenEvents < 2 || streamed.String() != streamResp.Answer || streamResp.Answer != resp.Answer {
+		t.Errorf("streamed %d tokens %q, done answer %q, want the answer %q in several tokens", tokenEvents, streamed.String(), streamResp.Answer, resp.Answer)
+	}
+	if len(streamResp.References) == 0 || streamResp.References[0].RelPath != "projects/garden.md" {
+		t.Errorf("streamed references = %+v, want projects/garden.md", streamResp.References)
+	}
+
+	// Inline operators scope the question to tagged notes
+	for _, tt := range []struct {
+		question      string
+		wantAbstained bool
+	}{
+		{question: "vault:personal tag:#gardening Where are the tomatoes planted?"},
+		{question: "tag:#cooking Where are the tomatoes planted?", wantAbstained: true},
+		{question: "after:2000-01-01 before:2001-01-01 Where are the tomatoes planted?", wantAbstained: true},
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask", strings.NewReader(fmt.Sprintf(`{"question":%q}`, tt.question))))
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /api/v1/ask (%s) status = %d, want 200: %s", tt.question, w.Code, w.Body.String())
+		}
+		var operatorResp handlers.AskResponse
+		if err := json.NewDecoder(w.Body).Decode(&operatorResp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if operatorResp.Abstained != tt.wantAbstained {
+			t.Errorf("%s: abstained = %v, want %v", tt.question, operatorResp.Abstained, tt.wantAbstained)
+		}
+		if !tt.wantAbstained && (len(operatorResp.References) == 0 || operatorResp.References[0].RelPath != "projects/garden.md") {
+			t.Errorf("%s: references = %+v, want projects/garden.md", tt.question, operatorResp.References)
+		}
+	}
+
+	// Pasted documents are answered without touching the index
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask/document?question=Summarize", strings.NewReader("Tomatoes need full sun.")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/v1/ask/document status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var documentResp handlers.AskResponse
+	if err := json.NewDecoder(w.Body).Decode(&documentResp); err != nil {
+		t.Fatalf("failed to decode document response: %v", err)
+	}
+	if !strings.HasPrefix(documentResp.Answer, llm.FakeAnswerPrefix) || len(documentResp.References) != 0 {
+		t.Errorf("document response = %+v, want fake answer without references", documentResp)
+	}
+	if documentResp.Meta == nil || documentResp.Meta.PromptVersion == "" || documentResp.Meta.RetrievalConfigHash != "" {
+		t.Errorf("document meta = %+v, want a prompt version and no retrieval config hash", documentResp.Meta)
+	}
+
+	// Search returns the ranked chunks without an answer, by query or body
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/search?q=Where+are+the+tomatoes+planted%3F&vault=personal", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/search", strings.NewReader(`{"question":"Where are the tomatoes planted?"}`)),
+	} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s /api/v1/search status = %d, want 200: %s", req.Method, w.Code, w.Body.String())
+		}
+		var searchResp handlers.SearchResponse
+		if err := json.NewDecoder(w.Body).Decode(&searchResp); err != nil {
+			t.Fatalf("failed to decode search response: %v", err)
+		}
+		if len(searchResp.Results) == 0 || searchResp.Results[0].Reference.RelPath != "projects/garden.md" ||
+			searchResp.Results[0].Rank != 1 || searchResp.Results[0].Text == "" || searchResp.Results[0].ScoreFinal <= 0 {
+			t.Errorf("%s /api/v1/search results = %+v, want projects/garden.md ranked first", req.Method, searchResp.Results)
+		}
+	}
+
+	// Runtime flag overrides need the admin token and take effect on the next request
+	flagRequest := func(target, body, token string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := flagRequest("/api/v1/admin/features/reranker", `{"enabled":false}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("PUT /api/v1/admin/features/reranker without a token status = %d, want 401", code)
+	}
+	if code := flagRequest("/api/v1/admin/features/reranker", `{"enabled":false}`, "admin-secret"); code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/admin/features/reranker status = %d, want 200", code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/ask?debug=true", strings.NewReader(`{"question":"Where are the tomatoes planted?"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/v1/ask (debug) status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var debugResp handlers.AskResponse
+	if err := json.NewDecoder(w.Body).Decode(&debugResp); err != nil {
+		t.Fatalf("failed to decode debug response: %v", err)
+	}
+	if debugResp.Debug == nil || debugResp.Debug.Features[features.Reranker] {
+		t.Errorf("debug features = %+v, want reranker disabled", debugResp.Debug)
+	}
+	// The question was asked before, so its embedding comes from the cache
+	if debugResp.Debug != nil && !debugResp.Debug.QuestionEmbeddingCached {
+		t.Error("debug question_embedding_cached = false for a repeated question, want true")
+	}
+	if code := flagRequest("/api/v1/admin/features/warp_drive", `{"enabled":true}`, "admin-secret"); code != http.StatusNotFound {
+		t.Errorf("PUT /api/v1/admin/features/warp_drive status = %d, want 404", code)
+	}
+
+	// Log level can be raised at runtime with the admin token
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("PUT /api/v1/admin/loglevel without token status = %d, want 401", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", strings.NewReader(`{"level":"debug","format":"json"}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/admin/loglevel status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if logSettings.Level() != slog.LevelDebug || logSettings.Format() != logging.FormatJSON {
+		t.Errorf("log settings = %v/%s, want DEBUG/json", logSettings.Level(), logSettings.Format())
+	}
+
+	// Automatic indexing can be paused for bulk edits and resumed early
+	pauseRequest := func(method, body string) handlers.IndexPauseResponse {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1/admin/index/pause", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s /api/v1/admin/index/pause status = %d, want 200: %s", method, w.Code, w.Body.String())
+		}
+		var resp handlers.IndexPauseResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode pause state: %v", err)
+		}
+		return resp
+	}
+	if paused := pauseRequest(http.MethodPut, `{"timeout_minutes":5}`); !paused.Paused || paused.PausedUntil == "" || paused.RemainingSeconds != 300 || !pipeline.AutoIndexingPaused() {
+		t.Errorf("pause state after PUT = %+v, want paused for 300s", paused)
+	}
+	if state := pauseRequest(http.MethodGet, ""); !state.Paused {
+		t.Errorf("pause state = %+v, want paused", state)
+	}
+	if resumed := pauseRequest(http.MethodDelete, ""); resumed.Paused || pipeline.AutoIndexingPaused() {
+		t.Errorf("pause state after DELETE = %+v, want resumed", resumed)
+	}
+
+	// Notes can be written and deleted over HTTP, re-indexing them each time
+	noteRequest := func(method, target, body string) (int, handlers.NoteWriteResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		router.ServeHTTP(w, req)
+		var resp handlers.NoteWriteResponse
+		if w.Code < 300 {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode note write response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+	if code, resp := noteRequest(http.MethodPut, "/api/v1/vaults/work/notes/inbox/ideas.md", "# Ideas\n\nBuild a cold frame for winter greens.\n"); code != http.StatusCreated || !resp.Created || resp.RelPath != "inbox/ideas.md" {
+		t.Errorf("PUT new note = %d %+v, want 201 created", code, resp)
+	}
+	if code, resp := noteRequest(http.MethodPut, "/api/v1/vaults/work/notes/inbox/ideas.md", "# Ideas\n\nBuild a cold frame and a trellis.\n"); code != http.StatusOK || resp.Created {
+		t.Errorf("PUT existing note = %d %+v, want 200 updated", code, resp)
+	}
+	if data, err := os.ReadFile(filepath.Join(workPath, "inbox", "ideas.md")); err != nil || !strings.Contains(string(data), "trellis") {
+		t.Errorf("written note = %q (err %v), want the updated content", data, err)
+	}
+	work, _ := vaultManager.VaultByName("work")
+	if _, err := noteRepo.GetByVaultAndPath(ctx, work.ID, "inbox/ideas.md"); err != nil {
+		t.Errorf("written note not indexed: %v", err)
+	}
+	for _, target := range []string{"/api/v1/vaults/work/notes/inbox/ideas.txt", "/api/v1/vaults/work/notes/.obsidian/app.md"} {
+		if code, _ := noteRequest(http.MethodPut, target, "x"); code != http.StatusBadRequest {
+			t.Errorf("PUT %s status = %d, want 400", target, code)
+		}
+	}
+	// A symlinked folder must not lead note writes and deletes outside the vault
+	outsidePath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outsidePath, "keep.md"), []byte("# Keep\n"), 0644); err != nil {
+		t.Fatalf("failed to write file outside the vault: %v", err)
+	}
+	if err := os.Symlink(outsidePath, filepath.Join(workPath, "linked")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	for _, target := range []string{"linked/escape.md", "linked/new/escape.md"} {
+		if code, _ := noteRequest(http.MethodPut, "/api/v1/vaults/work/notes/"+target, "x"); code != http.StatusBadRequest {
+			t.Errorf("PUT through symlink %s status = %d, want 400", target, code)
+		}
+	}
+	if code, _ := noteRequest(http.MethodDelete, "/api/v1/vaults/work/notes/linked/keep.md", ""); code != http.StatusBadRequest {
+		t.Errorf("DELETE through symlink status = %d, want 400", code)
+	}
+	if entries, _ := os.ReadDir(outsidePath); len(entries) != 1 || entries[0].Name() != "keep.md" {
+		t.Errorf("files outside the vault = %v, want only keep.md", entries)
+	}
+	if err := os.Remove(filepath.Join(workPath, "linked")); err != nil {
+		t.Fatalf("failed to remove symlink: %v", err)
+	}
+
+	if code, resp := noteRequest(http.MethodDelete, "/api/v1/vaults/work/notes/inbox/ideas.md", ""); code != http.StatusOK || resp.ChunksDeleted == 0 {
+		t.Errorf("DELETE note = %d %+v, want 200 with chunks removed", code, resp)
+	}
+	if _, err := os.Stat(filepath.Join(workPath, "inbox", "ideas.md")); !os.IsNotExist(err) {
+		t.Errorf("deleted note still on disk: %v", err)
+	}
+	if code, _ := noteRequest(http.MethodDelete, "/api/v1/vaults/work/notes/inbox/ideas.md", ""); code != http.StatusNotFound {
+		t.Errorf("DELETE missing note status = %d, want 404", code)
+	}
+
+	// Index verification matches the checksums stored by IndexAll and the note writes
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/index/verify", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/index/verify status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var verify handlers.IndexVerifyResponse
+	if err := json.NewDecoder(w.Body).Decode(&verify); err != nil {
+		t.Fatalf("failed to decode index verification: %v", err)
+	}
+	if !verify.Verified || len(verify.Vaults) != 2 {
+		t.Errorf("index verification = %+v, want verified with 2 vaults", verify)
+	}
+
+	// Storage stats report the indexed vectors and the exceeded SQLite soft limit
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/stats/storage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/stats/storage status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var stats handlers.StorageStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode storage stats: %v", err)
+	}
+	if stats.SQLiteBytes == 0 || len(stats.Collections) != 1 || stats.Collections[0].Points == 0 {
+		t.Errorf("storage stats = %+v, want non-zero SQLite size and indexed points", stats)
+	}
+	if len(stats.Exceeded) != 1 || stats.Exceeded[0] != monitor.ResourceSQLite {
+		t.Errorf("exceeded = %v, want [sqlite]", stats.Exceeded)
+	}
+
+	// Folder chunk stats cover the indexed note and the answers it contributed to
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/personal/folders/projects/stats?days=7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/vaults/personal/folders/projects/stats status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var folderStats handlers.FolderStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&folderStats); err != nil {
+		t.Fatalf("failed to decode folder stats: %v", err)
+	}
+	if folderStats.NoteCount != 1 || folderStats.ChunkCount == 0 || folderStats.AvgChunkTokens <= 0 || folderStats.LastIndexedAt == "" || folderStats.WindowDays != 7 {
+		t.Errorf("folder stats = %+v, want projects/garden.md counted over 7 days", folderStats)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/personal/folders/projects/stats?days=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GET folder stats with days=0 status = %d, want 400", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/shared/folders/projects/stats", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/v1/vaults/shared/folders/projects/stats status = %d, want 404", w.Code)
+	}
+
+	// A folder can be pruned from the index without touching the vault
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/vaults/personal/folders", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("DELETE /api/v1/vaults/personal/folders without prefix status = %d, want 400", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/vaults/shared/folders?prefix=projects", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DELETE /api/v1/vaults/shared/folders status = %d, want 404", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/vaults/personal/folders?prefix=projects", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE /api/v1/vaults/personal/folders status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var deleted handlers.FolderDeleteResponse
+	if err := json.NewDecoder(w.Body).Decode(&deleted); err != nil {
+		t.Fatalf("failed to decode folder deletion: %v", err)
+	}
+	if deleted.NotesDeleted != 1 || deleted.ChunksDeleted == 0 || deleted.NotesFailed != 0 {
+		t.Errorf("folder deletion = %+v, want projects/garden.md removed", deleted)
+	}
+	if info, err := vectorStore.GetCollectionInfo(ctx, collection); err != nil || info.PointsCount != 0 {
+		t.Errorf("collection after folder deletion = %+v (err %v), want no points", info, err)
+	}
+	// The pruned folder stays excluded until it is included again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/vaults/personal/folders/excluded", nil))
+	var exclusions handlers.FolderExclusionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&exclusions); err != nil {
+		t.Fatalf("failed to decode excluded folders: %v", err)
+	}
+	if len(exclusions.Folders) != 1 || exclusions.Folders[0].Prefix != "projects" || exclusions.Folders[0].ExcludedAt == "" {
+		t.Errorf("excluded folders = %+v, want projects", exclusions)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/vaults/personal/folders/excluded?prefix=projects", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE /api/v1/vaults/personal/folders/excluded status = %d, want 204: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/vaults/personal/folders/excluded?prefix=projects", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("DELETE /api/v1/vaults/personal/folders/excluded of an included folder status = %d, want 404", w.Code)
+	}
+
+	// The status reports the startup pass; a vault can be reindexed and cleared on its own
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/index/status", nil))
+	var status handlers.IndexStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode index status: %v", err)
+	}
+	if status.IsIndexing || status.FilesScanned != 1 || status.FilesIndexed != 1 || status.StartedAt == nil || status.FinishedAt == nil {
+		t.Errorf("index status = %+v, want the finished startup pass over 1 file", status)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/index/reindex?vault=shared", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST /api/v1/index/reindex?vault=shared status = %d, want 400", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/index/reindex?vault=personal", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /api/v1/index/reindex status = %d, want 202: %s", w.Code, w.Body.String())
+	}
+	var reindex handlers.IndexResponse
+	if err := json.NewDecoder(w.Body).Decode(&reindex); err != nil || reindex.JobID == "" {
+		t.Fatalf("POST /api/v1/index/reindex response = %+v (err %v), want a job ID", reindex, err)
+	}
+	// The reindex runs as a job that can be polled until it finishes
+	var job handlers.JobResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+reindex.JobID, nil))
+		job = handlers.JobResponse{}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		if job.State == storage.JobSucceeded || job.State == storage.JobFailed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Type != jobs.TypeReindex || job.State != storage.JobSucceeded || job.Progress != 1 || job.FinishedAt == nil {
+		t.Fatalf("reindex job = %+v, want a succeeded reindex", job)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/index/status", nil))
+	status = handlers.IndexStatusResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode index status: %v", err)
+	}
+	if status.IsIndexing || status.Vault != "personal" || status.FilesIndexed != 1 {
+		t.Errorf("index status after reindex = %+v, want personal reindexed", status)
+	}
+	if info, err := vectorStore.GetCollectionInfo(ctx, collection); err != nil || info.PointsCount == 0 {
+		t.Errorf("collection after reindex = %+v (err %v), want the pruned note back", info, err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/index?vault=personal", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE /api/v1/index status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var cleared handlers.IndexClearResponse
+	if err := json.NewDecoder(w.Body).Decode(&cleared); err != nil {
+		t.Fatalf("failed to decode index clear: %v", err)
+	}
+	if cleared.Vault != "personal" || cleared.NotesDeleted != 1 {
+		t.Errorf("index clear = %+v, want 1 personal note removed", cleared)
+	}
+	if info, err := vectorStore.GetCollectionInfo(ctx, collection); err != nil || info.PointsCount != 0 {
+		t.Errorf("collection after clear = %+v (err %v), want no points", info, err)
+	}
+}
@@ -0,0 +1,227 @@
+package indexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// canvasSummaryRunes caps how much of a node's text names it in connection lines.
+const canvasSummaryRunes = 60
+
+// canvasFile is the JSON Canvas document Obsidian writes to .canvas files.
+type canvasFile struct {
+	Nodes []canvasNode `json:"nodes"`
+	Edges []canvasEdge `json:"edges"`
+}
+
+// canvasNode is a card on a canvas: markdown text, an embedded vault file, a web link,
+// or a group enclosing other cards.
+type canvasNode struct {
+	ID     string  `json:"id"`
+	Type   string  `json:"type"`
+	Text   string  `json:"text,omitempty"`
+	File   string  `json:"file,omitempty"`
+	URL    string  `json:"url,omitempty"`
+	Label  string  `json:"label,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// canvasEdge is an arrow between two cards, optionally labelled.
+type canvasEdge struct {
+	ID       string `json:"id"`
+	FromNode string `json:"fromNode"`
+	ToNode   string `json:"toNode"`
+	Label    string `json:"label,omitempty"`
+}
+
+// contains reports whether node n lies entirely inside group g.
+func (g canvasNode) contains(n canvasNode) bool {
+	return n.X >= g.X && n.Y >= g.Y && n.X+n.Width <= g.X+g.Width && n.Y+n.Height <= g.Y+g.Height
+}
+
+// IsCanvas reports whether relPath is an Obsidian canvas file.
+func IsCanvas(relPath string) bool {
+	return strings.EqualFold(path.Ext(relPath), ".canvas")
+}
+
+// ChunkFile chunks a vault file by its type: canvases with ChunkCanvas, notes with ChunkMarkdown.
+func (c *GoldmarkChunker) ChunkFile(content []byte, relPath string) (title string, chunks []Chunk, err error) {
+	if IsCanvas(relPath) {
+		return c.ChunkCanvas(content, path.Base(relPath))
+	}
+	return c.ChunkMarkdown(content, path.Base(relPath))
+}
+
+// ChunkCanvas parses an Obsidian .canvas file and returns its title and chunks.
+// Each text card becomes a chunk under the heading path "# <canvas> > ## <group>"
+// (the group is the smallest one enclosing the card), extended with "### <heading>" when the
+// card starts with a markdown heading. Arrows to and from the card are appended as
+// "Links to:" and "Linked from:" lines so connections are searchable. Cards are read group by
+// group, top to bottom and left to right, and the usual size constraints apply.
+func (c *GoldmarkChunker) ChunkCanvas(content []byte, filename string) (title string, chunks []Chunk, err error) {
+	title = extractTitleFromFilename(filename)
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return title, []Chunk{}, nil
+	}
+
+	var canvas canvasFile
+	if err := json.Unmarshal(content, &canvas); err != nil {
+		return "", nil, fmt.Errorf("failed to parse canvas: %w", err)
+	}
+
+	nodes := make(map[string]canvasNode, len(canvas.Nodes))
+	var groups []canvasNode
+	for _, node := range canvas.Nodes {
+		nodes[node.ID] = node
+		if node.Type == "group" {
+			groups = append(groups, node)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return canvasNodeLess(groups[i], groups[j]) })
+
+	// groupOf returns the index into groups of the smallest group enclosing node, or -1
+	groupOf := func(node canvasNode) int {
+		best := -1
+		for i, group := range groups {
+			if group.ID == node.ID || !group.contains(node) {
+				continue
+			}
+			if best == -1 || group.Width*group.Height < groups[best].Width*groups[best].Height {
+				best = i
+			}
+		}
+		return best
+	}
+
+	var cards []canvasNode
+	cardGroup := make(map[string]int)
+	for _, node := range canvas.Nodes {
+		if node.Type != "text" || strings.TrimSpace(node.Text) == "" {
+			continue
+		}
+		cards = append(cards, node)
+		cardGroup[node.ID] = groupOf(node)
+	}
+	// Ungrouped cards first, then each group in reading order
+	sort.SliceStable(cards, func(i, j int) bool {
+		gi, gj := cardGroup[cards[i].ID], cardGroup[cards[j].ID]
+		if gi != gj {
+			return gi < gj
+		}
+		return canvasNodeLess(cards[i], cards[j])
+	})
+
+	outgoing := make(map[string][]string)
+	incoming := make(map[string][]string)
+	for _, edge := range canvas.Edges {
+		from, okFrom := nodes[edge.FromNode]
+		to, okTo := nodes[edge.ToNode]
+		if !okFrom || !okTo {
+			continue
+		}
+		outgoing[from.ID] = append(outgoing[from.ID], canvasConnection(to, edge.Label))
+		incoming[to.ID] = append(incoming[to.ID], canvasConnection(from, edge.Label))
+	}
+
+	for _, card := range cards {
+		headingPath := "# " + title
+		if g := cardGroup[card.ID]; g >= 0 && strings.TrimSpace(groups[g].Label) != "" {
+			headingPath += " > ## " + strings.TrimSpace(groups[g].Label)
+		}
+		text := strings.TrimSpace(card.Text)
+		if heading, rest, ok := splitCanvasHeading(text); ok {
+			headingPath += " > ### " + heading
+			text = rest
+		}
+		if links := outgoing[card.ID]; len(links) > 0 {
+			text += "\n\nLinks to: " + strings.Join(links, "; ")
+		}
+		if links := incoming[card.ID]; len(links) > 0 {
+			text += "\n\nLinked from: " + strings.Join(links, "; ")
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), HeadingPath: headingPath, Text: text})
+	}
+
+	return title, c.applySizeConstraints(chunks), nil
+}
+
+// canvasCardText returns the text of a canvas's text cards, one per paragraph, so tags
+// written on cards are found. Unparseable canvases have no text.
+func canvasCardText(content []byte) []byte {
+	var canvas canvasFile
+	if err := json.Unmarshal(content, &canvas); err != nil {
+		return nil
+	}
+	var texts []string
+	for _, node := range canvas.Nodes {
+		if node.Type == "text" {
+			texts = append(texts, node.Text)
+		}
+	}
+	return []byte(strings.Join(texts, "\n\n"))
+}
+
+// splitCanvasHeading splits a card's leading markdown heading from the rest of its text.
+func splitCanvasHeading(text string) (heading, rest string, ok bool) {
+	firstLine, rest, _ := strings.Cut(text, "\n")
+	trimmed := strings.TrimLeft(firstLine, "#")
+	if trimmed == firstLine || !strings.HasPrefix(trimmed, " ") || len(firstLine)-len(trimmed) > 6 {
+		return "", text, false
+	}
+	heading = strings.TrimSpace(trimmed)
+	if heading == "" {
+		return "", text, false
+	}
+	return heading, strings.TrimSpace(rest), true
+}
+
+// canvasConnection describes the card at the other end of an arrow.
+func canvasConnection(node canvasNode, label string) string {
+	summary := canvasNodeSummary(node)
+	if label = strings.TrimSpace(label); label != "" {
+		return fmt.Sprintf("%s (%s)", summary, label)
+	}
+	return summary
+}
+
+// canvasNodeSummary names a card: its heading or first line, the embedded file, the link,
+// or the group label.
+func canvasNodeSummary(node canvasNode) string {
+	switch node.Type {
+	case "file":
+		return node.File
+	case "link":
+		return node.URL
+	case "group":
+		return node.Label
+	}
+	text := strings.TrimSpace(node.Text)
+	if heading, _, ok := splitCanvasHeading(text); ok {
+		text = heading
+	} else {
+		text, _, _ = strings.Cut(text, "\n")
+	}
+	if utf8.RuneCountInString(text) > canvasSummaryRunes {
+		text = string([]rune(text)[:canvasSummaryRunes]) + "…"
+	}
+	return text
+}
+
+// canvasNodeLess orders two nodes by position: top to bottom, then left to right.
+func canvasNodeLess(a, b canvasNode) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"strings"
+	"testing"
+)
+
+const testCanvas = `{
+	"nodes": [
+		{"id": "g1", "type": "group", "label": "Launch plan", "x": 0, "y": 0, "width": 1000, "height": 600},
+		{"id": "t2", "type": "text", "text": "## Risks\nThe vendor contract expires in March and renewal pricing is still unknown.", "x": 500, "y": 100, "width": 300, "height": 200},
+		{"id": "t1", "type": "text", "text": "# Timeline\nBeta ships to internal users in February, general availability follows in April.", "x": 50, "y": 100, "width": 300, "height": 200},
+		{"id": "f1", "type": "file", "file": "projects/vendor.md", "x": 1200, "y": 100, "width": 300, "height": 200},
+		{"id": "t3", "type": "text", "text": "Loose idea: run a design review with the platform team before the beta starts.", "x": 0, "y": 800, "width": 300, "height": 200},
+		{"id": "t4", "type": "text", "text": "   ", "x": 0, "y": 1200, "width": 300, "height": 200}
+	],
+	"edges": [
+		{"id": "e1", "fromNode": "t1", "toNode": "t2", "label": "blocks"},
+		{"id": "e2", "fromNode": "t2", "toNode": "f1"}
+	]
+}`
+
+func TestGoldmarkChunker_ChunkCanvas(t *testing.T) {
+	chunker := NewGoldmarkChunker()
+
+	title, chunks, err := chunker.ChunkCanvas([]byte(testCanvas), "launch board.canvas")
+	if err != nil {
+		t.Fatalf("ChunkCanvas() error = %v", err)
+	}
+	if title != "Launch Board" {
+		t.Errorf("title = %q, want %q", title, "Launch Board")
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3: %+v", len(chunks), chunks)
+	}
+
+	// Ungrouped cards come first, then the group's cards left to right
+	wantPaths := []string{
+		"# Launch Board",
+		"# Launch Board > ## Launch plan > ### Timeline",
+		"# Launch Board > ## Launch plan > ### Risks",
+	}
+	for i, want := range wantPaths {
+		if chunks[i].HeadingPath != want {
+			t.Errorf("chunks[%d].HeadingPath = %q, want %q", i, chunks[i].HeadingPath, want)
+		}
+		if chunks[i].Index != i {
+			t.Errorf("chunks[%d].Index = %d, want %d", i, chunks[i].Index, i)
+		}
+	}
+
+	if !strings.Contains(chunks[1].Text, "Links to: Risks (blocks)") {
+		t.Errorf("timeline chunk should list its outgoing connection, got %q", chunks[1].Text)
+	}
+	if strings.Contains(chunks[1].Text, "# Timeline") {
+		t.Errorf("card heading should move to the heading path, got %q", chunks[1].Text)
+	}
+	risks := chunks[2].Text
+	if !strings.Contains(risks, "Links to: projects/vendor.md") || !strings.Contains(risks, "Linked from: Timeline (blocks)") {
+		t.Errorf("risks chunk should list both connections, got %q", risks)
+	}
+}
+
+func TestGoldmarkChunker_ChunkCanvas_Invalid(t *testing.T) {
+	chunker := NewGoldmarkChunker()
+
+	if _, _, err := chunker.ChunkCanvas([]byte("{not json"), "broken.canvas"); err == nil {
+		t.Error("ChunkCanvas() should fail on invalid JSON")
+	}
+
+	title, chunks, err := chunker.ChunkCanvas(nil, "empty.canvas")
+	if err != nil || title != "Empty" || len(chunks) != 0 {
+		t.Errorf("ChunkCanvas(empty) = %q, %d chunks, %v; want title and no chunks", title, len(chunks), err)
+	}
+}
+
+func TestGoldmarkChunker_ChunkFile(t *testing.T) {
+	chunker := NewGoldmarkChunker()
+
+	_, chunks, err := chunker.ChunkFile([]byte(testCanvas), "boards/Launch.canvas")
+	if err != nil {
+		t.Fatalf("ChunkFile(canvas) error = %v", err)
+	}
+	if len(chunks) == 0 || !strings.HasPrefix(chunks[0].HeadingPath, "# Launch") {
+		t.Errorf("ChunkFile should chunk .canvas files as canvases, got %+v", chunks)
+	}
+
+	title, _, err := chunker.ChunkFile([]byte("# Note\n\nSome markdown content for the note body."), "notes/note.md")
+	if err != nil || title != "Note" {
+		t.Errorf("ChunkFile(markdown) = %q, %v; want title from the heading", title, err)
+	}
+}
+
+func TestCanvasCardText(t *testing.T) {
+	text := string(canvasCardText([]byte(`{"nodes":[{"id":"a","type":"text","text":"Plan #launch"},{"id":"b","type":"file","file":"x.md"}]}`)))
+	if text != "Plan #launch" {
+		t.Errorf("canvasCardText() = %q, want %q", text, "Plan #launch")
+	}
+	if got := extractTags([]byte(text)); len(got) != 1 || got[0] != "launch" {
+		t.Errorf("extractTags(card text) = %v, want [launch]", got)
+	}
+}
@@ -0,0 +1,78 @@
+package indexer
+
+import (
+	"context"
+	"math"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// noteCentroidMetaKeys are the chunk payload fields copied onto a note centroid point.
+var noteCentroidMetaKeys = []string{"vault_id", "vault_name", "note_id", "rel_path", "folder", "note_title"}
+
+// updateNoteCentroid stores the mean of a note's chunk embeddings in the note collection,
+// which the RAG engine searches first to pick candidate notes. A note without indexed
+// chunks has its centroid removed. Failures are logged and never fail indexing.
+func (p *Pipeline) updateNoteCentroid(ctx context.Context, noteID string, points []vectorstore.Point) {
+	if p.noteCollection == "" {
+		return
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if len(points) == 0 {
+		if err := p.vectorStore.Delete(ctx, p.noteCollection, []string{noteID}); err != nil {
+			logger.WarnContext(ctx, "failed to delete note centroid", "note_id", noteID, "error", err)
+		}
+		return
+	}
+
+	vectors := make([][]float32, 0, len(points))
+	for _, point := range points {
+		vectors = append(vectors, point.Vec)
+	}
+	meta := make(map[string]any, len(noteCentroidMetaKeys)+1)
+	for _, key := range noteCentroidMetaKeys {
+		meta[key] = points[0].Meta[key]
+	}
+	meta["chunk_count"] = len(points)
+
+	centroid := vectorstore.Point{
+		ID:   noteID,
+		Vec:  meanVector(vectors),
+		Meta: meta,
+	}
+	if err := p.vectorStore.Upsert(ctx, p.noteCollection, []vectorstore.Point{centroid}); err != nil {
+		logger.WarnContext(ctx, "failed to upsert note centroid", "note_id", noteID, "error", err)
+	}
+}
+
+// meanVector returns the unit-length mean of vectors, so cosine scores against
+// centroids are comparable to scores against chunks.
+func meanVector(vectors [][]float32) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	mean := make([]float32, len(vectors[0]))
+	for _, vec := range vectors {
+		for i := range mean {
+			if i < len(vec) {
+				mean[i] += vec[i]
+			}
+		}
+	}
+
+	var norm float64
+	for _, value := range mean {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return mean
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range mean {
+		mean[i] *= scale
+	}
+	return mean
+}
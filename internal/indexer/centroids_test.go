@@ -0,0 +1,63 @@
+package indexer
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestMeanVector(t *testing.T) {
+	got := meanVector([][]float32{{1, 0}, {0, 1}})
+	want := float32(1 / math.Sqrt(2))
+	if len(got) != 2 || math.Abs(float64(got[0]-want)) > 1e-6 || math.Abs(float64(got[1]-want)) > 1e-6 {
+		t.Errorf("meanVector() = %v, want [%f %f]", got, want, want)
+	}
+
+	if got := meanVector(nil); got != nil {
+		t.Errorf("meanVector(nil) = %v, want nil", got)
+	}
+}
+
+func TestPipeline_UpdateNoteCentroid(t *testing.T) {
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes_notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	pipeline := &Pipeline{vectorStore: store, noteCollection: "notes_notes"}
+
+	meta := map[string]any{"vault_id": 1, "vault_name": "personal", "note_id": "note-1", "rel_path": "garden.md", "folder": "", "note_title": "Garden", "heading_path": "# Garden"}
+	pipeline.updateNoteCentroid(ctx, "note-1", []vectorstore.Point{
+		{ID: "chunk-1", Vec: []float32{1, 0}, Meta: meta},
+		{ID: "chunk-2", Vec: []float32{1, 0}, Meta: meta},
+	})
+
+	points, err := store.Retrieve(ctx, "notes_notes", []string{"note-1"})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("Retrieve() returned %d centroids, want 1", len(points))
+	}
+	if points[0].Vec[0] != 1 || points[0].Vec[1] != 0 {
+		t.Errorf("centroid = %v, want [1 0]", points[0].Vec)
+	}
+	if points[0].Meta["note_id"] != "note-1" || points[0].Meta["rel_path"] != "garden.md" {
+		t.Errorf("centroid meta = %v, want note-1 garden.md", points[0].Meta)
+	}
+	if _, ok := points[0].Meta["heading_path"]; ok {
+		t.Error("centroid meta should not carry chunk-level fields")
+	}
+
+	// A note left without indexed chunks loses its centroid
+	pipeline.updateNoteCentroid(ctx, "note-1", nil)
+	points, err = store.Retrieve(ctx, "notes_notes", []string{"note-1"})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(points) != 0 {
+		t.Errorf("Retrieve() returned %d centroids after removal, want 0", len(points))
+	}
+}
@@ -0,0 +1,143 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"helloworld-ai/internal/storage"
+)
+
+// ChunkChange classifies a chunk in a ChunkDiff.
+type ChunkChange string
+
+const (
+	// ChunkAdded is a chunk of the current generation with no counterpart in the previous one.
+	ChunkAdded ChunkChange = "added"
+	// ChunkRemoved is a chunk of the previous generation with no counterpart in the current one.
+	ChunkRemoved ChunkChange = "removed"
+	// ChunkChanged is a chunk whose heading path survived but whose text changed.
+	ChunkChanged ChunkChange = "changed"
+	// ChunkUnchanged is a chunk whose text is the same in both generations (it may have moved).
+	ChunkUnchanged ChunkChange = "unchanged"
+)
+
+// ChunkDiffEntry is one chunk of a ChunkDiff. Indexes are -1 on the side the chunk is missing from.
+type ChunkDiffEntry struct {
+	Change      ChunkChange
+	HeadingPath string
+	// PreviousHeadingPath is set when an unchanged chunk moved to another heading.
+	PreviousHeadingPath string
+	PreviousIndex       int
+	CurrentIndex        int
+	PreviousText        string
+	CurrentText         string
+}
+
+// ChunkDiff compares the chunks of a note before and after its last re-index.
+type ChunkDiff struct {
+	RelPath string
+	// HasPrevious is false when the note was never re-indexed, so every chunk shows as added.
+	HasPrevious            bool
+	PreviousChunkerVersion string
+	CurrentChunkerVersion  string
+	Added                  int
+	Removed                int
+	Changed                int
+	Unchanged              int
+	// Entries lists the current chunks in order, followed by the removed ones.
+	Entries []ChunkDiffEntry
+}
+
+// ChunkDiff diffs the chunks a note had before its last re-index against its current
+// chunks. Returns storage.ErrNotFound when the note is not indexed.
+func (p *Pipeline) ChunkDiff(ctx context.Context, vaultID int, relPath string) (ChunkDiff, error) {
+	note, err := p.noteRepo.GetByVaultAndPath(ctx, vaultID, relPath)
+	if err != nil {
+		return ChunkDiff{}, err
+	}
+	previous, err := p.chunkRepo.ListPreviousByNote(ctx, note.ID)
+	if err != nil {
+		return ChunkDiff{}, fmt.Errorf("failed to list previous chunks: %w", err)
+	}
+	current, err := p.chunkRepo.ListByNote(ctx, note.ID)
+	if err != nil {
+		return ChunkDiff{}, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	diff := diffChunks(previous, current)
+	diff.RelPath = relPath
+	return diff, nil
+}
+
+// diffChunks pairs chunks with identical text first (unchanged, even if moved), then the
+// remaining chunks by heading path in order (changed). What is left was added or removed.
+func diffChunks(previous, current []storage.ChunkRecord) ChunkDiff {
+	diff := ChunkDiff{HasPrevious: len(previous) > 0}
+	if len(previous) > 0 {
+		diff.PreviousChunkerVersion = previous[0].ChunkerVersion
+	}
+	if len(current) > 0 {
+		diff.CurrentChunkerVersion = current[0].ChunkerVersion
+	}
+
+	// match[i] is the previous chunk paired with current[i] (-1: none)
+	match := make([]int, len(current))
+	for i := range match {
+		match[i] = -1
+	}
+	paired := make([]bool, len(previous))
+	pair := func(same func(prev, cur storage.ChunkRecord) bool) {
+		for i, cur := range current {
+			if match[i] >= 0 {
+				continue
+			}
+			for j, prev := range previous {
+				if !paired[j] && same(prev, cur) {
+					match[i], paired[j] = j, true
+					break
+				}
+			}
+		}
+	}
+	pair(func(prev, cur storage.ChunkRecord) bool { return prev.TextHash == cur.TextHash })
+	pair(func(prev, cur storage.ChunkRecord) bool { return prev.HeadingPath == cur.HeadingPath })
+
+	for i, cur := range current {
+		entry := ChunkDiffEntry{HeadingPath: cur.HeadingPath, CurrentIndex: cur.ChunkIndex, PreviousIndex: -1}
+		j := match[i]
+		switch {
+		case j < 0:
+			entry.Change = ChunkAdded
+			entry.CurrentText = cur.Text
+			diff.Added++
+		case previous[j].TextHash == cur.TextHash:
+			entry.Change = ChunkUnchanged
+			entry.PreviousIndex = previous[j].ChunkIndex
+			if previous[j].HeadingPath != cur.HeadingPath {
+				entry.PreviousHeadingPath = previous[j].HeadingPath
+			}
+			diff.Unchanged++
+		default:
+			entry.Change = ChunkChanged
+			entry.PreviousIndex = previous[j].ChunkIndex
+			entry.PreviousText = previous[j].Text
+			entry.CurrentText = cur.Text
+			diff.Changed++
+		}
+		diff.Entries = append(diff.Entries, entry)
+	}
+	for j, prev := range previous {
+		if paired[j] {
+			continue
+		}
+		diff.Entries = append(diff.Entries, ChunkDiffEntry{
+			Change:        ChunkRemoved,
+			HeadingPath:   prev.HeadingPath,
+			PreviousIndex: prev.ChunkIndex,
+			CurrentIndex:  -1,
+			PreviousText:  prev.Text,
+		})
+		diff.Removed++
+	}
+	return diff
+}
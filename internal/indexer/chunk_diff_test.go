@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+func chunkRecord(index int, heading, text string) storage.ChunkRecord {
+	return storage.ChunkRecord{ChunkIndex: index, HeadingPath: heading, Text: text, TextHash: storage.TextHash(text)}
+}
+
+func TestDiffChunks(t *testing.T) {
+	previous := []storage.ChunkRecord{
+		chunkRecord(0, "# Plan", "Intro"),
+		chunkRecord(1, "# Plan > ## Budget", "Budget is 10k"),
+		chunkRecord(2, "# Plan > ## Risks", "Vendor lock-in"),
+	}
+	current := []storage.ChunkRecord{
+		chunkRecord(0, "# Plan", "Intro"),
+		chunkRecord(1, "# Plan > ## Budget", "Budget is 12k"),
+		chunkRecord(2, "# Plan > ## Timeline", "Ships in April"),
+	}
+
+	diff := diffChunks(previous, current)
+	if !diff.HasPrevious || diff.Added != 1 || diff.Removed != 1 || diff.Changed != 1 || diff.Unchanged != 1 {
+		t.Fatalf("diffChunks() counts = %+v, want one of each", diff)
+	}
+
+	want := []struct {
+		change    ChunkChange
+		heading   string
+		prev, cur int
+	}{
+		{ChunkUnchanged, "# Plan", 0, 0},
+		{ChunkChanged, "# Plan > ## Budget", 1, 1},
+		{ChunkAdded, "# Plan > ## Timeline", -1, 2},
+		{ChunkRemoved, "# Plan > ## Risks", 2, -1},
+	}
+	if len(diff.Entries) != len(want) {
+		t.Fatalf("diffChunks() entries = %+v, want %d", diff.Entries, len(want))
+	}
+	for i, w := range want {
+		got := diff.Entries[i]
+		if got.Change != w.change || got.HeadingPath != w.heading || got.PreviousIndex != w.prev || got.CurrentIndex != w.cur {
+			t.Errorf("entries[%d] = %+v, want %s %q (%d -> %d)", i, got, w.change, w.heading, w.prev, w.cur)
+		}
+	}
+	if changed := diff.Entries[1]; changed.PreviousText != "Budget is 10k" || changed.CurrentText != "Budget is 12k" {
+		t.Errorf("changed entry texts = %q -> %q", changed.PreviousText, changed.CurrentText)
+	}
+}
+
+func TestDiffChunks_MovedAndFirstIndex(t *testing.T) {
+	// A section moved under a new heading keeps its text, so it counts as unchanged
+	diff := diffChunks(
+		[]storage.ChunkRecord{chunkRecord(0, "# Notes", "Water the tomatoes")},
+		[]storage.ChunkRecord{chunkRecord(0, "# Garden", "Water the tomatoes")},
+	)
+	if diff.Unchanged != 1 || diff.Entries[0].PreviousHeadingPath != "# Notes" {
+		t.Errorf("diffChunks(moved) = %+v, want unchanged with the previous heading", diff)
+	}
+
+	// Without a previous generation every chunk is added
+	diff = diffChunks(nil, []storage.ChunkRecord{chunkRecord(0, "# Garden", "Water the tomatoes")})
+	if diff.HasPrevious || diff.Added != 1 {
+		t.Errorf("diffChunks(first index) = %+v, want one added chunk", diff)
+	}
+}
@@ -16,11 +16,17 @@ import (
 const (
 	minChunkSize = 50
 	maxChunkSize = 700 // Max runes per chunk (targets ~450 tokens for 512-token embedding model)
+	// MaxChunkOverlap is the largest overlap between consecutive chunks, in runes.
+	MaxChunkOverlap = maxChunkSize / 2
+	// overlapSeparator joins the repeated text to the chunk it is prepended to.
+	overlapSeparator = "\n\n"
 )
 
 // GoldmarkChunker chunks markdown content using goldmark AST parsing.
 type GoldmarkChunker struct {
 	parser goldmark.Markdown
+	// overlap is how many runes of the previous chunk each chunk repeats (0 disables it).
+	overlap int
 }
 
 // NewGoldmarkChunker creates a new goldmark chunker.
@@ -34,7 +40,9 @@ func NewGoldmarkChunker() *GoldmarkChunker {
 
 // ChunkMarkdown parses markdown content and returns the title and chunks.
 // The chunks are organized by heading hierarchy with size constraints.
+// YAML frontmatter is metadata, not note text, so it is left out of the chunks.
 func (c *GoldmarkChunker) ChunkMarkdown(content []byte, filename string) (title string, chunks []Chunk, err error) {
+	_, content, _ = splitFrontmatter(content)
 	if len(content) == 0 {
 		// Empty file: use filename as title, return empty chunks
 		title = extractTitleFromFilename(filename)
@@ -51,7 +59,7 @@ func (c *GoldmarkChunker) ChunkMarkdown(content []byte, filename string) (title
 	// Walk AST to build chunks
 	chunks = c.buildChunks(doc, content, title)
 
-	// Apply size constraints: merge tiny chunks, split oversized chunks
+	// Apply size constraints: merge tiny chunks, split oversized chunks, add overlap
 	chunks = c.applySizeConstraints(chunks)
 
 	return title, chunks, nil
@@ -374,12 +382,16 @@ func extractTableRowText(row ast.Node, content []byte) string {
 // - Merge chunks smaller than minChunkSize with the next chunk
 // - Merge chunks with the same heading path (helps with content before headings)
 // - Split chunks larger than maxChunkSize (prefer heading boundaries, but split if needed)
+// - Prefix each chunk with the end of the previous one when overlap is enabled
 // Size is measured in runes (not bytes) for consistency with embedding token estimation.
+// With overlap, chunks are sized to leave room for the repeated text, so they still fit
+// within maxChunkSize.
 func (c *GoldmarkChunker) applySizeConstraints(chunks []Chunk) []Chunk {
 	if len(chunks) == 0 {
 		return chunks
 	}
 
+	maxRunes := c.maxBodyRunes()
 	result := []Chunk{}
 	i := 0
 
@@ -400,7 +412,7 @@ func (c *GoldmarkChunker) applySizeConstraints(chunks []Chunk) []Chunk {
 				}
 
 				// If merged chunk is still reasonable, use it
-				if utf8.RuneCountInString(merged.Text) <= maxChunkSize {
+				if utf8.RuneCountInString(merged.Text) <= maxRunes {
 					current = merged
 					currentRunes = utf8.RuneCountInString(current.Text)
 					i++ // Skip next chunk since we merged it
@@ -418,7 +430,7 @@ func (c *GoldmarkChunker) applySizeConstraints(chunks []Chunk) []Chunk {
 			}
 
 			// If merged chunk is still reasonable, use it
-			if utf8.RuneCountInString(merged.Text) <= maxChunkSize {
+			if utf8.RuneCountInString(merged.Text) <= maxRunes {
 				current = merged
 				currentRunes = utf8.RuneCountInString(current.Text)
 				i++ // Skip next chunk since we merged it
@@ -426,7 +438,7 @@ func (c *GoldmarkChunker) applySizeConstraints(chunks []Chunk) []Chunk {
 		}
 
 		// If chunk is too large, split it
-		if currentRunes > maxChunkSize {
+		if currentRunes > maxRunes {
 			splitChunks := c.splitChunk(current)
 			result = append(result, splitChunks...)
 		} else {
@@ -441,15 +453,16 @@ func (c *GoldmarkChunker) applySizeConstraints(chunks []Chunk) []Chunk {
 		result[i].Index = i
 	}
 
-	return result
+	return c.applyOverlap(result)
 }
 
-// splitChunk splits a chunk that exceeds maxChunkSize.
+// splitChunk splits a chunk that exceeds maxChunkSize (less the overlap, see maxBodyRunes).
 // Tries to split at paragraph boundaries, otherwise splits at sentence boundaries, otherwise hard split.
 // Size is measured in runes (not bytes) for consistency with embedding token estimation.
 func (c *GoldmarkChunker) splitChunk(chunk Chunk) []Chunk {
+	maxRunes := c.maxBodyRunes()
 	chunkRunes := utf8.RuneCountInString(chunk.Text)
-	if chunkRunes <= maxChunkSize {
+	if chunkRunes <= maxRunes {
 		return []Chunk{chunk}
 	}
 
@@ -460,7 +473,7 @@ func (c *GoldmarkChunker) splitChunk(chunk Chunk) []Chunk {
 	splitIndex := 0
 
 	for start < len(textRunes) {
-		end := start + maxChunkSize
+		end := start + maxRunes
 
 		if end >= len(textRunes) {
 			// Last chunk
@@ -496,3 +509,69 @@ func (c *GoldmarkChunker) splitChunk(chunk Chunk) []Chunk {
 
 	return splits
 }
+
+// maxBodyRunes is the size chunks are built to before overlap is added, so that chunks with
+// the previous chunk's text prepended stay within maxChunkSize.
+func (c *GoldmarkChunker) maxBodyRunes() int {
+	if c.overlap <= 0 {
+		return maxChunkSize
+	}
+	return maxChunkSize - c.overlap
+}
+
+// applyOverlap prefixes each chunk with the trailing sentences of the chunk before it (at
+// most overlap runes including the separator), so text cut at a chunk boundary, such as the
+// end of a section, is embedded with the chunk that follows it too. OverlapRunes records the
+// prefix length so retrieval can drop the repeat when both chunks end up in the context.
+func (c *GoldmarkChunker) applyOverlap(chunks []Chunk) []Chunk {
+	if c.overlap <= 0 || len(chunks) < 2 {
+		return chunks
+	}
+
+	limit := c.overlap - utf8.RuneCountInString(overlapSeparator)
+	previous := chunks[0].Text
+	for i := 1; i < len(chunks); i++ {
+		text := chunks[i].Text
+		if tail := overlapTail(previous, limit); tail != "" {
+			prefix := tail + overlapSeparator
+			chunks[i].Text = prefix + text
+			chunks[i].OverlapRunes = utf8.RuneCountInString(prefix)
+		}
+		previous = text
+	}
+	return chunks
+}
+
+// overlapTail returns the end of text to repeat in the next chunk: the trailing whole
+// sentences (or lines) that fit in maxRunes, or the trailing words when the last sentence is
+// longer. It never returns the whole text, and returns "" when no boundary fits.
+func overlapTail(text string, maxRunes int) string {
+	runes := []rune(strings.TrimRightFunc(text, unicode.IsSpace))
+	if maxRunes <= 0 || len(runes) < 2 {
+		return ""
+	}
+	start := len(runes) - maxRunes
+	if start < 1 {
+		start = 1
+	}
+
+	cut := -1
+	for k := start; k < len(runes); k++ {
+		if runes[k-1] == '\n' || (k >= 2 && unicode.IsSpace(runes[k-1]) && strings.ContainsRune(".!?", runes[k-2])) {
+			cut = k
+			break
+		}
+	}
+	if cut < 0 {
+		for k := start; k < len(runes); k++ {
+			if unicode.IsSpace(runes[k-1]) {
+				cut = k
+				break
+			}
+		}
+	}
+	if cut < 0 {
+		return ""
+	}
+	return strings.TrimSpace(string(runes[cut:]))
+}
@@ -218,3 +218,83 @@ func TestExtractTitleFromFilename(t *testing.T) {
 	}
 }
 
+
+func TestGoldmarkChunker_ChunkMarkdown_Overlap(t *testing.T) {
+	chunker := NewGoldmarkChunker()
+	chunker.overlap = 60
+
+	content := []byte("# Garden\n\n" +
+		"The tomatoes went in on the first of May this year. They were watered every morning before work.\n\n" +
+		"## Harvest\n\n" +
+		"The first ripe tomatoes came in late July, about a month earlier than the year before.")
+	_, chunks, err := chunker.ChunkMarkdown(content, "garden.md")
+	if err != nil {
+		t.Fatalf("ChunkMarkdown() error = %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("ChunkMarkdown() returned %d chunks, want 2", len(chunks))
+	}
+	if chunks[0].OverlapRunes != 0 {
+		t.Errorf("first chunk OverlapRunes = %d, want 0", chunks[0].OverlapRunes)
+	}
+
+	second := chunks[1]
+	if second.OverlapRunes == 0 || second.OverlapRunes > chunker.overlap {
+		t.Fatalf("second chunk OverlapRunes = %d, want between 1 and %d", second.OverlapRunes, chunker.overlap)
+	}
+	prefix := string([]rune(second.Text)[:second.OverlapRunes])
+	if prefix != "They were watered every morning before work.\n\n" {
+		t.Errorf("second chunk overlap = %q, want the last sentence of the first chunk", prefix)
+	}
+	if second.HeadingPath != "# Garden > ## Harvest" {
+		t.Errorf("second chunk HeadingPath = %q, want its own heading", second.HeadingPath)
+	}
+}
+
+func TestGoldmarkChunker_ChunkMarkdown_OverlapSize(t *testing.T) {
+	chunker := NewGoldmarkChunker()
+	chunker.overlap = MaxChunkOverlap
+
+	paragraph := "This sentence is about forty-six runes long. "
+	var content []byte
+	for i := 0; i < 60; i++ {
+		content = append(content, paragraph...)
+	}
+	_, chunks, err := chunker.ChunkMarkdown(content, "long.md")
+	if err != nil {
+		t.Fatalf("ChunkMarkdown() error = %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("ChunkMarkdown() returned %d chunks, want the note split", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if runes := utf8.RuneCountInString(chunk.Text); runes > maxChunkSize {
+			t.Errorf("chunk %d has %d runes with overlap, want at most %d", i, runes, maxChunkSize)
+		}
+		if i > 0 && chunk.OverlapRunes == 0 {
+			t.Errorf("chunk %d has no overlap", i)
+		}
+	}
+}
+
+func TestOverlapTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{"whole sentences", "One. Two words. Three more words.", 30, "Two words. Three more words."},
+		{"line boundary", "first line\nsecond line", 15, "second line"},
+		{"word fallback", "a very long sentence without any stops", 12, "any stops"},
+		{"never the whole text", "Short.", 50, ""},
+		{"disabled", "One. Two.", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlapTail(tt.text, tt.maxRunes); got != tt.want {
+				t.Errorf("overlapTail(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package indexer
+
+import "helloworld-ai/internal/vectorstore"
+
+// SetCollectionPerVault stores each vault's chunks in its own collection (see
+// vectorstore.VaultCollection) instead of the shared one, so a vault can be cleared or
+// rebuilt without touching the others' points. The cold and note centroid collections stay
+// shared. Call it before indexing starts; switching it leaves existing points behind in the
+// previous collections until the next force reindex.
+func (p *Pipeline) SetCollectionPerVault(enabled bool) {
+	p.collectionPerVault = enabled
+}
+
+// CollectionPerVault reports whether chunks are stored in per-vault collections.
+func (p *Pipeline) CollectionPerVault() bool {
+	return p.collectionPerVault
+}
+
+// ChunkCollections returns the collections holding hot chunks: the shared collection, or
+// one collection per configured vault.
+func (p *Pipeline) ChunkCollections() []string {
+	if !p.collectionPerVault {
+		return []string{p.collection}
+	}
+	vaults := p.vaultManager.ListVaults()
+	collections := make([]string, 0, len(vaults))
+	for _, v := range vaults {
+		collections = append(collections, vectorstore.VaultCollection(p.collection, v.ID))
+	}
+	return collections
+}
+
+// chunkCollection returns the collection holding a vault's hot chunks. During a rebuild it
+// is the new collection being built for that name.
+func (p *Pipeline) chunkCollection(vaultID int) string {
+	collection := p.collection
+	if p.collectionPerVault {
+		collection = vectorstore.VaultCollection(p.collection, vaultID)
+	}
+	if target, ok := p.rebuildTargets[collection]; ok {
+		return target
+	}
+	return collection
+}
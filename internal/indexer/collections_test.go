@@ -0,0 +1,121 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestPipeline_CollectionPerVault(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	vaultDirs := map[string]string{"personal": filepath.Join(tmpDir, "personal"), "work": filepath.Join(tmpDir, "work")}
+	for name, dir := range vaultDirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create vault dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte("# "+name+"\n\nNotes about "+name+"."), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: vaultDirs["personal"]}, {Name: "work", Path: vaultDirs["work"]}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+
+	const vectorSize = 16
+	fakeLLM := llm.NewFakeServer(vectorSize)
+	defer fakeLLM.Close()
+	embedder := llm.NewEmbeddingsClient(fakeLLM.URL, "dummy-key", "fake-embedding", vectorSize)
+
+	store := vectorstore.NewMemoryStore()
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+	pipeline := NewPipeline(vaultManager, noteRepo, chunkRepo, nil, nil, embedder, store, "notes", "", "", storage.NewShadowIndex(db, dbPath+".rebuild"))
+	pipeline.SetCollectionPerVault(true)
+
+	collections := pipeline.ChunkCollections()
+	personalCollection := vectorstore.VaultCollection("notes", personal.ID)
+	workCollection := vectorstore.VaultCollection("notes", work.ID)
+	if len(collections) != 2 || collections[0] != personalCollection || collections[1] != workCollection {
+		t.Fatalf("ChunkCollections() = %v, want [%s %s]", collections, personalCollection, workCollection)
+	}
+	for _, collection := range collections {
+		if err := store.EnsureCollection(ctx, collection, vectorSize); err != nil {
+			t.Fatalf("EnsureCollection() error = %v", err)
+		}
+	}
+
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	// Each vault's chunks land only in its own collection
+	chunkIDs := func(vaultID int) []string {
+		notes, _ := noteRepo.ListByVault(ctx, vaultID)
+		var ids []string
+		for _, note := range notes {
+			noteIDs, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+			ids = append(ids, noteIDs...)
+		}
+		return ids
+	}
+	personalIDs, workIDs := chunkIDs(personal.ID), chunkIDs(work.ID)
+	if len(personalIDs) == 0 || len(workIDs) == 0 {
+		t.Fatalf("chunk IDs = %v and %v, want chunks in both vaults", personalIDs, workIDs)
+	}
+	if points, _ := store.Retrieve(ctx, personalCollection, personalIDs); len(points) != len(personalIDs) {
+		t.Errorf("personal collection holds %d of %d personal chunks", len(points), len(personalIDs))
+	}
+	if points, _ := store.Retrieve(ctx, personalCollection, workIDs); len(points) != 0 {
+		t.Errorf("personal collection holds %d work chunks, want 0", len(points))
+	}
+
+	// Clearing one vault leaves the other vault's collection untouched
+	if _, err := pipeline.ClearVault(ctx, personal.ID); err != nil {
+		t.Fatalf("ClearVault() error = %v", err)
+	}
+	if points, _ := store.Retrieve(ctx, personalCollection, personalIDs); len(points) != 0 {
+		t.Errorf("personal collection holds %d points after ClearVault, want 0", len(points))
+	}
+	if points, _ := store.Retrieve(ctx, workCollection, workIDs); len(points) != len(workIDs) {
+		t.Errorf("work collection holds %d of %d chunks after clearing personal", len(points), len(workIDs))
+	}
+
+	// A rebuild switches an alias per vault collection
+	if err := pipeline.Rebuild(ctx); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	for _, collection := range collections {
+		if target, _ := store.AliasTarget(ctx, collection); target == "" || target == collection {
+			t.Errorf("AliasTarget(%s) = %q, want a rebuild collection", collection, target)
+		}
+	}
+	if points, _ := store.Retrieve(ctx, personalCollection, chunkIDs(personal.ID)); len(points) == 0 {
+		t.Error("personal collection is empty after the rebuild, want the re-indexed chunks")
+	}
+	if points, _ := store.Retrieve(ctx, personalCollection, chunkIDs(work.ID)); len(points) != 0 {
+		t.Errorf("personal collection holds %d work chunks after the rebuild, want 0", len(points))
+	}
+}
@@ -0,0 +1,12 @@
+package indexer
+
+// IndexEpoch returns a counter that advances whenever an index pass finishes or notes are
+// removed, so readers can cache note metadata (vaults, folders) until it changes.
+func (p *Pipeline) IndexEpoch() uint64 {
+	return p.epoch.Load()
+}
+
+// advanceEpoch marks cached note metadata as outdated.
+func (p *Pipeline) advanceEpoch() {
+	p.epoch.Add(1)
+}
@@ -0,0 +1,56 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipeline_IndexEpoch(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{filepath.Join(personalDir, "Archive"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(personalDir, "Archive", "old.md"), []byte("# Old\n\nLast year's plans."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, _, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if got := pipeline.IndexEpoch(); got != 0 {
+		t.Fatalf("IndexEpoch() = %d before indexing, want 0", got)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if got := pipeline.IndexEpoch(); got != 1 {
+		t.Errorf("IndexEpoch() = %d after IndexAll, want 1", got)
+	}
+
+	// Deleting nothing leaves cached metadata valid
+	personal, _ := vaultManager.VaultByName("personal")
+	if _, err := pipeline.DeleteFolder(ctx, personal.ID, "Missing"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if got := pipeline.IndexEpoch(); got != 1 {
+		t.Errorf("IndexEpoch() = %d after deleting nothing, want 1", got)
+	}
+	if _, err := pipeline.DeleteFolder(ctx, personal.ID, "Archive"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if got := pipeline.IndexEpoch(); got != 2 {
+		t.Errorf("IndexEpoch() = %d after DeleteFolder, want 2", got)
+	}
+
+	if err := pipeline.ClearAll(ctx); err != nil {
+		t.Fatalf("ClearAll() error = %v", err)
+	}
+	if got := pipeline.IndexEpoch(); got != 3 {
+		t.Errorf("IndexEpoch() = %d after ClearAll, want 3", got)
+	}
+}
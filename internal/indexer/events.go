@@ -0,0 +1,45 @@
+package indexer
+
+import (
+	"context"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// indexEventRetention is how many index events are kept; clients further behind than this
+// re-fetch everything instead of replaying events.
+const indexEventRetention = 10000
+
+// SetEventStore makes the pipeline record index changes (notes added, updated, moved, or
+// deleted, and completed index passes) so clients can invalidate caches incrementally.
+// A nil store records nothing. Call it before indexing starts.
+func (p *Pipeline) SetEventStore(store storage.IndexEventStore) {
+	p.eventRepo = store
+}
+
+// recordEvent appends an index event when an event store is configured.
+// Failures are logged and never fail indexing.
+func (p *Pipeline) recordEvent(ctx context.Context, event storage.IndexEventRecord) {
+	if p.eventRepo == nil {
+		return
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+	if err := p.eventRepo.Record(ctx, &event); err != nil {
+		logger.WarnContext(ctx, "failed to record index event", "type", event.Type, "rel_path", event.RelPath, "error", err)
+	}
+}
+
+// recordReindexCompleted records the end of an index pass over vaultID (0 = all vaults)
+// and prunes events beyond the retention limit.
+func (p *Pipeline) recordReindexCompleted(ctx context.Context, vaultID int) {
+	if p.eventRepo == nil {
+		return
+	}
+	p.recordEvent(ctx, storage.IndexEventRecord{Type: storage.IndexEventReindexCompleted, VaultID: vaultID})
+	if pruned, err := p.eventRepo.Prune(ctx, indexEventRetention); err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to prune index events", "error", err)
+	} else if pruned > 0 {
+		contextutil.LoggerFromContext(ctx).DebugContext(ctx, "pruned index events", "count", pruned)
+	}
+}
@@ -0,0 +1,88 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+func TestPipeline_RecordsIndexEvents(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{filepath.Join(personalDir, "projects"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	gardenPath := filepath.Join(personalDir, "projects", "garden.md")
+	if err := os.WriteFile(gardenPath, []byte("# Garden\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, _, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	events := storage.NewIndexEventRepo(db)
+	pipeline.SetEventStore(events)
+	personal, _ := vaultManager.VaultByName("personal")
+
+	// Added, unchanged (no event), updated, moved, then deleted
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if err := os.WriteFile(gardenPath, []byte("# Garden\n\nTomatoes moved to the south bed."), 0644); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if err := os.Rename(filepath.Join(personalDir, "projects"), filepath.Join(personalDir, "archive")); err != nil {
+		t.Fatalf("Failed to rename folder: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if _, err := pipeline.DeleteFolder(ctx, personal.ID, "archive"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+
+	recorded, err := events.ListSince(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("ListSince() error = %v", err)
+	}
+	var types []string
+	for i, event := range recorded {
+		types = append(types, event.Type)
+		if event.Seq != int64(i+1) {
+			t.Errorf("event %d seq = %d, want %d", i, event.Seq, i+1)
+		}
+	}
+	want := []string{
+		storage.IndexEventNoteAdded, storage.IndexEventReindexCompleted,
+		storage.IndexEventReindexCompleted,
+		storage.IndexEventNoteUpdated, storage.IndexEventReindexCompleted,
+		storage.IndexEventNoteMoved, storage.IndexEventReindexCompleted,
+		storage.IndexEventNoteDeleted,
+	}
+	if !slices.Equal(types, want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	moved := recorded[5]
+	if moved.VaultID != personal.ID || moved.RelPath != "archive/garden.md" || moved.PreviousRelPath != "projects/garden.md" || moved.NoteID != recorded[0].NoteID {
+		t.Errorf("moved event = %+v, want the added note moved from projects/ to archive/", moved)
+	}
+}
@@ -0,0 +1,101 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// SetExcludedFolderStore makes DeleteFolder exclude the folders it removes, so index runs
+// skip them instead of indexing their notes again. A nil store excludes nothing. Call it
+// before indexing starts.
+func (p *Pipeline) SetExcludedFolderStore(store storage.ExcludedFolderStore) {
+	p.excludedFolderRepo = store
+}
+
+// ExcludedFolders returns the folder prefixes of vaultID (0 = all vaults) that index runs
+// skip, ordered by vault and prefix.
+func (p *Pipeline) ExcludedFolders(ctx context.Context, vaultID int) ([]storage.ExcludedFolderRecord, error) {
+	if p.excludedFolderRepo == nil {
+		return nil, nil
+	}
+	folders, err := p.excludedFolderRepo.List(ctx, vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list excluded folders: %w", err)
+	}
+	return folders, nil
+}
+
+// IncludeFolder lifts the exclusion DeleteFolder recorded for a folder prefix, so the next
+// index run indexes its notes again. Returns an error wrapping storage.ErrNotFound if the
+// prefix is not excluded.
+func (p *Pipeline) IncludeFolder(ctx context.Context, vaultID int, prefix string) error {
+	prefix = normalizeFolderPrefix(prefix)
+	if p.excludedFolderRepo == nil {
+		return fmt.Errorf("folder %q is not excluded: %w", prefix, storage.ErrNotFound)
+	}
+	if err := p.excludedFolderRepo.Remove(ctx, vaultID, prefix); err != nil {
+		return fmt.Errorf("failed to include folder %q: %w", prefix, err)
+	}
+	return nil
+}
+
+// folderExclusions maps vault IDs to their excluded folder prefixes.
+type folderExclusions map[int][]string
+
+// covers reports whether folder is one of the vault's excluded prefixes or below one.
+func (e folderExclusions) covers(vaultID int, folder string) bool {
+	for _, prefix := range e[vaultID] {
+		if inFolder(folder, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadExclusions returns the excluded folders of vaultID (0 = all vaults).
+func (p *Pipeline) loadExclusions(ctx context.Context, vaultID int) (folderExclusions, error) {
+	folders, err := p.ExcludedFolders(ctx, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	exclusions := make(folderExclusions)
+	for _, f := range folders {
+		exclusions[f.VaultID] = append(exclusions[f.VaultID], f.Prefix)
+	}
+	return exclusions, nil
+}
+
+// withoutExcludedFolders drops the scanned files under excluded folders. The index run then
+// treats notes still indexed there like deleted files and removes them.
+func (p *Pipeline) withoutExcludedFolders(ctx context.Context, vaultID int, files []vault.ScannedFile) ([]vault.ScannedFile, error) {
+	exclusions, err := p.loadExclusions(ctx, vaultID)
+	if err != nil || len(exclusions) == 0 {
+		return files, err
+	}
+	kept := make([]vault.ScannedFile, 0, len(files))
+	for _, file := range files {
+		if !exclusions.covers(file.VaultID, file.Folder) {
+			kept = append(kept, file)
+		}
+	}
+	return kept, nil
+}
+
+// folderExcluded reports whether folder of a vault is excluded from the index.
+func (p *Pipeline) folderExcluded(ctx context.Context, vaultID int, folder string) (bool, error) {
+	exclusions, err := p.loadExclusions(ctx, vaultID)
+	if err != nil {
+		return false, err
+	}
+	return exclusions.covers(vaultID, folder), nil
+}
+
+// inFolder reports whether folder is prefix or one of its subfolders. Whole folder names are
+// matched: "Archive" covers "Archive/2020" but not "Archived".
+func inFolder(folder, prefix string) bool {
+	return folder == prefix || strings.HasPrefix(folder, prefix+"/")
+}
@@ -0,0 +1,97 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+func TestPipeline_DeleteFolder_ExcludesFolder(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	writeNote := func(relPath, content string) {
+		t.Helper()
+		absPath := filepath.Join(personalDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	writeNote("Archive/old.md", "# Old\n\nLast year's plans.")
+	writeNote("Archive/2020/review.md", "# Review\n\nThe 2020 review.")
+	writeNote("garden.md", "# Garden\n\nTomatoes grow in the north bed.")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	pipeline.SetExcludedFolderStore(storage.NewExcludedFolderRepo(db))
+	personal, _ := vaultManager.VaultByName("personal")
+
+	indexedPaths := func() []string {
+		t.Helper()
+		notes, err := noteRepo.ListByVault(ctx, personal.ID)
+		if err != nil {
+			t.Fatalf("ListByVault() error = %v", err)
+		}
+		paths := make([]string, 0, len(notes))
+		for _, note := range notes {
+			paths = append(paths, note.RelPath)
+		}
+		return paths
+	}
+
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if _, err := pipeline.DeleteFolder(ctx, personal.ID, "Archive"); err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+
+	// The files are still there, but index runs and single-note writes skip the folder
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	writeNote("Archive/new.md", "# New\n\nWritten after the folder was removed.")
+	if err := pipeline.ReindexNote(ctx, personal.ID, "Archive/new.md"); err != nil {
+		t.Fatalf("ReindexNote() error = %v", err)
+	}
+	if got := indexedPaths(); len(got) != 1 || got[0] != "garden.md" {
+		t.Errorf("indexed notes after DeleteFolder() = %v, want only garden.md", got)
+	}
+	excluded, err := pipeline.ExcludedFolders(ctx, personal.ID)
+	if err != nil {
+		t.Fatalf("ExcludedFolders() error = %v", err)
+	}
+	if len(excluded) != 1 || excluded[0].Prefix != "Archive" {
+		t.Errorf("ExcludedFolders() = %+v, want Archive", excluded)
+	}
+
+	if err := pipeline.IncludeFolder(ctx, personal.ID, "/Archive/"); err != nil {
+		t.Fatalf("IncludeFolder() error = %v", err)
+	}
+	if err := pipeline.IncludeFolder(ctx, personal.ID, "Archive"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("IncludeFolder() of an included folder error = %v, want ErrNotFound", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if got := indexedPaths(); len(got) != 4 {
+		t.Errorf("indexed notes after IncludeFolder() = %v, want all 4", got)
+	}
+}
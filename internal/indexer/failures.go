@@ -0,0 +1,41 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// SetFailureStore makes the pipeline record the chunks each note's indexing run skipped or
+// failed to embed, for GET /api/v1/index/failures and the skipped counts of IndexingCoverage.
+// A nil store records nothing. Call it before indexing starts.
+func (p *Pipeline) SetFailureStore(store storage.IndexFailureStore) {
+	p.failureRepo = store
+}
+
+// recordFailures replaces the failures recorded for a note with failures (none clears them)
+// when a failure store is configured. Failures are logged and never fail indexing.
+func (p *Pipeline) recordFailures(ctx context.Context, noteID, relPath string, failures []storage.IndexFailureRecord) {
+	if p.failureRepo == nil {
+		return
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+	if err := p.failureRepo.ReplaceForNote(ctx, noteID, failures); err != nil {
+		logger.WarnContext(ctx, "failed to record index failures", "rel_path", relPath, "failures", len(failures), "error", err)
+	}
+}
+
+// IndexFailures returns up to limit chunks left out of the index by the last indexing run of
+// their note, newest first.
+func (p *Pipeline) IndexFailures(ctx context.Context, limit int) ([]storage.IndexFailureRecord, error) {
+	if p.failureRepo == nil {
+		return nil, fmt.Errorf("index failure store is not configured")
+	}
+	failures, err := p.failureRepo.List(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list index failures: %w", err)
+	}
+	return failures, nil
+}
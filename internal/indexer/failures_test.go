@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+)
+
+func TestPipeline_RecordsIndexFailures(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+
+	// Texts containing "oversized" exceed the embedding model's context
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.EmbeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		resp := llm.EmbeddingsResponse{}
+		for _, text := range req.Input {
+			if strings.Contains(text, "oversized") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"code":400,"message":"input is too large to process","type":"exceed_context_size_error","n_prompt_tokens":900,"n_ctx":512}}`))
+				return
+			}
+			resp.Data = append(resp.Data, llm.EmbeddingData{Embedding: llm.FakeEmbedding(text, 16)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	pipeline, _, _, chunkRepo, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	pipeline.embedder = llm.NewEmbeddingsClient(server.URL, "dummy-key", "fake-embedding", 16)
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	pipeline.SetFailureStore(storage.NewIndexFailureRepo(db))
+
+	// The oversized section is first in its batch, so the chunks after it must keep their own embeddings
+	notePath := filepath.Join(personalDir, "garden.md")
+	sections := []string{
+		"## Pasted log\n\nAn oversized paste of build output that the embedding model cannot take in one request.",
+		"## Tomatoes\n\nTomatoes grow in the north bed and need watering every morning in July.",
+		"## Compost\n\nThe compost heap is turned every two weeks and covered before the autumn rain.",
+	}
+	if err := os.WriteFile(notePath, []byte("# Garden\n\n"+strings.Join(sections, "\n\n")), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	failures, err := pipeline.IndexFailures(ctx, 10)
+	if err != nil {
+		t.Fatalf("IndexFailures() error = %v", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("IndexFailures() = %+v, want the oversized chunk", failures)
+	}
+	if got := failures[0]; got.RelPath != "garden.md" || got.Reason != storage.IndexFailureContextSize || got.ChunkIndex != 0 || !strings.Contains(got.Error, "runes") {
+		t.Errorf("IndexFailures()[0] = %+v, want chunk 0 of garden.md skipped for context size", got)
+	}
+	chunks, err := chunkRepo.ListByNote(ctx, failures[0].NoteID)
+	if err != nil {
+		t.Fatalf("ListByNote() error = %v", err)
+	}
+	if len(chunks) != 2 || chunks[0].ChunkIndex != 1 || !strings.Contains(chunks[0].Text, "Tomatoes") || !strings.Contains(chunks[1].Text, "compost") {
+		t.Fatalf("indexed chunks = %+v, want the tomatoes and compost chunks", chunks)
+	}
+
+	stats, err := pipeline.GetIndexingCoverageStats(ctx, "fake-embedding")
+	if err != nil {
+		t.Fatalf("GetIndexingCoverageStats() error = %v", err)
+	}
+	if stats.ChunksSkipped != 1 || stats.ChunksSkippedReasons[storage.IndexFailureContextSize] != 1 || stats.ChunksAttempted != 3 {
+		t.Errorf("stats = skipped %d %v, attempted %d; want 1 context_size skip of 3", stats.ChunksSkipped, stats.ChunksSkippedReasons, stats.ChunksAttempted)
+	}
+
+	// Fixing the note clears its failures on the next run
+	sections[0] = "## Pasted log\n\nA short summary of the build output instead of the whole paste."
+	if err := os.WriteFile(notePath, []byte("# Garden\n\n"+strings.Join(sections, "\n\n")), 0644); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	failures, err = pipeline.IndexFailures(ctx, 10)
+	if err != nil {
+		t.Fatalf("IndexFailures() error = %v", err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("IndexFailures() = %+v, want none after the fix", failures)
+	}
+}
@@ -0,0 +1,66 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// FolderStats describes the indexed content of a folder, for tuning how it is chunked.
+type FolderStats struct {
+	// Prefix is the normalized folder prefix.
+	Prefix string
+	// Notes is the number of indexed notes under the prefix.
+	Notes int
+	// Chunks is the number of chunks of those notes.
+	Chunks int
+	// AvgChunkTokens is the mean estimated token count per chunk (TokensPerRune).
+	AvgChunkTokens float64
+	// LastIndexedAt is when a note under the prefix was last indexed (nil without notes).
+	LastIndexedAt *time.Time
+	// WindowDays is the period retrievals are counted over.
+	WindowDays int
+	// Retrievals is the number of answers the folder's notes contributed to within the window.
+	Retrievals int
+	// RetrievalsPerDay is Retrievals averaged over the window.
+	RetrievalsPerDay float64
+}
+
+// FolderStats summarizes the notes of a vault under a folder prefix, counting retrievals
+// over the last windowDays days. The prefix matches whole folder names, as in DeleteFolder.
+func (p *Pipeline) FolderStats(ctx context.Context, vaultID int, prefix string, windowDays int) (FolderStats, error) {
+	prefix = normalizeFolderPrefix(prefix)
+	if prefix == "" {
+		return FolderStats{}, fmt.Errorf("folder prefix is required")
+	}
+	if windowDays <= 0 {
+		return FolderStats{}, fmt.Errorf("window must be at least one day")
+	}
+
+	since := time.Now().AddDate(0, 0, -windowDays)
+	stored, err := p.noteRepo.FolderChunkStats(ctx, vaultID, prefix, since)
+	if err != nil {
+		return FolderStats{}, fmt.Errorf("failed to get folder chunk stats: %w", err)
+	}
+
+	stats := FolderStats{
+		Prefix:           prefix,
+		Notes:            stored.NoteCount,
+		Chunks:           stored.ChunkCount,
+		LastIndexedAt:    stored.LastIndexedAt,
+		WindowDays:       windowDays,
+		Retrievals:       stored.Retrievals,
+		RetrievalsPerDay: roundTo(float64(stored.Retrievals)/float64(windowDays), 2),
+	}
+	if stored.ChunkCount > 0 {
+		stats.AvgChunkTokens = roundTo(float64(stored.TextChars)/TokensPerRune/float64(stored.ChunkCount), 1)
+	}
+	return stats, nil
+}
+
+// roundTo rounds value to the given number of decimal places.
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,181 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/storage"
+)
+
+// frontmatterDateLayouts are the date formats accepted for the created and modified keys.
+var frontmatterDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// excludedByFrontmatter reports whether a note opts out of indexing through its YAML
+// frontmatter, with `rag: false` or `private: true`. Only top-level scalar keys are read;
+// anything that is not a recognizable boolean leaves the note indexed.
+func excludedByFrontmatter(content []byte) bool {
+	frontmatter, ok := extractFrontmatter(content)
+	if !ok {
+		return false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(frontmatter))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Indented lines belong to nested values
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		enabled, ok := parseFrontmatterBool(value)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "rag":
+			if !enabled {
+				return true
+			}
+		case "private":
+			if enabled {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractMetadata returns the aliases and created/modified dates of a note from its YAML
+// frontmatter. Aliases come from the `aliases` (or `alias`) key, written as an inline list,
+// a scalar, or a block list; dates come from `created` (or `date`) and `modified` (or
+// `updated`). Dates without a zone are UTC, and unparseable dates are ignored.
+func extractMetadata(content []byte) storage.NoteMetadata {
+	var meta storage.NoteMetadata
+	frontmatter, ok := extractFrontmatter(content)
+	if !ok {
+		return meta
+	}
+
+	addAlias := func(alias string) {
+		alias = strings.Trim(strings.TrimSpace(alias), `"'`)
+		if alias != "" {
+			meta.Aliases = append(meta.Aliases, alias)
+		}
+	}
+	inList := false
+	scanner := bufio.NewScanner(bytes.NewReader(frontmatter))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inList {
+			if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+				addAlias(item)
+				continue
+			}
+			inList = false
+		}
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value, _, _ = strings.Cut(value, " #")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aliases", "alias":
+			if value == "" {
+				inList = true
+				continue
+			}
+			if inline, ok := strings.CutPrefix(value, "["); ok {
+				for _, alias := range strings.Split(strings.TrimSuffix(inline, "]"), ",") {
+					addAlias(alias)
+				}
+				continue
+			}
+			addAlias(value)
+		case "created", "date":
+			if t, ok := parseFrontmatterDate(value); ok {
+				meta.Created = t
+			}
+		case "modified", "updated":
+			if t, ok := parseFrontmatterDate(value); ok {
+				meta.Modified = t
+			}
+		}
+	}
+	return meta
+}
+
+// parseFrontmatterDate parses a YAML date or timestamp scalar, ignoring quotes.
+func parseFrontmatterDate(value string) (time.Time, bool) {
+	value = strings.Trim(value, `"'`)
+	for _, layout := range frontmatterDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// extractFrontmatter returns the YAML block between the opening and closing `---` lines
+// at the start of content.
+func extractFrontmatter(content []byte) ([]byte, bool) {
+	frontmatter, _, ok := splitFrontmatter(content)
+	return frontmatter, ok
+}
+
+// splitFrontmatter returns the YAML block at the start of content and the body after its
+// closing `---` line.
+func splitFrontmatter(content []byte) (frontmatter, body []byte, ok bool) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	rest, ok := bytes.CutPrefix(content, []byte("---\n"))
+	if !ok {
+		if rest, ok = bytes.CutPrefix(content, []byte("---\r\n")); !ok {
+			return nil, content, false
+		}
+	}
+	for offset := 0; offset <= len(rest); {
+		end := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if string(bytes.TrimRight(line, " \t\r")) == "---" {
+			if end < 0 {
+				return rest[:offset], nil, true
+			}
+			return rest[:offset], rest[offset+end+1:], true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return nil, content, false
+}
+
+// parseFrontmatterBool parses a YAML boolean scalar, ignoring quotes and trailing comments.
+func parseFrontmatterBool(value string) (bool, bool) {
+	value, _, _ = strings.Cut(value, " #")
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, true
+	case "false", "no", "off":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,134 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/storage"
+)
+
+func TestExcludedByFrontmatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "no frontmatter", content: "# Note\n\nrag: false", want: false},
+		{name: "rag false", content: "---\ntags: [health]\nrag: false\n---\n# Note", want: true},
+		{name: "private true", content: "---\nprivate: true\n---\n# Note", want: true},
+		{name: "quoted and commented", content: "---\nprivate: \"yes\" # keep out\n---\n", want: true},
+		{name: "case and CRLF", content: "---\r\nRAG: False\r\n---\r\n# Note", want: true},
+		{name: "byte order mark", content: "\ufeff---\nprivate: true\n---\n", want: true},
+		{name: "rag true", content: "---\nrag: true\nprivate: false\n---\n# Note", want: false},
+		{name: "nested key ignored", content: "---\nsettings:\n  private: true\n---\n# Note", want: false},
+		{name: "not a boolean", content: "---\nprivate: sometimes\n---\n# Note", want: false},
+		{name: "unclosed frontmatter", content: "---\nprivate: true\n# Note", want: false},
+		{name: "flag after frontmatter", content: "---\ntitle: Note\n---\nprivate: true", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := excludedByFrontmatter([]byte(tt.content)); got != tt.want {
+				t.Errorf("excludedByFrontmatter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		content string
+		want    storage.NoteMetadata
+	}{
+		{name: "no frontmatter", content: "# Note\n\ncreated: 2024-01-01"},
+		{name: "inline aliases and dates", content: "---\naliases: [Tomatoes, \"Garden beds\"]\ncreated: 2024-01-15\nmodified: 2024-03-01T10:30:00Z\n---\n# Note",
+			want: storage.NoteMetadata{Aliases: []string{"Tomatoes", "Garden beds"}, Created: day(2024, 1, 15), Modified: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)}},
+		{name: "block list and alternate keys", content: "---\nalias:\n  - Tomatoes\n  - 'Beds'\ndate: \"2023-12-31\"\nupdated: 2024-02-02 08:15\n---\n",
+			want: storage.NoteMetadata{Aliases: []string{"Tomatoes", "Beds"}, Created: day(2023, 12, 31), Modified: time.Date(2024, 2, 2, 8, 15, 0, 0, time.UTC)}},
+		{name: "scalar alias", content: "---\naliases: Tomatoes # plants\n---\n", want: storage.NoteMetadata{Aliases: []string{"Tomatoes"}}},
+		{name: "invalid date ignored", content: "---\ncreated: last spring\n---\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractMetadata([]byte(tt.content))
+			if !slices.Equal(got.Aliases, tt.want.Aliases) || !got.Created.Equal(tt.want.Created) || !got.Modified.Equal(tt.want.Modified) {
+				t.Errorf("extractMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkMarkdown_SkipsFrontmatter(t *testing.T) {
+	content := "---\ntags: [garden]\ncreated: 2024-01-15\n---\n# Tomatoes\n\nWater the tomato beds every morning before it gets hot outside."
+	title, chunks, err := NewGoldmarkChunker().ChunkMarkdown([]byte(content), "tomatoes.md")
+	if err != nil {
+		t.Fatalf("ChunkMarkdown() error = %v", err)
+	}
+	if title != "Tomatoes" {
+		t.Errorf("ChunkMarkdown() title = %q, want Tomatoes", title)
+	}
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Text, "created:") || strings.Contains(chunk.HeadingPath, "tags") {
+			t.Errorf("ChunkMarkdown() chunk %+v contains frontmatter", chunk)
+		}
+	}
+}
+
+func TestPipeline_FrontmatterExclusion(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	writeNote := func(relPath, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(personalDir, relPath), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	writeNote("diary.md", "# Diary\n\nA long day at the clinic.")
+	writeNote("secret.md", "---\nprivate: true\n---\n# Secret\n\nThe safe code.")
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	if _, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "secret.md"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("excluded note indexed, GetByVaultAndPath() error = %v", err)
+	}
+	diary, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "diary.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, diary.ID)
+	if len(chunkIDs) == 0 {
+		t.Fatal("diary.md has no chunks")
+	}
+
+	// Flagging an indexed note removes it on the next pass
+	writeNote("diary.md", "---\nrag: false\n---\n# Diary\n\nA long day at the clinic.")
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	if _, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "diary.md"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("flagged note still indexed, GetByVaultAndPath() error = %v", err)
+	}
+	if ids, _ := chunkRepo.ListIDsByNote(ctx, diary.ID); len(ids) != 0 {
+		t.Errorf("chunks after flagging = %v, want none", ids)
+	}
+	if points, _ := store.Retrieve(ctx, "notes", chunkIDs); len(points) != 0 {
+		t.Errorf("vector points after flagging = %d, want none", len(points))
+	}
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// HydrateChunk recovers a chunk whose SQLite row or text is missing by re-reading and
+// re-chunking its source note. Chunk IDs hash the chunk text, so a chunk is only recovered
+// while the note on disk still produces it; otherwise storage.ErrNotFound is returned.
+// The SQLite row is repaired inline: a missing text is restored, and a missing row is
+// inserted when its note is still indexed. Repair failures are logged, not returned.
+func (p *Pipeline) HydrateChunk(ctx context.Context, vaultID int, relPath, chunkID string) (*storage.ChunkRecord, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	absPath := p.vaultManager.AbsPath(vaultID, relPath)
+	if absPath == "" {
+		return nil, fmt.Errorf("failed to resolve absolute path for vault %d, relPath %s", vaultID, relPath)
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", absPath, err)
+	}
+	// A note flagged since it was indexed must not leak back into answers
+	if excludedByFrontmatter(content) {
+		return nil, storage.ErrNotFound
+	}
+	_, chunks, err := p.chunker.ChunkFile(content, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to chunk file: %w", err)
+	}
+
+	var record *storage.ChunkRecord
+	for _, chunk := range chunks {
+		if generateStableChunkID(vaultID, relPath, chunk.HeadingPath, chunk.Text) == chunkID {
+			record = &storage.ChunkRecord{
+				ID:             chunkID,
+				ChunkIndex:     chunk.Index,
+				HeadingPath:    chunk.HeadingPath,
+				Text:           chunk.Text,
+				OverlapRunes:   chunk.OverlapRunes,
+				ChunkerVersion: ChunkerVersion,
+			}
+			break
+		}
+	}
+	if record == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	// The text is recovered either way; the repair only saves the next query the trip to disk
+	existing, err := p.chunkRepo.GetByID(ctx, chunkID)
+	switch {
+	case err == nil:
+		record.NoteID = existing.NoteID
+		if err := p.chunkRepo.RestoreText(ctx, chunkID, record.Text); err != nil {
+			logger.WarnContext(ctx, "failed to restore chunk text", "chunk_id", chunkID, "error", err)
+		}
+	case errors.Is(err, storage.ErrNotFound):
+		note, err := p.noteRepo.GetByVaultAndPath(ctx, vaultID, relPath)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot restore chunk row without its note", "chunk_id", chunkID, "rel_path", relPath, "error", err)
+			break
+		}
+		record.NoteID = note.ID
+		record.EmbeddingModel = p.embedder.Model
+		if err := p.chunkRepo.Insert(ctx, record); err != nil {
+			logger.WarnContext(ctx, "failed to restore chunk row", "chunk_id", chunkID, "error", err)
+		}
+	default:
+		logger.WarnContext(ctx, "failed to check chunk row", "chunk_id", chunkID, "error", err)
+	}
+
+	logger.InfoContext(ctx, "hydrated chunk from source note", "chunk_id", chunkID, "rel_path", relPath)
+	return record, nil
+}
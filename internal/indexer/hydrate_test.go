@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+func TestPipeline_HydrateChunk(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	notePath := filepath.Join(personalDir, "garden.md")
+	if err := os.WriteFile(notePath, []byte("# Garden\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "garden.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	ids, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+	if len(ids) != 1 {
+		t.Fatalf("chunks = %v, want 1", ids)
+	}
+	original, _ := chunkRepo.GetByID(ctx, ids[0])
+
+	assertHydrated := func(t *testing.T) {
+		t.Helper()
+		chunk, err := pipeline.HydrateChunk(ctx, personal.ID, "garden.md", ids[0])
+		if err != nil {
+			t.Fatalf("HydrateChunk() error = %v", err)
+		}
+		if chunk.Text != original.Text || chunk.NoteID != note.ID {
+			t.Errorf("HydrateChunk() = %+v, want the indexed chunk", chunk)
+		}
+		repaired, err := chunkRepo.GetByID(ctx, ids[0])
+		if err != nil || repaired.Text != original.Text {
+			t.Errorf("chunk row after hydration = %+v, %v, want it repaired", repaired, err)
+		}
+	}
+
+	t.Run("missing text", func(t *testing.T) {
+		if _, err := chunkRepo.DB().Exec("DELETE FROM chunk_texts"); err != nil {
+			t.Fatalf("delete chunk_texts: %v", err)
+		}
+		assertHydrated(t)
+	})
+
+	t.Run("missing row", func(t *testing.T) {
+		if err := chunkRepo.DeleteByNote(ctx, note.ID); err != nil {
+			t.Fatalf("DeleteByNote() error = %v", err)
+		}
+		assertHydrated(t)
+	})
+
+	t.Run("note changed on disk", func(t *testing.T) {
+		if err := os.WriteFile(notePath, []byte("# Garden\n\nPeppers replaced the tomatoes."), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+		if _, err := pipeline.HydrateChunk(ctx, personal.ID, "garden.md", ids[0]); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("HydrateChunk() error = %v, want ErrNotFound", err)
+		}
+	})
+}
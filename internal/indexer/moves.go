@@ -0,0 +1,190 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// applyMoves detects notes whose folder was moved or renamed since the last index run and
+// moves them with their chunks and vectors (see moveNote), so IndexNote sees them as
+// unchanged instead of re-embedding them as new notes.
+//
+// A scanned file with no note at its path is a move when an indexed note of the same vault
+// is missing from the scan, has the same file name, and has the same content hash.
+// Renamed files are not matched and are indexed as new notes. Failures are logged and the
+// affected files fall back to regular indexing. Returns the number of notes moved.
+func (p *Pipeline) applyMoves(ctx context.Context, scannedFiles []vault.ScannedFile) int {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	byVault := make(map[int][]vault.ScannedFile)
+	for _, file := range scannedFiles {
+		byVault[file.VaultID] = append(byVault[file.VaultID], file)
+	}
+
+	moved := 0
+	for vaultID, files := range byVault {
+		notes, err := p.noteRepo.ListByVault(ctx, vaultID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to list notes for move detection", "vault_id", vaultID, "error", err)
+			continue
+		}
+
+		scannedPaths := make(map[string]struct{}, len(files))
+		for _, file := range files {
+			scannedPaths[file.RelPath] = struct{}{}
+		}
+		indexedPaths := make(map[string]struct{}, len(notes))
+		// Notes no longer found at their path, keyed by file name and content hash
+		missing := make(map[moveKey][]storage.NoteRecord)
+		for _, note := range notes {
+			indexedPaths[note.RelPath] = struct{}{}
+			if _, ok := scannedPaths[note.RelPath]; !ok {
+				key := moveKey{name: path.Base(note.RelPath), hash: note.Hash}
+				missing[key] = append(missing[key], note)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		for _, file := range files {
+			if _, ok := indexedPaths[file.RelPath]; ok {
+				continue
+			}
+			content, err := os.ReadFile(file.AbsPath)
+			if err != nil {
+				continue // IndexNote reports unreadable files
+			}
+			key := moveKey{name: path.Base(file.RelPath), hash: fmt.Sprintf("%x", sha256.Sum256(content))}
+			candidates := missing[key]
+			if len(candidates) == 0 {
+				continue
+			}
+			note := candidates[0]
+			missing[key] = candidates[1:]
+
+			if err := p.moveNote(ctx, note, file.RelPath, filepath.ToSlash(file.Folder)); err != nil {
+				logger.WarnContext(ctx, "failed to apply note move, reindexing instead",
+					"from", note.RelPath,
+					"to", file.RelPath,
+					"error", err,
+				)
+				continue
+			}
+			moved++
+			logger.DebugContext(ctx, "note moved", "from", note.RelPath, "to", file.RelPath)
+		}
+	}
+
+	if moved > 0 {
+		logger.InfoContext(ctx, "applied note moves without re-embedding", "count", moved)
+	}
+	return moved
+}
+
+// moveKey identifies a note across a folder move: same file name, same content.
+type moveKey struct {
+	name string
+	hash string
+}
+
+// moveNote points a note and its vector payloads at a new path. Chunk IDs include the
+// path, so the chunks are re-keyed to the IDs IndexNote would give them at the new path:
+// their stored vectors are written under the new IDs, the SQLite rows renamed, and the
+// old points deleted, which keeps hydration and vector reuse working for moved notes
+// without re-embedding. Until the rows are renamed every step is undone on failure, so
+// both stores keep the old path.
+func (p *Pipeline) moveNote(ctx context.Context, note storage.NoteRecord, relPath, folder string) error {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	chunks, err := p.chunkRepo.ListByNote(ctx, note.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+	oldIDs := make([]string, len(chunks))
+	renamed := make(map[string]string, len(chunks))
+	for i, chunk := range chunks {
+		// Without its text the new ID cannot be derived; reindexing recovers the chunk
+		if chunk.Text == "" {
+			return fmt.Errorf("chunk %s has no text", chunk.ID)
+		}
+		oldIDs[i] = chunk.ID
+		renamed[chunk.ID] = generateStableChunkID(note.VaultID, relPath, chunk.HeadingPath, chunk.Text)
+	}
+
+	collection := p.chunkCollection(note.VaultID)
+	if note.Tier == storage.TierCold && p.coldCollection != "" {
+		collection = p.coldCollection
+	}
+	points, err := p.vectorStore.Retrieve(ctx, collection, oldIDs)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve chunk vectors: %w", err)
+	}
+	if len(points) != len(oldIDs) {
+		return fmt.Errorf("found %d of %d chunk vectors", len(points), len(oldIDs))
+	}
+	newIDs := make([]string, 0, len(points))
+	for i, point := range points {
+		point.ID = renamed[point.ID]
+		point.Meta["rel_path"] = relPath
+		point.Meta["folder"] = folder
+		points[i] = point
+		newIDs = append(newIDs, point.ID)
+	}
+
+	// Old and new points coexist until the SQLite rows point at the new ones
+	if len(points) > 0 {
+		if err := p.vectorStore.Upsert(ctx, collection, points); err != nil {
+			_ = p.vectorStore.Delete(ctx, collection, newIDs)
+			return fmt.Errorf("failed to write re-keyed chunk vectors: %w", err)
+		}
+	}
+	payload := map[string]any{"rel_path": relPath, "folder": folder}
+	restore := func() {
+		_ = p.vectorStore.Delete(ctx, collection, newIDs)
+		if p.noteCollection != "" {
+			_ = p.vectorStore.SetPayload(ctx, p.noteCollection, []string{note.ID}, map[string]any{"rel_path": note.RelPath, "folder": note.Folder})
+		}
+	}
+	if p.noteCollection != "" {
+		if err := p.vectorStore.SetPayload(ctx, p.noteCollection, []string{note.ID}, payload); err != nil {
+			restore()
+			return fmt.Errorf("failed to update note centroid payload: %w", err)
+		}
+	}
+
+	if err := p.noteRepo.Move(ctx, note.ID, relPath, folder); err != nil {
+		restore()
+		return fmt.Errorf("failed to move note: %w", err)
+	}
+	if err := p.chunkRepo.RenameIDs(ctx, renamed); err != nil {
+		restore()
+		if revertErr := p.noteRepo.Move(ctx, note.ID, note.RelPath, note.Folder); revertErr != nil {
+			return fmt.Errorf("failed to rename chunks: %w (and failed to restore note path: %v)", err, revertErr)
+		}
+		return fmt.Errorf("failed to rename chunks: %w", err)
+	}
+
+	// The note is moved either way; old points left behind no longer match any chunk row
+	if len(oldIDs) > 0 {
+		if err := p.vectorStore.Delete(ctx, collection, oldIDs); err != nil {
+			logger.WarnContext(ctx, "failed to delete chunk vectors of the previous path", "rel_path", relPath, "error", err)
+		}
+	}
+	p.recordEvent(ctx, storage.IndexEventRecord{
+		Type:            storage.IndexEventNoteMoved,
+		VaultID:         note.VaultID,
+		NoteID:          note.ID,
+		RelPath:         relPath,
+		PreviousRelPath: note.RelPath,
+	})
+	return nil
+}
@@ -0,0 +1,188 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestPipeline_IndexAll_FolderRename(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{filepath.Join(personalDir, "projects"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(personalDir, "projects", "garden.md"), []byte("# Garden\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	before, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "projects/garden.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, before.ID)
+	original, _ := store.Retrieve(ctx, "notes", chunkIDs)
+
+	// Rename the folder
+	if err := os.Rename(filepath.Join(personalDir, "projects"), filepath.Join(personalDir, "archive")); err != nil {
+		t.Fatalf("Failed to rename folder: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() after rename error = %v", err)
+	}
+
+	notes, _ := noteRepo.ListByVault(ctx, personal.ID)
+	if len(notes) != 1 || notes[0].ID != before.ID || notes[0].RelPath != "archive/garden.md" || notes[0].Folder != "archive" {
+		t.Fatalf("notes after rename = %+v, want the original note at archive/garden.md", notes)
+	}
+	// Chunks are re-keyed to the stable IDs of the new path, keeping their vectors
+	chunks, _ := chunkRepo.ListByNote(ctx, before.ID)
+	if len(chunks) != len(chunkIDs) || len(original) != len(chunkIDs) {
+		t.Fatalf("%d chunks and %d points before the rename, want %d", len(chunks), len(original), len(chunkIDs))
+	}
+	afterIDs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		afterIDs[i] = chunk.ID
+		if want := generateStableChunkID(personal.ID, "archive/garden.md", chunk.HeadingPath, chunk.Text); chunk.ID != want {
+			t.Errorf("chunk ID after rename = %s, want %s", chunk.ID, want)
+		}
+	}
+	if stale, _ := store.Retrieve(ctx, "notes", chunkIDs); len(stale) != 0 {
+		t.Errorf("%d points left under the old chunk IDs", len(stale))
+	}
+
+	points, _ := store.Retrieve(ctx, "notes", afterIDs)
+	centroids, _ := store.Retrieve(ctx, "notes_notes", []string{before.ID})
+	for _, point := range append(points, centroids...) {
+		if point.Meta["rel_path"] != "archive/garden.md" || point.Meta["folder"] != "archive" {
+			t.Errorf("point %s payload = %v, want rel_path archive/garden.md and folder archive", point.ID, point.Meta)
+		}
+		if point.Meta["note_title"] != "Garden" {
+			t.Errorf("point %s lost other payload keys: %v", point.ID, point.Meta)
+		}
+	}
+	if len(points) != len(chunkIDs) || len(centroids) != 1 {
+		t.Fatalf("retrieved %d chunk points and %d centroids, want %d and 1", len(points), len(centroids), len(chunkIDs))
+	}
+	// Not re-embedded: the vector and its provenance are the original ones
+	if points[0].Meta["run_id"] != original[0].Meta["run_id"] || !slices.Equal(points[0].Vec, original[0].Vec) {
+		t.Errorf("point after rename = %v, want the original vector from run %v", points[0].Meta, original[0].Meta["run_id"])
+	}
+}
+
+func TestPipeline_MovedNoteHydratesAndReusesChunks(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{filepath.Join(personalDir, "projects"), workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	tomatoes := "## Tomatoes\n\nTomatoes grow in the north bed and need watering every morning in July."
+	write := func(relPath, beans string) {
+		t.Helper()
+		content := "# Garden\n\n" + tomatoes + "\n\n" + beans
+		if err := os.WriteFile(filepath.Join(personalDir, relPath), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	write("projects/garden.md", "## Beans\n\nBeans climb the fence by the shed and are picked twice a week.")
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	if err := os.Rename(filepath.Join(personalDir, "projects"), filepath.Join(personalDir, "archive")); err != nil {
+		t.Fatalf("Failed to rename folder: %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() after rename error = %v", err)
+	}
+	note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "archive/garden.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	moved, _ := chunkRepo.ListByNote(ctx, note.ID)
+	if len(moved) < 2 {
+		t.Fatalf("moved note has %d chunks, want at least 2", len(moved))
+	}
+
+	// A chunk of the moved note is recovered from the file at its new path
+	hydrated, err := pipeline.HydrateChunk(ctx, personal.ID, "archive/garden.md", moved[0].ID)
+	if err != nil {
+		t.Fatalf("HydrateChunk() error = %v", err)
+	}
+	if hydrated.Text != moved[0].Text {
+		t.Errorf("HydrateChunk() text = %q, want %q", hydrated.Text, moved[0].Text)
+	}
+
+	// Editing one section after the move keeps the other section's vector
+	tomatoesPoint, _ := store.Retrieve(ctx, "notes", []string{moved[0].ID})
+	write("archive/garden.md", "## Beans\n\nBeans moved to the south fence this year.")
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() after edit error = %v", err)
+	}
+	reused, _ := store.Retrieve(ctx, "notes", []string{moved[0].ID})
+	if len(tomatoesPoint) != 1 || len(reused) != 1 || reused[0].Meta["run_id"] != tomatoesPoint[0].Meta["run_id"] {
+		t.Errorf("unchanged chunk after edit = %v, want the vector reused from run %v", reused, tomatoesPoint)
+	}
+}
+
+// newIntegrationPipeline builds a pipeline on real SQLite, an in-memory vector store, and
+// the fake embeddings server, with chunks in "notes" and note centroids in "notes_notes".
+func newIntegrationPipeline(t *testing.T, tmpDir, personalDir, workDir string) (*Pipeline, *vault.Manager, *storage.NoteRepo, *storage.ChunkRepo, *vectorstore.MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := storage.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: personalDir}, {Name: "work", Path: workDir}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	const vectorSize = 16
+	fakeLLM := llm.NewFakeServer(vectorSize)
+	t.Cleanup(fakeLLM.Close)
+	embedder := llm.NewEmbeddingsClient(fakeLLM.URL, "dummy-key", "fake-embedding", vectorSize)
+
+	store := vectorstore.NewMemoryStore()
+	for _, collection := range []string{"notes", "notes_notes"} {
+		if err := store.EnsureCollection(ctx, collection, vectorSize); err != nil {
+			t.Fatalf("EnsureCollection() error = %v", err)
+		}
+	}
+
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+	pipeline := NewPipeline(vaultManager, noteRepo, chunkRepo, nil, nil, embedder, store, "notes", "", "notes_notes", nil)
+	return pipeline, vaultManager, noteRepo, chunkRepo, store
+}
@@ -0,0 +1,51 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// ReindexNote indexes one note written outside an index pass (e.g. through the note API),
+// then records the index checksums and advances the index epoch so verification and cached
+// folder lists include the change.
+func (p *Pipeline) ReindexNote(ctx context.Context, vaultID int, relPath string) error {
+	folder := path.Dir(relPath)
+	if folder == "." {
+		folder = ""
+	}
+	if err := p.IndexNote(ctx, vaultID, relPath, folder); err != nil {
+		return err
+	}
+
+	// An updated note leaves its previous chunk texts behind unless another chunk shares them
+	if _, err := p.chunkRepo.PruneTexts(ctx); err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to prune unreferenced chunk texts", "error", err)
+	}
+	p.recordChecksums(ctx)
+	p.advanceEpoch()
+	return nil
+}
+
+// DeleteNote removes one note from the index with its chunks and vector points, and returns
+// the number of chunks removed. The file itself is not touched. Returns an error wrapping
+// storage.ErrNotFound if the note is not indexed.
+func (p *Pipeline) DeleteNote(ctx context.Context, vaultID int, relPath string) (int, error) {
+	note, err := p.noteRepo.GetByVaultAndPath(ctx, vaultID, relPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get note: %w", err)
+	}
+	chunks, err := p.deleteNote(ctx, *note)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := p.chunkRepo.PruneTexts(ctx); err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to prune unreferenced chunk texts", "error", err)
+	}
+	p.recordChecksums(ctx)
+	p.advanceEpoch()
+	return chunks, nil
+}
@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+func TestPipeline_ReindexAndDeleteNote(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	personal, _ := vaultManager.VaultByName("personal")
+
+	absPath := filepath.Join(personalDir, "Garden", "beds.md")
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(absPath, []byte("# Beds\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	epoch := pipeline.IndexEpoch()
+	if err := pipeline.ReindexNote(ctx, personal.ID, "Garden/beds.md"); err != nil {
+		t.Fatalf("ReindexNote() error = %v", err)
+	}
+	note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "Garden/beds.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	if note.Folder != "Garden" {
+		t.Errorf("note folder = %q, want Garden", note.Folder)
+	}
+	if pipeline.IndexEpoch() == epoch {
+		t.Error("ReindexNote() did not advance the index epoch")
+	}
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+	if len(chunkIDs) == 0 {
+		t.Fatal("ReindexNote() stored no chunks")
+	}
+
+	chunks, err := pipeline.DeleteNote(ctx, personal.ID, "Garden/beds.md")
+	if err != nil {
+		t.Fatalf("DeleteNote() error = %v", err)
+	}
+	if chunks != len(chunkIDs) {
+		t.Errorf("DeleteNote() = %d chunks, want %d", chunks, len(chunkIDs))
+	}
+	if _, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "Garden/beds.md"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetByVaultAndPath() after DeleteNote() error = %v, want ErrNotFound", err)
+	}
+	if points, _ := store.Retrieve(ctx, "notes", chunkIDs); len(points) != 0 {
+		t.Errorf("%d chunk points left after DeleteNote()", len(points))
+	}
+
+	if _, err := pipeline.DeleteNote(ctx, personal.ID, "Garden/beds.md"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteNote() of an unindexed note error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,47 @@
+package indexer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// embeddingBatch is a batch of chunk texts embedded in one request, with its result.
+type embeddingBatch struct {
+	texts   []string
+	indices []int // Chunk indices of texts
+	start   int   // First chunk index (for logging)
+	end     int   // Last chunk index (for logging)
+
+	embeddings [][]float32
+	err        error
+}
+
+// SetEmbeddingParallelism sets how many embedding batches of a note are requested at once,
+// normally the embedding server's slot count. n <= 1 embeds batches one at a time.
+// Call it before indexing starts.
+func (p *Pipeline) SetEmbeddingParallelism(n int) {
+	p.embedParallelism = n
+}
+
+// embedBatches embeds each batch with embedTextsWithRetry, storing the result on the
+// batch. Up to embedParallelism batches are in flight at once.
+func (p *Pipeline) embedBatches(ctx context.Context, batches []embeddingBatch, relPath string, logger *slog.Logger) {
+	if p.embedParallelism <= 1 || len(batches) < 2 {
+		for i := range batches {
+			batches[i].embeddings, batches[i].err = p.embedTextsWithRetry(ctx, batches[i].texts, relPath, logger)
+		}
+		return
+	}
+
+	slots := make(chan struct{}, p.embedParallelism)
+	var wg sync.WaitGroup
+	for i := range batches {
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			batches[i].embeddings, batches[i].err = p.embedTextsWithRetry(ctx, batches[i].texts, relPath, logger)
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPipeline_EmbeddingParallelism(t *testing.T) {
+	ctx := context.Background()
+
+	// Enough sections for several embedding batches
+	var note strings.Builder
+	for i := range 12 {
+		fmt.Fprintf(&note, "## Section %d\n\nNotes about topic %d with enough words to stand on their own as a chunk.\n\n", i, i)
+	}
+
+	// indexNote returns the vectors stored per chunk ID
+	indexNote := func(parallelism int) map[string][]float32 {
+		tmpDir := t.TempDir()
+		personalDir := filepath.Join(tmpDir, "personal")
+		workDir := filepath.Join(tmpDir, "work")
+		for _, dir := range []string{personalDir, workDir} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(personalDir, "topics.md"), []byte(note.String()), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+
+		pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+		pipeline.SetEmbeddingParallelism(parallelism)
+		personal, _ := vaultManager.VaultByName("personal")
+		if err := pipeline.IndexNote(ctx, personal.ID, "topics.md", ""); err != nil {
+			t.Fatalf("IndexNote() error = %v", err)
+		}
+
+		stored, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "topics.md")
+		if err != nil {
+			t.Fatalf("GetByVaultAndPath() error = %v", err)
+		}
+		ids, err := chunkRepo.ListIDsByNote(ctx, stored.ID)
+		if err != nil {
+			t.Fatalf("ListIDsByNote() error = %v", err)
+		}
+		points, err := store.Retrieve(ctx, "notes", ids)
+		if err != nil || len(points) != len(ids) {
+			t.Fatalf("Retrieve() = %d points (err %v), want %d", len(points), err, len(ids))
+		}
+		vectors := make(map[string][]float32, len(points))
+		for _, point := range points {
+			vectors[point.ID] = point.Vec
+		}
+		return vectors
+	}
+
+	sequential := indexNote(1)
+	parallel := indexNote(4)
+	if len(sequential) < 6 {
+		t.Fatalf("indexed %d chunks, want enough for several batches", len(sequential))
+	}
+	// Each chunk keeps the embedding of its own text
+	if !maps.EqualFunc(parallel, sequential, slices.Equal) {
+		t.Errorf("vectors with parallel embedding differ from sequential embedding")
+	}
+}
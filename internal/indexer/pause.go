@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+const (
+	// DefaultAutoIndexPause is the pause timeout used when a request does not name one.
+	DefaultAutoIndexPause = 30 * time.Minute
+	// MaxAutoIndexPause caps a pause so a forgotten one cannot leave the index stale for good.
+	MaxAutoIndexPause = 24 * time.Hour
+)
+
+// autoIndexPause records until when automatic indexing is paused. The zero value is not paused.
+type autoIndexPause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// PauseAutoIndexing suspends automatic indexing (scheduled jobs such as the weekly digest)
+// during bulk edits in a vault, e.g. mass renames or plugin migrations, so they do not index
+// half-finished changes. Indexing resumes by itself after timeout (<= 0 uses
+// DefaultAutoIndexPause, capped at MaxAutoIndexPause). Pausing again replaces the timeout.
+// Explicit re-index requests are not affected. Returns when the pause ends.
+func (p *Pipeline) PauseAutoIndexing(ctx context.Context, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		timeout = DefaultAutoIndexPause
+	}
+	timeout = min(timeout, MaxAutoIndexPause)
+
+	p.pause.mu.Lock()
+	p.pause.until = time.Now().Add(timeout)
+	until := p.pause.until
+	p.pause.mu.Unlock()
+
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "automatic indexing paused", "timeout", timeout.String(), "until", until)
+	return until
+}
+
+// ResumeAutoIndexing ends a pause early. Reports whether indexing was paused.
+func (p *Pipeline) ResumeAutoIndexing(ctx context.Context) bool {
+	p.pause.mu.Lock()
+	wasPaused := time.Now().Before(p.pause.until)
+	p.pause.until = time.Time{}
+	p.pause.mu.Unlock()
+
+	if wasPaused {
+		contextutil.LoggerFromContext(ctx).InfoContext(ctx, "automatic indexing resumed")
+	}
+	return wasPaused
+}
+
+// AutoIndexingPausedUntil returns when the current pause ends, or the zero time when
+// automatic indexing is running.
+func (p *Pipeline) AutoIndexingPausedUntil() time.Time {
+	p.pause.mu.Lock()
+	defer p.pause.mu.Unlock()
+	if !time.Now().Before(p.pause.until) {
+		return time.Time{}
+	}
+	return p.pause.until
+}
+
+// AutoIndexingPaused reports whether automatic indexing is paused.
+func (p *Pipeline) AutoIndexingPaused() bool {
+	return !p.AutoIndexingPausedUntil().IsZero()
+}
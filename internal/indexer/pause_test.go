@@ -0,0 +1,43 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPipeline_PauseAutoIndexing(t *testing.T) {
+	ctx := context.Background()
+	pipeline := &Pipeline{}
+
+	if pipeline.AutoIndexingPaused() {
+		t.Fatal("AutoIndexingPaused() = true before any pause")
+	}
+
+	// Zero and oversized timeouts fall back to the default and the cap
+	until := pipeline.PauseAutoIndexing(ctx, 0)
+	if remaining := time.Until(until); remaining <= DefaultAutoIndexPause-time.Minute || remaining > DefaultAutoIndexPause {
+		t.Errorf("default pause remaining = %v, want about %v", remaining, DefaultAutoIndexPause)
+	}
+	until = pipeline.PauseAutoIndexing(ctx, 7*24*time.Hour)
+	if remaining := time.Until(until); remaining > MaxAutoIndexPause {
+		t.Errorf("capped pause remaining = %v, want at most %v", remaining, MaxAutoIndexPause)
+	}
+	if !pipeline.AutoIndexingPaused() || !pipeline.AutoIndexingPausedUntil().Equal(until) {
+		t.Errorf("AutoIndexingPausedUntil() = %v, want %v", pipeline.AutoIndexingPausedUntil(), until)
+	}
+
+	if !pipeline.ResumeAutoIndexing(ctx) {
+		t.Error("ResumeAutoIndexing() = false, want true while paused")
+	}
+	if pipeline.AutoIndexingPaused() || pipeline.ResumeAutoIndexing(ctx) {
+		t.Error("pipeline still paused after ResumeAutoIndexing()")
+	}
+
+	// Indexing resumes by itself once the timeout passes
+	pipeline.PauseAutoIndexing(ctx, 20*time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+	if pipeline.AutoIndexingPaused() || !pipeline.AutoIndexingPausedUntil().IsZero() {
+		t.Error("pipeline still paused after the timeout")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
@@ -26,30 +28,82 @@ type Pipeline struct {
 	vaultManager *vault.Manager
 	noteRepo     storage.NoteStore
 	chunkRepo    storage.ChunkStore
-	embedder     *llm.EmbeddingsClient
-	vectorStore  vectorstore.VectorStore
-	collection   string
-	chunker      *GoldmarkChunker
+	timingRepo   storage.IndexTimingStore
+	checksumRepo storage.IndexChecksumStore
+	// eventRepo records index changes for client cache invalidation (nil disables it, see SetEventStore).
+	eventRepo storage.IndexEventStore
+	// failureRepo records chunks left out of the index (nil disables it, see SetFailureStore).
+	failureRepo storage.IndexFailureStore
+	// excludedFolderRepo holds the folders DeleteFolder removed, which index runs skip (nil disables it, see SetExcludedFolderStore).
+	excludedFolderRepo storage.ExcludedFolderStore
+	embedder           *llm.EmbeddingsClient
+	vectorStore        vectorstore.VectorStore
+	collection         string
+	// collectionPerVault stores each vault's chunks in its own collection (see SetCollectionPerVault).
+	collectionPerVault bool
+	// rebuildTargets maps chunk collection names to the collections a rebuild writes instead (nil outside Rebuild).
+	rebuildTargets map[string]string
+	// coldCollection holds chunks of notes moved to cold storage (empty disables tiering).
+	coldCollection string
+	// noteCollection holds one centroid embedding per note for two-stage retrieval (empty disables it).
+	noteCollection string
+	// shadowIndex holds the SQLite side of a blue/green rebuild (nil disables Rebuild).
+	shadowIndex shadowIndex
+	chunker     *GoldmarkChunker
+	// pause suspends automatic indexing during bulk vault edits (see PauseAutoIndexing).
+	pause autoIndexPause
+	// epoch advances when indexing changes the notes (see IndexEpoch).
+	epoch atomic.Uint64
+	// embedParallelism bounds the embedding batches of a note in flight (see SetEmbeddingParallelism).
+	embedParallelism int
+	// progress tracks the running indexing pass (see IndexProgress).
+	progress *indexProgress
 }
 
 // NewPipeline creates a new indexing pipeline.
+// timingRepo may be nil, in which case per-file durations are only logged.
+// checksumRepo may be nil, in which case no integrity checksums are recorded.
+// noteCollection receives note centroid embeddings when non-empty.
+// shadowIndex may be nil, in which case force reindexing clears the live index instead of rebuilding beside it.
 func NewPipeline(
 	vaultManager *vault.Manager,
 	noteRepo storage.NoteStore,
 	chunkRepo storage.ChunkStore,
+	timingRepo storage.IndexTimingStore,
+	checksumRepo storage.IndexChecksumStore,
 	embedder *llm.EmbeddingsClient,
 	vectorStore vectorstore.VectorStore,
 	collection string,
+	coldCollection string,
+	noteCollection string,
+	shadowIndex *storage.ShadowIndex,
 ) *Pipeline {
-	return &Pipeline{
-		vaultManager: vaultManager,
-		noteRepo:     noteRepo,
-		chunkRepo:    chunkRepo,
-		embedder:     embedder,
-		vectorStore:  vectorStore,
-		collection:   collection,
-		chunker:      NewGoldmarkChunker(),
-	}
+	p := &Pipeline{
+		vaultManager:   vaultManager,
+		noteRepo:       noteRepo,
+		chunkRepo:      chunkRepo,
+		timingRepo:     timingRepo,
+		checksumRepo:   checksumRepo,
+		embedder:       embedder,
+		vectorStore:    vectorStore,
+		collection:     collection,
+		coldCollection: coldCollection,
+		noteCollection: noteCollection,
+		chunker:        NewGoldmarkChunker(),
+		progress:       &indexProgress{},
+	}
+	if shadowIndex != nil {
+		p.shadowIndex = shadowIndex
+	}
+	return p
+}
+
+// SetChunkOverlap makes each chunk repeat up to runes runes of trailing sentences from the
+// chunk before it (0 disables overlap, larger values are capped at MaxChunkOverlap).
+// Changing it changes chunk texts, so notes only pick it up when re-indexed.
+// Call it before indexing starts.
+func (p *Pipeline) SetChunkOverlap(runes int) {
+	p.chunker.overlap = min(max(runes, 0), MaxChunkOverlap)
 }
 
 // generateStableChunkID generates a deterministic chunk ID based on vault_id, rel_path, heading_path, and chunk text.
@@ -73,6 +127,7 @@ var ErrChunkSkipped = errors.New("chunk skipped due to context size limit")
 // if the server returns an "input is too large" error.
 // This function recursively splits batches in half when encountering size limit errors.
 // If a single chunk is too large, it returns ErrChunkSkipped and the caller should skip that chunk.
+// Chunks skipped while splitting a batch get a nil embedding, so the result stays aligned with texts.
 // Note: The embedding model (granite-embedding-278m-multilingual) has n_ctx=512 tokens.
 func (p *Pipeline) embedTextsWithRetry(ctx context.Context, texts []string, relPath string, logger *slog.Logger) ([][]float32, error) {
 	if len(texts) == 0 {
@@ -152,7 +207,7 @@ func (p *Pipeline) embedTextsWithRetry(ctx context.Context, texts []string, relP
 	if err != nil {
 		// If first half failed with skip error, continue with second half
 		if errors.Is(err, ErrChunkSkipped) {
-			firstEmbeddings = nil
+			firstEmbeddings = make([][]float32, len(firstHalf))
 		} else {
 			return nil, fmt.Errorf("failed to embed first half: %w", err)
 		}
@@ -162,20 +217,16 @@ func (p *Pipeline) embedTextsWithRetry(ctx context.Context, texts []string, relP
 	if err != nil {
 		// If second half failed with skip error, return first half results
 		if errors.Is(err, ErrChunkSkipped) {
-			secondEmbeddings = nil
+			secondEmbeddings = make([][]float32, len(secondHalf))
 		} else {
 			return nil, fmt.Errorf("failed to embed second half: %w", err)
 		}
 	}
 
-	// Combine results (handling nil slices from skipped chunks)
-	result := make([][]float32, 0)
-	if firstEmbeddings != nil {
-		result = append(result, firstEmbeddings...)
-	}
-	if secondEmbeddings != nil {
-		result = append(result, secondEmbeddings...)
-	}
+	// Combine results, keeping the nil embeddings of skipped chunks in place
+	result := make([][]float32, 0, len(texts))
+	result = append(result, firstEmbeddings...)
+	result = append(result, secondEmbeddings...)
 
 	return result, nil
 }
@@ -186,6 +237,9 @@ func (p *Pipeline) embedTextsWithRetry(ctx context.Context, texts []string, relP
 // folder is the folder path (already calculated from relPath during scanning).
 func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder string) error {
 	logger := contextutil.LoggerFromContext(ctx)
+	timing := &storage.IndexTimingRecord{VaultID: vaultID, RelPath: relPath}
+	fileStart := time.Now()
+	phaseStart := fileStart
 
 	// Get absolute path
 	absPath := p.vaultManager.AbsPath(vaultID, relPath)
@@ -198,6 +252,11 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 	if err != nil {
 		return fmt.Errorf("failed to read file %s: %w", absPath, err)
 	}
+	// The modification time dates the note for digests and modified filters
+	var fileModified time.Time
+	if info, err := os.Stat(absPath); err == nil {
+		fileModified = info.ModTime()
+	}
 
 	// Compute SHA256 hash
 	hash := sha256.Sum256(content)
@@ -209,24 +268,66 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 		return fmt.Errorf("failed to check existing note: %w", err)
 	}
 
+	// Notes in folders removed from the index stay out of it, like those opted out via frontmatter
+	excluded, err := p.folderExcluded(ctx, vaultID, folder)
+	if err != nil {
+		return err
+	}
+	if excluded {
+		if existingNote != nil {
+			chunks, err := p.deleteNote(ctx, *existingNote)
+			if err != nil {
+				return fmt.Errorf("failed to remove note in excluded folder from index: %w", err)
+			}
+			logger.InfoContext(ctx, "removed note in excluded folder from index", "rel_path", relPath, "chunks", chunks)
+			return nil
+		}
+		logger.DebugContext(ctx, "skipping note in excluded folder", "rel_path", relPath)
+		return nil
+	}
+
+	// Notes opted out via frontmatter are not indexed, and lose their index entries once flagged
+	if excludedByFrontmatter(content) {
+		if existingNote != nil {
+			chunks, err := p.deleteNote(ctx, *existingNote)
+			if err != nil {
+				return fmt.Errorf("failed to remove excluded note from index: %w", err)
+			}
+			logger.InfoContext(ctx, "removed note excluded by frontmatter", "rel_path", relPath, "chunks", chunks)
+			return nil
+		}
+		logger.DebugContext(ctx, "skipping note excluded by frontmatter", "rel_path", relPath)
+		return nil
+	}
+
 	// Skip re-indexing if hash matches (unless force is enabled)
 	// Force reindex is handled at the IndexAll level by clearing all data first
 	if existingNote != nil && existingNote.Hash == hashHex {
 		logger.DebugContext(ctx, "skipping unchanged file", "rel_path", relPath, "hash", hashHex)
+		// Notes indexed before file times were recorded get theirs without re-indexing
+		if !fileModified.IsZero() {
+			if err := p.noteRepo.SetFileModified(ctx, existingNote.ID, fileModified); err != nil {
+				logger.WarnContext(ctx, "failed to record file modification time", "rel_path", relPath, "error", err)
+			}
+		}
 		return nil
 	}
-
-	// Extract filename for title fallback
-	filename := filepath.Base(relPath)
+	timing.SizeBytes = int64(len(content))
+	timing.ReadMs = time.Since(phaseStart).Milliseconds()
 
 	// Chunk content
-	title, chunks, err := p.chunker.ChunkMarkdown(content, filename)
+	phaseStart = time.Now()
+	title, chunks, err := p.chunker.ChunkFile(content, relPath)
 	if err != nil {
-		return fmt.Errorf("failed to chunk markdown: %w", err)
+		return fmt.Errorf("failed to chunk file: %w", err)
 	}
+	timing.ChunkMs = time.Since(phaseStart).Milliseconds()
+	timing.ChunkCount = len(chunks)
 
 	if len(chunks) == 0 {
 		logger.WarnContext(ctx, "no chunks generated", "rel_path", relPath)
+		timing.TotalMs = time.Since(fileStart).Milliseconds()
+		p.recordTiming(ctx, timing)
 		return nil
 	}
 
@@ -236,17 +337,12 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 		folder = filepath.ToSlash(folder)
 	}
 
-	// Get vault name for metadata by checking known vault names
-	vaultName := ""
-	for name := range map[string]struct{}{"personal": {}, "work": {}} {
-		if v, err := p.vaultManager.VaultByName(name); err == nil && v.ID == vaultID {
-			vaultName = name
-			break
-		}
-	}
-	if vaultName == "" {
+	// Get vault name for metadata
+	vaultName := "unknown" // Fallback
+	if v, err := p.vaultManager.VaultByID(vaultID); err == nil {
+		vaultName = v.Name
+	} else {
 		logger.WarnContext(ctx, "vault name not found for vault ID", "vault_id", vaultID)
-		vaultName = "unknown" // Fallback
 	}
 
 	// Generate or get note ID
@@ -258,43 +354,54 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 	}
 
 	// Upsert note record
+	phaseStart = time.Now()
 	noteRecord := &storage.NoteRecord{
-		ID:      noteID,
-		VaultID: vaultID,
-		RelPath: relPath,
-		Folder:  folder,
-		Title:   title,
-		Hash:    hashHex,
+		ID:           noteID,
+		VaultID:      vaultID,
+		RelPath:      relPath,
+		Folder:       folder,
+		Title:        title,
+		Hash:         hashHex,
+		FileModified: fileModified,
 	}
 	if err := p.noteRepo.Upsert(ctx, noteRecord); err != nil {
 		return fmt.Errorf("failed to upsert note: %w", err)
 	}
+	tags := extractTags(content)
+	if IsCanvas(relPath) {
+		tags = extractTags(canvasCardText(content))
+	}
+	if err := p.noteRepo.SetTags(ctx, noteRecord.ID, tags); err != nil {
+		return fmt.Errorf("failed to set note tags: %w", err)
+	}
+	meta := extractMetadata(content)
+	if err := p.noteRepo.SetMetadata(ctx, noteRecord.ID, meta); err != nil {
+		return fmt.Errorf("failed to set note metadata: %w", err)
+	}
 
-	// If existing note, delete old chunks
-	if existingNote != nil {
-		oldChunkIDs, err := p.chunkRepo.ListIDsByNote(ctx, noteID)
-		if err != nil {
-			return fmt.Errorf("failed to list old chunk IDs: %w", err)
-		}
+	timing.UpsertMs = time.Since(phaseStart).Milliseconds()
 
-		if len(oldChunkIDs) > 0 {
-			// Delete from Qdrant
-			if err := p.vectorStore.Delete(ctx, p.collection, oldChunkIDs); err != nil {
-				logger.WarnContext(ctx, "failed to delete old chunks from Qdrant", "error", err, "count", len(oldChunkIDs))
-				// Continue anyway - we'll overwrite with new chunks
-			}
+	// Chunk IDs are stable, so a chunk whose heading path and text are unchanged keeps its ID
+	chunkIDs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = generateStableChunkID(vaultID, relPath, chunk.HeadingPath, chunk.Text)
+	}
 
-			// Delete from SQLite
-			if err := p.chunkRepo.DeleteByNote(ctx, noteID); err != nil {
-				return fmt.Errorf("failed to delete old chunks from SQLite: %w", err)
-			}
+	// The previous chunks stay searchable until the new ones are embedded, and unchanged
+	// chunks keep their stored vectors instead of being embedded again
+	phaseStart = time.Now()
+	var previous previousChunks
+	if existingNote != nil {
+		previous, err = p.loadPreviousChunks(ctx, *existingNote, chunkIDs)
+		if err != nil {
+			return err
 		}
 	}
-
-	// Extract chunk texts for embedding
-	chunkTexts := make([]string, len(chunks))
-	for i, chunk := range chunks {
-		chunkTexts[i] = chunk.Text
+	pending := make([]int, 0, len(chunks)) // Indices of chunks to embed
+	for i := range chunks {
+		if _, ok := previous.points[chunkIDs[i]]; !ok {
+			pending = append(pending, i)
+		}
 	}
 
 	// Generate embeddings in batches to avoid exceeding server batch size limits.
@@ -306,18 +413,18 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 	const maxBatchChars = 1000 // Max total runes per batch (target ~350-400 tokens, ~4 chars/token)
 	embeddings := make([][]float32, 0, len(chunks))
 
-	i := 0
-	chunkToEmbeddingMap := make(map[int]int) // Maps chunk index to embedding index
-	embeddingIdx := 0
-	for i < len(chunkTexts) {
+	var batches []embeddingBatch
+	k := 0
+	for k < len(pending) {
 		// Build batch respecting both count and character limits
 		batch := make([]string, 0, maxBatchCount)
 		batchIndices := make([]int, 0, maxBatchCount) // Track original chunk indices
 		batchChars := 0
-		startIdx := i
+		startIdx := pending[k]
 
-		for i < len(chunkTexts) && len(batch) < maxBatchCount {
-			chunkText := chunkTexts[i]
+		for k < len(pending) && len(batch) < maxBatchCount {
+			i := pending[k]
+			chunkText := chunks[i].Text
 			chunkRunes := utf8.RuneCountInString(chunkText)
 
 			// If adding this chunk would exceed character limit, stop
@@ -338,39 +445,63 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 			batch = append(batch, chunkText)
 			batchIndices = append(batchIndices, i)
 			batchChars += chunkRunes
-			i++
+			k++
 		}
 
 		if len(batch) == 0 {
 			// Shouldn't happen, but safety check
 			break
 		}
+		batches = append(batches, embeddingBatch{texts: batch, indices: batchIndices, start: startIdx, end: batchIndices[len(batchIndices)-1]})
+	}
 
-		// Generate embeddings with automatic batch size reduction on "input too large" errors
-		batchEmbeddings, err := p.embedTextsWithRetry(ctx, batch, relPath, logger)
-		if err != nil {
+	// Generate embeddings with automatic batch size reduction on "input too large" errors,
+	// up to embedParallelism batches at a time
+	p.embedBatches(ctx, batches, relPath, logger)
+
+	chunkToEmbeddingMap := make(map[int]int) // Maps chunk index to embedding index
+	for i, id := range chunkIDs {
+		if point, ok := previous.points[id]; ok {
+			chunkToEmbeddingMap[i] = len(embeddings)
+			embeddings = append(embeddings, point.Vec)
+		}
+	}
+	var failures []storage.IndexFailureRecord
+	for _, batch := range batches {
+		if err := batch.err; err != nil {
 			// Check if this is a skip error - if so, skip all chunks in this batch
 			if errors.Is(err, ErrChunkSkipped) {
 				logger.WarnContext(ctx, "batch skipped due to context size limit",
 					"rel_path", relPath,
-					"batch_start", startIdx,
-					"batch_end", i-1,
-					"batch_size", len(batch),
+					"batch_start", batch.start,
+					"batch_end", batch.end,
+					"batch_size", len(batch.texts),
 				)
 				// Don't add any embeddings for this batch - chunks will be skipped
 				continue
 			}
-			return fmt.Errorf("failed to generate embeddings for batch %d-%d: %w", startIdx, i-1, err)
+			for _, chunkIdx := range batch.indices {
+				failures = append(failures, storage.IndexFailureRecord{
+					ChunkIndex: chunks[chunkIdx].Index,
+					Reason:     storage.IndexFailureEmbedding,
+					Error:      err.Error(),
+				})
+			}
+			p.recordFailures(ctx, noteID, relPath, failures)
+			// The previous chunks are still indexed; clear the hash so the next pass retries the note
+			noteRecord.Hash = ""
+			if upsertErr := p.noteRepo.Upsert(ctx, noteRecord); upsertErr != nil {
+				logger.WarnContext(ctx, "failed to mark note for retry", "rel_path", relPath, "error", upsertErr)
+			}
+			return fmt.Errorf("failed to generate embeddings for batch %d-%d: %w", batch.start, batch.end, err)
 		}
 
 		// Map chunk indices to embedding indices
-		// If we got fewer embeddings than chunks, the last N chunks were skipped
-		// (recursive splitting preserves order, so skipped chunks are at the end)
-		numEmbeddings := len(batchEmbeddings)
-		for j, chunkIdx := range batchIndices {
-			if j < numEmbeddings {
-				chunkToEmbeddingMap[chunkIdx] = embeddingIdx
-				embeddingIdx++
+		// Skipped chunks have a nil embedding (recursive splitting keeps the batch order)
+		for j, chunkIdx := range batch.indices {
+			if j < len(batch.embeddings) && batch.embeddings[j] != nil {
+				chunkToEmbeddingMap[chunkIdx] = len(embeddings)
+				embeddings = append(embeddings, batch.embeddings[j])
 			} else {
 				// This chunk was skipped (no embedding generated)
 				logger.DebugContext(ctx, "chunk skipped during batch processing",
@@ -379,10 +510,12 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 				)
 			}
 		}
-
-		embeddings = append(embeddings, batchEmbeddings...)
 	}
 
+	timing.EmbedMs = time.Since(phaseStart).Milliseconds()
+	embeddedAt := time.Now().UTC()
+	runID := runIDFromContext(ctx)
+
 	// Handle skipped chunks - we may have fewer embeddings than chunks
 	if len(embeddings) < len(chunks) {
 		skippedCount := len(chunks) - len(embeddings)
@@ -398,12 +531,18 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 	// Only include chunks that have embeddings (skip those that were too large)
 	chunkRecords := make([]*storage.ChunkRecord, 0, len(embeddings))
 	points := make([]vectorstore.Point, 0, len(embeddings))
+	written := make(map[string]bool, len(chunks)) // Chunk IDs in chunkRecords
 
 	for i, chunk := range chunks {
 		// Check if this chunk has an embedding
 		embIdx, hasEmbedding := chunkToEmbeddingMap[i]
 		if !hasEmbedding {
-			// This chunk was skipped - don't include it
+			// This chunk was skipped - don't include it, but keep a record of it
+			failures = append(failures, storage.IndexFailureRecord{
+				ChunkIndex: chunk.Index,
+				Reason:     storage.IndexFailureContextSize,
+				Error:      fmt.Sprintf("%s (%d runes)", ErrChunkSkipped, utf8.RuneCountInString(chunk.Text)),
+			})
 			continue
 		}
 
@@ -417,20 +556,35 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 			continue
 		}
 
-		// Generate stable chunk ID based on deterministic hash
-		chunkID := generateStableChunkID(vaultID, relPath, chunk.HeadingPath, chunk.Text)
+		// Identical chunks under the same heading share a stable ID; the first one is kept
+		chunkID := chunkIDs[i]
+		if written[chunkID] {
+			logger.DebugContext(ctx, "skipping duplicate chunk", "rel_path", relPath, "chunk_index", chunk.Index)
+			continue
+		}
+		written[chunkID] = true
+		chunkEmbeddedAt, chunkRunID := embeddedAt, runID
+		if point, ok := previous.points[chunkID]; ok {
+			// Reused vectors keep the provenance of the run that embedded them
+			chunkEmbeddedAt, chunkRunID = reusedProvenance(point, embeddedAt, runID)
+		}
 
 		// Create chunk record
 		chunkRecords = append(chunkRecords, &storage.ChunkRecord{
-			ID:          chunkID,
-			NoteID:      noteID,
-			ChunkIndex:  chunk.Index,
-			HeadingPath: chunk.HeadingPath,
-			Text:        chunk.Text,
+			ID:             chunkID,
+			NoteID:         noteID,
+			ChunkIndex:     chunk.Index,
+			HeadingPath:    chunk.HeadingPath,
+			Text:           chunk.Text,
+			OverlapRunes:   chunk.OverlapRunes,
+			ChunkerVersion: ChunkerVersion,
+			EmbeddingModel: p.embedder.Model,
+			EmbeddedAt:     chunkEmbeddedAt,
+			RunID:          chunkRunID,
 		})
 
 		// Create vector point with metadata
-		points = append(points, vectorstore.Point{
+		point := vectorstore.Point{
 			ID:  chunkID,
 			Vec: embeddings[embIdx],
 			Meta: map[string]any{
@@ -442,8 +596,38 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 				"heading_path": chunk.HeadingPath,
 				"chunk_index":  chunk.Index,
 				"note_title":   title,
+				// Provenance, mirrored from the SQLite chunk row
+				"chunker_version": ChunkerVersion,
+				"embedding_model": p.embedder.Model,
+				"embedded_at":     chunkEmbeddedAt.Format(time.RFC3339),
+				"run_id":          chunkRunID,
 			},
-		})
+		}
+		if chunk.OverlapRunes > 0 {
+			point.Meta["overlap_runes"] = chunk.OverlapRunes
+		}
+		// Frontmatter metadata, only when the note has it
+		if len(tags) > 0 {
+			point.Meta["tags"] = tags
+		}
+		if len(meta.Aliases) > 0 {
+			point.Meta["aliases"] = meta.Aliases
+		}
+		if !meta.Created.IsZero() {
+			point.Meta["created"] = meta.Created.UTC().Format(time.RFC3339)
+		}
+		if !meta.Modified.IsZero() {
+			point.Meta["modified"] = meta.Modified.UTC().Format(time.RFC3339)
+		}
+		points = append(points, point)
+	}
+
+	// Replace the previous chunks, deleting the vectors of those the note no longer has
+	phaseStart = time.Now()
+	if existingNote != nil {
+		if err := p.replacePreviousChunks(ctx, *existingNote, previous, written); err != nil {
+			return err
+		}
 	}
 
 	// Insert chunks into SQLite (only chunks that have embeddings)
@@ -455,10 +639,31 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 		}
 
 		// Batch upsert points to Qdrant
-		if err := p.vectorStore.Upsert(ctx, p.collection, points); err != nil {
-			return fmt.Errorf("failed to upsert vectors: %w", err)
+		if err := p.vectorStore.Upsert(ctx, p.chunkCollection(vaultID), points); err != nil {
+			var batchErr *vectorstore.UpsertBatchError
+			if !errors.As(err, &batchErr) || !batchErr.Partial() {
+				return fmt.Errorf("failed to upsert vectors: %w", err)
+			}
+			// Keep the batches that were written searchable, and clear the hash so the
+			// next index pass re-indexes the note and fills in the missing points
+			logger.WarnContext(ctx, "some vector batches failed to upsert, note will be retried",
+				"rel_path", relPath,
+				"failed_points", len(batchErr.FailedIDs()),
+				"failed_batches", len(batchErr.Failed),
+				"total_points", batchErr.Total,
+				"error", err,
+			)
+			noteRecord.Hash = ""
+			if err := p.noteRepo.Upsert(ctx, noteRecord); err != nil {
+				return fmt.Errorf("failed to mark note for retry: %w", err)
+			}
 		}
 	}
+	p.updateNoteCentroid(ctx, noteID, points)
+	// Replaces the failures of the previous run, clearing them when every chunk was indexed
+	p.recordFailures(ctx, noteID, relPath, failures)
+	timing.UpsertMs += time.Since(phaseStart).Milliseconds()
+	timing.TotalMs = time.Since(fileStart).Milliseconds()
 
 	logger.InfoContext(ctx, "indexed note",
 		"rel_path", relPath,
@@ -466,15 +671,52 @@ func (p *Pipeline) IndexNote(ctx context.Context, vaultID int, relPath, folder s
 		"indexed_chunks", len(chunkRecords),
 		"skipped_chunks", len(chunks)-len(chunkRecords),
 		"title", title,
+		"read_ms", timing.ReadMs,
+		"chunk_ms", timing.ChunkMs,
+		"embed_ms", timing.EmbedMs,
+		"upsert_ms", timing.UpsertMs,
+		"total_ms", timing.TotalMs,
+		"bottleneck", timing.Bottleneck(),
 	)
+	p.recordTiming(ctx, timing)
+	eventType := storage.IndexEventNoteAdded
+	if existingNote != nil {
+		eventType = storage.IndexEventNoteUpdated
+	}
+	p.recordEvent(ctx, storage.IndexEventRecord{Type: eventType, VaultID: vaultID, NoteID: noteID, RelPath: relPath})
 	return nil
 }
 
+// recordTiming persists the per-phase durations for a file when a timing store is configured.
+// Failures are logged and never fail indexing.
+func (p *Pipeline) recordTiming(ctx context.Context, timing *storage.IndexTimingRecord) {
+	if p.timingRepo == nil {
+		return
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+	if err := p.timingRepo.Record(ctx, timing); err != nil {
+		logger.WarnContext(ctx, "failed to record index timing", "rel_path", timing.RelPath, "error", err)
+	}
+}
+
+// SlowestFiles returns up to limit files ordered by their most recent indexing time, slowest first.
+func (p *Pipeline) SlowestFiles(ctx context.Context, limit int) ([]storage.IndexTimingRecord, error) {
+	if p.timingRepo == nil {
+		return nil, fmt.Errorf("index timing store is not configured")
+	}
+	timings, err := p.timingRepo.ListSlowest(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slowest files: %w", err)
+	}
+	return timings, nil
+}
+
 // ClearAll deletes all indexed data (chunks, notes, and Qdrant points).
 // This is used for force reindexing.
 func (p *Pipeline) ClearAll(ctx context.Context) error {
 	logger := contextutil.LoggerFromContext(ctx)
 	logger.InfoContext(ctx, "clearing all indexed data")
+	defer p.advanceEpoch()
 
 	// Get all chunk IDs from database before deleting
 	chunkIDs, err := p.chunkRepo.GetAllIDs(ctx)
@@ -482,13 +724,33 @@ func (p *Pipeline) ClearAll(ctx context.Context) error {
 		return fmt.Errorf("failed to get chunk IDs: %w", err)
 	}
 
-	// Delete all points from Qdrant
+	// Delete all points from Qdrant (every vault collection, since chunk IDs do not say which)
 	if len(chunkIDs) > 0 {
-		if err := p.vectorStore.Delete(ctx, p.collection, chunkIDs); err != nil {
-			logger.WarnContext(ctx, "failed to delete some points from Qdrant", "error", err)
-			// Continue even if Qdrant deletion fails
-		} else {
-			logger.InfoContext(ctx, "deleted points from Qdrant", "count", len(chunkIDs))
+		for _, collection := range p.ChunkCollections() {
+			if err := p.vectorStore.Delete(ctx, collection, chunkIDs); err != nil {
+				logger.WarnContext(ctx, "failed to delete some points from Qdrant", "collection", collection, "error", err)
+				// Continue even if Qdrant deletion fails
+			} else {
+				logger.InfoContext(ctx, "deleted points from Qdrant", "collection", collection, "count", len(chunkIDs))
+			}
+		}
+		if p.coldCollection != "" {
+			if err := p.vectorStore.Delete(ctx, p.coldCollection, chunkIDs); err != nil {
+				logger.WarnContext(ctx, "failed to delete some points from cold collection", "error", err)
+			}
+		}
+	}
+
+	// Delete note centroids (note IDs change on reindex, so stale centroids would never be overwritten)
+	if p.noteCollection != "" {
+		noteIDs, err := p.noteRepo.GetAllIDs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get note IDs: %w", err)
+		}
+		if len(noteIDs) > 0 {
+			if err := p.vectorStore.Delete(ctx, p.noteCollection, noteIDs); err != nil {
+				logger.WarnContext(ctx, "failed to delete some note centroids", "error", err)
+			}
 		}
 	}
 
@@ -503,6 +765,7 @@ func (p *Pipeline) ClearAll(ctx context.Context) error {
 		return fmt.Errorf("failed to delete notes: %w", err)
 	}
 	logger.InfoContext(ctx, "deleted all notes from database")
+	p.recordEvent(ctx, storage.IndexEventRecord{Type: storage.IndexEventIndexCleared})
 
 	return nil
 }
@@ -510,38 +773,121 @@ func (p *Pipeline) ClearAll(ctx context.Context) error {
 // IndexAll scans all vaults and indexes all markdown files.
 // Errors for individual files are logged but don't stop the indexing process.
 func (p *Pipeline) IndexAll(ctx context.Context) error {
-	logger := contextutil.LoggerFromContext(ctx)
+	return p.indexAll(ctx, 0, nil)
+}
+
+// IndexVault scans one vault and indexes its markdown files, like IndexAll.
+func (p *Pipeline) IndexVault(ctx context.Context, vaultID int) error {
+	return p.indexAll(ctx, vaultID, nil)
+}
+
+// IndexAllPrioritized is IndexAll in two phases: the files priority selects are indexed
+// first and made visible to queries, then the remaining files are backfilled.
+func (p *Pipeline) IndexAllPrioritized(ctx context.Context, priority IndexPriority) error {
+	return p.indexAll(ctx, 0, &priority)
+}
+
+// indexAll indexes the files scanned in vaultID (0 = all vaults), in priority phases when
+// priority is non-nil. Progress is reported through IndexProgress.
+func (p *Pipeline) indexAll(ctx context.Context, vaultID int, priority *IndexPriority) (err error) {
+	runID := uuid.New().String()
+	ctx = withRunID(ctx, runID)
+	logger := contextutil.LoggerFromContext(ctx).With("run_id", runID)
+	ctx = context.WithValue(ctx, contextutil.LoggerKey(), logger)
 
-	// Scan all vaults
-	scannedFiles, err := p.vaultManager.ScanAll(ctx)
+	p.progress.start(vaultID)
+	defer func() { p.progress.finish(err) }()
+
+	var scannedFiles []vault.ScannedFile
+	if vaultID == 0 {
+		scannedFiles, err = p.vaultManager.ScanAll(ctx)
+	} else {
+		scannedFiles, err = p.vaultManager.ScanVault(ctx, vaultID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to scan vaults: %w", err)
 	}
+	// Folders removed from the index stay out of it until they are included again
+	scannedFiles, err = p.withoutExcludedFolders(ctx, vaultID, scannedFiles)
+	if err != nil {
+		return err
+	}
+	p.progress.scanned(len(scannedFiles))
+
+	logger.InfoContext(ctx, "starting indexing", "total_files", len(scannedFiles), "vault_id", vaultID)
+	// Even a cancelled or failed pass may have changed notes
+	defer p.advanceEpoch()
+
+	// Moved folders keep their embeddings; only path metadata changes
+	p.applyMoves(ctx, scannedFiles)
+
+	// Notes whose files are gone would otherwise keep answering questions
+	removed, err := p.removeDeletedNotes(ctx, vaultID, scannedFiles)
+	if err != nil {
+		return fmt.Errorf("failed to remove deleted notes: %w", err)
+	}
+	p.progress.removed(removed)
 
-	logger.InfoContext(ctx, "starting indexing", "total_files", len(scannedFiles))
+	phases := [][]vault.ScannedFile{scannedFiles}
+	if priority != nil {
+		vaultNames := make(map[int]string)
+		for _, v := range p.vaultManager.ListVaults() {
+			vaultNames[v.ID] = v.Name
+		}
+		first, rest := priority.split(scannedFiles, vaultNames, time.Now())
+		phases = [][]vault.ScannedFile{first, rest}
+		logger.InfoContext(ctx, "indexing priority files first", "order", priority.Order, "priority_files", len(first), "backfill_files", len(rest))
+	}
 
 	var successCount, errorCount int
 
 	// Index each file
-	for _, file := range scannedFiles {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		if err := p.IndexNote(ctx, file.VaultID, file.RelPath, file.Folder); err != nil {
-			errorCount++
-			logger.ErrorContext(ctx, "failed to index file", "rel_path", file.RelPath, "error", err)
-			// Continue with next file
-			continue
+	for phase, files := range phases {
+		for _, file := range files {
+			// Check for context cancellation
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			err := p.IndexNote(ctx, file.VaultID, file.RelPath, file.Folder)
+			p.progress.fileDone(err)
+			if err != nil {
+				errorCount++
+				logger.ErrorContext(ctx, "failed to index file", "rel_path", file.RelPath, "error", err)
+				// Continue with next file
+				continue
+			}
+
+			successCount++
+		}
+		if priority != nil && phase == 0 {
+			// Fresh notes become queryable (and their folders selectable) before the backfill ends
+			p.advanceEpoch()
+			logger.InfoContext(ctx, "priority files indexed, backfilling remaining files", "priority_files", len(files), "success", successCount, "errors", errorCount)
 		}
+	}
+
+	logger.InfoContext(ctx, "indexing completed",
+		"total_files", len(scannedFiles),
+		"success", successCount,
+		"errors", errorCount,
+		"notes_removed", removed.Notes,
+		"chunks_removed", removed.Chunks,
+		"notes_remove_failed", removed.Failed,
+	)
 
-		successCount++
+	// Reindexed notes leave their previous chunk texts behind unless another chunk shares them
+	if pruned, err := p.chunkRepo.PruneTexts(ctx); err != nil {
+		logger.WarnContext(ctx, "failed to prune unreferenced chunk texts", "error", err)
+	} else if pruned > 0 {
+		logger.InfoContext(ctx, "pruned unreferenced chunk texts", "count", pruned)
 	}
 
-	logger.InfoContext(ctx, "indexing completed", "total_files", len(scannedFiles), "success", successCount, "errors", errorCount)
+	// Record what the index now contains so later tampering can be detected
+	p.recordChecksums(ctx)
+	p.recordReindexCompleted(ctx, vaultID)
 
 	if errorCount > 0 {
 		return fmt.Errorf("indexing completed with %d errors", errorCount)
@@ -2,11 +2,18 @@ package indexer
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
 	storage_mocks "helloworld-ai/internal/storage/mocks"
 	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
 	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
 
 	"go.uber.org/mock/gomock"
@@ -26,9 +33,14 @@ func TestNewPipeline(t *testing.T) {
 		mockVaultManager,
 		mockNoteRepo,
 		mockChunkRepo,
+		nil,
+		nil,
 		mockEmbedder,
 		mockVectorStore,
 		"test-collection",
+		"",
+		"",
+		nil,
 	)
 
 	if pipeline == nil {
@@ -59,9 +71,14 @@ func TestPipeline_IndexNote_Structure(t *testing.T) {
 		mockVaultManager,
 		mockNoteRepo,
 		mockChunkRepo,
+		nil,
+		nil,
 		embedder,
 		mockVectorStore,
 		"test-collection",
+		"",
+		"",
+		nil,
 	)
 
 	// Verify structure
@@ -94,11 +111,18 @@ func TestPipeline_IndexAll_Structure(t *testing.T) {
 		mockVaultManager,
 		mockNoteRepo,
 		mockChunkRepo,
+		nil,
+		nil,
 		embedder,
 		mockVectorStore,
 		"test-collection",
+		"",
+		"",
+		nil,
 	)
 
+	mockChunkRepo.EXPECT().PruneTexts(gomock.Any()).Return(int64(0), nil).AnyTimes()
+
 	// Verify IndexAll method exists and has correct signature
 	ctx := context.Background()
 	// This will fail without proper vault setup, but we're just testing structure
@@ -271,3 +295,208 @@ func TestGenerateStableChunkID_RealWorldPaths(t *testing.T) {
 		})
 	}
 }
+
+func TestPipeline_SlowestFiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTimingRepo := storage_mocks.NewMockIndexTimingStore(ctrl)
+	pipeline := NewPipeline(
+		&vault.Manager{},
+		storage_mocks.NewMockNoteStore(ctrl),
+		storage_mocks.NewMockChunkStore(ctrl),
+		mockTimingRepo,
+		nil,
+		&llm.EmbeddingsClient{},
+		vectorstore_mocks.NewMockVectorStore(ctrl),
+		"test-collection",
+		"",
+		"",
+		nil,
+	)
+
+	want := []storage.IndexTimingRecord{{RelPath: "huge-table.md", ChunkMs: 900, TotalMs: 950}}
+	mockTimingRepo.EXPECT().ListSlowest(gomock.Any(), 10).Return(want, nil)
+
+	got, err := pipeline.SlowestFiles(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("SlowestFiles() error = %v", err)
+	}
+	if len(got) != 1 || got[0].RelPath != "huge-table.md" {
+		t.Errorf("SlowestFiles() = %v, want %v", got, want)
+	}
+
+	// Without a timing store the report is unavailable
+	pipeline.timingRepo = nil
+	if _, err := pipeline.SlowestFiles(context.Background(), 10); err == nil {
+		t.Error("SlowestFiles() expected error without a timing store")
+	}
+}
+
+// partialUpsertStore writes only the first point of the next chunk upsert and reports the
+// rest as a failed batch, like a Qdrant batch that kept failing after retries.
+type partialUpsertStore struct {
+	*vectorstore.MemoryStore
+	failNext bool
+}
+
+func (s *partialUpsertStore) Upsert(ctx context.Context, collection string, points []vectorstore.Point) error {
+	if !s.failNext || collection != "notes" || len(points) < 2 {
+		return s.MemoryStore.Upsert(ctx, collection, points)
+	}
+	s.failNext = false
+	if err := s.MemoryStore.Upsert(ctx, collection, points[:1]); err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(points)-1)
+	for _, point := range points[1:] {
+		ids = append(ids, point.ID)
+	}
+	return &vectorstore.UpsertBatchError{
+		Total:  len(points),
+		Failed: []vectorstore.BatchFailure{{Start: 1, PointIDs: ids, Err: errors.New("unavailable")}},
+	}
+}
+
+func TestPipeline_IndexNote_PartialUpsert(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	content := "# Garden\n\n## Tomatoes\n\nTomatoes grow in the north bed.\n\n## Beans\n\nBeans climb the fence by the shed."
+	if err := os.WriteFile(filepath.Join(personalDir, "garden.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, memoryStore := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	store := &partialUpsertStore{MemoryStore: memoryStore, failNext: true}
+	pipeline.vectorStore = store
+	personal, _ := vaultManager.VaultByName("personal")
+
+	// The note is indexed with the points that were written, and marked for retry
+	if err := pipeline.IndexNote(ctx, personal.ID, "garden.md", ""); err != nil {
+		t.Fatalf("IndexNote() error = %v, want partial upserts to keep the note", err)
+	}
+	note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "garden.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	if note.Hash != "" {
+		t.Errorf("note hash = %q after a partial upsert, want it cleared so the note is retried", note.Hash)
+	}
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+	if len(chunkIDs) < 2 {
+		t.Fatalf("indexed %d chunks, want at least 2 for a partial upsert", len(chunkIDs))
+	}
+	if points, _ := store.Retrieve(ctx, "notes", chunkIDs); len(points) != 1 {
+		t.Errorf("stored %d points after the partial upsert, want 1", len(points))
+	}
+
+	// The next pass re-indexes the note and fills in the missing points
+	if err := pipeline.IndexNote(ctx, personal.ID, "garden.md", ""); err != nil {
+		t.Fatalf("IndexNote() retry error = %v", err)
+	}
+	note, _ = noteRepo.GetByVaultAndPath(ctx, personal.ID, "garden.md")
+	chunkIDs, _ = chunkRepo.ListIDsByNote(ctx, note.ID)
+	if points, _ := store.Retrieve(ctx, "notes", chunkIDs); note.Hash == "" || len(points) != len(chunkIDs) {
+		t.Errorf("after retry: hash %q and %d of %d points, want the note fully indexed", note.Hash, len(points), len(chunkIDs))
+	}
+}
+
+func TestPipeline_PayloadsExcludeChunkText(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	const body = "My bank PIN is 4921 and the spare key is under the flowerpot."
+	if err := os.WriteFile(filepath.Join(personalDir, "secrets.md"), []byte("# Secrets\n\n"+body), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	personal, _ := vaultManager.VaultByName("personal")
+	note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "secrets.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+	if len(chunkIDs) == 0 {
+		t.Fatal("no chunks indexed")
+	}
+
+	// The text lives only in SQLite; the vector store sees IDs and metadata
+	chunk, err := chunkRepo.GetByID(ctx, chunkIDs[0])
+	if err != nil || !strings.Contains(chunk.Text, body) {
+		t.Fatalf("GetByID() = %+v, %v, want the chunk text in SQLite", chunk, err)
+	}
+	for collection, ids := range map[string][]string{"notes": chunkIDs, "notes_notes": {note.ID}} {
+		points, _ := store.Retrieve(ctx, collection, ids)
+		if len(points) == 0 {
+			t.Fatalf("no points in %s", collection)
+		}
+		for _, point := range points {
+			for key, value := range point.Meta {
+				if s, ok := value.(string); ok && strings.Contains(s, "flowerpot") {
+					t.Errorf("%s payload %q of %s contains chunk text: %q", collection, key, point.ID, s)
+				}
+			}
+		}
+	}
+}
+
+func TestPipeline_IndexNote_RecordsFileModifiedOfUnchangedNotes(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	notePath := filepath.Join(personalDir, "garden.md")
+	if err := os.WriteFile(notePath, []byte("# Garden\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	personal, _ := vaultManager.VaultByName("personal")
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	before, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "garden.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+
+	// Same content, different file time: the note is skipped but its file time is recorded
+	modified := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(notePath, modified, modified); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	got, err := noteRepo.ListModifiedBetween(ctx, nil, modified.Add(-time.Hour), modified.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListModifiedBetween() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Note.ID != before.ID || !got[0].ModifiedAt.Equal(modified) {
+		t.Errorf("ListModifiedBetween() = %+v, want garden.md modified at %v", got, modified)
+	}
+}
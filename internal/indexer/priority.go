@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/vault"
+)
+
+// Index orders for startup indexing (INDEX_STARTUP_ORDER).
+const (
+	// IndexOrderScan indexes files in vault scan order.
+	IndexOrderScan = "scan"
+	// IndexOrderRecent indexes the most recently modified files first.
+	IndexOrderRecent = "recent"
+)
+
+// ParseIndexOrder validates an index order name (case-insensitive). Empty selects IndexOrderScan.
+func ParseIndexOrder(name string) (string, error) {
+	switch order := strings.ToLower(strings.TrimSpace(name)); order {
+	case "":
+		return IndexOrderScan, nil
+	case IndexOrderScan, IndexOrderRecent:
+		return order, nil
+	default:
+		return "", fmt.Errorf("invalid index order %q (must be %s or %s)", name, IndexOrderScan, IndexOrderRecent)
+	}
+}
+
+// IndexPriority decides which files a prioritized index run indexes first.
+type IndexPriority struct {
+	// Order is IndexOrderScan or IndexOrderRecent; it orders files within each phase.
+	Order string
+	// RecentWindow puts files modified within it in the priority phase (0 disables it).
+	RecentWindow time.Duration
+	// Folders are "vault/folder" prefixes whose files are in the priority phase.
+	Folders []string
+}
+
+// prioritizedFile is a scanned file with the modification time used to order it.
+type prioritizedFile struct {
+	file    vault.ScannedFile
+	modTime time.Time
+}
+
+// split divides files into the priority phase and the backfill, each ordered by p.Order.
+// vaultNames maps vault IDs to the names used in p.Folders. Files that cannot be
+// stat'ed sort as the oldest.
+func (p IndexPriority) split(files []vault.ScannedFile, vaultNames map[int]string, now time.Time) (first, rest []vault.ScannedFile) {
+	needModTime := p.Order == IndexOrderRecent || p.RecentWindow > 0
+	var priority, backfill []prioritizedFile
+	for _, file := range files {
+		entry := prioritizedFile{file: file}
+		if needModTime {
+			if info, err := os.Stat(file.AbsPath); err == nil {
+				entry.modTime = info.ModTime()
+			}
+		}
+		recent := p.RecentWindow > 0 && !entry.modTime.IsZero() && now.Sub(entry.modTime) <= p.RecentWindow
+		if recent || p.inFolders(vaultNames[file.VaultID], file.RelPath) {
+			priority = append(priority, entry)
+		} else {
+			backfill = append(backfill, entry)
+		}
+	}
+	return p.order(priority), p.order(backfill)
+}
+
+// inFolders reports whether relPath in the named vault falls under one of p.Folders.
+func (p IndexPriority) inFolders(vaultName, relPath string) bool {
+	path := vaultName + "/" + relPath
+	for _, folder := range p.Folders {
+		if strings.HasPrefix(path, strings.TrimSuffix(folder, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// order returns the files of entries sorted by p.Order. Scan order is kept as is.
+func (p IndexPriority) order(entries []prioritizedFile) []vault.ScannedFile {
+	if p.Order == IndexOrderRecent {
+		slices.SortStableFunc(entries, func(a, b prioritizedFile) int {
+			return b.modTime.Compare(a.modTime)
+		})
+	}
+	files := make([]vault.ScannedFile, len(entries))
+	for i, entry := range entries {
+		files[i] = entry.file
+	}
+	return files
+}
@@ -0,0 +1,157 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/vault"
+)
+
+func TestParseIndexOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: IndexOrderScan},
+		{name: "scan", want: IndexOrderScan},
+		{name: " Recent ", want: IndexOrderRecent},
+		{name: "alphabetical", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIndexOrder(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIndexOrder(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseIndexOrder(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexPriority_Split(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"old.md":           90 * 24 * time.Hour,
+		"older.md":         400 * 24 * time.Hour,
+		"today.md":         time.Hour,
+		"yesterday.md":     30 * time.Hour,
+		"projects/plan.md": 200 * 24 * time.Hour,
+	}
+	// Scan order, deliberately not sorted by age
+	relPaths := []string{"old.md", "today.md", "projects/plan.md", "older.md", "yesterday.md", "missing.md"}
+	var files []vault.ScannedFile
+	for _, relPath := range relPaths {
+		absPath := filepath.Join(dir, relPath)
+		if age, ok := ages[relPath]; ok {
+			if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(absPath, []byte("# Note"), 0644); err != nil {
+				t.Fatalf("Failed to write note: %v", err)
+			}
+			if err := os.Chtimes(absPath, now.Add(-age), now.Add(-age)); err != nil {
+				t.Fatalf("Failed to set mtime: %v", err)
+			}
+		}
+		files = append(files, vault.ScannedFile{VaultID: 1, RelPath: relPath, AbsPath: absPath})
+	}
+	vaultNames := map[int]string{1: "personal"}
+
+	paths := func(files []vault.ScannedFile) []string {
+		var out []string
+		for _, file := range files {
+			out = append(out, file.RelPath)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		priority  IndexPriority
+		wantFirst []string
+		wantRest  []string
+	}{
+		{
+			name:     "scan order without priorities",
+			priority: IndexPriority{Order: IndexOrderScan},
+			wantRest: relPaths,
+		},
+		{
+			name:      "recent window and folders in scan order",
+			priority:  IndexPriority{Order: IndexOrderScan, RecentWindow: 7 * 24 * time.Hour, Folders: []string{"personal/projects/"}},
+			wantFirst: []string{"today.md", "projects/plan.md", "yesterday.md"},
+			wantRest:  []string{"old.md", "older.md", "missing.md"},
+		},
+		{
+			name:      "recent order puts the newest first in each phase",
+			priority:  IndexPriority{Order: IndexOrderRecent, RecentWindow: 7 * 24 * time.Hour},
+			wantFirst: []string{"today.md", "yesterday.md"},
+			wantRest:  []string{"old.md", "projects/plan.md", "older.md", "missing.md"},
+		},
+		{
+			name:     "folders of another vault",
+			priority: IndexPriority{Order: IndexOrderScan, Folders: []string{"work/projects"}},
+			wantRest: relPaths,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, rest := tt.priority.split(files, vaultNames, now)
+			if got := paths(first); !slices.Equal(got, tt.wantFirst) {
+				t.Errorf("split() first = %v, want %v", got, tt.wantFirst)
+			}
+			if got := paths(rest); !slices.Equal(got, tt.wantRest) {
+				t.Errorf("split() rest = %v, want %v", got, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestPipeline_IndexAllPrioritized(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	old := time.Now().AddDate(-1, 0, 0)
+	for name, content := range map[string]string{"fresh.md": "# Fresh\n\nToday's standup notes.", "stale.md": "# Stale\n\nLast year's plans."} {
+		path := filepath.Join(personalDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+		if name == "stale.md" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatalf("Failed to set mtime: %v", err)
+			}
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	priority := IndexPriority{Order: IndexOrderRecent, RecentWindow: 24 * time.Hour}
+	if err := pipeline.IndexAllPrioritized(ctx, priority); err != nil {
+		t.Fatalf("IndexAllPrioritized() error = %v", err)
+	}
+
+	// One advance once the priority phase is queryable, one when the run ends
+	if got := pipeline.IndexEpoch(); got != 2 {
+		t.Errorf("IndexEpoch() = %d, want 2", got)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	for _, relPath := range []string{"fresh.md", "stale.md"} {
+		if _, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, relPath); err != nil {
+			t.Errorf("GetByVaultAndPath(%s) error = %v, want the note indexed", relPath, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"sync"
+	"time"
+)
+
+// IndexProgress reports the state of the running indexing pass, or of the last one when
+// none is running. The zero value means no pass has run since startup.
+type IndexProgress struct {
+	// Running is true while a pass is indexing files.
+	Running bool
+	// VaultID is the vault being indexed (0 = all vaults).
+	VaultID int
+	// StartedAt is when the pass started.
+	StartedAt time.Time
+	// FinishedAt is when the pass ended (zero while running).
+	FinishedAt time.Time
+	// FilesScanned is the number of markdown files found in the vaults.
+	FilesScanned int
+	// FilesIndexed is the number of files indexed (or found unchanged) so far.
+	FilesIndexed int
+	// FilesFailed is the number of files that could not be indexed.
+	FilesFailed int
+	// NotesRemoved is the number of indexed notes removed because their files are gone.
+	NotesRemoved int
+	// ChunksRemoved is the number of chunks (and vector points) removed with them.
+	ChunksRemoved int
+	// Error is the error the pass ended with, if any.
+	Error string
+}
+
+// ETA estimates the time left in a running pass from the average time per file so far.
+// Returns 0 when the pass is not running or no file has been processed yet.
+func (pr IndexProgress) ETA(now time.Time) time.Duration {
+	done := pr.FilesIndexed + pr.FilesFailed
+	if !pr.Running || done == 0 || done >= pr.FilesScanned {
+		return 0
+	}
+	perFile := now.Sub(pr.StartedAt) / time.Duration(done)
+	return perFile * time.Duration(pr.FilesScanned-done)
+}
+
+// indexProgress tracks IndexProgress across goroutines. A nil tracker ignores updates.
+type indexProgress struct {
+	mu    sync.Mutex
+	state IndexProgress
+}
+
+// start resets the progress for a new pass over vaultID (0 = all vaults).
+func (t *indexProgress) start(vaultID int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = IndexProgress{Running: true, VaultID: vaultID, StartedAt: time.Now()}
+}
+
+// scanned records the number of files the pass will index.
+func (t *indexProgress) scanned(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.FilesScanned = n
+}
+
+// fileDone counts one processed file, failed when err is non-nil.
+func (t *indexProgress) fileDone(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.state.FilesFailed++
+	} else {
+		t.state.FilesIndexed++
+	}
+}
+
+// removed records the notes removed because their files are gone.
+func (t *indexProgress) removed(result FolderDeleteResult) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.NotesRemoved = result.Notes
+	t.state.ChunksRemoved = result.Chunks
+}
+
+// finish marks the pass as ended with err.
+func (t *indexProgress) finish(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Running = false
+	t.state.FinishedAt = time.Now()
+	if err != nil {
+		t.state.Error = err.Error()
+	}
+}
+
+// IndexProgress returns the progress of the running or last indexing pass, including
+// passes run by Rebuild.
+func (p *Pipeline) IndexProgress() IndexProgress {
+	if p.progress == nil {
+		return IndexProgress{}
+	}
+	p.progress.mu.Lock()
+	defer p.progress.mu.Unlock()
+	return p.progress.state
+}
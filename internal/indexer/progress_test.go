@@ -0,0 +1,65 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexProgress_ETA(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		progress IndexProgress
+		want     time.Duration
+	}{
+		{name: "half done", progress: IndexProgress{Running: true, StartedAt: start, FilesScanned: 10, FilesIndexed: 4, FilesFailed: 1}, want: 10 * time.Second},
+		{name: "nothing done", progress: IndexProgress{Running: true, StartedAt: start, FilesScanned: 10}, want: 0},
+		{name: "finished", progress: IndexProgress{StartedAt: start, FilesScanned: 10, FilesIndexed: 10}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.progress.ETA(start.Add(10 * time.Second)); got != tt.want {
+				t.Errorf("ETA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_IndexVaultProgress(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for dir, names := range map[string][]string{personalDir: {"a.md", "b.md"}, workDir: {"c.md"}} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("# "+name+"\n\nSome content."), 0644); err != nil {
+				t.Fatalf("Failed to write note: %v", err)
+			}
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, _, _ := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if got := pipeline.IndexProgress(); !got.StartedAt.IsZero() {
+		t.Errorf("IndexProgress() before indexing = %+v, want zero", got)
+	}
+
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+	if err := pipeline.IndexVault(ctx, personal.ID); err != nil {
+		t.Fatalf("IndexVault() error = %v", err)
+	}
+
+	got := pipeline.IndexProgress()
+	if got.Running || got.VaultID != personal.ID || got.FilesScanned != 2 || got.FilesIndexed != 2 || got.FilesFailed != 0 || got.FinishedAt.IsZero() || got.Error != "" {
+		t.Errorf("IndexProgress() = %+v, want 2 personal files indexed", got)
+	}
+	if notes, _ := noteRepo.ListByVault(ctx, work.ID); len(notes) != 0 {
+		t.Errorf("IndexVault() indexed %d work notes, want 0", len(notes))
+	}
+}
@@ -0,0 +1,24 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type runIDKey struct{}
+
+// withRunID tags ctx with the ID of the indexing run, recorded in the provenance of
+// every chunk the run writes.
+func withRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// runIDFromContext returns the run ID set by IndexAll. Notes indexed on their own
+// (outside IndexAll) get a fresh run ID.
+func runIDFromContext(ctx context.Context) string {
+	if runID, ok := ctx.Value(runIDKey{}).(string); ok && runID != "" {
+		return runID
+	}
+	return uuid.New().String()
+}
@@ -0,0 +1,64 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPipeline_RecordsChunkProvenance(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	for _, name := range []string{"garden.md", "recipes.md"} {
+		if err := os.WriteFile(filepath.Join(personalDir, name), []byte("# "+name+"\n\nSome content worth indexing here."), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	before := time.Now().UTC().Add(-time.Second)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+
+	personal, _ := vaultManager.VaultByName("personal")
+	runIDs := make(map[string]bool)
+	for _, name := range []string{"garden.md", "recipes.md"} {
+		note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, name)
+		if err != nil {
+			t.Fatalf("GetByVaultAndPath(%s) error = %v", name, err)
+		}
+		chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+		if len(chunkIDs) == 0 {
+			t.Fatalf("no chunks indexed for %s", name)
+		}
+
+		chunk, err := chunkRepo.GetByID(ctx, chunkIDs[0])
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if chunk.ChunkerVersion != ChunkerVersion || chunk.EmbeddingModel != "fake-embedding" || chunk.RunID == "" || chunk.EmbeddedAt.Before(before) {
+			t.Errorf("chunk provenance = %q, %q, %q, %v, want chunker %q, model fake-embedding, a run ID, and a recent time",
+				chunk.ChunkerVersion, chunk.EmbeddingModel, chunk.RunID, chunk.EmbeddedAt, ChunkerVersion)
+		}
+		runIDs[chunk.RunID] = true
+
+		points, _ := store.Retrieve(ctx, "notes", chunkIDs[:1])
+		if len(points) != 1 || points[0].Meta["run_id"] != chunk.RunID || points[0].Meta["chunker_version"] != ChunkerVersion ||
+			points[0].Meta["embedding_model"] != "fake-embedding" || points[0].Meta["embedded_at"] != chunk.EmbeddedAt.Format(time.RFC3339) {
+			t.Errorf("payload provenance = %v, want it to mirror the SQLite row %+v", points, chunk)
+		}
+	}
+	if len(runIDs) != 1 {
+		t.Errorf("chunks from one IndexAll run have %d run IDs, want 1", len(runIDs))
+	}
+}
@@ -0,0 +1,234 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+)
+
+// FolderDeleteResult reports what DeleteFolder or ClearVault removed from the index.
+type FolderDeleteResult struct {
+	// Notes is the number of notes removed.
+	Notes int
+	// Chunks is the number of chunks (and vector points) removed with them.
+	Chunks int
+	// Failed is the number of notes that could not be removed and are still indexed.
+	Failed int
+}
+
+// DeleteFolder removes every indexed note under a folder prefix of a vault, with its
+// chunks, vector points, and note centroid. Files are not touched. With an excluded folder
+// store (SetExcludedFolderStore) the prefix is recorded first and index runs skip it until
+// IncludeFolder; without one, notes still in the vault are indexed again on the next run.
+// The prefix matches whole folder names: "Archive" covers "Archive" and "Archive/2020" but
+// not "Archived".
+//
+// Vector points are deleted before the SQLite rows, so a note that fails stays fully
+// indexed and can be deleted again. Failures are logged and counted in the result.
+func (p *Pipeline) DeleteFolder(ctx context.Context, vaultID int, prefix string) (FolderDeleteResult, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	prefix = normalizeFolderPrefix(prefix)
+	if prefix == "" {
+		return FolderDeleteResult{}, fmt.Errorf("folder prefix is required")
+	}
+	// Excluded before deleting, so an index run in between cannot add the notes back
+	if p.excludedFolderRepo != nil {
+		if err := p.excludedFolderRepo.Add(ctx, vaultID, prefix); err != nil {
+			return FolderDeleteResult{}, fmt.Errorf("failed to exclude folder: %w", err)
+		}
+	}
+
+	notes, err := p.noteRepo.ListByVault(ctx, vaultID)
+	if err != nil {
+		return FolderDeleteResult{}, fmt.Errorf("failed to list notes: %w", err)
+	}
+
+	var result FolderDeleteResult
+	for _, note := range notes {
+		if !inFolder(note.Folder, prefix) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		chunks, err := p.deleteNote(ctx, note)
+		if err != nil {
+			result.Failed++
+			logger.WarnContext(ctx, "failed to delete note from index", "rel_path", note.RelPath, "error", err)
+			continue
+		}
+		result.Notes++
+		result.Chunks += chunks
+	}
+
+	if result.Notes > 0 {
+		if _, err := p.chunkRepo.PruneTexts(ctx); err != nil {
+			logger.WarnContext(ctx, "failed to prune unreferenced chunk texts", "error", err)
+		}
+		// The vault checksum changed on purpose, so verification should not report it
+		p.recordChecksums(ctx)
+		p.advanceEpoch()
+	}
+
+	logger.InfoContext(ctx, "deleted folder from index",
+		"vault_id", vaultID,
+		"prefix", prefix,
+		"notes", result.Notes,
+		"chunks", result.Chunks,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// ClearVault removes every indexed note of a vault, with its chunks, vector points, and
+// note centroid, leaving other vaults indexed. It is the per-vault ClearAll; failures are
+// handled as in DeleteFolder.
+func (p *Pipeline) ClearVault(ctx context.Context, vaultID int) (FolderDeleteResult, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	notes, err := p.noteRepo.ListByVault(ctx, vaultID)
+	if err != nil {
+		return FolderDeleteResult{}, fmt.Errorf("failed to list notes: %w", err)
+	}
+
+	var result FolderDeleteResult
+	for _, note := range notes {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		chunks, err := p.deleteNote(ctx, note)
+		if err != nil {
+			result.Failed++
+			logger.WarnContext(ctx, "failed to delete note from index", "rel_path", note.RelPath, "error", err)
+			continue
+		}
+		result.Notes++
+		result.Chunks += chunks
+	}
+
+	if result.Notes > 0 {
+		if _, err := p.chunkRepo.PruneTexts(ctx); err != nil {
+			logger.WarnContext(ctx, "failed to prune unreferenced chunk texts", "error", err)
+		}
+		p.recordChecksums(ctx)
+		p.advanceEpoch()
+	}
+
+	logger.InfoContext(ctx, "cleared vault from index",
+		"vault_id", vaultID,
+		"notes", result.Notes,
+		"chunks", result.Chunks,
+		"failed", result.Failed,
+	)
+	return result, nil
+}
+
+// removeDeletedNotes removes the indexed notes of vaultID (0 = all vaults) whose files were
+// not found by the scan, with their chunks, vector points, and note centroids, so notes
+// deleted from a vault stop showing up in answers. It runs after applyMoves, so moved notes
+// are kept. A vault whose scan found no files at all is skipped: an unmounted or emptied
+// vault directory should not wipe its index (ClearVault does that on purpose).
+// Failures are handled as in DeleteFolder.
+func (p *Pipeline) removeDeletedNotes(ctx context.Context, vaultID int, scannedFiles []vault.ScannedFile) (FolderDeleteResult, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	scannedPaths := make(map[int]map[string]struct{})
+	for _, file := range scannedFiles {
+		if scannedPaths[file.VaultID] == nil {
+			scannedPaths[file.VaultID] = make(map[string]struct{})
+		}
+		scannedPaths[file.VaultID][file.RelPath] = struct{}{}
+	}
+	vaultIDs := []int{vaultID}
+	if vaultID == 0 {
+		vaultIDs = vaultIDs[:0]
+		for _, v := range p.vaultManager.ListVaults() {
+			vaultIDs = append(vaultIDs, v.ID)
+		}
+	}
+
+	var result FolderDeleteResult
+	for _, id := range vaultIDs {
+		notes, err := p.noteRepo.ListByVault(ctx, id)
+		if err != nil {
+			return result, fmt.Errorf("failed to list notes: %w", err)
+		}
+		paths := scannedPaths[id]
+		if len(paths) == 0 {
+			if len(notes) > 0 {
+				logger.WarnContext(ctx, "vault scan found no files, keeping its indexed notes", "vault_id", id, "notes", len(notes))
+			}
+			continue
+		}
+
+		for _, note := range notes {
+			if _, ok := paths[note.RelPath]; ok {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			default:
+			}
+
+			chunks, err := p.deleteNote(ctx, note)
+			if err != nil {
+				result.Failed++
+				logger.WarnContext(ctx, "failed to remove deleted note from index", "rel_path", note.RelPath, "error", err)
+				continue
+			}
+			logger.DebugContext(ctx, "removed deleted note from index", "vault_id", id, "rel_path", note.RelPath, "chunks", chunks)
+			result.Notes++
+			result.Chunks += chunks
+		}
+	}
+	return result, nil
+}
+
+// deleteNote removes a note's vector points and centroid, then its SQLite rows.
+// Returns the number of chunks removed.
+func (p *Pipeline) deleteNote(ctx context.Context, note storage.NoteRecord) (int, error) {
+	chunkIDs, err := p.chunkRepo.ListIDsByNote(ctx, note.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list chunk IDs: %w", err)
+	}
+
+	if len(chunkIDs) > 0 {
+		collection := p.chunkCollection(note.VaultID)
+		if note.Tier == storage.TierCold && p.coldCollection != "" {
+			collection = p.coldCollection
+		}
+		if err := p.vectorStore.Delete(ctx, collection, chunkIDs); err != nil {
+			return 0, fmt.Errorf("failed to delete points: %w", err)
+		}
+	}
+	if p.noteCollection != "" {
+		if err := p.vectorStore.Delete(ctx, p.noteCollection, []string{note.ID}); err != nil {
+			return 0, fmt.Errorf("failed to delete note centroid: %w", err)
+		}
+	}
+
+	if err := p.noteRepo.Delete(ctx, note.ID); err != nil {
+		return 0, fmt.Errorf("failed to delete note: %w", err)
+	}
+	p.recordEvent(ctx, storage.IndexEventRecord{Type: storage.IndexEventNoteDeleted, VaultID: note.VaultID, NoteID: note.ID, RelPath: note.RelPath})
+	return len(chunkIDs), nil
+}
+
+// normalizeFolderPrefix cleans a folder prefix to the form stored in notes.folder
+// (slash-separated, no leading or trailing slash). The vault root normalizes to "".
+func normalizeFolderPrefix(prefix string) string {
+	return strings.Trim(path.Clean("/"+strings.ReplaceAll(prefix, "\\", "/")), "/")
+}
@@ -0,0 +1,178 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipeline_DeleteFolder(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	files := map[string]string{
+		"Archive/old.md":         "# Old\n\nLast year's plans.",
+		"Archive/2020/review.md": "# Review\n\nThe 2020 review.",
+		"Archived/keep.md":       "# Keep\n\nSimilar name, different folder.",
+		"garden.md":              "# Garden\n\nTomatoes grow in the north bed.",
+	}
+	for relPath, content := range files {
+		absPath := filepath.Join(personalDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	before, _ := noteRepo.ListByVault(ctx, personal.ID)
+	var deletedChunkIDs []string
+	var deletedNoteIDs []string
+	for _, note := range before {
+		if note.Folder == "Archive" || note.Folder == "Archive/2020" {
+			ids, _ := chunkRepo.ListIDsByNote(ctx, note.ID)
+			deletedChunkIDs = append(deletedChunkIDs, ids...)
+			deletedNoteIDs = append(deletedNoteIDs, note.ID)
+		}
+	}
+
+	result, err := pipeline.DeleteFolder(ctx, personal.ID, "/Archive/")
+	if err != nil {
+		t.Fatalf("DeleteFolder() error = %v", err)
+	}
+	if result.Notes != 2 || result.Chunks != len(deletedChunkIDs) || result.Failed != 0 {
+		t.Errorf("DeleteFolder() = %+v, want 2 notes and %d chunks", result, len(deletedChunkIDs))
+	}
+
+	after, _ := noteRepo.ListByVault(ctx, personal.ID)
+	if len(after) != 2 || after[0].RelPath != "Archived/keep.md" || after[1].RelPath != "garden.md" {
+		t.Errorf("notes after DeleteFolder() = %+v, want Archived/keep.md and garden.md", after)
+	}
+	if points, _ := store.Retrieve(ctx, "notes", deletedChunkIDs); len(points) != 0 {
+		t.Errorf("%d chunk points left after DeleteFolder()", len(points))
+	}
+	if centroids, _ := store.Retrieve(ctx, "notes_notes", deletedNoteIDs); len(centroids) != 0 {
+		t.Errorf("%d note centroids left after DeleteFolder()", len(centroids))
+	}
+	// Files are untouched
+	if _, err := os.Stat(filepath.Join(personalDir, "Archive", "old.md")); err != nil {
+		t.Errorf("file removed by DeleteFolder(): %v", err)
+	}
+
+	if _, err := pipeline.DeleteFolder(ctx, personal.ID, "/"); err == nil {
+		t.Error("DeleteFolder() with an empty prefix error = nil, want an error")
+	}
+}
+
+func TestPipeline_ClearVault(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for dir, content := range map[string]string{personalDir: "# Garden\n\nTomatoes grow in the north bed.", workDir: "# Sprint\n\nShip the importer."} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+	personalNotes, _ := noteRepo.ListByVault(ctx, personal.ID)
+	chunkIDs, _ := chunkRepo.ListIDsByNote(ctx, personalNotes[0].ID)
+
+	result, err := pipeline.ClearVault(ctx, personal.ID)
+	if err != nil {
+		t.Fatalf("ClearVault() error = %v", err)
+	}
+	if result.Notes != 1 || result.Chunks != len(chunkIDs) || result.Failed != 0 {
+		t.Errorf("ClearVault() = %+v, want 1 note and %d chunks", result, len(chunkIDs))
+	}
+	if notes, _ := noteRepo.ListByVault(ctx, personal.ID); len(notes) != 0 {
+		t.Errorf("%d personal notes left after ClearVault()", len(notes))
+	}
+	if points, _ := store.Retrieve(ctx, "notes", chunkIDs); len(points) != 0 {
+		t.Errorf("%d chunk points left after ClearVault()", len(points))
+	}
+	if notes, _ := noteRepo.ListByVault(ctx, work.ID); len(notes) != 1 {
+		t.Errorf("work notes after ClearVault() = %d, want 1", len(notes))
+	}
+}
+
+func TestPipeline_IndexAll_RemovesDeletedNotes(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	files := map[string]string{
+		filepath.Join(personalDir, "garden.md"):        "# Garden\n\nTomatoes grow in the north bed.",
+		filepath.Join(personalDir, "trips", "rome.md"): "# Rome\n\nThe trip to Rome in April.",
+		filepath.Join(workDir, "plan.md"):              "# Plan\n\nThe quarterly plan.",
+	}
+	for absPath, content := range files {
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	personal, _ := vaultManager.VaultByName("personal")
+	work, _ := vaultManager.VaultByName("work")
+	rome, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "trips/rome.md")
+	if err != nil {
+		t.Fatalf("GetByVaultAndPath() error = %v", err)
+	}
+	romeChunkIDs, _ := chunkRepo.ListIDsByNote(ctx, rome.ID)
+
+	// One note is deleted, and the work vault is emptied (as if its disk were unmounted)
+	for _, absPath := range []string{filepath.Join(personalDir, "trips", "rome.md"), filepath.Join(workDir, "plan.md")} {
+		if err := os.Remove(absPath); err != nil {
+			t.Fatalf("Failed to remove note: %v", err)
+		}
+	}
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() after delete error = %v", err)
+	}
+
+	notes, _ := noteRepo.ListByVault(ctx, personal.ID)
+	if len(notes) != 1 || notes[0].RelPath != "garden.md" {
+		t.Errorf("personal notes after delete = %+v, want only garden.md", notes)
+	}
+	if points, _ := store.Retrieve(ctx, "notes", romeChunkIDs); len(points) != 0 {
+		t.Errorf("%d chunk points left for the deleted note", len(points))
+	}
+	if centroids, _ := store.Retrieve(ctx, "notes_notes", []string{rome.ID}); len(centroids) != 0 {
+		t.Errorf("%d note centroids left for the deleted note", len(centroids))
+	}
+	if workNotes, _ := noteRepo.ListByVault(ctx, work.ID); len(workNotes) != 1 {
+		t.Errorf("work notes after its scan found no files = %d, want 1 kept", len(workNotes))
+	}
+
+	progress := pipeline.IndexProgress()
+	if progress.NotesRemoved != 1 || progress.ChunksRemoved != len(romeChunkIDs) {
+		t.Errorf("IndexProgress() removed %d notes, %d chunks, want 1, %d", progress.NotesRemoved, progress.ChunksRemoved, len(romeChunkIDs))
+	}
+}
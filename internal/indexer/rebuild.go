@@ -0,0 +1,225 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// ErrRebuildUnsupported is returned by Rebuild when no shadow index is configured or the
+// vector store does not support aliases. Callers fall back to ClearAll followed by IndexAll.
+var ErrRebuildUnsupported = errors.New("blue/green rebuild is not supported")
+
+// shadowIndex is the SQLite side of a blue/green rebuild. *storage.ShadowIndex implements it.
+type shadowIndex interface {
+	Open(ctx context.Context) (*sql.DB, error)
+	Swap(ctx context.Context) error
+	Discard() error
+}
+
+// RebuildSupported reports whether Rebuild can run.
+func (p *Pipeline) RebuildSupported() bool {
+	_, ok := p.vectorStore.(vectorstore.AliasStore)
+	return ok && p.shadowIndex != nil
+}
+
+// Rebuild re-indexes every note from scratch without queries ever seeing an empty or partial
+// index. The new index is built into fresh Qdrant collections and a shadow SQLite database
+// while the current one keeps serving. Once every file is indexed, the collection aliases are
+// switched and the shadow notes and chunks replace the live ones in one transaction. If any
+// step fails before the switch, the new data is discarded and the current index is kept.
+// Plain collections still using an alias name (an index built before the first rebuild) are
+// only replaced by aliases once the new SQLite index is live, since deleting them cannot be
+// undone.
+func (p *Pipeline) Rebuild(ctx context.Context) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	if !p.RebuildSupported() {
+		return ErrRebuildUnsupported
+	}
+	aliasStore := p.vectorStore.(vectorstore.AliasStore)
+
+	// Collections queried by name (aliases after the first rebuild) and the ones replacing them
+	aliases := p.ChunkCollections()
+	if p.noteCollection != "" {
+		aliases = append(aliases, p.noteCollection)
+	}
+	// Nanoseconds keep back-to-back rebuilds from reusing the live collection's name
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	next := make(map[string]string, len(aliases))
+	discard := func() {
+		for _, collection := range next {
+			if err := aliasStore.DeleteCollection(context.Background(), collection); err != nil {
+				logger.WarnContext(ctx, "failed to delete unused rebuild collection", "collection", collection, "error", err)
+			}
+		}
+		if err := p.shadowIndex.Discard(); err != nil {
+			logger.WarnContext(ctx, "failed to discard shadow index", "error", err)
+		}
+	}
+
+	for _, alias := range aliases {
+		collection := alias + "_" + suffix
+		if err := aliasStore.EnsureCollection(ctx, collection, p.embedder.ExpectedSize); err != nil {
+			discard()
+			return fmt.Errorf("failed to create rebuild collection: %w", err)
+		}
+		next[alias] = collection
+	}
+
+	shadowDB, err := p.shadowIndex.Open(ctx)
+	if err != nil {
+		discard()
+		return fmt.Errorf("failed to open shadow index: %w", err)
+	}
+
+	logger.InfoContext(ctx, "building new index", "collections", next)
+	builder := &Pipeline{
+		vaultManager:       p.vaultManager,
+		noteRepo:           storage.NewNoteRepo(shadowDB),
+		chunkRepo:          storage.NewChunkRepo(shadowDB),
+		timingRepo:         p.timingRepo,
+		embedder:           p.embedder,
+		vectorStore:        p.vectorStore,
+		collection:         p.collection,
+		collectionPerVault: p.collectionPerVault,
+		rebuildTargets:     next,
+		noteCollection:     next[p.noteCollection],
+		chunker:            p.chunker,
+		embedParallelism:   p.embedParallelism,
+		excludedFolderRepo: p.excludedFolderRepo,
+		// The build shows up as this pipeline's progress
+		progress: p.progress,
+	}
+	if p.failureRepo != nil {
+		builder.failureRepo = storage.NewIndexFailureRepo(shadowDB)
+	}
+	if err := builder.IndexAll(ctx); err != nil {
+		discard()
+		return fmt.Errorf("failed to build new index, keeping current index: %w", err)
+	}
+
+	// Chunk IDs of the current index, to clear the cold collection once the new index is live
+	oldChunkIDs, err := p.chunkRepo.GetAllIDs(ctx)
+	if err != nil {
+		discard()
+		return fmt.Errorf("failed to get chunk IDs: %w", err)
+	}
+
+	previous, plain, err := p.planAliases(ctx, aliasStore, next)
+	if err != nil {
+		discard()
+		return err
+	}
+	switchNow := make(map[string]string, len(next))
+	for alias, collection := range next {
+		if _, ok := plain[alias]; !ok {
+			switchNow[alias] = collection
+		}
+	}
+	// Point the aliases back on failure so vectors and SQLite describe the same index
+	restore := func() {
+		if err := p.switchAliases(context.WithoutCancel(ctx), aliasStore, previous); err != nil {
+			logger.ErrorContext(ctx, "failed to restore collection aliases", "error", err)
+		}
+	}
+	if err := p.switchAliases(ctx, aliasStore, switchNow); err != nil {
+		restore()
+		discard()
+		return err
+	}
+	if err := p.shadowIndex.Swap(ctx); err != nil {
+		restore()
+		discard()
+		return fmt.Errorf("failed to swap in new index: %w", err)
+	}
+	logger.InfoContext(ctx, "new index is live", "collections", next)
+	p.advanceEpoch()
+	// The builder records no note events, so clients learn of the new index from this one
+	p.recordReindexCompleted(ctx, 0)
+
+	// Searches of these collections fail briefly until their alias exists. Rebuild collections
+	// whose plain collection could not be replaced are kept, so no vectors are lost.
+	replaceErr := p.replacePlainCollections(ctx, aliasStore, plain)
+
+	for _, collection := range previous {
+		if err := aliasStore.DeleteCollection(ctx, collection); err != nil {
+			logger.WarnContext(ctx, "failed to delete previous collection", "collection", collection, "error", err)
+		}
+	}
+	// Every note is hot again after a rebuild, so cold copies of the old chunks are stale
+	if p.coldCollection != "" && len(oldChunkIDs) > 0 {
+		if err := p.vectorStore.Delete(ctx, p.coldCollection, oldChunkIDs); err != nil {
+			logger.WarnContext(ctx, "failed to delete some points from cold collection", "error", err)
+		}
+	}
+
+	p.recordChecksums(ctx)
+	return replaceErr
+}
+
+// planAliases returns the collections the aliases in targets point at now and, apart, the
+// targets whose alias name is still taken by a plain collection.
+func (p *Pipeline) planAliases(ctx context.Context, aliasStore vectorstore.AliasStore, targets map[string]string) (previous, plain map[string]string, err error) {
+	previous = make(map[string]string, len(targets))
+	plain = make(map[string]string)
+	for alias, collection := range targets {
+		target, err := aliasStore.AliasTarget(ctx, alias)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve alias %s: %w", alias, err)
+		}
+		if target != "" {
+			previous[alias] = target
+			continue
+		}
+		exists, err := p.vectorStore.CollectionExists(ctx, alias)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check collection %s: %w", alias, err)
+		}
+		if exists {
+			plain[alias] = collection
+		}
+	}
+	return previous, plain, nil
+}
+
+// switchAliases points each alias at its target collection.
+func (p *Pipeline) switchAliases(ctx context.Context, aliasStore vectorstore.AliasStore, targets map[string]string) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	for alias, collection := range targets {
+		if err := aliasStore.SwitchAlias(ctx, alias, collection); err != nil {
+			return fmt.Errorf("failed to switch alias %s: %w", alias, err)
+		}
+		logger.InfoContext(ctx, "switched collection alias", "alias", alias, "collection", collection)
+	}
+	return nil
+}
+
+// replacePlainCollections deletes each plain collection using an alias name and points the
+// alias at its target collection instead.
+func (p *Pipeline) replacePlainCollections(ctx context.Context, aliasStore vectorstore.AliasStore, targets map[string]string) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	var errs []error
+	for alias, collection := range targets {
+		logger.WarnContext(ctx, "replacing plain collection with alias", "collection", alias)
+		if err := aliasStore.DeleteCollection(ctx, alias); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete collection %s: %w", alias, err))
+			continue
+		}
+		if err := aliasStore.SwitchAlias(ctx, alias, collection); err != nil {
+			errs = append(errs, fmt.Errorf("failed to switch alias %s: %w", alias, err))
+			continue
+		}
+		logger.InfoContext(ctx, "switched collection alias", "alias", alias, "collection", collection)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("new index is live, but collections could not be replaced by aliases: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestPipeline_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	vaultDir := filepath.Join(tmpDir, "vault")
+	if err := os.MkdirAll(vaultDir, 0755); err != nil {
+		t.Fatalf("Failed to create vault dir: %v", err)
+	}
+	writeNote := func(content string) {
+		if err := os.WriteFile(filepath.Join(vaultDir, "garden.md"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	writeNote("# Garden\n\nTomatoes grow in the north bed.")
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: vaultDir}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	const vectorSize = 16
+	fakeLLM := llm.NewFakeServer(vectorSize)
+	defer fakeLLM.Close()
+	embedder := llm.NewEmbeddingsClient(fakeLLM.URL, "dummy-key", "fake-embedding", vectorSize)
+
+	store := vectorstore.NewMemoryStore()
+	for _, collection := range []string{"notes", "notes_notes"} {
+		if err := store.EnsureCollection(ctx, collection, vectorSize); err != nil {
+			t.Fatalf("EnsureCollection() error = %v", err)
+		}
+	}
+
+	noteRepo := storage.NewNoteRepo(db)
+	chunkRepo := storage.NewChunkRepo(db)
+	pipeline := NewPipeline(vaultManager, noteRepo, chunkRepo, nil, storage.NewIndexChecksumRepo(db), embedder, store, "notes", "", "notes_notes", storage.NewShadowIndex(db, dbPath+".rebuild"))
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	oldIDs, _ := chunkRepo.GetAllIDs(ctx)
+
+	writeNote("# Garden\n\nPeppers grow in the south bed.")
+	if err := pipeline.Rebuild(ctx); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+
+	// Both collections are now aliases to fresh collections; the plain ones were replaced
+	for _, alias := range []string{"notes", "notes_notes"} {
+		target, _ := store.AliasTarget(ctx, alias)
+		if target == "" || target == alias {
+			t.Errorf("AliasTarget(%s) = %q, want a rebuild collection", alias, target)
+		}
+	}
+
+	// Both vaults point at the same directory, so each index holds two chunks
+	newIDs, _ := chunkRepo.GetAllIDs(ctx)
+	if len(newIDs) != 2 || len(oldIDs) != 2 || newIDs[0] == oldIDs[0] || newIDs[0] == oldIDs[1] {
+		t.Fatalf("chunk IDs after rebuild = %v (before %v), want the new note's chunks", newIDs, oldIDs)
+	}
+	points, err := store.Retrieve(ctx, "notes", newIDs)
+	if err != nil || len(points) != 2 {
+		t.Errorf("Retrieve(alias) = %v, %v, want the new chunk's point", points, err)
+	}
+	if stale, _ := store.Retrieve(ctx, "notes", oldIDs); len(stale) != 0 {
+		t.Errorf("Retrieve(alias) found %d stale points, want 0", len(stale))
+	}
+
+	verifications, err := pipeline.VerifyIndex(ctx)
+	if err != nil {
+		t.Fatalf("VerifyIndex() error = %v", err)
+	}
+	for _, verification := range verifications {
+		if verification.Status != VerifyStatusOK {
+			t.Errorf("VerifyIndex() vault %s status = %s, want ok", verification.VaultName, verification.Status)
+		}
+	}
+
+	// A second rebuild replaces the previous rebuild collection
+	firstTarget, _ := store.AliasTarget(ctx, "notes")
+	if err := pipeline.Rebuild(ctx); err != nil {
+		t.Fatalf("second Rebuild() error = %v", err)
+	}
+	if secondTarget, _ := store.AliasTarget(ctx, "notes"); secondTarget == firstTarget {
+		t.Errorf("second rebuild reused collection %s", firstTarget)
+	}
+	if exists, _ := store.CollectionExists(ctx, firstTarget); exists {
+		t.Errorf("previous collection %s should be deleted after the second rebuild", firstTarget)
+	}
+}
+
+// failingSwap is a shadow index whose swap fails after the new index was built.
+type failingSwap struct {
+	*storage.ShadowIndex
+}
+
+func (f failingSwap) Swap(context.Context) error {
+	return errors.New("disk I/O error")
+}
+
+// creationRecordingStore records the collections it creates.
+type creationRecordingStore struct {
+	*vectorstore.MemoryStore
+	created []string
+}
+
+func (s *creationRecordingStore) EnsureCollection(ctx context.Context, collection string, vectorSize int) error {
+	s.created = append(s.created, collection)
+	return s.MemoryStore.EnsureCollection(ctx, collection, vectorSize)
+}
+
+func TestPipeline_Rebuild_FailedSwap(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	vaultDir := filepath.Join(tmpDir, "vault")
+	if err := os.MkdirAll(vaultDir, 0755); err != nil {
+		t.Fatalf("Failed to create vault dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vaultDir, "garden.md"), []byte("# Garden\n\nTomatoes grow in the north bed."), 0644); err != nil {
+		t.Fatalf("Failed to write note: %v", err)
+	}
+
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	vaultManager, err := vault.NewManager(ctx, storage.NewVaultRepo(db), []vault.Config{{Name: "personal", Path: vaultDir}}, vault.SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	const vectorSize = 16
+	fakeLLM := llm.NewFakeServer(vectorSize)
+	defer fakeLLM.Close()
+	embedder := llm.NewEmbeddingsClient(fakeLLM.URL, "dummy-key", "fake-embedding", vectorSize)
+	store := &creationRecordingStore{MemoryStore: vectorstore.NewMemoryStore()}
+	if err := store.EnsureCollection(ctx, "notes", vectorSize); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+
+	chunkRepo := storage.NewChunkRepo(db)
+	pipeline := NewPipeline(vaultManager, storage.NewNoteRepo(db), chunkRepo, nil, nil, embedder, store, "notes", "", "", storage.NewShadowIndex(db, dbPath+".rebuild"))
+	if err := pipeline.IndexAll(ctx); err != nil {
+		t.Fatalf("IndexAll() error = %v", err)
+	}
+	ids, _ := chunkRepo.GetAllIDs(ctx)
+
+	// The first rebuild would replace the plain collection with an alias
+	pipeline.shadowIndex = failingSwap{storage.NewShadowIndex(db, dbPath+".rebuild")}
+	if err := pipeline.Rebuild(ctx); err == nil {
+		t.Fatal("Rebuild() error = nil, want the swap error")
+	}
+
+	// The plain collection and its vectors are still what SQLite describes
+	if target, _ := store.AliasTarget(ctx, "notes"); target != "" {
+		t.Errorf("AliasTarget(notes) = %q, want the plain collection kept", target)
+	}
+	if points, err := store.Retrieve(ctx, "notes", ids); err != nil || len(points) != len(ids) {
+		t.Errorf("Retrieve(notes) = %d points, %v; want the %d points of the current index", len(points), err, len(ids))
+	}
+	if after, _ := chunkRepo.GetAllIDs(ctx); len(after) != len(ids) {
+		t.Errorf("chunk IDs after failed rebuild = %v, want %v", after, ids)
+	}
+	for _, collection := range store.created[1:] {
+		if exists, _ := store.CollectionExists(ctx, collection); exists {
+			t.Errorf("rebuild collection %s still exists, want it deleted", collection)
+		}
+	}
+}
+
+func TestPipeline_Rebuild_Unsupported(t *testing.T) {
+	pipeline := &Pipeline{vectorStore: vectorstore.NewMemoryStore()}
+	if pipeline.RebuildSupported() {
+		t.Error("RebuildSupported() = true without a shadow index")
+	}
+	if err := pipeline.Rebuild(context.Background()); !errors.Is(err, ErrRebuildUnsupported) {
+		t.Errorf("Rebuild() error = %v, want ErrRebuildUnsupported", err)
+	}
+}
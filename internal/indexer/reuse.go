@@ -0,0 +1,117 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// previousChunks are the chunks a note had before it is re-indexed.
+type previousChunks struct {
+	ids []string
+	// collection holds their vectors (the cold collection for cold notes).
+	collection string
+	// points are the stored points of chunks the note still has, by chunk ID.
+	points map[string]vectorstore.Point
+}
+
+// loadPreviousChunks lists the chunks a note had before re-indexing and retrieves the
+// vectors of those whose stable ID is among chunkIDs, so unchanged chunks are not embedded
+// again. Vectors from another embedding model are not reused. Failing to retrieve vectors
+// only means every chunk is embedded.
+func (p *Pipeline) loadPreviousChunks(ctx context.Context, note storage.NoteRecord, chunkIDs []string) (previousChunks, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ids, err := p.chunkRepo.ListIDsByNote(ctx, note.ID)
+	if err != nil {
+		return previousChunks{}, fmt.Errorf("failed to list old chunk IDs: %w", err)
+	}
+	previous := previousChunks{
+		ids:        ids,
+		collection: p.chunkCollection(note.VaultID),
+		points:     make(map[string]vectorstore.Point),
+	}
+	if note.Tier == storage.TierCold && p.coldCollection != "" {
+		previous.collection = p.coldCollection
+	}
+
+	old := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		old[id] = true
+	}
+	var unchanged []string
+	for _, id := range chunkIDs {
+		if old[id] {
+			unchanged = append(unchanged, id)
+		}
+	}
+	if len(unchanged) == 0 {
+		return previous, nil
+	}
+
+	points, err := p.vectorStore.Retrieve(ctx, previous.collection, unchanged)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to retrieve unchanged chunk vectors, embedding them again", "rel_path", note.RelPath, "error", err)
+		return previous, nil
+	}
+	for _, point := range points {
+		if model, _ := point.Meta["embedding_model"].(string); model != p.embedder.Model || len(point.Vec) != p.embedder.ExpectedSize {
+			continue
+		}
+		previous.points[point.ID] = point
+	}
+	return previous, nil
+}
+
+// reusedProvenance returns when and in which run a reused vector was embedded, read from its
+// payload, falling back to embeddedAt and runID for points stored without them.
+func reusedProvenance(point vectorstore.Point, embeddedAt time.Time, runID string) (time.Time, string) {
+	if value, ok := point.Meta["embedded_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			embeddedAt = parsed
+		}
+	}
+	if value, ok := point.Meta["run_id"].(string); ok && value != "" {
+		runID = value
+	}
+	return embeddedAt, runID
+}
+
+// replacePreviousChunks removes a re-indexed note's previous chunks from SQLite, archiving
+// them for chunk diffs, and deletes the vectors of chunks not among the current chunk IDs.
+// Vectors of chunks the note still has are left for the upsert to overwrite, so their IDs
+// never disappear from the collection.
+func (p *Pipeline) replacePreviousChunks(ctx context.Context, note storage.NoteRecord, previous previousChunks, current map[string]bool) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	if len(previous.ids) == 0 {
+		return nil
+	}
+
+	collection := p.chunkCollection(note.VaultID)
+	var stale []string
+	for _, id := range previous.ids {
+		// Chunks moving out of the cold collection are all stale there
+		if previous.collection != collection || !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) > 0 {
+		if err := p.vectorStore.Delete(ctx, previous.collection, stale); err != nil {
+			logger.WarnContext(ctx, "failed to delete stale chunks from Qdrant", "error", err, "count", len(stale))
+			// Continue anyway - the note's current chunks are still written
+		}
+	}
+
+	// Keep the old generation for chunk diffs; losing it only affects the diff
+	if err := p.chunkRepo.ArchiveByNote(ctx, note.ID); err != nil {
+		logger.WarnContext(ctx, "failed to archive old chunks", "error", err)
+	}
+	if err := p.chunkRepo.DeleteByNote(ctx, note.ID); err != nil {
+		return fmt.Errorf("failed to delete old chunks from SQLite: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+func TestPipeline_ReindexReusesUnchangedChunks(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	personalDir := filepath.Join(tmpDir, "personal")
+	workDir := filepath.Join(tmpDir, "work")
+	for _, dir := range []string{personalDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+
+	// Records the texts sent for embedding
+	var mu sync.Mutex
+	var embedded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.EmbeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		embedded = append(embedded, req.Input...)
+		mu.Unlock()
+		resp := llm.EmbeddingsResponse{}
+		for _, text := range req.Input {
+			resp.Data = append(resp.Data, llm.EmbeddingData{Embedding: llm.FakeEmbedding(text, 16)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	pipeline, vaultManager, noteRepo, chunkRepo, store := newIntegrationPipeline(t, tmpDir, personalDir, workDir)
+	pipeline.embedder = llm.NewEmbeddingsClient(server.URL, "dummy-key", "fake-embedding", 16)
+	personal, _ := vaultManager.VaultByName("personal")
+
+	// The two TODO sections are identical, so their chunks share a stable ID
+	notePath := filepath.Join(personalDir, "garden.md")
+	tomatoes := "## Tomatoes\n\nTomatoes grow in the north bed and need watering every morning in July."
+	todo := "## TODO\n\nBuy more stakes for the tomato plants before the summer storms arrive."
+	write := func(beans string) {
+		t.Helper()
+		content := "# Garden\n\n" + tomatoes + "\n\n" + beans + "\n\n" + todo + "\n\n" + todo
+		if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write note: %v", err)
+		}
+	}
+	chunkIDs := func() []string {
+		t.Helper()
+		note, err := noteRepo.GetByVaultAndPath(ctx, personal.ID, "garden.md")
+		if err != nil {
+			t.Fatalf("GetByVaultAndPath() error = %v", err)
+		}
+		ids, err := chunkRepo.ListIDsByNote(ctx, note.ID)
+		if err != nil {
+			t.Fatalf("ListIDsByNote() error = %v", err)
+		}
+		return ids
+	}
+
+	write("## Beans\n\nBeans climb the fence by the shed and are picked twice a week.")
+	if err := pipeline.IndexNote(ctx, personal.ID, "garden.md", ""); err != nil {
+		t.Fatalf("IndexNote() error = %v", err)
+	}
+	before := chunkIDs()
+	if len(before) != 3 {
+		t.Fatalf("indexed %d chunks, want 3 (the duplicate TODO chunk written once)", len(before))
+	}
+	first, err := store.Retrieve(ctx, "notes", before[:1])
+	if err != nil || len(first) != 1 {
+		t.Fatalf("Retrieve() = %v, %v; want the tomatoes point", first, err)
+	}
+
+	mu.Lock()
+	embedded = nil
+	mu.Unlock()
+	write("## Beans\n\nThe beans were pulled out and the bed is resting until spring.")
+	if err := pipeline.IndexNote(ctx, personal.ID, "garden.md", ""); err != nil {
+		t.Fatalf("IndexNote() error = %v", err)
+	}
+	after := chunkIDs()
+
+	// Only the changed section is embedded again
+	if len(embedded) != 1 || !strings.Contains(embedded[0], "pulled out") {
+		t.Errorf("embedded %q on re-index, want only the changed beans chunk", embedded)
+	}
+	if len(after) != 3 || after[0] != before[0] || after[2] != before[2] || after[1] == before[1] {
+		t.Errorf("chunk IDs %v -> %v, want the tomatoes and TODO IDs kept and the beans ID replaced", before, after)
+	}
+	if stale, _ := store.Retrieve(ctx, "notes", before[1:2]); len(stale) != 0 {
+		t.Errorf("old beans point still stored, want it deleted")
+	}
+	points, err := store.Retrieve(ctx, "notes", after)
+	if err != nil || len(points) != 3 {
+		t.Fatalf("Retrieve() = %d points, %v; want all 3 current chunks", len(points), err)
+	}
+	for _, point := range points {
+		if point.ID == before[0] && point.Meta["run_id"] != first[0].Meta["run_id"] {
+			t.Errorf("reused point run_id = %v, want the run that embedded it (%v)", point.Meta["run_id"], first[0].Meta["run_id"])
+		}
+	}
+}
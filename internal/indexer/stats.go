@@ -14,8 +14,8 @@ import (
 
 const (
 	// ChunkerVersion is the version identifier for the chunker implementation.
-	// Update this when chunking logic changes significantly.
-	ChunkerVersion = "v1.0"
+	// Update this when chunking logic changes significantly; it is recorded in chunk provenance.
+	ChunkerVersion = "v1.1"
 	// TokensPerRune is an approximation for token counting (4 chars per token).
 	TokensPerRune = 4.0
 )
@@ -95,8 +95,18 @@ func (p *Pipeline) GetIndexingCoverageStats(ctx context.Context, embeddingModelN
 	}
 
 	stats.ChunksEmbedded = len(chunks)
-	stats.ChunksAttempted = stats.ChunksEmbedded // We don't track attempted separately, so use embedded as approximation
-	stats.ChunksSkipped = 0                       // We don't track skipped chunks in DB, so set to 0
+	// Skipped chunks come from the failure records of each note's last indexing run
+	if p.failureRepo != nil {
+		reasons, err := p.failureRepo.CountByReason(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count index failures: %w", err)
+		}
+		for reason, count := range reasons {
+			stats.ChunksSkippedReasons[reason] = count
+			stats.ChunksSkipped += count
+		}
+	}
+	stats.ChunksAttempted = stats.ChunksEmbedded + stats.ChunksSkipped
 
 	// Compute token statistics from chunk texts
 	if len(chunks) > 0 {
@@ -128,6 +138,9 @@ func (p *Pipeline) GetIndexingCoverageStats(ctx context.Context, embeddingModelN
 	const maxChunkSize = 700
 	indexVersionInput := fmt.Sprintf("%s|%s|minChunkSize=%d|maxChunkSize=%d",
 		ChunkerVersion, embeddingModelName, minChunkSize, maxChunkSize)
+	if p.chunker != nil && p.chunker.overlap > 0 {
+		indexVersionInput += fmt.Sprintf("|overlapRunes=%d", p.chunker.overlap)
+	}
 	hash := sha256.Sum256([]byte(indexVersionInput))
 	stats.IndexVersion = hex.EncodeToString(hash[:])[:16] // 16 hex chars = 64 bits
 
@@ -175,7 +188,9 @@ func (p *Pipeline) getAllChunks(ctx context.Context, chunkRepo *storage.ChunkRep
 		return nil, fmt.Errorf("chunkRepo.DB() returned nil")
 	}
 
-	rows, err := db.QueryContext(ctx, "SELECT id, note_id, chunk_index, heading_path, text FROM chunks")
+	rows, err := db.QueryContext(ctx, `SELECT c.id, c.note_id, c.chunk_index, c.heading_path, COALESCE(t.text, c.text)
+		FROM chunks c
+		LEFT JOIN chunk_texts t ON t.hash = c.text_hash`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunks: %w", err)
 	}
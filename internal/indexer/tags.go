@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// inlineTagPattern matches #tags in body text (letters, digits, _, -, and / for nesting).
+	inlineTagPattern = regexp.MustCompile(`(?:^|[\s(\[,])#([\p{L}\p{N}_/-]+)`)
+	// inlineCodePattern matches `code spans`, whose contents are not tags.
+	inlineCodePattern = regexp.MustCompile("`[^`]*`")
+)
+
+// extractTags returns the tags of a note, lowercased, deduplicated, and sorted. Tags come
+// from the `tags` (or `tag`) frontmatter key, written as an inline list, a comma- or
+// space-separated scalar, or a block list, and from inline #tags in the body outside code.
+// As in Obsidian, a tag must contain at least one character that is not a digit.
+func extractTags(content []byte) []string {
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.Trim(strings.TrimSpace(tag), `"'`)
+		tag = strings.ToLower(strings.Trim(strings.TrimPrefix(tag, "#"), "/"))
+		if tag == "" || !strings.ContainsFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) {
+			return
+		}
+		seen[tag] = true
+	}
+
+	frontmatter, body, ok := splitFrontmatter(content)
+	if ok {
+		frontmatterTags(frontmatter, add)
+	}
+
+	inFence := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		line = inlineCodePattern.ReplaceAllString(line, "")
+		for _, match := range inlineTagPattern.FindAllStringSubmatch(line, -1) {
+			add(match[1])
+		}
+	}
+
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// frontmatterTags passes each tag listed under the `tags` or `tag` key of frontmatter to add.
+func frontmatterTags(frontmatter []byte, add func(string)) {
+	inList := false
+	scanner := bufio.NewScanner(bytes.NewReader(frontmatter))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Block list items follow the key on indented (or unindented) "- " lines
+		if inList {
+			if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+				add(item)
+				continue
+			}
+			inList = false
+		}
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "tags", "tag":
+		default:
+			continue
+		}
+		value, _, _ = strings.Cut(value, " #")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			inList = true
+			continue
+		}
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			add(tag)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "inline list frontmatter",
+			content: "---\ntags: [Golang, \"concurrency\"]\n---\n# Note\n",
+			want:    []string{"concurrency", "golang"},
+		},
+		{
+			name:    "block list frontmatter",
+			content: "---\ntitle: Plan\ntags:\n  - project/alpha\n  - '#planning'\nstatus: draft\n---\nBody",
+			want:    []string{"planning", "project/alpha"},
+		},
+		{
+			name:    "scalar frontmatter",
+			content: "---\ntag: golang, testing\n---\n",
+			want:    []string{"golang", "testing"},
+		},
+		{
+			name:    "inline body tags",
+			content: "# Heading\n\nNotes on #golang and (#Testing/unit), see issue #123.\n",
+			want:    []string{"golang", "testing/unit"},
+		},
+		{
+			name:    "code is skipped",
+			content: "Use `#define` here.\n\n```c\n#include <stdio.h>\n```\n\nReal #tag",
+			want:    []string{"tag"},
+		},
+		{
+			name:    "frontmatter and body combined without duplicates",
+			content: "---\ntags: [golang]\n---\n#golang #rust",
+			want:    []string{"golang", "rust"},
+		},
+		{
+			name:    "empty frontmatter",
+			content: "---\n---\n#golang",
+			want:    []string{"golang"},
+		},
+		{
+			name:    "no tags",
+			content: "# Title\n\nJust text with a url http://example.com/#anchor.",
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractTags([]byte(tt.content)); !slices.Equal(got, tt.want) {
+				t.Errorf("extractTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
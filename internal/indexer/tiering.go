@@ -0,0 +1,91 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// ColdStorageEnabled reports whether the pipeline has a cold collection configured.
+func (p *Pipeline) ColdStorageEnabled() bool {
+	return p.coldCollection != ""
+}
+
+// MoveToCold moves chunks of notes that have not been updated or retrieved since cutoff
+// from the primary collection into the cold collection. Cold chunks are excluded from
+// default queries, which keeps the hot search space small on large vaults.
+// Returns the number of notes moved. Errors for individual notes are logged and skipped.
+func (p *Pipeline) MoveToCold(ctx context.Context, cutoff time.Time) (int, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if !p.ColdStorageEnabled() {
+		return 0, fmt.Errorf("cold storage is not configured")
+	}
+
+	candidates, err := p.noteRepo.ListColdCandidates(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list cold candidates: %w", err)
+	}
+
+	logger.InfoContext(ctx, "applying cold storage policy",
+		"cutoff", cutoff.UTC().Format(time.RFC3339),
+		"candidates", len(candidates),
+	)
+
+	moved := 0
+	for _, note := range candidates {
+		select {
+		case <-ctx.Done():
+			return moved, ctx.Err()
+		default:
+		}
+
+		if err := p.moveNoteToCold(ctx, note); err != nil {
+			logger.WarnContext(ctx, "failed to move note to cold storage", "rel_path", note.RelPath, "error", err)
+			continue
+		}
+		moved++
+	}
+
+	logger.InfoContext(ctx, "cold storage policy applied", "moved", moved, "candidates", len(candidates))
+	return moved, nil
+}
+
+// moveNoteToCold copies a note's points into the cold collection, removes them from the
+// primary collection, and marks the note as cold.
+func (p *Pipeline) moveNoteToCold(ctx context.Context, note storage.NoteRecord) error {
+	chunkIDs, err := p.chunkRepo.ListIDsByNote(ctx, note.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunk IDs: %w", err)
+	}
+
+	if len(chunkIDs) > 0 {
+		points, err := p.vectorStore.Retrieve(ctx, p.chunkCollection(note.VaultID), chunkIDs)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve points: %w", err)
+		}
+
+		// Upsert into cold before deleting from hot so a failure never loses vectors
+		if err := p.vectorStore.Upsert(ctx, p.coldCollection, points); err != nil {
+			return fmt.Errorf("failed to upsert cold points: %w", err)
+		}
+		if err := p.vectorStore.Delete(ctx, p.chunkCollection(note.VaultID), chunkIDs); err != nil {
+			return fmt.Errorf("failed to delete hot points: %w", err)
+		}
+	}
+
+	// Cold notes are excluded from the note prefilter like their chunks are from default queries
+	if p.noteCollection != "" {
+		if err := p.vectorStore.Delete(ctx, p.noteCollection, []string{note.ID}); err != nil {
+			return fmt.Errorf("failed to delete note centroid: %w", err)
+		}
+	}
+
+	if err := p.noteRepo.SetTier(ctx, note.ID, storage.TierCold); err != nil {
+		return fmt.Errorf("failed to set note tier: %w", err)
+	}
+	return nil
+}
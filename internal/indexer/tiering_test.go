@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vault"
+	"helloworld-ai/internal/vectorstore"
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestPipeline_MoveToCold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockNoteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+
+	pipeline := NewPipeline(
+		&vault.Manager{},
+		mockNoteRepo,
+		mockChunkRepo,
+		nil,
+		nil,
+		&llm.EmbeddingsClient{},
+		mockVectorStore,
+		"notes",
+		"notes_cold",
+		"",
+		nil,
+	)
+
+	cutoff := time.Now().AddDate(0, -6, 0)
+	points := []vectorstore.Point{
+		{ID: "chunk-1", Vec: []float32{0.1, 0.2}},
+		{ID: "chunk-2", Vec: []float32{0.3, 0.4}},
+	}
+
+	mockNoteRepo.EXPECT().
+		ListColdCandidates(gomock.Any(), cutoff).
+		Return([]storage.NoteRecord{
+			{ID: "note-1", RelPath: "old.md"},
+			{ID: "note-2", RelPath: "broken.md"},
+		}, nil)
+
+	gomock.InOrder(
+		mockChunkRepo.EXPECT().ListIDsByNote(gomock.Any(), "note-1").Return([]string{"chunk-1", "chunk-2"}, nil),
+		mockVectorStore.EXPECT().Retrieve(gomock.Any(), "notes", []string{"chunk-1", "chunk-2"}).Return(points, nil),
+		mockVectorStore.EXPECT().Upsert(gomock.Any(), "notes_cold", points).Return(nil),
+		mockVectorStore.EXPECT().Delete(gomock.Any(), "notes", []string{"chunk-1", "chunk-2"}).Return(nil),
+		mockNoteRepo.EXPECT().SetTier(gomock.Any(), "note-1", storage.TierCold).Return(nil),
+	)
+
+	// Second note fails to list chunks and should be skipped without aborting
+	mockChunkRepo.EXPECT().ListIDsByNote(gomock.Any(), "note-2").Return(nil, errors.New("db error"))
+
+	moved, err := pipeline.MoveToCold(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("MoveToCold() error = %v", err)
+	}
+	if moved != 1 {
+		t.Errorf("MoveToCold() moved = %d, want 1", moved)
+	}
+}
+
+func TestPipeline_MoveToCold_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipeline := NewPipeline(
+		&vault.Manager{},
+		storage_mocks.NewMockNoteStore(ctrl),
+		storage_mocks.NewMockChunkStore(ctrl),
+		nil,
+		nil,
+		&llm.EmbeddingsClient{},
+		vectorstore_mocks.NewMockVectorStore(ctrl),
+		"notes",
+		"",
+		"",
+		nil,
+	)
+
+	if pipeline.ColdStorageEnabled() {
+		t.Error("ColdStorageEnabled() = true, want false without a cold collection")
+	}
+	if _, err := pipeline.MoveToCold(context.Background(), time.Now()); err == nil {
+		t.Error("MoveToCold() expected error when cold storage is not configured")
+	}
+}
@@ -5,4 +5,6 @@ type Chunk struct {
 	Index       int    // Chunk index within note (starts at 0)
 	HeadingPath string // Format: "# Heading1 > ## Heading2"
 	Text        string // Chunk text content
+	// OverlapRunes is the length of the leading text repeated from the previous chunk (0: none)
+	OverlapRunes int
 }
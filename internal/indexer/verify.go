@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// Verification statuses reported by VerifyIndex.
+const (
+	// VerifyStatusOK means the current index matches the checksum stored after the last index run.
+	VerifyStatusOK = "ok"
+	// VerifyStatusMismatch means notes or chunks changed outside the indexer since the last run.
+	VerifyStatusMismatch = "mismatch"
+	// VerifyStatusNotRecorded means no index run has stored a checksum for the vault yet.
+	VerifyStatusNotRecorded = "not_recorded"
+)
+
+// VaultVerification compares a vault's stored index checksum with a freshly computed one.
+type VaultVerification struct {
+	VaultID           int
+	VaultName         string
+	Status            string
+	StoredChecksum    string
+	CurrentChecksum   string
+	StoredNoteCount   int
+	CurrentNoteCount  int
+	StoredChunkCount  int
+	CurrentChunkCount int
+	RecordedAt        time.Time // When the stored checksum was computed (zero if not recorded)
+}
+
+// recordChecksums stores the current index checksum of every vault.
+// Failures are logged and never fail indexing.
+func (p *Pipeline) recordChecksums(ctx context.Context) {
+	if p.checksumRepo == nil {
+		return
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	for _, vault := range p.vaultManager.ListVaults() {
+		checksum, err := p.checksumRepo.Compute(ctx, vault.ID)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to compute index checksum", "vault", vault.Name, "error", err)
+			continue
+		}
+		if err := p.checksumRepo.Save(ctx, checksum); err != nil {
+			logger.WarnContext(ctx, "failed to save index checksum", "vault", vault.Name, "error", err)
+			continue
+		}
+		logger.DebugContext(ctx, "recorded index checksum",
+			"vault", vault.Name,
+			"checksum", checksum.Checksum,
+			"notes", checksum.NoteCount,
+			"chunks", checksum.ChunkCount,
+		)
+	}
+}
+
+// VerifyIndex recomputes each vault's index checksum and compares it with the one stored
+// after the last index run. A mismatch while indexing is in progress is expected.
+func (p *Pipeline) VerifyIndex(ctx context.Context) ([]VaultVerification, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if p.checksumRepo == nil {
+		return nil, fmt.Errorf("index checksum store is not configured")
+	}
+
+	vaults := p.vaultManager.ListVaults()
+	results := make([]VaultVerification, 0, len(vaults))
+	for _, vault := range vaults {
+		current, err := p.checksumRepo.Compute(ctx, vault.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute checksum for vault %s: %w", vault.Name, err)
+		}
+
+		result := VaultVerification{
+			VaultID:           vault.ID,
+			VaultName:         vault.Name,
+			CurrentChecksum:   current.Checksum,
+			CurrentNoteCount:  current.NoteCount,
+			CurrentChunkCount: current.ChunkCount,
+		}
+
+		stored, err := p.checksumRepo.Get(ctx, vault.ID)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			result.Status = VerifyStatusNotRecorded
+		case err != nil:
+			return nil, fmt.Errorf("failed to get stored checksum for vault %s: %w", vault.Name, err)
+		default:
+			result.StoredChecksum = stored.Checksum
+			result.StoredNoteCount = stored.NoteCount
+			result.StoredChunkCount = stored.ChunkCount
+			result.RecordedAt = stored.ComputedAt
+			result.Status = VerifyStatusOK
+			if stored.Checksum != current.Checksum {
+				result.Status = VerifyStatusMismatch
+				logger.WarnContext(ctx, "index checksum mismatch",
+					"vault", vault.Name,
+					"stored_checksum", stored.Checksum,
+					"current_checksum", current.Checksum,
+					"stored_notes", stored.NoteCount,
+					"current_notes", current.NoteCount,
+					"stored_chunks", stored.ChunkCount,
+					"current_chunks", current.ChunkCount,
+				)
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/storage"
+)
+
+// Index job types registered by RegisterIndexJobs.
+const (
+	// TypeReindex re-indexes all vaults or one (ReindexParams).
+	TypeReindex = "reindex"
+	// TypeClearIndex removes the indexed data of all vaults or one (ClearIndexParams).
+	TypeClearIndex = "clear_index"
+	// TypeDeleteFolder removes a folder prefix of a vault from the index (DeleteFolderParams).
+	TypeDeleteFolder = "delete_folder"
+)
+
+// IndexTypes are the job types that rewrite the index.
+var IndexTypes = []string{TypeReindex, TypeClearIndex, TypeDeleteFolder}
+
+// indexPollInterval is how often index jobs sample progress and check whether an
+// indexing pass started outside the queue (startup or automatic indexing) has ended.
+const indexPollInterval = time.Second
+
+// ReindexParams are the parameters of a TypeReindex job.
+type ReindexParams struct {
+	// Vault re-indexes only this vault (empty = all vaults).
+	Vault string `json:"vault,omitempty"`
+	// Force rebuilds from scratch: blue/green for all vaults when supported, otherwise
+	// clearing the vaults' existing data first.
+	Force bool `json:"force,omitempty"`
+}
+
+// ClearIndexParams are the parameters of a TypeClearIndex job.
+type ClearIndexParams struct {
+	// Vault clears only this vault (empty = all vaults).
+	Vault string `json:"vault,omitempty"`
+}
+
+// DeleteFolderParams are the parameters of a TypeDeleteFolder job.
+type DeleteFolderParams struct {
+	// Vault is the vault the folder belongs to.
+	Vault string `json:"vault"`
+	// Prefix is the folder prefix to remove, with its subfolders.
+	Prefix string `json:"prefix"`
+}
+
+// VaultResolver looks up configured vaults by name. *vault.Manager implements it.
+type VaultResolver interface {
+	VaultByName(name string) (storage.VaultRecord, error)
+}
+
+// RegisterIndexJobs registers the reindex, clear_index, and delete_folder job types.
+// Vault names are checked when a job is queued and resolved again when it runs.
+func RegisterIndexJobs(q *Queue, pipeline *indexer.Pipeline, vaults VaultResolver) {
+	idx := &indexJobs{pipeline: pipeline, vaults: vaults}
+	q.Register(TypeReindex, Handler{Validate: idx.validateReindex, Run: idx.reindex})
+	q.Register(TypeClearIndex, Handler{Validate: idx.validateClear, Run: idx.clear})
+	q.Register(TypeDeleteFolder, Handler{Validate: idx.validateDeleteFolder, Run: idx.deleteFolder})
+}
+
+// indexJobs runs index job types against the indexer pipeline.
+type indexJobs struct {
+	pipeline *indexer.Pipeline
+	vaults   VaultResolver
+}
+
+func (j *indexJobs) validateReindex(raw json.RawMessage) error {
+	var params ReindexParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	_, err := j.vault(params.Vault)
+	return err
+}
+
+func (j *indexJobs) validateClear(raw json.RawMessage) error {
+	var params ClearIndexParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	_, err := j.vault(params.Vault)
+	return err
+}
+
+func (j *indexJobs) validateDeleteFolder(raw json.RawMessage) error {
+	var params DeleteFolderParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	if params.Vault == "" {
+		return errors.New("vault is required")
+	}
+	if strings.Trim(params.Prefix, "/") == "" {
+		return errors.New("prefix is required")
+	}
+	_, err := j.vault(params.Vault)
+	return err
+}
+
+// reindex runs a TypeReindex job. A forced re-index of all vaults is built beside the
+// live index and swapped in when supported, so questions never see a partial index.
+func (j *indexJobs) reindex(ctx context.Context, raw json.RawMessage, progress func(float64)) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	var params ReindexParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	vaultRecord, err := j.vault(params.Vault)
+	if err != nil {
+		return err
+	}
+	if err := j.waitIdle(ctx); err != nil {
+		return err
+	}
+
+	stop := j.reportProgress(ctx, progress)
+	defer stop()
+
+	if params.Force && vaultRecord.ID == 0 && j.pipeline.RebuildSupported() {
+		if err := j.pipeline.Rebuild(ctx); err != nil {
+			return fmt.Errorf("rebuild failed, current index kept: %w", err)
+		}
+		return nil
+	}
+	if params.Force {
+		if err := j.clearVault(ctx, vaultRecord.ID); err != nil {
+			return fmt.Errorf("failed to clear existing data: %w", err)
+		}
+		logger.InfoContext(ctx, "cleared existing indexed data", "vault", vaultRecord.Name)
+	}
+	if vaultRecord.ID != 0 {
+		return j.pipeline.IndexVault(ctx, vaultRecord.ID)
+	}
+	return j.pipeline.IndexAll(ctx)
+}
+
+// clear runs a TypeClearIndex job.
+func (j *indexJobs) clear(ctx context.Context, raw json.RawMessage, _ func(float64)) error {
+	var params ClearIndexParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	vaultRecord, err := j.vault(params.Vault)
+	if err != nil {
+		return err
+	}
+	if err := j.waitIdle(ctx); err != nil {
+		return err
+	}
+	return j.clearVault(ctx, vaultRecord.ID)
+}
+
+// deleteFolder runs a TypeDeleteFolder job.
+func (j *indexJobs) deleteFolder(ctx context.Context, raw json.RawMessage, _ func(float64)) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	var params DeleteFolderParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	vaultRecord, err := j.vault(params.Vault)
+	if err != nil {
+		return err
+	}
+	if err := j.waitIdle(ctx); err != nil {
+		return err
+	}
+
+	result, err := j.pipeline.DeleteFolder(ctx, vaultRecord.ID, params.Prefix)
+	if err != nil {
+		return err
+	}
+	logger.InfoContext(ctx, "folder deleted from index", "vault", vaultRecord.Name, "prefix", params.Prefix,
+		"notes", result.Notes, "chunks", result.Chunks, "failed", result.Failed)
+	if result.Failed > 0 {
+		return fmt.Errorf("failed to delete %d notes", result.Failed)
+	}
+	return nil
+}
+
+// vault resolves a vault name. The zero record means all vaults.
+func (j *indexJobs) vault(name string) (storage.VaultRecord, error) {
+	if name == "" {
+		return storage.VaultRecord{}, nil
+	}
+	vaultRecord, err := j.vaults.VaultByName(name)
+	if err != nil {
+		return storage.VaultRecord{}, fmt.Errorf("unknown vault: %s", name)
+	}
+	return vaultRecord, nil
+}
+
+// clearVault removes the indexed data of a vault, or of all vaults when vaultID is 0.
+func (j *indexJobs) clearVault(ctx context.Context, vaultID int) error {
+	if vaultID == 0 {
+		return j.pipeline.ClearAll(ctx)
+	}
+	result, err := j.pipeline.ClearVault(ctx, vaultID)
+	if err != nil {
+		return err
+	}
+	if result.Failed > 0 {
+		return fmt.Errorf("failed to clear %d notes", result.Failed)
+	}
+	return nil
+}
+
+// waitIdle waits for an indexing pass started outside the queue to end, so a job never
+// rewrites the index under it.
+func (j *indexJobs) waitIdle(ctx context.Context) error {
+	if !j.pipeline.IndexProgress().Running {
+		return nil
+	}
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "waiting for the running indexing pass to finish")
+	ticker := time.NewTicker(indexPollInterval)
+	defer ticker.Stop()
+	for j.pipeline.IndexProgress().Running {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// reportProgress samples the pipeline's indexing progress until the returned function
+// is called.
+func (j *indexJobs) reportProgress(ctx context.Context, progress func(float64)) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(indexPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if pr := j.pipeline.IndexProgress(); pr.Running && pr.FilesScanned > 0 {
+				progress(float64(pr.FilesIndexed+pr.FilesFailed) / float64(pr.FilesScanned))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// decodeParams decodes job parameters, rejecting unknown fields so typos are reported
+// when a job is queued rather than silently ignored.
+func decodeParams(raw json.RawMessage, params any) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(params); err != nil {
+		return fmt.Errorf("failed to decode params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"helloworld-ai/internal/indexer"
+	"helloworld-ai/internal/storage"
+)
+
+type stubVaults map[string]int
+
+func (v stubVaults) VaultByName(name string) (storage.VaultRecord, error) {
+	id, ok := v[name]
+	if !ok {
+		return storage.VaultRecord{}, storage.ErrNotFound
+	}
+	return storage.VaultRecord{ID: id, Name: name}, nil
+}
+
+func TestRegisterIndexJobs_Validate(t *testing.T) {
+	queue, _ := newTestQueue(t)
+	RegisterIndexJobs(queue, &indexer.Pipeline{}, stubVaults{"personal": 1})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		jobType string
+		params  any
+		wantErr bool
+	}{
+		{name: "reindex all vaults", jobType: TypeReindex, params: nil},
+		{name: "force reindex one vault", jobType: TypeReindex, params: ReindexParams{Vault: "personal", Force: true}},
+		{name: "reindex unknown vault", jobType: TypeReindex, params: ReindexParams{Vault: "work"}, wantErr: true},
+		{name: "reindex unknown field", jobType: TypeReindex, params: map[string]any{"vaults": "personal"}, wantErr: true},
+		{name: "clear one vault", jobType: TypeClearIndex, params: ClearIndexParams{Vault: "personal"}},
+		{name: "delete folder", jobType: TypeDeleteFolder, params: DeleteFolderParams{Vault: "personal", Prefix: "Archive"}},
+		{name: "delete folder without prefix", jobType: TypeDeleteFolder, params: DeleteFolderParams{Vault: "personal", Prefix: "/"}, wantErr: true},
+		{name: "delete folder without vault", jobType: TypeDeleteFolder, params: DeleteFolderParams{Prefix: "Archive"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := queue.Enqueue(ctx, tt.jobType, tt.params)
+			if tt.wantErr && !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Enqueue() error = %v, want ErrInvalidParams", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Enqueue() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Package jobs runs long-running operations (re-indexing, clearing, bulk deletes) on a
+// background worker. Jobs are persisted in the jobs table, so clients can enqueue work
+// and poll its state and progress, and a restart marks interrupted jobs as failed.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+const (
+	// defaultPollInterval is how often the worker looks for queued jobs without being
+	// woken by Enqueue, so jobs queued by another process are picked up too.
+	defaultPollInterval = 5 * time.Second
+	// interruptedError is recorded for jobs that were running when the server stopped.
+	interruptedError = "interrupted by shutdown"
+)
+
+var (
+	// ErrUnknownType is returned by Enqueue for a job type with no registered handler.
+	ErrUnknownType = errors.New("unknown job type")
+	// ErrInvalidParams is returned by Enqueue when a handler rejects the job parameters.
+	ErrInvalidParams = errors.New("invalid job params")
+)
+
+// Handler runs one type of job.
+type Handler struct {
+	// Validate checks the parameters of a job before it is queued (nil accepts any).
+	Validate func(params json.RawMessage) error
+	// Run does the work. It reports the fraction done (0 to 1) through progress and
+	// should return promptly when ctx is cancelled.
+	Run func(ctx context.Context, params json.RawMessage, progress func(float64)) error
+}
+
+// Queue persists jobs and runs them one at a time on a single worker, so operations
+// that rewrite the index never overlap.
+type Queue struct {
+	store        storage.JobStore
+	handlers     map[string]Handler
+	wake         chan struct{}
+	pollInterval time.Duration
+}
+
+// NewQueue creates a new Queue. Register handlers before calling Enqueue or Run.
+func NewQueue(store storage.JobStore) *Queue {
+	return &Queue{
+		store:        store,
+		handlers:     make(map[string]Handler),
+		wake:         make(chan struct{}, 1),
+		pollInterval: defaultPollInterval,
+	}
+}
+
+// Register sets the handler for a job type.
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// Types returns the registered job types, sorted.
+func (q *Queue) Types() []string {
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Enqueue validates and persists a job, and wakes the worker. params is encoded as JSON
+// (nil for no parameters). Returns ErrUnknownType or ErrInvalidParams (wrapped) for jobs
+// that cannot run.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, params any) (*storage.JobRecord, error) {
+	handler, ok := q.handlers[jobType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, jobType)
+	}
+
+	encoded := []byte("{}")
+	if params != nil {
+		var err error
+		if encoded, err = json.Marshal(params); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+		}
+	}
+	if handler.Validate != nil {
+		if err := handler.Validate(encoded); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+		}
+	}
+
+	job := &storage.JobRecord{Type: jobType, Params: string(encoded)}
+	if err := q.store.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get returns a job by ID. Returns storage.ErrNotFound if it does not exist.
+func (q *Queue) Get(ctx context.Context, id string) (*storage.JobRecord, error) {
+	return q.store.Get(ctx, id)
+}
+
+// Active returns the oldest queued or running job of one of the given types (any type
+// when none are given), or nil when there is none.
+func (q *Queue) Active(ctx context.Context, jobTypes ...string) (*storage.JobRecord, error) {
+	active, err := q.store.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range active {
+		if len(jobTypes) == 0 || slices.Contains(jobTypes, job.Type) {
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Run marks jobs left running by a previous process as failed, then runs queued jobs
+// until ctx is cancelled. A job running at shutdown is cancelled and recorded as failed.
+func (q *Queue) Run(ctx context.Context) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if failed, err := q.store.FailRunning(ctx, interruptedError); err != nil {
+		logger.ErrorContext(ctx, "failed to mark interrupted jobs as failed", "error", err)
+	} else if failed > 0 {
+		logger.WarnContext(ctx, "marked interrupted jobs as failed", "jobs", failed)
+	}
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil && q.runNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs the oldest queued job. Returns false when none was queued (or
+// the queue could not be read), so Run waits before trying again.
+func (q *Queue) runNext(ctx context.Context) bool {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	job, err := q.store.ClaimNext(ctx)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to claim job", "error", err)
+		return false
+	}
+
+	logger = logger.With("job_id", job.ID, "job_type", job.Type)
+	jobCtx := context.WithValue(ctx, contextutil.LoggerKey(), logger)
+	logger.InfoContext(jobCtx, "job started")
+	start := time.Now()
+
+	err = q.execute(jobCtx, job)
+	errMsg := ""
+	switch {
+	case err != nil && ctx.Err() != nil:
+		errMsg = interruptedError
+	case err != nil:
+		errMsg = err.Error()
+	}
+
+	// Record the outcome even when the job was cancelled by shutdown
+	if finishErr := q.store.Finish(context.WithoutCancel(ctx), job.ID, errMsg); finishErr != nil {
+		logger.ErrorContext(jobCtx, "failed to record job outcome", "error", finishErr)
+	}
+	if err != nil {
+		logger.ErrorContext(jobCtx, "job failed", "duration_ms", time.Since(start).Milliseconds(), "error", err)
+	} else {
+		logger.InfoContext(jobCtx, "job succeeded", "duration_ms", time.Since(start).Milliseconds())
+	}
+	return true
+}
+
+// execute runs a claimed job's handler, turning a panic into an error so one bad job
+// does not stop the worker.
+func (q *Queue) execute(ctx context.Context, job *storage.JobRecord) (err error) {
+	handler, ok := q.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownType, job.Type)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	progress := func(done float64) {
+		if err := q.store.UpdateProgress(ctx, job.ID, done); err != nil {
+			contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to record job progress", "error", err)
+		}
+	}
+	return handler.Run(ctx, json.RawMessage(job.Params), progress)
+}
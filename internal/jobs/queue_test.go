@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/storage"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *storage.JobRepo) {
+	t.Helper()
+	db, err := storage.New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	repo := storage.NewJobRepo(db)
+	queue := NewQueue(repo)
+	queue.pollInterval = 10 * time.Millisecond
+	return queue, repo
+}
+
+// waitForState polls a job until it reaches state or the test times out.
+func waitForState(t *testing.T, queue *Queue, id, state string) *storage.JobRecord {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := queue.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.State == state {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	queue, _ := newTestQueue(t)
+	queue.Register("echo", Handler{
+		Validate: func(params json.RawMessage) error {
+			var p struct{ N int }
+			_ = json.Unmarshal(params, &p)
+			if p.N < 0 {
+				return errors.New("n must not be negative")
+			}
+			return nil
+		},
+		Run: func(context.Context, json.RawMessage, func(float64)) error { return nil },
+	})
+	ctx := context.Background()
+
+	if _, err := queue.Enqueue(ctx, "missing", nil); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Enqueue(unknown type) error = %v, want ErrUnknownType", err)
+	}
+	if _, err := queue.Enqueue(ctx, "echo", map[string]int{"N": -1}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Enqueue(invalid params) error = %v, want ErrInvalidParams", err)
+	}
+
+	job, err := queue.Enqueue(ctx, "echo", map[string]int{"N": 2})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if job.ID == "" || job.State != storage.JobQueued || job.Params != `{"N":2}` {
+		t.Errorf("Enqueue() = %+v, want a queued job with its params", job)
+	}
+	if active, err := queue.Active(ctx, "other"); err != nil || active != nil {
+		t.Errorf("Active(other) = %+v, %v, want none", active, err)
+	}
+	if active, err := queue.Active(ctx, "echo"); err != nil || active == nil || active.ID != job.ID {
+		t.Errorf("Active(echo) = %+v, %v, want the queued job", active, err)
+	}
+	if types := queue.Types(); len(types) != 1 || types[0] != "echo" {
+		t.Errorf("Types() = %v, want [echo]", types)
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	queue, repo := newTestQueue(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A job left running by a previous process is failed when the worker starts
+	stale := &storage.JobRecord{Type: "work"}
+	if err := repo.Create(ctx, stale); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := repo.ClaimNext(ctx); err != nil {
+		t.Fatalf("ClaimNext() error = %v", err)
+	}
+
+	release := make(chan struct{})
+	queue.Register("work", Handler{Run: func(ctx context.Context, _ json.RawMessage, progress func(float64)) error {
+		progress(0.5)
+		<-release
+		return nil
+	}})
+	queue.Register("fail", Handler{Run: func(context.Context, json.RawMessage, func(float64)) error {
+		return errors.New("disk full")
+	}})
+	queue.Register("panic", Handler{Run: func(context.Context, json.RawMessage, func(float64)) error {
+		panic("boom")
+	}})
+	queue.Register("block", Handler{Run: func(ctx context.Context, _ json.RawMessage, _ func(float64)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+
+	if job := waitForState(t, queue, stale.ID, storage.JobFailed); job.Error != interruptedError {
+		t.Errorf("stale job error = %q, want %q", job.Error, interruptedError)
+	}
+
+	work, _ := queue.Enqueue(ctx, "work", nil)
+	failing, _ := queue.Enqueue(ctx, "fail", nil)
+	panicking, _ := queue.Enqueue(ctx, "panic", nil)
+
+	// Jobs run one at a time: the others wait while the first one runs
+	running := waitForState(t, queue, work.ID, storage.JobRunning)
+	for deadline := time.Now().Add(5 * time.Second); running.Progress != 0.5 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+		running, _ = queue.Get(ctx, work.ID)
+	}
+	if running.Progress != 0.5 {
+		t.Errorf("running job progress = %v, want 0.5", running.Progress)
+	}
+	if job, _ := queue.Get(ctx, failing.ID); job.State != storage.JobQueued {
+		t.Errorf("second job state = %s while the first runs, want queued", job.State)
+	}
+	close(release)
+
+	if job := waitForState(t, queue, work.ID, storage.JobSucceeded); job.Progress != 1 || job.FinishedAt == nil {
+		t.Errorf("succeeded job = %+v, want progress 1 and a finish time", job)
+	}
+	if job := waitForState(t, queue, failing.ID, storage.JobFailed); job.Error != "disk full" {
+		t.Errorf("failed job error = %q, want disk full", job.Error)
+	}
+	if job := waitForState(t, queue, panicking.ID, storage.JobFailed); job.Error != "job panicked: boom" {
+		t.Errorf("panicked job error = %q, want job panicked: boom", job.Error)
+	}
+
+	// Shutdown cancels the running job and records it as interrupted
+	blocking, _ := queue.Enqueue(ctx, "block", nil)
+	waitForState(t, queue, blocking.ID, storage.JobRunning)
+	cancel()
+	<-done
+	if job, _ := queue.Get(context.Background(), blocking.ID); job.State != storage.JobFailed || job.Error != interruptedError {
+		t.Errorf("job running at shutdown = %s %q, want failed %q", job.State, job.Error, interruptedError)
+	}
+}
@@ -0,0 +1,174 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helloworld-ai/internal/llm"
+)
+
+// PromptVersion identifies the judge prompt of DefaultRubric, so scores from different
+// prompts are not compared. Rubric files carry their own version.
+const PromptVersion = "answer-judge-v2"
+
+// sourceChars bounds how much of each source is shown to the judge.
+const sourceChars = 1200
+
+// ChatBackend generates chat completions. *llm.Client implements it.
+type ChatBackend interface {
+	ChatWithMessages(ctx context.Context, messages []llm.Message, params llm.ChatParams) (string, error)
+}
+
+// Source is one retrieved chunk an answer was generated from.
+type Source struct {
+	// Label names the source for the judge, e.g. "Garden.md (Tomatoes)".
+	Label string
+	// Text is the chunk text.
+	Text string
+}
+
+// Verdict is the judge's scores for one answer.
+type Verdict struct {
+	// Faithfulness is how fully the answer's claims are supported by the sources (0-1, 0
+	// when the rubric does not score it).
+	Faithfulness float32
+	// Relevance is how directly the answer addresses the question (0-1, 0 when the rubric
+	// does not score it).
+	Relevance float32
+	// Scores holds every criterion of the rubric by name, normalized from its scale to 0-1.
+	Scores map[string]float32
+	// Reason is the judge's one-line explanation, naming unsupported claims if any.
+	Reason string
+	// RubricVersion identifies the rubric the answer was graded with.
+	RubricVersion string
+}
+
+// Judge scores generated answers against the sources they were generated from with the
+// local chat model, replacing the judge scripts of the Python eval for live requests.
+type Judge struct {
+	chat   ChatBackend
+	rubric Rubric
+}
+
+// New creates a Judge that asks chat for verdicts on rubric, which must be valid.
+func New(chat ChatBackend, rubric Rubric) *Judge {
+	return &Judge{chat: chat, rubric: rubric}
+}
+
+// Rubric returns the rubric the judge grades on.
+func (j *Judge) Rubric() Rubric {
+	return j.rubric
+}
+
+// Score asks the chat model to grade answer to question on the rubric's criteria against
+// sources. It fails when the model fails or its reply has no valid scores.
+func (j *Judge) Score(ctx context.Context, question, answer string, sources []Source) (Verdict, error) {
+	var sourceBuilder strings.Builder
+	for i, source := range sources {
+		fmt.Fprintf(&sourceBuilder, "[Source %d] %s\n%s\n\n", i+1, source.Label, truncateRunes(source.Text, sourceChars))
+	}
+	var criteria, example strings.Builder
+	example.WriteString("{")
+	for _, criterion := range j.rubric.Criteria {
+		fmt.Fprintf(&criteria, "- %s: %s\n", criterion.Name, criterion.Guide)
+		fmt.Fprintf(&example, "%q: %g, ", criterion.Name, j.rubric.Scale.Max)
+	}
+	example.WriteString(`"reason": "one short sentence naming any unsupported claim"}`)
+
+	prompt := fmt.Sprintf(`Grade an answer that was written from the user's notes.
+
+Question: %s
+
+Notes:
+%s
+Answer:
+%s
+
+Instructions:
+- Score each criterion from %g to %g:
+%s- Judge only against the notes; do not use outside knowledge, and ignore citation formatting
+- Return ONLY a JSON object like %s, nothing else
+
+Your response (JSON object only):`, question, sourceBuilder.String(), answer, j.rubric.Scale.Min, j.rubric.Scale.Max, criteria.String(), example.String())
+
+	reply, err := j.chat.ChatWithMessages(ctx, []llm.Message{
+		{Role: "user", Content: prompt},
+	}, llm.ChatParams{
+		Model:          "",                              // Use default from client
+		MaxTokens:      100 + 25*len(j.rubric.Criteria), // The scores and one sentence
+		Temperature:    0,                               // Deterministic verdicts for the same answer
+		ResponseFormat: verdictFormat(j.rubric),
+	})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to judge answer: %w", err)
+	}
+	return parseVerdict(reply, j.rubric)
+}
+
+// verdictFormat constrains the judge's reply to the verdict object of rubric.
+func verdictFormat(rubric Rubric) *llm.ResponseFormat {
+	score := map[string]any{"type": "number", "minimum": rubric.Scale.Min, "maximum": rubric.Scale.Max}
+	properties := map[string]any{"reason": map[string]any{"type": "string"}}
+	required := make([]string, 0, len(rubric.Criteria)+1)
+	for _, criterion := range rubric.Criteria {
+		properties[criterion.Name] = score
+		required = append(required, criterion.Name)
+	}
+	return llm.JSONSchemaFormat("answer_judgement", map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             append(required, "reason"),
+		"additionalProperties": false,
+	})
+}
+
+// parseVerdict extracts the scores and reason from the judge's reply. Servers that ignore
+// response_format may wrap the object in prose, so the outermost {...} is decoded. Scores
+// outside the rubric's scale are rejected rather than clamped, since they indicate a
+// misread scale.
+func parseVerdict(reply string, rubric Rubric) (Verdict, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return Verdict{}, fmt.Errorf("reply is not a JSON object")
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &fields); err != nil {
+		return Verdict{}, fmt.Errorf("failed to unmarshal judge verdict: %w", err)
+	}
+
+	verdict := Verdict{Scores: make(map[string]float32, len(rubric.Criteria)), RubricVersion: rubric.Version}
+	for _, criterion := range rubric.Criteria {
+		raw, ok := fields[criterion.Name]
+		if !ok {
+			return Verdict{}, fmt.Errorf("judge verdict is missing the %s score", criterion.Name)
+		}
+		var score float32
+		if err := json.Unmarshal(raw, &score); err != nil {
+			return Verdict{}, fmt.Errorf("judge verdict %s score is not a number: %w", criterion.Name, err)
+		}
+		if score < rubric.Scale.Min || score > rubric.Scale.Max {
+			return Verdict{}, fmt.Errorf("%s score %v is outside [%g, %g]", criterion.Name, score, rubric.Scale.Min, rubric.Scale.Max)
+		}
+		verdict.Scores[criterion.Name] = rubric.normalize(score)
+	}
+	if raw, ok := fields["reason"]; ok {
+		var reason string
+		if err := json.Unmarshal(raw, &reason); err == nil {
+			verdict.Reason = strings.TrimSpace(reason)
+		}
+	}
+	verdict.Faithfulness = verdict.Scores[CriterionFaithfulness]
+	verdict.Relevance = verdict.Scores[CriterionRelevance]
+	return verdict, nil
+}
+
+// truncateRunes shortens text to at most n runes, marking the cut with "...".
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
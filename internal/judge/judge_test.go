@@ -0,0 +1,88 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+// fakeChat returns a fixed reply and records the prompt it was sent.
+type fakeChat struct {
+	reply  string
+	err    error
+	prompt string
+	params llm.ChatParams
+}
+
+func (f *fakeChat) ChatWithMessages(_ context.Context, messages []llm.Message, params llm.ChatParams) (string, error) {
+	f.prompt = messages[len(messages)-1].Content
+	f.params = params
+	return f.reply, f.err
+}
+
+func TestJudge_Score(t *testing.T) {
+	chat := &fakeChat{reply: `{"faithfulness": 0.5, "relevance": 1, "reason": "The watering schedule is not in the notes."}`}
+	sources := []Source{{Label: "Garden.md (Tomatoes)", Text: "Tomatoes grow in the north bed."}}
+
+	verdict, err := New(chat, DefaultRubric(0)).Score(context.Background(), "Where do tomatoes grow?", "In the north bed, watered daily.", sources)
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if verdict.Faithfulness != 0.5 || verdict.Relevance != 1 || verdict.Reason != "The watering schedule is not in the notes." || verdict.RubricVersion != PromptVersion {
+		t.Errorf("Score() = %+v", verdict)
+	}
+	for _, want := range []string{"Where do tomatoes grow?", "[Source 1] Garden.md (Tomatoes)", "Tomatoes grow in the north bed.", "In the north bed, watered daily."} {
+		if !strings.Contains(chat.prompt, want) {
+			t.Errorf("judge prompt missing %q", want)
+		}
+	}
+	if chat.params.ResponseFormat == nil || chat.params.Temperature != 0 {
+		t.Errorf("judge params = %+v, want a response format at temperature 0", chat.params)
+	}
+
+	chat.err = errors.New("connection refused")
+	if _, err := New(chat, DefaultRubric(0)).Score(context.Background(), "q", "a", sources); err == nil {
+		t.Error("Score() error = nil, want the chat error")
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    Verdict
+		wantErr bool
+	}{
+		{name: "bare object", reply: `{"faithfulness": 0.9, "relevance": 0.8, "reason": " Supported. "}`, want: verdict(0.9, 0.8, "Supported.")},
+		{name: "wrapped in prose", reply: "Here is my grade:\n```json\n{\"faithfulness\": 0, \"relevance\": 0.5}\n```", want: verdict(0, 0.5, "")},
+		{name: "missing relevance", reply: `{"faithfulness": 1}`, wantErr: true},
+		{name: "score out of range", reply: `{"faithfulness": 8, "relevance": 1}`, wantErr: true},
+		{name: "not json", reply: "The answer is faithful.", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVerdict(tt.reply, DefaultRubric(0))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVerdict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseVerdict() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// verdict is the Verdict parseVerdict returns for a DefaultRubric reply.
+func verdict(faithfulness, relevance float32, reason string) Verdict {
+	return Verdict{
+		Faithfulness:  faithfulness,
+		Relevance:     relevance,
+		Scores:        map[string]float32{CriterionFaithfulness: faithfulness, CriterionRelevance: relevance},
+		Reason:        reason,
+		RubricVersion: PromptVersion,
+	}
+}
@@ -0,0 +1,187 @@
+package judge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// Criteria of DefaultRubric. Rubric files may score other criteria too.
+const (
+	// CriterionFaithfulness is how fully an answer's claims are supported by its sources.
+	// Regeneration needs it, since the model is told its claims were unsupported.
+	CriterionFaithfulness = "faithfulness"
+	// CriterionRelevance is how directly an answer addresses the question.
+	CriterionRelevance = "relevance"
+)
+
+// criterionName matches the names a criterion may use as its key in the judge's reply.
+var criterionName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Rubric is what the judge grades answers on: the criteria it scores, the scale it scores
+// them on, and the thresholds that act on the scores. DefaultRubric grades faithfulness and
+// relevance from 0 to 1; LoadRubric reads a rubric from a JSON file (ANSWER_JUDGE_RUBRIC).
+type Rubric struct {
+	// Version identifies the rubric in verdicts, so scores from different rubrics are not compared.
+	Version string `json:"version"`
+	// Scale is the range every criterion is scored on, e.g. 0-1 or 1-5.
+	Scale Scale `json:"scale"`
+	// Criteria are the scored dimensions, in prompt order.
+	Criteria []Criterion `json:"criteria"`
+	// Thresholds act on the scores, in the units of Scale.
+	Thresholds Thresholds `json:"thresholds"`
+}
+
+// Scale is the score range of a rubric.
+type Scale struct {
+	Min float32 `json:"min"`
+	Max float32 `json:"max"`
+}
+
+// Criterion is one scored dimension of a rubric.
+type Criterion struct {
+	// Name is the score's key in the judge's reply, e.g. "completeness".
+	Name string `json:"name"`
+	// Guide tells the judge how to score the criterion on the rubric's scale.
+	Guide string `json:"guide"`
+}
+
+// Thresholds are the scores below which an answer is acted on, in the units of the
+// rubric's scale. Zero disables a threshold.
+type Thresholds struct {
+	// Regenerate generates answers scored less faithful than this again.
+	Regenerate float32 `json:"regenerate,omitempty"`
+	// Abstain overrides answers that still score below these after any regeneration with
+	// an abstention, keyed by criterion.
+	Abstain map[string]float32 `json:"abstain,omitempty"`
+}
+
+// DefaultRubric grades faithfulness and relevance from 0 to 1, regenerating answers less
+// faithful than regenerateBelow (ANSWER_JUDGE_FAITHFULNESS_THRESHOLD, 0 only scores).
+func DefaultRubric(regenerateBelow float32) Rubric {
+	return Rubric{
+		Version: PromptVersion,
+		Scale:   Scale{Min: 0, Max: 1},
+		Criteria: []Criterion{
+			{Name: CriterionFaithfulness, Guide: "1.0 if every claim in the answer is stated in the notes, 0.5 if some claims are not, 0.0 if the answer is mostly unsupported. Saying the notes lack the information is supported"},
+			{Name: CriterionRelevance, Guide: "1.0 if the answer directly addresses the question, 0.5 if only in part, 0.0 if it does not"},
+		},
+		Thresholds: Thresholds{Regenerate: regenerateBelow},
+	}
+}
+
+// ConfiguredRubric loads the rubric file at path (ANSWER_JUDGE_RUBRIC), or returns
+// DefaultRubric(regenerateBelow) when path is empty.
+func ConfiguredRubric(path string, regenerateBelow float32) (Rubric, error) {
+	if path == "" {
+		return DefaultRubric(regenerateBelow), nil
+	}
+	return LoadRubric(path)
+}
+
+// LoadRubric reads a rubric from the JSON file at path. Unknown fields are rejected, so a
+// misspelled threshold fails instead of silently not applying.
+func LoadRubric(path string) (Rubric, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rubric{}, fmt.Errorf("failed to read judge rubric: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var rubric Rubric
+	if err := decoder.Decode(&rubric); err != nil {
+		return Rubric{}, fmt.Errorf("failed to parse judge rubric %s: %w", path, err)
+	}
+	if err := rubric.Validate(); err != nil {
+		return Rubric{}, fmt.Errorf("invalid judge rubric %s: %w", path, err)
+	}
+	return rubric, nil
+}
+
+// Validate checks that the rubric has a version, a scale, uniquely named criteria, and
+// thresholds on its scale for criteria it scores.
+func (r Rubric) Validate() error {
+	if r.Version == "" {
+		return fmt.Errorf("version is required")
+	}
+	if r.Scale.Max <= r.Scale.Min {
+		return fmt.Errorf("scale max %g must be above min %g", r.Scale.Max, r.Scale.Min)
+	}
+	if len(r.Criteria) == 0 {
+		return fmt.Errorf("at least one criterion is required")
+	}
+	for i, criterion := range r.Criteria {
+		if !criterionName.MatchString(criterion.Name) || criterion.Name == "reason" {
+			return fmt.Errorf("criterion name %q must be lowercase letters, digits, and underscores, and not \"reason\"", criterion.Name)
+		}
+		if criterion.Guide == "" {
+			return fmt.Errorf("criterion %s has no guide", criterion.Name)
+		}
+		if slices.ContainsFunc(r.Criteria[:i], func(c Criterion) bool { return c.Name == criterion.Name }) {
+			return fmt.Errorf("criterion %s is listed twice", criterion.Name)
+		}
+	}
+	if r.Thresholds.Regenerate != 0 {
+		if err := r.checkThreshold("regenerate", r.Thresholds.Regenerate); err != nil {
+			return err
+		}
+		if !r.scores(CriterionFaithfulness) {
+			return fmt.Errorf("the regenerate threshold needs a %s criterion", CriterionFaithfulness)
+		}
+	}
+	for name, threshold := range r.Thresholds.Abstain {
+		if !r.scores(name) {
+			return fmt.Errorf("abstain threshold for %s, which the rubric does not score", name)
+		}
+		if threshold == 0 {
+			continue
+		}
+		if err := r.checkThreshold("abstain "+name, threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegenerateBelow is the regenerate threshold normalized to 0-1 like verdict scores.
+func (r Rubric) RegenerateBelow() float32 {
+	if r.Thresholds.Regenerate == 0 {
+		return 0
+	}
+	return r.normalize(r.Thresholds.Regenerate)
+}
+
+// AbstainBelow is the abstain thresholds normalized to 0-1 like verdict scores, by criterion.
+func (r Rubric) AbstainBelow() map[string]float32 {
+	if len(r.Thresholds.Abstain) == 0 {
+		return nil
+	}
+	thresholds := make(map[string]float32, len(r.Thresholds.Abstain))
+	for name, threshold := range r.Thresholds.Abstain {
+		if threshold != 0 {
+			thresholds[name] = r.normalize(threshold)
+		}
+	}
+	return thresholds
+}
+
+// scores reports whether the rubric has a criterion named name.
+func (r Rubric) scores(name string) bool {
+	return slices.ContainsFunc(r.Criteria, func(c Criterion) bool { return c.Name == name })
+}
+
+// checkThreshold rejects thresholds outside the rubric's scale.
+func (r Rubric) checkThreshold(name string, threshold float32) error {
+	if threshold < r.Scale.Min || threshold > r.Scale.Max {
+		return fmt.Errorf("%s threshold %g is outside the scale %g-%g", name, threshold, r.Scale.Min, r.Scale.Max)
+	}
+	return nil
+}
+
+// normalize maps a score on the rubric's scale to 0-1.
+func (r Rubric) normalize(score float32) float32 {
+	return (score - r.Scale.Min) / (r.Scale.Max - r.Scale.Min)
+}
@@ -0,0 +1,100 @@
+package judge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRubric(t *testing.T) {
+	valid := `{
+		"version": "garden-rubric-v1",
+		"scale": {"min": 1, "max": 5},
+		"criteria": [
+			{"name": "faithfulness", "guide": "5 if every claim is in the notes, 1 if none is"},
+			{"name": "completeness", "guide": "5 if the answer covers every part of the question, 1 if it covers none"}
+		],
+		"thresholds": {"regenerate": 3, "abstain": {"faithfulness": 2}}
+	}`
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: valid},
+		{name: "unknown field", content: `{"version": "v1", "scale": {"min": 0, "max": 1}, "criteria": [{"name": "relevance", "guide": "g"}], "treshold": 0.5}`, wantErr: "unknown field"},
+		{name: "missing version", content: `{"scale": {"min": 0, "max": 1}, "criteria": [{"name": "relevance", "guide": "g"}]}`, wantErr: "version is required"},
+		{name: "empty scale", content: `{"version": "v1", "criteria": [{"name": "relevance", "guide": "g"}]}`, wantErr: "scale max"},
+		{name: "no criteria", content: `{"version": "v1", "scale": {"min": 0, "max": 1}}`, wantErr: "at least one criterion"},
+		{name: "duplicate criterion", content: `{"version": "v1", "scale": {"min": 0, "max": 1}, "criteria": [{"name": "relevance", "guide": "g"}, {"name": "relevance", "guide": "g"}]}`, wantErr: "listed twice"},
+		{name: "reserved criterion name", content: `{"version": "v1", "scale": {"min": 0, "max": 1}, "criteria": [{"name": "reason", "guide": "g"}]}`, wantErr: "criterion name"},
+		{name: "regenerate without faithfulness", content: `{"version": "v1", "scale": {"min": 0, "max": 1}, "criteria": [{"name": "relevance", "guide": "g"}], "thresholds": {"regenerate": 0.5}}`, wantErr: "needs a faithfulness criterion"},
+		{name: "abstain on an unscored criterion", content: `{"version": "v1", "scale": {"min": 0, "max": 1}, "criteria": [{"name": "relevance", "guide": "g"}], "thresholds": {"abstain": {"completeness": 0.5}}}`, wantErr: "does not score"},
+		{name: "threshold off the scale", content: `{"version": "v1", "scale": {"min": 1, "max": 5}, "criteria": [{"name": "relevance", "guide": "g"}], "thresholds": {"abstain": {"relevance": 7}}}`, wantErr: "outside the scale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rubric.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write rubric: %v", err)
+			}
+			_, err := LoadRubric(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadRubric() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("LoadRubric() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadRubric(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadRubric() error = nil for a missing file")
+	}
+}
+
+func TestJudge_ScoreWithRubric(t *testing.T) {
+	rubric := Rubric{
+		Version: "garden-rubric-v1",
+		Scale:   Scale{Min: 1, Max: 5},
+		Criteria: []Criterion{
+			{Name: CriterionFaithfulness, Guide: "5 if every claim is in the notes, 1 if none is"},
+			{Name: "completeness", Guide: "5 if the answer covers every part of the question, 1 if it covers none"},
+		},
+		Thresholds: Thresholds{Regenerate: 3, Abstain: map[string]float32{"completeness": 2}},
+	}
+	if err := rubric.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := rubric.RegenerateBelow(); got != 0.5 {
+		t.Errorf("RegenerateBelow() = %v, want 0.5", got)
+	}
+	if got := rubric.AbstainBelow(); got["completeness"] != 0.25 {
+		t.Errorf("AbstainBelow() = %v, want completeness 0.25", got)
+	}
+
+	chat := &fakeChat{reply: `{"faithfulness": 4, "completeness": 2, "reason": "Misses the watering schedule."}`}
+	verdict, err := New(chat, rubric).Score(context.Background(), "How do I grow tomatoes?", "In the north bed.", []Source{{Label: "Garden.md", Text: "Tomatoes grow in the north bed."}})
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if verdict.Faithfulness != 0.75 || verdict.Relevance != 0 || verdict.Scores["completeness"] != 0.25 || verdict.RubricVersion != "garden-rubric-v1" {
+		t.Errorf("Score() = %+v, want scores normalized from 1-5", verdict)
+	}
+	for _, want := range []string{"Score each criterion from 1 to 5", "- completeness: 5 if the answer covers", `"completeness": 5`} {
+		if !strings.Contains(chat.prompt, want) {
+			t.Errorf("judge prompt missing %q:\n%s", want, chat.prompt)
+		}
+	}
+
+	chat.reply = `{"faithfulness": 4, "reason": "No completeness."}`
+	if _, err := New(chat, rubric).Score(context.Background(), "q", "a", nil); err == nil {
+		t.Error("Score() error = nil, want the missing completeness score")
+	}
+	chat.reply = `{"faithfulness": 0.8, "completeness": 4}`
+	if _, err := New(chat, rubric).Score(context.Background(), "q", "a", nil); err == nil {
+		t.Error("Score() error = nil, want a score below the scale")
+	}
+}
@@ -9,15 +9,28 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/tracing"
 )
 
 // Client is a client for interacting with llama.cpp chat completions API.
 type Client struct {
 	BaseURL string
 	APIKey  string
-	Model   string
-	client  *http.Client
+	// Model is the default chat model. Once the client is shared, read it with ModelName
+	// and change it with SetModel.
+	Model  string
+	client *http.Client
+	// slots bounds concurrent chat requests (nil is unbounded, see SetMaxConcurrency).
+	slots chan struct{}
+	// modelMu guards Model, which an admin model swap changes at runtime.
+	modelMu sync.RWMutex
+	// models reloads idle-unloaded models before requests (nil: none, see SetModelManager).
+	models *ModelManager
 }
 
 // newHTTPClient creates a configured HTTP client with timeouts and connection pooling.
@@ -44,6 +57,63 @@ func NewClient(baseURL, apiKey, model string) *Client {
 	}
 }
 
+// SetMaxConcurrency bounds concurrent chat requests to n, normally the server's slot count.
+// Requests beyond it wait here, where the HTTP timeout does not run, instead of in the
+// server's queue. n <= 0 removes the bound. Call it before the client is shared.
+func (c *Client) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		c.slots = nil
+		return
+	}
+	c.slots = make(chan struct{}, n)
+}
+
+// SetModelManager makes requests acquire their model from models, which reloads a model
+// unloaded for being idle and keeps it loaded while the request runs. Call it before the
+// client is shared.
+func (c *Client) SetModelManager(models *ModelManager) {
+	c.models = models
+}
+
+// ModelName returns the default chat model.
+func (c *Client) ModelName() string {
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	return c.Model
+}
+
+// SetModel changes the default chat model for subsequent requests.
+func (c *Client) SetModel(model string) {
+	c.modelMu.Lock()
+	defer c.modelMu.Unlock()
+	c.Model = model
+}
+
+// acquireSlot waits for a free generation slot, after making sure model is loaded when a
+// ModelManager is set. The returned release must be called once done.
+func (c *Client) acquireSlot(ctx context.Context, model string) (func(), error) {
+	releaseModel := func() {}
+	if c.models != nil {
+		var err error
+		if releaseModel, err = c.models.Acquire(ctx, model); err != nil {
+			return nil, err
+		}
+	}
+	if c.slots == nil {
+		return releaseModel, nil
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return func() {
+			<-c.slots
+			releaseModel()
+		}, nil
+	case <-ctx.Done():
+		releaseModel()
+		return nil, fmt.Errorf("failed to acquire generation slot: %w", ctx.Err())
+	}
+}
+
 // ChatMessage represents a single message in a chat conversation.
 type ChatMessage struct {
 	Role    string `json:"role"`
@@ -57,6 +127,13 @@ type ChatRequest struct {
 	Stream      bool          `json:"stream,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Temperature float32       `json:"temperature,omitempty"`
+	// Sampling controls understood by llama.cpp's OpenAI-compatible endpoint
+	Stop          []string `json:"stop,omitempty"`
+	RepeatPenalty float32  `json:"repeat_penalty,omitempty"`
+	TopP          float32  `json:"top_p,omitempty"`
+	TopK          int      `json:"top_k,omitempty"`
+	// ResponseFormat requests schema-constrained JSON output
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 }
 
 // ChatChoiceMessage represents the message in a chat choice.
@@ -84,7 +161,7 @@ func (c *Client) Chat(ctx context.Context, message string) (string, error) {
 	url := fmt.Sprintf("%s/v1/chat/completions", c.BaseURL)
 
 	payload := ChatRequest{
-		Model: c.Model,
+		Model: c.ModelName(),
 		Messages: []ChatMessage{
 			{
 				Role:    "user",
@@ -106,6 +183,12 @@ func (c *Client) Chat(ctx context.Context, message string) (string, error) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
 	req.Header.Set("Content-Type", "application/json")
 
+	release, err := c.acquireSlot(ctx, payload.Model)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
@@ -137,7 +220,7 @@ func (c *Client) StreamChat(ctx context.Context, message string, callback func(c
 	url := fmt.Sprintf("%s/v1/chat/completions", c.BaseURL)
 
 	payload := ChatRequest{
-		Model: c.Model,
+		Model: c.ModelName(),
 		Messages: []ChatMessage{
 			{
 				Role:    "user",
@@ -161,6 +244,12 @@ func (c *Client) StreamChat(ctx context.Context, message string, callback func(c
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "text/event-stream")
 
+	release, err := c.acquireSlot(ctx, payload.Model)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
@@ -174,8 +263,181 @@ func (c *Client) StreamChat(ctx context.Context, message string, callback func(c
 		return fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
 	}
 
-	// Read Server-Sent Events
-	scanner := bufio.NewScanner(resp.Body)
+	return readChatStream(resp.Body, callback)
+}
+
+// ChatWithMessages sends a chat completion request with structured messages and parameters.
+// This method is used by the RAG engine and other consumers that need system prompts
+// and multiple messages. The existing Chat method remains for backward compatibility.
+func (c *Client) ChatWithMessages(ctx context.Context, messages []Message, params ChatParams) (string, error) {
+	payload := c.messagesPayload(messages, params)
+	ctx, span := tracing.Start(ctx, "llm.chat", chatAttributes(payload, false)...)
+	defer span.End()
+
+	answer, err := c.sendChat(ctx, payload)
+	span.RecordError(err)
+	return answer, err
+}
+
+// sendChat posts a non-streaming chat completion request and returns the reply.
+func (c *Client) sendChat(ctx context.Context, payload ChatRequest) (string, error) {
+	url := fmt.Sprintf("%s/v1/chat/completions", c.BaseURL)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	req.Header.Set("Content-Type", "application/json")
+
+	release, err := c.acquireSlot(ctx, payload.Model)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
+	}
+
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// StreamChatWithMessages is the streaming form of ChatWithMessages: it calls callback with
+// each chunk of the reply as the server generates it.
+func (c *Client) StreamChatWithMessages(ctx context.Context, messages []Message, params ChatParams, callback func(chunk string) error) error {
+	payload := c.messagesPayload(messages, params)
+	payload.Stream = true
+	ctx, span := tracing.Start(ctx, "llm.chat", chatAttributes(payload, true)...)
+	defer span.End()
+
+	err := c.streamChat(ctx, payload, callback)
+	span.RecordError(err)
+	return err
+}
+
+// streamChat posts a streaming chat completion request and passes each chunk to callback.
+func (c *Client) streamChat(ctx context.Context, payload ChatRequest, callback func(chunk string) error) error {
+	url := fmt.Sprintf("%s/v1/chat/completions", c.BaseURL)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	release, err := c.acquireSlot(ctx, payload.Model)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
+	}
+
+	return readChatStream(resp.Body, callback)
+}
+
+// messagesPayload builds the chat completion request for messages and params.
+// chatAttributes describes a chat request on its trace span.
+func chatAttributes(payload ChatRequest, stream bool) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("llm.model", payload.Model),
+		attribute.Int("llm.messages", len(payload.Messages)),
+		attribute.Bool("llm.stream", stream),
+	}
+}
+
+func (c *Client) messagesPayload(messages []Message, params ChatParams) ChatRequest {
+	// Convert []Message to []ChatMessage for internal API call
+	chatMessages := make([]ChatMessage, len(messages))
+	for i, msg := range messages {
+		chatMessages[i] = ChatMessage(msg) // Direct conversion since structs are identical
+	}
+
+	// Use params.Model if provided, otherwise fallback to client's default model
+	model := params.Model
+	if model == "" {
+		model = c.ModelName()
+	}
+
+	payload := ChatRequest{
+		Model:    model,
+		Messages: chatMessages,
+	}
+
+	// Add optional parameters if specified
+	if params.MaxTokens > 0 {
+		payload.MaxTokens = params.MaxTokens
+	}
+	if params.Temperature > 0 {
+		payload.Temperature = params.Temperature
+	}
+	if len(params.Stop) > 0 {
+		payload.Stop = params.Stop
+	}
+	if params.RepeatPenalty > 0 {
+		payload.RepeatPenalty = params.RepeatPenalty
+	}
+	if params.TopP > 0 {
+		payload.TopP = params.TopP
+	}
+	if params.TopK > 0 {
+		payload.TopK = params.TopK
+	}
+	if params.ResponseFormat != nil {
+		payload.ResponseFormat = params.ResponseFormat
+	}
+
+	return payload
+}
+
+// readChatStream reads Server-Sent Events from a streaming chat completion response and
+// calls callback for each non-empty content chunk.
+func readChatStream(body io.Reader, callback func(chunk string) error) error {
+	scanner := bufio.NewScanner(body)
 	var dataPrefix = "data: "
 	var donePrefix = "[DONE]"
 
@@ -229,72 +491,3 @@ func (c *Client) StreamChat(ctx context.Context, message string, callback func(c
 
 	return nil
 }
-
-// ChatWithMessages sends a chat completion request with structured messages and parameters.
-// This method is used by the RAG engine and other consumers that need system prompts
-// and multiple messages. The existing Chat method remains for backward compatibility.
-func (c *Client) ChatWithMessages(ctx context.Context, messages []Message, params ChatParams) (string, error) {
-	url := fmt.Sprintf("%s/v1/chat/completions", c.BaseURL)
-
-	// Convert []Message to []ChatMessage for internal API call
-	chatMessages := make([]ChatMessage, len(messages))
-	for i, msg := range messages {
-		chatMessages[i] = ChatMessage(msg) // Direct conversion since structs are identical
-	}
-
-	// Use params.Model if provided, otherwise fallback to client's default model
-	model := params.Model
-	if model == "" {
-		model = c.Model
-	}
-
-	payload := ChatRequest{
-		Model:    model,
-		Messages: chatMessages,
-	}
-
-	// Add optional parameters if specified
-	if params.MaxTokens > 0 {
-		payload.MaxTokens = params.MaxTokens
-	}
-	if params.Temperature > 0 {
-		payload.Temperature = params.Temperature
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		raw, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
-	}
-
-	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices returned")
-	}
-
-	return chatResp.Choices[0].Message.Content, nil
-}
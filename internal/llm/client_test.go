@@ -255,6 +255,45 @@ func TestClient_ChatWithMessages(t *testing.T) {
 	}
 }
 
+func TestClient_StreamChatWithMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req) // Ignore decode error in test
+
+		if !req.Stream {
+			t.Errorf("expected a streaming request")
+		}
+		if len(req.Messages) != 2 || req.Model != "custom-model" || req.Temperature != 0.3 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n"))
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\" there\"},\"finish_reason\":\"stop\"}]}\n\n"))
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", "test-model")
+
+	messages := []Message{
+		{Role: "system", Content: "You are a helpful assistant"},
+		{Role: "user", Content: "Hello"},
+	}
+
+	var chunks []string
+	err := client.StreamChatWithMessages(context.Background(), messages, ChatParams{Model: "custom-model", Temperature: 0.3}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamChatWithMessages() error = %v", err)
+	}
+	if len(chunks) != 2 || chunks[0] != "Hello" || chunks[1] != " there" {
+		t.Errorf("StreamChatWithMessages() chunks = %q, want [Hello  there]", chunks)
+	}
+}
+
 func TestClient_ChatWithMessages_DefaultModel(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req ChatRequest
@@ -298,3 +337,127 @@ func TestClient_ChatWithMessages_DefaultModel(t *testing.T) {
 		t.Errorf("ChatWithMessages() reply = %v, want Response", reply)
 	}
 }
+
+func TestClient_ChatWithMessages_SamplingParams(t *testing.T) {
+	var raw map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = nil
+		_ = json.NewDecoder(r.Body).Decode(&raw) // Ignore decode error in test
+
+		resp := ChatResponse{
+			Choices: []ChatChoice{{Message: ChatChoiceMessage{Content: "Response"}}},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", "test-model")
+	messages := []Message{{Role: "user", Content: "Hello"}}
+
+	params := ChatParams{
+		Stop:          []string{"\n\nCitations:", "<|im_end|>"},
+		RepeatPenalty: 1.1,
+		TopP:          0.9,
+		TopK:          40,
+	}
+	if _, err := client.ChatWithMessages(context.Background(), messages, params); err != nil {
+		t.Fatalf("ChatWithMessages() error = %v", err)
+	}
+
+	stop, _ := raw["stop"].([]any)
+	if len(stop) != 2 || stop[0] != "\n\nCitations:" || stop[1] != "<|im_end|>" {
+		t.Errorf("stop = %v, want the configured stop sequences", raw["stop"])
+	}
+	if v, _ := raw["repeat_penalty"].(float64); v < 1.09 || v > 1.11 {
+		t.Errorf("repeat_penalty = %v, want 1.1", raw["repeat_penalty"])
+	}
+	if v, _ := raw["top_p"].(float64); v < 0.89 || v > 0.91 {
+		t.Errorf("top_p = %v, want 0.9", raw["top_p"])
+	}
+	if raw["top_k"] != float64(40) {
+		t.Errorf("top_k = %v, want 40", raw["top_k"])
+	}
+
+	// Unset controls are omitted so the server defaults apply
+	if _, err := client.ChatWithMessages(context.Background(), messages, ChatParams{}); err != nil {
+		t.Fatalf("ChatWithMessages() error = %v", err)
+	}
+	for _, key := range []string{"stop", "repeat_penalty", "top_p", "top_k", "response_format"} {
+		if _, ok := raw[key]; ok {
+			t.Errorf("payload contains %s = %v, want it omitted", key, raw[key])
+		}
+	}
+}
+
+func TestClient_ChatWithMessages_ResponseFormat(t *testing.T) {
+	var raw map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&raw) // Ignore decode error in test
+
+		resp := ChatResponse{
+			Choices: []ChatChoice{{Message: ChatChoiceMessage{Content: `["a"]`}}},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", "test-model")
+	schema := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	params := ChatParams{ResponseFormat: JSONSchemaFormat("folders", schema)}
+	if _, err := client.ChatWithMessages(context.Background(), []Message{{Role: "user", Content: "Rank"}}, params); err != nil {
+		t.Fatalf("ChatWithMessages() error = %v", err)
+	}
+
+	format, _ := raw["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("response_format = %v, want type json_schema", raw["response_format"])
+	}
+	jsonSchema, _ := format["json_schema"].(map[string]any)
+	if jsonSchema["name"] != "folders" || jsonSchema["strict"] != true {
+		t.Errorf("json_schema = %v, want name folders and strict", jsonSchema)
+	}
+	if s, _ := jsonSchema["schema"].(map[string]any); s["type"] != "array" {
+		t.Errorf("json_schema.schema = %v, want the array schema", jsonSchema["schema"])
+	}
+}
+
+func TestClient_SetMaxConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		_ = json.NewEncoder(w).Encode(ChatResponse{Choices: []ChatChoice{{Message: ChatChoiceMessage{Content: "ok"}}}})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", "test-model")
+	client.SetMaxConcurrency(1)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.ChatWithMessages(context.Background(), []Message{{Role: "user", Content: "first"}}, ChatParams{})
+		done <- err
+	}()
+	<-started
+
+	// The only slot is taken, so a second request waits until its context ends
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ChatWithMessages(ctx, []Message{{Role: "user", Content: "second"}}, ChatParams{}); err == nil || !strings.Contains(err.Error(), "generation slot") {
+		t.Errorf("ChatWithMessages() with all slots busy error = %v, want a slot error", err)
+	}
+	if len(started) != 0 {
+		t.Error("second request reached the server while the only slot was busy")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first ChatWithMessages() error = %v", err)
+	}
+	if _, err := client.ChatWithMessages(context.Background(), []Message{{Role: "user", Content: "third"}}, ChatParams{}); err != nil {
+		t.Errorf("ChatWithMessages() after the slot was released error = %v", err)
+	}
+}
@@ -4,10 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/tracing"
 )
 
 // EmbeddingsClient is a client for interacting with llama.cpp embeddings API.
@@ -17,8 +25,21 @@ type EmbeddingsClient struct {
 	Model        string
 	ExpectedSize int // Expected vector size for validation
 	client       *http.Client
+	// models reloads the model if it was unloaded for being idle (nil: none, see SetModelManager).
+	models *ModelManager
+	// slots bounds concurrent embedding requests (nil is unbounded, see SetMaxConcurrency).
+	slots chan struct{}
+	// retryBackoff is the first wait before retrying a server error; it doubles per attempt.
+	retryBackoff time.Duration
 }
 
+// Server errors (5xx) are retried a few times with exponential backoff and full jitter, so
+// parallel requests that failed together do not retry in lockstep.
+const (
+	maxEmbedAttempts  = 3
+	embedRetryBackoff = 200 * time.Millisecond
+)
+
 // NewEmbeddingsClient creates a new embeddings client.
 // expectedSize is the expected vector size (from QDRANT_VECTOR_SIZE config).
 // All embeddings returned by EmbedTexts will be validated against this size.
@@ -29,9 +50,34 @@ func NewEmbeddingsClient(baseURL, apiKey, model string, expectedSize int) *Embed
 		Model:        model,
 		ExpectedSize: expectedSize,
 		client:       newHTTPClient(),
+		retryBackoff: embedRetryBackoff,
+	}
+}
+
+// SetMaxConcurrency bounds concurrent embedding requests to n, normally the embedding
+// server's slot count, across every caller sharing the client (indexing and questions).
+// Requests beyond it wait here, where the HTTP timeout does not run. The connection pool
+// keeps at least n idle connections to the server, so parallel requests reuse keep-alive
+// connections instead of reconnecting. n <= 0 removes the bound. Call it before the client
+// is shared.
+func (c *EmbeddingsClient) SetMaxConcurrency(n int) {
+	if n <= 0 {
+		c.slots = nil
+		return
+	}
+	c.slots = make(chan struct{}, n)
+	if transport, ok := c.client.Transport.(*http.Transport); ok && transport.MaxIdleConnsPerHost < n {
+		transport.MaxIdleConnsPerHost = n
+		transport.MaxIdleConns = max(transport.MaxIdleConns, n)
 	}
 }
 
+// SetModelManager makes requests acquire the model from models, which reloads it if it was
+// unloaded for being idle. Call it before the client is shared.
+func (c *EmbeddingsClient) SetModelManager(models *ModelManager) {
+	c.models = models
+}
+
 // EmbeddingsRequest represents the request payload for embeddings API.
 type EmbeddingsRequest struct {
 	Model string   `json:"model"`
@@ -108,6 +154,76 @@ func (c *EmbeddingsClient) EmbedTexts(ctx context.Context, texts []string) ([][]
 		return nil, fmt.Errorf("empty input array")
 	}
 
+	ctx, span := tracing.Start(ctx, "llm.embeddings",
+		attribute.String("llm.model", c.Model),
+		attribute.Int("llm.texts", len(texts)),
+	)
+	defer span.End()
+
+	vectors, err := c.embedWithRetry(ctx, texts)
+	span.RecordError(err)
+	return vectors, err
+}
+
+// embedWithRetry embeds texts, retrying server errors up to maxEmbedAttempts times. The
+// wait before each retry is drawn uniformly from zero to a doubling backoff, and no slot is
+// held while waiting. Other errors, including context size errors, are returned at once.
+func (c *EmbeddingsClient) embedWithRetry(ctx context.Context, texts []string) ([][]float32, error) {
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		vectors, err := c.embed(ctx, texts)
+		var embedErr *EmbeddingError
+		if err == nil || attempt == maxEmbedAttempts || !errors.As(err, &embedErr) ||
+			embedErr.StatusCode < http.StatusInternalServerError || embedErr.IsExceedContextSizeError() {
+			return vectors, err
+		}
+
+		wait := time.Duration(0)
+		if backoff > 0 {
+			wait = rand.N(backoff)
+		}
+		logger := contextutil.LoggerFromContext(ctx)
+		logger.WarnContext(ctx, "embedding request failed, retrying",
+			"attempt", attempt,
+			"status", embedErr.StatusCode,
+			"wait_ms", wait.Milliseconds(),
+		)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("embedding retry cancelled: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// acquireSlot waits for a free embedding slot, after making sure the model is loaded when a
+// ModelManager is set. The returned release must be called once done.
+func (c *EmbeddingsClient) acquireSlot(ctx context.Context) (func(), error) {
+	releaseModel := func() {}
+	if c.models != nil {
+		var err error
+		if releaseModel, err = c.models.Acquire(ctx, c.Model); err != nil {
+			return nil, err
+		}
+	}
+	if c.slots == nil {
+		return releaseModel, nil
+	}
+	select {
+	case c.slots <- struct{}{}:
+		return func() {
+			<-c.slots
+			releaseModel()
+		}, nil
+	case <-ctx.Done():
+		releaseModel()
+		return nil, fmt.Errorf("failed to acquire embedding slot: %w", ctx.Err())
+	}
+}
+
+// embed requests embeddings for a non-empty batch of texts and validates their size.
+func (c *EmbeddingsClient) embed(ctx context.Context, texts []string) ([][]float32, error) {
 	url := fmt.Sprintf("%s/v1/embeddings", c.BaseURL)
 
 	payload := EmbeddingsRequest{
@@ -128,11 +244,19 @@ func (c *EmbeddingsClient) EmbedTexts(ctx context.Context, texts []string) ([][]
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
 	req.Header.Set("Content-Type", "application/json")
 
+	release, err := c.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer func() {
+		// Reading to EOF lets the connection go back to the keep-alive pool
+		_, _ = io.Copy(io.Discard, resp.Body)
 		_ = resp.Body.Close()
 	}()
 
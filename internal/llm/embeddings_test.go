@@ -3,9 +3,13 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewEmbeddingsClient(t *testing.T) {
@@ -178,3 +182,89 @@ func TestEmbeddingsClient_EmbedTexts_ConvertsFloat64ToFloat32(t *testing.T) {
 		t.Errorf("EmbedTexts() embedding[2] = %v, want 3.5", emb[2])
 	}
 }
+
+func TestEmbeddingsClient_RetriesServerErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		failures     int
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "recovers after server errors", status: http.StatusServiceUnavailable, failures: 2, wantRequests: 3},
+		{name: "gives up after max attempts", status: http.StatusBadGateway, failures: 5, wantErr: true, wantRequests: maxEmbedAttempts},
+		{name: "client errors are not retried", status: http.StatusBadRequest, failures: 5, wantErr: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					_, _ = w.Write([]byte("server busy"))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(EmbeddingsResponse{Data: []EmbeddingData{{Embedding: make([]float64, 4)}}})
+			}))
+			defer server.Close()
+
+			client := NewEmbeddingsClient(server.URL, "test-key", "test-model", 4)
+			client.retryBackoff = time.Millisecond
+			_, err := client.EmbedTexts(context.Background(), []string{"Hello"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EmbedTexts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var embedErr *EmbeddingError
+			if tt.wantErr && (!errors.As(err, &embedErr) || embedErr.StatusCode != tt.status) {
+				t.Errorf("EmbedTexts() error = %v, want an EmbeddingError with status %d", err, tt.status)
+			}
+			if got := int(requests.Load()); got != tt.wantRequests {
+				t.Errorf("server got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestEmbeddingsClient_SetMaxConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		_ = json.NewEncoder(w).Encode(EmbeddingsResponse{Data: []EmbeddingData{{Embedding: make([]float64, 4)}}})
+	}))
+	defer server.Close()
+
+	client := NewEmbeddingsClient(server.URL, "test-key", "test-model", 4)
+	client.SetMaxConcurrency(16)
+	if transport := client.client.Transport.(*http.Transport); transport.MaxIdleConnsPerHost < 16 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want at least the 16 in-flight requests", transport.MaxIdleConnsPerHost)
+	}
+	client.SetMaxConcurrency(1)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.EmbedTexts(context.Background(), []string{"first"})
+		done <- err
+	}()
+	<-started
+
+	// The only slot is taken, so a second request waits until its context ends
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.EmbedTexts(ctx, []string{"second"}); err == nil || !strings.Contains(err.Error(), "embedding slot") {
+		t.Errorf("EmbedTexts() with all slots busy error = %v, want a slot error", err)
+	}
+	if len(started) != 0 {
+		t.Error("second request reached the server while the only slot was busy")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first EmbedTexts() error = %v", err)
+	}
+	if _, err := client.EmbedTexts(context.Background(), []string{"third"}); err != nil {
+		t.Errorf("EmbedTexts() after the slot was released error = %v", err)
+	}
+}
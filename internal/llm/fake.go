@@ -0,0 +1,208 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"unicode"
+)
+
+// FakeAnswerPrefix starts every canned answer from the fake LLM so tests can recognise it.
+const FakeAnswerPrefix = "Test mode answer."
+
+// FakeServer is an in-process stand-in for the llama.cpp server used in test mode.
+// It serves the OpenAI-compatible chat and embeddings endpoints with deterministic
+// responses, so the API runs without llama.cpp.
+type FakeServer struct {
+	// URL is the base URL to configure LLM and embeddings clients with.
+	URL string
+
+	server     *httptest.Server
+	vectorSize int
+}
+
+// NewFakeServer starts a fake LLM server producing embeddings of the given size.
+// Call Close to shut it down.
+func NewFakeServer(vectorSize int) *FakeServer {
+	f := &FakeServer{vectorSize: vectorSize}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", f.handleChat)
+	mux.HandleFunc("/v1/embeddings", f.handleEmbeddings)
+	mux.HandleFunc("/tokenize", f.handleTokenize)
+
+	f.server = httptest.NewServer(mux)
+	f.URL = f.server.URL
+	return f
+}
+
+// Close shuts down the fake server.
+func (f *FakeServer) Close() {
+	f.server.Close()
+}
+
+// handleChat returns a canned answer. Folder ranking prompts get an empty JSON array;
+// RAG prompts get an answer citing the first chunk in the context so reference extraction works,
+// as a cited_answer JSON object when that response format is requested.
+func (f *FakeServer) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	prompt := ""
+	if len(req.Messages) > 0 {
+		prompt = req.Messages[len(req.Messages)-1].Content
+	}
+	answer := FakeChatAnswer(prompt)
+	if req.ResponseFormat != nil && req.ResponseFormat.JSONSchema != nil && req.ResponseFormat.JSONSchema.Name == "cited_answer" {
+		answer = fakeCitedAnswer(answer, prompt)
+	}
+
+	if req.Stream {
+		// Stream word by word, like a server emitting tokens
+		w.Header().Set("Content-Type", "text/event-stream")
+		words := strings.SplitAfter(answer, " ")
+		for i, word := range words {
+			finishReason := ""
+			if i == len(words)-1 {
+				finishReason = "stop"
+			}
+			chunk, _ := json.Marshal(map[string]any{
+				"choices": []map[string]any{{"delta": map[string]string{"content": word}, "finish_reason": finishReason}},
+			})
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ChatResponse{
+		ID:     "fake-chat",
+		Object: "chat.completion",
+		Choices: []ChatChoice{{
+			Message:      ChatChoiceMessage{Role: "assistant", Content: answer},
+			FinishReason: "stop",
+		}},
+	})
+}
+
+// FakeChatAnswer returns the deterministic answer the fake LLM gives for a prompt.
+func FakeChatAnswer(prompt string) string {
+	if strings.Contains(prompt, "folder ranking assistant") {
+		return "[]"
+	}
+
+	file, section := firstContextSource(prompt)
+	if file == "" {
+		return FakeAnswerPrefix
+	}
+	return fmt.Sprintf("%s See the note for details. [File: %s, Section: %s]", FakeAnswerPrefix, file, section)
+}
+
+// fakeCitedAnswer wraps answer in a cited_answer object citing the first chunk ID in the prompt.
+func fakeCitedAnswer(answer, prompt string) string {
+	citations := []map[string]string{}
+	for _, line := range strings.Split(prompt, "\n") {
+		if idx := strings.Index(line, "] ID: "); idx >= 0 && strings.HasPrefix(line, "[Chunk ") {
+			citations = append(citations, map[string]string{"chunk_id": strings.TrimSpace(line[idx+len("] ID: "):])})
+			break
+		}
+	}
+	reply, _ := json.Marshal(map[string]any{"answer": answer, "citations": citations})
+	return string(reply)
+}
+
+// firstContextSource extracts the file and section of the first chunk in a RAG context block.
+func firstContextSource(prompt string) (string, string) {
+	var file, section string
+	for _, line := range strings.Split(prompt, "\n") {
+		if file == "" {
+			if idx := strings.Index(line, "File: "); idx >= 0 && strings.HasPrefix(line, "[Vault:") {
+				file = strings.TrimSpace(line[idx+len("File: "):])
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "Section: ") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "Section: "))
+			break
+		}
+	}
+	return file, section
+}
+
+// handleEmbeddings returns deterministic bag-of-words embeddings so texts sharing words score higher.
+func (f *FakeServer) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req EmbeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := EmbeddingsResponse{Data: make([]EmbeddingData, 0, len(req.Input))}
+	for _, text := range req.Input {
+		resp.Data = append(resp.Data, EmbeddingData{Embedding: FakeEmbedding(text, f.vectorSize)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleTokenize returns one token per whitespace-separated word, a stand-in for a real tokenizer.
+func (f *FakeServer) handleTokenize(w http.ResponseWriter, r *http.Request) {
+	var req TokenizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	words := strings.Fields(req.Content)
+	resp := TokenizeResponse{Tokens: make([]int, len(words))}
+	for i, word := range words {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		resp.Tokens[i] = int(h.Sum32() % 32000)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// FakeEmbedding hashes each lower-cased word of text into one of size buckets and
+// L2-normalises the result. Identical texts always produce identical vectors.
+func FakeEmbedding(text string, size int) []float64 {
+	vec := make([]float64, size)
+	if size == 0 {
+		return vec
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		vec[h.Sum32()%uint32(size)]++
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm == 0 {
+		// Empty text still needs a non-zero vector for cosine similarity
+		vec[0] = 1
+		return vec
+	}
+	norm = math.Sqrt(norm)
+	for i := range vec {
+		vec[i] /= norm
+	}
+	return vec
+}
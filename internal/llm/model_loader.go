@@ -7,13 +7,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
 // ModelLoader loads models into llama.cpp server via the /models/load endpoint.
+// It also keeps the server properties fetched per model (see FetchProps).
 type ModelLoader struct {
 	baseURL string
 	client  *http.Client
+
+	mu    sync.Mutex
+	props map[string]ServerProps
 }
 
 // NewModelLoader creates a new model loader.
@@ -21,6 +26,7 @@ func NewModelLoader(baseURL string) *ModelLoader {
 	return &ModelLoader{
 		baseURL: baseURL,
 		client:  newHTTPClient(),
+		props:   make(map[string]ServerProps),
 	}
 }
 
@@ -52,17 +58,41 @@ type ModelsResponse struct {
 	Data []ModelStatus `json:"data"`
 }
 
+// UnloadModelRequest represents the request payload for unloading a model.
+type UnloadModelRequest struct {
+	Model string `json:"model"`
+}
+
+// Readiness polling backoff used by LoadModel.
+const (
+	// DefaultLoadTimeout is how long LoadModel waits for a model by default.
+	DefaultLoadTimeout = 60 * time.Second
+	// readyInitialBackoff is the first wait between readiness checks; it doubles up to readyMaxBackoff.
+	readyInitialBackoff = 250 * time.Millisecond
+	// readyMaxBackoff caps the wait between readiness checks.
+	readyMaxBackoff = 5 * time.Second
+)
+
 // IsModelLoaded checks if a model is already loaded (in cache) in the llama.cpp server.
 func (ml *ModelLoader) IsModelLoaded(ctx context.Context, modelName string) (bool, error) {
+	status, err := ml.modelStatus(ctx, modelName)
+	if err != nil {
+		return false, err
+	}
+	return status != nil && status.InCache, nil
+}
+
+// modelStatus returns the server's status of modelName, or nil if the server does not list it.
+func (ml *ModelLoader) modelStatus(ctx context.Context, modelName string) (*ModelStatus, error) {
 	modelsURL := fmt.Sprintf("%s/models", ml.baseURL)
 	statusReq, err := http.NewRequestWithContext(ctx, "GET", modelsURL, nil)
 	if err != nil {
-		return false, fmt.Errorf("failed to create status request: %w", err)
+		return nil, fmt.Errorf("failed to create status request: %w", err)
 	}
 
 	statusResp, err := ml.client.Do(statusReq)
 	if err != nil {
-		return false, fmt.Errorf("failed to check model status: %w", err)
+		return nil, fmt.Errorf("failed to check model status: %w", err)
 	}
 	defer func() {
 		_ = statusResp.Body.Close()
@@ -70,56 +100,63 @@ func (ml *ModelLoader) IsModelLoaded(ctx context.Context, modelName string) (boo
 
 	if statusResp.StatusCode != http.StatusOK {
 		raw, _ := io.ReadAll(statusResp.Body)
-		return false, fmt.Errorf("bad status %d: %s", statusResp.StatusCode, string(raw))
+		return nil, fmt.Errorf("bad status %d: %s", statusResp.StatusCode, string(raw))
 	}
 
 	var modelsResp ModelsResponse
 	if err := json.NewDecoder(statusResp.Body).Decode(&modelsResp); err != nil {
-		return false, fmt.Errorf("failed to decode models response: %w", err)
+		return nil, fmt.Errorf("failed to decode models response: %w", err)
 	}
 
-	// Find our model
 	for _, model := range modelsResp.Data {
 		if model.ID == modelName {
-			return model.InCache, nil
+			return &model, nil
 		}
 	}
-
-	// Model not found in the list
-	return false, nil
+	return nil, nil
 }
 
-// LoadModel loads a model into the llama.cpp server with optional extra arguments.
-// It checks if the model is already loaded first, and only loads if not in cache.
-// It waits for the model to actually load and verifies it's in cache before returning.
+// LoadModel loads a model into the llama.cpp server with optional extra arguments and waits
+// up to DefaultLoadTimeout for it to be ready. It does nothing if the model is already loaded.
 func (ml *ModelLoader) LoadModel(ctx context.Context, modelName string, extraArgs []string) error {
-	// Check if model is already loaded
-	loaded, err := ml.IsModelLoaded(ctx, modelName)
-	if err != nil {
-		// If we can't check status, proceed with loading attempt
-		// (might be a transient error)
-	} else if loaded {
-		// Model is already loaded, no need to load again
+	return ml.LoadModelWithTimeout(ctx, modelName, extraArgs, DefaultLoadTimeout)
+}
+
+// LoadModelWithTimeout is LoadModel with a custom readiness timeout.
+// /models/load returns as soon as the load starts, and the load itself may still fail, so the
+// model's status is polled with exponential backoff until it is in cache or reported failed.
+func (ml *ModelLoader) LoadModelWithTimeout(ctx context.Context, modelName string, extraArgs []string, timeout time.Duration) error {
+	// A failed check may be transient, so loading is attempted anyway
+	if loaded, err := ml.IsModelLoaded(ctx, modelName); err == nil && loaded {
 		return nil
 	}
 
-	url := fmt.Sprintf("%s/models/load", ml.baseURL)
+	if err := ml.post(ctx, "/models/load", LoadModelRequest{Model: modelName, ExtraArgs: extraArgs}); err != nil {
+		return fmt.Errorf("model load failed: %w", err)
+	}
+	return ml.waitReady(ctx, modelName, timeout)
+}
 
-	payload := LoadModelRequest{
-		Model:     modelName,
-		ExtraArgs: extraArgs,
+// UnloadModel unloads a model from the llama.cpp server (router mode), freeing its memory.
+// The server loads it again on its next request.
+func (ml *ModelLoader) UnloadModel(ctx context.Context, modelName string) error {
+	if err := ml.post(ctx, "/models/unload", UnloadModelRequest{Model: modelName}); err != nil {
+		return fmt.Errorf("model unload failed: %w", err)
 	}
+	return nil
+}
 
+// post sends a JSON request to a model management endpoint and checks its LoadModelResponse.
+func (ml *ModelLoader) post(ctx context.Context, path string, payload any) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", ml.baseURL+path, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := ml.client.Do(req)
@@ -135,67 +172,49 @@ func (ml *ModelLoader) LoadModel(ctx context.Context, modelName string, extraArg
 		return fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
 	}
 
-	var loadResp LoadModelResponse
-	if err := json.NewDecoder(resp.Body).Decode(&loadResp); err != nil {
+	var result LoadModelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	if !loadResp.Success {
-		return fmt.Errorf("model load failed: %s", loadResp.Error)
+	if !result.Success {
+		return fmt.Errorf("%s", result.Error)
 	}
+	return nil
+}
 
-	// Wait and verify the model actually loaded successfully
-	// Poll the /models endpoint to check if the model is in cache
-	// This is necessary because /models/load returns success immediately,
-	// but the actual loading happens asynchronously and may fail
-	modelsURL := fmt.Sprintf("%s/models", ml.baseURL)
-	maxAttempts := 30 // Wait up to 30 seconds (1 second per attempt)
-	for i := 0; i < maxAttempts; i++ {
-		// Check model status
-		statusReq, err := http.NewRequestWithContext(ctx, "GET", modelsURL, nil)
-		if err != nil {
-			return fmt.Errorf("failed to create status request: %w", err)
-		}
-
-		statusResp, err := ml.client.Do(statusReq)
-		if err != nil {
-			// If we can't check status, assume it's still loading and continue
-			time.Sleep(time.Second)
-			continue
-		}
-
-		var modelsResp ModelsResponse
-		if err := json.NewDecoder(statusResp.Body).Decode(&modelsResp); err != nil {
-			_ = statusResp.Body.Close()
-			time.Sleep(time.Second)
-			continue
-		}
-		_ = statusResp.Body.Close()
-
-		// Find our model
-		for _, model := range modelsResp.Data {
-			if model.ID == modelName {
-				// Check if model is in cache (successfully loaded)
-				if model.InCache {
-					return nil
-				}
-				// Check if model load failed
-				if model.Status.Failed != nil && *model.Status.Failed {
-					exitCode := 0
-					if model.Status.ExitCode != nil {
-						exitCode = *model.Status.ExitCode
-					}
-					return fmt.Errorf("model load failed with exit code %d", exitCode)
+// waitReady polls the model's status with exponential backoff until it is in cache, its
+// load failed, or timeout passes. Failed status checks are retried.
+func (ml *ModelLoader) waitReady(ctx context.Context, modelName string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultLoadTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := readyInitialBackoff
+	for {
+		status, err := ml.modelStatus(waitCtx, modelName)
+		if err == nil && status != nil {
+			if status.InCache {
+				return nil
+			}
+			if status.Status.Failed != nil && *status.Status.Failed {
+				exitCode := 0
+				if status.Status.ExitCode != nil {
+					exitCode = *status.Status.ExitCode
 				}
-				// Model is still loading, continue polling
-				break
+				return fmt.Errorf("model load failed with exit code %d", exitCode)
 			}
 		}
 
-		// Wait before next attempt
-		time.Sleep(time.Second)
+		select {
+		case <-waitCtx.Done():
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("model %s did not load within %s", modelName, timeout)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, readyMaxBackoff)
 	}
-
-	// If we get here, the model didn't load within the timeout
-	return fmt.Errorf("model did not load within timeout period")
 }
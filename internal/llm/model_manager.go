@@ -0,0 +1,359 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// Model roles registered with a ModelManager.
+const (
+	// RoleChat is the chat model answering questions.
+	RoleChat = "chat"
+	// RoleEmbedding is the embedding model the index was built with.
+	RoleEmbedding = "embedding"
+)
+
+var (
+	// ErrUnknownRole is returned for a role that was never registered.
+	ErrUnknownRole = errors.New("unknown model role")
+	// ErrSwapUnsupported is returned when swapping a role registered without OnSwap, such as
+	// the embedding model, whose vectors would no longer match the index.
+	ErrSwapUnsupported = errors.New("model role cannot be swapped")
+)
+
+// idlePollInterval is how often Swap checks whether the previous model's requests are done.
+const idlePollInterval = 100 * time.Millisecond
+
+// ModelRole is a model the API depends on.
+type ModelRole struct {
+	// Model is the model name (its .gguf file name without the extension).
+	Model string
+	// Args returns the llama.cpp arguments that load model in this role.
+	Args func(model string) []string
+	// OnSwap points the role's client at a new model after a swap (nil: the role cannot be swapped).
+	OnSwap func(model string)
+}
+
+// ModelManagerOptions configures a ModelManager.
+type ModelManagerOptions struct {
+	// LoadTimeout bounds waiting for a model to become ready (0: DefaultLoadTimeout).
+	LoadTimeout time.Duration
+	// IdleTTL unloads models unused for this long, reloading them on their next request
+	// (0: models stay loaded).
+	IdleTTL time.Duration
+}
+
+// ModelState reports a managed model.
+type ModelState struct {
+	Role     string
+	Model    string
+	Loaded   bool
+	LastUsed time.Time
+	// LastError is the error of the last load or unload (empty after a success).
+	LastError string
+}
+
+// ModelManager loads the API's models into a llama.cpp server in router mode, waits for them
+// to be ready, unloads idle ones after IdleTTL, and swaps models at runtime. Clients call
+// Acquire before each request, which reloads an unloaded model and keeps it from being
+// unloaded mid-request.
+type ModelManager struct {
+	loader *ModelLoader
+	opts   ModelManagerOptions
+	now    func() time.Time
+
+	mu     sync.Mutex
+	roles  map[string]*managedModel
+	byName map[string]*managedModel
+}
+
+// managedModel is the state of one role's model. Its fields are guarded by ModelManager.mu,
+// except loading, which serializes loads and unloads of the model.
+type managedModel struct {
+	role     string
+	spec     ModelRole
+	loaded   bool
+	inFlight int
+	lastUsed time.Time
+	lastErr  string
+	loading  sync.Mutex
+}
+
+// NewModelManager creates a ModelManager loading models through loader.
+func NewModelManager(loader *ModelLoader, opts ModelManagerOptions) *ModelManager {
+	if opts.LoadTimeout <= 0 {
+		opts.LoadTimeout = DefaultLoadTimeout
+	}
+	return &ModelManager{
+		loader: loader,
+		opts:   opts,
+		now:    time.Now,
+		roles:  make(map[string]*managedModel),
+		byName: make(map[string]*managedModel),
+	}
+}
+
+// Register adds a role. Call it before LoadAll and before the manager is shared.
+func (m *ModelManager) Register(role string, spec ModelRole) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	model := &managedModel{role: role, spec: spec, lastUsed: m.now()}
+	m.roles[role] = model
+	m.byName[spec.Model] = model
+}
+
+// LoadAll loads every registered model concurrently and waits until each is ready or has
+// failed. Failures are logged and returned joined; the server loads a failed model on its
+// first request.
+func (m *ModelManager) LoadAll(ctx context.Context) error {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	m.mu.Lock()
+	models := make([]*managedModel, 0, len(m.roles))
+	for _, model := range m.roles {
+		models = append(models, model)
+	}
+	m.mu.Unlock()
+
+	errs := make([]error, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Go(func() {
+			start := m.now()
+			if err := m.load(ctx, model); err != nil {
+				logger.WarnContext(ctx, "failed to load model (will be loaded on first use)", "role", model.role, "model", model.spec.Model, "error", err)
+				errs[i] = fmt.Errorf("%s model %s: %w", model.role, model.spec.Model, err)
+				return
+			}
+			logger.InfoContext(ctx, "model ready", "role", model.role, "model", model.spec.Model, "duration_ms", m.now().Sub(start).Milliseconds())
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// load loads a model unless it is loaded, recording the outcome.
+func (m *ModelManager) load(ctx context.Context, model *managedModel) error {
+	model.loading.Lock()
+	defer model.loading.Unlock()
+
+	m.mu.Lock()
+	loaded, name, args := model.loaded, model.spec.Model, model.spec.Args
+	m.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	var extraArgs []string
+	if args != nil {
+		extraArgs = args(name)
+	}
+	err := m.loader.LoadModelWithTimeout(ctx, name, extraArgs, m.opts.LoadTimeout)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	model.loaded = err == nil
+	model.lastErr = errorString(err)
+	if err == nil {
+		model.lastUsed = m.now()
+	}
+	return err
+}
+
+// Acquire marks modelName in use until the returned release is called, reloading it first
+// if it was unloaded for being idle. Models the manager does not know are left to the server.
+func (m *ModelManager) Acquire(ctx context.Context, modelName string) (func(), error) {
+	m.mu.Lock()
+	model, ok := m.byName[modelName]
+	if !ok {
+		m.mu.Unlock()
+		return func() {}, nil
+	}
+	model.inFlight++
+	model.lastUsed = m.now()
+	loaded := model.loaded
+	m.mu.Unlock()
+
+	release := func() {
+		m.mu.Lock()
+		model.inFlight--
+		model.lastUsed = m.now()
+		m.mu.Unlock()
+	}
+	if !loaded {
+		if err := m.load(ctx, model); err != nil {
+			release()
+			return nil, fmt.Errorf("failed to load model %s: %w", modelName, err)
+		}
+	}
+	return release, nil
+}
+
+// UnloadIdle unloads the loaded models that have had no request in flight for IdleTTL and
+// returns their names. It does nothing when IdleTTL is 0.
+func (m *ModelManager) UnloadIdle(ctx context.Context) []string {
+	if m.opts.IdleTTL <= 0 {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	m.mu.Lock()
+	cutoff := m.now().Add(-m.opts.IdleTTL)
+	var idle []*managedModel
+	for _, model := range m.roles {
+		if model.loaded && model.inFlight == 0 && model.lastUsed.Before(cutoff) {
+			idle = append(idle, model)
+		}
+	}
+	m.mu.Unlock()
+
+	var unloaded []string
+	for _, model := range idle {
+		if m.unloadIfIdle(ctx, model, cutoff) {
+			logger.InfoContext(ctx, "unloaded idle model", "role", model.role, "model", model.spec.Model, "idle_ttl", m.opts.IdleTTL)
+			unloaded = append(unloaded, model.spec.Model)
+		}
+	}
+	sort.Strings(unloaded)
+	return unloaded
+}
+
+// unloadIfIdle unloads a model if it is still idle once its loading lock is held.
+func (m *ModelManager) unloadIfIdle(ctx context.Context, model *managedModel, cutoff time.Time) bool {
+	model.loading.Lock()
+	defer model.loading.Unlock()
+
+	m.mu.Lock()
+	if !model.loaded || model.inFlight > 0 || !model.lastUsed.Before(cutoff) {
+		m.mu.Unlock()
+		return false
+	}
+	// Requests arriving during the unload reload the model after it
+	model.loaded = false
+	name := model.spec.Model
+	m.mu.Unlock()
+
+	err := m.loader.UnloadModel(ctx, name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	model.lastErr = errorString(err)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to unload idle model", "model", name, "error", err)
+		model.loaded = true
+		return false
+	}
+	return true
+}
+
+// Run unloads idle models every interval until ctx is cancelled. It returns at once when
+// IdleTTL is 0.
+func (m *ModelManager) Run(ctx context.Context, interval time.Duration) {
+	if m.opts.IdleTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.UnloadIdle(ctx)
+		}
+	}
+}
+
+// Swap loads model for role, points the role's client at it, and unloads the previous model.
+// The new model is ready before any request uses it, so answers are never served by a model
+// that is still loading. It returns the previous model name.
+func (m *ModelManager) Swap(ctx context.Context, role, modelName string) (string, error) {
+	m.mu.Lock()
+	current, ok := m.roles[role]
+	if !ok {
+		m.mu.Unlock()
+		return "", fmt.Errorf("%w: %q", ErrUnknownRole, role)
+	}
+	if current.spec.OnSwap == nil {
+		m.mu.Unlock()
+		return "", fmt.Errorf("%w: %q", ErrSwapUnsupported, role)
+	}
+	previous := current.spec.Model
+	if previous == modelName {
+		m.mu.Unlock()
+		return previous, nil
+	}
+	next := &managedModel{role: role, spec: current.spec, lastUsed: m.now()}
+	next.spec.Model = modelName
+	m.mu.Unlock()
+
+	if err := m.load(ctx, next); err != nil {
+		return previous, fmt.Errorf("failed to load model %s: %w", modelName, err)
+	}
+
+	m.mu.Lock()
+	m.roles[role] = next
+	delete(m.byName, previous)
+	m.byName[modelName] = next
+	m.mu.Unlock()
+	next.spec.OnSwap(modelName)
+
+	// Requests already sent to the previous model finish before the server unloads it
+	if err := m.waitIdle(ctx, current); err != nil {
+		return previous, err
+	}
+	if err := m.loader.UnloadModel(ctx, previous); err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to unload previous model", "model", previous, "error", err)
+	}
+	return previous, nil
+}
+
+// waitIdle waits until model has no request in flight.
+func (m *ModelManager) waitIdle(ctx context.Context, model *managedModel) error {
+	for {
+		m.mu.Lock()
+		inFlight := model.inFlight
+		m.mu.Unlock()
+		if inFlight == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(idlePollInterval):
+		}
+	}
+}
+
+// States reports every managed model, ordered by role.
+func (m *ModelManager) States() []ModelState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make([]ModelState, 0, len(m.roles))
+	for role, model := range m.roles {
+		states = append(states, ModelState{
+			Role:      role,
+			Model:     model.spec.Model,
+			Loaded:    model.loaded,
+			LastUsed:  model.lastUsed,
+			LastError: model.lastErr,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Role < states[j].Role })
+	return states
+}
+
+// errorString returns err's message, or "" for nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
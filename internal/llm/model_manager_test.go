@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeModelServer imitates the model endpoints of llama.cpp in router mode. A loaded model
+// reports ready after one status poll, and models named "broken" fail to load.
+type fakeModelServer struct {
+	mu       sync.Mutex
+	polls    map[string]int
+	inCache  map[string]bool
+	loads    []string
+	unloads  []string
+	loadArgs map[string][]string
+}
+
+func newFakeModelServer(t *testing.T) (*fakeModelServer, *httptest.Server) {
+	t.Helper()
+	fake := &fakeModelServer{polls: make(map[string]int), inCache: make(map[string]bool), loadArgs: make(map[string][]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeModelServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/models":
+		var resp ModelsResponse
+		for name, pollsLeft := range f.polls {
+			status := ModelStatus{ID: name, InCache: f.inCache[name]}
+			if name == "broken" {
+				failed, exitCode := true, 1
+				status.Status.Failed, status.Status.ExitCode = &failed, &exitCode
+			} else if !status.InCache {
+				if pollsLeft == 0 {
+					f.inCache[name] = true
+				}
+				f.polls[name] = pollsLeft - 1
+			}
+			resp.Data = append(resp.Data, status)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case "/models/load":
+		var req LoadModelRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.loads = append(f.loads, req.Model)
+		f.loadArgs[req.Model] = req.ExtraArgs
+		f.polls[req.Model] = 1
+		_ = json.NewEncoder(w).Encode(LoadModelResponse{Success: true})
+	case "/models/unload":
+		var req UnloadModelRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.unloads = append(f.unloads, req.Model)
+		delete(f.inCache, req.Model)
+		delete(f.polls, req.Model)
+		_ = json.NewEncoder(w).Encode(LoadModelResponse{Success: true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeModelServer) calls() (loads, unloads []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.loads...), append([]string(nil), f.unloads...)
+}
+
+func TestModelManager_LoadAll(t *testing.T) {
+	fake, server := newFakeModelServer(t)
+	manager := NewModelManager(NewModelLoader(server.URL), ModelManagerOptions{LoadTimeout: 5 * time.Second})
+	manager.Register(RoleChat, ModelRole{Model: "chat", Args: func(model string) []string { return []string{"--model", model + ".gguf"} }})
+	manager.Register(RoleEmbedding, ModelRole{Model: "embed"})
+
+	if err := manager.LoadAll(context.Background()); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+	loads, _ := fake.calls()
+	if len(loads) != 2 {
+		t.Errorf("loads = %v, want chat and embed", loads)
+	}
+	if got := fake.loadArgs["chat"]; !reflect.DeepEqual(got, []string{"--model", "chat.gguf"}) {
+		t.Errorf("chat load args = %v, want the role's Args", got)
+	}
+	for _, state := range manager.States() {
+		if !state.Loaded || state.LastError != "" {
+			t.Errorf("state = %+v, want loaded without error", state)
+		}
+	}
+
+	// A model that fails to load is reported, not fatal
+	manager.Register("extra", ModelRole{Model: "broken"})
+	if err := manager.LoadAll(context.Background()); err == nil {
+		t.Error("LoadAll() error = nil, want the broken model's failure")
+	}
+	states := manager.States()
+	if states[2].Role != "extra" || states[2].Loaded || states[2].LastError == "" {
+		t.Errorf("broken state = %+v, want unloaded with an error", states[2])
+	}
+}
+
+func TestModelManager_UnloadIdleAndAcquire(t *testing.T) {
+	fake, server := newFakeModelServer(t)
+	manager := NewModelManager(NewModelLoader(server.URL), ModelManagerOptions{LoadTimeout: 5 * time.Second, IdleTTL: time.Minute})
+	now := time.Now()
+	manager.now = func() time.Time { return now }
+	manager.Register(RoleChat, ModelRole{Model: "chat"})
+	manager.Register(RoleEmbedding, ModelRole{Model: "embed"})
+	ctx := context.Background()
+	if err := manager.LoadAll(ctx); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	if unloaded := manager.UnloadIdle(ctx); len(unloaded) != 0 {
+		t.Errorf("UnloadIdle() before the TTL = %v, want none", unloaded)
+	}
+
+	// A request in flight keeps its model loaded
+	release, err := manager.Acquire(ctx, "embed")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if unloaded := manager.UnloadIdle(ctx); !reflect.DeepEqual(unloaded, []string{"chat"}) {
+		t.Errorf("UnloadIdle() = %v, want [chat]", unloaded)
+	}
+	release()
+
+	// The next request reloads the unloaded model
+	release, err = manager.Acquire(ctx, "chat")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	release()
+	loads, unloads := fake.calls()
+	if !reflect.DeepEqual(unloads, []string{"chat"}) || len(loads) != 3 || loads[2] != "chat" {
+		t.Errorf("loads = %v, unloads = %v, want chat unloaded and reloaded", loads, unloads)
+	}
+
+	// Models the manager does not know are left to the server
+	release, err = manager.Acquire(ctx, "other")
+	if err != nil {
+		t.Fatalf("Acquire() unknown model error = %v", err)
+	}
+	release()
+}
+
+func TestModelManager_Swap(t *testing.T) {
+	fake, server := newFakeModelServer(t)
+	manager := NewModelManager(NewModelLoader(server.URL), ModelManagerOptions{LoadTimeout: 5 * time.Second})
+	var current string
+	manager.Register(RoleChat, ModelRole{Model: "chat", OnSwap: func(model string) { current = model }})
+	manager.Register(RoleEmbedding, ModelRole{Model: "embed"})
+	ctx := context.Background()
+	if err := manager.LoadAll(ctx); err != nil {
+		t.Fatalf("LoadAll() error = %v", err)
+	}
+
+	if _, err := manager.Swap(ctx, RoleEmbedding, "other-embed"); !errors.Is(err, ErrSwapUnsupported) {
+		t.Errorf("Swap() embedding error = %v, want ErrSwapUnsupported", err)
+	}
+	if _, err := manager.Swap(ctx, "vision", "x"); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("Swap() unknown role error = %v, want ErrUnknownRole", err)
+	}
+	if _, err := manager.Swap(ctx, RoleChat, "broken"); err == nil || current != "" {
+		t.Errorf("Swap() to a broken model error = %v, client model = %q, want an error and no swap", err, current)
+	}
+
+	previous, err := manager.Swap(ctx, RoleChat, "chat-2")
+	if err != nil {
+		t.Fatalf("Swap() error = %v", err)
+	}
+	if previous != "chat" || current != "chat-2" {
+		t.Errorf("Swap() previous = %q, client model = %q, want chat and chat-2", previous, current)
+	}
+	_, unloads := fake.calls()
+	if !reflect.DeepEqual(unloads, []string{"chat"}) {
+		t.Errorf("unloads = %v, want the previous model", unloads)
+	}
+	if states := manager.States(); states[0].Model != "chat-2" || !states[0].Loaded {
+		t.Errorf("chat state = %+v, want chat-2 loaded", states[0])
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ServerProps is the part of the llama.cpp /props response used to size client concurrency.
+type ServerProps struct {
+	// TotalSlots is how many requests the server processes in parallel (its --parallel setting).
+	TotalSlots int `json:"total_slots"`
+}
+
+// FetchProps queries the server's /props for modelName and stores the result for Slots.
+// In router mode the model parameter selects the model's server; single-model servers ignore it.
+func (ml *ModelLoader) FetchProps(ctx context.Context, modelName string) (ServerProps, error) {
+	propsURL := fmt.Sprintf("%s/props?model=%s", ml.baseURL, url.QueryEscape(modelName))
+	req, err := http.NewRequestWithContext(ctx, "GET", propsURL, nil)
+	if err != nil {
+		return ServerProps{}, fmt.Errorf("failed to create props request: %w", err)
+	}
+
+	resp, err := ml.client.Do(req)
+	if err != nil {
+		return ServerProps{}, fmt.Errorf("failed to get server props: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return ServerProps{}, fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
+	}
+
+	var props ServerProps
+	if err := json.NewDecoder(resp.Body).Decode(&props); err != nil {
+		return ServerProps{}, fmt.Errorf("failed to decode props response: %w", err)
+	}
+
+	ml.mu.Lock()
+	ml.props[modelName] = props
+	ml.mu.Unlock()
+	return props, nil
+}
+
+// Slots returns the slot count last fetched for modelName, or 0 if it is unknown.
+func (ml *ModelLoader) Slots(modelName string) int {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	return ml.props[modelName].TotalSlots
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModelLoader_FetchProps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/props" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("model") != "chat-model" {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"total_slots":4,"model_path":"chat-model.gguf","default_generation_settings":{"n_ctx":8192}}`))
+	}))
+	defer server.Close()
+
+	loader := NewModelLoader(server.URL)
+	if got := loader.Slots("chat-model"); got != 0 {
+		t.Errorf("Slots() before FetchProps = %d, want 0", got)
+	}
+
+	props, err := loader.FetchProps(context.Background(), "chat-model")
+	if err != nil {
+		t.Fatalf("FetchProps() error = %v", err)
+	}
+	if props.TotalSlots != 4 {
+		t.Errorf("FetchProps() TotalSlots = %d, want 4", props.TotalSlots)
+	}
+	if got := loader.Slots("chat-model"); got != 4 {
+		t.Errorf("Slots() = %d, want 4", got)
+	}
+
+	if _, err := loader.FetchProps(context.Background(), "missing-model"); err == nil {
+		t.Error("FetchProps() for an unknown model error = nil, want an error")
+	}
+	if got := loader.Slots("missing-model"); got != 0 {
+		t.Errorf("Slots() for an unknown model = %d, want 0", got)
+	}
+}
@@ -0,0 +1,20 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// quantizationPattern matches GGUF quantization tags such as Q4_K_M, IQ3_XXS, Q8_0, or F16
+// between name separators.
+var quantizationPattern = regexp.MustCompile(`(?i)(?:^|[-_.:/ ])(I?Q[1-8](?:_[0-9A-Z]+)*|BF16|FP16|F16|F32)(?:$|[-.:/ ])`)
+
+// Quantization returns the quantization tag in a model name, upper-cased, or "" when the
+// name carries none. The last tag wins, so "llama-3-8b-q4_0.gguf" yields "Q4_0".
+func Quantization(model string) string {
+	matches := quantizationPattern.FindAllStringSubmatch(model, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return strings.ToUpper(matches[len(matches)-1][1])
+}
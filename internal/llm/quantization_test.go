@@ -0,0 +1,25 @@
+package llm
+
+import "testing"
+
+func TestQuantization(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{model: "Qwen2.5-3B-Instruct-Q4_K_M", want: "Q4_K_M"},
+		{model: "ggml-org_embeddinggemma-300M-GGUF_embeddinggemma-300M-Q8_0", want: "Q8_0"},
+		{model: "llama-3-8b-instruct.q4_0.gguf", want: "Q4_0"},
+		{model: "Meta-Llama-3-8B-IQ3_XXS", want: "IQ3_XXS"},
+		{model: "phi-3-mini-f16", want: "F16"},
+		{model: "qwen2.5:7b", want: ""},
+		{model: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := Quantization(tt.model); got != tt.want {
+				t.Errorf("Quantization(%q) = %q, want %q", tt.model, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultRerankBatchSize is the number of texts sent per rerank request when no batch size
+// is configured. It matches the default client batch limit of text-embeddings-inference.
+const DefaultRerankBatchSize = 32
+
+// RerankerClient scores texts against a query with an external reranker over HTTP.
+//
+// Protocol: POST {BaseURL}/rerank with {"query": "...", "texts": ["...", ...]}. The reranker
+// answers 200 with one {"index": i, "score": s} entry per text, in any order, where a higher
+// score means more relevant. This is the /rerank API of Hugging Face text-embeddings-inference,
+// so TEI serving a cross-encoder such as bge-reranker works as is; other rerankers (for
+// example a Python sidecar) only need to implement this one endpoint.
+type RerankerClient struct {
+	BaseURL   string
+	APIKey    string
+	BatchSize int
+	client    *http.Client
+}
+
+// NewRerankerClient creates a new reranker client.
+// batchSize caps the texts sent per request (0 uses DefaultRerankBatchSize); apiKey is sent
+// as a bearer token when set.
+func NewRerankerClient(baseURL, apiKey string, batchSize int) *RerankerClient {
+	if batchSize <= 0 {
+		batchSize = DefaultRerankBatchSize
+	}
+	return &RerankerClient{
+		BaseURL:   baseURL,
+		APIKey:    apiKey,
+		BatchSize: batchSize,
+		client:    newHTTPClient(),
+	}
+}
+
+// RerankRequest represents the request payload for the rerank API.
+type RerankRequest struct {
+	Query string   `json:"query"`
+	Texts []string `json:"texts"`
+}
+
+// RerankScore is the reranker's score for one text of a RerankRequest.
+type RerankScore struct {
+	// Index is the position of the text in RerankRequest.Texts.
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// Rerank scores each text against query. The returned scores are in the order of texts.
+// Texts are sent in batches of BatchSize; any failed batch fails the whole call.
+func (c *RerankerClient) Rerank(ctx context.Context, query string, texts []string) ([]float32, error) {
+	scores := make([]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.BatchSize {
+		end := min(start+c.BatchSize, len(texts))
+		batch, err := c.rerankBatch(ctx, query, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, batch...)
+	}
+	return scores, nil
+}
+
+// rerankBatch sends one rerank request and orders its scores like texts.
+func (c *RerankerClient) rerankBatch(ctx context.Context, query string, texts []string) ([]float32, error) {
+	body, err := json.Marshal(RerankRequest{Query: query, Texts: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/rerank", c.BaseURL), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
+	}
+
+	var results []RerankScore
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(results) != len(texts) {
+		return nil, fmt.Errorf("expected %d scores, got %d", len(texts), len(results))
+	}
+
+	scores := make([]float32, len(texts))
+	scored := make([]bool, len(texts))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(texts) || scored[result.Index] {
+			return nil, fmt.Errorf("invalid or repeated score index %d", result.Index)
+		}
+		scores[result.Index] = float32(result.Score)
+		scored[result.Index] = true
+	}
+	return scores, nil
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRerankerClient_Rerank(t *testing.T) {
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rerank" {
+			t.Errorf("request = %s %s, want POST /rerank", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+		var req RerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Query != "tomatoes" {
+			t.Errorf("query = %q, want tomatoes", req.Query)
+		}
+		batches = append(batches, req.Texts)
+
+		// Scores come back sorted by relevance, not in request order
+		results := make([]RerankScore, 0, len(req.Texts))
+		for i, text := range req.Texts {
+			score := 0.1
+			if strings.Contains(text, "tomato") {
+				score = 0.9
+			}
+			results = append(results, RerankScore{Index: i, Score: score})
+		}
+		slices.Reverse(results)
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	client := NewRerankerClient(server.URL, "secret", 2)
+	texts := []string{"tomato beds", "meeting notes", "water the tomatoes"}
+	scores, err := client.Rerank(context.Background(), "tomatoes", texts)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if want := []float32{0.9, 0.1, 0.9}; !slices.Equal(scores, want) {
+		t.Errorf("Rerank() = %v, want %v", scores, want)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("batches = %v, want texts split into batches of 2", batches)
+	}
+}
+
+func TestRerankerClient_RerankErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverResp func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name: "server error",
+			serverResp: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+			},
+		},
+		{
+			name: "missing score",
+			serverResp: func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]RerankScore{{Index: 0, Score: 0.5}})
+			},
+		},
+		{
+			name: "repeated index",
+			serverResp: func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]RerankScore{{Index: 0, Score: 0.5}, {Index: 0, Score: 0.4}})
+			},
+		},
+		{
+			name: "invalid JSON",
+			serverResp: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("not json"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(tt.serverResp))
+			defer server.Close()
+
+			client := NewRerankerClient(server.URL, "", 0)
+			if _, err := client.Rerank(context.Background(), "q", []string{"a", "b"}); err == nil {
+				t.Error("Rerank() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// TokenizeRequest represents the request payload for llama.cpp's /tokenize endpoint.
+type TokenizeRequest struct {
+	// Model routes the request to the model's server in router mode; single-model servers ignore it.
+	Model   string `json:"model,omitempty"`
+	Content string `json:"content"`
+}
+
+// TokenizeResponse represents the response from llama.cpp's /tokenize endpoint.
+type TokenizeResponse struct {
+	Tokens []int `json:"tokens"`
+}
+
+// CountTokens returns the number of tokens text takes in the chat model's tokenizer, using
+// llama.cpp's /tokenize endpoint. Special tokens added by the chat template are not counted.
+// It does not take a generation slot.
+func (c *Client) CountTokens(ctx context.Context, text string) (int, error) {
+	body, err := json.Marshal(TokenizeRequest{Model: c.ModelName(), Content: text})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/tokenize", c.BaseURL), bytes.NewBuffer(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.APIKey))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("bad status %d: %s", resp.StatusCode, string(raw))
+	}
+
+	var tokenizeResp TokenizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenizeResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return len(tokenizeResp.Tokens), nil
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_CountTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/tokenize" {
+			t.Errorf("request = %s %s, want POST /tokenize", r.Method, r.URL.Path)
+		}
+		var req TokenizeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Model != "test-model" || req.Content != "Where are the tomatoes?" {
+			t.Errorf("request = %+v, want the model and text", req)
+		}
+		_ = json.NewEncoder(w).Encode(TokenizeResponse{Tokens: []int{9241, 525, 279, 41613, 30}})
+	}))
+	defer server.Close()
+
+	count, err := NewClient(server.URL, "test-key", "test-model").CountTokens(context.Background(), "Where are the tomatoes?")
+	if err != nil {
+		t.Fatalf("CountTokens() error = %v", err)
+	}
+	if count != 5 {
+		t.Errorf("CountTokens() = %d, want 5", count)
+	}
+
+	// Servers without the endpoint (e.g. other OpenAI-compatible backends) fail the count
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	if _, err := NewClient(notFound.URL, "test-key", "test-model").CountTokens(context.Background(), "text"); err == nil {
+		t.Error("CountTokens() error = nil, want error for a missing endpoint")
+	}
+}
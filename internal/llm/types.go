@@ -19,4 +19,46 @@ type ChatParams struct {
 	// Temperature controls the randomness of the output.
 	// Default is 0.7 if not specified.
 	Temperature float32
+
+	// Stop lists sequences that end generation when produced (not included in the output).
+	Stop []string
+
+	// RepeatPenalty penalizes repeated tokens (1.0 disables it).
+	// If 0, the server default is used.
+	RepeatPenalty float32
+
+	// TopP limits sampling to the smallest token set whose probability mass reaches TopP.
+	// If 0, the server default is used.
+	TopP float32
+
+	// TopK limits sampling to the K most likely tokens.
+	// If 0, the server default is used.
+	TopK int
+
+	// ResponseFormat constrains the reply to JSON matching a schema (llama.cpp compiles it
+	// to a grammar). If nil, the reply is free-form text.
+	ResponseFormat *ResponseFormat
+}
+
+// ResponseFormat is the OpenAI-compatible response_format of a chat completion request.
+type ResponseFormat struct {
+	// Type is "json_schema" for schema-constrained output.
+	Type string `json:"type"`
+	// JSONSchema names the schema the reply must match.
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is a named JSON Schema for structured output.
+type JSONSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict,omitempty"`
+	Schema map[string]any `json:"schema"`
+}
+
+// JSONSchemaFormat returns a response format constraining the reply to schema.
+func JSONSchemaFormat(name string, schema map[string]any) *ResponseFormat {
+	return &ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &JSONSchema{Name: name, Strict: true, Schema: schema},
+	}
 }
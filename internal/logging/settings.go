@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel parses DEBUG, INFO, WARN, or ERROR (case-insensitive).
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return slog.LevelDebug, nil
+	case "INFO":
+		return slog.LevelInfo, nil
+	case "WARN":
+		return slog.LevelWarn, nil
+	case "ERROR":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level: %s (must be DEBUG, INFO, WARN, or ERROR)", s)
+	}
+}
+
+// ParseFormat parses text or json (case-insensitive).
+func ParseFormat(s string) (string, error) {
+	switch format := strings.ToLower(s); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid log format: %s (must be text or json)", s)
+	}
+}
+
+// Settings holds the log level and output format, both adjustable at runtime.
+// Handlers created by Handler (and loggers derived from them with With) follow changes
+// immediately, so the level can be raised to debug without restarting the server.
+type Settings struct {
+	level *slog.LevelVar
+	json  atomic.Bool
+}
+
+// NewSettings creates Settings with the given level and format.
+func NewSettings(level slog.Level, format string) (*Settings, error) {
+	s := &Settings{level: new(slog.LevelVar)}
+	if err := s.Set(level, format); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Level returns the current log level.
+func (s *Settings) Level() slog.Level {
+	return s.level.Level()
+}
+
+// Format returns the current output format (text or json).
+func (s *Settings) Format() string {
+	if s.json.Load() {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// Set changes the log level and output format.
+func (s *Settings) Set(level slog.Level, format string) error {
+	format, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	s.level.Set(level)
+	s.json.Store(format == FormatJSON)
+	return nil
+}
+
+// Handler returns a slog.Handler writing to w that follows the current settings.
+func (s *Settings) Handler(w io.Writer) slog.Handler {
+	opts := &slog.HandlerOptions{Level: s.level}
+	return &switchHandler{
+		settings: s,
+		text:     slog.NewTextHandler(w, opts),
+		json:     slog.NewJSONHandler(w, opts),
+	}
+}
+
+// switchHandler keeps a text and a JSON handler with the same attributes and groups,
+// and dispatches each record to whichever matches the current format.
+type switchHandler struct {
+	settings *Settings
+	text     slog.Handler
+	json     slog.Handler
+}
+
+func (h *switchHandler) current() slog.Handler {
+	if h.settings.json.Load() {
+		return h.json
+	}
+	return h.text
+}
+
+func (h *switchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.current().Enabled(ctx, level)
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.current().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &switchHandler{
+		settings: h.settings,
+		text:     h.text.WithAttrs(attrs),
+		json:     h.json.WithAttrs(attrs),
+	}
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	return &switchHandler{
+		settings: h.settings,
+		text:     h.text.WithGroup(name),
+		json:     h.json.WithGroup(name),
+	}
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{in: "debug", want: slog.LevelDebug},
+		{in: "INFO", want: slog.LevelInfo},
+		{in: "Warn", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "trace", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLevel(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSettings_InvalidFormat(t *testing.T) {
+	if _, err := NewSettings(slog.LevelInfo, "xml"); err == nil {
+		t.Error("NewSettings() with format xml expected error")
+	}
+}
+
+func TestSettings_RuntimeChange(t *testing.T) {
+	settings, err := NewSettings(slog.LevelInfo, FormatText)
+	if err != nil {
+		t.Fatalf("NewSettings() error = %v", err)
+	}
+	var buf bytes.Buffer
+	// Derived loggers must follow later changes too
+	logger := slog.New(settings.Handler(&buf)).With("component", "rag")
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug record written at info level: %q", buf.String())
+	}
+
+	if err := settings.Set(slog.LevelDebug, FormatJSON); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	logger.Debug("visible")
+	out := buf.String()
+	if !strings.HasPrefix(out, "{") || !strings.Contains(out, `"msg":"visible"`) || !strings.Contains(out, `"component":"rag"`) {
+		t.Errorf("after Set(debug, json) output = %q, want a JSON debug record with attributes", out)
+	}
+	if settings.Level() != slog.LevelDebug || settings.Format() != FormatJSON {
+		t.Errorf("settings = %v/%s, want DEBUG/json", settings.Level(), settings.Format())
+	}
+
+	buf.Reset()
+	if err := settings.Set(slog.LevelInfo, FormatText); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	logger.Debug("hidden again")
+	logger.Info("plain")
+	if out := buf.String(); strings.Contains(out, "hidden again") || !strings.Contains(out, "msg=plain") {
+		t.Errorf("after Set(info, text) output = %q, want only the text info record", out)
+	}
+}
@@ -0,0 +1,239 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Resource names used in limit breaches and webhook payloads.
+const (
+	ResourceSQLite = "sqlite"
+	ResourceQdrant = "qdrant"
+)
+
+// bytesPerVectorDimension is the size of one float32 vector component stored by Qdrant.
+const bytesPerVectorDimension = 4
+
+// CollectionInfoProvider reports the size of a vector collection.
+// It is implemented by vectorstore.QdrantStore and vectorstore.MemoryStore.
+type CollectionInfoProvider interface {
+	GetCollectionInfo(ctx context.Context, collection string) (*vectorstore.CollectionInfo, error)
+}
+
+// StorageLimits holds soft limits in bytes. Zero disables a limit.
+type StorageLimits struct {
+	SQLiteBytes int64
+	QdrantBytes int64
+}
+
+// CollectionUsage is the measured size of one vector collection.
+type CollectionUsage struct {
+	Name           string `json:"name"`
+	Points         int    `json:"points"`
+	VectorSize     int    `json:"vector_size"`
+	EstimatedBytes int64  `json:"estimated_bytes"` // Raw vector data only (points x dimensions x 4 bytes)
+}
+
+// StorageUsage is a snapshot of storage usage compared against the soft limits.
+type StorageUsage struct {
+	SQLiteBytes       int64             `json:"sqlite_bytes"` // Database file plus WAL and shared-memory files
+	SQLiteLimitBytes  int64             `json:"sqlite_limit_bytes,omitempty"`
+	QdrantBytes       int64             `json:"qdrant_estimated_bytes"`
+	QdrantLimitBytes  int64             `json:"qdrant_limit_bytes,omitempty"`
+	QdrantCollections []CollectionUsage `json:"qdrant_collections"`
+	Exceeded          []string          `json:"exceeded"` // Resources over their soft limit
+	CheckedAt         time.Time         `json:"checked_at"`
+}
+
+// LimitAlert is the JSON body POSTed to the webhook when a soft limit is first exceeded.
+type LimitAlert struct {
+	Event      string    `json:"event"`
+	Resource   string    `json:"resource"`
+	UsageBytes int64     `json:"usage_bytes"`
+	LimitBytes int64     `json:"limit_bytes"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// StorageMonitor measures SQLite and Qdrant usage and warns when soft limits are exceeded.
+// Each breach is logged and sent to the webhook once; it re-arms after usage drops below the limit.
+type StorageMonitor struct {
+	dbPath      string
+	vectorStore CollectionInfoProvider
+	collections []string
+	limits      StorageLimits
+	webhookURL  string
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	exceeded map[string]bool
+}
+
+// NewStorageMonitor creates a new StorageMonitor.
+// collections lists the vector collections to measure; webhookURL may be empty to only log breaches.
+func NewStorageMonitor(dbPath string, vectorStore CollectionInfoProvider, collections []string, limits StorageLimits, webhookURL string) *StorageMonitor {
+	return &StorageMonitor{
+		dbPath:      dbPath,
+		vectorStore: vectorStore,
+		collections: collections,
+		limits:      limits,
+		webhookURL:  webhookURL,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		exceeded:    make(map[string]bool),
+	}
+}
+
+// Check measures current storage usage and alerts on newly exceeded soft limits.
+func (m *StorageMonitor) Check(ctx context.Context) (StorageUsage, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	usage := StorageUsage{
+		SQLiteLimitBytes:  m.limits.SQLiteBytes,
+		QdrantLimitBytes:  m.limits.QdrantBytes,
+		QdrantCollections: make([]CollectionUsage, 0, len(m.collections)),
+		Exceeded:          []string{},
+		CheckedAt:         time.Now().UTC(),
+	}
+
+	sqliteBytes, err := sqliteSize(m.dbPath)
+	if err != nil {
+		return StorageUsage{}, err
+	}
+	usage.SQLiteBytes = sqliteBytes
+
+	for _, collection := range m.collections {
+		info, err := m.vectorStore.GetCollectionInfo(ctx, collection)
+		if err != nil {
+			return StorageUsage{}, fmt.Errorf("failed to measure collection %s: %w", collection, err)
+		}
+		collectionUsage := CollectionUsage{
+			Name:           collection,
+			Points:         info.PointsCount,
+			VectorSize:     info.VectorSize,
+			EstimatedBytes: int64(info.PointsCount) * int64(info.VectorSize) * bytesPerVectorDimension,
+		}
+		usage.QdrantBytes += collectionUsage.EstimatedBytes
+		usage.QdrantCollections = append(usage.QdrantCollections, collectionUsage)
+	}
+
+	m.evaluate(ctx, ResourceSQLite, usage.SQLiteBytes, m.limits.SQLiteBytes, &usage)
+	m.evaluate(ctx, ResourceQdrant, usage.QdrantBytes, m.limits.QdrantBytes, &usage)
+
+	logger.DebugContext(ctx, "storage usage measured",
+		"sqlite_bytes", usage.SQLiteBytes,
+		"qdrant_estimated_bytes", usage.QdrantBytes,
+		"exceeded", usage.Exceeded,
+	)
+	return usage, nil
+}
+
+// Run checks storage usage every interval until ctx is cancelled.
+func (m *StorageMonitor) Run(ctx context.Context, interval time.Duration) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.Check(ctx); err != nil {
+			logger.WarnContext(ctx, "storage usage check failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate records a breach in usage and alerts when the resource has just crossed its limit.
+func (m *StorageMonitor) evaluate(ctx context.Context, resource string, usageBytes, limitBytes int64, usage *StorageUsage) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	over := limitBytes > 0 && usageBytes > limitBytes
+	if over {
+		usage.Exceeded = append(usage.Exceeded, resource)
+	}
+
+	m.mu.Lock()
+	wasOver := m.exceeded[resource]
+	m.exceeded[resource] = over
+	m.mu.Unlock()
+
+	if !over || wasOver {
+		if wasOver && !over {
+			logger.InfoContext(ctx, "storage usage back under soft limit", "resource", resource, "usage_bytes", usageBytes, "limit_bytes", limitBytes)
+		}
+		return
+	}
+
+	logger.WarnContext(ctx, "storage soft limit exceeded",
+		"resource", resource,
+		"usage_bytes", usageBytes,
+		"limit_bytes", limitBytes,
+	)
+	if m.webhookURL == "" {
+		return
+	}
+	alert := LimitAlert{
+		Event:      "storage_soft_limit_exceeded",
+		Resource:   resource,
+		UsageBytes: usageBytes,
+		LimitBytes: limitBytes,
+		CheckedAt:  usage.CheckedAt,
+	}
+	if err := m.sendAlert(ctx, alert); err != nil {
+		logger.WarnContext(ctx, "failed to send storage alert webhook", "resource", resource, "error", err)
+	}
+}
+
+// sendAlert POSTs alert as JSON to the configured webhook.
+func (m *StorageMonitor) sendAlert(ctx context.Context, alert LimitAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sqliteSize returns the combined size of the database file and its WAL and shared-memory files.
+func sqliteSize(dbPath string) (int64, error) {
+	var total int64
+	for _, path := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat database file: %w", err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
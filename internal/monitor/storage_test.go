@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestStorageMonitor_Check(t *testing.T) {
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(dbPath, make([]byte, 1000), 0644); err != nil {
+		t.Fatalf("failed to write db file: %v", err)
+	}
+	if err := os.WriteFile(dbPath+"-wal", make([]byte, 500), 0644); err != nil {
+		t.Fatalf("failed to write wal file: %v", err)
+	}
+
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes", 4); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	points := []vectorstore.Point{
+		{ID: "a", Vec: []float32{1, 0, 0, 0}},
+		{ID: "b", Vec: []float32{0, 1, 0, 0}},
+	}
+	if err := store.Upsert(ctx, "notes", points); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var alerts []LimitAlert
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert LimitAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		mu.Lock()
+		alerts = append(alerts, alert)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	limits := StorageLimits{SQLiteBytes: 1200, QdrantBytes: 1 << 20}
+	monitor := NewStorageMonitor(dbPath, store, []string{"notes"}, limits, webhook.URL)
+
+	usage, err := monitor.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if usage.SQLiteBytes != 1500 {
+		t.Errorf("SQLiteBytes = %d, want 1500", usage.SQLiteBytes)
+	}
+	if usage.QdrantBytes != 2*4*bytesPerVectorDimension {
+		t.Errorf("QdrantBytes = %d, want %d", usage.QdrantBytes, 2*4*bytesPerVectorDimension)
+	}
+	if len(usage.Exceeded) != 1 || usage.Exceeded[0] != ResourceSQLite {
+		t.Errorf("Exceeded = %v, want [sqlite]", usage.Exceeded)
+	}
+
+	// A breach that persists is only alerted once
+	if _, err := monitor.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	mu.Lock()
+	if len(alerts) != 1 {
+		t.Fatalf("webhook received %d alerts, want 1", len(alerts))
+	}
+	if alerts[0].Resource != ResourceSQLite || alerts[0].UsageBytes != 1500 || alerts[0].LimitBytes != 1200 {
+		t.Errorf("alert = %+v, want sqlite 1500/1200", alerts[0])
+	}
+	mu.Unlock()
+
+	// Dropping below the limit re-arms the alert
+	if err := os.Remove(dbPath + "-wal"); err != nil {
+		t.Fatalf("failed to remove wal file: %v", err)
+	}
+	usage, err = monitor.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(usage.Exceeded) != 0 {
+		t.Errorf("Exceeded = %v, want none", usage.Exceeded)
+	}
+	if err := os.WriteFile(dbPath+"-wal", make([]byte, 500), 0644); err != nil {
+		t.Fatalf("failed to write wal file: %v", err)
+	}
+	if _, err := monitor.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 2 {
+		t.Errorf("webhook received %d alerts after re-crossing, want 2", len(alerts))
+	}
+}
+
+func TestStorageMonitor_CheckNoLimits(t *testing.T) {
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes", 4); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+
+	// Missing database files count as zero bytes
+	monitor := NewStorageMonitor(filepath.Join(t.TempDir(), "missing.db"), store, []string{"notes"}, StorageLimits{}, "")
+	usage, err := monitor.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if usage.SQLiteBytes != 0 || usage.QdrantBytes != 0 || len(usage.Exceeded) != 0 {
+		t.Errorf("usage = %+v, want zero usage and no breaches", usage)
+	}
+
+	if _, err := NewStorageMonitor("", store, []string{"missing"}, StorageLimits{}, "").Check(ctx); err == nil {
+		t.Error("Check() expected error for missing collection")
+	}
+}
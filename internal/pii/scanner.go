@@ -0,0 +1,304 @@
+package pii
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// Built-in pattern names.
+const (
+	PatternCreditCard = "credit_card"
+	PatternEmail      = "email"
+	PatternPhone      = "phone"
+)
+
+// maxNotesPerFolder caps the notes listed per folder in a report, most matches first.
+const maxNotesPerFolder = 20
+
+// Pattern is a named Go regular expression for one kind of personal data.
+type Pattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultPatterns are scanned for unless a patterns file replaces them by name.
+// Credit card candidates must also pass the Luhn check.
+var DefaultPatterns = []Pattern{
+	{Name: PatternCreditCard, Pattern: `\b\d(?:[ -]?\d){12,18}\b`},
+	{Name: PatternEmail, Pattern: `(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`},
+	{Name: PatternPhone, Pattern: `(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\b\d{2,4})[ .-]?\d{3,4}[ .-]?\d{3,4}\b`},
+}
+
+// LoadPatterns reads patterns from a JSON file ({"patterns": [{"name": ..., "pattern": ...}]})
+// and merges them with DefaultPatterns: a pattern named like a default replaces it, others
+// are added after the defaults.
+func LoadPatterns(path string) ([]Pattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII patterns: %w", err)
+	}
+	var file struct {
+		Patterns []Pattern `json:"patterns"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse PII patterns: %w", err)
+	}
+
+	patterns := append([]Pattern(nil), DefaultPatterns...)
+	for _, pattern := range file.Patterns {
+		replaced := false
+		for i := range patterns {
+			if patterns[i].Name == pattern.Name {
+				patterns[i] = pattern
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// ChunkLister lists indexed chunks with their note and vault. It is implemented by storage.ChunkRepo.
+type ChunkLister interface {
+	ListForExport(ctx context.Context, filter storage.ChunkExportFilter) ([]storage.ChunkExportRecord, error)
+}
+
+// Scanner looks for personal data in indexed chunk text.
+type Scanner struct {
+	chunks   ChunkLister
+	patterns []compiledPattern
+}
+
+// compiledPattern is a Pattern with its expression compiled.
+type compiledPattern struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// NewScanner compiles patterns (DefaultPatterns when empty).
+func NewScanner(chunks ChunkLister, patterns []Pattern) (*Scanner, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	scanner := &Scanner{chunks: chunks}
+	for _, pattern := range patterns {
+		name := strings.TrimSpace(pattern.Name)
+		if name == "" {
+			return nil, fmt.Errorf("PII pattern without a name")
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %s: %w", name, err)
+		}
+		compiled := compiledPattern{name: name, re: re}
+		if name == PatternCreditCard {
+			compiled.valid = luhnValid
+		}
+		scanner.patterns = append(scanner.patterns, compiled)
+	}
+	return scanner, nil
+}
+
+// PatternNames returns the names of the patterns scanned for, in scan order.
+func (s *Scanner) PatternNames() []string {
+	names := make([]string, 0, len(s.patterns))
+	for _, pattern := range s.patterns {
+		names = append(names, pattern.name)
+	}
+	return names
+}
+
+// Report summarizes the personal data found in a scan. It holds counts and note paths only,
+// never the matched text.
+type Report struct {
+	ScannedAt time.Time
+	Patterns  []string
+	Notes     int
+	Chunks    int
+	// Matches counts matches per pattern over all folders.
+	Matches map[string]int
+	// Folders lists every scanned folder, ordered by vault and folder, including folders
+	// without matches so clean folders can be told apart from unscanned ones.
+	Folders []FolderReport
+}
+
+// FolderReport is the scan result for one folder of a vault (not including subfolders).
+type FolderReport struct {
+	VaultName string
+	Folder    string
+	Notes     int
+	Chunks    int
+	// NotesWithMatches counts notes with at least one match.
+	NotesWithMatches int
+	Matches          map[string]int
+	// MatchedNotes lists the notes with matches, most matches first (at most 20).
+	MatchedNotes []NoteMatches
+}
+
+// NoteMatches counts the matches in one note.
+type NoteMatches struct {
+	RelPath string
+	Matches map[string]int
+}
+
+// total returns the number of matches over all patterns.
+func (n NoteMatches) total() int {
+	total := 0
+	for _, count := range n.Matches {
+		total += count
+	}
+	return total
+}
+
+// Scan walks the chunk text of the notes selected by filter and counts pattern matches per
+// vault and folder. Patterns are applied in order and text matched by one is not counted
+// again by later ones, so a card number is not also reported as a phone number.
+func (s *Scanner) Scan(ctx context.Context, filter storage.ChunkExportFilter) (*Report, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	chunks, err := s.chunks.ListForExport(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	type folderKey struct {
+		vaultName string
+		folder    string
+	}
+	type folderScan struct {
+		report FolderReport
+		notes  map[string]*NoteMatches
+		order  []string
+	}
+	folders := make(map[folderKey]*folderScan)
+	report := &Report{
+		ScannedAt: time.Now().UTC(),
+		Patterns:  s.PatternNames(),
+		Matches:   make(map[string]int),
+	}
+
+	for _, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		key := folderKey{chunk.VaultName, chunk.Folder}
+		scan, ok := folders[key]
+		if !ok {
+			scan = &folderScan{
+				report: FolderReport{VaultName: chunk.VaultName, Folder: chunk.Folder, Matches: make(map[string]int)},
+				notes:  make(map[string]*NoteMatches),
+			}
+			folders[key] = scan
+		}
+		note, ok := scan.notes[chunk.RelPath]
+		if !ok {
+			note = &NoteMatches{RelPath: chunk.RelPath, Matches: make(map[string]int)}
+			scan.notes[chunk.RelPath] = note
+			scan.order = append(scan.order, chunk.RelPath)
+			scan.report.Notes++
+			report.Notes++
+		}
+		scan.report.Chunks++
+		report.Chunks++
+
+		for name, count := range s.match(chunk.Text) {
+			note.Matches[name] += count
+			scan.report.Matches[name] += count
+			report.Matches[name] += count
+		}
+	}
+
+	for _, scan := range folders {
+		for _, relPath := range scan.order {
+			if note := scan.notes[relPath]; len(note.Matches) > 0 {
+				scan.report.MatchedNotes = append(scan.report.MatchedNotes, *note)
+			}
+		}
+		scan.report.NotesWithMatches = len(scan.report.MatchedNotes)
+		sort.SliceStable(scan.report.MatchedNotes, func(i, j int) bool {
+			return scan.report.MatchedNotes[i].total() > scan.report.MatchedNotes[j].total()
+		})
+		if len(scan.report.MatchedNotes) > maxNotesPerFolder {
+			scan.report.MatchedNotes = scan.report.MatchedNotes[:maxNotesPerFolder]
+		}
+		report.Folders = append(report.Folders, scan.report)
+	}
+	sort.Slice(report.Folders, func(i, j int) bool {
+		if report.Folders[i].VaultName != report.Folders[j].VaultName {
+			return report.Folders[i].VaultName < report.Folders[j].VaultName
+		}
+		return report.Folders[i].Folder < report.Folders[j].Folder
+	})
+
+	logger.InfoContext(ctx, "PII scan completed",
+		"vault", filter.VaultName,
+		"folder", filter.Folder,
+		"notes", report.Notes,
+		"chunks", report.Chunks,
+		"matches", report.Matches,
+	)
+	return report, nil
+}
+
+// match counts the matches of each pattern in text, blanking matched text so later
+// patterns skip it.
+func (s *Scanner) match(text string) map[string]int {
+	var counts map[string]int
+	for _, pattern := range s.patterns {
+		spans := pattern.re.FindAllStringIndex(text, -1)
+		if len(spans) == 0 {
+			continue
+		}
+		masked := []byte(text)
+		for _, span := range spans {
+			if pattern.valid != nil && !pattern.valid(text[span[0]:span[1]]) {
+				continue
+			}
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[pattern.name]++
+			for i := span[0]; i < span[1]; i++ {
+				masked[i] = ' '
+			}
+		}
+		text = string(masked)
+	}
+	return counts
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum used by card numbers.
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,168 @@
+package pii
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+)
+
+// fakeChunks lists a fixed set of chunks and records the filter it was given.
+type fakeChunks struct {
+	chunks []storage.ChunkExportRecord
+	filter storage.ChunkExportFilter
+}
+
+func (f *fakeChunks) ListForExport(_ context.Context, filter storage.ChunkExportFilter) ([]storage.ChunkExportRecord, error) {
+	f.filter = filter
+	return f.chunks, nil
+}
+
+func chunk(vaultName, folder, relPath, text string) storage.ChunkExportRecord {
+	return storage.ChunkExportRecord{
+		ChunkRecord: storage.ChunkRecord{Text: text},
+		VaultName:   vaultName,
+		Folder:      folder,
+		RelPath:     relPath,
+	}
+}
+
+func TestScanner_Match(t *testing.T) {
+	scanner, err := NewScanner(nil, nil)
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want map[string]int
+	}{
+		{
+			name: "email",
+			text: "Write to jane.doe+notes@example.com about the trip.",
+			want: map[string]int{PatternEmail: 1},
+		},
+		{
+			name: "phone numbers",
+			text: "Call the plumber at (555) 123-4567 or +44 20 7946 0958.",
+			want: map[string]int{PatternPhone: 2},
+		},
+		{
+			name: "card number is not also a phone number",
+			text: "Card: 4111 1111 1111 1111, expires soon.",
+			want: map[string]int{PatternCreditCard: 1},
+		},
+		{
+			name: "digits failing the Luhn check",
+			text: "Order 1234567812345678 shipped.",
+			want: nil,
+		},
+		{
+			name: "dates and plain numbers",
+			text: "On 2026-10-16 we walked 12 km in 3 hours.",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scanner.match(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	chunks := &fakeChunks{chunks: []storage.ChunkExportRecord{
+		chunk("personal", "contacts", "contacts/family.md", "Mum: mum@example.com, (555) 123-4567"),
+		chunk("personal", "contacts", "contacts/family.md", "Dad: dad@example.com"),
+		chunk("personal", "contacts", "contacts/friends.md", "Sam: sam@example.org"),
+		chunk("personal", "contacts", "contacts/notes.md", "Nothing personal here."),
+		chunk("personal", "garden", "garden/tomatoes.md", "Planted in May."),
+		chunk("work", "", "todo.md", "Email boss@example.com"),
+	}}
+	scanner, err := NewScanner(chunks, nil)
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v", err)
+	}
+
+	filter := storage.ChunkExportFilter{VaultName: "personal"}
+	report, err := scanner.Scan(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if chunks.filter != filter {
+		t.Errorf("ListForExport() filter = %+v, want %+v", chunks.filter, filter)
+	}
+
+	if report.Notes != 5 || report.Chunks != 6 {
+		t.Errorf("Scan() scanned %d notes, %d chunks, want 5, 6", report.Notes, report.Chunks)
+	}
+	if want := map[string]int{PatternEmail: 4, PatternPhone: 1}; !reflect.DeepEqual(report.Matches, want) {
+		t.Errorf("Scan() matches = %v, want %v", report.Matches, want)
+	}
+	if len(report.Folders) != 3 {
+		t.Fatalf("Scan() returned %d folders, want 3", len(report.Folders))
+	}
+
+	contacts := report.Folders[0]
+	if contacts.VaultName != "personal" || contacts.Folder != "contacts" {
+		t.Fatalf("Folders[0] = %s/%s, want personal/contacts", contacts.VaultName, contacts.Folder)
+	}
+	if contacts.Notes != 3 || contacts.NotesWithMatches != 2 {
+		t.Errorf("contacts notes = %d, with matches = %d, want 3, 2", contacts.Notes, contacts.NotesWithMatches)
+	}
+	wantNotes := []NoteMatches{
+		{RelPath: "contacts/family.md", Matches: map[string]int{PatternEmail: 2, PatternPhone: 1}},
+		{RelPath: "contacts/friends.md", Matches: map[string]int{PatternEmail: 1}},
+	}
+	if !reflect.DeepEqual(contacts.MatchedNotes, wantNotes) {
+		t.Errorf("contacts matched notes = %+v, want %+v", contacts.MatchedNotes, wantNotes)
+	}
+
+	garden := report.Folders[1]
+	if garden.Folder != "garden" || len(garden.Matches) != 0 || garden.MatchedNotes != nil {
+		t.Errorf("Folders[1] = %+v, want the garden folder without matches", garden)
+	}
+	if root := report.Folders[2]; root.VaultName != "work" || root.Folder != "" {
+		t.Errorf("Folders[2] = %s/%s, want the work vault root", root.VaultName, root.Folder)
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns.json")
+	data := `{"patterns": [{"name": "phone", "pattern": "\\b\\d{4} \\d{6}\\b"}, {"name": "iban", "pattern": "\\b[A-Z]{2}\\d{2}[A-Z0-9]{11,30}\\b"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	patterns, err := LoadPatterns(path)
+	if err != nil {
+		t.Fatalf("LoadPatterns() error = %v", err)
+	}
+	scanner, err := NewScanner(nil, patterns)
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v", err)
+	}
+	if want := []string{PatternCreditCard, PatternEmail, PatternPhone, "iban"}; !reflect.DeepEqual(scanner.PatternNames(), want) {
+		t.Errorf("PatternNames() = %v, want %v", scanner.PatternNames(), want)
+	}
+
+	got := scanner.match("Pay DE89370400440532013000, call 0161 496000")
+	if want := map[string]int{PatternPhone: 1, "iban": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("match() = %v, want %v", got, want)
+	}
+}
+
+func TestNewScanner_InvalidPattern(t *testing.T) {
+	if _, err := NewScanner(nil, []Pattern{{Name: "broken", Pattern: "("}}); err == nil {
+		t.Error("NewScanner() error = nil, want an error for an invalid pattern")
+	}
+	if _, err := NewScanner(nil, []Pattern{{Pattern: `\d+`}}); err == nil {
+		t.Error("NewScanner() error = nil, want an error for a pattern without a name")
+	}
+}
@@ -0,0 +1,277 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/storage"
+)
+
+// AnswerCacheOptions configures the on-disk cache of answers to repeated questions.
+type AnswerCacheOptions struct {
+	// TTL is how long an answer stays cached (0 disables the cache).
+	TTL time.Duration
+	// Config identifies the answering configuration (engine, model, stages). It is part of
+	// the cache key, so answers cached before a configuration change are not served.
+	Config string
+	// Flags are part of the cache key, so runtime flag overrides take effect immediately
+	// (nil uses built-in defaults).
+	Flags *features.Flags
+	// Caches receives the answer cache for the admin stats and flush API (optional).
+	Caches *cache.Registry
+}
+
+// answerCacheEngine wraps an Engine so repeated questions are answered from SQLite
+// without embedding, retrieval, or generation.
+type answerCacheEngine struct {
+	Engine
+	store    storage.AnswerCacheStore
+	versions storage.IndexEventStore
+	opts     AnswerCacheOptions
+	now      func() time.Time
+
+	mu sync.Mutex
+	// prunedVersion and prunedAt record the last stale-entry cleanup.
+	prunedVersion int64
+	prunedAt      time.Time
+
+	// hits and misses count lookups since start or the last flush.
+	hits, misses atomic.Int64
+}
+
+// NewAnswerCacheEngine wraps engine so Ask and AskStream answers are cached in store, keyed
+// by the normalized question and every request option. Entries are stamped with the index
+// version (the latest sequence number in events) and stop being served as soon as indexing
+// changes a note, or once opts.TTL has passed. Debug requests and requests with NoCache set
+// bypass the cache. Returns engine unchanged when opts.TTL is not positive.
+func NewAnswerCacheEngine(engine Engine, store storage.AnswerCacheStore, events storage.IndexEventStore, opts AnswerCacheOptions) Engine {
+	if opts.TTL <= 0 {
+		return engine
+	}
+	c := &answerCacheEngine{
+		Engine:        engine,
+		store:         store,
+		versions:      events,
+		opts:          opts,
+		now:           time.Now,
+		prunedVersion: -1,
+	}
+	opts.Caches.Register(CacheAnswers, c)
+	return c
+}
+
+// Stats reports the answer cache's lookups. Entries live in SQLite and are not counted.
+func (c *answerCacheEngine) Stats() cache.Stats {
+	return cache.Stats{
+		Name:       CacheAnswers,
+		Storage:    "sqlite",
+		TTLSeconds: int64(c.opts.TTL / time.Second),
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+	}.WithHitRate()
+}
+
+// Flush deletes every cached answer and resets the counters.
+func (c *answerCacheEngine) Flush(ctx context.Context) error {
+	deleted, err := c.store.DeleteAll(ctx)
+	if err != nil {
+		return err
+	}
+	c.hits.Store(0)
+	c.misses.Store(0)
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "flushed answer cache", "count", deleted)
+	return nil
+}
+
+// Ask answers req from the cache when an answer for the current index is stored.
+func (c *answerCacheEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error) {
+	key, version, ok := c.lookupKey(ctx, req)
+	if !ok {
+		return c.Engine.Ask(ctx, req)
+	}
+	if resp, hit := c.get(ctx, key, version); hit {
+		return resp, nil
+	}
+
+	resp, err := c.Engine.Ask(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	c.put(ctx, key, version, resp)
+	return resp, nil
+}
+
+// AskStream answers like Ask. A cached answer is passed to onToken in one piece.
+func (c *answerCacheEngine) AskStream(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
+	key, version, ok := c.lookupKey(ctx, req)
+	if !ok {
+		return c.Engine.AskStream(ctx, req, onToken)
+	}
+	if resp, hit := c.get(ctx, key, version); hit {
+		if resp.Answer != "" {
+			if err := onToken(resp.Answer); err != nil {
+				return AskResponse{}, err
+			}
+		}
+		return resp, nil
+	}
+
+	resp, err := c.Engine.AskStream(ctx, req, onToken)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	c.put(ctx, key, version, resp)
+	return resp, nil
+}
+
+// lookupKey returns the cache key and current index version for req. ok is false when
+// the request bypasses the cache or the index version cannot be read.
+func (c *answerCacheEngine) lookupKey(ctx context.Context, req AskRequest) (key string, version int64, ok bool) {
+	// Debug responses describe this run's retrieval and timings, so they are never cached
+	if req.NoCache || req.Debug {
+		return "", 0, false
+	}
+	_, version, err := c.versions.Bounds(ctx)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to read index version, skipping answer cache", "error", err)
+		return "", 0, false
+	}
+	allowedVaults, _ := contextutil.AllowedVaults(ctx)
+	key, err = answerCacheKey(req, allowedVaults, c.opts)
+	if err != nil {
+		return "", 0, false
+	}
+	return key, version, true
+}
+
+// get returns the cached answer for key if it was stored for version and has not expired.
+// Read failures count as misses.
+func (c *answerCacheEngine) get(ctx context.Context, key string, version int64) (AskResponse, bool) {
+	resp, hit := c.lookup(ctx, key, version)
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return resp, hit
+}
+
+// lookup reads and decodes the answer cached for key, if still valid.
+func (c *answerCacheEngine) lookup(ctx context.Context, key string, version int64) (AskResponse, bool) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	record, err := c.store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			logger.WarnContext(ctx, "failed to read cached answer", "error", err)
+		}
+		return AskResponse{}, false
+	}
+	if record.IndexVersion != version || !c.now().Before(record.ExpiresAt) {
+		return AskResponse{}, false
+	}
+
+	var resp AskResponse
+	if err := json.Unmarshal([]byte(record.Response), &resp); err != nil {
+		logger.WarnContext(ctx, "failed to decode cached answer", "error", err)
+		return AskResponse{}, false
+	}
+	resp.Cached = true
+	logger.InfoContext(ctx, "answered from cache", "index_version", version, "cached_at", record.CreatedAt)
+	return resp, true
+}
+
+// put stores resp under key unless it was truncated. Failures are logged and never fail the request. Stale entries
+// are deleted when the index version changes, and at most once per TTL otherwise.
+func (c *answerCacheEngine) put(ctx context.Context, key string, version int64, resp AskResponse) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// A truncated answer is only what one slow generation managed to produce
+	if resp.Truncated {
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to encode answer for cache", "error", err)
+		return
+	}
+	now := c.now()
+	if err := c.store.Put(ctx, &storage.AnswerCacheRecord{
+		Key:          key,
+		IndexVersion: version,
+		Response:     string(data),
+		ExpiresAt:    now.Add(c.opts.TTL),
+	}); err != nil {
+		logger.WarnContext(ctx, "failed to cache answer", "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	prune := version != c.prunedVersion || now.Sub(c.prunedAt) >= c.opts.TTL
+	if prune {
+		c.prunedVersion, c.prunedAt = version, now
+	}
+	c.mu.Unlock()
+	if !prune {
+		return
+	}
+	if deleted, err := c.store.DeleteStale(ctx, version, now); err != nil {
+		logger.WarnContext(ctx, "failed to delete stale cached answers", "error", err)
+	} else if deleted > 0 {
+		logger.DebugContext(ctx, "deleted stale cached answers", "count", deleted)
+	}
+}
+
+// answerCacheKey hashes the normalized question with every request option that can change
+// the answer, the vaults the caller may read (nil for every vault), the prompt version, the
+// answering configuration, and the feature flags in effect. The hash keeps question text out
+// of the cache table.
+func answerCacheKey(req AskRequest, allowedVaults []int, opts AnswerCacheOptions) (string, error) {
+	req.Question = normalizeCacheQuestion(req.Question)
+	req.Vaults = sortedCopy(req.Vaults)
+	req.Folders = sortedCopy(req.Folders)
+	req.Tags = sortedCopy(req.Tags)
+	req.Debug, req.NoCache = false, false
+
+	data, err := json.Marshal(struct {
+		Request       AskRequest      `json:"request"`
+		AllowedVaults []int           `json:"allowed_vaults"`
+		Prompt        string          `json:"prompt"`
+		Config        string          `json:"config"`
+		Flags         map[string]bool `json:"flags"`
+	}{req, allowedVaults, answerPromptVersion, opts.Config, opts.Flags.Snapshot()})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// normalizeCacheQuestion folds case and whitespace and drops trailing punctuation, so
+// "What is X?" and "what is  x" share a cache entry.
+func normalizeCacheQuestion(question string) string {
+	question = strings.Join(strings.Fields(strings.ToLower(question)), " ")
+	return strings.TrimRight(question, "?!. ")
+}
+
+// sortedCopy returns a sorted copy of values, so filter order does not change the key.
+func sortedCopy(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return sorted
+}
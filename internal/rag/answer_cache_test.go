@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// countingEngine answers every question and counts Ask and AskStream calls.
+type countingEngine struct {
+	Engine
+	calls int
+}
+
+func (c *countingEngine) Ask(_ context.Context, req AskRequest) (AskResponse, error) {
+	c.calls++
+	return AskResponse{Answer: "answer to " + req.Question, References: []Reference{{Vault: "personal", RelPath: "garden.md"}}}, nil
+}
+
+func (c *countingEngine) AskStream(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
+	resp, _ := c.Ask(ctx, req)
+	return resp, onToken(resp.Answer)
+}
+
+func TestAnswerCacheEngine(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	events := storage.NewIndexEventRepo(db)
+
+	ctx := context.Background()
+	inner := &countingEngine{}
+	engine := NewAnswerCacheEngine(inner, storage.NewAnswerCacheRepo(db), events, AnswerCacheOptions{TTL: time.Hour, Config: "llm"})
+	now := time.Now()
+	engine.(*answerCacheEngine).now = func() time.Time { return now }
+
+	ask := func(req AskRequest) AskResponse {
+		t.Helper()
+		resp, err := engine.Ask(ctx, req)
+		if err != nil {
+			t.Fatalf("Ask() error = %v", err)
+		}
+		return resp
+	}
+	req := AskRequest{Question: "Where are the tomatoes?", Vaults: []string{"work", "personal"}}
+
+	if resp := ask(req); resp.Cached || inner.calls != 1 {
+		t.Fatalf("first Ask() cached = %v, calls = %d, want fresh answer", resp.Cached, inner.calls)
+	}
+	// Case, whitespace, trailing punctuation, and filter order do not change the key
+	resp := ask(AskRequest{Question: "  where are the   TOMATOES", Vaults: []string{"personal", "work"}})
+	if !resp.Cached || inner.calls != 1 {
+		t.Fatalf("repeated Ask() cached = %v, calls = %d, want cached answer", resp.Cached, inner.calls)
+	}
+	if resp.Answer != "answer to Where are the tomatoes?" || len(resp.References) != 1 {
+		t.Errorf("cached Ask() = %+v, want the original answer and references", resp)
+	}
+
+	var streamed string
+	streamResp, err := engine.AskStream(ctx, req, func(token string) error {
+		streamed += token
+		return nil
+	})
+	if err != nil || !streamResp.Cached || streamed != streamResp.Answer || inner.calls != 1 {
+		t.Errorf("AskStream() = %+v, %v, streamed %q, want cached answer in one piece", streamResp, err, streamed)
+	}
+
+	// Different filters, no_cache, and debug requests are answered afresh
+	for _, other := range []AskRequest{
+		{Question: req.Question, Vaults: []string{"personal"}},
+		{Question: req.Question, Vaults: req.Vaults, NoCache: true},
+		{Question: req.Question, Vaults: req.Vaults, Debug: true},
+	} {
+		calls := inner.calls
+		if resp := ask(other); resp.Cached || inner.calls != calls+1 {
+			t.Errorf("Ask(%+v) cached = %v, want fresh answer", other, resp.Cached)
+		}
+	}
+
+	// A user limited to some vaults never gets an answer cached for every vault
+	calls := inner.calls
+	if resp, err := engine.Ask(contextutil.WithAllowedVaults(ctx, []int{1}), req); err != nil || resp.Cached || inner.calls != calls+1 {
+		t.Errorf("Ask() limited to vault 1 cached = %v, want fresh answer", resp.Cached)
+	}
+
+	// An index change invalidates the cache
+	if err := events.Record(ctx, &storage.IndexEventRecord{Type: storage.IndexEventNoteUpdated, VaultID: 1, RelPath: "garden.md"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	calls = inner.calls
+	if resp := ask(req); resp.Cached || inner.calls != calls+1 {
+		t.Errorf("Ask() after index change cached = %v, want fresh answer", resp.Cached)
+	}
+	if resp := ask(req); !resp.Cached {
+		t.Error("Ask() after re-caching cached = false, want true")
+	}
+
+	// So does the TTL passing
+	now = now.Add(2 * time.Hour)
+	calls = inner.calls
+	if resp := ask(req); resp.Cached || inner.calls != calls+1 {
+		t.Errorf("Ask() after TTL cached = %v, want fresh answer", resp.Cached)
+	}
+}
+
+func TestNewAnswerCacheEngine_Disabled(t *testing.T) {
+	inner := &countingEngine{}
+	if engine := NewAnswerCacheEngine(inner, nil, nil, AnswerCacheOptions{}); engine != Engine(inner) {
+		t.Errorf("NewAnswerCacheEngine() with zero TTL = %T, want the wrapped engine", engine)
+	}
+}
+
+func TestAnswerCacheEngine_RegistryFlush(t *testing.T) {
+	db, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	inner := &countingEngine{}
+	registry := cache.NewRegistry()
+	engine := NewAnswerCacheEngine(inner, storage.NewAnswerCacheRepo(db), storage.NewIndexEventRepo(db), AnswerCacheOptions{TTL: time.Hour, Caches: registry})
+	req := AskRequest{Question: "Where are the tomatoes?"}
+	for range 2 {
+		if _, err := engine.Ask(ctx, req); err != nil {
+			t.Fatalf("Ask() error = %v", err)
+		}
+	}
+
+	stats := registry.Stats()
+	if len(stats) != 1 || stats[0].Name != CacheAnswers || stats[0].Hits != 1 || stats[0].Misses != 1 || stats[0].Storage != "sqlite" {
+		t.Fatalf("registry.Stats() = %+v, want the answer cache with one hit and one miss", stats)
+	}
+
+	if err := registry.Flush(ctx, CacheAnswers); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if resp, _ := engine.Ask(ctx, req); resp.Cached || inner.calls != 2 {
+		t.Errorf("Ask() after flush cached = %v, calls = %d, want fresh answer", resp.Cached, inner.calls)
+	}
+}
@@ -0,0 +1,114 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/tracing"
+)
+
+// AnswerabilityOptions configures the answerability check that runs before generation.
+type AnswerabilityOptions struct {
+	// Threshold is the lowest score (0-1) the LLM judge may give the selected context before
+	// the question is abstained as insufficient_information (0 disables the check).
+	Threshold float32
+}
+
+// answerabilityContextChars bounds how much of each chunk is shown to the judge.
+const answerabilityContextChars = 800
+
+// answerabilityStage asks the LLM judge whether the selected chunks can answer the question
+// and abstains with insufficient_information when the score is below the threshold. Vector
+// and final scores only measure similarity, so a note about the right topic that lacks the
+// asked-for fact still passes select; the judge catches those before the model guesses.
+// A failed or unparseable judgement keeps the question answerable.
+func (e *ragEngine) answerabilityStage(ctx context.Context, s *askState) error {
+	threshold := e.answerability.Threshold
+	if threshold <= 0 || len(s.chunks) == 0 {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// The judge is an LLM call, so it is not counted as retrieval
+	s.endRetrieval()
+
+	judgeCtx, judgeSpan := tracing.Start(ctx, "rag.answerability", attribute.Int("chunks", len(s.chunks)))
+	score, reason, err := e.judgeAnswerability(judgeCtx, s.req.Question, s.chunks)
+	judgeSpan.RecordError(err)
+	latencyMs := judgeSpan.End().Milliseconds()
+	if err != nil {
+		logger.WarnContext(ctx, "answerability check failed, answering anyway", "error", err)
+		return nil
+	}
+
+	s.answerability = &AnswerabilityCheck{Score: score, Threshold: threshold, Reason: reason, LatencyMs: latencyMs}
+	logger.InfoContext(ctx, "answerability checked", "score", score, "threshold", threshold, "reason", reason)
+	if score < threshold {
+		e.abstainWithReason(ctx, s, s.retrieval.candidates, "insufficient_information",
+			"Your notes mention related topics, but they don't contain enough information to answer this question.")
+	}
+	return nil
+}
+
+// judgeAnswerability asks the LLM to score (0-1) whether chunks contain the information
+// needed to answer question.
+func (e *ragEngine) judgeAnswerability(ctx context.Context, question string, chunks []chunkData) (float32, string, error) {
+	var contextBuilder strings.Builder
+	for i, chunk := range chunks {
+		fmt.Fprintf(&contextBuilder, "[Chunk %d] %s (%s)\n%s\n\n", i+1, chunk.relPath, chunk.headingPath, truncateString(chunk.text, answerabilityContextChars))
+	}
+
+	prompt := fmt.Sprintf(`Decide whether the notes below contain the information needed to answer the question.
+
+Question: %s
+
+Notes:
+%s
+Instructions:
+- Score 1.0 if the notes state the answer, 0.5 if they answer it only in part, and 0.0 if they are about the topic but lack the answer or are unrelated
+- Judge only what the notes say; do not use outside knowledge
+- Return ONLY a JSON object like {"score": 0.8, "reason": "one short sentence"}, nothing else
+
+Your response (JSON object only):`, question, contextBuilder.String())
+
+	reply, err := e.chat(ctx, []llm.Message{
+		{Role: "user", Content: prompt},
+	}, llm.ChatParams{
+		Model:       "",  // Use default from client
+		MaxTokens:   100, // A score and one sentence
+		Temperature: 0,   // Deterministic verdicts for the same context
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to judge answerability: %w", err)
+	}
+	return parseAnswerability(reply)
+}
+
+// parseAnswerability extracts the score and reason from the judge's reply. Scores outside
+// [0, 1] are rejected rather than clamped, since they indicate the judge misread the scale.
+func parseAnswerability(reply string) (float32, string, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return 0, "", fmt.Errorf("reply is not a JSON object")
+	}
+	var verdict struct {
+		Score  *float32 `json:"score"`
+		Reason string   `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &verdict); err != nil {
+		return 0, "", fmt.Errorf("failed to unmarshal answerability verdict: %w", err)
+	}
+	if verdict.Score == nil {
+		return 0, "", fmt.Errorf("answerability verdict has no score")
+	}
+	if *verdict.Score < 0 || *verdict.Score > 1 {
+		return 0, "", fmt.Errorf("answerability score %v is outside [0, 1]", *verdict.Score)
+	}
+	return *verdict.Score, strings.TrimSpace(verdict.Reason), nil
+}
@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAnswerability(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		wantScore  float32
+		wantReason string
+		wantErr    bool
+	}{
+		{
+			name:       "plain object",
+			reply:      `{"score": 0.8, "reason": "The note gives the date."}`,
+			wantScore:  0.8,
+			wantReason: "The note gives the date.",
+		},
+		{
+			name:      "surrounding prose",
+			reply:     "Verdict:\n```json\n{\"score\": 0}\n```",
+			wantScore: 0,
+		},
+		{name: "not an object", reply: "The notes answer it.", wantErr: true},
+		{name: "missing score", reply: `{"reason": "unclear"}`, wantErr: true},
+		{name: "score out of range", reply: `{"score": 8}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reason, err := parseAnswerability(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAnswerability() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if score != tt.wantScore || reason != tt.wantReason {
+				t.Errorf("parseAnswerability() = (%v, %q), want (%v, %q)", score, reason, tt.wantScore, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAnswerabilityStage(t *testing.T) {
+	chunks := []chunkData{{text: "Booked the dentist for the spring.", relPath: "health/dentist.md", headingPath: "# Dentist"}}
+	newState := func() *askState {
+		return &askState{req: AskRequest{Question: "What time is my dentist appointment?"}, chunks: chunks}
+	}
+	tests := []struct {
+		name          string
+		threshold     float32
+		chat          *classifierChat
+		wantAbstained bool
+		wantJudged    bool
+	}{
+		{name: "below threshold abstains", threshold: 0.5, chat: &classifierChat{reply: `{"score": 0.2, "reason": "No time is given."}`}, wantAbstained: true, wantJudged: true},
+		{name: "at threshold answers", threshold: 0.5, chat: &classifierChat{reply: `{"score": 0.5}`}, wantJudged: true},
+		{name: "disabled", threshold: 0, chat: &classifierChat{reply: `{"score": 0}`}},
+		{name: "judge failure answers", threshold: 0.5, chat: &classifierChat{err: errors.New("llm down")}},
+		{name: "unparseable verdict answers", threshold: 0.5, chat: &classifierChat{reply: "maybe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &ragEngine{llmClient: tt.chat, answerability: AnswerabilityOptions{Threshold: tt.threshold}}
+			s := newState()
+			if err := engine.answerabilityStage(context.Background(), s); err != nil {
+				t.Fatalf("answerabilityStage() error = %v", err)
+			}
+
+			if abstained := s.resp != nil && s.resp.Abstained; abstained != tt.wantAbstained {
+				t.Fatalf("answerabilityStage() abstained = %v, want %v", abstained, tt.wantAbstained)
+			}
+			if tt.wantAbstained && s.resp.AbstainReason != "insufficient_information" {
+				t.Errorf("AbstainReason = %q, want insufficient_information", s.resp.AbstainReason)
+			}
+			if (s.answerability != nil) != tt.wantJudged {
+				t.Errorf("answerability = %+v, want judged %v", s.answerability, tt.wantJudged)
+			}
+			if tt.threshold == 0 && tt.chat.messages != nil {
+				t.Errorf("judge was called with the check disabled")
+			}
+		})
+	}
+
+	chat := &classifierChat{reply: `{"score": 1}`}
+	engine := &ragEngine{llmClient: chat, answerability: AnswerabilityOptions{Threshold: 0.5}}
+	if err := engine.answerabilityStage(context.Background(), newState()); err != nil {
+		t.Fatalf("answerabilityStage() error = %v", err)
+	}
+	if len(chat.messages) != 1 || !strings.Contains(chat.messages[0].Content, "Booked the dentist") {
+		t.Errorf("judge prompt does not include the selected chunk: %+v", chat.messages)
+	}
+}
@@ -0,0 +1,42 @@
+package rag
+
+import (
+	"helloworld-ai/internal/cache"
+)
+
+// Names the engine's caches are registered under (see EngineDeps.Caches).
+const (
+	// CacheQuestionEmbeddings holds recent question embeddings.
+	CacheQuestionEmbeddings = "question_embeddings"
+	// CacheVaults holds the vault list of the current index epoch.
+	CacheVaults = "vaults"
+	// CacheFolders holds folder stats per vault selection for the current index epoch.
+	CacheFolders = "folders"
+	// CacheFolderSelections holds folder rankings per question embedding and folder list.
+	CacheFolderSelections = "folder_selections"
+	// CachePromptTokens holds token counts of system prompts for debug responses.
+	CachePromptTokens = "prompt_tokens"
+	// CacheAnswers is the SQLite-backed answer cache (see NewAnswerCacheEngine).
+	CacheAnswers = "answers"
+)
+
+// maxPromptTokenCounts bounds the cached system prompt token counts; there is one system
+// prompt per mode and language setting, so a handful suffices.
+const maxPromptTokenCounts = 16
+
+// registerCaches adds the engine's enabled caches to registry.
+func (e *ragEngine) registerCaches(registry *cache.Registry) {
+	if e.questionCache != nil {
+		registry.Register(CacheQuestionEmbeddings, e.questionCache)
+	}
+	if e.folderCache != nil {
+		registry.Register(CacheFolderSelections, e.folderCache)
+	}
+	if e.metadata != nil {
+		registry.Register(CacheVaults, e.metadata.vaults)
+		registry.Register(CacheFolders, e.metadata.folders)
+	}
+	if e.promptTokens != nil {
+		registry.Register(CachePromptTokens, e.promptTokens)
+	}
+}
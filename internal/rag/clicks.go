@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"context"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// recordShown counts an impression for each returned reference, the denominator of its
+// click-through rate. Failures are logged and otherwise ignored.
+func (e *ragEngine) recordShown(ctx context.Context, references []Reference) {
+	if e.clickStore == nil || e.readOnly || len(references) == 0 {
+		return
+	}
+
+	chunkIDs := make([]string, 0, len(references))
+	for _, ref := range references {
+		if ref.ChunkID != "" {
+			chunkIDs = append(chunkIDs, ref.ChunkID)
+		}
+	}
+	if err := e.clickStore.RecordShown(ctx, chunkIDs); err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to record shown references", "error", err)
+	}
+}
+
+// addClickThrough fills in the click-through of debug chunks, so evaluation runs can
+// compare rankings with what users actually opened.
+func (e *ragEngine) addClickThrough(ctx context.Context, chunks []RetrievedChunk) {
+	if e.clickStore == nil || len(chunks) == 0 {
+		return
+	}
+
+	chunkIDs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		chunkIDs[i] = chunk.ChunkID
+	}
+	stats, err := e.clickStore.GetStats(ctx, chunkIDs)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to load reference click-through", "error", err)
+		return
+	}
+	for i := range chunks {
+		if s, ok := stats[chunks[i].ChunkID]; ok {
+			chunks[i].ClickThrough = &ClickThrough{Shown: s.Shown, Clicks: s.Clicks, Rate: s.ClickThrough()}
+		}
+	}
+}
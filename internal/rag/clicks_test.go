@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestRecordShown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClickStore := storage_mocks.NewMockChunkClickStore(ctrl)
+	engine := &ragEngine{clickStore: mockClickStore}
+	ctx := context.Background()
+
+	references := chunkReferences([]chunkData{
+		{vaultName: "personal", relPath: "garden.md", result: vectorstore.SearchResult{PointID: "chunk-1"}},
+		{vaultName: "work", relPath: "plan.md", result: vectorstore.SearchResult{PointID: "chunk-2"}},
+	})
+	if references[0].ChunkID != "chunk-1" || references[1].ChunkID != "chunk-2" {
+		t.Fatalf("references = %+v, want chunk IDs from the search results", references)
+	}
+
+	mockClickStore.EXPECT().RecordShown(gomock.Any(), []string{"chunk-1", "chunk-2"}).Return(nil)
+	engine.recordShown(ctx, references)
+
+	// Failures are logged, not returned
+	mockClickStore.EXPECT().RecordShown(gomock.Any(), gomock.Any()).Return(errors.New("database is locked"))
+	engine.recordShown(ctx, references)
+
+	// Without a store nothing is recorded
+	(&ragEngine{}).recordShown(ctx, references)
+
+	// Read-only replicas never write impressions
+	(&ragEngine{clickStore: mockClickStore, readOnly: true}).recordShown(ctx, references)
+}
+
+func TestAddClickThrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClickStore := storage_mocks.NewMockChunkClickStore(ctrl)
+	engine := &ragEngine{clickStore: mockClickStore}
+	chunks := []RetrievedChunk{{ChunkID: "chunk-1"}, {ChunkID: "chunk-2"}}
+
+	mockClickStore.EXPECT().GetStats(gomock.Any(), []string{"chunk-1", "chunk-2"}).
+		Return(map[string]storage.ChunkClickStats{"chunk-1": {ChunkID: "chunk-1", Shown: 4, Clicks: 1}}, nil)
+	engine.addClickThrough(context.Background(), chunks)
+
+	if got := chunks[0].ClickThrough; got == nil || *got != (ClickThrough{Shown: 4, Clicks: 1, Rate: 0.25}) {
+		t.Errorf("chunk-1 click-through = %+v, want 1 of 4", got)
+	}
+	if chunks[1].ClickThrough != nil {
+		t.Errorf("chunk-2 click-through = %+v, want nil for a chunk never shown", chunks[1].ClickThrough)
+	}
+}
@@ -0,0 +1,104 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// coalescingEngine wraps an Engine so identical Ask requests that arrive while one is
+// in flight share its result instead of repeating retrieval and generation.
+type coalescingEngine struct {
+	Engine
+
+	mu    sync.Mutex
+	calls map[string]*askCall
+}
+
+// askCall is an in-flight Ask shared by every caller with the same request.
+type askCall struct {
+	done    chan struct{}
+	resp    AskResponse
+	err     error
+	callers int
+}
+
+// NewCoalescingEngine wraps engine so concurrent Ask calls with identical requests
+// (same question, filters, and options) run once and all callers receive the same
+// response. Nothing is cached: a request arriving after the shared call finished runs
+// again. AskDocument and AskStream are passed through unchanged.
+func NewCoalescingEngine(engine Engine) Engine {
+	return &coalescingEngine{
+		Engine: engine,
+		calls:  make(map[string]*askCall),
+	}
+}
+
+// Ask answers req, joining an identical in-flight request when there is one.
+// The shared call is not cancelled when the caller that started it goes away, since
+// other callers may still be waiting; each caller stops waiting when its own ctx ends.
+func (c *coalescingEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error) {
+	key, err := coalesceKey(ctx, req)
+	if err != nil {
+		return c.Engine.Ask(ctx, req)
+	}
+
+	c.mu.Lock()
+	call, inFlight := c.calls[key]
+	if !inFlight {
+		call = &askCall{done: make(chan struct{})}
+		c.calls[key] = call
+	}
+	call.callers++
+	c.mu.Unlock()
+
+	if inFlight {
+		contextutil.LoggerFromContext(ctx).InfoContext(ctx, "joined identical in-flight question")
+	} else {
+		go c.run(context.WithoutCancel(ctx), key, call, req)
+	}
+
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		return AskResponse{}, ctx.Err()
+	}
+}
+
+// coalesceKey identifies requests that may share one call: the same request from callers
+// allowed the same vaults. The shared call runs with the first caller's context, so users
+// limited to other vaults must not join it.
+func coalesceKey(ctx context.Context, req AskRequest) (string, error) {
+	allowedVaults, _ := contextutil.AllowedVaults(ctx)
+	key, err := json.Marshal(struct {
+		Request       AskRequest `json:"request"`
+		AllowedVaults []int      `json:"allowed_vaults"`
+	}{req, allowedVaults})
+	return string(key), err
+}
+
+// run executes the shared call and releases its waiters. It runs outside the request
+// goroutine, so a panic is turned into an error instead of crashing the server.
+func (c *coalescingEngine) run(ctx context.Context, key string, call *askCall, req AskRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.resp, call.err = AskResponse{}, fmt.Errorf("failed to answer question: panic: %v", r)
+		}
+
+		c.mu.Lock()
+		delete(c.calls, key)
+		callers := call.callers
+		c.mu.Unlock()
+		close(call.done)
+
+		if callers > 1 {
+			contextutil.LoggerFromContext(ctx).InfoContext(ctx, "coalesced identical questions", "callers", callers)
+		}
+	}()
+
+	call.resp, call.err = c.Engine.Ask(ctx, req)
+}
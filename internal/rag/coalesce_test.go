@@ -0,0 +1,169 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// blockingEngine answers once release is closed and counts Ask calls.
+type blockingEngine struct {
+	Engine
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingEngine) Ask(_ context.Context, req AskRequest) (AskResponse, error) {
+	b.calls.Add(1)
+	b.started <- struct{}{}
+	<-b.release
+	return AskResponse{Answer: "answer to " + req.Question}, nil
+}
+
+func TestCoalescingEngine_SharesIdenticalRequests(t *testing.T) {
+	inner := &blockingEngine{started: make(chan struct{}, 4), release: make(chan struct{})}
+	engine := NewCoalescingEngine(inner)
+	ctx := context.Background()
+	req := AskRequest{Question: "Where are the tomatoes?", Vaults: []string{"personal"}}
+
+	var wg sync.WaitGroup
+	answers := make([]string, 3)
+	ask := func(i int, req AskRequest) {
+		defer wg.Done()
+		resp, err := engine.Ask(ctx, req)
+		if err != nil {
+			t.Errorf("Ask() error = %v", err)
+		}
+		answers[i] = resp.Answer
+	}
+
+	wg.Add(1)
+	go ask(0, req)
+	<-inner.started
+	// The identical request joins the in-flight call; a different filter runs on its own
+	wg.Add(2)
+	go ask(1, req)
+	go ask(2, AskRequest{Question: req.Question, Vaults: []string{"work"}})
+	<-inner.started
+	waitForCallers(t, engine.(*coalescingEngine), req, 2)
+	close(inner.release)
+	wg.Wait()
+
+	if got := inner.calls.Load(); got != 2 {
+		t.Errorf("inner Ask calls = %d, want 2 (one per distinct request)", got)
+	}
+	for i, answer := range answers {
+		if answer != "answer to Where are the tomatoes?" {
+			t.Errorf("caller %d answer = %q, want the shared answer", i, answer)
+		}
+	}
+
+	// Finished calls are not cached
+	inner.release = make(chan struct{})
+	close(inner.release)
+	if _, err := engine.Ask(ctx, req); err != nil {
+		t.Fatalf("Ask() after completion error = %v", err)
+	}
+	<-inner.started
+	if got := inner.calls.Load(); got != 3 {
+		t.Errorf("inner Ask calls = %d after a later identical request, want 3", got)
+	}
+}
+
+func TestCoalescingEngine_CallerCancellation(t *testing.T) {
+	inner := &blockingEngine{started: make(chan struct{}, 2), release: make(chan struct{})}
+	engine := NewCoalescingEngine(inner)
+	req := AskRequest{Question: "Where are the tomatoes?"}
+
+	// The caller that started the shared call gives up; a joined caller still gets the answer
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := engine.Ask(leaderCtx, req)
+		leaderErr <- err
+	}()
+	<-inner.started
+
+	followerResp := make(chan AskResponse, 1)
+	go func() {
+		resp, _ := engine.Ask(context.Background(), req)
+		followerResp <- resp
+	}()
+	waitForCallers(t, engine.(*coalescingEngine), req, 2)
+
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(inner.release)
+	if resp := <-followerResp; resp.Answer == "" {
+		t.Error("joined caller got no answer after the starting caller cancelled")
+	}
+}
+
+func TestCoalescingEngine_SeparatesUserVaults(t *testing.T) {
+	inner := &blockingEngine{started: make(chan struct{}, 4), release: make(chan struct{})}
+	engine := NewCoalescingEngine(inner)
+	req := AskRequest{Question: "Where are the tomatoes?"}
+	personalUser := contextutil.WithAllowedVaults(context.Background(), []int{1})
+	workUser := contextutil.WithAllowedVaults(context.Background(), []int{2})
+
+	if askKey(t, personalUser, req) == askKey(t, workUser, req) || askKey(t, personalUser, req) == askKey(t, context.Background(), req) {
+		t.Error("coalesceKey() is the same for callers allowed different vaults")
+	}
+
+	// Users allowed different vaults each get a call of their own
+	var wg sync.WaitGroup
+	for _, ctx := range []context.Context{personalUser, workUser} {
+		wg.Go(func() {
+			if _, err := engine.Ask(ctx, req); err != nil {
+				t.Errorf("Ask() error = %v", err)
+			}
+		})
+	}
+	<-inner.started
+	<-inner.started
+	close(inner.release)
+	wg.Wait()
+
+	if got := inner.calls.Load(); got != 2 {
+		t.Errorf("inner Ask calls = %d, want 2 (one per user)", got)
+	}
+}
+
+// waitForCallers waits until n callers share the in-flight call for req.
+func waitForCallers(t *testing.T, engine *coalescingEngine, req AskRequest, n int) {
+	t.Helper()
+	key := askKey(t, context.Background(), req)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		engine.mu.Lock()
+		call := engine.calls[key]
+		callers := 0
+		if call != nil {
+			callers = call.callers
+		}
+		engine.mu.Unlock()
+		if callers >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d callers", n)
+}
+
+// askKey returns the coalescing key for req asked with ctx.
+func askKey(t *testing.T, ctx context.Context, req AskRequest) string {
+	t.Helper()
+	key, err := coalesceKey(ctx, req)
+	if err != nil {
+		t.Fatalf("coalesceKey() error = %v", err)
+	}
+	return key
+}
@@ -0,0 +1,209 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conflict kinds reported in Conflict.Kind.
+const (
+	ConflictDate   = "date"
+	ConflictNumber = "number"
+)
+
+const (
+	// maxConflicts caps the number of conflicts injected into the prompt.
+	maxConflicts = 3
+	// minConflictSharedTerms is how many subject terms two statements must share to be
+	// treated as claims about the same fact.
+	minConflictSharedTerms = 2
+	// minConflictOverlap is the minimum Jaccard overlap of the two statements' subject terms.
+	minConflictOverlap = 0.4
+)
+
+var (
+	// sentenceBoundaryPattern splits chunk text into sentences and lines. Decimal points
+	// are not followed by whitespace, so "12.5" stays in one piece.
+	sentenceBoundaryPattern = regexp.MustCompile(`[.!?]+(?:\s+|$)|\n+`)
+	// claimNumberPattern matches a number with an optional unit ("12 people", "3.5 km", "40%").
+	claimNumberPattern = regexp.MustCompile(`(?i)(?:^|[^\w.])(-?\d+(?:\.\d+)?)(?:\s*(%)|\s+([a-z]+))?`)
+	// claimMonthNames are dropped from subject terms so differing dates do not read as
+	// differing subjects.
+	claimMonthNames = map[string]struct{}{
+		"january": {}, "february": {}, "march": {}, "april": {}, "may": {}, "june": {}, "july": {},
+		"august": {}, "september": {}, "october": {}, "november": {}, "december": {},
+		"jan": {}, "feb": {}, "mar": {}, "apr": {}, "jun": {}, "jul": {}, "aug": {},
+		"sep": {}, "sept": {}, "oct": {}, "nov": {}, "dec": {},
+	}
+)
+
+// claim is a sentence stating a single date or number.
+type claim struct {
+	kind     string
+	value    string // Comparable value (ISO date, or the number as written)
+	display  string // Value as shown to the LLM (number with its unit)
+	unit     string
+	terms    map[string]struct{}
+	chunk    int // Index of the source chunk
+	sentence string
+}
+
+// detectConflicts finds statements in different notes that give a different date or number
+// for what appears to be the same fact, so the LLM can surface the discrepancy instead of
+// silently picking one value. Two statements are about the same fact when their subject words
+// (the sentence without stopwords, months, and the value itself) overlap enough.
+func detectConflicts(chunks []chunkData) []Conflict {
+	var claims []claim
+	for i, chunk := range chunks {
+		claims = append(claims, extractClaims(chunk.text, i)...)
+	}
+
+	var conflicts []Conflict
+	seen := make(map[string]struct{})
+	for i := 0; i < len(claims) && len(conflicts) < maxConflicts; i++ {
+		for j := i + 1; j < len(claims) && len(conflicts) < maxConflicts; j++ {
+			a, b := claims[i], claims[j]
+			if !claimsConflict(a, b, chunks) {
+				continue
+			}
+			key := fmt.Sprintf("%s|%s|%s", a.kind, a.value, b.value)
+			if _, dup := seen[key]; dup {
+				continue
+			}
+			seen[key] = struct{}{}
+			conflicts = append(conflicts, Conflict{
+				Kind:   a.kind,
+				Claims: []ConflictClaim{conflictClaim(a, chunks[a.chunk]), conflictClaim(b, chunks[b.chunk])},
+			})
+		}
+	}
+	return conflicts
+}
+
+// extractClaims returns the sentences in text that state exactly one date or, failing that,
+// exactly one number.
+func extractClaims(text string, chunkIndex int) []claim {
+	var claims []claim
+	for _, sentence := range sentenceBoundaryPattern.Split(text, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+
+		if dates := extractDates(sentence); len(dates) > 0 {
+			if len(dates) == 1 {
+				value := dates[0].Format(time.DateOnly)
+				claims = append(claims, claim{
+					kind:     ConflictDate,
+					value:    value,
+					display:  value,
+					terms:    claimTerms(sentence, ""),
+					chunk:    chunkIndex,
+					sentence: sentence,
+				})
+			}
+			continue
+		}
+
+		matches := claimNumberPattern.FindAllStringSubmatch(thousandsPattern.ReplaceAllString(sentence, "$1$2"), -1)
+		if len(matches) != 1 {
+			continue
+		}
+		number, err := strconv.ParseFloat(matches[0][1], 64)
+		if err != nil {
+			continue
+		}
+		unit := strings.ToLower(matches[0][2] + matches[0][3])
+		if _, isStop := lexicalStopwords[unit]; isStop {
+			unit = ""
+		}
+		display := matches[0][1]
+		if unit == "%" {
+			display += unit
+		} else if unit != "" {
+			display += " " + unit
+		}
+		claims = append(claims, claim{
+			kind:     ConflictNumber,
+			value:    strconv.FormatFloat(number, 'f', -1, 64),
+			display:  display,
+			unit:     unit,
+			terms:    claimTerms(sentence, unit),
+			chunk:    chunkIndex,
+			sentence: sentence,
+		})
+	}
+	return claims
+}
+
+// claimTerms returns the subject words of a sentence: words of three or more letters that
+// are not stopwords, month names, or the claim's unit.
+func claimTerms(sentence, unit string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	for _, token := range filterStopwords(tokenize(sentence)) {
+		if len(token) < 3 || token == unit || strings.IndexFunc(token, func(r rune) bool { return r >= '0' && r <= '9' }) >= 0 {
+			continue
+		}
+		if _, isMonth := claimMonthNames[token]; isMonth {
+			continue
+		}
+		terms[token] = struct{}{}
+	}
+	return terms
+}
+
+// claimsConflict reports whether two claims from different notes give different values
+// for the same fact.
+func claimsConflict(a, b claim, chunks []chunkData) bool {
+	if a.kind != b.kind || a.unit != b.unit || a.value == b.value {
+		return false
+	}
+	chunkA, chunkB := chunks[a.chunk], chunks[b.chunk]
+	if chunkA.vaultName == chunkB.vaultName && chunkA.relPath == chunkB.relPath {
+		return false
+	}
+
+	shared := 0
+	for term := range a.terms {
+		if _, ok := b.terms[term]; ok {
+			shared++
+		}
+	}
+	union := len(a.terms) + len(b.terms) - shared
+	if shared < minConflictSharedTerms || union == 0 {
+		return false
+	}
+	return float64(shared)/float64(union) >= minConflictOverlap
+}
+
+func conflictClaim(c claim, chunk chunkData) ConflictClaim {
+	return ConflictClaim{
+		Value:       c.display,
+		VaultName:   chunk.vaultName,
+		RelPath:     chunk.relPath,
+		HeadingPath: chunk.headingPath,
+		Text:        c.sentence,
+	}
+}
+
+// formatConflicts renders conflicts as a prompt section (empty when there are none).
+func formatConflicts(conflicts []Conflict) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("--- Conflicting facts ---\n")
+	builder.WriteString("These notes appear to disagree. Do not pick one value; state both and cite both sources.\n")
+	for _, conflict := range conflicts {
+		parts := make([]string, 0, len(conflict.Claims))
+		for _, c := range conflict.Claims {
+			parts = append(parts, fmt.Sprintf("%q [File: %s, Section: %s]", c.Value, c.RelPath, c.HeadingPath))
+		}
+		builder.WriteString(fmt.Sprintf("[%s] %s\n", conflict.Kind, strings.Join(parts, " vs ")))
+	}
+	builder.WriteString("--- End Conflicting facts ---\n")
+	return builder.String()
+}
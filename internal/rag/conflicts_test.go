@@ -0,0 +1,93 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []chunkData
+		want   []string // Expected "kind: value vs value", in order
+	}{
+		{
+			name: "different dates for the same event",
+			chunks: []chunkData{
+				{vaultName: "personal", relPath: "Travel/Japan.md", headingPath: "Flights", text: "The flight to Tokyo departs on March 14, 2025."},
+				{vaultName: "personal", relPath: "Calendar/2025.md", headingPath: "March", text: "Packing list done. The Tokyo flight departs 2025-03-15."},
+			},
+			want: []string{"date: 2025-03-14 vs 2025-03-15"},
+		},
+		{
+			name: "different numbers with the same unit",
+			chunks: []chunkData{
+				{vaultName: "work", relPath: "Team.md", headingPath: "Team", text: "The platform team has 12 engineers."},
+				{vaultName: "work", relPath: "Org/Platform.md", headingPath: "Headcount", text: "Platform team headcount is 9 engineers after the reorg."},
+			},
+			want: []string{"number: 12 engineers vs 9 engineers"},
+		},
+		{
+			name: "same value agrees",
+			chunks: []chunkData{
+				{vaultName: "personal", relPath: "a.md", text: "The Tokyo flight departs on 2025-03-14."},
+				{vaultName: "personal", relPath: "b.md", text: "The Tokyo flight departs on March 14, 2025."},
+			},
+		},
+		{
+			name: "same note is not a conflict",
+			chunks: []chunkData{
+				{vaultName: "personal", relPath: "Travel/Japan.md", headingPath: "Plan", text: "The Tokyo flight departs on 2025-03-14."},
+				{vaultName: "personal", relPath: "Travel/Japan.md", headingPath: "Update", text: "The Tokyo flight departs on 2025-03-15."},
+			},
+		},
+		{
+			name: "different subjects",
+			chunks: []chunkData{
+				{vaultName: "personal", relPath: "a.md", text: "The Tokyo flight departs on 2025-03-14."},
+				{vaultName: "personal", relPath: "b.md", text: "Renewed my passport on 2025-01-02."},
+			},
+		},
+		{
+			name: "different units",
+			chunks: []chunkData{
+				{vaultName: "personal", relPath: "a.md", text: "The morning running route is 5 km."},
+				{vaultName: "personal", relPath: "b.md", text: "The morning running route is 3 miles."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := detectConflicts(tt.chunks)
+			var got []string
+			for _, conflict := range conflicts {
+				if len(conflict.Claims) != 2 {
+					t.Fatalf("conflict has %d claims, want 2", len(conflict.Claims))
+				}
+				got = append(got, conflict.Kind+": "+conflict.Claims[0].Value+" vs "+conflict.Claims[1].Value)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("detectConflicts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatConflicts(t *testing.T) {
+	if got := formatConflicts(nil); got != "" {
+		t.Errorf("formatConflicts(nil) = %q, want empty", got)
+	}
+
+	got := formatConflicts([]Conflict{{
+		Kind: ConflictDate,
+		Claims: []ConflictClaim{
+			{Value: "2025-03-14", RelPath: "Travel/Japan.md", HeadingPath: "Flights"},
+			{Value: "2025-03-15", RelPath: "Calendar/2025.md", HeadingPath: "March"},
+		},
+	}})
+	want := `[date] "2025-03-14" [File: Travel/Japan.md, Section: Flights] vs "2025-03-15" [File: Calendar/2025.md, Section: March]`
+	if !strings.Contains(got, "--- Conflicting facts ---") || !strings.Contains(got, want) {
+		t.Errorf("formatConflicts() = %q, want section containing %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package rag
 import (
 	"context"
 	"testing"
+	"time"
 
 	"helloworld-ai/internal/storage"
 	"helloworld-ai/internal/vectorstore"
@@ -61,6 +62,7 @@ func TestBuildDebugInfo(t *testing.T) {
 			lexicalScore: 0.80,
 			finalScore:   0.90,
 			originalRank: 1,
+			folderWeight: 1.0,
 		},
 		{
 			result: deduplicated[1],
@@ -142,6 +144,15 @@ func TestBuildDebugInfo(t *testing.T) {
 		if chunk.Text != "This is the main project overview." {
 			t.Errorf("expected text 'This is the main project overview.', got %q", chunk.Text)
 		}
+		if chunk.Explanation == nil {
+			t.Fatal("expected score explanation, got nil")
+		}
+		if chunk.Explanation.FolderWeight != 1.0 {
+			t.Errorf("expected folder_weight 1.0, got %f", chunk.Explanation.FolderWeight)
+		}
+		if chunk.Explanation.MatchedTerms == nil {
+			t.Error("expected matched_terms to be an empty list, got nil")
+		}
 	}
 
 	// Check folder selection
@@ -292,3 +303,25 @@ func TestBuildDebugInfo_FolderConversion(t *testing.T) {
 	}
 }
 
+
+func TestChunkProvenance(t *testing.T) {
+	meta := map[string]any{
+		"chunker_version": "v1.0",
+		"embedding_model": "embed-small",
+		"embedded_at":     "2026-10-16T09:30:00Z",
+		"run_id":          "run-1",
+	}
+	if got := chunkProvenance(meta, nil); got == nil || got.RunID != "run-1" || got.EmbeddedAt != "2026-10-16T09:30:00Z" {
+		t.Errorf("chunkProvenance(payload) = %+v, want the payload values", got)
+	}
+
+	// Points written before provenance was recorded fall back to the SQLite row
+	chunk := &storage.ChunkRecord{ChunkerVersion: "v1.0", EmbeddingModel: "embed-small", EmbeddedAt: time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC), RunID: "run-2"}
+	if got := chunkProvenance(map[string]any{}, chunk); got == nil || got.RunID != "run-2" || got.EmbeddedAt != "2026-10-16T09:30:00Z" {
+		t.Errorf("chunkProvenance(row) = %+v, want the row values", got)
+	}
+
+	if got := chunkProvenance(map[string]any{}, &storage.ChunkRecord{}); got != nil {
+		t.Errorf("chunkProvenance(none) = %+v, want nil", got)
+	}
+}
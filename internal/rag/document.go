@@ -0,0 +1,142 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+)
+
+// documentSectionChars is the largest document section sent to the LLM in one request.
+// Longer documents are answered map-reduce style: each section is condensed against the
+// question, then the answer is generated from the section notes.
+const documentSectionChars = 12000
+
+// DefaultDocumentQuestion is used when a document is submitted without a question.
+const DefaultDocumentQuestion = "Summarize this document."
+
+// AskDocument answers a question about a supplied document. No retrieval runs and the
+// answer carries no references, since the document is not part of the indexed notes.
+func (e *ragEngine) AskDocument(ctx context.Context, req DocumentRequest) (AskResponse, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		question = DefaultDocumentQuestion
+	}
+	if strings.TrimSpace(req.Document) == "" {
+		return AskResponse{}, fmt.Errorf("%w: document is empty", ErrNoContext)
+	}
+	if e.extractive {
+		return AskResponse{}, ErrGenerationDisabled
+	}
+
+	sections := splitDocument(req.Document, documentSectionChars)
+	logger.InfoContext(ctx, "document question started",
+		"question", contextutil.RedactQuestion(e.questionLogMode, question),
+		"document_name", req.Name,
+		"document_length", len(req.Document),
+		"sections", len(sections),
+	)
+
+	document := sections[0]
+	if len(sections) > 1 {
+		// Condense each section against the question so the final prompt fits the context window.
+		// Sections that run out of the generation timeout are left out and noted as unread.
+		notes := make([]string, 0, len(sections))
+		unread := 0
+		for i, section := range sections {
+			note, err := e.chat(ctx, []llm.Message{
+				{Role: "system", Content: "You read one section of a longer document. " +
+					"List the information in this section that helps answer the question, as concise bullet points. " +
+					"If nothing in the section is relevant, reply with 'Nothing relevant.'"},
+				{Role: "user", Content: fmt.Sprintf("Question: %s\n\nSection %d of %d:\n%s", question, i+1, len(sections), section)},
+			}, e.generation.apply(llm.ChatParams{Temperature: 0.2}))
+			if budgetExceeded(ctx, err) && unread+1 < len(sections) {
+				logger.WarnContext(ctx, "document section timed out, leaving it out", "section", i+1, "timeout", e.timeouts.Generation)
+				unread++
+				notes = append(notes, fmt.Sprintf("Section %d of %d: (not read: timed out)", i+1, len(sections)))
+				continue
+			}
+			if err != nil {
+				return AskResponse{}, fmt.Errorf("%w: document section %d: %w", ErrLLMFailure, i+1, err)
+			}
+			notes = append(notes, fmt.Sprintf("Section %d of %d:\n%s", i+1, len(sections), strings.TrimSpace(note)))
+		}
+		document = strings.Join(notes, "\n\n")
+		logger.InfoContext(ctx, "document sections condensed", "sections", len(sections), "notes_length", len(document))
+	}
+
+	systemPrompt := "You are a helpful assistant that answers questions about a document the user provided. " +
+		"Answer using only the document below. " +
+		"If the document doesn't contain enough information to answer the question, say so clearly."
+	if len(sections) > 1 {
+		systemPrompt += " The document was too long to read at once, so you are given notes taken from each of its sections in order."
+		systemPrompt += " If a section is marked as not read, say that the answer may be incomplete."
+	}
+
+	answer, err := e.chat(ctx, []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: fmt.Sprintf("%s\n\nDocument:\n%s", question, document)},
+	}, e.generation.apply(llm.ChatParams{Temperature: 0.3}))
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get LLM response", "error", err)
+		return AskResponse{}, fmt.Errorf("%w: %w", ErrLLMFailure, err)
+	}
+
+	meta := e.generationMeta(documentPromptVersion)
+	logger.InfoContext(ctx, "document question completed", "answer_length", len(answer), meta.logAttr())
+	return AskResponse{
+		Answer:     answer,
+		References: []Reference{},
+		Meta:       &meta,
+	}, nil
+}
+
+// splitDocument splits text into sections of at most maxChars bytes, breaking between
+// paragraphs where possible and never inside a UTF-8 character.
+func splitDocument(text string, maxChars int) []string {
+	text = strings.TrimSpace(text)
+	if len(text) <= maxChars {
+		return []string{text}
+	}
+
+	var sections []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			sections = append(sections, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+2+len(paragraph) > maxChars {
+			flush()
+		}
+		// A single paragraph longer than a section is cut at character boundaries
+		for len(paragraph) > maxChars {
+			cut := maxChars
+			for cut > 0 && !utf8.RuneStart(paragraph[cut]) {
+				cut--
+			}
+			flush()
+			sections = append(sections, paragraph[:cut])
+			paragraph = paragraph[cut:]
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+
+	return sections
+}
@@ -0,0 +1,116 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"helloworld-ai/internal/llm"
+)
+
+func TestSplitDocument(t *testing.T) {
+	paragraph := strings.Repeat("word ", 20) // 100 bytes with trailing space
+	tests := []struct {
+		name         string
+		text         string
+		maxChars     int
+		wantSections int
+	}{
+		{name: "fits in one section", text: "short text", maxChars: 100, wantSections: 1},
+		{name: "splits between paragraphs", text: paragraph + "\n\n" + paragraph + "\n\n" + paragraph, maxChars: 220, wantSections: 2},
+		{name: "cuts long paragraph", text: strings.Repeat("a", 250), maxChars: 100, wantSections: 3},
+		{name: "keeps multi-byte characters whole", text: strings.Repeat("é", 60), maxChars: 25, wantSections: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := splitDocument(tt.text, tt.maxChars)
+			if len(sections) != tt.wantSections {
+				t.Fatalf("splitDocument() returned %d sections, want %d", len(sections), tt.wantSections)
+			}
+			for _, section := range sections {
+				if len(section) > tt.maxChars {
+					t.Errorf("section length %d exceeds %d", len(section), tt.maxChars)
+				}
+				if !utf8.ValidString(section) {
+					t.Errorf("section is not valid UTF-8: %q", section)
+				}
+			}
+		})
+	}
+}
+
+func TestAskDocument_CondensesLongDocuments(t *testing.T) {
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+		_ = json.NewEncoder(w).Encode(llm.ChatResponse{
+			Choices: []llm.ChatChoice{{Message: llm.ChatChoiceMessage{Role: "assistant", Content: "- note"}}},
+		})
+	}))
+	defer server.Close()
+
+	engine := &ragEngine{llmClient: llm.NewClient(server.URL, "dummy-key", "test")}
+
+	short, err := engine.AskDocument(context.Background(), DocumentRequest{Document: "A short note."})
+	if err != nil {
+		t.Fatalf("AskDocument() error = %v", err)
+	}
+	if short.Answer != "- note" || len(prompts) != 1 {
+		t.Fatalf("short document: answer %q after %d LLM calls, want 1 call", short.Answer, len(prompts))
+	}
+	if !strings.HasPrefix(prompts[0], DefaultDocumentQuestion) {
+		t.Errorf("prompt should default to %q, got %q", DefaultDocumentQuestion, prompts[0][:40])
+	}
+
+	prompts = nil
+	long := strings.Repeat(strings.Repeat("x", 1000)+"\n\n", 30)
+	if _, err := engine.AskDocument(context.Background(), DocumentRequest{Question: "What is x?", Document: long}); err != nil {
+		t.Fatalf("AskDocument() error = %v", err)
+	}
+	// Three sections condensed, then one final answer over the section notes
+	if len(prompts) != 4 {
+		t.Fatalf("long document made %d LLM calls, want 4", len(prompts))
+	}
+	if !strings.Contains(prompts[3], "Section 3 of 3:\n- note") {
+		t.Errorf("final prompt should contain the section notes, got %q", prompts[3])
+	}
+
+	if _, err := engine.AskDocument(context.Background(), DocumentRequest{Document: " "}); !errors.Is(err, ErrNoContext) {
+		t.Errorf("AskDocument() error = %v, want ErrNoContext for empty document", err)
+	}
+}
+
+func TestAskDocument_GenerationOptions(t *testing.T) {
+	var got llm.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(llm.ChatResponse{
+			Choices: []llm.ChatChoice{{Message: llm.ChatChoiceMessage{Role: "assistant", Content: "answer"}}},
+		})
+	}))
+	defer server.Close()
+
+	engine := &ragEngine{
+		llmClient:  llm.NewClient(server.URL, "dummy-key", "test"),
+		generation: GenerationOptions{Stop: []string{"<|im_end|>"}, RepeatPenalty: 1.1, TopP: 0.9, TopK: 40},
+	}
+	if _, err := engine.AskDocument(context.Background(), DocumentRequest{Document: "A short note."}); err != nil {
+		t.Fatalf("AskDocument() error = %v", err)
+	}
+
+	if len(got.Stop) != 1 || got.Stop[0] != "<|im_end|>" || got.RepeatPenalty != 1.1 || got.TopP != 0.9 || got.TopK != 40 {
+		t.Errorf("request sampling controls = stop %v, repeat_penalty %v, top_p %v, top_k %v, want the engine options",
+			got.Stop, got.RepeatPenalty, got.TopP, got.TopK)
+	}
+	if got.Temperature != 0.3 {
+		t.Errorf("temperature = %v, want 0.3 kept from the call site", got.Temperature)
+	}
+}
@@ -9,9 +9,14 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/cache"
 	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
 	"helloworld-ai/internal/llm"
 	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/tracing"
 	"helloworld-ai/internal/vectorstore"
 )
 
@@ -28,6 +33,13 @@ const (
 	minFinalScoreThreshold  = 0.4
 )
 
+// Weak retrieval expansion: when the first pass only finds candidates barely above
+// minFinalScoreThreshold, retrieval runs once more with a larger K and relaxed folder scoping.
+const (
+	expandedCandidateKPerScope = 45
+	weakRetrievalMargin        = float32(0.05)
+)
+
 type rerankCandidate struct {
 	result       vectorstore.SearchResult
 	chunk        *storage.ChunkRecord
@@ -39,43 +51,196 @@ type rerankCandidate struct {
 	lexicalScore float32
 	finalScore   float32
 	originalRank int
+	folderWeight float32
+	lexical      lexicalBreakdown
+	// headingMatch is set when the question names the chunk's heading (see heading.go).
+	headingMatch bool
+	// rerankerScore is the external reranker's score (nil when the lexical score was used).
+	rerankerScore *float32
+	// tagMatches are the note tags sharing a word with the question (see tags.go).
+	tagMatches []string
+	// tagBoost is the boost added to the final score for tagMatches.
+	tagBoost float32
 }
 
 // Engine provides RAG (Retrieval-Augmented Generation) functionality.
 type Engine interface {
 	// Ask answers a question using RAG by retrieving relevant chunks and generating an answer.
 	Ask(ctx context.Context, req AskRequest) (AskResponse, error)
+	// AskDocument answers a question about a document supplied with the request instead of indexed notes.
+	AskDocument(ctx context.Context, req DocumentRequest) (AskResponse, error)
+	// AskStream answers like Ask, passing the answer text to onToken as it is generated.
+	// Answers that are not generated token by token are passed to onToken in one piece.
+	AskStream(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error)
+	// Search retrieves and ranks the chunks for a question like Ask, without calling the chat LLM.
+	Search(ctx context.Context, req AskRequest) (SearchResponse, error)
 }
 
 // ragEngine implements the Engine interface.
 type ragEngine struct {
-	embedder    *llm.EmbeddingsClient
-	vectorStore vectorstore.VectorStore
-	collection  string
-	chunkRepo   storage.ChunkStore
-	vaultRepo   storage.VaultStore
-	noteRepo    storage.NoteStore
-	llmClient   *llm.Client
+	embedder       Embedder
+	vectorStore    vectorstore.VectorStore
+	collection     string
+	coldCollection string
+	chunkRepo      storage.ChunkStore
+	vaultRepo      storage.VaultStore
+	noteRepo       storage.NoteStore
+	llmClient      ChatBackend
+	// questionLogMode controls how question text is logged (see contextutil.QuestionLog*).
+	questionLogMode string
+	// folderSelection bounds the folder list offered to the LLM and selects the ranking mode.
+	folderSelection FolderSelectionOptions
+	// folderCache holds folder rankings per question embedding (nil when disabled).
+	folderCache *cache.Cache[string, []string]
+	// notePrefilter configures the note-level first stage of two-stage retrieval.
+	notePrefilter NotePrefilterOptions
+	// flags gates experimental behaviors (nil uses built-in defaults).
+	flags *features.Flags
+	// questionCache holds recent question embeddings (nil when disabled).
+	questionCache *cache.Cache[string, []float32]
+	// extractive skips LLM folder ranking and answer generation (see EngineExtractive).
+	extractive bool
+	// generation holds the sampling controls for answer generation.
+	generation GenerationOptions
+	// hydrator recovers chunk text from disk when SQLite lacks it (nil disables it).
+	hydrator ChunkHydrator
+	// metadata caches vaults and folders per index epoch (nil reads SQLite on every question).
+	metadata *metadataCache
+	// scoreFloor bounds how far requests may lower the score thresholds (zero: not below the defaults).
+	scoreFloor ScoreThresholds
+	// clickStore counts reference impressions and clicks (nil disables click-through tracking).
+	clickStore storage.ChunkClickStore
+	// readOnly skips the best-effort writes made while answering (read-only replicas).
+	readOnly bool
+	// stages is the ask pipeline (nil runs DefaultStages).
+	stages []string
+	// reranker scores candidates in place of the lexical score (nil uses lexical scores).
+	reranker Reranker
+	// tokenizer counts the answer prompt's tokens for debug responses (nil omits them).
+	tokenizer Tokenizer
+	// promptTokens caches system prompt token counts (nil without a tokenizer).
+	promptTokens *cache.Cache[string, int]
+	// answerability configures the LLM answerability check before generation.
+	answerability AnswerabilityOptions
+	// neighbors bounds the neighbor chunks merged into the context before generation.
+	neighbors NeighborOptions
+	// collectionPerVault searches each vault's own chunk collection instead of collection.
+	collectionPerVault bool
+	// retrieval tunes candidate retrieval and scoring (zero fields use DefaultRetrievalOptions).
+	retrieval RetrievalOptions
+	// timeouts bound the embedding, search, and generation calls (zero: request context only).
+	timeouts TimeoutOptions
+	// judging scores generated answers and regenerates unfaithful ones (nil Judge disables it).
+	judging JudgeOptions
 }
 
-// NewEngine creates a new RAG engine.
+// NewEngine creates a new RAG engine backed by an embedder and a chat backend.
+// Use NewEngineByName to select an engine implementation from configuration.
+// coldCollection is searched only when a request opts in via IncludeCold (empty disables it).
+// questionLogMode is one of the contextutil.QuestionLog* modes (empty logs questions verbatim).
+// notePrefilter enables two-stage retrieval when its collection and TopM are set.
+// flags gates experimental behaviors per deployment; nil uses the built-in defaults.
+// questionCache bounds the cache of recent question embeddings (zero Size disables it).
+// generation sets stop sequences and sampling controls for answers (zero values use server defaults).
 func NewEngine(
-	embedder *llm.EmbeddingsClient,
+	embedder Embedder,
 	vectorStore vectorstore.VectorStore,
 	collection string,
+	coldCollection string,
 	chunkRepo storage.ChunkStore,
 	vaultRepo storage.VaultStore,
 	noteRepo storage.NoteStore,
-	llmClient *llm.Client,
+	llmClient ChatBackend,
+	questionLogMode string,
+	folderSelection FolderSelectionOptions,
+	notePrefilter NotePrefilterOptions,
+	flags *features.Flags,
+	questionCache QuestionCacheOptions,
+	generation GenerationOptions,
 ) Engine {
 	return &ragEngine{
-		embedder:    embedder,
-		vectorStore: vectorStore,
-		collection:  collection,
-		chunkRepo:   chunkRepo,
-		vaultRepo:   vaultRepo,
-		noteRepo:    noteRepo,
-		llmClient:   llmClient,
+		embedder:        embedder,
+		vectorStore:     vectorStore,
+		collection:      collection,
+		coldCollection:  coldCollection,
+		chunkRepo:       chunkRepo,
+		vaultRepo:       vaultRepo,
+		noteRepo:        noteRepo,
+		llmClient:       llmClient,
+		questionLogMode: questionLogMode,
+		folderSelection: folderSelection,
+		folderCache:     newFolderCache(folderSelection),
+		notePrefilter:   notePrefilter,
+		flags:           flags,
+		questionCache:   newQuestionCache(questionCache),
+		generation:      generation,
+	}
+}
+
+// search queries the primary collection and, when includeCold is set and a cold
+// collection is configured, the cold collection as well. A cold search that runs out of its
+// search timeout leaves the primary results.
+func (e *ragEngine) search(ctx context.Context, queryVector []float32, k int, filters map[string]any, includeCold bool) ([]vectorstore.SearchResult, error) {
+	results, err := e.searchCollection(ctx, e.chunkCollection(filters), queryVector, k, filters)
+	if err != nil {
+		return nil, err
+	}
+	if !includeCold || e.coldCollection == "" {
+		return results, nil
+	}
+
+	coldResults, err := e.searchCollection(ctx, e.coldCollection, queryVector, k, filters)
+	if budgetExceeded(ctx, err) {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "cold collection search timed out, using primary results only", "timeout", e.timeouts.Search)
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search cold collection: %w", err)
+	}
+	return append(results, coldResults...), nil
+}
+
+// searchCollection runs one vector search within the search timeout.
+func (e *ragEngine) searchCollection(ctx context.Context, collection string, queryVector []float32, k int, filters map[string]any) ([]vectorstore.SearchResult, error) {
+	searchCtx, cancel := withTimeout(ctx, e.timeouts.Search)
+	defer cancel()
+	return e.vectorStore.Search(searchCtx, collection, queryVector, k, filters)
+}
+
+// chunkCollection returns the collection holding the hot chunks a search may match. Searches
+// are scoped to one vault by their vault_id filter, so with per-vault collections the fan-out
+// across vaults queries each vault's collection and the results are merged by the caller.
+func (e *ragEngine) chunkCollection(filters map[string]any) string {
+	if vaultID, ok := filters["vault_id"].(int); ok && e.collectionPerVault {
+		return vectorstore.VaultCollection(e.collection, vaultID)
+	}
+	return e.collection
+}
+
+// markRetrieved records which notes contributed chunks to an answer so the cold
+// storage policy keeps them hot. Failures are logged and otherwise ignored.
+func (e *ragEngine) markRetrieved(ctx context.Context, candidates []rerankCandidate) {
+	if e.noteRepo == nil || e.readOnly || len(candidates) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	noteIDs := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		noteID, _ := candidate.result.Meta["note_id"].(string)
+		if noteID == "" && candidate.chunk != nil {
+			noteID = candidate.chunk.NoteID
+		}
+		if noteID == "" || seen[noteID] {
+			continue
+		}
+		seen[noteID] = true
+		noteIDs = append(noteIDs, noteID)
+	}
+
+	if err := e.noteRepo.MarkRetrieved(ctx, noteIDs); err != nil {
+		logger := contextutil.LoggerFromContext(ctx)
+		logger.WarnContext(ctx, "failed to record note retrieval", "error", err)
 	}
 }
 
@@ -87,15 +252,10 @@ func truncateString(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-// selectRelevantFolders uses LLM to rank folders by relevance to the question.
-// Returns ordered list: user-provided folders first, then LLM-ranked folders.
-// availableFolders format is "<vaultID>/folder" (e.g., "1/projects/work").
-// userFolders format can be "<vaultID>/folder" or just "folder" (prefix matching).
-// Returns folders in format "<vaultName>/folder" (e.g., "personal/workouts").
-func (e *ragEngine) selectRelevantFolders(ctx context.Context, question string, availableFolders []string, userFolders []string, vaultIDs []int, vaultMap map[int]string) []string {
-	logger := contextutil.LoggerFromContext(ctx)
-
-	// Start with user-provided folders (they are already prioritized)
+// matchUserFolders returns the available folders ("<vaultID>/folder") matching the user's
+// folders, in the user's order. User folders may be "folder", "<vaultID>/folder", or
+// "<vaultName>/folder"; a folder also matches its subfolders and its ancestors.
+func matchUserFolders(availableFolders, userFolders []string, vaultMap map[int]string) []string {
 	orderedFolders := make([]string, 0, len(userFolders))
 	seenFolders := make(map[string]bool)
 
@@ -132,11 +292,39 @@ func (e *ragEngine) selectRelevantFolders(ctx context.Context, question string,
 			}
 		}
 	}
+	return orderedFolders
+}
+
+// selectRelevantFolders uses LLM to rank folders by relevance to the question.
+// Returns ordered list: user-provided folders first, then LLM-ranked folders.
+// availableFolders format is "<vaultID>/folder" (e.g., "1/projects/work").
+// userFolders format can be "<vaultID>/folder" or just "folder" (prefix matching).
+// queryVector keys the folder ranking cache and drives the heuristic ranking of
+// FolderSelectionHeuristic mode, which skips the LLM when one folder clearly leads (nil
+// disables both).
+// folderRetrievals counts past retrievals per available folder; it ranks folders when the
+// LLM fails (see fallbackFolderRanking).
+// Returns folders in format "<vaultName>/folder" (e.g., "personal/workouts") and how they
+// were chosen (one of the folderMethod* values).
+func (e *ragEngine) selectRelevantFolders(ctx context.Context, question string, queryVector []float32, availableFolders []string, userFolders []string, vaultIDs []int, vaultMap map[int]string, folderRetrievals map[string]int) ([]string, string) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// Start with user-provided folders (they are already prioritized)
+	orderedFolders := matchUserFolders(availableFolders, userFolders, vaultMap)
+	seenFolders := make(map[string]bool, len(orderedFolders))
+	for _, folder := range orderedFolders {
+		seenFolders[folder] = true
+	}
+
+	// Without generation there is no LLM to rank folders; only user folders scope the search
+	if e.extractive {
+		return orderedFolders, folderMethodUser
+	}
 
 	// If no available folders, return empty list
 	if len(availableFolders) == 0 {
 		logger.WarnContext(ctx, "no available folders for selection")
-		return orderedFolders
+		return orderedFolders, folderMethodUser
 	}
 
 	// Filter out user folders from available list for LLM ranking
@@ -150,7 +338,24 @@ func (e *ragEngine) selectRelevantFolders(ctx context.Context, question string,
 	// If no folders left for LLM, return user folders only
 	if len(foldersForLLM) == 0 {
 		logger.InfoContext(ctx, "all folders already selected by user", "folder_count", len(orderedFolders))
-		return orderedFolders
+		return orderedFolders, folderMethodUser
+	}
+
+	// Repeated questions reuse the ranking made for the same embedding and folders
+	cacheKey := folderCacheKey(queryVector, availableFolders, userFolders)
+	if cached, ok := e.cachedFolders(cacheKey); ok {
+		logger.InfoContext(ctx, "folder selection served from cache", "ordered_folders", len(cached))
+		return cached, folderMethodCache
+	}
+
+	// The heuristic ranking replaces the LLM call unless it is inconclusive
+	if e.folderSelection.Mode == FolderSelectionHeuristic {
+		if ranked, ok := e.heuristicFolders(ctx, queryVector, foldersForLLM, vaultIDs); ok {
+			orderedFolders = append(orderedFolders, ranked...)
+			e.cacheFolders(cacheKey, orderedFolders)
+			return orderedFolders, folderMethodHeuristic
+		}
+		logger.InfoContext(ctx, "falling back to LLM folder selection")
 	}
 
 	// Convert folders to use vault names instead of IDs for LLM
@@ -203,95 +408,40 @@ Your response (JSON array only):`, question, folderList)
 		{Role: "user", Content: prompt},
 	}
 
-	llmResponse, err := e.llmClient.ChatWithMessages(ctx, messages, llm.ChatParams{
-		Model:       "",  // Use default from client
-		MaxTokens:   500, // Limit response size
-		Temperature: 0.3, // Lower temperature for more consistent ranking
+	// The schema restricts the reply to an array of the offered folders, so llama.cpp's
+	// grammar sampling cannot produce prose, markdown fences, or unknown folders
+	rankCtx := ctx
+	if e.folderSelection.Timeout > 0 {
+		var cancel context.CancelFunc
+		rankCtx, cancel = context.WithTimeout(ctx, e.folderSelection.Timeout)
+		defer cancel()
+	}
+	llmResponse, err := e.llmClient.ChatWithMessages(rankCtx, messages, llm.ChatParams{
+		Model:          "",  // Use default from client
+		MaxTokens:      500, // Limit response size
+		Temperature:    0.3, // Lower temperature for more consistent ranking
+		ResponseFormat: folderRankingFormat(foldersWithVaultNames),
 	})
 
 	if err != nil {
-		logger.WarnContext(ctx, "failed to get LLM response for folder selection, using all available folders", "error", err)
-		// Fallback: add all remaining folders in original order
-		orderedFolders = append(orderedFolders, foldersForLLM...)
-		return orderedFolders
+		logger.WarnContext(ctx, "failed to get LLM response for folder selection, ranking folders without the LLM", "error", err)
+		return e.fallbackFolders(ctx, question, orderedFolders, foldersForLLM, folderRetrievals), folderMethodFallback
 	}
 
 	// Check for empty response
 	llmResponse = strings.TrimSpace(llmResponse)
 	if llmResponse == "" {
-		logger.WarnContext(ctx, "LLM returned empty response for folder selection, using all available folders",
+		logger.WarnContext(ctx, "LLM returned empty response for folder selection, ranking folders without the LLM",
 			"prompt_length", len(prompt),
 			"folder_count", len(foldersWithVaultNames),
 		)
-		// Fallback: add all remaining folders in original order
-		orderedFolders = append(orderedFolders, foldersForLLM...)
-		return orderedFolders
-	}
-
-	// Parse JSON response
-	var llmRankedFolders []string
-
-	// First, try to find JSON array pattern in the response
-	// Look for pattern: [ ... ] where ... contains quoted strings
-	jsonStart := strings.Index(llmResponse, "[")
-	jsonEnd := strings.LastIndex(llmResponse, "]")
-
-	if jsonStart >= 0 && jsonEnd > jsonStart {
-		// Extract the JSON array portion
-		jsonCandidate := llmResponse[jsonStart : jsonEnd+1]
-		// Try parsing this extracted portion
-		if err := json.Unmarshal([]byte(jsonCandidate), &llmRankedFolders); err == nil {
-			// Successfully parsed the extracted JSON
-			logger.DebugContext(ctx, "extracted JSON array from LLM response",
-				"extracted_json", jsonCandidate,
-				"parsed_folders", llmRankedFolders,
-			)
-		} else {
-			// Try removing markdown code blocks if present
-			cleanedResponse := llmResponse
-			if strings.HasPrefix(cleanedResponse, "```") {
-				lines := strings.Split(cleanedResponse, "\n")
-				if len(lines) > 1 {
-					cleanedResponse = strings.Join(lines[1:len(lines)-1], "\n")
-				}
-				cleanedResponse = strings.TrimSpace(cleanedResponse)
-			}
-			// Remove json prefix if present
-			if strings.HasPrefix(cleanedResponse, "json") {
-				cleanedResponse = strings.TrimPrefix(cleanedResponse, "json")
-				cleanedResponse = strings.TrimSpace(cleanedResponse)
-			}
-			// Try parsing the cleaned response
-			if err := json.Unmarshal([]byte(cleanedResponse), &llmRankedFolders); err != nil {
-				logger.WarnContext(ctx, "failed to parse LLM response as JSON, using all available folders", "error", err, "response_preview", truncateString(llmResponse, 200))
-				// Fallback: add all remaining folders in original order
-				orderedFolders = append(orderedFolders, foldersForLLM...)
-				return orderedFolders
-			}
-		}
-	} else {
-		// No JSON array pattern found, try parsing the whole response after cleaning
-		cleanedResponse := llmResponse
-		// Remove markdown code blocks if present
-		if strings.HasPrefix(cleanedResponse, "```") {
-			lines := strings.Split(cleanedResponse, "\n")
-			if len(lines) > 1 {
-				cleanedResponse = strings.Join(lines[1:len(lines)-1], "\n")
-			}
-			cleanedResponse = strings.TrimSpace(cleanedResponse)
-		}
-		// Remove json prefix if present
-		if strings.HasPrefix(cleanedResponse, "json") {
-			cleanedResponse = strings.TrimPrefix(cleanedResponse, "json")
-			cleanedResponse = strings.TrimSpace(cleanedResponse)
-		}
-		// Try parsing the cleaned response
-		if err := json.Unmarshal([]byte(cleanedResponse), &llmRankedFolders); err != nil {
-			logger.WarnContext(ctx, "failed to parse LLM response as JSON, using all available folders", "error", err, "response_preview", truncateString(llmResponse, 200))
-			// Fallback: add all remaining folders in original order
-			orderedFolders = append(orderedFolders, foldersForLLM...)
-			return orderedFolders
-		}
+		return e.fallbackFolders(ctx, question, orderedFolders, foldersForLLM, folderRetrievals), folderMethodFallback
+	}
+
+	llmRankedFolders, err := parseFolderRanking(llmResponse)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to parse LLM response as JSON, ranking folders without the LLM", "error", err, "response_preview", truncateString(llmResponse, 200))
+		return e.fallbackFolders(ctx, question, orderedFolders, foldersForLLM, folderRetrievals), folderMethodFallback
 	}
 
 	logger.DebugContext(ctx, "LLM folder ranking response",
@@ -326,7 +476,8 @@ Your response (JSON array only):`, question, folderList)
 	// If both are empty, return all available folders
 	if len(orderedFolders) == 0 && len(userFolders) == 0 && len(llmRankedFolders) == 0 {
 		logger.InfoContext(ctx, "no user or LLM folders selected, returning all available folders")
-		return availableFolders
+		e.cacheFolders(cacheKey, availableFolders)
+		return availableFolders, folderMethodLLM
 	}
 
 	logger.InfoContext(ctx, "folder selection completed",
@@ -340,7 +491,54 @@ Your response (JSON array only):`, question, folderList)
 		"llm_ranked_folders", llmRankedFolders,
 	)
 
-	return orderedFolders
+	e.cacheFolders(cacheKey, orderedFolders)
+	return orderedFolders, folderMethodLLM
+}
+
+// fallbackFolders appends the folders ranked by fallbackFolderRanking to the user folders.
+// When nothing matches and the user chose no folders, the result is empty and every folder
+// is searched without positional weights.
+func (e *ragEngine) fallbackFolders(ctx context.Context, question string, orderedFolders, candidates []string, folderRetrievals map[string]int) []string {
+	ranked := fallbackFolderRanking(question, candidates, folderRetrievals)
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "folders ranked without the LLM",
+		"candidates", len(candidates),
+		"matched", len(ranked),
+	)
+	return append(orderedFolders, ranked...)
+}
+
+// folderRankingFormat returns the response format for folder ranking: a JSON array of
+// distinct folders drawn from folders, or nil (free-form output) when there are none.
+func folderRankingFormat(folders []string) *llm.ResponseFormat {
+	if len(folders) == 0 {
+		return nil
+	}
+	return llm.JSONSchemaFormat("folder_ranking", map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string", "enum": folders},
+		"uniqueItems": true,
+		"maxItems":    len(folders),
+	})
+}
+
+// parseFolderRanking decodes the folder ranking reply. Schema-constrained replies are a bare
+// JSON array; servers that ignore response_format may wrap it in prose or a markdown code
+// fence, so the outermost [...] is tried next.
+func parseFolderRanking(reply string) ([]string, error) {
+	var folders []string
+	reply = strings.TrimSpace(reply)
+	if err := json.Unmarshal([]byte(reply), &folders); err == nil {
+		return folders, nil
+	}
+
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("reply is not a JSON array")
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &folders); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal folders: %w", err)
+	}
+	return folders, nil
 }
 
 // chunkData represents a chunk with its metadata for context formatting and citation extraction.
@@ -350,7 +548,11 @@ type chunkData struct {
 	relPath     string
 	headingPath string
 	chunkIndex  int
-	result      vectorstore.SearchResult
+	// noteID is the chunk's note, for looking up its neighbors (empty when unknown)
+	noteID string
+	// overlapRunes is the length of the leading text repeated from the previous chunk
+	overlapRunes int
+	result       vectorstore.SearchResult
 }
 
 // normalizePath normalizes a file path for comparison by:
@@ -678,6 +880,7 @@ func (e *ragEngine) extractCitationsFromAnswer(ctx context.Context, answer strin
 
 		if matchedFile != "" && matchedSection != "" {
 			references = append(references, Reference{
+				ChunkID:     chunk.result.PointID,
 				Vault:       chunk.vaultName,
 				RelPath:     chunk.relPath,
 				HeadingPath: chunk.headingPath,
@@ -727,119 +930,195 @@ func (e *ragEngine) extractCitationsFromAnswer(ctx context.Context, answer strin
 
 // Ask answers a question using RAG.
 func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error) {
+	return e.respond(ctx, req, nil)
+}
+
+// AskStream answers a question using RAG, streaming the generated answer to onToken when
+// the chat backend supports streaming. Abstentions, extractive answers, and answers from a
+// backend that cannot stream reach onToken in one piece once they are complete.
+func (e *ragEngine) AskStream(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
+	streamed := false
+	resp, err := e.respond(ctx, req, func(token string) error {
+		streamed = true
+		return onToken(token)
+	})
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if !streamed && resp.Answer != "" {
+		if err := onToken(resp.Answer); err != nil {
+			return AskResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+// respond answers req for Ask and AskStream. Every answer, abstentions included, is stamped
+// with ResponseMeta, which is also logged with the query.
+func (e *ragEngine) respond(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
+	meta := e.generationMeta(answerPromptVersion)
+	meta.applyOverrides(req.Generation)
+	meta.RetrievalConfigHash = e.retrievalConfigHash()
+	if req.MinScore != (ScoreThresholds{}) {
+		applied := e.scoreThresholds(req)
+		meta.ScoreThresholds = &applied
+	}
+	if req.Retrieval != (RetrievalOverrides{}) && e.flags.Enabled(features.RetrievalOptions) {
+		applied := e.retrievalOptions(req)
+		meta.Retrieval = &applied
+	}
+
+	resp, err := e.ask(ctx, req, onToken)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	resp.Meta = &meta
+
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "RAG query answered",
+		"question", contextutil.RedactQuestion(e.questionLogMode, req.Question),
+		"abstained", resp.Abstained,
+		meta.logAttr(),
+	)
+	return resp, nil
+}
+
+// ask runs the ask pipeline stages (see pipeline.go) for Ask. A non-nil onToken receives
+// the answer as it is generated.
+func (e *ragEngine) ask(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
 	logger := contextutil.LoggerFromContext(ctx)
 
-	// Track total time for the entire RAG query
-	startTime := time.Now()
+	// Track total time for the entire RAG query; stage spans are its children
+	ctx, span := tracing.Start(ctx, "rag.ask", attribute.Int("question_length", len(req.Question)))
+	defer span.End()
+	s := &askState{req: req, onToken: onToken, span: span}
+	// A stage that fails mid-retrieval leaves the retrieval span open
+	defer s.endRetrieval()
 
 	logger.InfoContext(ctx, "RAG query started",
-		"question", req.Question,
+		"question", contextutil.RedactQuestion(e.questionLogMode, req.Question),
+		"question_length", len(req.Question),
 		"vaults", req.Vaults,
 		"folders", req.Folders,
 		"k", req.K,
+		"include_cold", req.IncludeCold,
 	)
 
-	// Embed the question
-	embeddings, err := e.embedder.EmbedTexts(ctx, []string{req.Question})
-	if err != nil {
-		logger.ErrorContext(ctx, "failed to embed question", "error", err)
-		return AskResponse{}, fmt.Errorf("failed to embed question: %w", err)
-	}
-	if len(embeddings) == 0 {
-		return AskResponse{}, fmt.Errorf("no embedding returned for question")
+	for _, name := range e.pipelineStages() {
+		stageCtx, stageSpan := tracing.Start(ctx, "rag.stage."+name)
+		if err := askStages[name].run(e, stageCtx, s); err != nil {
+			stageSpan.RecordError(err)
+			stageSpan.End()
+			span.RecordError(err)
+			return AskResponse{}, err
+		}
+		duration := stageSpan.End()
+		logger.DebugContext(ctx, "ask stage completed", "stage", name, "duration_ms", duration.Milliseconds())
+		if s.resp != nil {
+			return *s.resp, nil
+		}
 	}
-	queryVector := embeddings[0]
+	return e.finishAnswer(ctx, s), nil
+}
 
-	// Get all vaults to resolve names to IDs
-	allVaults, err := e.vaultRepo.ListAll(ctx)
-	if err != nil {
-		logger.ErrorContext(ctx, "failed to list vaults", "error", err)
-		return AskResponse{}, fmt.Errorf("failed to list vaults: %w", err)
-	}
+// generate calls the LLM for an answer within the generation timeout. With a non-nil onToken
+// and a streaming backend the answer is streamed to onToken as it is generated; otherwise it
+// is requested in one piece. A streamed answer that runs out of time after producing text is
+// returned as it stands with truncated set, since the client already received that text.
+func (e *ragEngine) generate(ctx context.Context, messages []llm.Message, params llm.ChatParams, onToken func(token string) error) (answer string, truncated bool, err error) {
+	generationCtx, cancel := withTimeout(ctx, e.timeouts.Generation)
+	defer cancel()
 
-	// Build map of vault name to ID
-	vaultMap := make(map[string]int)
-	for _, vault := range allVaults {
-		vaultMap[vault.Name] = vault.ID
+	streamer, ok := e.llmClient.(StreamingChatBackend)
+	if onToken == nil || !ok {
+		answer, err = e.llmClient.ChatWithMessages(generationCtx, messages, params)
+		return answer, false, err
 	}
 
-	// Resolve vault names to IDs - if no vaults specified, use all vaults
-	var vaultIDs []int
-	if len(req.Vaults) > 0 {
-		// Collect vault IDs for requested vaults
-		for _, vaultName := range req.Vaults {
-			if vaultID, ok := vaultMap[vaultName]; ok {
-				vaultIDs = append(vaultIDs, vaultID)
-			} else {
-				logger.WarnContext(ctx, "unknown vault name", "vault", vaultName)
-				// Continue with other vaults
-			}
-		}
-	} else {
-		// No vaults specified - search all vaults
-		for _, vault := range allVaults {
-			vaultIDs = append(vaultIDs, vault.ID)
+	var streamed strings.Builder
+	err = streamer.StreamChatWithMessages(generationCtx, messages, params, func(chunk string) error {
+		streamed.WriteString(chunk)
+		return onToken(chunk)
+	})
+	if err != nil {
+		// Errors reading a cut-off stream need not wrap the deadline, so check the context
+		if streamed.Len() > 0 && generationCtx.Err() != nil && ctx.Err() == nil {
+			return streamed.String(), true, nil
 		}
+		return "", false, err
 	}
+	return streamed.String(), false, nil
+}
 
-	autoK := determineAutoK(req.Question, req.Folders, req.Detail)
-	userHintK := clampUserProvidedK(req.K)
-	targetK := autoK
-	kSource := "auto"
-	if userHintK > 0 {
-		targetK = userHintK
-		kSource = "user_override"
-	}
-
-	logger.InfoContext(ctx, "k selection completed",
-		"auto_k", autoK,
-		"user_hint_k", userHintK,
-		"target_k", targetK,
-		"detail_hint", req.Detail,
-		"k_source", kSource,
-	)
+// retrievalPass holds the results of one vector search and rerank pass.
+type retrievalPass struct {
+	deduplicated []vectorstore.SearchResult
+	candidates   []rerankCandidate // Sorted by final score
+	filtered     []rerankCandidate // Candidates meeting minFinalScore
+	// minFinalScore is the final score threshold applied to the pass.
+	minFinalScore float32
+	// headingMatches is the number of leading candidates added by a heading match.
+	headingMatches int
+	// searchErr is the last search error when every search of the pass failed.
+	searchErr error
+}
 
-	// Get all unique folders for selected vaults
-	availableFolders, err := e.noteRepo.ListUniqueFolders(ctx, vaultIDs)
-	if err != nil {
-		logger.WarnContext(ctx, "failed to list unique folders, searching all folders", "error", err)
-		availableFolders = []string{} // Empty list means search all folders
+// topScore returns the best final score of the pass (0 when nothing was reranked).
+func (p retrievalPass) topScore() float32 {
+	if len(p.candidates) == 0 {
+		return 0
 	}
+	return p.candidates[0].finalScore
+}
 
-	// Build map of vault ID to name for folder conversion
-	vaultIDToNameMap := make(map[int]string)
-	for _, vault := range allVaults {
-		vaultIDToNameMap[vault.ID] = vault.Name
+// weakReason explains why a pass is too weak to answer from, or returns "" if it is not weak.
+// A pass is weak when it found nothing, nothing met the final threshold, or its best
+// candidate sits only barely above the threshold.
+func (p retrievalPass) weakReason() string {
+	switch {
+	case len(p.deduplicated) == 0 || len(p.candidates) == 0:
+		return "no_results"
+	case len(p.filtered) == 0:
+		return "below_threshold"
+	case p.topScore() < p.minFinalScore+weakRetrievalMargin:
+		return "marginal_scores"
+	default:
+		return ""
 	}
+}
 
-	// Track folder selection time
-	folderSelectionStart := time.Now()
-	// Select relevant folders using LLM
-	orderedFolders := e.selectRelevantFolders(ctx, req.Question, availableFolders, req.Folders, vaultIDs, vaultIDToNameMap)
-	folderSelectionMs := time.Since(folderSelectionStart).Milliseconds()
-
-	logger.InfoContext(ctx, "folder selection completed",
-		"available_folders", len(availableFolders),
-		"ordered_folders", len(orderedFolders),
-		"user_folders", len(req.Folders),
-	)
-	logger.DebugContext(ctx, "final ordered folder list",
-		"ordered_folders", orderedFolders,
-		"available_folders", availableFolders,
-	)
+// retrieve runs one full retrieval pass: searchCandidates, then rerank when the reranker
+// flag is on.
+func (e *ragEngine) retrieve(ctx context.Context, req AskRequest, queryVector []float32, vaultIDs []int, orderedFolders []string, noteIDs []string, kPerScope int) retrievalPass {
+	pass := e.searchCandidates(ctx, req, queryVector, vaultIDs, orderedFolders, noteIDs, kPerScope)
+	if e.flags.Enabled(features.Reranker) {
+		pass = e.rerank(ctx, req, pass)
+	}
+	return pass
+}
 
-	// Track retrieval time (vector search + reranking)
-	retrievalStart := time.Now()
+// searchCandidates searches each vault (or each ordered folder) with kPerScope results per
+// scope and deduplicates the results. Candidates are ranked by vector score until reranked.
+// A non-empty noteIDs restricts every scope to chunks of those notes.
+func (e *ragEngine) searchCandidates(ctx context.Context, req AskRequest, queryVector []float32, vaultIDs []int, orderedFolders []string, noteIDs []string, kPerScope int) retrievalPass {
+	logger := contextutil.LoggerFromContext(ctx)
+	thresholds := e.scoreThresholds(req)
 
 	// Search vector store - search each vault and folder separately
 	var allSearchResults []vectorstore.SearchResult
+	// Folder weight applied to each result's score, keyed by point ID (first folder wins, like deduplication)
+	folderWeights := make(map[string]float32)
 	logger.InfoContext(ctx, "searching vector store",
 		"vault_count", len(vaultIDs),
 		"vault_ids", vaultIDs,
 		"folder_count", len(orderedFolders),
-		"candidate_k_per_scope", candidateKPerScope,
+		"candidate_k_per_scope", kPerScope,
+		"note_filter_count", len(noteIDs),
 	)
 
+	// A pass whose every search failed reports the vector store as down rather than finding nothing
+	var searches, failures int
+	var searchErr error
+
 	// If no folders selected (neither user nor LLM selected any), search all folders (no folder filter)
 	if len(orderedFolders) == 0 {
 		logger.InfoContext(ctx, "no folders selected by user or LLM, searching all folders")
@@ -847,10 +1126,16 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			filters := make(map[string]any)
 			filters["vault_id"] = vaultID
 			// No folder filter means search all folders
+			if len(noteIDs) > 0 {
+				filters["note_id"] = noteIDs
+			}
 
-			logger.DebugContext(ctx, "searching vault (all folders)", "vault_id", vaultID, "k", candidateKPerScope)
-			results, err := e.vectorStore.Search(ctx, e.collection, queryVector, candidateKPerScope, filters)
+			logger.DebugContext(ctx, "searching vault (all folders)", "vault_id", vaultID, "k", kPerScope)
+			searches++
+			results, err := e.search(ctx, queryVector, kPerScope, filters, req.IncludeCold)
 			if err != nil {
+				failures++
+				searchErr = err
 				logger.ErrorContext(ctx, "failed to search vector store", "vault_id", vaultID, "error", err)
 				// Continue with other vaults
 				continue
@@ -895,6 +1180,9 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			filters := make(map[string]any)
 			filters["vault_id"] = vaultID
 			filters["folder"] = folder
+			if len(noteIDs) > 0 {
+				filters["note_id"] = noteIDs
+			}
 
 			// Calculate weight for this folder (earlier folders get higher weight)
 			folderWeight := maxFolderWeight - (float32(folderIdx) * folderWeightStep)
@@ -902,9 +1190,12 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 				folderWeight = 0.1 // Minimum weight
 			}
 
-			logger.DebugContext(ctx, "searching folder", "vault_id", vaultID, "folder", folder, "folder_index", folderIdx, "weight", folderWeight, "k", candidateKPerScope)
-			results, err := e.vectorStore.Search(ctx, e.collection, queryVector, candidateKPerScope, filters)
+			logger.DebugContext(ctx, "searching folder", "vault_id", vaultID, "folder", folder, "folder_index", folderIdx, "weight", folderWeight, "k", kPerScope)
+			searches++
+			results, err := e.search(ctx, queryVector, kPerScope, filters, req.IncludeCold)
 			if err != nil {
+				failures++
+				searchErr = err
 				logger.ErrorContext(ctx, "failed to search vector store", "vault_id", vaultID, "folder", folder, "error", err)
 				// Continue with other folders
 				continue
@@ -913,12 +1204,19 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			// Apply weight to scores based on folder position
 			for i := range results {
 				results[i].Score = results[i].Score * folderWeight
+				if _, ok := folderWeights[results[i].PointID]; !ok {
+					folderWeights[results[i].PointID] = folderWeight
+				}
 			}
 
 			allSearchResults = append(allSearchResults, results...)
 		}
 	}
 
+	if searches > 0 && failures == searches {
+		return retrievalPass{minFinalScore: thresholds.Final, searchErr: searchErr}
+	}
+
 	// Deduplicate by PointID and sort by score (highest first)
 	seen := make(map[string]bool)
 	deduplicated := make([]vectorstore.SearchResult, 0, len(allSearchResults))
@@ -938,43 +1236,19 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 		"deduplicated_count", len(deduplicated),
 	)
 
-	if len(deduplicated) == 0 {
-		logger.InfoContext(ctx, "no search results found")
-		resp := AskResponse{
-			Answer:        "I couldn't find any relevant information in your notes to answer this question.",
-			References:    []Reference{},
-			Abstained:     true,
-			AbstainReason: "no_relevant_context",
-		}
-		// Build debug info even when no results, if requested
-		if req.Debug {
-			maxDebugChunks := targetK * 2
-			if maxDebugChunks > 50 {
-				maxDebugChunks = 50
-			}
-			// Retrieval completed but found no results, so calculate retrieval time
-			retrievalMs := time.Since(retrievalStart).Milliseconds()
-			generationMs := int64(0)
-			totalMs := time.Since(startTime).Milliseconds()
-			debugInfo := e.buildDebugInfo(ctx, deduplicated, []rerankCandidate{}, []rerankCandidate{}, orderedFolders, availableFolders, vaultIDToNameMap, maxDebugChunks, folderSelectionMs, retrievalMs, generationMs, totalMs)
-			resp.Debug = debugInfo
-		}
-		return resp, nil
-	}
-
-	if len(deduplicated) > maxCandidates {
+	if limit := e.retrievalOptions(req).MaxCandidates; len(deduplicated) > limit {
 		logger.InfoContext(ctx, "trimming candidates to global cap",
 			"before_trim", len(deduplicated),
-			"cap", maxCandidates,
+			"cap", limit,
 		)
-		deduplicated = deduplicated[:maxCandidates]
+		deduplicated = deduplicated[:limit]
 	}
 
 	// Fetch chunk texts and compute lexical scores for reranking
 	candidates := make([]rerankCandidate, 0, len(deduplicated))
 	for idx, result := range deduplicated {
 		vectorScore := result.Score
-		if vectorScore < minVectorScoreThreshold {
+		if vectorScore < thresholds.Vector {
 			logger.DebugContext(ctx, "skipping candidate below vector threshold",
 				"point_id", result.PointID,
 				"vector_score", vectorScore,
@@ -983,6 +1257,12 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 		}
 
 		chunk, err := e.chunkRepo.GetByID(ctx, result.PointID)
+		if err != nil || chunk.Text == "" {
+			// Re-read the source note so the chunk still contributes context
+			if hydrated := e.hydrateChunk(ctx, result); hydrated != nil {
+				chunk, err = hydrated, nil
+			}
+		}
 		vaultName, _ := result.Meta["vault_name"].(string)
 		relPath, _ := result.Meta["rel_path"].(string)
 		headingPathMeta, _ := result.Meta["heading_path"].(string)
@@ -1028,8 +1308,10 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			}
 		}
 
-		lexScore := lexicalScore(req.Question, chunkText, headingPath)
-		finalScore := combineScores(vectorScore, lexScore)
+		folderWeight, ok := folderWeights[result.PointID]
+		if !ok {
+			folderWeight = 1.0
+		}
 		candidates = append(candidates, rerankCandidate{
 			result:       result,
 			chunk:        chunk,
@@ -1038,36 +1320,46 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			headingPath:  headingPath,
 			chunkIndex:   chunkIndex,
 			vectorScore:  vectorScore,
-			lexicalScore: lexScore,
-			finalScore:   finalScore,
+			finalScore:   vectorScore,
 			originalRank: idx + 1,
+			folderWeight: folderWeight,
 		})
 	}
 
-	if len(candidates) == 0 {
-		logger.InfoContext(ctx, "no candidates passed vector threshold after rerank preparation")
-		resp := AskResponse{
-			Answer:        "I couldn't find any relevant information in your notes to answer this question.",
-			References:    []Reference{},
-			Abstained:     true,
-			AbstainReason: "no_relevant_context",
+	return rankCandidates(ctx, deduplicated, candidates, thresholds.Final)
+}
+
+// rerank combines each candidate's vector score with its lexical score, or with the external
+// reranker's score when one is configured, adds the tag boost to chunks whose note tags match
+// the question, and ranks the pass again.
+func (e *ragEngine) rerank(ctx context.Context, req AskRequest, pass retrievalPass) retrievalPass {
+	opts := e.retrievalOptions(req)
+	external := e.externalRerankScores(ctx, req.Question, pass.candidates)
+	candidates := make([]rerankCandidate, 0, len(pass.candidates))
+	for i, candidate := range pass.candidates {
+		candidate.lexical = explainLexicalScore(req.Question, candidate.chunk.Text, candidate.headingPath)
+		candidate.lexicalScore = candidate.lexical.score
+		relevance := candidate.lexicalScore
+		if external != nil {
+			candidate.rerankerScore = &external[i]
+			relevance = external[i]
 		}
-		// Build debug info even when no candidates, if requested
-		// This shows what was retrieved from vector store even if chunks couldn't be fetched from DB
-		if req.Debug {
-			maxDebugChunks := targetK * 2
-			if maxDebugChunks > 50 {
-				maxDebugChunks = 50
+		candidate.finalScore = opts.combineScores(candidate.vectorScore, relevance)
+		candidate.tagMatches, candidate.tagBoost = nil, 0
+		if opts.TagBoost > 0 {
+			if candidate.tagMatches = matchTags(req.Question, pointTags(candidate.result.Meta)); len(candidate.tagMatches) > 0 {
+				candidate.tagBoost = opts.TagBoost
+				candidate.finalScore += candidate.tagBoost
 			}
-			// Retrieval completed but no generation happened
-			retrievalMs := time.Since(retrievalStart).Milliseconds()
-			generationMs := int64(0)
-			totalMs := time.Since(startTime).Milliseconds()
-			debugInfo := e.buildDebugInfo(ctx, deduplicated, candidates, []rerankCandidate{}, orderedFolders, availableFolders, vaultIDToNameMap, maxDebugChunks, folderSelectionMs, retrievalMs, generationMs, totalMs)
-			resp.Debug = debugInfo
 		}
-		return resp, nil
+		candidates = append(candidates, candidate)
 	}
+	return rankCandidates(ctx, pass.deduplicated, candidates, pass.minFinalScore)
+}
+
+// rankCandidates sorts candidates by final score and keeps those meeting minFinalScore.
+func rankCandidates(ctx context.Context, deduplicated []vectorstore.SearchResult, candidates []rerankCandidate, minFinalScore float32) retrievalPass {
+	logger := contextutil.LoggerFromContext(ctx)
 
 	sort.Slice(candidates, func(i, j int) bool {
 		if candidates[i].finalScore == candidates[j].finalScore {
@@ -1076,9 +1368,13 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 		return candidates[i].finalScore > candidates[j].finalScore
 	})
 
+	// Exact duplicates (templates, boilerplate repeated across notes) share a text hash;
+	// only the best-scoring copy is kept so it doesn't crowd out other context.
 	filteredCandidates := make([]rerankCandidate, 0, len(candidates))
+	seenTexts := make(map[string]struct{}, len(candidates))
+	var duplicatesSuppressed int
 	for _, candidate := range candidates {
-		if candidate.finalScore < minFinalScoreThreshold {
+		if candidate.finalScore < minFinalScore {
 			logger.DebugContext(ctx, "candidate dropped by final score",
 				"point_id", candidate.result.PointID,
 				"final_score", candidate.finalScore,
@@ -1087,242 +1383,32 @@ func (e *ragEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error
 			)
 			continue
 		}
+		if hash := candidate.chunk.TextHash; hash != "" {
+			if _, seen := seenTexts[hash]; seen {
+				duplicatesSuppressed++
+				logger.DebugContext(ctx, "candidate dropped as exact duplicate",
+					"point_id", candidate.result.PointID,
+					"rel_path", candidate.relPath,
+				)
+				continue
+			}
+			seenTexts[hash] = struct{}{}
+		}
 		filteredCandidates = append(filteredCandidates, candidate)
 	}
 
-	logger.InfoContext(ctx, "rerank completed",
+	logger.InfoContext(ctx, "candidates ranked",
 		"candidates_considered", len(candidates),
 		"candidates_after_threshold", len(filteredCandidates),
-		"target_k", targetK,
-	)
-
-	if len(filteredCandidates) == 0 {
-		logger.InfoContext(ctx, "no candidates met final score threshold")
-		resp := AskResponse{
-			Answer:        "I couldn't find any relevant information in your notes to answer this question.",
-			References:    []Reference{},
-			Abstained:     true,
-			AbstainReason: "no_relevant_context",
-		}
-		// Build debug info even when no candidates passed threshold, if requested
-		// This shows what was retrieved and scored even if it didn't meet the threshold
-		if req.Debug {
-			maxDebugChunks := targetK * 2
-			if maxDebugChunks > 50 {
-				maxDebugChunks = 50
-			}
-			// Retrieval completed but no generation happened
-			retrievalMs := time.Since(retrievalStart).Milliseconds()
-			generationMs := int64(0)
-			totalMs := time.Since(startTime).Milliseconds()
-			debugInfo := e.buildDebugInfo(ctx, deduplicated, candidates, []rerankCandidate{}, orderedFolders, availableFolders, vaultIDToNameMap, maxDebugChunks, folderSelectionMs, retrievalMs, generationMs, totalMs)
-			resp.Debug = debugInfo
-		}
-		return resp, nil
-	}
-
-	// Determine final chunk count respecting rerank cap
-	finalCount := targetK
-	if finalCount > rerankKeep {
-		finalCount = rerankKeep
-	}
-	if finalCount > len(filteredCandidates) {
-		finalCount = len(filteredCandidates)
-	}
-	if finalCount <= 0 {
-		finalCount = len(filteredCandidates)
-	}
-
-	selectedCandidates := filteredCandidates[:finalCount]
-
-	// Log top candidate scores to aid tuning
-	logPreview := make([]map[string]any, 0, len(selectedCandidates))
-	for i := 0; i < len(selectedCandidates) && i < 5; i++ {
-		candidate := selectedCandidates[i]
-		logPreview = append(logPreview, map[string]any{
-			"rank":          i + 1,
-			"point_id":      candidate.result.PointID,
-			"vector_score":  candidate.vectorScore,
-			"lexical_score": candidate.lexicalScore,
-			"final_score":   candidate.finalScore,
-		})
-	}
-	logger.DebugContext(ctx, "top reranked candidates", "preview", logPreview)
-
-	chunks := make([]chunkData, 0, len(selectedCandidates))
-	for rank, candidate := range selectedCandidates {
-		chunks = append(chunks, chunkData{
-			text:        candidate.chunk.Text,
-			vaultName:   candidate.vaultName,
-			relPath:     candidate.relPath,
-			headingPath: candidate.headingPath,
-			chunkIndex:  candidate.chunkIndex,
-			result:      candidate.result,
-		})
-
-		textPreview := candidate.chunk.Text
-		if len(textPreview) > 100 {
-			textPreview = textPreview[:100] + "..."
-		}
-		logger.DebugContext(ctx, "selected chunk",
-			"rank", rank+1,
-			"final_score", candidate.finalScore,
-			"vector_score", candidate.vectorScore,
-			"lexical_score", candidate.lexicalScore,
-			"vault", candidate.vaultName,
-			"rel_path", candidate.relPath,
-			"heading_path", candidate.headingPath,
-			"chunk_index", candidate.chunkIndex,
-			"text_preview", textPreview,
-			"text_length", len(candidate.chunk.Text),
-		)
-	}
-
-	logger.InfoContext(ctx, "chunks selected after rerank",
-		"total_selected", len(chunks),
-		"requested_k", targetK,
-		"rerank_cap", rerankKeep,
+		"duplicates_suppressed", duplicatesSuppressed,
 	)
 
-	// Format context string
-	var contextBuilder strings.Builder
-	contextBuilder.WriteString("--- Context from notes ---\n\n")
-
-	for i, chunk := range chunks {
-		contextBuilder.WriteString(fmt.Sprintf("[Chunk %d]\n", i+1))
-		contextBuilder.WriteString(fmt.Sprintf("[Vault: %s] File: %s\n", chunk.vaultName, chunk.relPath))
-		contextBuilder.WriteString(fmt.Sprintf("Section: %s\n", chunk.headingPath))
-		contextBuilder.WriteString(fmt.Sprintf("Content: %s\n\n", chunk.text))
+	return retrievalPass{
+		deduplicated:  deduplicated,
+		candidates:    candidates,
+		filtered:      filteredCandidates,
+		minFinalScore: minFinalScore,
 	}
-
-	contextBuilder.WriteString("--- End Context ---\n")
-	contextBuilder.WriteString("\nWhen citing sources, use the format '[File: filename.md, Section: section name]' matching the exact filename and section name from the context above.")
-
-	contextString := contextBuilder.String()
-	logger.InfoContext(ctx, "context formatted for LLM",
-		"context_length", len(contextString),
-		"chunks_included", len(chunks),
-	)
-	logger.DebugContext(ctx, "full context being sent to LLM", "context", contextString)
-
-	// Retrieval phase complete (vector search + reranking)
-	retrievalMs := time.Since(retrievalStart).Milliseconds()
-
-	// Track generation time (LLM call)
-	generationStart := time.Now()
-
-	// Construct LLM messages
-	systemPrompt := "You are a helpful assistant that answers questions based on the provided context from the user's notes. " +
-		"Your primary goal is to provide accurate, complete answers to the question. " +
-		"Answer the question using only the information from the context below. " +
-		"CRITICAL: You MUST cite all major claims and factual statements using the exact format '[File: filename.md, Section: section name]' where the filename and section name match the context provided. " +
-		"Do NOT make any unsupported claims - if information is not in the context, explicitly state that it is not available. " +
-		"If the context doesn't contain enough information to answer the question, say so clearly. " +
-		"REQUIRED: At the END of your answer, you MUST include a 'Citations:' section listing all sources used. " +
-		"Example format:\n" +
-		"Citations:\n" +
-		"[File: Software/LeetCode Tips.md, Section: Golang Tips & Oddities]\n" +
-		"[File: Software/Data Structures & Algorithms/Hash Tables.md, Section: Designing a HashMap]\n" +
-		"Remember: Answer quality comes first, but citations are required for all major claims."
-
-	userMessage := fmt.Sprintf("%s\n\n%s", req.Question, contextString)
-
-	messages := []llm.Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userMessage},
-	}
-
-	logger.InfoContext(ctx, "sending request to LLM",
-		"question", req.Question,
-		"system_prompt_length", len(systemPrompt),
-		"user_message_length", len(userMessage),
-		"total_context_length", len(contextString),
-	)
-	userMessagePreview := userMessage
-	if len(userMessagePreview) > 500 {
-		userMessagePreview = userMessagePreview[:500] + "..."
-	}
-	logger.DebugContext(ctx, "LLM messages", "system_prompt", systemPrompt, "user_message_preview", userMessagePreview)
-
-	// Call LLM
-	answer, err := e.llmClient.ChatWithMessages(ctx, messages, llm.ChatParams{
-		Model:       "",  // Use default from client
-		MaxTokens:   0,   // No limit
-		Temperature: 0.3, // Lower temperature for more focused, citation-aware responses with less hallucination
-	})
-	if err != nil {
-		logger.ErrorContext(ctx, "failed to get LLM response", "error", err)
-		return AskResponse{}, fmt.Errorf("failed to get LLM response: %w", err)
-	}
-
-	logger.InfoContext(ctx, "received LLM response", "answer_length", len(answer))
-	logger.DebugContext(ctx, "LLM answer", "answer", answer)
-
-	// Generation phase complete
-	generationMs := time.Since(generationStart).Milliseconds()
-
-	// Extract citations from answer and build references from only cited chunks
-	references := e.extractCitationsFromAnswer(ctx, answer, chunks)
-	if len(references) == 0 {
-		// Check if answer contains any citation-like patterns (even if not in expected format)
-		hasCitationPatterns := false
-		citationPatterns := []string{"[File:", "[file:", "File:", "file:", "Section:", "section:"}
-		answerLower := strings.ToLower(answer)
-		for _, pattern := range citationPatterns {
-			if strings.Contains(answerLower, strings.ToLower(pattern)) {
-				hasCitationPatterns = true
-				break
-			}
-		}
-
-		if hasCitationPatterns {
-			// Answer contains citation-like patterns but extraction failed
-			logger.WarnContext(ctx, "citation patterns detected but extraction failed, falling back to all chunks",
-				"answer_length", len(answer),
-				"chunks_available", len(chunks),
-				"answer_preview", truncateString(answer, 200))
-		} else {
-			// No citation patterns at all
-			logger.InfoContext(ctx, "no citations found in answer, falling back to all chunks",
-				"answer_length", len(answer),
-				"chunks_available", len(chunks))
-		}
-
-		// Fallback: include all chunks (backward compatibility)
-		references = make([]Reference, 0, len(chunks))
-		for _, chunk := range chunks {
-			references = append(references, Reference{
-				Vault:       chunk.vaultName,
-				RelPath:     chunk.relPath,
-				HeadingPath: chunk.headingPath,
-				ChunkIndex:  chunk.chunkIndex,
-			})
-		}
-	} else {
-		logger.InfoContext(ctx, "extracted citations from answer",
-			"citations_found", len(references),
-			"total_chunks", len(chunks))
-	}
-
-	logger.InfoContext(ctx, "RAG query completed", "question_length", len(req.Question), "chunks_used", len(chunks), "answer_length", len(answer))
-
-	resp := AskResponse{
-		Answer:     answer,
-		References: references,
-	}
-
-	// Collect debug information if requested
-	if req.Debug {
-		maxDebugChunks := targetK * 2
-		if maxDebugChunks > 50 {
-			maxDebugChunks = 50
-		}
-		totalMs := time.Since(startTime).Milliseconds()
-		debugInfo := e.buildDebugInfo(ctx, deduplicated, candidates, selectedCandidates, orderedFolders, availableFolders, vaultIDToNameMap, maxDebugChunks, folderSelectionMs, retrievalMs, generationMs, totalMs)
-		resp.Debug = debugInfo
-	}
-
-	return resp, nil
 }
 
 // buildDebugInfo constructs debug information from retrieval results.
@@ -1383,6 +1469,8 @@ func (e *ragEngine) buildDebugInfo(
 				ScoreFinal:   float64(candidate.finalScore),
 				Text:         chunkText,
 				Rank:         rank + 1,
+				Explanation:  candidate.explanation(),
+				Provenance:   chunkProvenance(candidate.result.Meta, candidate.chunk),
 			})
 		}
 		if len(candidates) > maxDebugChunks {
@@ -1405,8 +1493,10 @@ func (e *ragEngine) buildDebugInfo(
 
 			// Try to fetch chunk text from database
 			chunkText := ""
+			var chunkRecord *storage.ChunkRecord
 			if chunk, err := e.chunkRepo.GetByID(ctx, result.PointID); err == nil {
 				chunkText = chunk.Text
+				chunkRecord = chunk
 			} else {
 				logger.DebugContext(ctx, "failed to fetch chunk text from DB",
 					"chunk_id", result.PointID,
@@ -1422,6 +1512,7 @@ func (e *ragEngine) buildDebugInfo(
 				ScoreFinal:   float64(result.Score), // Use vector score as final when no lexical score
 				Text:         chunkText,
 				Rank:         rank + 1,
+				Provenance:   chunkProvenance(result.Meta, chunkRecord),
 			})
 		}
 		logger.DebugContext(ctx, "debug info built from deduplicated results (chunks not fetched from DB)",
@@ -1468,6 +1559,8 @@ func (e *ragEngine) buildDebugInfo(
 		}
 	}
 
+	e.addClickThrough(ctx, retrievedChunks)
+
 	logger.DebugContext(ctx, "building debug info",
 		"retrieved_chunks_count", len(retrievedChunks),
 		"selected_folders_count", len(displayOrderedFolders),
@@ -1483,14 +1576,60 @@ func (e *ragEngine) buildDebugInfo(
 			FolderSelectionMs: folderSelectionMs,
 			RetrievalMs:       retrievalMs,
 			GenerationMs:      generationMs,
-			JudgeMs:           0, // Judging happens in Python, not Go
+			JudgeMs:           0, // Set by askDebugInfo when the answer was judged
 			TotalMs:           totalMs,
 		},
+		Features: e.flags.Snapshot(),
 	}
 }
 
-func combineScores(vectorScore, lexicalScore float32) float32 {
-	return (vectorScore * vectorScoreWeight) + (lexicalScore * lexicalScoreWeight)
+// chunkProvenance reads a chunk's provenance from its vector payload, falling back to the
+// SQLite row (chunk may be nil). Returns nil when neither records any.
+func chunkProvenance(meta map[string]any, chunk *storage.ChunkRecord) *ChunkProvenance {
+	var provenance ChunkProvenance
+	provenance.ChunkerVersion, _ = meta["chunker_version"].(string)
+	provenance.EmbeddingModel, _ = meta["embedding_model"].(string)
+	provenance.EmbeddedAt, _ = meta["embedded_at"].(string)
+	provenance.RunID, _ = meta["run_id"].(string)
+	if provenance.RunID == "" && chunk != nil {
+		provenance.ChunkerVersion = chunk.ChunkerVersion
+		provenance.EmbeddingModel = chunk.EmbeddingModel
+		provenance.RunID = chunk.RunID
+		if !chunk.EmbeddedAt.IsZero() {
+			provenance.EmbeddedAt = chunk.EmbeddedAt.UTC().Format(time.RFC3339)
+		}
+	}
+	if provenance == (ChunkProvenance{}) {
+		return nil
+	}
+	return &provenance
+}
+
+// explanation returns the score breakdown of a candidate for debug output.
+func (c rerankCandidate) explanation() *ScoreExplanation {
+	matchedTerms := c.lexical.matchedTerms
+	if matchedTerms == nil {
+		matchedTerms = []string{}
+	}
+	var rerankerScore *float64
+	if c.rerankerScore != nil {
+		score := float64(*c.rerankerScore)
+		rerankerScore = &score
+	}
+	return &ScoreExplanation{
+		MatchedTerms:      matchedTerms,
+		TermFrequencies:   c.lexical.termFrequencies,
+		ChunkTokenCount:   c.lexical.chunkTokenCount,
+		TermScore:         float64(c.lexical.termScore),
+		HeadingMatches:    c.lexical.headingMatches,
+		HeadingBonus:      float64(c.lexical.headingBonus),
+		LexicalCapped:     c.lexical.capped,
+		FolderWeight:      float64(c.folderWeight),
+		HeadingExactMatch: c.headingMatch,
+		RerankerScore:     rerankerScore,
+		TagMatches:        c.tagMatches,
+		TagBoost:          float64(c.tagBoost),
+	}
 }
 
 var broadQueryKeywords = []string{
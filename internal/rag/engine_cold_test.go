@@ -0,0 +1,112 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestSearchIncludeCold(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	engine := &ragEngine{
+		vectorStore:    mockVectorStore,
+		collection:     "notes",
+		coldCollection: "notes_cold",
+	}
+
+	query := []float32{0.1, 0.2}
+	filters := map[string]any{"vault_id": 1}
+	hot := []vectorstore.SearchResult{{PointID: "hot-1", Score: 0.9}}
+	cold := []vectorstore.SearchResult{{PointID: "cold-1", Score: 0.8}}
+
+	// Default queries only touch the hot collection
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", query, 5, filters).Return(hot, nil)
+	results, err := engine.search(context.Background(), query, 5, filters, false)
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].PointID != "hot-1" {
+		t.Errorf("search() without include_cold = %v, want only hot results", results)
+	}
+
+	// Opt-in queries combine hot and cold results
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", query, 5, filters).Return(hot, nil)
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes_cold", query, 5, filters).Return(cold, nil)
+	results, err = engine.search(context.Background(), query, 5, filters, true)
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("search() with include_cold returned %d results, want 2", len(results))
+	}
+}
+
+func TestSearchIncludeCold_NoColdCollection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	engine := &ragEngine{vectorStore: mockVectorStore, collection: "notes"}
+
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, gomock.Any()).Return(nil, nil)
+	if _, err := engine.search(context.Background(), []float32{0.1}, 5, nil, true); err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+}
+
+func TestSearchCollectionPerVault(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	engine := &ragEngine{
+		vectorStore:        mockVectorStore,
+		collection:         "notes",
+		coldCollection:     "notes_cold",
+		collectionPerVault: true,
+	}
+
+	// Hot chunks come from the vault's own collection; the cold collection stays shared
+	filters := map[string]any{"vault_id": 2, "folder": "projects"}
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes_vault_2", gomock.Any(), 5, filters).Return(nil, nil)
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes_cold", gomock.Any(), 5, filters).Return(nil, nil)
+	if _, err := engine.search(context.Background(), []float32{0.1}, 5, filters, true); err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+
+	// Searches without a vault filter fall back to the shared collection
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, gomock.Any()).Return(nil, nil)
+	if _, err := engine.search(context.Background(), []float32{0.1}, 5, nil, false); err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+}
+
+func TestMarkRetrieved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockNoteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	engine := &ragEngine{noteRepo: mockNoteRepo}
+
+	candidates := []rerankCandidate{
+		{result: vectorstore.SearchResult{Meta: map[string]any{"note_id": "note-1"}}},
+		{result: vectorstore.SearchResult{Meta: map[string]any{"note_id": "note-1"}}},
+		{result: vectorstore.SearchResult{Meta: map[string]any{}}, chunk: &storage.ChunkRecord{NoteID: "note-2"}},
+	}
+
+	mockNoteRepo.EXPECT().MarkRetrieved(gomock.Any(), []string{"note-1", "note-2"}).Return(nil)
+	engine.markRetrieved(context.Background(), candidates)
+
+	// Read-only replicas leave retrieval times to the primary
+	engine.readOnly = true
+	engine.markRetrieved(context.Background(), candidates)
+}
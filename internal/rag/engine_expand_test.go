@@ -0,0 +1,137 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestRetrievalPassWeakReason(t *testing.T) {
+	result := []vectorstore.SearchResult{{PointID: "c1"}}
+	strong := rerankCandidate{finalScore: 0.7}
+	marginal := rerankCandidate{finalScore: minFinalScoreThreshold + weakRetrievalMargin/2}
+	below := rerankCandidate{finalScore: 0.2}
+
+	tests := []struct {
+		name string
+		pass retrievalPass
+		want string
+	}{
+		{name: "nothing found", pass: retrievalPass{}, want: "no_results"},
+		{name: "nothing reranked", pass: retrievalPass{deduplicated: result}, want: "no_results"},
+		{
+			name: "nothing above threshold",
+			pass: retrievalPass{deduplicated: result, candidates: []rerankCandidate{below}, minFinalScore: minFinalScoreThreshold},
+			want: "below_threshold",
+		},
+		{
+			name: "barely above threshold",
+			pass: retrievalPass{deduplicated: result, candidates: []rerankCandidate{marginal}, filtered: []rerankCandidate{marginal}, minFinalScore: minFinalScoreThreshold},
+			want: "marginal_scores",
+		},
+		{
+			name: "strong",
+			pass: retrievalPass{deduplicated: result, candidates: []rerankCandidate{strong, marginal}, filtered: []rerankCandidate{strong, marginal}, minFinalScore: minFinalScoreThreshold},
+			want: "",
+		},
+		{
+			name: "strong against a lowered threshold",
+			pass: retrievalPass{deduplicated: result, candidates: []rerankCandidate{marginal}, filtered: []rerankCandidate{marginal}, minFinalScore: 0.25},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pass.weakReason(); got != tt.want {
+				t.Errorf("weakReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieve_ScopesAndK(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	engine := &ragEngine{
+		vectorStore: mockVectorStore,
+		chunkRepo:   mockChunkRepo,
+		collection:  "notes",
+	}
+
+	req := AskRequest{Question: "tomatoes garden"}
+	query := []float32{0.1, 0.2}
+	hit := []vectorstore.SearchResult{{PointID: "c1", Score: 0.8, Meta: map[string]any{"rel_path": "garden.md"}}}
+	mockChunkRepo.EXPECT().GetByID(gomock.Any(), "c1").Return(&storage.ChunkRecord{
+		ID:   "c1",
+		Text: "The tomatoes in the garden",
+	}, nil).Times(2)
+
+	// Folder-scoped first pass searches each ordered folder
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", query, candidateKPerScope,
+		map[string]any{"vault_id": 1, "folder": "projects"}).Return(hit, nil)
+	pass := engine.retrieve(context.Background(), req, query, []int{1}, []string{"1/projects"}, nil, candidateKPerScope)
+	if len(pass.filtered) != 1 {
+		t.Fatalf("retrieve() filtered = %d candidates, want 1", len(pass.filtered))
+	}
+
+	// Relaxed second pass searches the whole vault with the expanded K
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", query, expandedCandidateKPerScope,
+		map[string]any{"vault_id": 1}).Return(hit, nil)
+	expanded := engine.retrieve(context.Background(), req, query, []int{1}, nil, nil, expandedCandidateKPerScope)
+	if expanded.topScore() <= 0 {
+		t.Errorf("retrieve() topScore = %f, want > 0", expanded.topScore())
+	}
+	if got := expanded.candidates[0].folderWeight; got != 1.0 {
+		t.Errorf("relaxed pass folderWeight = %f, want 1.0", got)
+	}
+}
+
+func TestRetrieve_SuppressesDuplicateTexts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	engine := &ragEngine{
+		vectorStore: mockVectorStore,
+		chunkRepo:   mockChunkRepo,
+		collection:  "notes",
+	}
+
+	boilerplate := "Weekly review: tomatoes, garden, watering"
+	hits := []vectorstore.SearchResult{
+		{PointID: "c1", Score: 0.9, Meta: map[string]any{"rel_path": "week1.md"}},
+		{PointID: "c2", Score: 0.85, Meta: map[string]any{"rel_path": "week2.md"}},
+		{PointID: "c3", Score: 0.8, Meta: map[string]any{"rel_path": "garden.md"}},
+	}
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), candidateKPerScope, gomock.Any()).Return(hits, nil)
+	for _, chunk := range []*storage.ChunkRecord{
+		{ID: "c1", Text: boilerplate, TextHash: storage.TextHash(boilerplate)},
+		{ID: "c2", Text: boilerplate, TextHash: storage.TextHash(boilerplate)},
+		{ID: "c3", Text: "The tomatoes in the garden", TextHash: storage.TextHash("The tomatoes in the garden")},
+	} {
+		mockChunkRepo.EXPECT().GetByID(gomock.Any(), chunk.ID).Return(chunk, nil)
+	}
+
+	pass := engine.retrieve(context.Background(), AskRequest{Question: "tomatoes garden"}, []float32{0.1}, []int{1}, nil, nil, candidateKPerScope)
+	if len(pass.candidates) != 3 {
+		t.Fatalf("retrieve() candidates = %d, want 3", len(pass.candidates))
+	}
+	var ids []string
+	for _, c := range pass.filtered {
+		ids = append(ids, c.result.PointID)
+	}
+	if len(ids) != 2 || ids[0] != "c1" || ids[1] != "c3" {
+		t.Errorf("retrieve() filtered = %v, want [c1 c3]", ids)
+	}
+}
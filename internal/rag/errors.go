@@ -0,0 +1,16 @@
+package rag
+
+import "errors"
+
+// Errors returned by Ask and AskDocument, wrapped around the underlying client error, so
+// callers can tell a failing dependency from a bad request with errors.Is.
+var (
+	// ErrEmbeddingUnavailable is returned when the question cannot be embedded.
+	ErrEmbeddingUnavailable = errors.New("embedding service unavailable")
+	// ErrVectorStoreUnavailable is returned when every vector search of a retrieval pass fails.
+	ErrVectorStoreUnavailable = errors.New("vector store unavailable")
+	// ErrLLMFailure is returned when the chat model fails to produce an answer.
+	ErrLLMFailure = errors.New("LLM request failed")
+	// ErrNoContext is returned when there is nothing to answer from, such as an empty document.
+	ErrNoContext = errors.New("no context to answer from")
+)
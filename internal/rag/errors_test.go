@@ -0,0 +1,50 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestSearchCandidates_VectorStoreDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	engine := &ragEngine{vectorStore: mockVectorStore, collection: "notes"}
+	searchErr := errors.New("connection refused")
+
+	// Every scope failing reports the vector store as down
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, gomock.Any()).Return(nil, searchErr).Times(2)
+	pass := engine.searchCandidates(context.Background(), AskRequest{}, []float32{0.1}, []int{1, 2}, nil, nil, 5)
+	if !errors.Is(pass.searchErr, searchErr) {
+		t.Errorf("searchCandidates() searchErr = %v, want %v", pass.searchErr, searchErr)
+	}
+
+	// One scope answering is a retrieval that found nothing, not an outage
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, gomock.Any()).Return(nil, searchErr)
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, gomock.Any()).Return(nil, nil)
+	pass = engine.searchCandidates(context.Background(), AskRequest{}, []float32{0.1}, []int{1, 2}, nil, nil, 5)
+	if pass.searchErr != nil {
+		t.Errorf("searchCandidates() searchErr = %v, want nil when a search succeeded", pass.searchErr)
+	}
+}
+
+func TestRetrieveStage_VectorStoreDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	engine := &ragEngine{vectorStore: mockVectorStore, collection: "notes"}
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+
+	s := &askState{queryVector: []float32{0.1}, vaultIDs: []int{1}}
+	err := engine.retrieveStage(context.Background(), s)
+	if !errors.Is(err, ErrVectorStoreUnavailable) {
+		t.Errorf("retrieveStage() error = %v, want ErrVectorStoreUnavailable", err)
+	}
+}
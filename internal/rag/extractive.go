@@ -0,0 +1,46 @@
+package rag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EngineExtractive answers without LLM generation: the top reranked chunks are returned
+// verbatim under their headings. Folder ranking is skipped too, so only the embedding
+// model has to be loaded.
+const EngineExtractive = "extractive"
+
+// ErrGenerationDisabled is returned for requests that cannot be answered without LLM
+// generation (document questions) when the extractive engine is selected.
+var ErrGenerationDisabled = errors.New("LLM generation is disabled by the extractive engine")
+
+// extractiveAnswer stitches the selected chunks into an answer, each under a header in
+// the citation format used by generated answers.
+func extractiveAnswer(chunks []chunkData) string {
+	sections := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		header := fmt.Sprintf("[File: %s", chunk.relPath)
+		if chunk.headingPath != "" {
+			header += fmt.Sprintf(", Section: %s", chunk.headingPath)
+		}
+		header += "]"
+		sections = append(sections, header+"\n"+strings.TrimSpace(chunk.text))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// chunkReferences builds one reference per chunk, in rank order.
+func chunkReferences(chunks []chunkData) []Reference {
+	references := make([]Reference, 0, len(chunks))
+	for _, chunk := range chunks {
+		references = append(references, Reference{
+			ChunkID:     chunk.result.PointID,
+			Vault:       chunk.vaultName,
+			RelPath:     chunk.relPath,
+			HeadingPath: chunk.headingPath,
+			ChunkIndex:  chunk.chunkIndex,
+		})
+	}
+	return references
+}
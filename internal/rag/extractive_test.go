@@ -0,0 +1,41 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExtractiveAnswer(t *testing.T) {
+	chunks := []chunkData{
+		{text: "Tomatoes grow in the north bed.\n", vaultName: "personal", relPath: "garden.md", headingPath: "# Garden > ## Beds", chunkIndex: 2},
+		{text: "Water weekly.", vaultName: "personal", relPath: "care.md"},
+	}
+
+	want := "[File: garden.md, Section: # Garden > ## Beds]\nTomatoes grow in the north bed.\n\n[File: care.md]\nWater weekly."
+	if got := extractiveAnswer(chunks); got != want {
+		t.Errorf("extractiveAnswer() = %q, want %q", got, want)
+	}
+
+	refs := chunkReferences(chunks)
+	if len(refs) != 2 || refs[0].RelPath != "garden.md" || refs[0].ChunkIndex != 2 || refs[1].Vault != "personal" {
+		t.Errorf("chunkReferences() = %+v, want one reference per chunk in order", refs)
+	}
+}
+
+func TestExtractiveEngine_SkipsLLM(t *testing.T) {
+	// No chat backend is configured, so any LLM call would panic
+	engine := &ragEngine{extractive: true}
+	ctx := context.Background()
+
+	vaultMap := map[int]string{1: "personal"}
+	folders, _ := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", nil, []string{"1/garden", "1/work"}, []string{"garden"}, []int{1}, vaultMap, nil)
+	if len(folders) != 1 || folders[0] != "1/garden" {
+		t.Errorf("selectRelevantFolders() = %v, want only the user folder [1/garden]", folders)
+	}
+
+	_, err := engine.AskDocument(ctx, DocumentRequest{Document: "Some text."})
+	if !errors.Is(err, ErrGenerationDisabled) {
+		t.Errorf("AskDocument() error = %v, want ErrGenerationDisabled", err)
+	}
+}
@@ -0,0 +1,175 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Embedder turns texts into embedding vectors. *llm.EmbeddingsClient implements it.
+type Embedder interface {
+	EmbedTexts(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// ChatBackend generates chat completions. *llm.Client implements it.
+type ChatBackend interface {
+	ChatWithMessages(ctx context.Context, messages []llm.Message, params llm.ChatParams) (string, error)
+}
+
+// StreamingChatBackend is a ChatBackend that can also stream replies. *llm.Client implements it.
+type StreamingChatBackend interface {
+	ChatBackend
+	StreamChatWithMessages(ctx context.Context, messages []llm.Message, params llm.ChatParams, callback func(chunk string) error) error
+}
+
+// Reranker scores texts against a query, higher meaning more relevant. *llm.RerankerClient implements it.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, texts []string) ([]float32, error)
+}
+
+// Tokenizer counts tokens with the chat model's tokenizer. *llm.Client implements it.
+type Tokenizer interface {
+	CountTokens(ctx context.Context, text string) (int, error)
+}
+
+// EngineLLM is the default engine: vector retrieval followed by LLM answer generation.
+const EngineLLM = "llm"
+
+// EngineDeps holds the dependencies an Engine implementation may use.
+// Fields mirror the NewEngine parameters; engines ignore what they do not need.
+type EngineDeps struct {
+	Embedder        Embedder
+	VectorStore     vectorstore.VectorStore
+	Collection      string
+	ColdCollection  string
+	ChunkRepo       storage.ChunkStore
+	VaultRepo       storage.VaultStore
+	NoteRepo        storage.NoteStore
+	Chat            ChatBackend
+	QuestionLogMode string
+	FolderSelection FolderSelectionOptions
+	NotePrefilter   NotePrefilterOptions
+	Flags           *features.Flags
+	QuestionCache   QuestionCacheOptions
+	Generation      GenerationOptions
+	// CollectionPerVault searches each vault's chunks in vectorstore.VaultCollection(Collection, id)
+	// instead of Collection.
+	CollectionPerVault bool
+	// Hydrator recovers chunk text from disk when SQLite lacks it (optional).
+	Hydrator ChunkHydrator
+	// IndexEpoch enables caching vaults and folders until indexing changes the notes (optional).
+	IndexEpoch IndexEpochSource
+	// ScoreFloor is the lowest score thresholds a request may set (zero: not below the defaults).
+	ScoreFloor ScoreThresholds
+	// Retrieval tunes candidate retrieval and scoring (zero fields use DefaultRetrievalOptions).
+	Retrieval RetrievalOptions
+	// ClickStore counts reference impressions and clicks for click-through rates (optional).
+	ClickStore storage.ChunkClickStore
+	// ReadOnly skips recording note retrievals and reference impressions, for replicas
+	// whose database is opened read-only. Click-through rates are still read.
+	ReadOnly bool
+	// Reranker replaces the lexical score in the rerank stage (optional).
+	Reranker Reranker
+	// Stages is the ask pipeline, checked by ValidateStages (empty: DefaultStages).
+	Stages []string
+	// Tokenizer reports the answer prompt's token counts in debug responses (optional).
+	Tokenizer Tokenizer
+	// Answerability configures the LLM check that abstains when the selected context cannot
+	// answer the question (zero threshold: disabled).
+	Answerability AnswerabilityOptions
+	// Neighbors configures merging the chunks around each selected chunk into the context
+	// (zero token budget: disabled).
+	Neighbors NeighborOptions
+	// Timeouts bound the embedding, search, and generation calls of each request (zero
+	// durations: bounded by the request context only).
+	Timeouts TimeoutOptions
+	// Judging scores generated answers for faithfulness and relevance and regenerates
+	// unfaithful ones (nil Judge: disabled).
+	Judging JudgeOptions
+	// Caches receives the engine's in-memory caches for the admin stats and flush API (optional).
+	Caches *cache.Registry
+}
+
+// EngineFactory builds an Engine from its dependencies.
+type EngineFactory func(deps EngineDeps) Engine
+
+// engineFactories lists the engines selectable by name (RAG_ENGINE).
+var engineFactories = map[string]EngineFactory{
+	EngineLLM: func(deps EngineDeps) Engine {
+		return newRAGEngine(deps)
+	},
+	EngineExtractive: func(deps EngineDeps) Engine {
+		engine := newRAGEngine(deps)
+		engine.extractive = true
+		return engine
+	},
+}
+
+// newRAGEngine builds the standard engine from deps.
+func newRAGEngine(deps EngineDeps) *ragEngine {
+	engine := NewEngine(
+		deps.Embedder,
+		deps.VectorStore,
+		deps.Collection,
+		deps.ColdCollection,
+		deps.ChunkRepo,
+		deps.VaultRepo,
+		deps.NoteRepo,
+		deps.Chat,
+		deps.QuestionLogMode,
+		deps.FolderSelection,
+		deps.NotePrefilter,
+		deps.Flags,
+		deps.QuestionCache,
+		deps.Generation,
+	).(*ragEngine)
+	engine.hydrator = deps.Hydrator
+	engine.metadata = newMetadataCache(deps.IndexEpoch)
+	engine.scoreFloor = deps.ScoreFloor
+	engine.retrieval = deps.Retrieval
+	engine.clickStore = deps.ClickStore
+	engine.readOnly = deps.ReadOnly
+	engine.stages = deps.Stages
+	engine.reranker = deps.Reranker
+	engine.tokenizer = deps.Tokenizer
+	if deps.Tokenizer != nil {
+		engine.promptTokens = cache.New[string, int](CachePromptTokens, cache.Options{Size: maxPromptTokenCounts})
+	}
+	engine.answerability = deps.Answerability
+	engine.neighbors = deps.Neighbors
+	engine.collectionPerVault = deps.CollectionPerVault
+	engine.timeouts = deps.Timeouts
+	engine.judging = deps.Judging
+	engine.registerCaches(deps.Caches)
+	return engine
+}
+
+// EngineNames returns the names of the available engines, sorted.
+func EngineNames() []string {
+	names := make([]string, 0, len(engineFactories))
+	for name := range engineFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEngineByName builds the engine registered under name (case-insensitive).
+// It fails for unknown engines and for an invalid ask pipeline in deps.Stages.
+func NewEngineByName(name string, deps EngineDeps) (Engine, error) {
+	factory, ok := engineFactories[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown RAG engine %q (available: %s)", name, strings.Join(EngineNames(), ", "))
+	}
+	if err := ValidateStages(deps.Stages); err != nil {
+		return nil, err
+	}
+	return factory(deps), nil
+}
@@ -0,0 +1,51 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+// stubEmbedder and stubChat stand in for the llm clients to show engines only need the interfaces.
+type stubEmbedder struct{}
+
+func (stubEmbedder) EmbedTexts(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1, 0, 0}
+	}
+	return vectors, nil
+}
+
+type stubChat struct{}
+
+func (stubChat) ChatWithMessages(context.Context, []llm.Message, llm.ChatParams) (string, error) {
+	return "stub answer", nil
+}
+
+func TestNewEngineByName(t *testing.T) {
+	deps := EngineDeps{Embedder: stubEmbedder{}, Chat: stubChat{}}
+
+	engine, err := NewEngineByName("LLM", deps)
+	if err != nil {
+		t.Fatalf("NewEngineByName(LLM) error = %v", err)
+	}
+	re, ok := engine.(*ragEngine)
+	if !ok {
+		t.Fatalf("NewEngineByName(LLM) = %T, want *ragEngine", engine)
+	}
+	if _, ok := re.embedder.(stubEmbedder); !ok {
+		t.Errorf("engine embedder = %T, want the injected stubEmbedder", re.embedder)
+	}
+	if _, ok := re.llmClient.(stubChat); !ok {
+		t.Errorf("engine chat backend = %T, want the injected stubChat", re.llmClient)
+	}
+
+	if _, err := NewEngineByName("missing", deps); err == nil {
+		t.Error("NewEngineByName(missing) error = nil, want unknown engine error")
+	}
+	if names := EngineNames(); len(names) != 2 || names[0] != EngineExtractive || names[1] != EngineLLM {
+		t.Errorf("EngineNames() = %v, want [%s %s]", names, EngineExtractive, EngineLLM)
+	}
+}
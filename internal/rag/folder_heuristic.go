@@ -0,0 +1,182 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+)
+
+// How the searched folders were chosen, reported in debug responses.
+const (
+	// folderMethodUser: only the user's folders (nothing left to rank, or no LLM).
+	folderMethodUser = "user"
+	// folderMethodCache: a ranking cached for the same question embedding and folders.
+	folderMethodCache = "cache"
+	// folderMethodHeuristic: folders ranked by chunk similarity to the question.
+	folderMethodHeuristic = "heuristic"
+	// folderMethodLLM: folders ranked by the LLM.
+	folderMethodLLM = "llm"
+	// folderMethodFallback: the LLM failed and folders were ranked by path words.
+	folderMethodFallback = "fallback"
+)
+
+const (
+	// heuristicProbeK is how many chunks per vault the heuristic ranking retrieves.
+	heuristicProbeK = 40
+	// heuristicHitsPerFolder is how many of a folder's best chunks are averaged into its score.
+	heuristicHitsPerFolder = 3
+	// heuristicMaxFolders caps the folders the heuristic ranking selects.
+	heuristicMaxFolders = 3
+)
+
+// newFolderCache returns the cache of folder rankings, or nil when opts disables it.
+// A nil cache never hits and ignores writes.
+func newFolderCache(opts FolderSelectionOptions) *cache.Cache[string, []string] {
+	if opts.CacheSize <= 0 || opts.CacheTTL <= 0 {
+		return nil
+	}
+	return cache.New[string, []string](CacheFolderSelections, cache.Options{Size: opts.CacheSize, TTL: opts.CacheTTL})
+}
+
+// folderCacheKey identifies a folder ranking by the question embedding and the folders it
+// was chosen from, so repeated questions reuse it until the folder list changes. Returns ""
+// (no caching) without an embedding.
+func folderCacheKey(queryVector []float32, availableFolders, userFolders []string) string {
+	if len(queryVector) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(struct {
+		Vector    []float32 `json:"vector"`
+		Available []string  `json:"available"`
+		User      []string  `json:"user"`
+	}{queryVector, availableFolders, userFolders})
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// cachedFolders returns the cached ranking for key, copied so callers may modify it.
+func (e *ragEngine) cachedFolders(key string) ([]string, bool) {
+	if key == "" {
+		return nil, false
+	}
+	folders, ok := e.folderCache.Get(key)
+	return slices.Clone(folders), ok
+}
+
+// cacheFolders stores a ranking under key.
+func (e *ragEngine) cacheFolders(key string, folders []string) {
+	if key == "" {
+		return
+	}
+	e.folderCache.Set(key, slices.Clone(folders))
+}
+
+// heuristicFolders ranks candidates ("<vaultID>/folder") without the LLM: it retrieves the
+// chunks nearest the question in each vault, assigns each chunk to the deepest candidate
+// folder containing it, and scores a folder by the mean similarity of its best
+// heuristicHitsPerFolder chunks, an approximation of the folder centroid's similarity.
+// It returns up to heuristicMaxFolders folders, best first, and false when the ranking is
+// inconclusive: no chunk falls in a candidate folder, or the best folder leads the runner-up
+// by less than the configured margin.
+func (e *ragEngine) heuristicFolders(ctx context.Context, queryVector []float32, candidates []string, vaultIDs []int) ([]string, bool) {
+	logger := contextutil.LoggerFromContext(ctx)
+	if len(queryVector) == 0 || len(candidates) == 0 {
+		return nil, false
+	}
+
+	isCandidate := make(map[string]bool, len(candidates))
+	for _, folder := range candidates {
+		isCandidate[folder] = true
+	}
+
+	hits := make(map[string][]float64)
+	for _, vaultID := range vaultIDs {
+		results, err := e.search(ctx, queryVector, heuristicProbeK, map[string]any{"vault_id": vaultID}, false)
+		if err != nil {
+			logger.WarnContext(ctx, "heuristic folder search failed", "vault_id", vaultID, "error", err)
+			return nil, false
+		}
+		for _, result := range results {
+			folder, _ := result.Meta["folder"].(string)
+			if match := candidateFolder(isCandidate, vaultID, folder); match != "" {
+				hits[match] = append(hits[match], float64(result.Score))
+			}
+		}
+	}
+
+	type scoredFolder struct {
+		path  string
+		score float64
+	}
+	ranked := make([]scoredFolder, 0, len(hits))
+	for folder, scores := range hits {
+		sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+		top := scores[:min(len(scores), heuristicHitsPerFolder)]
+		var sum float64
+		for _, score := range top {
+			sum += score
+		}
+		ranked = append(ranked, scoredFolder{path: folder, score: sum / float64(len(top))})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].path < ranked[j].path
+	})
+
+	if len(ranked) == 0 {
+		logger.InfoContext(ctx, "heuristic folder ranking inconclusive: no chunks in candidate folders")
+		return nil, false
+	}
+	if len(ranked) > 1 && ranked[0].score-ranked[1].score < e.folderSelection.HeuristicMargin {
+		logger.InfoContext(ctx, "heuristic folder ranking inconclusive: no clear leader",
+			"best", ranked[0].path,
+			"best_score", ranked[0].score,
+			"runner_up", ranked[1].path,
+			"runner_up_score", ranked[1].score,
+			"margin", e.folderSelection.HeuristicMargin,
+		)
+		return nil, false
+	}
+
+	folders := make([]string, 0, min(len(ranked), heuristicMaxFolders))
+	for _, folder := range ranked[:min(len(ranked), heuristicMaxFolders)] {
+		folders = append(folders, folder.path)
+	}
+	logger.InfoContext(ctx, "folders ranked by similarity",
+		"folders", folders,
+		"best_score", ranked[0].score,
+		"scored_folders", len(ranked),
+	)
+	return folders, true
+}
+
+// candidateFolder returns the deepest candidate folder of vaultID that contains folder, or
+// "" when none does. Candidates are collapsed to FolderSelectionOptions.MaxDepth, so a chunk
+// usually falls in an ancestor of its own folder.
+func candidateFolder(isCandidate map[string]bool, vaultID int, folder string) string {
+	prefix := strconv.Itoa(vaultID) + "/"
+	for folder != "" {
+		if isCandidate[prefix+folder] {
+			return prefix + folder
+		}
+		idx := strings.LastIndex(folder, "/")
+		if idx < 0 {
+			break
+		}
+		folder = folder[:idx]
+	}
+	return ""
+}
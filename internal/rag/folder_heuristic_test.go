@@ -0,0 +1,147 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// countingChat replies to folder ranking requests and counts the calls.
+type countingChat struct {
+	reply string
+	calls int
+}
+
+func (c *countingChat) ChatWithMessages(context.Context, []llm.Message, llm.ChatParams) (string, error) {
+	c.calls++
+	return c.reply, nil
+}
+
+// newHeuristicTestEngine indexes points into a memory store and returns an engine ranking
+// folders in heuristic mode.
+func newHeuristicTestEngine(t *testing.T, chat ChatBackend, points []vectorstore.Point) *ragEngine {
+	t.Helper()
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	if err := store.Upsert(ctx, "notes", points); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	opts := FolderSelectionOptions{Mode: FolderSelectionHeuristic, HeuristicMargin: 0.05, CacheSize: 8, CacheTTL: time.Minute}
+	return &ragEngine{
+		vectorStore:     store,
+		collection:      "notes",
+		llmClient:       chat,
+		folderSelection: opts,
+		folderCache:     newFolderCache(opts),
+	}
+}
+
+func folderPoint(id, folder string, vec ...float32) vectorstore.Point {
+	return vectorstore.Point{ID: id, Vec: vec, Meta: map[string]any{"vault_id": 1, "folder": folder}}
+}
+
+func TestSelectRelevantFolders_Heuristic(t *testing.T) {
+	ctx := context.Background()
+	vaultMap := map[int]string{1: "personal"}
+	available := []string{"1/garden", "1/recipes", "1/work"}
+
+	t.Run("clear leader skips the LLM", func(t *testing.T) {
+		chat := &countingChat{reply: `["personal/work"]`}
+		engine := newHeuristicTestEngine(t, chat, []vectorstore.Point{
+			folderPoint("a", "garden/tomatoes", 1, 0),
+			folderPoint("b", "garden", 0.9, 0.1),
+			folderPoint("c", "recipes", 0.5, 0.5),
+			folderPoint("d", "journal", 1, 0),
+		})
+
+		folders, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, available, nil, []int{1}, vaultMap, nil)
+		if !reflect.DeepEqual(folders, []string{"1/garden", "1/recipes"}) || method != folderMethodHeuristic {
+			t.Errorf("selectRelevantFolders() = %v, %s, want [1/garden 1/recipes] by heuristic", folders, method)
+		}
+		if chat.calls != 0 {
+			t.Errorf("LLM called %d times, want 0", chat.calls)
+		}
+	})
+
+	t.Run("close scores ask the LLM", func(t *testing.T) {
+		chat := &countingChat{reply: `["personal/work"]`}
+		engine := newHeuristicTestEngine(t, chat, []vectorstore.Point{
+			folderPoint("a", "garden", 1, 0),
+			folderPoint("b", "recipes", 1, 0.01),
+		})
+
+		folders, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, available, nil, []int{1}, vaultMap, nil)
+		if !reflect.DeepEqual(folders, []string{"1/work"}) || method != folderMethodLLM || chat.calls != 1 {
+			t.Errorf("selectRelevantFolders() = %v, %s after %d LLM calls, want [1/work] by one LLM call", folders, method, chat.calls)
+		}
+	})
+
+	t.Run("no chunks in candidate folders ask the LLM", func(t *testing.T) {
+		chat := &countingChat{reply: `["personal/work"]`}
+		engine := newHeuristicTestEngine(t, chat, []vectorstore.Point{folderPoint("a", "", 1, 0)})
+
+		if _, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, available, nil, []int{1}, vaultMap, nil); method != folderMethodLLM {
+			t.Errorf("selectRelevantFolders() method = %s, want llm", method)
+		}
+	})
+}
+
+func TestSelectRelevantFolders_Cache(t *testing.T) {
+	ctx := context.Background()
+	vaultMap := map[int]string{1: "personal"}
+	available := []string{"1/garden", "1/work"}
+	chat := &countingChat{reply: `["personal/garden"]`}
+	opts := FolderSelectionOptions{Mode: FolderSelectionLLM, CacheSize: 8, CacheTTL: time.Minute}
+	engine := &ragEngine{llmClient: chat, folderSelection: opts, folderCache: newFolderCache(opts)}
+
+	first, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, available, nil, []int{1}, vaultMap, nil)
+	if method != folderMethodLLM {
+		t.Fatalf("first selectRelevantFolders() method = %s, want llm", method)
+	}
+	first[0] = "modified by the caller"
+
+	second, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, available, nil, []int{1}, vaultMap, nil)
+	if !reflect.DeepEqual(second, []string{"1/garden"}) || method != folderMethodCache || chat.calls != 1 {
+		t.Errorf("repeated selectRelevantFolders() = %v, %s after %d LLM calls, want the cached [1/garden]", second, method, chat.calls)
+	}
+
+	// A different embedding or folder list is ranked again
+	if _, method := engine.selectRelevantFolders(ctx, "Where is the report?", []float32{0, 1}, available, nil, []int{1}, vaultMap, nil); method != folderMethodLLM {
+		t.Errorf("new question method = %s, want llm", method)
+	}
+	if _, method := engine.selectRelevantFolders(ctx, "Where are the tomatoes?", []float32{1, 0}, append(available, "1/recipes"), nil, []int{1}, vaultMap, nil); method != folderMethodLLM {
+		t.Errorf("changed folders method = %s, want llm", method)
+	}
+	if chat.calls != 3 {
+		t.Errorf("LLM called %d times, want 3", chat.calls)
+	}
+}
+
+func TestCandidateFolder(t *testing.T) {
+	isCandidate := map[string]bool{"1/projects": true, "1/projects/go": true, "2/projects": true}
+
+	tests := []struct {
+		vaultID int
+		folder  string
+		want    string
+	}{
+		{1, "projects/go/web", "1/projects/go"},
+		{1, "projects/rust", "1/projects"},
+		{1, "projects", "1/projects"},
+		{2, "projects/go", "2/projects"},
+		{1, "journal", ""},
+		{1, "", ""},
+	}
+	for _, tt := range tests {
+		if got := candidateFolder(isCandidate, tt.vaultID, tt.folder); got != tt.want {
+			t.Errorf("candidateFolder(%d, %q) = %q, want %q", tt.vaultID, tt.folder, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package rag
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/storage"
+)
+
+// FolderSelectionOptions bounds the folder list offered to the LLM during folder selection,
+// so vaults with thousands of folders do not blow up the prompt.
+type FolderSelectionOptions struct {
+	// MaxDepth collapses deeper folders into their ancestor at this depth (0 = unlimited).
+	MaxDepth int
+	// MaxFolders caps the number of folders offered; larger lists are sampled (0 = unlimited).
+	MaxFolders int
+	// Timeout bounds the LLM ranking call; past it folders are ranked without the LLM
+	// (0 = no limit beyond the request's).
+	Timeout time.Duration
+	// Mode is FolderSelectionLLM or FolderSelectionHeuristic (empty = FolderSelectionLLM).
+	Mode string
+	// HeuristicMargin is how far the best folder's similarity must lead the runner-up for
+	// the heuristic ranking to be used without asking the LLM.
+	HeuristicMargin float64
+	// CacheSize is the maximum number of cached folder rankings (0 disables the cache).
+	CacheSize int
+	// CacheTTL is how long a folder ranking stays cached.
+	CacheTTL time.Duration
+}
+
+// Folder selection modes (FOLDER_SELECTION_MODE).
+const (
+	// FolderSelectionLLM asks the LLM to rank folders on every uncached question.
+	FolderSelectionLLM = "llm"
+	// FolderSelectionHeuristic ranks folders by the similarity of their chunks to the
+	// question and asks the LLM only when no folder clearly leads.
+	FolderSelectionHeuristic = "heuristic"
+)
+
+// DefaultFolderSelectionOptions offers the top two folder levels, capped at 200 folders,
+// stops waiting for the LLM ranking after 10 seconds, and caches the last 256 rankings for
+// 10 minutes.
+var DefaultFolderSelectionOptions = FolderSelectionOptions{
+	MaxDepth:        2,
+	MaxFolders:      200,
+	Timeout:         10 * time.Second,
+	Mode:            FolderSelectionLLM,
+	HeuristicMargin: 0.05,
+	CacheSize:       256,
+	CacheTTL:        10 * time.Minute,
+}
+
+// sampleFolders returns at most maxFolders folder paths from stats.
+// When the list is over the cap, shallower folders are preferred (they cover the most of
+// the vault once used as prefix filters), then folders holding more notes. Vaults take
+// turns so a large vault cannot crowd out a small one. The result is ordered by path.
+func sampleFolders(stats []storage.FolderStat, maxFolders int) []string {
+	if maxFolders <= 0 || len(stats) <= maxFolders {
+		paths := make([]string, 0, len(stats))
+		for _, stat := range stats {
+			paths = append(paths, stat.Path)
+		}
+		return paths
+	}
+
+	// Rank folders within each vault
+	byVault := make(map[int][]storage.FolderStat)
+	var vaultIDs []int
+	for _, stat := range stats {
+		if _, ok := byVault[stat.VaultID]; !ok {
+			vaultIDs = append(vaultIDs, stat.VaultID)
+		}
+		byVault[stat.VaultID] = append(byVault[stat.VaultID], stat)
+	}
+	sort.Ints(vaultIDs)
+	for _, vaultID := range vaultIDs {
+		ranked := byVault[vaultID]
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if ranked[i].Depth != ranked[j].Depth {
+				return ranked[i].Depth < ranked[j].Depth
+			}
+			if ranked[i].NoteCount != ranked[j].NoteCount {
+				return ranked[i].NoteCount > ranked[j].NoteCount
+			}
+			return ranked[i].Path < ranked[j].Path
+		})
+	}
+
+	// Take folders round-robin across vaults until the cap is reached
+	paths := make([]string, 0, maxFolders)
+	for round := 0; len(paths) < maxFolders; round++ {
+		added := false
+		for _, vaultID := range vaultIDs {
+			if round < len(byVault[vaultID]) && len(paths) < maxFolders {
+				paths = append(paths, byVault[vaultID][round].Path)
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// fallbackFolderRanking ranks folders without the LLM, for when it is down, slow, or replies
+// with something unusable. Folders whose path shares words with the question are kept,
+// ordered by the number of shared question words, then by how often their notes were
+// retrieved before (retrievals, keyed by "<vaultID>/folder"), then by path. Returns nil when
+// no folder matches, so the whole vault is searched rather than an arbitrary folder order.
+func fallbackFolderRanking(question string, folders []string, retrievals map[string]int) []string {
+	questionTokens := filterStopwords(tokenize(question))
+	if len(questionTokens) == 0 {
+		return nil
+	}
+
+	type rankedFolder struct {
+		path    string
+		overlap int
+		score   float64
+	}
+	var ranked []rankedFolder
+	for _, folder := range folders {
+		_, folderPath, ok := strings.Cut(folder, "/")
+		if !ok {
+			continue
+		}
+		overlap := folderTokenOverlap(questionTokens, tokenize(folderPath))
+		if overlap == 0 {
+			continue
+		}
+		ranked = append(ranked, rankedFolder{
+			path:    folder,
+			overlap: overlap,
+			score:   math.Log1p(float64(retrievals[folder])),
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].overlap != ranked[j].overlap {
+			return ranked[i].overlap > ranked[j].overlap
+		}
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].path < ranked[j].path
+	})
+
+	paths := make([]string, 0, len(ranked))
+	for _, folder := range ranked {
+		paths = append(paths, folder.path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths
+}
+
+// folderTokenOverlap counts the question tokens matching a folder path token, exactly or,
+// for words of four letters or more, as a prefix either way ("workout" and "workouts").
+func folderTokenOverlap(questionTokens, folderTokens []string) int {
+	overlap := 0
+	for _, q := range questionTokens {
+		for _, f := range folderTokens {
+			if q == f || (len(q) >= 4 && len(f) >= 4 && (strings.HasPrefix(q, f) || strings.HasPrefix(f, q))) {
+				overlap++
+				break
+			}
+		}
+	}
+	return overlap
+}
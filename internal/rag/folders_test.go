@@ -0,0 +1,159 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+)
+
+func TestSampleFolders(t *testing.T) {
+	stats := []storage.FolderStat{
+		{Path: "1/", VaultID: 1, Depth: 0, NoteCount: 2},
+		{Path: "1/archive", VaultID: 1, Depth: 1, NoteCount: 1},
+		{Path: "1/projects", VaultID: 1, Depth: 1, NoteCount: 40},
+		{Path: "1/projects/go", VaultID: 1, Depth: 2, NoteCount: 30},
+		{Path: "1/projects/misc", VaultID: 1, Depth: 2, NoteCount: 2},
+		{Path: "2/", VaultID: 2, Depth: 0, NoteCount: 1},
+		{Path: "2/work", VaultID: 2, Depth: 1, NoteCount: 5},
+	}
+
+	t.Run("under cap returns all folders", func(t *testing.T) {
+		if got := sampleFolders(stats, 0); len(got) != len(stats) {
+			t.Errorf("sampleFolders() returned %d folders, want %d", len(got), len(stats))
+		}
+	})
+
+	t.Run("over cap prefers shallow busy folders across vaults", func(t *testing.T) {
+		// Roots and depth-1 folders win over depth-2 ones; the result is sorted by path
+		got := sampleFolders(stats, 5)
+		want := []string{"1/", "1/archive", "1/projects", "2/", "2/work"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sampleFolders() = %v, want %v", got, want)
+		}
+	})
+}
+
+// rankingChat replies to folder ranking requests and records the params it was sent.
+type rankingChat struct {
+	reply  string
+	err    error
+	params llm.ChatParams
+}
+
+func (c *rankingChat) ChatWithMessages(_ context.Context, _ []llm.Message, params llm.ChatParams) (string, error) {
+	c.params = params
+	return c.reply, c.err
+}
+
+func TestSelectRelevantFolders_StructuredOutput(t *testing.T) {
+	chat := &rankingChat{reply: `["personal/garden"]`}
+	engine := &ragEngine{llmClient: chat}
+	vaultMap := map[int]string{1: "personal"}
+
+	folders, _ := engine.selectRelevantFolders(context.Background(), "Where are the tomatoes?", nil, []string{"1/garden", "1/work"}, nil, []int{1}, vaultMap, nil)
+	if !reflect.DeepEqual(folders, []string{"1/garden"}) {
+		t.Errorf("selectRelevantFolders() = %v, want [1/garden]", folders)
+	}
+
+	format := chat.params.ResponseFormat
+	if format == nil || format.Type != "json_schema" || format.JSONSchema == nil {
+		t.Fatalf("ResponseFormat = %+v, want a JSON schema", format)
+	}
+	items, _ := format.JSONSchema.Schema["items"].(map[string]any)
+	if enum, _ := items["enum"].([]string); !reflect.DeepEqual(enum, []string{"personal/garden", "personal/work"}) {
+		t.Errorf("schema items enum = %v, want the offered folders", items["enum"])
+	}
+}
+
+func TestSelectRelevantFolders_FallbackWhenLLMFails(t *testing.T) {
+	engine := &ragEngine{llmClient: &rankingChat{err: errors.New("connection refused")}}
+	vaultMap := map[int]string{1: "personal"}
+	available := []string{"1/garden", "1/work", "1/garden/tomatoes"}
+
+	folders, _ := engine.selectRelevantFolders(context.Background(), "When did I plant the tomatoes?", nil, available, nil, []int{1}, vaultMap, nil)
+	if !reflect.DeepEqual(folders, []string{"1/garden/tomatoes"}) {
+		t.Errorf("selectRelevantFolders() = %v, want [1/garden/tomatoes]", folders)
+	}
+
+	folders, _ = engine.selectRelevantFolders(context.Background(), "What is the meaning of life?", nil, available, []string{"1/work"}, []int{1}, vaultMap, nil)
+	if !reflect.DeepEqual(folders, []string{"1/work"}) {
+		t.Errorf("selectRelevantFolders() with no matching folder = %v, want only the user folders", folders)
+	}
+}
+
+func TestFallbackFolderRanking(t *testing.T) {
+	folders := []string{"1/projects", "1/projects/garden", "1/recipes", "2/garden", "2/workouts"}
+
+	tests := []struct {
+		name       string
+		question   string
+		retrievals map[string]int
+		want       []string
+	}{
+		{
+			name:     "more shared words rank first",
+			question: "garden projects this spring",
+			want:     []string{"1/projects/garden", "1/projects", "2/garden"},
+		},
+		{
+			name:       "past retrievals break ties",
+			question:   "what did I plant in the garden",
+			retrievals: map[string]int{"2/garden": 12, "1/projects/garden": 3},
+			want:       []string{"2/garden", "1/projects/garden"},
+		},
+		{
+			name:     "prefix matches plural forms",
+			question: "my last workout",
+			want:     []string{"2/workouts"},
+		},
+		{
+			name:     "no match",
+			question: "what is the weather like",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fallbackFolderRanking(tt.question, folders, tt.retrievals)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fallbackFolderRanking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFolderRanking(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "bare array", reply: ` ["personal/a", "work/b"] `, want: []string{"personal/a", "work/b"}},
+		{name: "empty array", reply: `[]`, want: []string{}},
+		{name: "code fence", reply: "```json\n[\"personal/a\"]\n```", want: []string{"personal/a"}},
+		{name: "prose around array", reply: `Ranked: ["personal/a"] (most relevant first)`, want: []string{"personal/a"}},
+		{name: "no array", reply: `personal/a`, wantErr: true},
+		{name: "not strings", reply: `[1, 2]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFolderRanking(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFolderRanking() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFolderRanking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if format := folderRankingFormat(nil); format != nil {
+		t.Errorf("folderRankingFormat(nil) = %+v, want nil", format)
+	}
+}
@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+)
+
+// maxFollowUps bounds the follow-up questions suggested with an answer.
+const maxFollowUps = 3
+
+// followUpContextChars bounds how much of each chunk is shown when asking for follow-ups.
+const followUpContextChars = 600
+
+// suggestFollowUps asks the LLM for follow-up questions the retrieved chunks can answer,
+// so clients can offer "you might also ask". Failures are logged and yield no suggestions;
+// they never fail the answer.
+func (e *ragEngine) suggestFollowUps(ctx context.Context, question, answer string, chunks []chunkData) []string {
+	logger := contextutil.LoggerFromContext(ctx)
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	var contextBuilder strings.Builder
+	for i, chunk := range chunks {
+		fmt.Fprintf(&contextBuilder, "[Chunk %d] %s (%s)\n%s\n\n", i+1, chunk.relPath, chunk.headingPath, truncateString(chunk.text, followUpContextChars))
+	}
+
+	prompt := fmt.Sprintf(`Suggest follow-up questions a user might ask next about their notes.
+
+Question: %s
+Answer: %s
+
+Notes:
+%s
+Instructions:
+- Suggest 2 or 3 short questions that the notes above can answer
+- Do not repeat the original question or ask what the answer already covers
+- Return ONLY a JSON array of strings, nothing else
+
+Your response (JSON array only):`, question, answer, contextBuilder.String())
+
+	reply, err := e.chat(ctx, []llm.Message{
+		{Role: "user", Content: prompt},
+	}, llm.ChatParams{
+		Model:       "",  // Use default from client
+		MaxTokens:   200, // A few short questions
+		Temperature: 0.5,
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "failed to get follow-up suggestions", "error", err)
+		return nil
+	}
+
+	suggestions, err := parseFollowUps(reply, question)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to parse follow-up suggestions", "error", err, "reply_preview", truncateString(reply, 200))
+		return nil
+	}
+	logger.InfoContext(ctx, "follow-up suggestions generated", "count", len(suggestions))
+	return suggestions
+}
+
+// parseFollowUps extracts the JSON array of questions from an LLM reply, dropping blanks,
+// duplicates, and restatements of the original question, and keeps at most maxFollowUps.
+func parseFollowUps(reply, question string) ([]string, error) {
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("reply is not a JSON array")
+	}
+	var raw []string
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suggestions: %w", err)
+	}
+
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(question)): true}
+	var suggestions []string
+	for _, suggestion := range raw {
+		suggestion = strings.TrimSpace(suggestion)
+		key := strings.ToLower(suggestion)
+		if suggestion == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		suggestions = append(suggestions, suggestion)
+		if len(suggestions) == maxFollowUps {
+			break
+		}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,76 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFollowUps(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "plain array",
+			reply: `["When is the next checkup?", "Which doctor did I see?"]`,
+			want:  []string{"When is the next checkup?", "Which doctor did I see?"},
+		},
+		{
+			name:  "surrounding prose",
+			reply: "Here you go:\n```json\n[\"What did the lab results say?\"]\n```",
+			want:  []string{"What did the lab results say?"},
+		},
+		{
+			name:  "drops blanks, duplicates and the original question",
+			reply: `["What was my blood pressure?", " ", "Which doctor did I see?", "which doctor did I see?"]`,
+			want:  []string{"Which doctor did I see?"},
+		},
+		{
+			name:  "keeps at most three",
+			reply: `["A?", "B?", "C?", "D?"]`,
+			want:  []string{"A?", "B?", "C?"},
+		},
+		{name: "not an array", reply: "No suggestions.", wantErr: true},
+		{name: "not strings", reply: `[1, 2]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFollowUps(tt.reply, "What was my blood pressure?")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFollowUps() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFollowUps() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRagEngine_SuggestFollowUps(t *testing.T) {
+	chunks := []chunkData{{text: "Blood pressure was 120/80. Next checkup in March.", relPath: "health/checkup.md", headingPath: "# Checkup"}}
+
+	chat := &classifierChat{reply: `["When is the next checkup?"]`}
+	engine := &ragEngine{llmClient: chat}
+	got := engine.suggestFollowUps(context.Background(), "What was my blood pressure?", "It was 120/80.", chunks)
+	if !slices.Equal(got, []string{"When is the next checkup?"}) {
+		t.Errorf("suggestFollowUps() = %q, want the suggested question", got)
+	}
+	if len(chat.messages) != 1 || !strings.Contains(chat.messages[0].Content, "Next checkup in March") {
+		t.Errorf("prompt does not include the retrieved chunk: %+v", chat.messages)
+	}
+
+	chat = &classifierChat{err: errors.New("llm down")}
+	engine = &ragEngine{llmClient: chat}
+	if got := engine.suggestFollowUps(context.Background(), "What was my blood pressure?", "It was 120/80.", chunks); got != nil {
+		t.Errorf("suggestFollowUps() with failing LLM = %q, want nil", got)
+	}
+
+	if got := engine.suggestFollowUps(context.Background(), "What was my blood pressure?", "I don't know.", nil); got != nil {
+		t.Errorf("suggestFollowUps() without chunks = %q, want nil", got)
+	}
+}
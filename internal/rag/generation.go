@@ -0,0 +1,102 @@
+package rag
+
+import (
+	"fmt"
+	"slices"
+
+	"helloworld-ai/internal/llm"
+)
+
+// GenerationOptions holds sampling controls applied to answer generation. Some local
+// models loop or append trailing text after the Citations block; stop sequences and a
+// repetition penalty cut that off. Zero values leave the llama.cpp server defaults.
+type GenerationOptions struct {
+	// Stop lists sequences that end generation when produced.
+	Stop []string
+	// RepeatPenalty penalizes repeated tokens (1.0 disables it).
+	RepeatPenalty float32
+	// TopP is the nucleus sampling threshold.
+	TopP float32
+	// TopK limits sampling to the K most likely tokens.
+	TopK int
+	// Model names the chat model for ResponseMeta (LLM_MODEL). Requests still use the
+	// chat backend's configured model.
+	Model string
+	// CurrentModel returns the chat backend's model after a runtime swap (llm.Client.ModelName),
+	// reported in place of Model (nil: Model is always reported).
+	CurrentModel func() string
+	// StructuredCitations requests answers as JSON ({"answer", "citations": [{"chunk_id"}]})
+	// and matches citations to chunks by ID (LLM_STRUCTURED_CITATIONS). Streamed answers, and
+	// replies that are not such JSON, fall back to parsing bracket citations from the text.
+	StructuredCitations bool
+}
+
+// apply copies the configured controls onto params.
+func (o GenerationOptions) apply(params llm.ChatParams) llm.ChatParams {
+	params.Stop = o.Stop
+	params.RepeatPenalty = o.RepeatPenalty
+	params.TopP = o.TopP
+	params.TopK = o.TopK
+	return params
+}
+
+// MaxTemperature is the highest temperature a request may set.
+const MaxTemperature = 2
+
+// GenerationOverrides change answer generation for one request, so power users can trade
+// speed for quality per question. Zero values keep the server's settings. They are checked
+// with GenerationLimits.Validate before the request reaches the engine.
+type GenerationOverrides struct {
+	// Model selects the chat model (empty: the configured LLM_MODEL).
+	Model string `json:"model,omitempty"`
+	// Temperature replaces the answer temperature (0: the engine's default of 0.3).
+	Temperature float32 `json:"temperature,omitempty"`
+	// MaxTokens caps the answer length in tokens (0: no cap).
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// SystemPrompt replaces the answer system prompt. Answers may lose their citations, so
+	// references fall back to every retrieved chunk when none are cited.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+}
+
+// apply copies the overrides set onto params.
+func (o GenerationOverrides) apply(params llm.ChatParams) llm.ChatParams {
+	if o.Model != "" {
+		params.Model = o.Model
+	}
+	if o.Temperature > 0 {
+		params.Temperature = o.Temperature
+	}
+	if o.MaxTokens > 0 {
+		params.MaxTokens = o.MaxTokens
+	}
+	return params
+}
+
+// GenerationLimits bound the generation overrides requests may make.
+type GenerationLimits struct {
+	// DefaultModel is the configured chat model, which is always allowed.
+	DefaultModel string
+	// AllowedModels are the other models requests may select (LLM_ALLOWED_MODELS).
+	AllowedModels []string
+	// MaxTokens is the highest max_tokens a request may set (0: no limit).
+	MaxTokens int
+	// AllowSystemPrompt permits replacing the answer system prompt (ASK_ALLOW_SYSTEM_PROMPT).
+	AllowSystemPrompt bool
+}
+
+// Validate returns an error describing the first override outside the limits.
+func (l GenerationLimits) Validate(o GenerationOverrides) error {
+	if o.Model != "" && o.Model != l.DefaultModel && !slices.Contains(l.AllowedModels, o.Model) {
+		return fmt.Errorf("model %q is not allowed", o.Model)
+	}
+	if o.Temperature < 0 || o.Temperature > MaxTemperature {
+		return fmt.Errorf("temperature must be between 0 and %d", MaxTemperature)
+	}
+	if o.MaxTokens < 0 || (l.MaxTokens > 0 && o.MaxTokens > l.MaxTokens) {
+		return fmt.Errorf("max_tokens must be between 0 and %d", l.MaxTokens)
+	}
+	if o.SystemPrompt != "" && !l.AllowSystemPrompt {
+		return fmt.Errorf("system_prompt_override is disabled on this server")
+	}
+	return nil
+}
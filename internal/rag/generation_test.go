@@ -0,0 +1,54 @@
+package rag
+
+import (
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+func TestGenerationLimits_Validate(t *testing.T) {
+	limits := GenerationLimits{
+		DefaultModel:  "Qwen2.5-3B-Instruct-Q4_K_M",
+		AllowedModels: []string{"Llama-3.1-8B-Instruct-Q4_K_M"},
+		MaxTokens:     1024,
+	}
+
+	tests := []struct {
+		name      string
+		overrides GenerationOverrides
+		limits    GenerationLimits
+		wantErr   bool
+	}{
+		{name: "no overrides", overrides: GenerationOverrides{}, limits: limits},
+		{name: "default model", overrides: GenerationOverrides{Model: "Qwen2.5-3B-Instruct-Q4_K_M"}, limits: limits},
+		{name: "allowed model", overrides: GenerationOverrides{Model: "Llama-3.1-8B-Instruct-Q4_K_M", Temperature: 0.9, MaxTokens: 1024}, limits: limits},
+		{name: "unknown model", overrides: GenerationOverrides{Model: "gpt-4"}, limits: limits, wantErr: true},
+		{name: "negative temperature", overrides: GenerationOverrides{Temperature: -0.1}, limits: limits, wantErr: true},
+		{name: "temperature too high", overrides: GenerationOverrides{Temperature: 2.5}, limits: limits, wantErr: true},
+		{name: "too many tokens", overrides: GenerationOverrides{MaxTokens: 1025}, limits: limits, wantErr: true},
+		{name: "unlimited tokens", overrides: GenerationOverrides{MaxTokens: 100000}, limits: GenerationLimits{}},
+		{name: "system prompt disabled", overrides: GenerationOverrides{SystemPrompt: "Answer in French."}, limits: limits, wantErr: true},
+		{name: "system prompt allowed", overrides: GenerationOverrides{SystemPrompt: "Answer in French."}, limits: GenerationLimits{AllowSystemPrompt: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.overrides, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerationOverrides_Apply(t *testing.T) {
+	base := llm.ChatParams{Temperature: 0.3, TopK: 40}
+
+	if got := (GenerationOverrides{}).apply(base); got.Model != "" || got.Temperature != 0.3 || got.MaxTokens != 0 || got.TopK != 40 {
+		t.Errorf("apply() without overrides = %+v, want the params unchanged", got)
+	}
+
+	got := GenerationOverrides{Model: "Llama-3.1-8B-Instruct-Q4_K_M", Temperature: 0.8, MaxTokens: 256}.apply(base)
+	if got.Model != "Llama-3.1-8B-Instruct-Q4_K_M" || got.Temperature != 0.8 || got.MaxTokens != 256 || got.TopK != 40 {
+		t.Errorf("apply() = %+v, want the overridden model, temperature, and max tokens", got)
+	}
+}
@@ -0,0 +1,167 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Heading match: a question that names a heading (near-)verbatim, such as "Designing a
+// HashMap", ranks every chunk under that heading ahead of the reranked candidates.
+const (
+	// maxHeadingQuestionTerms skips the lookup for questions too long to be a heading.
+	maxHeadingQuestionTerms = 8
+	// maxHeadingMatchChunks caps the chunks a heading match adds.
+	maxHeadingMatchChunks = rerankKeep
+	// headingMatchScore is the final score given to heading matches, the top of the blended range.
+	headingMatchScore = float32(1.0)
+)
+
+// headingTerms splits text into lowercase runs of letters and digits, the form in which
+// questions and headings are compared. Markdown markers and punctuation are dropped.
+func headingTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// headingMatchIndex returns the index of the first heading path segment whose terms equal
+// terms, or -1. Chunks of subheadings carry the parent segment too, so they match as well.
+func headingMatchIndex(headingPath string, terms []string) int {
+	for i, segment := range strings.Split(headingPath, " > ") {
+		if strings.Join(headingTerms(segment), " ") == strings.Join(terms, " ") {
+			return i
+		}
+	}
+	return -1
+}
+
+// findHeadingMatches returns candidates for the chunks under a heading that matches the
+// question, in note order and capped at maxHeadingMatchChunks. The lookup honours the
+// request's vaults, folders, and cold-tier opt-in. Errors are logged and yield no matches.
+func (e *ragEngine) findHeadingMatches(ctx context.Context, req AskRequest, vaultIDs []int) []rerankCandidate {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	terms := headingTerms(req.Question)
+	if len(terms) == 0 || len(terms) > maxHeadingQuestionTerms || len(strings.Join(terms, "")) < 3 {
+		return nil
+	}
+
+	records, err := e.chunkRepo.ListByHeadingTerms(ctx, vaultIDs, terms)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to look up heading matches", "error", err)
+		return nil
+	}
+
+	var matches []rerankCandidate
+	for i := range records {
+		rec := records[i]
+		if headingMatchIndex(rec.HeadingPath, terms) < 0 {
+			continue
+		}
+		if rec.Tier == storage.TierCold && !req.IncludeCold {
+			continue
+		}
+		if len(req.Folders) > 0 && !inRequestFolders(rec, req.Folders) {
+			continue
+		}
+		if len(matches) == maxHeadingMatchChunks {
+			logger.InfoContext(ctx, "heading match capped", "cap", maxHeadingMatchChunks)
+			break
+		}
+		matches = append(matches, rerankCandidate{
+			result: vectorstore.SearchResult{
+				PointID: rec.ID,
+				Meta: map[string]any{
+					"vault_id":     rec.VaultID,
+					"vault_name":   rec.VaultName,
+					"note_id":      rec.NoteID,
+					"rel_path":     rec.RelPath,
+					"folder":       rec.Folder,
+					"heading_path": rec.HeadingPath,
+					"chunk_index":  rec.ChunkIndex,
+					"note_title":   rec.NoteTitle,
+				},
+			},
+			chunk:        &rec.ChunkRecord,
+			vaultName:    rec.VaultName,
+			relPath:      rec.RelPath,
+			headingPath:  rec.HeadingPath,
+			chunkIndex:   rec.ChunkIndex,
+			finalScore:   headingMatchScore,
+			folderWeight: 1.0,
+			headingMatch: true,
+		})
+	}
+
+	if len(matches) > 0 {
+		logger.InfoContext(ctx, "question matches a heading", "chunks", len(matches))
+	}
+	return matches
+}
+
+// inRequestFolders reports whether rec's note lies in one of the request folders, given as
+// "folder", "<vaultID>/folder", or "<vaultName>/folder" (prefix matching, as for search).
+func inRequestFolders(rec storage.ChunkExportRecord, folders []string) bool {
+	for _, folder := range folders {
+		folder = strings.Trim(folder, "/")
+		for _, prefix := range []string{"", fmt.Sprintf("%d/", rec.VaultID), rec.VaultName + "/"} {
+			noteFolder := prefix + rec.Folder
+			if noteFolder == folder || strings.HasPrefix(noteFolder, folder+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withHeadingMatches returns the pass with matches ranked ahead of every other candidate.
+// A match that vector search also found keeps its scores and payload from that result.
+func (p retrievalPass) withHeadingMatches(matches []rerankCandidate) retrievalPass {
+	found := make(map[string]rerankCandidate, len(p.candidates))
+	for _, candidate := range p.candidates {
+		found[candidate.result.PointID] = candidate
+	}
+	matched := make(map[string]bool, len(matches))
+	for i, match := range matches {
+		if candidate, ok := found[match.result.PointID]; ok {
+			candidate.finalScore = headingMatchScore
+			candidate.headingMatch = true
+			matches[i] = candidate
+		}
+		matched[match.result.PointID] = true
+	}
+
+	matchesFirst := func(candidates []rerankCandidate) []rerankCandidate {
+		ranked := append([]rerankCandidate{}, matches...)
+		for _, candidate := range candidates {
+			if !matched[candidate.result.PointID] {
+				ranked = append(ranked, candidate)
+			}
+		}
+		return ranked
+	}
+	inResults := make(map[string]bool, len(p.deduplicated))
+	for _, result := range p.deduplicated {
+		inResults[result.PointID] = true
+	}
+	deduplicated := make([]vectorstore.SearchResult, 0, len(p.deduplicated)+len(matches))
+	for _, match := range matches {
+		if !inResults[match.result.PointID] {
+			deduplicated = append(deduplicated, match.result)
+		}
+	}
+
+	return retrievalPass{
+		deduplicated:   append(deduplicated, p.deduplicated...),
+		candidates:     matchesFirst(p.candidates),
+		filtered:       matchesFirst(p.filtered),
+		headingMatches: len(matches),
+		minFinalScore:  p.minFinalScore,
+	}
+}
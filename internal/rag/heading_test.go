@@ -0,0 +1,135 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestHeadingMatchIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		question    string
+		headingPath string
+		want        int
+	}{
+		{name: "exact heading", question: "Designing a HashMap", headingPath: "Hash Tables > Designing a HashMap", want: 1},
+		{name: "case and punctuation", question: "designing a hashmap?", headingPath: "# Hash Tables > ## Designing a HashMap", want: 1},
+		{name: "subheading chunk", question: "Designing a HashMap", headingPath: "Hash Tables > Designing a HashMap > Resizing", want: 1},
+		{name: "top-level heading", question: "Hash Tables", headingPath: "Hash Tables > Collisions", want: 0},
+		{name: "partial heading", question: "HashMap", headingPath: "Hash Tables > Designing a HashMap", want: -1},
+		{name: "question around heading", question: "How do I design a HashMap?", headingPath: "Hash Tables > Designing a HashMap", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headingMatchIndex(tt.headingPath, headingTerms(tt.question)); got != tt.want {
+				t.Errorf("headingMatchIndex(%q, %q) = %d, want %d", tt.headingPath, tt.question, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHeadingMatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	record := func(id, vaultName, folder, headingPath, tier string) storage.ChunkExportRecord {
+		return storage.ChunkExportRecord{
+			ChunkRecord: storage.ChunkRecord{ID: id, NoteID: "note-" + id, HeadingPath: headingPath, Text: "text " + id},
+			VaultID:     1,
+			VaultName:   vaultName,
+			RelPath:     folder + "/hash-tables.md",
+			Folder:      folder,
+			Tier:        tier,
+		}
+	}
+	records := []storage.ChunkExportRecord{
+		record("section", "personal", "Software/DSA", "Hash Tables > Designing a HashMap", storage.TierHot),
+		record("subsection", "personal", "Software/DSA", "Hash Tables > Designing a HashMap > Resizing", storage.TierHot),
+		record("similar", "personal", "Software/DSA", "Hash Tables > Designing a HashMap in Go", storage.TierHot),
+		record("archived", "personal", "Archive", "Designing a HashMap", storage.TierCold),
+	}
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	mockChunkRepo.EXPECT().ListByHeadingTerms(gomock.Any(), []int{1}, []string{"designing", "a", "hashmap"}).Return(records, nil).AnyTimes()
+	engine := &ragEngine{chunkRepo: mockChunkRepo}
+
+	tests := []struct {
+		name    string
+		req     AskRequest
+		wantIDs []string
+	}{
+		{name: "hot chunks under the heading", req: AskRequest{Question: "Designing a HashMap?"}, wantIDs: []string{"section", "subsection"}},
+		{name: "cold notes on opt-in", req: AskRequest{Question: "Designing a HashMap", IncludeCold: true}, wantIDs: []string{"section", "subsection", "archived"}},
+		{name: "folder filter", req: AskRequest{Question: "Designing a HashMap", Folders: []string{"personal/Software"}}, wantIDs: []string{"section", "subsection"}},
+		{name: "folder filter excludes", req: AskRequest{Question: "Designing a HashMap", Folders: []string{"Work"}}},
+		{name: "long question skips lookup", req: AskRequest{Question: "What are the steps for designing a HashMap with open addressing in Go?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := engine.findHeadingMatches(context.Background(), tt.req, []int{1})
+			var gotIDs []string
+			for _, match := range matches {
+				gotIDs = append(gotIDs, match.result.PointID)
+				if !match.headingMatch || match.finalScore != headingMatchScore || match.chunk.Text == "" {
+					t.Errorf("match %s = %+v, want a heading match with its chunk text", match.result.PointID, match)
+				}
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("findHeadingMatches() IDs = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestRetrievalPassWithHeadingMatches(t *testing.T) {
+	vector := func(id string, score float32) rerankCandidate {
+		return rerankCandidate{
+			result:      vectorstore.SearchResult{PointID: id, Score: score, Meta: map[string]any{"run_id": "run-" + id}},
+			chunk:       &storage.ChunkRecord{ID: id},
+			vectorScore: score,
+			finalScore:  score,
+		}
+	}
+	top, found, weak := vector("top", 0.9), vector("found", 0.6), vector("weak", 0.35)
+	pass := retrievalPass{
+		deduplicated: []vectorstore.SearchResult{top.result, found.result, weak.result},
+		candidates:   []rerankCandidate{top, found, weak},
+		filtered:     []rerankCandidate{top, found},
+	}
+	matches := []rerankCandidate{
+		{result: vectorstore.SearchResult{PointID: "found"}, chunk: &storage.ChunkRecord{ID: "found"}, finalScore: headingMatchScore, headingMatch: true},
+		{result: vectorstore.SearchResult{PointID: "new"}, chunk: &storage.ChunkRecord{ID: "new"}, finalScore: headingMatchScore, headingMatch: true},
+	}
+
+	got := pass.withHeadingMatches(matches)
+
+	ids := func(candidates []rerankCandidate) string {
+		var out []string
+		for _, c := range candidates {
+			out = append(out, c.result.PointID)
+		}
+		return fmt.Sprint(out)
+	}
+	if ids(got.filtered) != "[found new top]" {
+		t.Errorf("filtered = %s, want heading matches first", ids(got.filtered))
+	}
+	if ids(got.candidates) != "[found new top weak]" {
+		t.Errorf("candidates = %s, want heading matches first", ids(got.candidates))
+	}
+	if got.headingMatches != 2 {
+		t.Errorf("headingMatches = %d, want 2", got.headingMatches)
+	}
+	if len(got.deduplicated) != 4 || got.deduplicated[0].PointID != "new" {
+		t.Errorf("deduplicated = %v, want the new match added once", got.deduplicated)
+	}
+	// The vector hit keeps its score and payload
+	if first := got.filtered[0]; first.vectorScore != 0.6 || first.finalScore != headingMatchScore || first.result.Meta["run_id"] != "run-found" || !first.explanation().HeadingExactMatch {
+		t.Errorf("matched vector hit = %+v, want vector score and payload kept", first)
+	}
+}
@@ -0,0 +1,53 @@
+package rag
+
+import (
+	"context"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// ChunkHydrator recovers a chunk from its source note on disk when SQLite has lost its
+// row or text. *indexer.Pipeline implements it.
+type ChunkHydrator interface {
+	HydrateChunk(ctx context.Context, vaultID int, relPath, chunkID string) (*storage.ChunkRecord, error)
+}
+
+// hydrateChunk recovers the chunk behind a search result from disk. Returns nil when no
+// hydrator is configured or the chunk cannot be recovered, leaving the caller to fall back
+// to the vector metadata.
+func (e *ragEngine) hydrateChunk(ctx context.Context, result vectorstore.SearchResult) *storage.ChunkRecord {
+	if e.hydrator == nil {
+		return nil
+	}
+	relPath, _ := result.Meta["rel_path"].(string)
+	vaultID, ok := metaInt(result.Meta["vault_id"])
+	if !ok || relPath == "" {
+		return nil
+	}
+
+	chunk, err := e.hydrator.HydrateChunk(ctx, vaultID, relPath, result.PointID)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to hydrate chunk from source note",
+			"chunk_id", result.PointID,
+			"rel_path", relPath,
+			"error", err)
+		return nil
+	}
+	return chunk
+}
+
+// metaInt reads an integer payload value. Qdrant returns int64 or float64 depending on how
+// the value was stored; the in-memory store keeps the original int.
+func metaInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
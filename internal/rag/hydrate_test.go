@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// fakeHydrator recovers chunks from a fixed map keyed by chunk ID.
+type fakeHydrator struct {
+	chunks  map[string]*storage.ChunkRecord
+	vaultID int
+	relPath string
+}
+
+func (f *fakeHydrator) HydrateChunk(_ context.Context, vaultID int, relPath, chunkID string) (*storage.ChunkRecord, error) {
+	f.vaultID, f.relPath = vaultID, relPath
+	if chunk, ok := f.chunks[chunkID]; ok {
+		return chunk, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func TestRagEngine_HydrateChunk(t *testing.T) {
+	hydrator := &fakeHydrator{chunks: map[string]*storage.ChunkRecord{
+		"chunk-1": {ID: "chunk-1", Text: "Tomatoes grow in the north bed."},
+	}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		engine   *ragEngine
+		result   vectorstore.SearchResult
+		wantText string
+	}{
+		{
+			name:     "qdrant integer payload",
+			engine:   &ragEngine{hydrator: hydrator},
+			result:   vectorstore.SearchResult{PointID: "chunk-1", Meta: map[string]any{"vault_id": int64(2), "rel_path": "garden.md"}},
+			wantText: "Tomatoes grow in the north bed.",
+		},
+		{
+			name:     "in-memory payload",
+			engine:   &ragEngine{hydrator: hydrator},
+			result:   vectorstore.SearchResult{PointID: "chunk-1", Meta: map[string]any{"vault_id": 2, "rel_path": "garden.md"}},
+			wantText: "Tomatoes grow in the north bed.",
+		},
+		{
+			name:   "not recoverable",
+			engine: &ragEngine{hydrator: hydrator},
+			result: vectorstore.SearchResult{PointID: "chunk-2", Meta: map[string]any{"vault_id": 2, "rel_path": "garden.md"}},
+		},
+		{
+			name:   "missing vault id",
+			engine: &ragEngine{hydrator: hydrator},
+			result: vectorstore.SearchResult{PointID: "chunk-1", Meta: map[string]any{"rel_path": "garden.md"}},
+		},
+		{
+			name:   "no hydrator",
+			engine: &ragEngine{},
+			result: vectorstore.SearchResult{PointID: "chunk-1", Meta: map[string]any{"vault_id": 2, "rel_path": "garden.md"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk := tt.engine.hydrateChunk(ctx, tt.result)
+			if tt.wantText == "" {
+				if chunk != nil {
+					t.Errorf("hydrateChunk() = %+v, want nil", chunk)
+				}
+				return
+			}
+			if chunk == nil || chunk.Text != tt.wantText {
+				t.Fatalf("hydrateChunk() = %+v, want text %q", chunk, tt.wantText)
+			}
+			if hydrator.vaultID != 2 || hydrator.relPath != "garden.md" {
+				t.Errorf("hydrator called with vault %d, %q, want 2, garden.md", hydrator.vaultID, hydrator.relPath)
+			}
+		})
+	}
+}
+
+func TestNewEngineByName_Hydrator(t *testing.T) {
+	hydrator := &fakeHydrator{}
+	engine, err := NewEngineByName(EngineLLM, EngineDeps{Hydrator: hydrator})
+	if err != nil {
+		t.Fatalf("NewEngineByName() error = %v", err)
+	}
+	if got := engine.(*ragEngine).hydrator; got != hydrator {
+		t.Errorf("hydrator = %v, want the configured hydrator", got)
+	}
+}
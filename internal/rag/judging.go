@@ -0,0 +1,168 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/judge"
+	"helloworld-ai/internal/tracing"
+)
+
+// AnswerJudge scores a generated answer against the sources it was generated from.
+// *judge.Judge implements it.
+type AnswerJudge interface {
+	Score(ctx context.Context, question, answer string, sources []judge.Source) (judge.Verdict, error)
+}
+
+// JudgeOptions configures judging of generated answers.
+type JudgeOptions struct {
+	// Judge scores each generated answer for faithfulness and relevance (nil disables judging).
+	Judge AnswerJudge
+	// FaithfulnessThreshold regenerates answers the judge scores as less faithful than this
+	// (0 only reports the scores).
+	FaithfulnessThreshold float32
+	// MaxRegenerations caps how many times an unfaithful answer is regenerated.
+	MaxRegenerations int
+	// AbstainThresholds abstains with unsupported_answer in place of answers whose final
+	// verdict scores a criterion below its threshold (0-1, by criterion; judge.Rubric.AbstainBelow).
+	AbstainThresholds map[string]float32
+}
+
+// abstainCriterion returns the first criterion, by name, that verdict scores below its
+// abstain threshold. Criteria the verdict has no score for never abstain.
+func (o JudgeOptions) abstainCriterion(verdict judge.Verdict) (string, bool) {
+	for _, name := range slices.Sorted(maps.Keys(o.AbstainThresholds)) {
+		if score, ok := verdict.Scores[name]; ok && score < o.AbstainThresholds[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// answerDraft is a generated answer with its verdict, kept while regenerating so the most
+// faithful answer can be restored.
+type answerDraft struct {
+	answer        string
+	citedChunkIDs []string
+	prompt        *CapturedPrompt
+	promptTokens  *PromptTokens
+	suggestions   []string
+	verdict       judge.Verdict
+}
+
+// draft captures the generated answer held by the state.
+func (s *askState) draft(verdict judge.Verdict) answerDraft {
+	return answerDraft{
+		answer:        s.answer,
+		citedChunkIDs: s.citedChunkIDs,
+		prompt:        s.prompt,
+		promptTokens:  s.promptTokens,
+		suggestions:   s.suggestions,
+		verdict:       verdict,
+	}
+}
+
+// restore puts a captured answer back into the state.
+func (s *askState) restore(d answerDraft) {
+	s.answer = d.answer
+	s.citedChunkIDs = d.citedChunkIDs
+	s.prompt = d.prompt
+	s.promptTokens = d.promptTokens
+	s.suggestions = d.suggestions
+}
+
+// judgeStage scores the generated answer for faithfulness to the selected chunks and
+// relevance to the question. Below the faithfulness threshold the answer is generated again,
+// told that it made unsupported claims, up to MaxRegenerations times, and the most faithful
+// answer is kept. An answer that still scores below an abstain threshold is replaced with an
+// unsupported_answer abstention. Streamed answers have already reached the client, so they
+// are only scored. A failed judgement keeps the answer as it is.
+func (e *ragEngine) judgeStage(ctx context.Context, s *askState) error {
+	if e.judging.Judge == nil || e.extractive || s.answer == "" {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+	threshold := e.judging.FaithfulnessThreshold
+
+	verdict, err := e.judgeAnswer(ctx, s)
+	if err != nil {
+		logger.WarnContext(ctx, "answer judging failed, keeping the answer", "error", err)
+		return nil
+	}
+	best := s.draft(verdict)
+	judgement := &AnswerJudgement{Threshold: threshold, Attempts: 1, PromptVersion: verdict.RubricVersion}
+
+	for best.verdict.Faithfulness < threshold && judgement.Attempts <= e.judging.MaxRegenerations && s.onToken == nil {
+		logger.InfoContext(ctx, "answer below faithfulness threshold, regenerating",
+			"faithfulness", best.verdict.Faithfulness,
+			"threshold", threshold,
+			"reason", best.verdict.Reason,
+		)
+		s.regenerationReason = best.verdict.Reason
+		if err := e.generateStage(ctx, s); err != nil {
+			logger.WarnContext(ctx, "answer regeneration failed, keeping the previous answer", "error", err)
+			break
+		}
+		judgement.Attempts++
+		verdict, err := e.judgeAnswer(ctx, s)
+		if err != nil {
+			logger.WarnContext(ctx, "judging the regenerated answer failed, keeping the previous answer", "error", err)
+			break
+		}
+		if verdict.Faithfulness > best.verdict.Faithfulness {
+			best = s.draft(verdict)
+			judgement.Regenerated = true
+		}
+	}
+	s.restore(best)
+	s.regenerationReason = ""
+
+	judgement.Faithfulness = best.verdict.Faithfulness
+	judgement.Relevance = best.verdict.Relevance
+	judgement.Scores = best.verdict.Scores
+	judgement.Reason = best.verdict.Reason
+	s.judgement = judgement
+	logger.InfoContext(ctx, "answer judged",
+		"faithfulness", judgement.Faithfulness,
+		"relevance", judgement.Relevance,
+		"attempts", judgement.Attempts,
+		"regenerated", judgement.Regenerated,
+	)
+
+	if criterion, ok := e.judging.abstainCriterion(best.verdict); ok && s.onToken == nil {
+		logger.InfoContext(ctx, "answer below abstain threshold, abstaining",
+			"criterion", criterion,
+			"score", best.verdict.Scores[criterion],
+			"threshold", e.judging.AbstainThresholds[criterion],
+		)
+		judgement.Abstained = true
+		e.abstainWithReason(ctx, s, s.retrieval.candidates, "unsupported_answer",
+			"Your notes mention related topics, but I couldn't write an answer they support.")
+	}
+	return nil
+}
+
+// judgeAnswer scores the state's answer against its chunks within the generation timeout,
+// adding the time spent to the judge latency.
+func (e *ragEngine) judgeAnswer(ctx context.Context, s *askState) (judge.Verdict, error) {
+	sources := make([]judge.Source, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		sources = append(sources, judge.Source{
+			Label: fmt.Sprintf("%s/%s (%s)", chunk.vaultName, chunk.relPath, chunk.headingPath),
+			Text:  chunk.text,
+		})
+	}
+
+	judgeCtx, judgeSpan := tracing.Start(ctx, "rag.judge", attribute.Int("chunks", len(sources)))
+	judgeCtx, cancel := withTimeout(judgeCtx, e.timeouts.Generation)
+	defer cancel()
+	verdict, err := e.judging.Judge.Score(judgeCtx, s.req.Question, s.answer, sources)
+	judgeSpan.RecordError(err)
+	s.judgeMs += judgeSpan.End().Milliseconds()
+	return verdict, err
+}
@@ -0,0 +1,145 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/judge"
+	"helloworld-ai/internal/llm"
+)
+
+// sequenceChat answers each call with the next of its replies and records the system prompts.
+type sequenceChat struct {
+	replies []string
+	prompts []string
+}
+
+func (c *sequenceChat) ChatWithMessages(_ context.Context, messages []llm.Message, _ llm.ChatParams) (string, error) {
+	c.prompts = append(c.prompts, messages[0].Content)
+	reply := c.replies[0]
+	if len(c.replies) > 1 {
+		c.replies = c.replies[1:]
+	}
+	return reply, nil
+}
+
+// scriptedJudge returns its verdicts in order, or err once they run out.
+type scriptedJudge struct {
+	verdicts []judge.Verdict
+	err      error
+	answers  []string
+}
+
+func (j *scriptedJudge) Score(_ context.Context, _, answer string, _ []judge.Source) (judge.Verdict, error) {
+	j.answers = append(j.answers, answer)
+	if len(j.verdicts) == 0 {
+		return judge.Verdict{}, j.err
+	}
+	verdict := j.verdicts[0]
+	j.verdicts = j.verdicts[1:]
+	return verdict, nil
+}
+
+func TestJudgeStage(t *testing.T) {
+	chunks := []chunkData{{text: "Tomatoes grow in the north bed.", vaultName: "personal", relPath: "garden.md", headingPath: "# Tomatoes"}}
+	newState := func() *askState {
+		return &askState{req: AskRequest{Question: "Where do tomatoes grow?"}, chunks: chunks}
+	}
+	unfaithful := judge.Verdict{Faithfulness: 0.3, Relevance: 1, Reason: "Watering is not in the notes."}
+	faithful := judge.Verdict{Faithfulness: 1, Relevance: 1}
+
+	t.Run("regenerates an unfaithful answer", func(t *testing.T) {
+		chat := &sequenceChat{replies: []string{"In the north bed, watered daily.", "In the north bed."}}
+		answerJudge := &scriptedJudge{verdicts: []judge.Verdict{unfaithful, faithful}}
+		engine := &ragEngine{llmClient: chat, judging: JudgeOptions{Judge: answerJudge, FaithfulnessThreshold: 0.7, MaxRegenerations: 1}}
+		s := newState()
+		if err := engine.generateStage(context.Background(), s); err != nil {
+			t.Fatalf("generateStage() error = %v", err)
+		}
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+
+		if s.answer != "In the north bed." {
+			t.Errorf("answer = %q, want the regenerated answer", s.answer)
+		}
+		if s.judgement == nil || s.judgement.Attempts != 2 || !s.judgement.Regenerated || s.judgement.Faithfulness != 1 {
+			t.Errorf("judgement = %+v, want the regenerated answer's scores after 2 attempts", s.judgement)
+		}
+		if len(chat.prompts) != 2 || !strings.Contains(chat.prompts[1], "Watering is not in the notes.") {
+			t.Errorf("regeneration prompt does not carry the judge's reason: %q", chat.prompts)
+		}
+	})
+
+	t.Run("keeps the more faithful answer", func(t *testing.T) {
+		chat := &sequenceChat{replies: []string{"In the north bed, watered daily.", "Somewhere sunny."}}
+		answerJudge := &scriptedJudge{verdicts: []judge.Verdict{unfaithful, {Faithfulness: 0.1, Relevance: 0.5}}}
+		engine := &ragEngine{llmClient: chat, judging: JudgeOptions{Judge: answerJudge, FaithfulnessThreshold: 0.7, MaxRegenerations: 1}}
+		s := newState()
+		if err := engine.generateStage(context.Background(), s); err != nil {
+			t.Fatalf("generateStage() error = %v", err)
+		}
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+		if s.answer != "In the north bed, watered daily." || s.judgement.Regenerated || s.judgement.Attempts != 2 {
+			t.Errorf("answer = %q, judgement = %+v; want the first answer kept", s.answer, s.judgement)
+		}
+	})
+
+	t.Run("streamed answers are only scored", func(t *testing.T) {
+		answerJudge := &scriptedJudge{verdicts: []judge.Verdict{unfaithful, faithful}}
+		engine := &ragEngine{judging: JudgeOptions{Judge: answerJudge, FaithfulnessThreshold: 0.7, MaxRegenerations: 1}}
+		s := newState()
+		s.answer = "In the north bed, watered daily."
+		s.onToken = func(string) error { return nil }
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+		if len(answerJudge.answers) != 1 || s.judgement == nil || s.judgement.Attempts != 1 || s.judgement.Faithfulness != unfaithful.Faithfulness {
+			t.Errorf("judgement = %+v, want one scoring without regeneration", s.judgement)
+		}
+	})
+
+	t.Run("abstains below an abstain threshold", func(t *testing.T) {
+		incomplete := judge.Verdict{Faithfulness: 1, Scores: map[string]float32{judge.CriterionFaithfulness: 1, "completeness": 0.2}, RubricVersion: "garden-rubric-v1"}
+		engine := &ragEngine{judging: JudgeOptions{Judge: &scriptedJudge{verdicts: []judge.Verdict{incomplete}}, AbstainThresholds: map[string]float32{"completeness": 0.5}}}
+		s := newState()
+		s.answer = "In the north bed."
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+		if s.resp == nil || !s.resp.Abstained || s.resp.AbstainReason != "unsupported_answer" {
+			t.Fatalf("response = %+v, want an unsupported_answer abstention", s.resp)
+		}
+		if !s.judgement.Abstained || s.judgement.Scores["completeness"] != 0.2 || s.judgement.PromptVersion != "garden-rubric-v1" {
+			t.Errorf("judgement = %+v, want the abstaining verdict and its rubric", s.judgement)
+		}
+
+		// A streamed answer has already reached the client
+		engine.judging.Judge = &scriptedJudge{verdicts: []judge.Verdict{incomplete}}
+		s = newState()
+		s.answer = "In the north bed."
+		s.onToken = func(string) error { return nil }
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+		if s.resp != nil || s.judgement.Abstained {
+			t.Errorf("response = %+v, judgement = %+v; want a streamed answer kept", s.resp, s.judgement)
+		}
+	})
+
+	t.Run("judge failure keeps the answer", func(t *testing.T) {
+		engine := &ragEngine{judging: JudgeOptions{Judge: &scriptedJudge{err: errors.New("llm down")}, FaithfulnessThreshold: 0.7}}
+		s := newState()
+		s.answer = "In the north bed."
+		if err := engine.judgeStage(context.Background(), s); err != nil {
+			t.Fatalf("judgeStage() error = %v", err)
+		}
+		if s.answer != "In the north bed." || s.judgement != nil {
+			t.Errorf("answer = %q, judgement = %+v; want the answer unjudged", s.answer, s.judgement)
+		}
+	})
+}
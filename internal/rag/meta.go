@@ -0,0 +1,136 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"helloworld-ai/internal/llm"
+)
+
+// Prompt template versions recorded in ResponseMeta. Bump the version whenever the
+// matching system prompt changes, so answers can be attributed to the prompt that produced them.
+const (
+	// answerPromptVersion covers the answer prompt built in Ask.
+	answerPromptVersion = "answer-v1"
+	// documentPromptVersion covers the section and answer prompts in AskDocument.
+	documentPromptVersion = "document-v1"
+	// customPromptVersion marks answers generated with a request's own system prompt.
+	customPromptVersion = "custom"
+)
+
+// ResponseMeta records what produced an answer: the model, the prompt template version,
+// and a hash of the retrieval configuration. Comparing it across logged queries shows
+// whether a regression followed a model, prompt, or configuration change.
+type ResponseMeta struct {
+	// Model is the chat model that generated the answer (empty without generation).
+	Model string `json:"model,omitempty"`
+	// Quantization is the quantization tag in the model name, e.g. Q4_K_M.
+	Quantization string `json:"quantization,omitempty"`
+	// PromptVersion is the prompt template version (empty without generation).
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// RetrievalConfigHash identifies the retrieval configuration (empty without retrieval).
+	RetrievalConfigHash string `json:"retrieval_config_hash,omitempty"`
+	// ScoreThresholds are the thresholds applied when the request overrode the defaults.
+	ScoreThresholds *ScoreThresholds `json:"score_thresholds,omitempty"`
+	// Retrieval is the retrieval configuration applied when the request's overrides took effect.
+	Retrieval *RetrievalOptions `json:"retrieval,omitempty"`
+}
+
+// logAttr returns the metadata as a "meta" log group.
+func (m ResponseMeta) logAttr() slog.Attr {
+	attrs := []any{
+		"model", m.Model,
+		"quantization", m.Quantization,
+		"prompt_version", m.PromptVersion,
+		"retrieval_config_hash", m.RetrievalConfigHash,
+	}
+	if m.ScoreThresholds != nil {
+		attrs = append(attrs, "min_vector_score", m.ScoreThresholds.Vector, "min_final_score", m.ScoreThresholds.Final)
+	}
+	if m.Retrieval != nil {
+		attrs = append(attrs,
+			"vector_weight", m.Retrieval.VectorWeight,
+			"lexical_weight", m.Retrieval.LexicalWeight,
+			"candidate_k_per_scope", m.Retrieval.CandidateKPerScope,
+			"max_candidates", m.Retrieval.MaxCandidates,
+			"tag_boost", m.Retrieval.TagBoost,
+		)
+	}
+	return slog.Group("meta", attrs...)
+}
+
+// generationMeta returns the model and prompt part of ResponseMeta. The extractive engine
+// generates nothing, so it reports neither.
+func (e *ragEngine) generationMeta(promptVersion string) ResponseMeta {
+	if e.extractive {
+		return ResponseMeta{}
+	}
+	model := e.chatModel()
+	return ResponseMeta{
+		Model:         model,
+		Quantization:  llm.Quantization(model),
+		PromptVersion: promptVersion,
+	}
+}
+
+// chatModel returns the chat model answering requests, which an admin can swap at runtime.
+func (e *ragEngine) chatModel() string {
+	if e.generation.CurrentModel != nil {
+		if model := e.generation.CurrentModel(); model != "" {
+			return model
+		}
+	}
+	return e.generation.Model
+}
+
+// applyOverrides records the model and prompt a request selected in place of the defaults.
+// Responses without generation (the extractive engine) stay empty.
+func (m *ResponseMeta) applyOverrides(overrides GenerationOverrides) {
+	if m.PromptVersion == "" {
+		return
+	}
+	if overrides.Model != "" {
+		m.Model = overrides.Model
+		m.Quantization = llm.Quantization(overrides.Model)
+	}
+	if overrides.SystemPrompt != "" {
+		m.PromptVersion = customPromptVersion
+	}
+}
+
+// retrievalConfigHash returns a short SHA-256 over the settings that shape retrieval:
+// collections, retrieval options, folder selection and note prefilter options, the feature
+// flags in effect (including runtime overrides), the engine mode, the ask pipeline stages,
+// and whether an external reranker is configured. Other ranking constants change only with
+// the code and are not part of it.
+func (e *ragEngine) retrievalConfigHash() string {
+	data, err := json.Marshal(struct {
+		Collection      string
+		ColdCollection  string
+		Retrieval       RetrievalOptions
+		FolderSelection FolderSelectionOptions
+		NotePrefilter   NotePrefilterOptions
+		Features        map[string]bool
+		Extractive      bool
+		Stages          []string
+		Reranker        bool
+	}{
+		Collection:      e.collection,
+		ColdCollection:  e.coldCollection,
+		Retrieval:       e.retrieval.withDefaults(),
+		FolderSelection: e.folderSelection,
+		NotePrefilter:   e.notePrefilter,
+		Features:        e.flags.Snapshot(),
+		Extractive:      e.extractive,
+		Stages:          e.pipelineStages(),
+		Reranker:        e.reranker != nil,
+	})
+	if err != nil {
+		// Only plain values are marshalled, so this cannot happen
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
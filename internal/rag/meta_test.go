@@ -0,0 +1,84 @@
+package rag
+
+import (
+	"testing"
+
+	"helloworld-ai/internal/features"
+)
+
+func TestGenerationMeta(t *testing.T) {
+	engine := &ragEngine{generation: GenerationOptions{Model: "Qwen2.5-3B-Instruct-Q4_K_M"}}
+
+	got := engine.generationMeta(answerPromptVersion)
+	want := ResponseMeta{Model: "Qwen2.5-3B-Instruct-Q4_K_M", Quantization: "Q4_K_M", PromptVersion: answerPromptVersion}
+	if got != want {
+		t.Errorf("generationMeta() = %+v, want %+v", got, want)
+	}
+
+	engine.extractive = true
+	if got := engine.generationMeta(answerPromptVersion); got != (ResponseMeta{}) {
+		t.Errorf("generationMeta() for extractive engine = %+v, want empty", got)
+	}
+}
+
+func TestRetrievalConfigHash(t *testing.T) {
+	flags, err := features.New(nil)
+	if err != nil {
+		t.Fatalf("features.New() error = %v", err)
+	}
+	engine := &ragEngine{collection: "notes", folderSelection: DefaultFolderSelectionOptions, flags: flags}
+
+	hash := engine.retrievalConfigHash()
+	if len(hash) != 12 || engine.retrievalConfigHash() != hash {
+		t.Fatalf("retrievalConfigHash() = %q, want a stable 12-character hash", hash)
+	}
+
+	// Runtime flag overrides change retrieval, so they change the hash
+	if err := flags.Set(features.Reranker, !flags.Enabled(features.Reranker)); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if engine.retrievalConfigHash() == hash {
+		t.Error("retrievalConfigHash() unchanged after a flag override")
+	}
+	if err := flags.Reset(features.Reranker); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if engine.retrievalConfigHash() != hash {
+		t.Error("retrievalConfigHash() differs after the override was reset")
+	}
+
+	// Zero retrieval options are the defaults, so spelling the defaults out keeps the hash
+	engine.retrieval = DefaultRetrievalOptions
+	if engine.retrievalConfigHash() != hash {
+		t.Error("retrievalConfigHash() differs with the default retrieval options spelled out")
+	}
+	engine.retrieval.CandidateKPerScope = 30
+	if engine.retrievalConfigHash() == hash {
+		t.Error("retrievalConfigHash() unchanged after changing the retrieval options")
+	}
+	engine.retrieval = RetrievalOptions{}
+
+	engine.notePrefilter = NotePrefilterOptions{Collection: "notes_notes", TopM: 5}
+	if engine.retrievalConfigHash() == hash {
+		t.Error("retrievalConfigHash() unchanged after enabling the note prefilter")
+	}
+}
+
+func TestResponseMeta_ApplyOverrides(t *testing.T) {
+	engine := &ragEngine{generation: GenerationOptions{Model: "Qwen2.5-3B-Instruct-Q4_K_M"}}
+
+	meta := engine.generationMeta(answerPromptVersion)
+	meta.applyOverrides(GenerationOverrides{Model: "Llama-3.1-8B-Instruct-Q8_0", SystemPrompt: "Answer in French."})
+	want := ResponseMeta{Model: "Llama-3.1-8B-Instruct-Q8_0", Quantization: "Q8_0", PromptVersion: customPromptVersion}
+	if meta != want {
+		t.Errorf("applyOverrides() = %+v, want %+v", meta, want)
+	}
+
+	// Answers without generation have nothing to attribute
+	engine.extractive = true
+	meta = engine.generationMeta(answerPromptVersion)
+	meta.applyOverrides(GenerationOverrides{Model: "Llama-3.1-8B-Instruct-Q8_0"})
+	if meta != (ResponseMeta{}) {
+		t.Errorf("applyOverrides() for extractive engine = %+v, want empty", meta)
+	}
+}
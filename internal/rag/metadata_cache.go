@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// IndexEpochSource reports the index epoch, which advances whenever indexing changes the
+// notes. *indexer.Pipeline implements it.
+type IndexEpochSource interface {
+	IndexEpoch() uint64
+}
+
+// metadataCache holds the vault list and folder stats Ask uses to scope a question until
+// the index epoch advances, so concurrent questions do not each query SQLite for them.
+// Loads happen under the lock, so questions arriving together wait for one load.
+// A failed refresh serves the previous snapshot, keeping Ask working while SQLite is busy
+// (e.g. during migrations).
+type metadataCache struct {
+	source IndexEpochSource
+
+	mu sync.Mutex
+	// vaults holds the vault list under the empty key.
+	vaults *cache.Cache[string, vaultSnapshot]
+	// folders is keyed by the sorted vault IDs.
+	folders *cache.Cache[string, folderSnapshot]
+}
+
+// vaultSnapshot is the vault list as of an index epoch.
+type vaultSnapshot struct {
+	epoch  uint64
+	vaults []storage.VaultRecord
+}
+
+// folderSnapshot is the folder stats of a set of vaults as of an index epoch.
+type folderSnapshot struct {
+	epoch uint64
+	stats []storage.FolderStat
+	total int
+}
+
+// maxFolderSnapshots bounds the folder stats cached per distinct vault selection.
+const maxFolderSnapshots = 64
+
+// newMetadataCache returns a cache driven by source, or nil when source is nil.
+func newMetadataCache(source IndexEpochSource) *metadataCache {
+	if source == nil {
+		return nil
+	}
+	return &metadataCache{
+		source:  source,
+		vaults:  cache.New[string, vaultSnapshot](CacheVaults, cache.Options{}),
+		folders: cache.New[string, folderSnapshot](CacheFolders, cache.Options{Size: maxFolderSnapshots}),
+	}
+}
+
+// listVaults returns all vaults, from the cache when the index has not changed since they were loaded.
+// The returned slice is shared and must not be modified.
+func (e *ragEngine) listVaults(ctx context.Context) ([]storage.VaultRecord, error) {
+	c := e.metadata
+	if c == nil {
+		return e.vaultRepo.ListAll(ctx)
+	}
+	epoch := c.source.IndexEpoch()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.vaults.GetValid("", func(s vaultSnapshot) bool { return s.epoch == epoch }); ok {
+		return cached.vaults, nil
+	}
+
+	vaults, err := e.vaultRepo.ListAll(ctx)
+	if err != nil {
+		cached, ok := c.vaults.Peek("")
+		if !ok {
+			return nil, err
+		}
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to refresh vault list, using previous snapshot", "error", err)
+		return cached.vaults, nil
+	}
+	c.vaults.Set("", vaultSnapshot{epoch: epoch, vaults: vaults})
+	return vaults, nil
+}
+
+// listFolderStats returns the folder stats of vaultIDs for folder selection, from the cache
+// when the index has not changed since they were loaded. The returned slice is shared and
+// must not be modified.
+func (e *ragEngine) listFolderStats(ctx context.Context, vaultIDs []int) ([]storage.FolderStat, int, error) {
+	opts := storage.FolderListOptions{MaxDepth: e.folderSelection.MaxDepth}
+	c := e.metadata
+	if c == nil {
+		return e.noteRepo.ListFolderStats(ctx, vaultIDs, opts)
+	}
+	key := fmt.Sprint(slices.Sorted(slices.Values(vaultIDs)))
+	epoch := c.source.IndexEpoch()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.folders.GetValid(key, func(s folderSnapshot) bool { return s.epoch == epoch }); ok {
+		return cached.stats, cached.total, nil
+	}
+
+	stats, total, err := e.noteRepo.ListFolderStats(ctx, vaultIDs, opts)
+	if err != nil {
+		cached, ok := c.folders.Peek(key)
+		if !ok {
+			return nil, 0, err
+		}
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to refresh folder list, using previous snapshot", "error", err)
+		return cached.stats, cached.total, nil
+	}
+	c.folders.Set(key, folderSnapshot{epoch: epoch, stats: stats, total: total})
+	return stats, total, nil
+}
@@ -0,0 +1,125 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+// fakeEpoch is an IndexEpochSource the test advances by hand.
+type fakeEpoch struct {
+	mu    sync.Mutex
+	epoch uint64
+}
+
+func (f *fakeEpoch) IndexEpoch() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.epoch
+}
+
+func (f *fakeEpoch) advance() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.epoch++
+}
+
+func TestMetadataCache_Vaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	epoch := &fakeEpoch{}
+	engine := &ragEngine{vaultRepo: mockVaultRepo, metadata: newMetadataCache(epoch)}
+	ctx := context.Background()
+
+	first := []storage.VaultRecord{{ID: 1, Name: "personal"}}
+	second := []storage.VaultRecord{{ID: 1, Name: "personal"}, {ID: 2, Name: "work"}}
+	gomock.InOrder(
+		mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return(first, nil).Times(1),
+		mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return(second, nil).Times(1),
+		mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return(nil, errors.New("database is locked")).Times(1),
+	)
+
+	// Concurrent questions in one epoch share a single load
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if vaults, err := engine.listVaults(ctx); err != nil || len(vaults) != 1 {
+				t.Errorf("listVaults() = %v, %v, want the first snapshot", vaults, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	epoch.advance()
+	if vaults, err := engine.listVaults(ctx); err != nil || len(vaults) != 2 {
+		t.Errorf("listVaults() after reindex = %v, %v, want the refreshed list", vaults, err)
+	}
+
+	// A failed refresh serves the previous snapshot
+	epoch.advance()
+	if vaults, err := engine.listVaults(ctx); err != nil || len(vaults) != 2 {
+		t.Errorf("listVaults() with failing SQLite = %v, %v, want the previous snapshot", vaults, err)
+	}
+}
+
+func TestMetadataCache_FolderStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockNoteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	epoch := &fakeEpoch{}
+	engine := &ragEngine{
+		noteRepo:        mockNoteRepo,
+		folderSelection: FolderSelectionOptions{MaxDepth: 2},
+		metadata:        newMetadataCache(epoch),
+	}
+	ctx := context.Background()
+	opts := storage.FolderListOptions{MaxDepth: 2}
+
+	both := []storage.FolderStat{{Path: "1/garden", VaultID: 1}, {Path: "2/projects", VaultID: 2}}
+	work := []storage.FolderStat{{Path: "2/projects", VaultID: 2}}
+	mockNoteRepo.EXPECT().ListFolderStats(gomock.Any(), []int{2, 1}, opts).Return(both, 2, nil).Times(1)
+	mockNoteRepo.EXPECT().ListFolderStats(gomock.Any(), []int{2}, opts).Return(work, 1, nil).Times(1)
+
+	// Vault sets are cached independently of their order
+	for _, vaultIDs := range [][]int{{2, 1}, {1, 2}} {
+		if stats, total, err := engine.listFolderStats(ctx, vaultIDs); err != nil || total != 2 || len(stats) != 2 {
+			t.Errorf("listFolderStats(%v) = %v, %d, %v, want both vaults", vaultIDs, stats, total, err)
+		}
+	}
+	if stats, total, err := engine.listFolderStats(ctx, []int{2}); err != nil || total != 1 || stats[0].Path != "2/projects" {
+		t.Errorf("listFolderStats([2]) = %v, %d, %v, want the work vault", stats, total, err)
+	}
+
+	// Without a previous snapshot a failed load is returned
+	epoch.advance()
+	mockNoteRepo.EXPECT().ListFolderStats(gomock.Any(), []int{3}, opts).Return(nil, 0, errors.New("database is locked"))
+	if _, _, err := engine.listFolderStats(ctx, []int{3}); err == nil {
+		t.Error("listFolderStats() error = nil, want the load error")
+	}
+}
+
+func TestMetadataCache_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	engine := &ragEngine{vaultRepo: mockVaultRepo, metadata: newMetadataCache(nil)}
+
+	mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return(nil, nil).Times(2)
+	for range 2 {
+		if _, err := engine.listVaults(context.Background()); err != nil {
+			t.Fatalf("listVaults() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package rag
+
+import (
+	"context"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// NeighborOptions configures neighbor expansion, which widens each selected chunk with the
+// chunks before and after it in its note before generation.
+type NeighborOptions struct {
+	// TokenBudget bounds the estimated tokens of neighbor text added to the context across
+	// all selected chunks (0 disables expansion).
+	TokenBudget int
+}
+
+// neighborRunesPerToken approximates the token count of neighbor text (4 runes per token, as
+// the indexer estimates chunk tokens).
+const neighborRunesPerToken = 4
+
+// neighborKey identifies a chunk by its note and position.
+type neighborKey struct {
+	noteID string
+	index  int
+}
+
+// neighborsStage merges the chunks at chunk_index-1 and +1 of each selected chunk's note into
+// its text, so a chunk cut mid-thought reaches the model with the text around it. Chunks are
+// expanded in rank order until the token budget is spent; a neighbor that is selected itself
+// or already merged into another chunk is skipped. Best effort: a note whose chunks cannot
+// be listed keeps its chunks as selected.
+func (e *ragEngine) neighborsStage(ctx context.Context, s *askState) error {
+	budget := e.neighbors.TokenBudget * neighborRunesPerToken
+	if budget <= 0 || len(s.chunks) == 0 {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	used := make(map[neighborKey]struct{}, len(s.chunks))
+	for _, chunk := range s.chunks {
+		if chunk.noteID != "" {
+			used[neighborKey{chunk.noteID, chunk.chunkIndex}] = struct{}{}
+		}
+	}
+
+	notes := make(map[string]map[int]storage.ChunkRecord)
+	// prepended marks the chunks whose previous chunk was merged in front of them
+	prepended := make(map[neighborKey]bool)
+	added, spent := 0, 0
+	for i := range s.chunks {
+		chunk := &s.chunks[i]
+		if chunk.noteID == "" {
+			continue
+		}
+		noteChunks, ok := notes[chunk.noteID]
+		if !ok {
+			records, err := e.chunkRepo.ListByNote(ctx, chunk.noteID)
+			if err != nil {
+				logger.WarnContext(ctx, "failed to list note chunks for neighbor expansion", "note_id", chunk.noteID, "error", err)
+			}
+			noteChunks = make(map[int]storage.ChunkRecord, len(records))
+			for _, record := range records {
+				noteChunks[record.ChunkIndex] = record
+			}
+			notes[chunk.noteID] = noteChunks
+		}
+
+		if prev, ok := noteChunks[chunk.chunkIndex-1]; ok {
+			key := neighborKey{chunk.noteID, prev.ChunkIndex}
+			if _, dup := used[key]; !dup && prev.Text != "" {
+				if runes := len([]rune(prev.Text)); spent+runes <= budget {
+					// The chunk's leading overlap repeats the end of prev
+					chunk.text = prev.Text + "\n" + trimLeadingRunes(chunk.text, chunk.overlapRunes)
+					spent += runes
+					used[key] = struct{}{}
+					prepended[neighborKey{chunk.noteID, chunk.chunkIndex}] = true
+					added++
+				}
+			}
+		}
+
+		if next, ok := noteChunks[chunk.chunkIndex+1]; ok {
+			key := neighborKey{chunk.noteID, next.ChunkIndex}
+			if _, dup := used[key]; !dup && next.Text != "" {
+				// next's leading overlap repeats the end of the chunk
+				text := trimLeadingRunes(next.Text, next.OverlapRunes)
+				if runes := len([]rune(text)); runes > 0 && spent+runes <= budget {
+					chunk.text = chunk.text + "\n" + text
+					spent += runes
+					used[key] = struct{}{}
+					added++
+				}
+			}
+		}
+	}
+
+	// A selected chunk whose previous chunk was merged into another selected chunk still
+	// starts with the text it repeats from that chunk
+	for i := range s.chunks {
+		chunk := &s.chunks[i]
+		key := neighborKey{chunk.noteID, chunk.chunkIndex}
+		if chunk.noteID == "" || prepended[key] {
+			continue
+		}
+		if _, ok := used[neighborKey{chunk.noteID, chunk.chunkIndex - 1}]; ok && !s.hasChunk(chunk.noteID, chunk.chunkIndex-1) {
+			chunk.text = trimLeadingRunes(chunk.text, chunk.overlapRunes)
+		}
+	}
+
+	if added > 0 {
+		logger.InfoContext(ctx, "selected chunks expanded with neighbors",
+			"neighbors_added", added,
+			"estimated_tokens", (spent+neighborRunesPerToken-1)/neighborRunesPerToken,
+			"token_budget", e.neighbors.TokenBudget,
+		)
+	}
+	return nil
+}
+
+// hasChunk reports whether the chunk at index of note is one of the selected chunks.
+func (s *askState) hasChunk(noteID string, index int) bool {
+	for _, chunk := range s.chunks {
+		if chunk.noteID == noteID && chunk.chunkIndex == index {
+			return true
+		}
+	}
+	return false
+}
+
+// trimLeadingRunes drops the first n runes of text, keeping text whole when n would remove
+// all of it.
+func trimLeadingRunes(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if n >= len(runes) {
+		return text
+	}
+	return string(runes[n:])
+}
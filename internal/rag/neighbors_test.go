@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestNeighborsStage(t *testing.T) {
+	note := []storage.ChunkRecord{
+		{NoteID: "garden", ChunkIndex: 0, Text: "Soil prep in March."},
+		{NoteID: "garden", ChunkIndex: 1, Text: "March.\n\nPlanted in May.", OverlapRunes: 8},
+		{NoteID: "garden", ChunkIndex: 2, Text: "May.\n\nHarvested in July.", OverlapRunes: 6},
+		{NoteID: "garden", ChunkIndex: 3, Text: "July.\n\nComposted in fall.", OverlapRunes: 7},
+		{NoteID: "garden", ChunkIndex: 4, Text: "Covered the beds."},
+	}
+	newState := func() *askState {
+		// Chunks 1, 3, and 4 are selected; chunk 2 is between two of them
+		return &askState{chunks: []chunkData{
+			{noteID: "garden", chunkIndex: 1, text: "March.\n\nPlanted in May.", overlapRunes: 8},
+			{noteID: "garden", chunkIndex: 3, text: "July.\n\nComposted in fall.", overlapRunes: 7},
+			{noteID: "garden", chunkIndex: 4, text: "Covered the beds."},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		budget int
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			budget: 0,
+			want:   []string{"March.\n\nPlanted in May.", "July.\n\nComposted in fall.", "Covered the beds."},
+		},
+		{
+			// Chunk 2 is merged into chunk 1 only, and chunk 3 drops the text it repeats from it
+			name:   "neighbors merged once",
+			budget: 100,
+			want: []string{
+				"Soil prep in March.\nPlanted in May.\nHarvested in July.",
+				"Composted in fall.",
+				"Covered the beds.",
+			},
+		},
+		{
+			// 19 runes of chunk 0 fit in a 5-token budget; chunk 2 does not fit after it
+			name:   "budget spent in rank order",
+			budget: 5,
+			want:   []string{"Soil prep in March.\nPlanted in May.", "July.\n\nComposted in fall.", "Covered the beds."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+			mockChunkRepo.EXPECT().ListByNote(gomock.Any(), "garden").Return(note, nil).MaxTimes(1)
+			engine := &ragEngine{chunkRepo: mockChunkRepo, neighbors: NeighborOptions{TokenBudget: tt.budget}}
+
+			s := newState()
+			if err := engine.neighborsStage(context.Background(), s); err != nil {
+				t.Fatalf("neighborsStage() error = %v", err)
+			}
+			for i, chunk := range s.chunks {
+				if chunk.text != tt.want[i] {
+					t.Errorf("chunks[%d].text = %q, want %q", i, chunk.text, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNeighborsStage_ListFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	mockChunkRepo.EXPECT().ListByNote(gomock.Any(), "garden").Return(nil, errors.New("database locked"))
+	engine := &ragEngine{chunkRepo: mockChunkRepo, neighbors: NeighborOptions{TokenBudget: 100}}
+
+	s := &askState{chunks: []chunkData{{noteID: "garden", chunkIndex: 1, text: "Planted in May."}}}
+	if err := engine.neighborsStage(context.Background(), s); err != nil {
+		t.Fatalf("neighborsStage() error = %v", err)
+	}
+	if s.chunks[0].text != "Planted in May." {
+		t.Errorf("chunks[0].text = %q, want it unchanged", s.chunks[0].text)
+	}
+}
@@ -0,0 +1,140 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"helloworld-ai/internal/storage"
+)
+
+// questionDateLayout is the date format of the before: and after: operators.
+const questionDateLayout = "2006-01-02"
+
+// questionOperatorPattern matches an inline filter operator preceded by whitespace or the
+// start of the question. Values with spaces are quoted: folder:"Daily Notes".
+var questionOperatorPattern = regexp.MustCompile(`(?i)(^|\s)(vault|folder|tag|before|after):("[^"]*"|\S+)`)
+
+// QuestionOperators are the filters written inline in a question.
+type QuestionOperators struct {
+	// Vaults are the vault: values.
+	Vaults []string
+	// Folders are the folder: values, without leading or trailing slashes.
+	Folders []string
+	// Tags are the tag: values, lowercased and without the leading '#'.
+	Tags []string
+	// Before is the earliest before: date (zero if none).
+	Before time.Time
+	// After is the latest after: date (zero if none).
+	After time.Time
+}
+
+// ParseQuestionOperators removes inline filter operators from question and returns the
+// remaining question with the filters they set:
+//
+//	vault:work  folder:Projects/  tag:#golang  before:2024-01-01  after:2023-06-01
+//
+// Operators may repeat. Dates are YYYY-MM-DD in UTC; before: excludes its day and after:
+// includes it. Words that only look like operators (no value, unknown key) are left alone.
+func ParseQuestionOperators(question string) (string, QuestionOperators, error) {
+	if !questionOperatorPattern.MatchString(question) {
+		return question, QuestionOperators{}, nil
+	}
+
+	var ops QuestionOperators
+	var parseErr error
+
+	remaining := questionOperatorPattern.ReplaceAllStringFunc(question, func(match string) string {
+		groups := questionOperatorPattern.FindStringSubmatch(match)
+		key, value := strings.ToLower(groups[2]), strings.Trim(groups[3], `"`)
+		switch key {
+		case "vault":
+			if value != "" {
+				ops.Vaults = appendUnique(ops.Vaults, value)
+			}
+		case "folder":
+			if value = strings.Trim(value, "/"); value != "" {
+				ops.Folders = appendUnique(ops.Folders, value)
+			}
+		case "tag":
+			if value = strings.ToLower(strings.Trim(strings.TrimPrefix(value, "#"), "/")); value != "" {
+				ops.Tags = appendUnique(ops.Tags, value)
+			}
+		case "before", "after":
+			date, err := time.Parse(questionDateLayout, value)
+			if err != nil {
+				if parseErr == nil {
+					parseErr = fmt.Errorf("invalid %s: date %q (must be YYYY-MM-DD)", key, value)
+				}
+				break
+			}
+			if key == "before" && (ops.Before.IsZero() || date.Before(ops.Before)) {
+				ops.Before = date
+			}
+			if key == "after" && date.After(ops.After) {
+				ops.After = date
+			}
+		}
+		return groups[1]
+	})
+	if parseErr != nil {
+		return "", QuestionOperators{}, parseErr
+	}
+	return strings.Join(strings.Fields(remaining), " "), ops, nil
+}
+
+// appendUnique appends value to values unless it is already present.
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// noteFilter returns the tag and date filters of the request, and whether it has any.
+func (r AskRequest) noteFilter() (storage.NoteFilter, bool) {
+	filter := storage.NoteFilter{
+		Tags:          r.Tags,
+		UpdatedBefore: r.UpdatedBefore,
+		UpdatedAfter:  r.UpdatedAfter,
+		CreatedBefore: r.CreatedBefore,
+		CreatedAfter:  r.CreatedAfter,
+	}
+	return filter, len(r.Tags) > 0 || !r.UpdatedBefore.IsZero() || !r.UpdatedAfter.IsZero() ||
+		!r.CreatedBefore.IsZero() || !r.CreatedAfter.IsZero()
+}
+
+// withinScope restricts the prefiltered notes to scope, the notes matching the request's
+// tag and date filters (nil when it has none). When none of the prefiltered notes are in
+// scope, the whole scope is searched.
+func withinScope(noteIDs, scope []string) []string {
+	if len(scope) == 0 {
+		return noteIDs
+	}
+	var kept []string
+	for _, noteID := range noteIDs {
+		if slices.Contains(scope, noteID) {
+			kept = append(kept, noteID)
+		}
+	}
+	if len(kept) == 0 {
+		return scope
+	}
+	return kept
+}
+
+// inNoteScope drops the candidates whose notes are outside scope (nil keeps all).
+func inNoteScope(candidates []rerankCandidate, scope []string) []rerankCandidate {
+	if len(scope) == 0 {
+		return candidates
+	}
+	var kept []rerankCandidate
+	for _, candidate := range candidates {
+		if noteID, _ := candidate.result.Meta["note_id"].(string); slices.Contains(scope, noteID) {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
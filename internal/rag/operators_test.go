@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestParseQuestionOperators(t *testing.T) {
+	date := func(value string) time.Time {
+		d, _ := time.Parse(questionDateLayout, value)
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		question string
+		want     string
+		wantOps  QuestionOperators
+		wantErr  bool
+	}{
+		{
+			name:     "no operators",
+			question: "What did I plan\nfor the garden?",
+			want:     "What did I plan\nfor the garden?",
+		},
+		{
+			name:     "all operators",
+			question: "vault:work folder:Projects/ How do goroutines leak? tag:#golang before:2024-01-01 after:2023-06-01",
+			want:     "How do goroutines leak?",
+			wantOps: QuestionOperators{
+				Vaults:  []string{"work"},
+				Folders: []string{"Projects"},
+				Tags:    []string{"golang"},
+				Before:  date("2024-01-01"),
+				After:   date("2023-06-01"),
+			},
+		},
+		{
+			name:     "repeated operators",
+			question: "tag:Golang tag:#testing tag:golang before:2024-05-01 before:2024-01-01 after:2023-01-01 after:2023-06-01 testing tips",
+			want:     "testing tips",
+			wantOps: QuestionOperators{
+				Tags:   []string{"golang", "testing"},
+				Before: date("2024-01-01"),
+				After:  date("2023-06-01"),
+			},
+		},
+		{
+			name:     "quoted folder and case-insensitive keys",
+			question: `FOLDER:"Daily Notes/" what happened?`,
+			want:     "what happened?",
+			wantOps:  QuestionOperators{Folders: []string{"Daily Notes"}},
+		},
+		{
+			name:     "look-alikes are kept",
+			question: "Is http://example.com/a:b in note:x or folder: set?",
+			want:     "Is http://example.com/a:b in note:x or folder: set?",
+		},
+		{
+			name:     "invalid date",
+			question: "before:yesterday what happened?",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ops, err := ParseQuestionOperators(tt.question)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuestionOperators() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("question = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(ops.Vaults, tt.wantOps.Vaults) || !slices.Equal(ops.Folders, tt.wantOps.Folders) || !slices.Equal(ops.Tags, tt.wantOps.Tags) ||
+				!ops.Before.Equal(tt.wantOps.Before) || !ops.After.Equal(tt.wantOps.After) {
+				t.Errorf("operators = %+v, want %+v", ops, tt.wantOps)
+			}
+		})
+	}
+}
+
+func TestWithinScope(t *testing.T) {
+	if got := withinScope([]string{"a", "b"}, nil); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("withinScope() without scope = %v, want the prefiltered notes", got)
+	}
+	if got := withinScope([]string{"a", "b"}, []string{"b", "c"}); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("withinScope() = %v, want [b]", got)
+	}
+	if got := withinScope([]string{"a"}, []string{"b", "c"}); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("withinScope() without overlap = %v, want the whole scope", got)
+	}
+	if got := withinScope(nil, []string{"b", "c"}); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("withinScope() without prefilter = %v, want the whole scope", got)
+	}
+
+	candidates := []rerankCandidate{
+		{result: vectorstore.SearchResult{PointID: "1", Meta: map[string]any{"note_id": "a"}}},
+		{result: vectorstore.SearchResult{PointID: "2", Meta: map[string]any{"note_id": "b"}}},
+	}
+	if got := inNoteScope(candidates, []string{"b"}); len(got) != 1 || got[0].result.PointID != "2" {
+		t.Errorf("inNoteScope() = %+v, want only the candidate of note b", got)
+	}
+	if got := inNoteScope(candidates, nil); len(got) != 2 {
+		t.Errorf("inNoteScope() without scope kept %d candidates, want 2", len(got))
+	}
+}
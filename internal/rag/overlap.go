@@ -0,0 +1,33 @@
+package rag
+
+// trimChunkOverlaps drops the text a chunk repeats from the previous chunk of its note when
+// that chunk is in the context too, so overlapping chunks are not sent to the LLM twice.
+// Returns the number of chunks trimmed.
+func trimChunkOverlaps(chunks []chunkData) int {
+	type chunkKey struct {
+		vaultName string
+		relPath   string
+		index     int
+	}
+	present := make(map[chunkKey]struct{}, len(chunks))
+	for _, chunk := range chunks {
+		present[chunkKey{chunk.vaultName, chunk.relPath, chunk.chunkIndex}] = struct{}{}
+	}
+
+	trimmed := 0
+	for i, chunk := range chunks {
+		if chunk.overlapRunes <= 0 {
+			continue
+		}
+		if _, ok := present[chunkKey{chunk.vaultName, chunk.relPath, chunk.chunkIndex - 1}]; !ok {
+			continue
+		}
+		runes := []rune(chunk.text)
+		if chunk.overlapRunes >= len(runes) {
+			continue
+		}
+		chunks[i].text = string(runes[chunk.overlapRunes:])
+		trimmed++
+	}
+	return trimmed
+}
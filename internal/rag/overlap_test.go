@@ -0,0 +1,28 @@
+package rag
+
+import "testing"
+
+func TestTrimChunkOverlaps(t *testing.T) {
+	chunks := []chunkData{
+		{vaultName: "personal", relPath: "garden.md", chunkIndex: 0, text: "Planted in May."},
+		{vaultName: "personal", relPath: "garden.md", chunkIndex: 1, text: "Planted in May.\n\nHarvested in July.", overlapRunes: 17},
+		{vaultName: "personal", relPath: "garden.md", chunkIndex: 3, text: "Watered daily.\n\nNew bed in spring.", overlapRunes: 16},
+		{vaultName: "work", relPath: "garden.md", chunkIndex: 1, text: "Planted in May.\n\nBudget approved.", overlapRunes: 17},
+	}
+
+	if trimmed := trimChunkOverlaps(chunks); trimmed != 1 {
+		t.Errorf("trimChunkOverlaps() = %d, want 1", trimmed)
+	}
+	want := []string{
+		"Planted in May.",
+		"Harvested in July.",
+		// The previous chunk is not in the context, so its repeated text is kept
+		"Watered daily.\n\nNew bed in spring.",
+		"Planted in May.\n\nBudget approved.",
+	}
+	for i, chunk := range chunks {
+		if chunk.text != want[i] {
+			t.Errorf("chunks[%d].text = %q, want %q", i, chunk.text, want[i])
+		}
+	}
+}
@@ -0,0 +1,897 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/tracing"
+)
+
+// Ask pipeline stage names (RAG_STAGES). DefaultStages lists them in their default order.
+const (
+	// StageScope embeds the question, resolves vaults and note filters, picks K, and selects folders.
+	StageScope = "scope"
+	// StageRetrieve runs the note prefilter and the vector search over the selected scope.
+	StageRetrieve = "retrieve"
+	// StageRerank combines vector and lexical scores (reranker flag).
+	StageRerank = "rerank"
+	// StageExpand retries a weak retrieval with a larger K and relaxed scope (retrieval_expansion flag).
+	StageExpand = "expand"
+	// StageHeadings ranks chunks under a heading named by the question first (heading_match flag).
+	StageHeadings = "headings"
+	// StageSelect abstains when nothing relevant was found and otherwise keeps the top chunks.
+	StageSelect = "select"
+	// StageAnswerability abstains when an LLM judge scores the selected chunks below the
+	// answerability threshold (ANSWERABILITY_THRESHOLD).
+	StageAnswerability = "answerability"
+	// StageNeighbors merges the chunks before and after each selected chunk in its note into
+	// the context, within a token budget (CONTEXT_NEIGHBOR_TOKENS).
+	StageNeighbors = "neighbors"
+	// StageGenerate produces the answer from the selected chunks.
+	StageGenerate = "generate"
+	// StageJudge scores the answer's faithfulness and relevance with an LLM judge and
+	// regenerates unfaithful answers (ANSWER_JUDGE).
+	StageJudge = "judge"
+	// StageVerify keeps only the chunks the answer cites as references.
+	StageVerify = "verify"
+)
+
+// askStage is one step of the ask pipeline.
+type askStage struct {
+	// required stages cannot be left out of the pipeline.
+	required bool
+	// after lists the stages that must run earlier when both are in the pipeline.
+	after []string
+	// run reads and fills the ask state. Setting state.resp ends the pipeline early.
+	run func(e *ragEngine, ctx context.Context, s *askState) error
+}
+
+// askStages registers the ask pipeline stages by name. To add a stage, register it here
+// and, if it should run by default, add it to defaultStages.
+var askStages = map[string]askStage{
+	StageScope:    {required: true, run: (*ragEngine).scopeStage},
+	StageRetrieve: {required: true, after: []string{StageScope}, run: (*ragEngine).retrieveStage},
+	StageRerank:   {after: []string{StageRetrieve}, run: (*ragEngine).rerankStage},
+	StageExpand:   {after: []string{StageRetrieve}, run: (*ragEngine).expandStage},
+	// Reranking or replacing the pass afterwards would drop the heading matches from the top
+	StageHeadings:      {after: []string{StageRetrieve, StageRerank, StageExpand}, run: (*ragEngine).headingsStage},
+	StageSelect:        {required: true, after: []string{StageRetrieve, StageRerank, StageExpand, StageHeadings}, run: (*ragEngine).selectStage},
+	StageAnswerability: {after: []string{StageSelect}, run: (*ragEngine).answerabilityStage},
+	StageNeighbors:     {after: []string{StageSelect}, run: (*ragEngine).neighborsStage},
+	StageGenerate:      {required: true, after: []string{StageSelect, StageAnswerability, StageNeighbors}, run: (*ragEngine).generateStage},
+	StageJudge:         {after: []string{StageGenerate}, run: (*ragEngine).judgeStage},
+	StageVerify:        {after: []string{StageGenerate, StageJudge}, run: (*ragEngine).verifyStage},
+}
+
+// defaultStages is the pipeline used when no stages are configured.
+var defaultStages = []string{
+	StageScope,
+	StageRetrieve,
+	StageRerank,
+	StageExpand,
+	StageHeadings,
+	StageSelect,
+	StageAnswerability,
+	StageNeighbors,
+	StageGenerate,
+	StageJudge,
+	StageVerify,
+}
+
+// DefaultStages returns the default ask pipeline.
+func DefaultStages() []string {
+	return slices.Clone(defaultStages)
+}
+
+// StageNames returns the names of the available ask pipeline stages, sorted.
+func StageNames() []string {
+	names := make([]string, 0, len(askStages))
+	for name := range askStages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateStages checks an ask pipeline: every stage must be known and listed once, required
+// stages must be present, and each stage must come after the stages it depends on.
+// An empty pipeline is valid and selects DefaultStages.
+func ValidateStages(stages []string) error {
+	position := make(map[string]int, len(stages))
+	for i, name := range stages {
+		if _, ok := askStages[name]; !ok {
+			return fmt.Errorf("unknown RAG stage %q (available: %s)", name, strings.Join(StageNames(), ", "))
+		}
+		if _, dup := position[name]; dup {
+			return fmt.Errorf("RAG stage %q is listed more than once", name)
+		}
+		position[name] = i
+	}
+	if len(stages) == 0 {
+		return nil
+	}
+
+	for _, name := range StageNames() {
+		stage := askStages[name]
+		at, ok := position[name]
+		if !ok {
+			if stage.required {
+				return fmt.Errorf("RAG stage %q is required", name)
+			}
+			continue
+		}
+		for _, dep := range stage.after {
+			if depAt, ok := position[dep]; ok && depAt > at {
+				return fmt.Errorf("RAG stage %q must come after %q", name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// askState carries one question through the ask pipeline.
+type askState struct {
+	req     AskRequest
+	onToken func(token string) error
+	// retrievalOnly skips LLM folder ranking; Search ends the pipeline after select
+	retrievalOnly bool
+	// span covers the whole ask; its elapsed time is the total in the latency breakdown
+	span *tracing.Span
+
+	// Filled by scope
+	queryVector       []float32
+	embeddingCached   bool
+	vaultIDs          []int
+	vaultNames        map[int]string
+	scopeNoteIDs      []string
+	targetK           int
+	availableFolders  []string
+	folderRetrievals  map[string]int
+	orderedFolders    []string
+	folderMethod      string
+	folderSelectionMs int64
+
+	// Filled by retrieve, rerank, expand, and headings
+	retrievalSpan *tracing.Span
+	thresholds    ScoreThresholds
+	noteIDs       []string
+	notePrefilter *NotePrefilter
+	retrieval     retrievalPass
+	reranked      bool
+	expansion     *RetrievalExpansion
+
+	// Filled by select
+	selected []rerankCandidate
+	chunks   []chunkData
+
+	// Filled by answerability
+	answerability *AnswerabilityCheck
+
+	// Filled by judge
+	judgement *AnswerJudgement
+	judgeMs   int64
+
+	// Filled by generate and verify
+	retrievalMs  int64
+	generationMs int64
+	answer       string
+	// truncated is set when the streamed answer was cut off by the generation timeout
+	truncated bool
+	// regenerationReason is the judge's reason for regenerating the answer (empty on the
+	// first generation)
+	regenerationReason string
+	// citedChunkIDs are the chunk IDs cited by a structured answer (nil: parse the
+	// citations from the answer text)
+	citedChunkIDs []string
+	references    []Reference
+	toolResults   []ToolResult
+	conflicts     []Conflict
+	promptTokens  *PromptTokens
+	prompt        *CapturedPrompt
+	suggestions   []string
+
+	// resp ends the pipeline with this response when set (abstentions).
+	resp *AskResponse
+}
+
+// endRetrieval ends the retrieval span and records its duration for the latency breakdown.
+func (s *askState) endRetrieval() {
+	if s.retrievalSpan != nil {
+		s.retrievalMs = s.retrievalSpan.End().Milliseconds()
+	}
+}
+
+// pipelineStages returns the configured ask pipeline, or DefaultStages.
+func (e *ragEngine) pipelineStages() []string {
+	if len(e.stages) == 0 {
+		return defaultStages
+	}
+	return e.stages
+}
+
+// scopeStage embeds the question, resolves the vaults and note filters to search, chooses K,
+// and orders the folders to search.
+func (e *ragEngine) scopeStage(ctx context.Context, s *askState) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	req := s.req
+
+	// Embed the question (repeated questions are served from the cache)
+	queryVector, embeddingCached, err := e.embedQuestion(ctx, req.Question)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to embed question", "error", err)
+		return fmt.Errorf("%w: failed to embed question: %w", ErrEmbeddingUnavailable, err)
+	}
+	s.queryVector = queryVector
+	s.embeddingCached = embeddingCached
+
+	// Get all vaults to resolve names to IDs
+	allVaults, err := e.listVaults(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list vaults", "error", err)
+		return fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	// Requests with a user's API token only see the vaults that user may read; other vaults
+	// named in the request are dropped like unknown ones
+	_, limited := contextutil.AllowedVaults(ctx)
+	if limited {
+		allVaults = slices.DeleteFunc(slices.Clone(allVaults), func(v storage.VaultRecord) bool {
+			return !contextutil.VaultAllowed(ctx, v.ID)
+		})
+	}
+
+	// Build map of vault name to ID
+	vaultMap := make(map[string]int)
+	for _, vault := range allVaults {
+		vaultMap[vault.Name] = vault.ID
+	}
+
+	// Resolve vault names to IDs - if no vaults specified, use all vaults
+	if len(req.Vaults) > 0 {
+		// Collect vault IDs for requested vaults
+		for _, vaultName := range req.Vaults {
+			if vaultID, ok := vaultMap[vaultName]; ok {
+				s.vaultIDs = append(s.vaultIDs, vaultID)
+			} else {
+				logger.WarnContext(ctx, "unknown vault name", "vault", vaultName)
+				// Continue with other vaults
+			}
+		}
+	} else {
+		// No vaults specified - search all vaults
+		for _, vault := range allVaults {
+			s.vaultIDs = append(s.vaultIDs, vault.ID)
+		}
+	}
+	// Downstream, no vault IDs would mean every vault
+	if limited && len(s.vaultIDs) == 0 {
+		logger.InfoContext(ctx, "no permitted vaults to search")
+		e.abstain(ctx, s, []rerankCandidate{})
+		return nil
+	}
+
+	// Tag and date filters scope retrieval to the matching notes; expansion never widens it
+	if filter, ok := req.noteFilter(); ok {
+		s.scopeNoteIDs, err = e.noteRepo.ListIDsByFilter(ctx, s.vaultIDs, filter)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to filter notes", "error", err)
+			return fmt.Errorf("failed to filter notes: %w", err)
+		}
+		logger.InfoContext(ctx, "note filters applied",
+			"tags", req.Tags,
+			"updated_before", req.UpdatedBefore,
+			"updated_after", req.UpdatedAfter,
+			"created_before", req.CreatedBefore,
+			"created_after", req.CreatedAfter,
+			"matching_notes", len(s.scopeNoteIDs),
+		)
+		if len(s.scopeNoteIDs) == 0 {
+			s.resp = &AskResponse{
+				Answer:        "No notes match the tag and date filters of this question.",
+				References:    []Reference{},
+				Abstained:     true,
+				AbstainReason: "no_relevant_context",
+			}
+			return nil
+		}
+	}
+
+	autoK := determineAutoK(req.Question, req.Folders, req.Detail)
+	userHintK := clampUserProvidedK(req.K)
+	s.targetK = autoK
+	kSource := "auto"
+	if userHintK > 0 {
+		s.targetK = userHintK
+		kSource = "user_override"
+	}
+
+	logger.InfoContext(ctx, "k selection completed",
+		"auto_k", autoK,
+		"user_hint_k", userHintK,
+		"target_k", s.targetK,
+		"detail_hint", req.Detail,
+		"k_source", kSource,
+	)
+
+	// Get folders for selected vaults, collapsed and capped so large vaults keep the prompt small
+	s.availableFolders = []string{} // Empty list means search all folders
+	folderStats, totalFolders, err := e.listFolderStats(ctx, s.vaultIDs)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to list folders, searching all folders", "error", err)
+	} else {
+		s.availableFolders = sampleFolders(folderStats, e.folderSelection.MaxFolders)
+		s.folderRetrievals = make(map[string]int, len(folderStats))
+		for _, stat := range folderStats {
+			s.folderRetrievals[stat.Path] = stat.Retrievals
+		}
+		if len(s.availableFolders) < totalFolders {
+			logger.InfoContext(ctx, "sampled folders for selection",
+				"total_folders", totalFolders,
+				"offered_folders", len(s.availableFolders),
+				"max_depth", e.folderSelection.MaxDepth,
+			)
+		}
+	}
+
+	// Build map of vault ID to name for folder conversion
+	s.vaultNames = make(map[int]string)
+	for _, vault := range allVaults {
+		s.vaultNames[vault.ID] = vault.Name
+	}
+
+	// Select relevant folders using LLM (searches without generation keep to the user's folders)
+	folderCtx, folderSpan := tracing.Start(ctx, "rag.folder_selection", attribute.Int("folders.available", len(s.availableFolders)))
+	if s.retrievalOnly {
+		s.orderedFolders, s.folderMethod = matchUserFolders(s.availableFolders, req.Folders, s.vaultNames), folderMethodUser
+	} else {
+		s.orderedFolders, s.folderMethod = e.selectRelevantFolders(folderCtx, req.Question, s.queryVector, s.availableFolders, req.Folders, s.vaultIDs, s.vaultNames, s.folderRetrievals)
+	}
+	s.folderSelectionMs = folderSpan.End().Milliseconds()
+
+	logger.InfoContext(ctx, "folder selection completed",
+		"available_folders", len(s.availableFolders),
+		"ordered_folders", len(s.orderedFolders),
+		"user_folders", len(req.Folders),
+		"method", s.folderMethod,
+	)
+	logger.DebugContext(ctx, "final ordered folder list",
+		"ordered_folders", s.orderedFolders,
+		"available_folders", s.availableFolders,
+	)
+	return nil
+}
+
+// retrieveStage searches the vector store over the selected scope.
+func (e *ragEngine) retrieveStage(ctx context.Context, s *askState) error {
+	// Track retrieval time (vector search + reranking); the span ends once the context is built
+	_, s.retrievalSpan = tracing.Start(ctx, "rag.retrieval")
+
+	// Two-stage retrieval: pick candidate notes by centroid similarity before searching chunks
+	noteIDs, notePrefilter := e.prefilterNotes(ctx, s.req, s.queryVector, s.vaultIDs, s.vaultNames)
+	s.noteIDs = withinScope(noteIDs, s.scopeNoteIDs)
+	s.notePrefilter = notePrefilter
+
+	s.thresholds = e.scoreThresholds(s.req)
+	s.retrieval = e.searchCandidates(ctx, s.req, s.queryVector, s.vaultIDs, s.orderedFolders, s.noteIDs, e.retrievalOptions(s.req).CandidateKPerScope)
+	if err := s.retrieval.searchErr; err != nil {
+		return fmt.Errorf("%w: %w", ErrVectorStoreUnavailable, err)
+	}
+	if s.notePrefilter != nil {
+		s.notePrefilter.ChunkResults = len(s.retrieval.deduplicated)
+	}
+	return nil
+}
+
+// rerankStage reranks the retrieved candidates with lexical scores. With the reranker flag
+// off, candidates stay ranked by vector score alone.
+func (e *ragEngine) rerankStage(ctx context.Context, s *askState) error {
+	if !e.flags.Enabled(features.Reranker) {
+		return nil
+	}
+	s.retrieval = e.rerank(ctx, s.req, s.retrieval)
+	s.reranked = true
+	return nil
+}
+
+// expandStage gives a weak retrieval one broader pass before the select stage abstains.
+func (e *ragEngine) expandStage(ctx context.Context, s *askState) error {
+	if !e.flags.Enabled(features.RetrievalExpansion) {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	// Folders picked by the LLM may have missed the answer; folders picked by the user are kept
+	expandedFolders := s.orderedFolders
+	folderScopeRelaxed := false
+	if len(s.req.Folders) == 0 && len(s.orderedFolders) > 0 {
+		expandedFolders = nil
+		folderScopeRelaxed = true
+	}
+	// The prefiltered notes may have missed the answer too, so the second pass searches all
+	// notes (all notes matching the tag and date filters, if any)
+	noteScopeRelaxed := len(s.noteIDs) > 0 && len(s.noteIDs) != len(s.scopeNoteIDs)
+	// A larger K alone cannot help when the same scopes returned nothing at all
+	reason := s.retrieval.weakReason()
+	if reason == "" || !(folderScopeRelaxed || noteScopeRelaxed || len(s.retrieval.deduplicated) > 0) {
+		return nil
+	}
+
+	// The expanded pass never searches fewer chunks per scope than the initial pass
+	initialK := e.retrievalOptions(s.req).CandidateKPerScope
+	expandedK := max(expandedCandidateKPerScope, initialK)
+	logger.InfoContext(ctx, "weak initial retrieval, expanding search",
+		"reason", reason,
+		"top_score", s.retrieval.topScore(),
+		"expanded_k_per_scope", expandedK,
+		"folder_scope_relaxed", folderScopeRelaxed,
+		"note_scope_relaxed", noteScopeRelaxed,
+	)
+	// The expanded pass is ranked like the initial pass so their top scores are comparable
+	expanded := e.searchCandidates(ctx, s.req, s.queryVector, s.vaultIDs, expandedFolders, s.scopeNoteIDs, expandedK)
+	if s.reranked {
+		expanded = e.rerank(ctx, s.req, expanded)
+	}
+
+	s.expansion = &RetrievalExpansion{
+		Reason:             reason,
+		InitialCandidateK:  initialK,
+		ExpandedCandidateK: expandedK,
+		FolderScopeRelaxed: folderScopeRelaxed,
+		NoteScopeRelaxed:   noteScopeRelaxed,
+		InitialTopScore:    float64(s.retrieval.topScore()),
+		ExpandedTopScore:   float64(expanded.topScore()),
+	}
+	if expanded.topScore() > s.retrieval.topScore() {
+		s.retrieval = expanded
+		s.expansion.Used = true
+	}
+	logger.InfoContext(ctx, "retrieval expansion completed",
+		"used", s.expansion.Used,
+		"initial_top_score", s.expansion.InitialTopScore,
+		"expanded_top_score", s.expansion.ExpandedTopScore,
+	)
+	return nil
+}
+
+// headingsStage ranks every chunk under a heading named by the question first.
+func (e *ragEngine) headingsStage(ctx context.Context, s *askState) error {
+	if !e.flags.Enabled(features.HeadingMatch) {
+		return nil
+	}
+	if matches := inNoteScope(e.findHeadingMatches(ctx, s.req, s.vaultIDs), s.scopeNoteIDs); len(matches) > 0 {
+		s.retrieval = s.retrieval.withHeadingMatches(matches)
+	}
+	return nil
+}
+
+// selectStage abstains when retrieval found nothing relevant and otherwise keeps the top
+// targetK candidates (capped at rerankKeep) as the answer context.
+func (e *ragEngine) selectStage(ctx context.Context, s *askState) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	retrieval := s.retrieval
+
+	if len(retrieval.deduplicated) == 0 {
+		logger.InfoContext(ctx, "no search results found")
+		e.abstain(ctx, s, []rerankCandidate{})
+		return nil
+	}
+	if len(retrieval.candidates) == 0 {
+		// Debug info still shows what was retrieved from vector store even if chunks couldn't be fetched from DB
+		logger.InfoContext(ctx, "no candidates passed vector threshold after rerank preparation")
+		e.abstain(ctx, s, retrieval.candidates)
+		return nil
+	}
+	if len(retrieval.filtered) == 0 {
+		// Debug info still shows what was retrieved and scored even if it didn't meet the threshold
+		logger.InfoContext(ctx, "no candidates met final score threshold")
+		e.abstain(ctx, s, retrieval.candidates)
+		return nil
+	}
+
+	// Determine final chunk count respecting rerank cap
+	finalCount := s.targetK
+	if finalCount > rerankKeep {
+		finalCount = rerankKeep
+	}
+	if finalCount > len(retrieval.filtered) {
+		finalCount = len(retrieval.filtered)
+	}
+	if finalCount <= 0 {
+		finalCount = len(retrieval.filtered)
+	}
+	// The whole matched section is kept even when K is smaller
+	if finalCount < retrieval.headingMatches {
+		finalCount = retrieval.headingMatches
+	}
+
+	s.selected = retrieval.filtered[:finalCount]
+	e.markRetrieved(ctx, s.selected)
+
+	// Log top candidate scores to aid tuning
+	logPreview := make([]map[string]any, 0, len(s.selected))
+	for i := 0; i < len(s.selected) && i < 5; i++ {
+		candidate := s.selected[i]
+		logPreview = append(logPreview, map[string]any{
+			"rank":          i + 1,
+			"point_id":      candidate.result.PointID,
+			"vector_score":  candidate.vectorScore,
+			"lexical_score": candidate.lexicalScore,
+			"final_score":   candidate.finalScore,
+		})
+	}
+	logger.DebugContext(ctx, "top reranked candidates", "preview", logPreview)
+
+	s.chunks = make([]chunkData, 0, len(s.selected))
+	for rank, candidate := range s.selected {
+		s.chunks = append(s.chunks, chunkData{
+			text:         candidate.chunk.Text,
+			vaultName:    candidate.vaultName,
+			relPath:      candidate.relPath,
+			headingPath:  candidate.headingPath,
+			chunkIndex:   candidate.chunkIndex,
+			noteID:       candidate.chunk.NoteID,
+			overlapRunes: candidate.chunk.OverlapRunes,
+			result:       candidate.result,
+		})
+
+		textPreview := candidate.chunk.Text
+		if len(textPreview) > 100 {
+			textPreview = textPreview[:100] + "..."
+		}
+		logger.DebugContext(ctx, "selected chunk",
+			"rank", rank+1,
+			"final_score", candidate.finalScore,
+			"vector_score", candidate.vectorScore,
+			"lexical_score", candidate.lexicalScore,
+			"vault", candidate.vaultName,
+			"rel_path", candidate.relPath,
+			"heading_path", candidate.headingPath,
+			"chunk_index", candidate.chunkIndex,
+			"text_preview", textPreview,
+			"text_length", len(candidate.chunk.Text),
+		)
+	}
+
+	trimmed := trimChunkOverlaps(s.chunks)
+
+	logger.InfoContext(ctx, "chunks selected after rerank",
+		"total_selected", len(s.chunks),
+		"overlaps_trimmed", trimmed,
+		"requested_k", s.targetK,
+		"rerank_cap", rerankKeep,
+	)
+	return nil
+}
+
+// generateStage answers from the selected chunks with the LLM. The extractive engine returns
+// the chunks themselves, with one reference per chunk.
+func (e *ragEngine) generateStage(ctx context.Context, s *askState) error {
+	logger := contextutil.LoggerFromContext(ctx)
+	req := s.req
+	chunks := s.chunks
+
+	if e.extractive {
+		s.endRetrieval()
+		s.answer = extractiveAnswer(chunks)
+		s.references = chunkReferences(chunks)
+		logger.InfoContext(ctx, "answer built without generation", "chunks_used", len(chunks), "answer_length", len(s.answer))
+		return nil
+	}
+
+	// Streamed tokens go straight to the client, so only complete answers can be JSON
+	structured := e.generation.StructuredCitations && s.onToken == nil
+
+	// Format context string
+	var contextBuilder strings.Builder
+	contextBuilder.WriteString("--- Context from notes ---\n\n")
+
+	for i, chunk := range chunks {
+		if structured {
+			contextBuilder.WriteString(fmt.Sprintf("[Chunk %d] ID: %s\n", i+1, chunk.result.PointID))
+		} else {
+			contextBuilder.WriteString(fmt.Sprintf("[Chunk %d]\n", i+1))
+		}
+		contextBuilder.WriteString(fmt.Sprintf("[Vault: %s] File: %s\n", chunk.vaultName, chunk.relPath))
+		contextBuilder.WriteString(fmt.Sprintf("Section: %s\n", chunk.headingPath))
+		contextBuilder.WriteString(fmt.Sprintf("Content: %s\n\n", chunk.text))
+	}
+
+	contextBuilder.WriteString("--- End Context ---\n")
+	if structured {
+		contextBuilder.WriteString("\nWhen citing sources, list the ID of each chunk you used in citations.")
+	} else {
+		contextBuilder.WriteString("\nWhen citing sources, use the format '[File: filename.md, Section: section name]' matching the exact filename and section name from the context above.")
+	}
+
+	// Run deterministic tools (dates, arithmetic, unit conversions) the model is unreliable at
+	chunkTexts := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunkTexts = append(chunkTexts, chunk.text)
+	}
+	if e.flags.Enabled(features.AnswerTools) {
+		s.toolResults = runTools(req.Question, chunkTexts, time.Now())
+	}
+	if toolSection := formatToolResults(s.toolResults); toolSection != "" {
+		contextBuilder.WriteString("\n\n")
+		contextBuilder.WriteString(toolSection)
+		logger.InfoContext(ctx, "tool results added to context", "tool_results", len(s.toolResults))
+	}
+
+	// Flag dates and numbers that differ across notes so the model reports both
+	if e.flags.Enabled(features.ConflictDetection) {
+		s.conflicts = detectConflicts(chunks)
+	}
+	if conflictSection := formatConflicts(s.conflicts); conflictSection != "" {
+		contextBuilder.WriteString("\n\n")
+		contextBuilder.WriteString(conflictSection)
+		logger.InfoContext(ctx, "conflicting facts added to context", "conflicts", len(s.conflicts))
+	}
+
+	contextString := contextBuilder.String()
+	logger.InfoContext(ctx, "context formatted for LLM",
+		"context_length", len(contextString),
+		"chunks_included", len(chunks),
+	)
+	logger.DebugContext(ctx, "full context being sent to LLM", "context", contextString)
+
+	// Retrieval phase complete (vector search + reranking)
+	s.endRetrieval()
+
+	// Construct LLM messages
+	systemPrompt := "You are a helpful assistant that answers questions based on the provided context from the user's notes. " +
+		"Your primary goal is to provide accurate, complete answers to the question. " +
+		"Answer the question using only the information from the context below. " +
+		"CRITICAL: You MUST cite all major claims and factual statements using the exact format '[File: filename.md, Section: section name]' where the filename and section name match the context provided. " +
+		"Do NOT make any unsupported claims - if information is not in the context, explicitly state that it is not available. " +
+		"If the context doesn't contain enough information to answer the question, say so clearly. " +
+		"REQUIRED: At the END of your answer, you MUST include a 'Citations:' section listing all sources used. " +
+		"Example format:\n" +
+		"Citations:\n" +
+		"[File: Software/LeetCode Tips.md, Section: Golang Tips & Oddities]\n" +
+		"[File: Software/Data Structures & Algorithms/Hash Tables.md, Section: Designing a HashMap]\n" +
+		"Remember: Answer quality comes first, but citations are required for all major claims."
+	if structured {
+		systemPrompt = "You are a helpful assistant that answers questions based on the provided context from the user's notes. " +
+			"Your primary goal is to provide accurate, complete answers to the question. " +
+			"Answer the question using only the information from the context below. " +
+			"Reply with a JSON object: put your answer in \"answer\" and list the chunks that support it in \"citations\" as {\"chunk_id\": \"<ID from the context>\"}. " +
+			"CRITICAL: You MUST cite the chunk behind every major claim and factual statement. " +
+			"Do NOT make any unsupported claims - if information is not in the context, explicitly state that it is not available. " +
+			"If the context doesn't contain enough information to answer the question, say so clearly in the answer and leave citations empty. " +
+			"Remember: Answer quality comes first, but citations are required for all major claims."
+	}
+	if req.Generation.SystemPrompt != "" {
+		systemPrompt = req.Generation.SystemPrompt
+	}
+	if len(s.toolResults) > 0 {
+		systemPrompt += " When a 'Tool results' section is provided, use its values for dates, calculations, and unit conversions instead of computing them yourself."
+	}
+	if len(s.conflicts) > 0 {
+		systemPrompt += " When a 'Conflicting facts' section is provided, do not choose between the values: say that the notes disagree, give each value, and cite both sources."
+	}
+	if s.regenerationReason != "" {
+		systemPrompt += fmt.Sprintf(" A previous answer to this question made claims the context does not support (%s). Answer again, stating only what the context supports.", s.regenerationReason)
+	}
+
+	userMessage := fmt.Sprintf("%s\n\n%s", req.Question, contextString)
+
+	messages := []llm.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userMessage},
+	}
+
+	logger.InfoContext(ctx, "sending request to LLM",
+		"question", contextutil.RedactQuestion(e.questionLogMode, req.Question),
+		"system_prompt_length", len(systemPrompt),
+		"user_message_length", len(userMessage),
+		"total_context_length", len(contextString),
+	)
+	// Preview the redacted question so debug logs honour the question log mode
+	userMessagePreview := fmt.Sprintf("%s\n\n%s", contextutil.RedactQuestion(e.questionLogMode, req.Question), contextString)
+	if len(userMessagePreview) > 500 {
+		userMessagePreview = userMessagePreview[:500] + "..."
+	}
+	logger.DebugContext(ctx, "LLM messages", "system_prompt", systemPrompt, "user_message_preview", userMessagePreview)
+	if req.Debug {
+		s.promptTokens = e.countPromptTokens(ctx, systemPrompt, contextString, req.Question)
+	}
+
+	// Call LLM, timing the generation phase
+	params := req.Generation.apply(e.generation.apply(llm.ChatParams{
+		Model:       "",  // Use default from client
+		MaxTokens:   0,   // No limit
+		Temperature: 0.3, // Lower temperature for more focused, citation-aware responses with less hallucination
+	}))
+	if structured {
+		params.ResponseFormat = structuredAnswerFormat(chunks)
+	}
+	generationCtx, generationSpan := tracing.Start(ctx, "rag.generation", attribute.Int("chunks", len(chunks)))
+	answer, truncated, err := e.generate(generationCtx, messages, params, s.onToken)
+	generationSpan.RecordError(err)
+	s.generationMs += generationSpan.End().Milliseconds()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get LLM response", "error", err)
+		return fmt.Errorf("%w: %w", ErrLLMFailure, err)
+	}
+	if truncated {
+		logger.WarnContext(ctx, "answer generation timed out, returning the partial answer", "timeout", e.timeouts.Generation, "answer_length", len(answer))
+		s.truncated = true
+	}
+
+	logger.InfoContext(ctx, "received LLM response", "answer_length", len(answer))
+	logger.DebugContext(ctx, "LLM answer", "answer", answer)
+
+	s.answer = answer
+	if structured {
+		if parsed, err := parseStructuredAnswer(answer); err != nil {
+			// The chat server ignored response_format; look for bracket citations instead
+			logger.WarnContext(ctx, "failed to parse structured answer, parsing citations from the text",
+				"error", err, "answer_preview", truncateString(answer, 200))
+		} else {
+			s.answer = parsed.Answer
+			s.citedChunkIDs = parsed.chunkIDs()
+		}
+	}
+	if req.Debug && req.CapturePrompt {
+		s.prompt = &CapturedPrompt{System: systemPrompt, User: userMessage, Output: answer}
+	}
+
+	if e.flags.Enabled(features.FollowUps) {
+		s.suggestions = e.suggestFollowUps(ctx, req.Question, s.answer, chunks)
+	}
+	return nil
+}
+
+// verifyStage matches the citations in a generated answer to the selected chunks, so only
+// the cited chunks become references. Answers without matching citations keep every chunk.
+func (e *ragEngine) verifyStage(ctx context.Context, s *askState) error {
+	// Extractive answers already carry exact references
+	if s.references != nil {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+	answer := s.answer
+	chunks := s.chunks
+
+	// Structured answers cite chunks by ID, so no text matching is needed
+	if s.citedChunkIDs != nil {
+		cited, unmatched := citedChunks(s.citedChunkIDs, chunks)
+		for _, id := range unmatched {
+			logger.WarnContext(ctx, "cited chunk ID not in context", "chunk_id", id)
+		}
+		if len(cited) == 0 {
+			logger.InfoContext(ctx, "structured answer cited no chunks, falling back to all chunks",
+				"chunks_available", len(chunks))
+			s.references = chunkReferences(chunks)
+			return nil
+		}
+		logger.InfoContext(ctx, "matched structured citations",
+			"citations_found", len(s.citedChunkIDs),
+			"references_matched", len(cited),
+			"total_chunks", len(chunks))
+		s.references = chunkReferences(cited)
+		return nil
+	}
+
+	// Extract citations from answer and build references from only cited chunks
+	references := e.extractCitationsFromAnswer(ctx, answer, chunks)
+	if len(references) == 0 {
+		// Check if answer contains any citation-like patterns (even if not in expected format)
+		hasCitationPatterns := false
+		citationPatterns := []string{"[File:", "[file:", "File:", "file:", "Section:", "section:"}
+		answerLower := strings.ToLower(answer)
+		for _, pattern := range citationPatterns {
+			if strings.Contains(answerLower, strings.ToLower(pattern)) {
+				hasCitationPatterns = true
+				break
+			}
+		}
+
+		if hasCitationPatterns {
+			// Answer contains citation-like patterns but extraction failed
+			logger.WarnContext(ctx, "citation patterns detected but extraction failed, falling back to all chunks",
+				"answer_length", len(answer),
+				"chunks_available", len(chunks),
+				"answer_preview", truncateString(answer, 200))
+		} else {
+			// No citation patterns at all
+			logger.InfoContext(ctx, "no citations found in answer, falling back to all chunks",
+				"answer_length", len(answer),
+				"chunks_available", len(chunks))
+		}
+
+		// Fallback: include all chunks (backward compatibility)
+		references = chunkReferences(chunks)
+	} else {
+		logger.InfoContext(ctx, "extracted citations from answer",
+			"citations_found", len(references),
+			"total_chunks", len(chunks))
+	}
+	s.references = references
+	return nil
+}
+
+// finishAnswer builds the response once every stage has run.
+func (e *ragEngine) finishAnswer(ctx context.Context, s *askState) AskResponse {
+	references := s.references
+	if references == nil {
+		// Without the verify stage every selected chunk is a reference
+		references = chunkReferences(s.chunks)
+	}
+	e.addReferencePositions(ctx, references, s.chunks)
+	e.recordShown(ctx, references)
+
+	contextutil.LoggerFromContext(ctx).InfoContext(ctx, "RAG query completed",
+		"question_length", len(s.req.Question),
+		"chunks_used", len(s.chunks),
+		"answer_length", len(s.answer),
+	)
+
+	resp := AskResponse{
+		Answer:      s.answer,
+		References:  references,
+		Suggestions: s.suggestions,
+		Truncated:   s.truncated,
+	}
+	if s.req.Debug {
+		resp.Debug = e.askDebugInfo(ctx, s, s.retrieval.candidates, s.selected)
+	}
+	return resp
+}
+
+// abstain ends the pipeline without an answer because no relevant context was found.
+// Debug info reports the given candidates.
+func (e *ragEngine) abstain(ctx context.Context, s *askState, candidates []rerankCandidate) {
+	e.abstainWithReason(ctx, s, candidates, "no_relevant_context",
+		"I couldn't find any relevant information in your notes to answer this question.")
+}
+
+// abstainWithReason ends the pipeline with answer in place of a generated one.
+// Debug info reports the given candidates.
+func (e *ragEngine) abstainWithReason(ctx context.Context, s *askState, candidates []rerankCandidate, reason, answer string) {
+	resp := AskResponse{
+		Answer:        answer,
+		References:    []Reference{},
+		Abstained:     true,
+		AbstainReason: reason,
+	}
+	// Retrieval completed but no generation happened
+	s.endRetrieval()
+	if s.req.Debug {
+		resp.Debug = e.askDebugInfo(ctx, s, candidates, []rerankCandidate{})
+	}
+	s.resp = &resp
+}
+
+// askDebugInfo builds the debug info for a response from the pipeline state.
+func (e *ragEngine) askDebugInfo(ctx context.Context, s *askState, candidates, selected []rerankCandidate) *DebugInfo {
+	maxDebugChunks := s.targetK * 2
+	if maxDebugChunks > 50 {
+		maxDebugChunks = 50
+	}
+	totalMs := s.span.Elapsed().Milliseconds()
+	debugInfo := e.buildDebugInfo(ctx, s.retrieval.deduplicated, candidates, selected, s.orderedFolders, s.availableFolders, s.vaultNames, maxDebugChunks, s.folderSelectionMs, s.retrievalMs, s.generationMs, totalMs)
+	debugInfo.FolderSelection.Method = s.folderMethod
+	debugInfo.RetrievalExpansion = s.expansion
+	debugInfo.NotePrefilter = s.notePrefilter
+	debugInfo.QuestionEmbeddingCached = s.embeddingCached
+	debugInfo.ScoreThresholds = &s.thresholds
+	debugInfo.ToolResults = s.toolResults
+	debugInfo.Conflicts = s.conflicts
+	debugInfo.PromptTokens = s.promptTokens
+	debugInfo.Answerability = s.answerability
+	debugInfo.Judgement = s.judgement
+	debugInfo.Latency.JudgeMs = s.judgeMs
+	debugInfo.Prompt = s.prompt
+	return debugInfo
+}
@@ -0,0 +1,149 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestValidateStages(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []string
+		wantErr bool
+	}{
+		{name: "default order", stages: nil},
+		{name: "explicit default order", stages: DefaultStages()},
+		{name: "required stages only", stages: []string{StageScope, StageRetrieve, StageSelect, StageGenerate}},
+		{name: "expand before rerank", stages: []string{StageScope, StageRetrieve, StageExpand, StageRerank, StageSelect, StageGenerate}},
+		{name: "unknown stage", stages: []string{StageScope, StageRetrieve, "summarize", StageSelect, StageGenerate}, wantErr: true},
+		{name: "duplicate stage", stages: []string{StageScope, StageRetrieve, StageRerank, StageRerank, StageSelect, StageGenerate}, wantErr: true},
+		{name: "missing required stage", stages: []string{StageScope, StageRetrieve, StageGenerate}, wantErr: true},
+		{name: "rerank after select", stages: []string{StageScope, StageRetrieve, StageSelect, StageRerank, StageGenerate}, wantErr: true},
+		{name: "verify before generate", stages: []string{StageScope, StageRetrieve, StageSelect, StageVerify, StageGenerate}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStages(tt.stages)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStages(%v) error = %v, wantErr %v", tt.stages, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewEngineByName(EngineLLM, EngineDeps{Stages: []string{StageGenerate}}); err == nil {
+		t.Error("NewEngineByName() with an invalid pipeline error = nil, want stage error")
+	}
+}
+
+func TestRerankStage(t *testing.T) {
+	candidates := []rerankCandidate{
+		{result: vectorstore.SearchResult{PointID: "c1"}, chunk: &storage.ChunkRecord{Text: "Meeting notes"}, vectorScore: 0.8, finalScore: 0.8},
+		{result: vectorstore.SearchResult{PointID: "c2"}, chunk: &storage.ChunkRecord{Text: "Tomatoes grow in the garden"}, vectorScore: 0.75, finalScore: 0.75},
+	}
+	newState := func() *askState {
+		return &askState{
+			req:       AskRequest{Question: "tomatoes garden"},
+			retrieval: rankCandidates(context.Background(), nil, append([]rerankCandidate{}, candidates...), minFinalScoreThreshold),
+		}
+	}
+
+	// Lexical overlap lifts c2 above c1
+	s := newState()
+	if err := (&ragEngine{}).rerankStage(context.Background(), s); err != nil {
+		t.Fatalf("rerankStage() error = %v", err)
+	}
+	if !s.reranked || s.retrieval.candidates[0].result.PointID != "c2" || s.retrieval.candidates[0].lexicalScore == 0 {
+		t.Errorf("rerankStage() candidates = %+v, want c2 first with a lexical score", s.retrieval.candidates)
+	}
+
+	// With the reranker flag off, candidates keep their vector order
+	flags, err := features.New(map[string]bool{features.Reranker: false})
+	if err != nil {
+		t.Fatalf("features.New() error = %v", err)
+	}
+	s = newState()
+	if err := (&ragEngine{flags: flags}).rerankStage(context.Background(), s); err != nil {
+		t.Fatalf("rerankStage() error = %v", err)
+	}
+	if s.reranked || s.retrieval.candidates[0].result.PointID != "c1" {
+		t.Errorf("rerankStage() with reranker off reranked = %v, first = %s, want vector order", s.reranked, s.retrieval.candidates[0].result.PointID)
+	}
+}
+
+func TestFinishAnswer_WithoutVerify(t *testing.T) {
+	chunks := []chunkData{
+		{vaultName: "personal", relPath: "garden.md", headingPath: "# Garden > ## Beds", result: vectorstore.SearchResult{PointID: "c1"}},
+		{vaultName: "personal", relPath: "care.md", headingPath: "# Care", result: vectorstore.SearchResult{PointID: "c2"}},
+	}
+	s := &askState{
+		req:    AskRequest{Question: "Where are the tomatoes?"},
+		chunks: chunks,
+		answer: "In the north bed [File: garden.md, Section: Beds]",
+	}
+
+	// Without the verify stage the citation is not checked, so every chunk is a reference
+	resp := (&ragEngine{}).finishAnswer(context.Background(), s)
+	if len(resp.References) != 2 {
+		t.Errorf("finishAnswer() references = %+v, want both chunks", resp.References)
+	}
+
+	// Verify keeps only the cited chunk
+	s.references = nil
+	if err := (&ragEngine{}).verifyStage(context.Background(), s); err != nil {
+		t.Fatalf("verifyStage() error = %v", err)
+	}
+	if len(s.references) != 1 || s.references[0].RelPath != "garden.md" {
+		t.Errorf("verifyStage() references = %+v, want only garden.md", s.references)
+	}
+}
+
+func TestScopeStage_AllowedVaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := storage_mocks.NewMockVaultStore(ctrl)
+	mockNoteRepo := storage_mocks.NewMockNoteStore(ctrl)
+	engine := &ragEngine{embedder: stubEmbedder{}, vaultRepo: mockVaultRepo, noteRepo: mockNoteRepo}
+	vaults := []storage.VaultRecord{{ID: 1, Name: "personal"}, {ID: 2, Name: "work"}}
+	mockVaultRepo.EXPECT().ListAll(gomock.Any()).Return(vaults, nil).AnyTimes()
+	mockNoteRepo.EXPECT().ListFolderStats(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, 0, nil).AnyTimes()
+
+	scope := func(ctx context.Context, vaultNames ...string) *askState {
+		t.Helper()
+		s := &askState{req: AskRequest{Question: "What is on the roadmap?", Vaults: vaultNames}, retrievalOnly: true}
+		if err := engine.scopeStage(ctx, s); err != nil {
+			t.Fatalf("scopeStage() error = %v", err)
+		}
+		return s
+	}
+
+	// Without a user every vault is searched
+	if s := scope(context.Background()); !reflect.DeepEqual(s.vaultIDs, []int{1, 2}) {
+		t.Errorf("vaultIDs = %v, want both vaults", s.vaultIDs)
+	}
+
+	// A personal-only user searches the personal vault, even when asking for work
+	personalOnly := contextutil.WithAllowedVaults(context.Background(), []int{1})
+	if s := scope(personalOnly); !reflect.DeepEqual(s.vaultIDs, []int{1}) || s.vaultNames[2] != "" {
+		t.Errorf("vaultIDs = %v, names = %v; want only the personal vault", s.vaultIDs, s.vaultNames)
+	}
+	if s := scope(personalOnly, "personal", "work"); !reflect.DeepEqual(s.vaultIDs, []int{1}) {
+		t.Errorf("vaultIDs = %v, want the work vault dropped", s.vaultIDs)
+	}
+
+	// Asking only for vaults the user may not read finds nothing rather than everything
+	s := scope(personalOnly, "work")
+	if s.resp == nil || !s.resp.Abstained || s.resp.AbstainReason != "no_relevant_context" {
+		t.Errorf("resp = %+v, want a no_relevant_context abstention", s.resp)
+	}
+}
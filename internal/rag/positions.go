@@ -0,0 +1,46 @@
+package rag
+
+import (
+	"context"
+	"slices"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// addReferencePositions sets each reference's position and total chunk count within its
+// note, so clients can show "section 3 of 12" and page through the note around it.
+// Failures are logged and leave the fields unset.
+func (e *ragEngine) addReferencePositions(ctx context.Context, references []Reference, chunks []chunkData) {
+	if e.chunkRepo == nil || len(references) == 0 {
+		return
+	}
+
+	// References carry no note ID, so resolve it from the chunks they were built from
+	noteIDs := make(map[string]string)
+	var ids []string
+	for _, chunk := range chunks {
+		noteID, _ := chunk.result.Meta["note_id"].(string)
+		key := chunk.vaultName + "/" + chunk.relPath
+		if noteID == "" || noteIDs[key] != "" {
+			continue
+		}
+		noteIDs[key] = noteID
+		ids = append(ids, noteID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	indexes, err := e.chunkRepo.ListIndexesByNotes(ctx, ids)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to load chunk positions for references", "error", err)
+		return
+	}
+	for i := range references {
+		noteIndexes := indexes[noteIDs[references[i].Vault+"/"+references[i].RelPath]]
+		if position, found := slices.BinarySearch(noteIndexes, references[i].ChunkIndex); found {
+			references[i].Position = position + 1
+			references[i].TotalChunks = len(noteIndexes)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestAddReferencePositions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	engine := &ragEngine{chunkRepo: mockChunkRepo}
+	ctx := context.Background()
+
+	chunks := []chunkData{
+		{vaultName: "personal", relPath: "garden.md", chunkIndex: 2, result: vectorstore.SearchResult{Meta: map[string]any{"note_id": "note-1"}}},
+		{vaultName: "personal", relPath: "garden.md", chunkIndex: 5, result: vectorstore.SearchResult{Meta: map[string]any{"note_id": "note-1"}}},
+		{vaultName: "work", relPath: "plan.md", chunkIndex: 0, result: vectorstore.SearchResult{Meta: map[string]any{"note_id": "note-2"}}},
+	}
+	references := chunkReferences(chunks)
+
+	// Chunk 4 of garden.md was skipped during indexing, so chunk 5 is the fifth of five
+	mockChunkRepo.EXPECT().ListIndexesByNotes(gomock.Any(), []string{"note-1", "note-2"}).
+		Return(map[string][]int{"note-1": {0, 1, 2, 3, 5}, "note-2": {1}}, nil)
+	engine.addReferencePositions(ctx, references, chunks)
+
+	want := [][2]int{{3, 5}, {5, 5}, {0, 0}}
+	for i, ref := range references {
+		if ref.Position != want[i][0] || ref.TotalChunks != want[i][1] {
+			t.Errorf("reference %d = %d of %d, want %d of %d", i, ref.Position, ref.TotalChunks, want[i][0], want[i][1])
+		}
+	}
+
+	// Lookup failures leave references without positions
+	references = chunkReferences(chunks)
+	mockChunkRepo.EXPECT().ListIndexesByNotes(gomock.Any(), gomock.Any()).Return(nil, errors.New("database is locked"))
+	engine.addReferencePositions(ctx, references, chunks)
+	if references[0].Position != 0 || references[0].TotalChunks != 0 {
+		t.Errorf("reference = %+v, want no position after a failed lookup", references[0])
+	}
+}
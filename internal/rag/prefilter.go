@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"context"
+	"sort"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// NotePrefilterOptions configures two-stage retrieval: a note-level search over note centroid
+// embeddings picks the top M notes, and the chunk search is then restricted to those notes.
+type NotePrefilterOptions struct {
+	// Collection holds one centroid embedding per note (empty disables the prefilter).
+	Collection string
+	// TopM is the number of notes whose chunks are searched (0 disables the prefilter).
+	TopM int
+}
+
+// enabled reports whether the note-level first stage should run.
+func (o NotePrefilterOptions) enabled() bool {
+	return o.Collection != "" && o.TopM > 0
+}
+
+// prefilterNotes runs the first retrieval stage: each vault's note centroids are searched
+// and the best TopM notes overall are kept. It returns the selected note IDs (nil means the
+// chunk search runs unrestricted) and debug information (nil when the prefilter is off).
+// Cold notes have no centroids, so the prefilter is skipped when cold chunks are requested.
+func (e *ragEngine) prefilterNotes(ctx context.Context, req AskRequest, queryVector []float32, vaultIDs []int, vaultMap map[int]string) ([]string, *NotePrefilter) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if !e.notePrefilter.enabled() || req.IncludeCold || !e.flags.Enabled(features.NotePrefilter) {
+		return nil, nil
+	}
+
+	info := &NotePrefilter{
+		TopM:  e.notePrefilter.TopM,
+		Notes: []PrefilterNote{},
+	}
+
+	var results []vectorstore.SearchResult
+	for _, vaultID := range vaultIDs {
+		vaultResults, err := e.searchCollection(ctx, e.notePrefilter.Collection, queryVector, e.notePrefilter.TopM, map[string]any{"vault_id": vaultID})
+		if err != nil {
+			logger.WarnContext(ctx, "note prefilter search failed, searching chunks unrestricted", "vault_id", vaultID, "error", err)
+			info.FallbackReason = "search_failed"
+			return nil, info
+		}
+		results = append(results, vaultResults...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > e.notePrefilter.TopM {
+		results = results[:e.notePrefilter.TopM]
+	}
+
+	noteIDs := make([]string, 0, len(results))
+	for _, result := range results {
+		noteID, _ := result.Meta["note_id"].(string)
+		if noteID == "" {
+			continue
+		}
+		noteIDs = append(noteIDs, noteID)
+
+		relPath, _ := result.Meta["rel_path"].(string)
+		vaultName, _ := result.Meta["vault_name"].(string)
+		if vaultName == "" {
+			if vaultID, ok := result.Meta["vault_id"].(int64); ok {
+				vaultName = vaultMap[int(vaultID)]
+			}
+		}
+		info.Notes = append(info.Notes, PrefilterNote{
+			Vault:   vaultName,
+			RelPath: relPath,
+			Score:   float64(result.Score),
+		})
+	}
+
+	if len(noteIDs) == 0 {
+		// Centroids are built during indexing; an index from before the prefilter has none
+		logger.InfoContext(ctx, "note prefilter found no notes, searching chunks unrestricted")
+		info.FallbackReason = "no_note_centroids"
+		return nil, info
+	}
+
+	logger.InfoContext(ctx, "note prefilter selected notes",
+		"top_m", e.notePrefilter.TopM,
+		"selected", len(noteIDs),
+		"top_score", results[0].Score,
+	)
+	return noteIDs, info
+}
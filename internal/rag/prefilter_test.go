@@ -0,0 +1,89 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestPrefilterNotes(t *testing.T) {
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes_notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	centroid := func(noteID, relPath string, vaultID int, vec []float32) vectorstore.Point {
+		return vectorstore.Point{ID: noteID, Vec: vec, Meta: map[string]any{
+			"note_id": noteID, "rel_path": relPath, "vault_id": vaultID, "vault_name": "personal",
+		}}
+	}
+	if err := store.Upsert(ctx, "notes_notes", []vectorstore.Point{
+		centroid("n1", "garden.md", 1, []float32{1, 0}),
+		centroid("n2", "recipes.md", 1, []float32{0.6, 0.8}),
+		centroid("n3", "taxes.md", 2, []float32{0, 1}),
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	engine := &ragEngine{
+		vectorStore:   store,
+		notePrefilter: NotePrefilterOptions{Collection: "notes_notes", TopM: 2},
+	}
+	query := []float32{1, 0}
+
+	noteIDs, info := engine.prefilterNotes(ctx, AskRequest{}, query, []int{1, 2}, map[int]string{1: "personal", 2: "work"})
+	if len(noteIDs) != 2 || noteIDs[0] != "n1" || noteIDs[1] != "n2" {
+		t.Errorf("prefilterNotes() noteIDs = %v, want [n1 n2]", noteIDs)
+	}
+	if info == nil || info.TopM != 2 || len(info.Notes) != 2 || info.Notes[0].RelPath != "garden.md" || info.FallbackReason != "" {
+		t.Errorf("prefilterNotes() info = %+v, want garden.md first and no fallback", info)
+	}
+
+	// Cold chunks have no centroids, so the prefilter is skipped
+	if noteIDs, info := engine.prefilterNotes(ctx, AskRequest{IncludeCold: true}, query, []int{1}, nil); noteIDs != nil || info != nil {
+		t.Errorf("prefilterNotes(include_cold) = %v, %+v, want nil, nil", noteIDs, info)
+	}
+
+	// No centroids in scope means the chunk search runs unrestricted
+	noteIDs, info = engine.prefilterNotes(ctx, AskRequest{}, query, []int{3}, nil)
+	if noteIDs != nil || info == nil || info.FallbackReason != "no_note_centroids" {
+		t.Errorf("prefilterNotes(empty) = %v, %+v, want fallback no_note_centroids", noteIDs, info)
+	}
+
+	// Disabled prefilter reports nothing
+	engine.notePrefilter = NotePrefilterOptions{}
+	if noteIDs, info := engine.prefilterNotes(ctx, AskRequest{}, query, []int{1}, nil); noteIDs != nil || info != nil {
+		t.Errorf("prefilterNotes(disabled) = %v, %+v, want nil, nil", noteIDs, info)
+	}
+}
+
+func TestRetrieve_RestrictsToPrefilteredNotes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	store := vectorstore.NewMemoryStore()
+	if err := store.EnsureCollection(ctx, "notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	if err := store.Upsert(ctx, "notes", []vectorstore.Point{
+		{ID: "c1", Vec: []float32{1, 0}, Meta: map[string]any{"vault_id": 1, "note_id": "n1", "rel_path": "garden.md"}},
+		{ID: "c2", Vec: []float32{1, 0.1}, Meta: map[string]any{"vault_id": 1, "note_id": "n2", "rel_path": "recipes.md"}},
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	mockChunkRepo.EXPECT().GetByID(gomock.Any(), "c2").Return(&storage.ChunkRecord{ID: "c2", Text: "tomato recipes"}, nil)
+	engine := &ragEngine{vectorStore: store, chunkRepo: mockChunkRepo, collection: "notes"}
+
+	pass := engine.retrieve(ctx, AskRequest{Question: "tomato recipes"}, []float32{1, 0}, []int{1}, nil, []string{"n2"}, candidateKPerScope)
+	if len(pass.deduplicated) != 1 || pass.deduplicated[0].PointID != "c2" {
+		t.Errorf("retrieve() results = %+v, want only c2 from the prefiltered note", pass.deduplicated)
+	}
+}
@@ -0,0 +1,52 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"helloworld-ai/internal/cache"
+	"helloworld-ai/internal/contextutil"
+)
+
+// QuestionCacheOptions configures the cache of recent question embeddings, which lets
+// retried and repeated questions (identical text) skip the embedding call.
+type QuestionCacheOptions struct {
+	// Size is the maximum number of cached questions (0 disables the cache).
+	Size int
+	// TTL is how long an embedding stays cached after it was computed.
+	TTL time.Duration
+}
+
+// DefaultQuestionCacheOptions keeps the last 256 questions for 10 minutes.
+var DefaultQuestionCacheOptions = QuestionCacheOptions{Size: 256, TTL: 10 * time.Minute}
+
+// newQuestionCache returns the cache of question embeddings, or nil when opts disables it.
+// A nil cache never hits and ignores writes.
+func newQuestionCache(opts QuestionCacheOptions) *cache.Cache[string, []float32] {
+	if opts.Size <= 0 || opts.TTL <= 0 {
+		return nil
+	}
+	return cache.New[string, []float32](CacheQuestionEmbeddings, cache.Options{Size: opts.Size, TTL: opts.TTL})
+}
+
+// embedQuestion returns the question's embedding, from the cache when possible, within the
+// embedding timeout. cached reports whether the embedding call was skipped.
+func (e *ragEngine) embedQuestion(ctx context.Context, question string) (vector []float32, cached bool, err error) {
+	if vector, ok := e.questionCache.Get(question); ok {
+		contextutil.LoggerFromContext(ctx).DebugContext(ctx, "question embedding served from cache")
+		return vector, true, nil
+	}
+
+	embedCtx, cancel := withTimeout(ctx, e.timeouts.Embed)
+	defer cancel()
+	embeddings, err := e.embedder.EmbedTexts(embedCtx, []string{question})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(embeddings) == 0 {
+		return nil, false, fmt.Errorf("no embedding returned for question")
+	}
+	e.questionCache.Set(question, embeddings[0])
+	return embeddings[0], false, nil
+}
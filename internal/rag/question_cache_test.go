@@ -0,0 +1,71 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/llm"
+)
+
+func TestQuestionCache_Options(t *testing.T) {
+	cache := newQuestionCache(QuestionCacheOptions{Size: 2, TTL: time.Minute})
+	stats := cache.Stats()
+	if stats.Name != CacheQuestionEmbeddings || stats.MaxEntries != 2 || stats.TTLSeconds != 60 {
+		t.Errorf("newQuestionCache() stats = %+v, want size 2 and a one-minute TTL", stats)
+	}
+}
+
+func TestQuestionCache_Disabled(t *testing.T) {
+	cache := newQuestionCache(QuestionCacheOptions{})
+	if cache != nil {
+		t.Fatalf("newQuestionCache(zero) = %+v, want nil", cache)
+	}
+	cache.Set("a", []float32{1})
+	if _, ok := cache.Get("a"); ok {
+		t.Error("nil cache reported a hit")
+	}
+}
+
+func TestEmbedQuestion_SkipsRepeatedEmbeddingCalls(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req llm.EmbeddingsRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		resp := llm.EmbeddingsResponse{}
+		for _, text := range req.Input {
+			resp.Data = append(resp.Data, llm.EmbeddingData{Embedding: llm.FakeEmbedding(text, 4)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	engine := &ragEngine{
+		embedder:      llm.NewEmbeddingsClient(server.URL, "", "embed", 4),
+		questionCache: newQuestionCache(DefaultQuestionCacheOptions),
+	}
+	ctx := context.Background()
+
+	first, cached, err := engine.embedQuestion(ctx, "Where are the tomatoes?")
+	if err != nil || cached {
+		t.Fatalf("first embedQuestion() cached = %v, err = %v, want a fresh embedding", cached, err)
+	}
+	second, cached, err := engine.embedQuestion(ctx, "Where are the tomatoes?")
+	if err != nil || !cached {
+		t.Fatalf("repeated embedQuestion() cached = %v, err = %v, want a cache hit", cached, err)
+	}
+	if len(second) != len(first) || second[0] != first[0] {
+		t.Errorf("cached embedding = %v, want %v", second, first)
+	}
+	if _, cached, _ := engine.embedQuestion(ctx, "Where are the peppers?"); cached {
+		t.Error("different question reported a cache hit")
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("embedding server calls = %d, want 2", got)
+	}
+}
@@ -1,8 +1,12 @@
 package rag
 
 import (
+	"context"
 	"strings"
+	"time"
 	"unicode"
+
+	"helloworld-ai/internal/contextutil"
 )
 
 const (
@@ -17,18 +21,39 @@ var lexicalStopwords = map[string]struct{}{
 	"or": {}, "the": {}, "to": {}, "was": {}, "were": {}, "with": {},
 }
 
+// lexicalBreakdown holds the components that make up a lexical score.
+type lexicalBreakdown struct {
+	matchedTerms    []string
+	termFrequencies map[string]int
+	chunkTokenCount int
+	termScore       float32
+	headingMatches  []string
+	headingBonus    float32
+	capped          bool
+	score           float32
+}
+
 // lexicalScore computes a lightweight lexical relevance score for a chunk relative to a query.
 // The score is normalized to remain in a predictable range so it can be blended with vector scores.
 func lexicalScore(query, chunkText, headingPath string) float32 {
+	return explainLexicalScore(query, chunkText, headingPath).score
+}
+
+// explainLexicalScore computes the lexical score along with the matched terms and
+// bonuses that produced it, for debug output.
+func explainLexicalScore(query, chunkText, headingPath string) lexicalBreakdown {
+	var breakdown lexicalBreakdown
+
 	queryTokens := filterStopwords(tokenize(query))
 	if len(queryTokens) == 0 {
-		return 0
+		return breakdown
 	}
 
 	chunkTokens := tokenize(chunkText)
 	if len(chunkTokens) == 0 {
-		return 0
+		return breakdown
 	}
+	breakdown.chunkTokenCount = len(chunkTokens)
 
 	chunkFreq := make(map[string]int, len(chunkTokens))
 	for _, token := range chunkTokens {
@@ -37,10 +62,21 @@ func lexicalScore(query, chunkText, headingPath string) float32 {
 
 	var rawMatches int
 	for _, token := range queryTokens {
-		rawMatches += chunkFreq[token]
+		freq := chunkFreq[token]
+		rawMatches += freq
+		if freq > 0 {
+			if breakdown.termFrequencies == nil {
+				breakdown.termFrequencies = make(map[string]int)
+			}
+			if _, ok := breakdown.termFrequencies[token]; !ok {
+				breakdown.matchedTerms = append(breakdown.matchedTerms, token)
+			}
+			breakdown.termFrequencies[token] = freq
+		}
 	}
 
-	score := (float32(rawMatches) / (1 + float32(len(chunkTokens)))) * lexicalLengthScale
+	breakdown.termScore = (float32(rawMatches) / (1 + float32(len(chunkTokens)))) * lexicalLengthScale
+	score := breakdown.termScore
 
 	if headingPath != "" {
 		headingTokens := tokenize(headingPath)
@@ -49,23 +85,25 @@ func lexicalScore(query, chunkText, headingPath string) float32 {
 			for _, token := range headingTokens {
 				headingSet[token] = struct{}{}
 			}
-			var headingMatches int
 			for _, token := range queryTokens {
 				if _, ok := headingSet[token]; ok {
-					headingMatches++
+					breakdown.headingMatches = append(breakdown.headingMatches, token)
 				}
 			}
-			score += float32(headingMatches) * headingMatchBonus
+			breakdown.headingBonus = float32(len(breakdown.headingMatches)) * headingMatchBonus
+			score += breakdown.headingBonus
 		}
 	}
 
 	if score > maxLexicalScore {
-		return maxLexicalScore
+		breakdown.capped = true
+		score = maxLexicalScore
 	}
 	if score < 0 {
-		return 0
+		score = 0
 	}
-	return score
+	breakdown.score = score
+	return breakdown
 }
 
 func tokenize(text string) []string {
@@ -107,3 +145,47 @@ func filterStopwords(tokens []string) []string {
 	}
 	return result
 }
+
+// externalRerankScores scores candidates with the external reranker, in candidate order.
+// Candidates without text score 0. Returns nil, so lexical scores are used, when no reranker
+// is configured or the reranker fails.
+func (e *ragEngine) externalRerankScores(ctx context.Context, question string, candidates []rerankCandidate) []float32 {
+	if e.reranker == nil || len(candidates) == 0 {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	texts := make([]string, 0, len(candidates))
+	positions := make([]int, 0, len(candidates))
+	for i, candidate := range candidates {
+		if candidate.chunk.Text == "" {
+			continue
+		}
+		// The heading path gives the reranker the section context the chunk text lacks
+		text := candidate.chunk.Text
+		if candidate.headingPath != "" {
+			text = candidate.headingPath + "\n\n" + text
+		}
+		texts = append(texts, text)
+		positions = append(positions, i)
+	}
+
+	scores := make([]float32, len(candidates))
+	if len(texts) == 0 {
+		return scores
+	}
+	rerankStart := time.Now()
+	textScores, err := e.reranker.Rerank(ctx, question, texts)
+	if err != nil {
+		logger.WarnContext(ctx, "external reranker failed, using lexical scores", "error", err)
+		return nil
+	}
+	for i, score := range textScores {
+		scores[positions[i]] = score
+	}
+	logger.InfoContext(ctx, "external reranker scored candidates",
+		"candidates", len(texts),
+		"duration_ms", time.Since(rerankStart).Milliseconds(),
+	)
+	return scores
+}
@@ -1,9 +1,15 @@
 package rag
 
 import (
+	"context"
+	"errors"
 	"math"
+	"slices"
 	"strings"
 	"testing"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
 )
 
 func TestLexicalScoreBasicMatch(t *testing.T) {
@@ -52,3 +58,82 @@ func TestLexicalScoreNormalization(t *testing.T) {
 		t.Fatalf("expected score to be clamped to %f, got %f", maxLexicalScore, score)
 	}
 }
+
+func TestExplainLexicalScore(t *testing.T) {
+	query := "Project updates for the project"
+	chunk := "Project updates: the project shipped two updates."
+	breakdown := explainLexicalScore(query, chunk, "# Planning > ## Updates")
+
+	if got := strings.Join(breakdown.matchedTerms, ","); got != "project,updates" {
+		t.Errorf("matchedTerms = %q, want %q", got, "project,updates")
+	}
+	if breakdown.termFrequencies["project"] != 2 || breakdown.termFrequencies["updates"] != 2 {
+		t.Errorf("termFrequencies = %v, want project=2 updates=2", breakdown.termFrequencies)
+	}
+	if breakdown.chunkTokenCount != 7 {
+		t.Errorf("chunkTokenCount = %d, want 7", breakdown.chunkTokenCount)
+	}
+	if got := strings.Join(breakdown.headingMatches, ","); got != "updates" {
+		t.Errorf("headingMatches = %q, want %q", got, "updates")
+	}
+	if math.Abs(float64(breakdown.headingBonus-headingMatchBonus)) > 0.0001 {
+		t.Errorf("headingBonus = %f, want %f", breakdown.headingBonus, headingMatchBonus)
+	}
+	if !breakdown.capped || breakdown.score != maxLexicalScore {
+		t.Errorf("expected score capped at %f, got %f (capped=%v)", maxLexicalScore, breakdown.score, breakdown.capped)
+	}
+	if breakdown.score != lexicalScore(query, chunk, "# Planning > ## Updates") {
+		t.Error("explainLexicalScore and lexicalScore disagree")
+	}
+}
+
+// stubReranker returns fixed scores and records the texts it was asked to score.
+type stubReranker struct {
+	scores []float32
+	err    error
+	texts  []string
+}
+
+func (r *stubReranker) Rerank(_ context.Context, _ string, texts []string) ([]float32, error) {
+	r.texts = texts
+	return r.scores, r.err
+}
+
+func TestRerank_ExternalReranker(t *testing.T) {
+	pass := retrievalPass{
+		deduplicated: []vectorstore.SearchResult{{PointID: "c1"}, {PointID: "c2"}, {PointID: "c3"}},
+		candidates: []rerankCandidate{
+			{result: vectorstore.SearchResult{PointID: "c1"}, chunk: &storage.ChunkRecord{Text: "Tomatoes grow in the garden"}, headingPath: "# Garden", vectorScore: 0.8},
+			{result: vectorstore.SearchResult{PointID: "c2"}, chunk: &storage.ChunkRecord{Text: "Harvest in August"}, vectorScore: 0.75},
+			{result: vectorstore.SearchResult{PointID: "c3"}, chunk: &storage.ChunkRecord{}, vectorScore: 0.7},
+		},
+		minFinalScore: minFinalScoreThreshold,
+	}
+	req := AskRequest{Question: "When are tomatoes harvested?"}
+
+	// The reranker's scores replace the lexical scores, so c2 overtakes c1
+	reranker := &stubReranker{scores: []float32{0.1, 0.9}}
+	got := (&ragEngine{reranker: reranker}).rerank(context.Background(), req, pass)
+	if want := []string{"# Garden\n\nTomatoes grow in the garden", "Harvest in August"}; !slices.Equal(reranker.texts, want) {
+		t.Errorf("reranker texts = %q, want %q (candidates without text are skipped)", reranker.texts, want)
+	}
+	if got.candidates[0].result.PointID != "c2" {
+		t.Errorf("first candidate = %s, want c2", got.candidates[0].result.PointID)
+	}
+	explanation := got.candidates[0].explanation()
+	if explanation.RerankerScore == nil || math.Abs(*explanation.RerankerScore-0.9) > 1e-6 {
+		t.Errorf("explanation reranker score = %v, want 0.9", explanation.RerankerScore)
+	}
+
+	// A failing reranker falls back to lexical scores
+	failing := &stubReranker{err: errors.New("connection refused")}
+	got = (&ragEngine{reranker: failing}).rerank(context.Background(), req, pass)
+	for _, candidate := range got.candidates {
+		if candidate.rerankerScore != nil {
+			t.Errorf("candidate %s reranker score = %v, want nil after a reranker failure", candidate.result.PointID, *candidate.rerankerScore)
+		}
+	}
+	if got.candidates[0].result.PointID != "c1" {
+		t.Errorf("first candidate = %s, want c1 by lexical score", got.candidates[0].result.PointID)
+	}
+}
@@ -0,0 +1,131 @@
+package rag
+
+import (
+	"fmt"
+
+	"helloworld-ai/internal/features"
+)
+
+// Bounds on the candidate counts requests may set in RetrievalOverrides.
+const (
+	// MaxCandidateKPerScope is the highest candidate_k_per_scope a request may set.
+	MaxCandidateKPerScope = 100
+	// MaxRetrievalCandidates is the highest max_candidates a request may set.
+	MaxRetrievalCandidates = 1000
+)
+
+// RetrievalOptions tune how candidates are retrieved and scored. Zero fields use
+// DefaultRetrievalOptions.
+type RetrievalOptions struct {
+	// MinScore holds the default score thresholds, which requests may override with
+	// AskRequest.MinScore (down to the engine's score floor).
+	MinScore ScoreThresholds `json:"min_score"`
+	// VectorWeight is the weight of the vector score in the final score.
+	VectorWeight float32 `json:"vector_weight"`
+	// LexicalWeight is the weight of the lexical (or external reranker) score in the final score.
+	LexicalWeight float32 `json:"lexical_weight"`
+	// CandidateKPerScope is how many chunks each vault or folder search returns.
+	CandidateKPerScope int `json:"candidate_k_per_scope"`
+	// MaxCandidates caps the deduplicated candidates that are reranked.
+	MaxCandidates int `json:"max_candidates"`
+	// TagBoost is added to the final score of chunks whose note tags share a word with the
+	// question (0: no boost). Unlike the other fields it has no built-in default.
+	TagBoost float32 `json:"tag_boost"`
+}
+
+// DefaultRetrievalOptions are the built-in retrieval settings.
+var DefaultRetrievalOptions = RetrievalOptions{
+	MinScore:           DefaultScoreThresholds,
+	VectorWeight:       vectorScoreWeight,
+	LexicalWeight:      lexicalScoreWeight,
+	CandidateKPerScope: candidateKPerScope,
+	MaxCandidates:      maxCandidates,
+}
+
+// withDefaults fills zero fields from DefaultRetrievalOptions. Both weights are replaced
+// together, so a zero lexical weight can be configured to rank by vector score alone.
+func (o RetrievalOptions) withDefaults() RetrievalOptions {
+	if o.MinScore.Vector <= 0 {
+		o.MinScore.Vector = DefaultRetrievalOptions.MinScore.Vector
+	}
+	if o.MinScore.Final <= 0 {
+		o.MinScore.Final = DefaultRetrievalOptions.MinScore.Final
+	}
+	if o.VectorWeight <= 0 && o.LexicalWeight <= 0 {
+		o.VectorWeight = DefaultRetrievalOptions.VectorWeight
+		o.LexicalWeight = DefaultRetrievalOptions.LexicalWeight
+	}
+	if o.CandidateKPerScope <= 0 {
+		o.CandidateKPerScope = DefaultRetrievalOptions.CandidateKPerScope
+	}
+	if o.MaxCandidates <= 0 {
+		o.MaxCandidates = DefaultRetrievalOptions.MaxCandidates
+	}
+	return o
+}
+
+// combineScores blends a candidate's vector score with its lexical (or reranker) score.
+func (o RetrievalOptions) combineScores(vectorScore, lexicalScore float32) float32 {
+	return (vectorScore * o.VectorWeight) + (lexicalScore * o.LexicalWeight)
+}
+
+// RetrievalOverrides tune retrieval for one request, e.g. to compare settings without a
+// rebuild. They take effect only while the retrieval_options feature flag is on. Zero
+// values keep the engine's settings; thresholds are overridden with AskRequest.MinScore.
+type RetrievalOverrides struct {
+	// VectorWeight replaces the vector score weight (set with LexicalWeight).
+	VectorWeight float32 `json:"vector_weight,omitempty"`
+	// LexicalWeight replaces the lexical score weight (set with VectorWeight).
+	LexicalWeight float32 `json:"lexical_weight,omitempty"`
+	// CandidateKPerScope replaces how many chunks each vault or folder search returns.
+	CandidateKPerScope int `json:"candidate_k_per_scope,omitempty"`
+	// MaxCandidates replaces the cap on reranked candidates.
+	MaxCandidates int `json:"max_candidates,omitempty"`
+	// TagBoost replaces the boost of chunks whose note tags match the question.
+	TagBoost float32 `json:"tag_boost,omitempty"`
+}
+
+// Validate returns an error describing the first override out of range.
+func (o RetrievalOverrides) Validate() error {
+	if o.VectorWeight < 0 || o.VectorWeight > 1 || o.LexicalWeight < 0 || o.LexicalWeight > 1 {
+		return fmt.Errorf("retrieval weights must be between 0 and 1")
+	}
+	if o.CandidateKPerScope < 0 || o.CandidateKPerScope > MaxCandidateKPerScope {
+		return fmt.Errorf("candidate_k_per_scope must be between 0 and %d", MaxCandidateKPerScope)
+	}
+	if o.MaxCandidates < 0 || o.MaxCandidates > MaxRetrievalCandidates {
+		return fmt.Errorf("max_candidates must be between 0 and %d", MaxRetrievalCandidates)
+	}
+	if o.TagBoost < 0 || o.TagBoost > 1 {
+		return fmt.Errorf("tag_boost must be between 0 and 1")
+	}
+	return nil
+}
+
+// apply returns opts with the overrides set.
+func (o RetrievalOverrides) apply(opts RetrievalOptions) RetrievalOptions {
+	if o.VectorWeight > 0 || o.LexicalWeight > 0 {
+		opts.VectorWeight = o.VectorWeight
+		opts.LexicalWeight = o.LexicalWeight
+	}
+	if o.CandidateKPerScope > 0 {
+		opts.CandidateKPerScope = o.CandidateKPerScope
+	}
+	if o.MaxCandidates > 0 {
+		opts.MaxCandidates = o.MaxCandidates
+	}
+	if o.TagBoost > 0 {
+		opts.TagBoost = o.TagBoost
+	}
+	return opts
+}
+
+// retrievalOptions returns the retrieval settings applied to req: the engine's options,
+// with the request's overrides while the retrieval_options feature flag is on.
+func (e *ragEngine) retrievalOptions(req AskRequest) RetrievalOptions {
+	opts := e.retrieval.withDefaults()
+	if req.Retrieval != (RetrievalOverrides{}) && e.flags.Enabled(features.RetrievalOptions) {
+		opts = req.Retrieval.apply(opts)
+	}
+	return opts
+}
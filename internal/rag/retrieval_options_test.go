@@ -0,0 +1,115 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/features"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestRetrievalOverrides_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides RetrievalOverrides
+		wantErr   bool
+	}{
+		{name: "no overrides"},
+		{name: "all set", overrides: RetrievalOverrides{VectorWeight: 0.5, LexicalWeight: 0.5, CandidateKPerScope: 30, MaxCandidates: 500}},
+		{name: "vector only", overrides: RetrievalOverrides{VectorWeight: 1}},
+		{name: "weight above 1", overrides: RetrievalOverrides{VectorWeight: 1.5}, wantErr: true},
+		{name: "negative weight", overrides: RetrievalOverrides{LexicalWeight: -0.1}, wantErr: true},
+		{name: "candidate K too high", overrides: RetrievalOverrides{CandidateKPerScope: MaxCandidateKPerScope + 1}, wantErr: true},
+		{name: "too many candidates", overrides: RetrievalOverrides{MaxCandidates: MaxRetrievalCandidates + 1}, wantErr: true},
+		{name: "tag boost above 1", overrides: RetrievalOverrides{TagBoost: 1.5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.overrides.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.overrides, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetrievalOptions(t *testing.T) {
+	flags, err := features.New(nil)
+	if err != nil {
+		t.Fatalf("features.New() error = %v", err)
+	}
+	configured := RetrievalOptions{VectorWeight: 0.6, LexicalWeight: 0.4, CandidateKPerScope: 20}
+	engine := &ragEngine{retrieval: configured, flags: flags}
+	req := AskRequest{Retrieval: RetrievalOverrides{VectorWeight: 1, MaxCandidates: 50}}
+
+	// Zero configured fields fall back to the built-in defaults
+	want := RetrievalOptions{MinScore: DefaultScoreThresholds, VectorWeight: 0.6, LexicalWeight: 0.4, CandidateKPerScope: 20, MaxCandidates: maxCandidates}
+	if got := engine.retrievalOptions(AskRequest{}); got != want {
+		t.Errorf("retrievalOptions() = %+v, want %+v", got, want)
+	}
+
+	// Request overrides are ignored while the flag is off
+	if got := engine.retrievalOptions(req); got != want {
+		t.Errorf("retrievalOptions() with the flag off = %+v, want %+v", got, want)
+	}
+
+	if err := flags.Set(features.RetrievalOptions, true); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	want.VectorWeight, want.LexicalWeight, want.MaxCandidates = 1, 0, 50
+	if got := engine.retrievalOptions(req); got != want {
+		t.Errorf("retrievalOptions() with the flag on = %+v, want %+v", got, want)
+	}
+}
+
+func TestRerank_RetrievalWeights(t *testing.T) {
+	pass := retrievalPass{
+		deduplicated: []vectorstore.SearchResult{{PointID: "c1"}, {PointID: "c2"}},
+		candidates: []rerankCandidate{
+			{result: vectorstore.SearchResult{PointID: "c1"}, chunk: &storage.ChunkRecord{Text: "Meeting agenda"}, vectorScore: 0.8},
+			{result: vectorstore.SearchResult{PointID: "c2"}, chunk: &storage.ChunkRecord{Text: "Tomatoes are harvested in August"}, vectorScore: 0.7},
+		},
+		minFinalScore: 0.1,
+	}
+	req := AskRequest{Question: "When are tomatoes harvested?"}
+
+	// The default weights let the lexical match overtake the closer vector hit
+	got := (&ragEngine{}).rerank(context.Background(), req, pass)
+	if got.candidates[0].result.PointID != "c2" {
+		t.Errorf("first candidate = %s, want c2 by lexical match", got.candidates[0].result.PointID)
+	}
+
+	// Configured vector-only weights rank by vector score
+	got = (&ragEngine{retrieval: RetrievalOptions{VectorWeight: 1}}).rerank(context.Background(), req, pass)
+	if got.candidates[0].result.PointID != "c1" || got.candidates[0].finalScore != 0.8 {
+		t.Errorf("first candidate = %s (%.2f), want c1 scored 0.80 by vector score", got.candidates[0].result.PointID, got.candidates[0].finalScore)
+	}
+}
+
+func TestRerank_TagBoost(t *testing.T) {
+	pass := retrievalPass{
+		deduplicated: []vectorstore.SearchResult{{PointID: "c1"}, {PointID: "c2"}},
+		candidates: []rerankCandidate{
+			{result: vectorstore.SearchResult{PointID: "c1", Meta: map[string]any{"tags": []any{"cooking"}}}, chunk: &storage.ChunkRecord{Text: "Harvest notes"}, vectorScore: 0.8},
+			{result: vectorstore.SearchResult{PointID: "c2", Meta: map[string]any{"tags": []string{"garden/tomato"}}}, chunk: &storage.ChunkRecord{Text: "Harvest notes"}, vectorScore: 0.75},
+		},
+		minFinalScore: 0.1,
+	}
+	req := AskRequest{Question: "When are tomatoes harvested?"}
+
+	// Without a boost the closer vector hit ranks first
+	got := (&ragEngine{}).rerank(context.Background(), req, pass)
+	if got.candidates[0].result.PointID != "c1" || got.candidates[1].tagMatches != nil {
+		t.Errorf("first candidate = %s, tag matches = %v, want c1 and no tag matches without a boost", got.candidates[0].result.PointID, got.candidates[1].tagMatches)
+	}
+
+	got = (&ragEngine{retrieval: RetrievalOptions{TagBoost: 0.1}}).rerank(context.Background(), req, pass)
+	first := got.candidates[0]
+	if first.result.PointID != "c2" || first.tagBoost != 0.1 || len(first.tagMatches) != 1 || first.tagMatches[0] != "garden/tomato" {
+		t.Errorf("first candidate = %s (boost %.2f, matches %v), want c2 boosted for garden/tomato", first.result.PointID, first.tagBoost, first.tagMatches)
+	}
+	if explanation := first.explanation(); explanation.TagBoost != float64(float32(0.1)) || len(explanation.TagMatches) != 1 {
+		t.Errorf("explanation = %+v, want the tag boost and matches", explanation)
+	}
+}
@@ -0,0 +1,297 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/llm"
+)
+
+const (
+	// SafetyActionRedact replaces matched text with a placeholder naming its category.
+	SafetyActionRedact = "redact"
+	// SafetyActionBlock withholds the whole answer.
+	SafetyActionBlock = "block"
+)
+
+// SafetyCategory is one sensitive category in a safety rules file. Keywords match whole
+// words case-insensitively; patterns are Go regular expressions. The description is shown
+// to the LLM classifier.
+type SafetyCategory struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Patterns    []string `json:"patterns,omitempty"`
+}
+
+// SafetyRules is the content of a safety rules file (SAFETY_FILTER_RULES).
+type SafetyRules struct {
+	Categories []SafetyCategory `json:"categories"`
+}
+
+// LoadSafetyRules reads safety rules from a JSON file.
+func LoadSafetyRules(path string) (SafetyRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SafetyRules{}, fmt.Errorf("failed to read safety rules: %w", err)
+	}
+	var rules SafetyRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return SafetyRules{}, fmt.Errorf("failed to parse safety rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SafetyFilterOptions configures a SafetyFilter.
+type SafetyFilterOptions struct {
+	// Rules lists the sensitive categories.
+	Rules SafetyRules
+	// Action is SafetyActionRedact or SafetyActionBlock for keyword and pattern matches.
+	Action string
+	// Classifier, when set, is asked whether the answer falls into a category the lists
+	// missed. It cannot point at the offending text, so its findings always block.
+	Classifier ChatBackend
+}
+
+// SafetyFilter checks generated answers against configured sensitive categories.
+type SafetyFilter struct {
+	categories []safetyCategory
+	action     string
+	classifier ChatBackend
+}
+
+// safetyCategory is a SafetyCategory with its keywords and patterns compiled.
+type safetyCategory struct {
+	name        string
+	description string
+	patterns    []*regexp.Regexp
+}
+
+// NewSafetyFilter compiles the rules in opts.
+func NewSafetyFilter(opts SafetyFilterOptions) (*SafetyFilter, error) {
+	if opts.Action != SafetyActionRedact && opts.Action != SafetyActionBlock {
+		return nil, fmt.Errorf("invalid safety action %q (must be %s or %s)", opts.Action, SafetyActionRedact, SafetyActionBlock)
+	}
+	if len(opts.Rules.Categories) == 0 {
+		return nil, fmt.Errorf("safety rules define no categories")
+	}
+
+	filter := &SafetyFilter{action: opts.Action, classifier: opts.Classifier}
+	for _, category := range opts.Rules.Categories {
+		name := strings.TrimSpace(category.Name)
+		if name == "" {
+			return nil, fmt.Errorf("safety category without a name")
+		}
+		compiled := safetyCategory{name: name, description: category.Description}
+		for _, keyword := range category.Keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				compiled.patterns = append(compiled.patterns, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(keyword)+`\b`))
+			}
+		}
+		for _, pattern := range category.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern in safety category %s: %w", name, err)
+			}
+			compiled.patterns = append(compiled.patterns, re)
+		}
+		if len(compiled.patterns) == 0 && (opts.Classifier == nil || compiled.description == "") {
+			return nil, fmt.Errorf("safety category %s has no keywords or patterns", name)
+		}
+		filter.categories = append(filter.categories, compiled)
+	}
+	return filter, nil
+}
+
+// safetyBlockedAnswer replaces an answer the filter blocked.
+const safetyBlockedAnswer = "This answer was withheld because it may contain sensitive information (%s)."
+
+// Apply filters resp in place. Matched text is redacted or the answer blocked; a blocked
+// answer also loses its references. Debug output is dropped whenever the filter acts,
+// since it carries raw note text. Returns an error only when classification fails, so an
+// unchecked answer is never returned.
+func (f *SafetyFilter) Apply(ctx context.Context, resp *AskResponse) error {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	answer := resp.Answer
+	var matched []string
+	var spans []safetySpan
+	for _, category := range f.categories {
+		found := false
+		for _, re := range category.patterns {
+			for _, loc := range re.FindAllStringIndex(answer, -1) {
+				found = true
+				spans = append(spans, safetySpan{start: loc[0], end: loc[1], category: category.name})
+			}
+		}
+		if found {
+			matched = append(matched, category.name)
+		}
+	}
+	action := ""
+	if len(matched) > 0 {
+		action = f.action
+		if action == SafetyActionRedact {
+			answer = redactSpans(answer, spans)
+		}
+	}
+
+	if f.classifier != nil {
+		// Redacted text is already safe, so the classifier only sees what the lists let through
+		classified, err := f.classify(ctx, answer)
+		if err != nil {
+			return fmt.Errorf("%w: failed to classify answer: %w", ErrLLMFailure, err)
+		}
+		for _, name := range classified {
+			if !slices.Contains(matched, name) {
+				matched = append(matched, name)
+			}
+		}
+		if len(classified) > 0 {
+			action = SafetyActionBlock
+		}
+	}
+
+	if action == "" {
+		return nil
+	}
+	logger.WarnContext(ctx, "safety filter applied", "action", action, "categories", matched)
+
+	// Suggestions are drawn from the same notes, so they are withheld along with the debug output
+	resp.Debug = nil
+	resp.Suggestions = nil
+	resp.Safety = &SafetyResult{Action: action, Categories: matched}
+	if action == SafetyActionBlock {
+		resp.Answer = fmt.Sprintf(safetyBlockedAnswer, strings.Join(matched, ", "))
+		resp.References = []Reference{}
+		return nil
+	}
+	resp.Answer = answer
+	return nil
+}
+
+// safetySpan is a match of a category's keyword or pattern in an answer.
+type safetySpan struct {
+	start, end int
+	category   string
+}
+
+// redactSpans replaces each span with a placeholder naming its category. Overlapping
+// spans are merged under the category of the first.
+func redactSpans(text string, spans []safetySpan) string {
+	slices.SortStableFunc(spans, func(a, b safetySpan) int { return a.start - b.start })
+	var out strings.Builder
+	pos := 0
+	for i := 0; i < len(spans); i++ {
+		span := spans[i]
+		for i+1 < len(spans) && spans[i+1].start < span.end {
+			span.end = max(span.end, spans[i+1].end)
+			i++
+		}
+		out.WriteString(text[pos:span.start])
+		out.WriteString("[redacted: " + span.category + "]")
+		pos = span.end
+	}
+	out.WriteString(text[pos:])
+	return out.String()
+}
+
+// classify asks the classifier which described categories answer falls into.
+func (f *SafetyFilter) classify(ctx context.Context, answer string) ([]string, error) {
+	var categoryList strings.Builder
+	var known []string
+	for _, category := range f.categories {
+		if category.description == "" {
+			continue
+		}
+		fmt.Fprintf(&categoryList, "- %s: %s\n", category.name, category.description)
+		known = append(known, category.name)
+	}
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	reply, err := f.classifier.ChatWithMessages(ctx, []llm.Message{
+		{Role: "system", Content: "You check answers for sensitive content before they are shown to other people. " +
+			"Categories:\n" + categoryList.String() +
+			"Reply with only a JSON array of the names of the categories the answer contains, or [] if it contains none."},
+		{Role: "user", Content: answer},
+	}, llm.ChatParams{Temperature: 0.1})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("classifier reply is not a JSON array: %q", reply)
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &names); err != nil {
+		return nil, fmt.Errorf("failed to parse classifier reply: %w", err)
+	}
+	var categories []string
+	for _, name := range names {
+		// Names the classifier made up are ignored
+		if slices.Contains(known, name) && !slices.Contains(categories, name) {
+			categories = append(categories, name)
+		}
+	}
+	return categories, nil
+}
+
+// safetyEngine wraps an Engine and filters every answer before it is returned.
+type safetyEngine struct {
+	Engine
+	filter *SafetyFilter
+}
+
+// NewSafetyEngine wraps engine so answers from Ask, AskStream, and AskDocument pass through filter,
+// for deployments shared with family members or teammates.
+func NewSafetyEngine(engine Engine, filter *SafetyFilter) Engine {
+	return &safetyEngine{Engine: engine, filter: filter}
+}
+
+// Ask answers req and filters the answer.
+func (s *safetyEngine) Ask(ctx context.Context, req AskRequest) (AskResponse, error) {
+	resp, err := s.Engine.Ask(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if err := s.filter.Apply(ctx, &resp); err != nil {
+		return AskResponse{}, err
+	}
+	return resp, nil
+}
+
+// AskStream answers req and filters the answer. The filter needs the whole answer, so it
+// is not streamed: onToken receives the filtered answer in one piece.
+func (s *safetyEngine) AskStream(ctx context.Context, req AskRequest, onToken func(token string) error) (AskResponse, error) {
+	resp, err := s.Ask(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if resp.Answer != "" {
+		if err := onToken(resp.Answer); err != nil {
+			return AskResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+// AskDocument answers req and filters the answer.
+func (s *safetyEngine) AskDocument(ctx context.Context, req DocumentRequest) (AskResponse, error) {
+	resp, err := s.Engine.AskDocument(ctx, req)
+	if err != nil {
+		return AskResponse{}, err
+	}
+	if err := s.filter.Apply(ctx, &resp); err != nil {
+		return AskResponse{}, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,216 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+// classifierChat replies to classification requests with a fixed reply.
+type classifierChat struct {
+	reply    string
+	err      error
+	messages []llm.Message
+}
+
+func (c *classifierChat) ChatWithMessages(_ context.Context, messages []llm.Message, _ llm.ChatParams) (string, error) {
+	c.messages = messages
+	return c.reply, c.err
+}
+
+// answerEngine returns a fixed answer with one reference and debug output.
+type answerEngine struct {
+	Engine
+	answer string
+}
+
+func (a answerEngine) Ask(context.Context, AskRequest) (AskResponse, error) {
+	return AskResponse{
+		Answer:     a.answer,
+		References: []Reference{{Vault: "personal", RelPath: "health/checkup.md"}},
+		Debug:      &DebugInfo{},
+	}, nil
+}
+
+var testSafetyRules = SafetyRules{Categories: []SafetyCategory{
+	{Name: "health", Description: "medical conditions and medication", Keywords: []string{"diagnosis", "blood pressure"}},
+	{Name: "finance", Patterns: []string{`\bDE\d{20}\b`}},
+}}
+
+func TestNewSafetyFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SafetyFilterOptions
+	}{
+		{name: "unknown action", opts: SafetyFilterOptions{Rules: testSafetyRules, Action: "censor"}},
+		{name: "no categories", opts: SafetyFilterOptions{Action: SafetyActionRedact}},
+		{name: "unnamed category", opts: SafetyFilterOptions{Rules: SafetyRules{Categories: []SafetyCategory{{Keywords: []string{"x"}}}}, Action: SafetyActionRedact}},
+		{name: "invalid pattern", opts: SafetyFilterOptions{Rules: SafetyRules{Categories: []SafetyCategory{{Name: "x", Patterns: []string{"("}}}}, Action: SafetyActionRedact}},
+		{name: "nothing to match", opts: SafetyFilterOptions{Rules: SafetyRules{Categories: []SafetyCategory{{Name: "x", Description: "anything"}}}, Action: SafetyActionRedact}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSafetyFilter(tt.opts); err == nil {
+				t.Error("NewSafetyFilter() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestSafetyFilter_Apply(t *testing.T) {
+	tests := []struct {
+		name           string
+		action         string
+		answer         string
+		wantAnswer     string
+		wantCategories []string
+	}{
+		{
+			name:       "clean answer untouched",
+			action:     SafetyActionRedact,
+			answer:     "The tomatoes are planted by the fence.",
+			wantAnswer: "The tomatoes are planted by the fence.",
+		},
+		{
+			name:           "redact keywords and patterns",
+			action:         SafetyActionRedact,
+			answer:         "The Diagnosis was mild; pay to DE89370400440532013000.",
+			wantAnswer:     "The [redacted: health] was mild; pay to [redacted: finance].",
+			wantCategories: []string{"health", "finance"},
+		},
+		{
+			name:       "keywords match whole words only",
+			action:     SafetyActionRedact,
+			answer:     "Diagnoses differ from a prognosis.",
+			wantAnswer: "Diagnoses differ from a prognosis.",
+		},
+		{
+			name:           "block",
+			action:         SafetyActionBlock,
+			answer:         "Your blood pressure was 130/85.",
+			wantAnswer:     fmt.Sprintf(safetyBlockedAnswer, "health"),
+			wantCategories: []string{"health"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewSafetyFilter(SafetyFilterOptions{Rules: testSafetyRules, Action: tt.action})
+			if err != nil {
+				t.Fatalf("NewSafetyFilter() error = %v", err)
+			}
+			resp, err := NewSafetyEngine(answerEngine{answer: tt.answer}, filter).Ask(context.Background(), AskRequest{Question: "q"})
+			if err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+
+			if resp.Answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", resp.Answer, tt.wantAnswer)
+			}
+			if tt.wantCategories == nil {
+				if resp.Safety != nil || resp.Debug == nil || len(resp.References) != 1 {
+					t.Errorf("response = %+v, want it unchanged", resp)
+				}
+				return
+			}
+			if resp.Safety == nil || resp.Safety.Action != tt.action || fmt.Sprint(resp.Safety.Categories) != fmt.Sprint(tt.wantCategories) {
+				t.Errorf("safety = %+v, want %s of %v", resp.Safety, tt.action, tt.wantCategories)
+			}
+			if resp.Debug != nil {
+				t.Error("debug output kept after the filter acted")
+			}
+			if wantRefs := map[string]int{SafetyActionRedact: 1, SafetyActionBlock: 0}[tt.action]; len(resp.References) != wantRefs {
+				t.Errorf("references = %v, want %d", resp.References, wantRefs)
+			}
+		})
+	}
+}
+
+func TestRedactSpans_Overlapping(t *testing.T) {
+	text := "call 555-0100 now"
+	spans := []safetySpan{{start: 9, end: 13, category: "b"}, {start: 5, end: 12, category: "a"}}
+	if got, want := redactSpans(text, spans), "call [redacted: a] now"; got != want {
+		t.Errorf("redactSpans() = %q, want %q", got, want)
+	}
+}
+
+func TestSafetyFilter_Classifier(t *testing.T) {
+	tests := []struct {
+		name           string
+		chat           *classifierChat
+		answer         string
+		wantErr        bool
+		wantAction     string
+		wantCategories []string
+	}{
+		{name: "nothing found", chat: &classifierChat{reply: "[]"}, answer: "Water the tomatoes."},
+		{name: "classified category blocks", chat: &classifierChat{reply: "Sure: [\"health\"]"}, answer: "You take two pills daily.", wantAction: SafetyActionBlock, wantCategories: []string{"health"}},
+		{name: "unknown names ignored", chat: &classifierChat{reply: `["finance", "gossip"]`}, answer: "Water the tomatoes."},
+		{name: "keyword redaction then classifier", chat: &classifierChat{reply: "[]"}, answer: "The diagnosis came back.", wantAction: SafetyActionRedact, wantCategories: []string{"health"}},
+		{name: "unparseable reply", chat: &classifierChat{reply: "no idea"}, answer: "Water the tomatoes.", wantErr: true},
+		{name: "classifier error", chat: &classifierChat{err: errors.New("llm down")}, answer: "Water the tomatoes.", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewSafetyFilter(SafetyFilterOptions{Rules: testSafetyRules, Action: SafetyActionRedact, Classifier: tt.chat})
+			if err != nil {
+				t.Fatalf("NewSafetyFilter() error = %v", err)
+			}
+			resp := AskResponse{Answer: tt.answer}
+			err = filter.Apply(context.Background(), &resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Only described categories are offered, and redacted text is what gets classified
+			prompt := tt.chat.messages[0].Content
+			if !strings.Contains(prompt, "- health: medical conditions and medication") || strings.Contains(prompt, "finance") {
+				t.Errorf("classifier prompt = %q, want only the described category", prompt)
+			}
+			if strings.Contains(tt.chat.messages[1].Content, "diagnosis") {
+				t.Errorf("classifier saw unredacted text %q", tt.chat.messages[1].Content)
+			}
+
+			if tt.wantAction == "" {
+				if resp.Safety != nil {
+					t.Errorf("safety = %+v, want nil", resp.Safety)
+				}
+				return
+			}
+			if resp.Safety == nil || resp.Safety.Action != tt.wantAction || fmt.Sprint(resp.Safety.Categories) != fmt.Sprint(tt.wantCategories) {
+				t.Errorf("safety = %+v, want %s of %v", resp.Safety, tt.wantAction, tt.wantCategories)
+			}
+		})
+	}
+}
+
+func TestLoadSafetyRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "safety.json")
+	content := `{"categories":[{"name":"health","description":"medical details","keywords":["diagnosis"],"patterns":["\\bICD-10\\b"]}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+
+	rules, err := LoadSafetyRules(path)
+	if err != nil {
+		t.Fatalf("LoadSafetyRules() error = %v", err)
+	}
+	if len(rules.Categories) != 1 || rules.Categories[0].Name != "health" || rules.Categories[0].Patterns[0] != `\bICD-10\b` {
+		t.Errorf("rules = %+v, want the health category", rules)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if _, err := LoadSafetyRules(path); err == nil {
+		t.Error("LoadSafetyRules() error = nil for invalid JSON")
+	}
+}
@@ -0,0 +1,105 @@
+package rag
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/tracing"
+)
+
+// Search runs the ask pipeline up to the select stage and returns the selected chunks
+// instead of an answer. No chat LLM is called: folders are not ranked, so the search covers
+// the user's folders, or every folder when none are given. Generation settings in req are
+// ignored.
+func (e *ragEngine) Search(ctx context.Context, req AskRequest) (SearchResponse, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "rag.search", attribute.Int("question_length", len(req.Question)))
+	defer span.End()
+	s := &askState{req: req, retrievalOnly: true, span: span}
+	// A stage that fails mid-retrieval leaves the retrieval span open
+	defer s.endRetrieval()
+
+	logger.InfoContext(ctx, "RAG search started",
+		"question", contextutil.RedactQuestion(e.questionLogMode, req.Question),
+		"question_length", len(req.Question),
+		"vaults", req.Vaults,
+		"folders", req.Folders,
+		"k", req.K,
+		"include_cold", req.IncludeCold,
+	)
+
+	// Every retrieval stage runs before select; the stages after it need the LLM
+	for _, name := range e.pipelineStages() {
+		stageCtx, stageSpan := tracing.Start(ctx, "rag.stage."+name)
+		if err := askStages[name].run(e, stageCtx, s); err != nil {
+			stageSpan.RecordError(err)
+			stageSpan.End()
+			span.RecordError(err)
+			return SearchResponse{}, err
+		}
+		stageSpan.End()
+		if s.resp != nil {
+			return SearchResponse{Results: []SearchResult{}, AbstainReason: s.resp.AbstainReason, Debug: s.resp.Debug}, nil
+		}
+		if name == StageSelect {
+			break
+		}
+	}
+	s.endRetrieval()
+
+	references := chunkReferences(s.chunks)
+	e.addReferencePositions(ctx, references, s.chunks)
+	modified := e.noteModifiedTimes(ctx, s.selected)
+	results := make([]SearchResult, 0, len(s.selected))
+	for i, candidate := range s.selected {
+		results = append(results, SearchResult{
+			Reference:    references[i],
+			Text:         candidate.chunk.Text,
+			ScoreVector:  float64(candidate.vectorScore),
+			ScoreLexical: float64(candidate.lexicalScore),
+			ScoreFinal:   float64(candidate.finalScore),
+			Rank:         i + 1,
+			ModifiedAt:   modified[candidate.chunk.NoteID],
+		})
+	}
+
+	logger.InfoContext(ctx, "RAG search completed",
+		"question_length", len(req.Question),
+		"results", len(results),
+	)
+
+	resp := SearchResponse{Results: results}
+	if req.Debug {
+		resp.Debug = e.askDebugInfo(ctx, s, s.retrieval.candidates, s.selected)
+	}
+	return resp, nil
+}
+
+// noteModifiedTimes returns when the notes of candidates were last changed, keyed by note
+// ID, so search results can be sorted by date. A failed lookup is logged and leaves the
+// times out.
+func (e *ragEngine) noteModifiedTimes(ctx context.Context, candidates []rerankCandidate) map[string]time.Time {
+	if e.noteRepo == nil || len(candidates) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, candidate := range candidates {
+		if noteID := candidate.chunk.NoteID; noteID != "" && !slices.Contains(ids, noteID) {
+			ids = append(ids, noteID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	modified, err := e.noteRepo.ModifiedTimes(ctx, ids)
+	if err != nil {
+		contextutil.LoggerFromContext(ctx).WarnContext(ctx, "failed to load note modification times for search results", "error", err)
+		return nil
+	}
+	return modified
+}
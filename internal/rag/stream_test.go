@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+)
+
+// streamingChat streams a fixed reply in chunks.
+type streamingChat struct {
+	chunks []string
+}
+
+func (s *streamingChat) ChatWithMessages(context.Context, []llm.Message, llm.ChatParams) (string, error) {
+	return "", errors.New("unexpected non-streaming call")
+}
+
+func (s *streamingChat) StreamChatWithMessages(_ context.Context, _ []llm.Message, _ llm.ChatParams, callback func(chunk string) error) error {
+	for _, chunk := range s.chunks {
+		if err := callback(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRagEngine_Generate(t *testing.T) {
+	messages := []llm.Message{{Role: "user", Content: "What was my blood pressure?"}}
+
+	engine := &ragEngine{llmClient: &streamingChat{chunks: []string{"It was ", "120/80."}}}
+	var tokens []string
+	answer, _, err := engine.generate(context.Background(), messages, llm.ChatParams{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	if answer != "It was 120/80." || !slices.Equal(tokens, []string{"It was ", "120/80."}) {
+		t.Errorf("generate() = %q with tokens %q, want the streamed answer", answer, tokens)
+	}
+
+	// A token callback error stops generation
+	stop := errors.New("client went away")
+	if _, _, err := engine.generate(context.Background(), messages, llm.ChatParams{}, func(string) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("generate() error = %v, want %v", err, stop)
+	}
+
+	// Backends that cannot stream answer in one piece
+	engine = &ragEngine{llmClient: &classifierChat{reply: "It was 120/80."}}
+	answer, _, err = engine.generate(context.Background(), messages, llm.ChatParams{}, func(string) error {
+		t.Error("onToken called for a non-streaming backend")
+		return nil
+	})
+	if err != nil || answer != "It was 120/80." {
+		t.Errorf("generate() = %q, %v, want the whole answer", answer, err)
+	}
+}
+
+func TestSafetyEngine_AskStream(t *testing.T) {
+	filter, err := NewSafetyFilter(SafetyFilterOptions{Rules: testSafetyRules, Action: SafetyActionRedact})
+	if err != nil {
+		t.Fatalf("NewSafetyFilter() error = %v", err)
+	}
+
+	var tokens []string
+	resp, err := NewSafetyEngine(answerEngine{answer: "Your blood pressure was 130/85."}, filter).AskStream(context.Background(), AskRequest{Question: "q"}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AskStream() error = %v", err)
+	}
+	if resp.Safety == nil {
+		t.Fatal("AskStream() did not filter the answer")
+	}
+	if !slices.Equal(tokens, []string{resp.Answer}) {
+		t.Errorf("tokens = %q, want only the filtered answer %q", tokens, resp.Answer)
+	}
+}
@@ -0,0 +1,121 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helloworld-ai/internal/llm"
+)
+
+// structuredAnswer is an answer generated as JSON with the IDs of the chunks it cites.
+type structuredAnswer struct {
+	Answer    string               `json:"answer"`
+	Citations []structuredCitation `json:"citations"`
+}
+
+// structuredCitation cites one context chunk by ID.
+type structuredCitation struct {
+	ChunkID string `json:"chunk_id"`
+}
+
+// structuredAnswerFormat returns the response format for structured answers: an object with
+// the answer text and citations whose chunk IDs are limited to the chunks in the context, so
+// llama.cpp's grammar sampling cannot cite a chunk that was not offered.
+func structuredAnswerFormat(chunks []chunkData) *llm.ResponseFormat {
+	chunkIDs := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunkIDs = append(chunkIDs, chunk.result.PointID)
+	}
+	return llm.JSONSchemaFormat("cited_answer", map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"answer": map[string]any{"type": "string"},
+			"citations": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"chunk_id": map[string]any{"type": "string", "enum": chunkIDs},
+					},
+					"required":             []string{"chunk_id"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"answer", "citations"},
+		"additionalProperties": false,
+	})
+}
+
+// parseStructuredAnswer decodes a structured answer reply. Servers that ignore
+// response_format may wrap the object in prose or a markdown code fence, so the outermost
+// {...} is tried next. Replies without an answer field are rejected, so free-form answers
+// fall back to bracket citation parsing.
+func parseStructuredAnswer(reply string) (structuredAnswer, error) {
+	reply = strings.TrimSpace(reply)
+	answer, err := decodeStructuredAnswer(reply)
+	if err == nil {
+		return answer, nil
+	}
+
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return structuredAnswer{}, fmt.Errorf("reply is not a JSON object")
+	}
+	return decodeStructuredAnswer(reply[start : end+1])
+}
+
+// decodeStructuredAnswer decodes one JSON object with a non-empty answer.
+func decodeStructuredAnswer(text string) (structuredAnswer, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return structuredAnswer{}, fmt.Errorf("failed to unmarshal answer: %w", err)
+	}
+	if _, ok := fields["answer"]; !ok {
+		return structuredAnswer{}, fmt.Errorf("reply has no answer field")
+	}
+	var answer structuredAnswer
+	if err := json.Unmarshal([]byte(text), &answer); err != nil {
+		return structuredAnswer{}, fmt.Errorf("failed to unmarshal answer: %w", err)
+	}
+	if strings.TrimSpace(answer.Answer) == "" {
+		return structuredAnswer{}, fmt.Errorf("reply has an empty answer")
+	}
+	return answer, nil
+}
+
+// chunkIDs returns the distinct cited chunk IDs in citation order (never nil).
+func (a structuredAnswer) chunkIDs() []string {
+	ids := make([]string, 0, len(a.Citations))
+	seen := make(map[string]bool, len(a.Citations))
+	for _, citation := range a.Citations {
+		if citation.ChunkID == "" || seen[citation.ChunkID] {
+			continue
+		}
+		seen[citation.ChunkID] = true
+		ids = append(ids, citation.ChunkID)
+	}
+	return ids
+}
+
+// citedChunks returns the chunks whose IDs were cited, in context order, and the cited IDs
+// that match no chunk.
+func citedChunks(chunkIDs []string, chunks []chunkData) (cited []chunkData, unmatched []string) {
+	pending := make(map[string]bool, len(chunkIDs))
+	for _, id := range chunkIDs {
+		pending[id] = true
+	}
+	for _, chunk := range chunks {
+		if pending[chunk.result.PointID] {
+			delete(pending, chunk.result.PointID)
+			cited = append(cited, chunk)
+		}
+	}
+	for _, id := range chunkIDs {
+		if pending[id] {
+			unmatched = append(unmatched, id)
+		}
+	}
+	return cited, unmatched
+}
@@ -0,0 +1,167 @@
+package rag
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// structuredChat replies with a fixed answer and records the request's response format.
+type structuredChat struct {
+	reply  string
+	format *llm.ResponseFormat
+	system string
+}
+
+func (c *structuredChat) ChatWithMessages(_ context.Context, messages []llm.Message, params llm.ChatParams) (string, error) {
+	c.format = params.ResponseFormat
+	c.system = messages[0].Content
+	return c.reply, nil
+}
+
+func structuredTestChunks() []chunkData {
+	return []chunkData{
+		{text: "Tomatoes grow in the north bed.", vaultName: "personal", relPath: "garden.md", headingPath: "# Garden > ## Beds", result: vectorstore.SearchResult{PointID: "chunk-beds"}},
+		{text: "Water every morning.", vaultName: "personal", relPath: "garden.md", headingPath: "# Garden > ## Watering", chunkIndex: 1, result: vectorstore.SearchResult{PointID: "chunk-water"}},
+	}
+}
+
+func TestParseStructuredAnswer(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		wantErr   bool
+		wantText  string
+		wantCited []string
+	}{
+		{
+			name:      "bare object",
+			reply:     `{"answer": "In the north bed.", "citations": [{"chunk_id": "chunk-beds"}, {"chunk_id": "chunk-beds"}]}`,
+			wantText:  "In the north bed.",
+			wantCited: []string{"chunk-beds"},
+		},
+		{
+			name:      "code fence",
+			reply:     "```json\n{\"answer\": \"Every morning.\", \"citations\": [{\"chunk_id\": \"chunk-water\"}]}\n```",
+			wantText:  "Every morning.",
+			wantCited: []string{"chunk-water"},
+		},
+		{
+			name:      "no citations",
+			reply:     `{"answer": "The notes do not say.", "citations": []}`,
+			wantText:  "The notes do not say.",
+			wantCited: []string{},
+		},
+		{name: "plain text", reply: "In the north bed [File: garden.md, Section: Beds]", wantErr: true},
+		{name: "object without answer", reply: `{"citations": []}`, wantErr: true},
+		{name: "empty answer", reply: `{"answer": " ", "citations": []}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStructuredAnswer(tt.reply)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseStructuredAnswer() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStructuredAnswer() error = %v", err)
+			}
+			if got.Answer != tt.wantText || strings.Join(got.chunkIDs(), ",") != strings.Join(tt.wantCited, ",") {
+				t.Errorf("parseStructuredAnswer() = %q citing %v, want %q citing %v", got.Answer, got.chunkIDs(), tt.wantText, tt.wantCited)
+			}
+		})
+	}
+}
+
+func TestStructuredAnswerFormat(t *testing.T) {
+	format := structuredAnswerFormat(structuredTestChunks())
+	if format == nil || format.JSONSchema == nil || format.JSONSchema.Name != "cited_answer" {
+		t.Fatalf("structuredAnswerFormat() = %+v, want the cited_answer schema", format)
+	}
+	citations := format.JSONSchema.Schema["properties"].(map[string]any)["citations"].(map[string]any)
+	chunkID := citations["items"].(map[string]any)["properties"].(map[string]any)["chunk_id"].(map[string]any)
+	if enum, _ := chunkID["enum"].([]string); strings.Join(enum, ",") != "chunk-beds,chunk-water" {
+		t.Errorf("chunk_id enum = %v, want the context chunk IDs", chunkID["enum"])
+	}
+}
+
+func TestStructuredCitations(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		reply      string
+		stream     bool
+		wantFormat bool
+		wantAnswer string
+		wantRefs   []string
+	}{
+		{
+			name:       "cited chunk IDs become references",
+			reply:      `{"answer": "Every morning.", "citations": [{"chunk_id": "chunk-water"}]}`,
+			wantFormat: true,
+			wantAnswer: "Every morning.",
+			wantRefs:   []string{"chunk-water"},
+		},
+		{
+			name:       "no citations keep every chunk",
+			reply:      `{"answer": "The notes do not say.", "citations": []}`,
+			wantFormat: true,
+			wantAnswer: "The notes do not say.",
+			wantRefs:   []string{"chunk-beds", "chunk-water"},
+		},
+		{
+			name:       "free-form replies fall back to bracket citations",
+			reply:      "In the north bed [File: garden.md, Section: Beds]",
+			wantFormat: true,
+			wantAnswer: "In the north bed [File: garden.md, Section: Beds]",
+			wantRefs:   []string{"chunk-beds"},
+		},
+		{
+			name:       "streamed answers use bracket citations",
+			reply:      "In the north bed [File: garden.md, Section: Beds]",
+			stream:     true,
+			wantAnswer: "In the north bed [File: garden.md, Section: Beds]",
+			wantRefs:   []string{"chunk-beds"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := &structuredChat{reply: tt.reply}
+			engine := &ragEngine{llmClient: chat, generation: GenerationOptions{StructuredCitations: true}}
+			s := &askState{req: AskRequest{Question: "When do I water the tomatoes?"}, chunks: structuredTestChunks()}
+			if tt.stream {
+				s.onToken = func(string) error { return nil }
+			}
+
+			if err := engine.generateStage(ctx, s); err != nil {
+				t.Fatalf("generateStage() error = %v", err)
+			}
+			if err := engine.verifyStage(ctx, s); err != nil {
+				t.Fatalf("verifyStage() error = %v", err)
+			}
+
+			if (chat.format != nil) != tt.wantFormat {
+				t.Errorf("response format = %+v, want set = %v", chat.format, tt.wantFormat)
+			}
+			if tt.wantFormat != strings.Contains(chat.system, "JSON object") {
+				t.Errorf("system prompt = %q, want JSON instructions = %v", chat.system, tt.wantFormat)
+			}
+			if s.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", s.answer, tt.wantAnswer)
+			}
+			var refs []string
+			for _, ref := range s.references {
+				refs = append(refs, ref.ChunkID)
+			}
+			if strings.Join(refs, ",") != strings.Join(tt.wantRefs, ",") {
+				t.Errorf("references = %v, want %v", refs, tt.wantRefs)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"strings"
+)
+
+// pointTags returns the tags the indexer stored in a chunk point's payload. The in-memory
+// store returns them as []string, Qdrant and pgvector as []any.
+func pointTags(meta map[string]any) []string {
+	switch tags := meta["tags"].(type) {
+	case []string:
+		return tags
+	case []any:
+		result := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// matchTags returns the tags that share a word with the question's keywords (stopwords
+// removed). Nested tags are split into their parts, so "garden/vegetables" matches a
+// question about vegetables, and a trailing plural "s" or "es" is ignored on both sides.
+func matchTags(question string, tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	keywords := make(map[string]struct{})
+	for _, token := range filterStopwords(tokenize(question)) {
+		keywords[singular(token)] = struct{}{}
+	}
+	if len(keywords) == 0 {
+		return nil
+	}
+
+	var matches []string
+	for _, tag := range tags {
+		for _, part := range tokenize(tag) {
+			if _, ok := keywords[singular(part)]; ok {
+				matches = append(matches, tag)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// singular strips the plural ending of words longer than three letters ("es" after o, ch,
+// sh, and x, otherwise an "s" not following another), so "tomatoes" and "tomato" or
+// "recipes" and "recipe" compare equal.
+func singular(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+	if strings.HasSuffix(word, "oes") || strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") || strings.HasSuffix(word, "xes") {
+		return word[:len(word)-2]
+	}
+	if strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+		return word[:len(word)-1]
+	}
+	return word
+}
@@ -0,0 +1,44 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		question string
+		tags     []string
+		want     []string
+	}{
+		{name: "no tags", question: "When are tomatoes harvested?"},
+		{name: "exact word", question: "What did I plan for the garden?", tags: []string{"garden", "work"}, want: []string{"garden"}},
+		{name: "plural question word", question: "When are tomatoes harvested?", tags: []string{"tomato"}, want: []string{"tomato"}},
+		{name: "plural tag", question: "Which recipe uses basil?", tags: []string{"recipes"}, want: []string{"recipes"}},
+		{name: "nested tag part", question: "Which vegetables need shade?", tags: []string{"garden/vegetables", "garden/flowers"}, want: []string{"garden/vegetables"}},
+		{name: "hyphenated tag", question: "Notes on the project kickoff", tags: []string{"project-alpha"}, want: []string{"project-alpha"}},
+		{name: "hashtag in question", question: "Everything under #reading", tags: []string{"reading"}, want: []string{"reading"}},
+		{name: "stopwords do not match", question: "What is in the box?", tags: []string{"in", "the"}},
+		{name: "no overlap", question: "When are tomatoes harvested?", tags: []string{"cooking"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchTags(tt.question, tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchTags(%q, %v) = %v, want %v", tt.question, tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPointTags(t *testing.T) {
+	if got := pointTags(map[string]any{"tags": []any{"garden", 1, "work"}}); !reflect.DeepEqual(got, []string{"garden", "work"}) {
+		t.Errorf("pointTags([]any) = %v, want [garden work]", got)
+	}
+	if got := pointTags(map[string]any{"tags": []string{"garden"}}); !reflect.DeepEqual(got, []string{"garden"}) {
+		t.Errorf("pointTags([]string) = %v, want [garden]", got)
+	}
+	if got := pointTags(nil); got != nil {
+		t.Errorf("pointTags(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,37 @@
+package rag
+
+// ScoreThresholds are the minimum scores a retrieved chunk needs to be used as context.
+type ScoreThresholds struct {
+	// Vector is the minimum vector similarity (0 keeps the default).
+	Vector float32 `json:"vector,omitempty"`
+	// Final is the minimum combined vector and lexical score (0 keeps the default).
+	Final float32 `json:"final,omitempty"`
+}
+
+// DefaultScoreThresholds apply unless configured otherwise (RetrievalOptions.MinScore) or
+// overridden by a request.
+var DefaultScoreThresholds = ScoreThresholds{Vector: minVectorScoreThreshold, Final: minFinalScoreThreshold}
+
+// scoreThresholds returns the thresholds applied to req: its MinScore overrides, raised to
+// the engine's score floor and capped at 1, or the engine's configured defaults. A zero
+// floor keeps that threshold from being lowered below its default, so recall-heavy
+// overrides are opt-in per deployment.
+func (e *ragEngine) scoreThresholds(req AskRequest) ScoreThresholds {
+	defaults := e.retrieval.withDefaults().MinScore
+	return ScoreThresholds{
+		Vector: boundThreshold(req.MinScore.Vector, defaults.Vector, e.scoreFloor.Vector),
+		Final:  boundThreshold(req.MinScore.Final, defaults.Final, e.scoreFloor.Final),
+	}
+}
+
+// boundThreshold returns requested within [floor, 1], or def when nothing was requested.
+// The floor never exceeds def.
+func boundThreshold(requested, def, floor float32) float32 {
+	if requested <= 0 {
+		return def
+	}
+	if floor <= 0 || floor > def {
+		floor = def
+	}
+	return min(max(requested, floor), 1)
+}
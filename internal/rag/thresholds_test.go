@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestScoreThresholds(t *testing.T) {
+	floor := ScoreThresholds{Vector: 0.15, Final: 0.2}
+
+	tests := []struct {
+		name     string
+		floor    ScoreThresholds
+		minScore ScoreThresholds
+		want     ScoreThresholds
+	}{
+		{name: "defaults", floor: floor, want: DefaultScoreThresholds},
+		{
+			name:     "lowered within the floor",
+			floor:    floor,
+			minScore: ScoreThresholds{Vector: 0.2, Final: 0.25},
+			want:     ScoreThresholds{Vector: 0.2, Final: 0.25},
+		},
+		{
+			name:     "lowered past the floor",
+			floor:    floor,
+			minScore: ScoreThresholds{Vector: 0.01, Final: 0.05},
+			want:     floor,
+		},
+		{
+			name:     "only final overridden",
+			floor:    floor,
+			minScore: ScoreThresholds{Final: 0.3},
+			want:     ScoreThresholds{Vector: DefaultScoreThresholds.Vector, Final: 0.3},
+		},
+		{
+			name:     "raised and capped",
+			floor:    floor,
+			minScore: ScoreThresholds{Vector: 0.5, Final: 1.5},
+			want:     ScoreThresholds{Vector: 0.5, Final: 1},
+		},
+		{
+			name:     "no floor keeps the defaults as minimum",
+			minScore: ScoreThresholds{Vector: 0.1, Final: 0.6},
+			want:     ScoreThresholds{Vector: DefaultScoreThresholds.Vector, Final: 0.6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &ragEngine{scoreFloor: tt.floor}
+			if got := engine.scoreThresholds(AskRequest{MinScore: tt.minScore}); got != tt.want {
+				t.Errorf("scoreThresholds() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrieve_MinScoreOverride(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	engine := &ragEngine{
+		vectorStore: mockVectorStore,
+		chunkRepo:   mockChunkRepo,
+		collection:  "notes",
+		scoreFloor:  ScoreThresholds{Vector: 0.1, Final: 0.1},
+	}
+
+	// Below the default vector threshold, but within the lowered one
+	hit := []vectorstore.SearchResult{{PointID: "c1", Score: 0.25, Meta: map[string]any{"rel_path": "garden.md"}}}
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), candidateKPerScope, gomock.Any()).Return(hit, nil).Times(2)
+	mockChunkRepo.EXPECT().GetByID(gomock.Any(), "c1").Return(&storage.ChunkRecord{ID: "c1", Text: "Soil notes"}, nil)
+
+	req := AskRequest{Question: "tomatoes"}
+	if pass := engine.retrieve(context.Background(), req, []float32{0.1}, []int{1}, nil, nil, candidateKPerScope); len(pass.candidates) != 0 {
+		t.Errorf("retrieve() with default thresholds kept %d candidates, want 0", len(pass.candidates))
+	}
+
+	// With the reranker on, no lexical overlap leaves a final score of 0.7 * 0.25
+	req.MinScore = ScoreThresholds{Vector: 0.2, Final: 0.15}
+	pass := engine.retrieve(context.Background(), req, []float32{0.1}, []int{1}, nil, nil, candidateKPerScope)
+	if len(pass.filtered) != 1 || pass.minFinalScore != 0.15 {
+		t.Errorf("retrieve() with lowered thresholds = %d filtered, minFinalScore %v; want 1 and 0.15", len(pass.filtered), pass.minFinalScore)
+	}
+}
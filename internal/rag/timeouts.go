@@ -0,0 +1,44 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"helloworld-ai/internal/llm"
+)
+
+// TimeoutOptions bound the external calls made while answering, so a slow embedding server,
+// vector store, or chat model cannot hang a request. Each call gets its own budget within the
+// request context; zero durations leave calls bounded by the request context alone.
+type TimeoutOptions struct {
+	// Embed bounds embedding the question (EMBED_TIMEOUT).
+	Embed time.Duration
+	// Search bounds each vector store search (SEARCH_TIMEOUT). A search that times out is
+	// treated like a failed one: the other scopes of the pass still contribute results.
+	Search time.Duration
+	// Generation bounds each chat model call made for the answer (GENERATION_TIMEOUT). A
+	// streamed answer that times out keeps the text produced so far and is marked Truncated.
+	Generation time.Duration
+}
+
+// withTimeout derives a context bounded by timeout (the parent itself when timeout is zero).
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// budgetExceeded reports whether err comes from a call's own timeout running out, as opposed
+// to the request being cancelled or timing out as a whole.
+func budgetExceeded(ctx context.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+}
+
+// chat makes a one-piece chat model call within the generation timeout.
+func (e *ragEngine) chat(ctx context.Context, messages []llm.Message, params llm.ChatParams) (string, error) {
+	chatCtx, cancel := withTimeout(ctx, e.timeouts.Generation)
+	defer cancel()
+	return e.llmClient.ChatWithMessages(chatCtx, messages, params)
+}
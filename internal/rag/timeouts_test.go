@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/storage"
+	storage_mocks "helloworld-ai/internal/storage/mocks"
+	"helloworld-ai/internal/vectorstore"
+	vectorstore_mocks "helloworld-ai/internal/vectorstore/mocks"
+
+	"go.uber.org/mock/gomock"
+)
+
+// stallingChat streams its chunks, then stalls until the context ends; one-piece calls
+// stall from the start.
+type stallingChat struct {
+	chunks []string
+}
+
+func (s *stallingChat) ChatWithMessages(ctx context.Context, _ []llm.Message, _ llm.ChatParams) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (s *stallingChat) StreamChatWithMessages(ctx context.Context, _ []llm.Message, _ llm.ChatParams, callback func(chunk string) error) error {
+	for _, chunk := range s.chunks {
+		if err := callback(chunk); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return errors.New("failed to read stream: unexpected EOF")
+}
+
+func TestRagEngine_GenerateTimeout(t *testing.T) {
+	messages := []llm.Message{{Role: "user", Content: "What did I plant in the north bed?"}}
+	engine := &ragEngine{
+		llmClient: &stallingChat{chunks: []string{"Tomatoes ", "and"}},
+		timeouts:  TimeoutOptions{Generation: 20 * time.Millisecond},
+	}
+	onToken := func(string) error { return nil }
+
+	// A stream cut off by the generation timeout keeps what was produced
+	answer, truncated, err := engine.generate(context.Background(), messages, llm.ChatParams{}, onToken)
+	if err != nil || !truncated || answer != "Tomatoes and" {
+		t.Errorf("generate() = %q, %v, %v; want the partial answer, truncated", answer, truncated, err)
+	}
+
+	// One-piece answers have nothing to keep
+	if _, _, err := engine.generate(context.Background(), messages, llm.ChatParams{}, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("generate() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The request ending is not the generation timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	engine.timeouts.Generation = time.Minute
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, truncated, err := engine.generate(ctx, messages, llm.ChatParams{}, onToken); err == nil || truncated {
+		t.Errorf("generate() = truncated %v, %v; want an error once the request is cancelled", truncated, err)
+	}
+}
+
+func TestSearchCandidates_SearchTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVectorStore := vectorstore_mocks.NewMockVectorStore(ctrl)
+	mockChunkRepo := storage_mocks.NewMockChunkStore(ctrl)
+	engine := &ragEngine{
+		vectorStore: mockVectorStore,
+		chunkRepo:   mockChunkRepo,
+		collection:  "notes",
+		timeouts:    TimeoutOptions{Search: 20 * time.Millisecond},
+	}
+
+	// Vault 1 hangs past the search timeout; vault 2 still contributes its results
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, map[string]any{"vault_id": 1}).
+		DoAndReturn(func(ctx context.Context, _ string, _ []float32, _ int, _ map[string]any) ([]vectorstore.SearchResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	mockVectorStore.EXPECT().Search(gomock.Any(), "notes", gomock.Any(), 5, map[string]any{"vault_id": 2}).
+		Return([]vectorstore.SearchResult{{PointID: "chunk-1", Score: 0.9}}, nil)
+	mockChunkRepo.EXPECT().GetByID(gomock.Any(), "chunk-1").Return(&storage.ChunkRecord{ID: "chunk-1", Text: "Tomatoes grow in the north bed."}, nil)
+
+	pass := engine.searchCandidates(context.Background(), AskRequest{}, []float32{0.1}, []int{1, 2}, nil, nil, 5)
+	if pass.searchErr != nil || len(pass.deduplicated) != 1 || pass.deduplicated[0].PointID != "chunk-1" {
+		t.Errorf("searchCandidates() = %+v, %v; want the result of the vault that answered", pass.deduplicated, pass.searchErr)
+	}
+}
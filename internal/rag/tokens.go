@@ -0,0 +1,51 @@
+package rag
+
+import (
+	"context"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// countPromptTokens counts the tokens of the answer prompt's parts with the engine's
+// tokenizer. It returns nil without a tokenizer or when a count fails, since the counts
+// only inform debug responses.
+func (e *ragEngine) countPromptTokens(ctx context.Context, systemPrompt, contextBlock, question string) *PromptTokens {
+	if e.tokenizer == nil {
+		return nil
+	}
+	logger := contextutil.LoggerFromContext(ctx)
+
+	var counts [3]int
+	for i, text := range []string{systemPrompt, contextBlock, question} {
+		// The system prompt rarely changes, so its count is cached by text
+		if i == 0 {
+			if count, ok := e.promptTokens.Get(text); ok {
+				counts[i] = count
+				continue
+			}
+		}
+		count, err := e.tokenizer.CountTokens(ctx, text)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to count prompt tokens", "error", err)
+			return nil
+		}
+		if i == 0 {
+			e.promptTokens.Set(text, count)
+		}
+		counts[i] = count
+	}
+
+	tokens := &PromptTokens{
+		SystemPrompt: counts[0],
+		Context:      counts[1],
+		Question:     counts[2],
+		Total:        counts[0] + counts[1] + counts[2],
+	}
+	logger.InfoContext(ctx, "prompt tokens counted",
+		"system_prompt_tokens", tokens.SystemPrompt,
+		"context_tokens", tokens.Context,
+		"question_tokens", tokens.Question,
+		"total_tokens", tokens.Total,
+	)
+	return tokens
+}
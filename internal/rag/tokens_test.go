@@ -0,0 +1,43 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// wordTokenizer counts one token per word and fails from the failAt-th call on (0 never fails).
+type wordTokenizer struct {
+	calls  int
+	failAt int
+}
+
+func (t *wordTokenizer) CountTokens(_ context.Context, text string) (int, error) {
+	t.calls++
+	if t.failAt > 0 && t.calls >= t.failAt {
+		return 0, errors.New("tokenize endpoint not found")
+	}
+	return len(strings.Fields(text)), nil
+}
+
+func TestCountPromptTokens(t *testing.T) {
+	ctx := context.Background()
+	systemPrompt := "Answer from the context."
+	contextBlock := "--- Context from notes --- tomatoes grow in the north bed"
+	question := "Where are the tomatoes?"
+
+	got := (&ragEngine{tokenizer: &wordTokenizer{}}).countPromptTokens(ctx, systemPrompt, contextBlock, question)
+	want := &PromptTokens{SystemPrompt: 4, Context: 11, Question: 4, Total: 19}
+	if got == nil || *got != *want {
+		t.Errorf("countPromptTokens() = %+v, want %+v", got, want)
+	}
+
+	// Counts are best effort: no tokenizer or a failed count omits them
+	if got := (&ragEngine{}).countPromptTokens(ctx, systemPrompt, contextBlock, question); got != nil {
+		t.Errorf("countPromptTokens() without tokenizer = %+v, want nil", got)
+	}
+	if got := (&ragEngine{tokenizer: &wordTokenizer{failAt: 2}}).countPromptTokens(ctx, systemPrompt, contextBlock, question); got != nil {
+		t.Errorf("countPromptTokens() with a failing tokenizer = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,608 @@
+package rag
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tool names reported in ToolResult.Tool.
+const (
+	ToolDate           = "date"
+	ToolCalculator     = "calculator"
+	ToolUnitConversion = "unit_conversion"
+)
+
+const (
+	// maxToolDates caps how many distinct dates the date tool resolves per question.
+	maxToolDates = 5
+	// maxToolResults caps the number of tool results injected into the prompt.
+	maxToolResults = 10
+)
+
+var (
+	// dateIntentPattern detects questions asking for date arithmetic ("how many days since...").
+	dateIntentPattern = regexp.MustCompile(`(?i)\b(how many|how long|number of)\s+(days|weeks|months|years)\b|\bhow long (ago|since|until|before|after)\b|\bhow old\b|\b(days|weeks|months|years) (since|until|ago|between|left)\b`)
+	// dateOffsetPattern matches "90 days from today", "2 weeks after 2024-03-01", "3 months ago".
+	dateOffsetPattern = regexp.MustCompile(`(?i)\b(\d+)\s+(day|week|month|year)s?\s+(?:(from|after|before)\s+(today|now|yesterday|tomorrow|\d{4}-\d{2}-\d{2})|(ago))\b`)
+	isoDatePattern    = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	// monthDayYearPattern matches "March 1, 2024" and "Mar 1st 2024".
+	monthDayYearPattern = regexp.MustCompile(`(?i)\b(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	// dayMonthYearPattern matches "1 March 2024".
+	dayMonthYearPattern = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?,?\s+(\d{4})\b`)
+
+	percentOfPattern    = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*%\s*of\s+(\d+(?:\.\d+)?)`)
+	expressionPattern   = regexp.MustCompile(`[\d(][\d\s.+\-*/^()]*[\d)]`)
+	operatorPattern     = regexp.MustCompile(`[\d)]\s*[-+*/^]\s*[\d(]`)
+	numberRangePattern  = regexp.MustCompile(`\d-\d`)
+	thousandsPattern    = regexp.MustCompile(`(\d),(\d{3})\b`)
+	spacedTimesPattern  = regexp.MustCompile(`(\d)\s+[x×]\s+(\d)`)
+	calculatorOperators = strings.NewReplacer(
+		" multiplied by ", " * ",
+		" divided by ", " / ",
+		" plus ", " + ",
+		" minus ", " - ",
+		" times ", " * ",
+		"×", "*",
+		"÷", "/",
+	)
+
+	// unitConversionPattern matches "5 km to miles", "70 f in c".
+	unitConversionPattern = regexp.MustCompile(`(?i)(-?\d+(?:\.\d+)?)\s*(°?[a-z]+)\s+(?:to|in|into)\s+(°?[a-z]+)`)
+	// unitQuestionPattern matches "how many miles is 5 km".
+	unitQuestionPattern = regexp.MustCompile(`(?i)how many\s+(°?[a-z]+)\s+(?:is|are|in)\s+(-?\d+(?:\.\d+)?)\s*(°?[a-z]+)`)
+)
+
+var monthsByPrefix = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// unit describes a unit of measure as a factor to its dimension's base unit.
+type unit struct {
+	symbol    string
+	dimension string
+	factor    float64 // Base units per unit (unused for temperature)
+}
+
+var units = func() map[string]unit {
+	defs := []struct {
+		unit    unit
+		aliases []string
+	}{
+		{unit{"mm", "length", 0.001}, []string{"mm", "millimeter", "millimeters", "millimetre", "millimetres"}},
+		{unit{"cm", "length", 0.01}, []string{"cm", "centimeter", "centimeters", "centimetre", "centimetres"}},
+		{unit{"m", "length", 1}, []string{"m", "meter", "meters", "metre", "metres"}},
+		{unit{"km", "length", 1000}, []string{"km", "kilometer", "kilometers", "kilometre", "kilometres"}},
+		{unit{"in", "length", 0.0254}, []string{"inch", "inches"}},
+		{unit{"ft", "length", 0.3048}, []string{"ft", "foot", "feet"}},
+		{unit{"yd", "length", 0.9144}, []string{"yd", "yard", "yards"}},
+		{unit{"mi", "length", 1609.344}, []string{"mi", "mile", "miles"}},
+		{unit{"mg", "mass", 0.000001}, []string{"mg", "milligram", "milligrams"}},
+		{unit{"g", "mass", 0.001}, []string{"g", "gram", "grams"}},
+		{unit{"kg", "mass", 1}, []string{"kg", "kilo", "kilos", "kilogram", "kilograms"}},
+		{unit{"oz", "mass", 0.028349523125}, []string{"oz", "ounce", "ounces"}},
+		{unit{"lb", "mass", 0.45359237}, []string{"lb", "lbs", "pound", "pounds"}},
+		{unit{"ml", "volume", 0.001}, []string{"ml", "milliliter", "milliliters", "millilitre", "millilitres"}},
+		{unit{"l", "volume", 1}, []string{"l", "liter", "liters", "litre", "litres"}},
+		{unit{"tsp", "volume", 0.00492892159375}, []string{"tsp", "teaspoon", "teaspoons"}},
+		{unit{"tbsp", "volume", 0.01478676478125}, []string{"tbsp", "tablespoon", "tablespoons"}},
+		{unit{"cup", "volume", 0.2365882365}, []string{"cup", "cups"}},
+		{unit{"pt", "volume", 0.473176473}, []string{"pt", "pint", "pints"}},
+		{unit{"qt", "volume", 0.946352946}, []string{"qt", "quart", "quarts"}},
+		{unit{"gal", "volume", 3.785411784}, []string{"gal", "gallon", "gallons"}},
+		{unit{"km/h", "speed", 1 / 3.6}, []string{"kph", "kmh"}},
+		{unit{"mph", "speed", 0.44704}, []string{"mph"}},
+		{unit{"°C", "temperature", 0}, []string{"c", "°c", "celsius"}},
+		{unit{"°F", "temperature", 0}, []string{"f", "°f", "fahrenheit"}},
+		{unit{"K", "temperature", 0}, []string{"k", "kelvin"}},
+	}
+	byAlias := make(map[string]unit)
+	for _, def := range defs {
+		for _, alias := range def.aliases {
+			byAlias[alias] = def.unit
+		}
+	}
+	return byAlias
+}()
+
+// runTools detects deterministic sub-tasks in the question (date arithmetic, calculations,
+// unit conversions) and computes them, so the LLM does not have to do the arithmetic itself.
+// contextTexts are the selected chunk texts; the date tool also resolves dates found there.
+func runTools(question string, contextTexts []string, now time.Time) []ToolResult {
+	var results []ToolResult
+	results = append(results, runDateTool(question, contextTexts, now)...)
+	results = append(results, runUnitConversionTool(question)...)
+	results = append(results, runCalculatorTool(question)...)
+	if len(results) > maxToolResults {
+		results = results[:maxToolResults]
+	}
+	return results
+}
+
+// formatToolResults renders tool results as a prompt section (empty when there are none).
+func formatToolResults(results []ToolResult) string {
+	if len(results) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("--- Tool results ---\n")
+	builder.WriteString("These values were computed exactly. Use them instead of doing the arithmetic yourself.\n")
+	for _, result := range results {
+		builder.WriteString(fmt.Sprintf("[%s] %s\n", result.Tool, result.Output))
+	}
+	builder.WriteString("--- End Tool results ---\n")
+	return builder.String()
+}
+
+// runDateTool resolves dates against today when the question asks for date arithmetic.
+func runDateTool(question string, contextTexts []string, now time.Time) []ToolResult {
+	offsets := dateOffsetPattern.FindAllStringSubmatch(question, -1)
+	if !dateIntentPattern.MatchString(question) && len(offsets) == 0 {
+		return nil
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	results := []ToolResult{{
+		Tool:   ToolDate,
+		Input:  "today",
+		Output: fmt.Sprintf("Today is %s", formatDate(today)),
+	}}
+
+	for _, offset := range offsets {
+		if result, ok := resolveDateOffset(offset, today); ok {
+			results = append(results, result)
+		}
+	}
+
+	// Dates in the question come first, then dates found in the retrieved context
+	questionDates := extractDates(question)
+	dates := questionDates
+	for _, text := range contextTexts {
+		dates = append(dates, extractDates(text)...)
+	}
+	seen := make(map[time.Time]bool)
+	distinct := 0
+	for _, date := range dates {
+		if seen[date] || date.Equal(today) {
+			continue
+		}
+		seen[date] = true
+		distinct++
+		if distinct > maxToolDates {
+			break
+		}
+		results = append(results, ToolResult{
+			Tool:   ToolDate,
+			Input:  date.Format(time.DateOnly),
+			Output: describeDateFromToday(date, today),
+		})
+	}
+
+	if len(questionDates) >= 2 && !questionDates[0].Equal(questionDates[1]) {
+		from, to := questionDates[0], questionDates[1]
+		if to.Before(from) {
+			from, to = to, from
+		}
+		results = append(results, ToolResult{
+			Tool:   ToolDate,
+			Input:  fmt.Sprintf("%s to %s", from.Format(time.DateOnly), to.Format(time.DateOnly)),
+			Output: fmt.Sprintf("From %s to %s is %s", formatDate(from), formatDate(to), describeSpan(from, to)),
+		})
+	}
+
+	return results
+}
+
+// resolveDateOffset computes the date for a dateOffsetPattern match.
+func resolveDateOffset(match []string, today time.Time) (ToolResult, bool) {
+	amount, err := strconv.Atoi(match[1])
+	if err != nil {
+		return ToolResult{}, false
+	}
+	unitName := strings.ToLower(match[2])
+
+	base := today
+	direction := match[3]
+	if match[5] != "" {
+		direction = "before"
+	}
+	switch strings.ToLower(match[4]) {
+	case "", "today", "now":
+	case "yesterday":
+		base = today.AddDate(0, 0, -1)
+	case "tomorrow":
+		base = today.AddDate(0, 0, 1)
+	default:
+		parsed, err := time.Parse(time.DateOnly, match[4])
+		if err != nil {
+			return ToolResult{}, false
+		}
+		base = parsed
+	}
+
+	sign := 1
+	if strings.EqualFold(direction, "before") {
+		sign = -1
+	}
+	var resolved time.Time
+	switch unitName {
+	case "day":
+		resolved = base.AddDate(0, 0, sign*amount)
+	case "week":
+		resolved = base.AddDate(0, 0, sign*amount*7)
+	case "month":
+		resolved = base.AddDate(0, sign*amount, 0)
+	case "year":
+		resolved = base.AddDate(sign*amount, 0, 0)
+	}
+
+	return ToolResult{
+		Tool:   ToolDate,
+		Input:  match[0],
+		Output: fmt.Sprintf("%s is %s", match[0], formatDate(resolved)),
+	}, true
+}
+
+// extractDates returns the dates written in text, in order of appearance.
+func extractDates(text string) []time.Time {
+	type found struct {
+		pos  int
+		date time.Time
+	}
+	var matches []found
+
+	for _, m := range isoDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		year, _ := strconv.Atoi(text[m[2]:m[3]])
+		month, _ := strconv.Atoi(text[m[4]:m[5]])
+		day, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := makeDate(year, time.Month(month), day); ok {
+			matches = append(matches, found{m[0], date})
+		}
+	}
+	for _, m := range monthDayYearPattern.FindAllStringSubmatchIndex(text, -1) {
+		day, _ := strconv.Atoi(text[m[4]:m[5]])
+		year, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := makeDate(year, parseMonth(text[m[2]:m[3]]), day); ok {
+			matches = append(matches, found{m[0], date})
+		}
+	}
+	for _, m := range dayMonthYearPattern.FindAllStringSubmatchIndex(text, -1) {
+		day, _ := strconv.Atoi(text[m[2]:m[3]])
+		year, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := makeDate(year, parseMonth(text[m[4]:m[5]]), day); ok {
+			matches = append(matches, found{m[0], date})
+		}
+	}
+
+	// Restore order of appearance across the three patterns
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].pos < matches[j-1].pos; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+	dates := make([]time.Time, 0, len(matches))
+	for _, match := range matches {
+		dates = append(dates, match.date)
+	}
+	return dates
+}
+
+// makeDate builds a UTC date, rejecting out-of-range values such as February 30.
+func makeDate(year int, month time.Month, day int) (time.Time, bool) {
+	if month < time.January || month > time.December || day < 1 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// parseMonth maps a month name or abbreviation to its time.Month (0 if unknown).
+func parseMonth(name string) time.Month {
+	name = strings.ToLower(name)
+	if len(name) < 3 {
+		return 0
+	}
+	return monthsByPrefix[name[:3]]
+}
+
+// describeDateFromToday states how far date is from today.
+func describeDateFromToday(date, today time.Time) string {
+	if date.Before(today) {
+		return fmt.Sprintf("%s was %s ago", formatDate(date), describeSpan(date, today))
+	}
+	return fmt.Sprintf("%s is in %s", formatDate(date), describeSpan(today, date))
+}
+
+// describeSpan formats the distance between two dates (from <= to) in days and calendar units.
+func describeSpan(from, to time.Time) string {
+	// Count from the dates' UTC midnights in seconds: time.Duration saturates at about 292 years
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	days := int((toDay.Unix() - fromDay.Unix()) / 86400)
+	span := pluralize(days, "day")
+
+	years, months, remainingDays := calendarDiff(from, to)
+	if years > 0 || months > 0 {
+		var parts []string
+		if years > 0 {
+			parts = append(parts, pluralize(years, "year"))
+		}
+		if months > 0 {
+			parts = append(parts, pluralize(months, "month"))
+		}
+		if remainingDays > 0 {
+			parts = append(parts, pluralize(remainingDays, "day"))
+		}
+		span += fmt.Sprintf(" (%s)", strings.Join(parts, ", "))
+	} else if days >= 7 {
+		span += fmt.Sprintf(" (%.1f weeks)", float64(days)/7)
+	}
+	return span
+}
+
+// calendarDiff returns the whole years, months, and days between from and to (from <= to).
+func calendarDiff(from, to time.Time) (years, months, days int) {
+	years = to.Year() - from.Year()
+	months = int(to.Month()) - int(from.Month())
+	days = to.Day() - from.Day()
+	if days < 0 {
+		months--
+		// Borrow the length of the month before to's month
+		days += time.Date(to.Year(), to.Month(), 0, 0, 0, 0, 0, time.UTC).Day()
+	}
+	if months < 0 {
+		years--
+		months += 12
+	}
+	return years, months, days
+}
+
+func formatDate(date time.Time) string {
+	return fmt.Sprintf("%s (%s)", date.Format(time.DateOnly), date.Weekday())
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// runCalculatorTool evaluates arithmetic written in the question ("12.5 * 8", "15% of 240").
+func runCalculatorTool(question string) []ToolResult {
+	text := " " + strings.ToLower(question) + " "
+	// Dates are not subtractions
+	text = isoDatePattern.ReplaceAllString(text, " ")
+	for thousandsPattern.MatchString(text) {
+		text = thousandsPattern.ReplaceAllString(text, "$1$2")
+	}
+
+	var results []ToolResult
+	for _, match := range percentOfPattern.FindAllStringSubmatch(text, -1) {
+		percent, err1 := strconv.ParseFloat(match[1], 64)
+		total, err2 := strconv.ParseFloat(match[2], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		results = append(results, ToolResult{
+			Tool:   ToolCalculator,
+			Input:  match[0],
+			Output: fmt.Sprintf("%s%% of %s = %s", match[1], match[2], formatNumber(percent/100*total)),
+		})
+	}
+	text = percentOfPattern.ReplaceAllString(text, " ")
+
+	text = calculatorOperators.Replace(text)
+	text = spacedTimesPattern.ReplaceAllString(text, "$1 * $2")
+	for _, candidate := range expressionPattern.FindAllString(text, -1) {
+		expr := strings.TrimSpace(candidate)
+		// "10-15" is a range, not a subtraction
+		if !operatorPattern.MatchString(expr) || numberRangePattern.MatchString(expr) {
+			continue
+		}
+		value, err := evaluateExpression(expr)
+		if err != nil {
+			continue
+		}
+		results = append(results, ToolResult{
+			Tool:   ToolCalculator,
+			Input:  expr,
+			Output: fmt.Sprintf("%s = %s", expr, formatNumber(value)),
+		})
+	}
+	return results
+}
+
+// evaluateExpression evaluates + - * / ^ and parentheses with the usual precedence.
+func evaluateExpression(expr string) (float64, error) {
+	p := &expressionParser{input: strings.ReplaceAll(expr, " ", "")}
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+type expressionParser struct {
+	input string
+	pos   int
+}
+
+func (p *expressionParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *expressionParser) parseSum() (float64, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseProduct() (float64, error) {
+	left, err := p.parsePower()
+	if err != nil {
+		return 0, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parsePower()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			left *= right
+		} else {
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parsePower() (float64, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() == '^' {
+		p.pos++
+		// Right-associative: 2^3^2 = 2^9
+		exponent, err := p.parsePower()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+func (p *expressionParser) parseUnary() (float64, error) {
+	if p.peek() == '-' {
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	}
+	if p.peek() == '(' {
+		p.pos++
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return value, nil
+	}
+
+	start := p.pos
+	for c := p.peek(); (c >= '0' && c <= '9') || c == '.'; c = p.peek() {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("expected number at position %d", start)
+	}
+	return strconv.ParseFloat(p.input[start:p.pos], 64)
+}
+
+// runUnitConversionTool converts quantities written as "5 km to miles" or "how many miles is 5 km".
+func runUnitConversionTool(question string) []ToolResult {
+	text := strings.ToLower(question)
+
+	type request struct {
+		input      string
+		value      string
+		fromSymbol string
+		toSymbol   string
+	}
+	var requests []request
+	for _, match := range unitConversionPattern.FindAllStringSubmatch(text, -1) {
+		requests = append(requests, request{match[0], match[1], match[2], match[3]})
+	}
+	for _, match := range unitQuestionPattern.FindAllStringSubmatch(text, -1) {
+		requests = append(requests, request{match[0], match[2], match[3], match[1]})
+	}
+
+	var results []ToolResult
+	for _, req := range requests {
+		from, okFrom := units[req.fromSymbol]
+		to, okTo := units[req.toSymbol]
+		if !okFrom || !okTo || from.dimension != to.dimension || from.symbol == to.symbol {
+			continue
+		}
+		value, err := strconv.ParseFloat(req.value, 64)
+		if err != nil {
+			continue
+		}
+		results = append(results, ToolResult{
+			Tool:   ToolUnitConversion,
+			Input:  req.input,
+			Output: fmt.Sprintf("%s %s = %s %s", formatNumber(value), from.symbol, formatNumber(convertUnit(value, from, to)), to.symbol),
+		})
+	}
+	return results
+}
+
+// convertUnit converts value between two units of the same dimension.
+func convertUnit(value float64, from, to unit) float64 {
+	if from.dimension != "temperature" {
+		return value * from.factor / to.factor
+	}
+
+	celsius := value
+	switch from.symbol {
+	case "°F":
+		celsius = (value - 32) * 5 / 9
+	case "K":
+		celsius = value - 273.15
+	}
+	switch to.symbol {
+	case "°F":
+		return celsius*9/5 + 32
+	case "K":
+		return celsius + 273.15
+	}
+	return celsius
+}
+
+// formatNumber formats a result with at most six decimal places and no trailing zeros.
+func formatNumber(value float64) string {
+	rounded := math.Round(value*1e6) / 1e6
+	if rounded == 0 {
+		rounded = 0 // Avoid "-0"
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
@@ -0,0 +1,138 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTools(t *testing.T) {
+	now := time.Date(2026, time.October, 16, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		question     string
+		contextTexts []string
+		want         []string // Expected outputs, in order
+	}{
+		{
+			name:     "no intent",
+			question: "What did I plant in the garden?",
+			want:     nil,
+		},
+		{
+			name:         "days since a date in the context",
+			question:     "How many days since I started my new job?",
+			contextTexts: []string{"Started at Acme on March 1, 2024 as a backend engineer."},
+			want: []string{
+				"Today is 2026-10-16 (Friday)",
+				"2024-03-01 (Friday) was 959 days (2 years, 7 months, 15 days) ago",
+			},
+		},
+		{
+			name:     "days between two dates in the question",
+			question: "How many days between 2024-01-10 and 5 February 2024?",
+			want: []string{
+				"Today is 2026-10-16 (Friday)",
+				"2024-01-10 (Wednesday) was 1010 days (2 years, 9 months, 6 days) ago",
+				"2024-02-05 (Monday) was 984 days (2 years, 8 months, 11 days) ago",
+				"From 2024-01-10 (Wednesday) to 2024-02-05 (Monday) is 26 days (3.7 weeks)",
+			},
+		},
+		{
+			name:     "days since a date centuries ago",
+			question: "How many days since 1066-10-14?",
+			want: []string{
+				"Today is 2026-10-16 (Friday)",
+				"1066-10-14 (Sunday) was 350635 days (960 years, 2 days) ago",
+			},
+		},
+		{
+			name:     "date offset",
+			question: "What date is 90 days from today?",
+			want: []string{
+				"Today is 2026-10-16 (Friday)",
+				"90 days from today is 2027-01-14 (Thursday)",
+			},
+		},
+		{
+			name:     "arithmetic",
+			question: "What is (12.5 + 7.5) * 3 and 15% of 1,200?",
+			want: []string{
+				"15% of 1200 = 180",
+				"(12.5 + 7.5) * 3 = 60",
+			},
+		},
+		{
+			name:     "worded arithmetic",
+			question: "what is 7 times 6 minus 2",
+			want:     []string{"7 * 6 - 2 = 40"},
+		},
+		{
+			name:     "ranges are not subtractions",
+			question: "Which recipes take 10-15 minutes?",
+			want:     nil,
+		},
+		{
+			name:     "unit conversion",
+			question: "Convert 5 km to miles and how many fahrenheit is 100 c",
+			want: []string{
+				"5 km = 3.106856 mi",
+				"100 °C = 212 °F",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := runTools(tt.question, tt.contextTexts, now)
+			got := make([]string, 0, len(results))
+			for _, result := range results {
+				got = append(got, result.Output)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("runTools(%q) outputs =\n%s\nwant\n%s", tt.question, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    float64
+		wantErr bool
+	}{
+		{expr: "1 + 2 * 3", want: 7},
+		{expr: "(1 + 2) * 3", want: 9},
+		{expr: "2 ^ 3 ^ 2", want: 512},
+		{expr: "10 / 4", want: 2.5},
+		{expr: "-3 + 5", want: 2},
+		{expr: "1 / 0", wantErr: true},
+		{expr: "(1 + 2", wantErr: true},
+		{expr: "1 + ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := evaluateExpression(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("evaluateExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatToolResults(t *testing.T) {
+	if got := formatToolResults(nil); got != "" {
+		t.Errorf("formatToolResults(nil) = %q, want empty", got)
+	}
+
+	got := formatToolResults([]ToolResult{{Tool: ToolCalculator, Input: "2 + 2", Output: "2 + 2 = 4"}})
+	if !strings.HasPrefix(got, "--- Tool results ---\n") || !strings.Contains(got, "[calculator] 2 + 2 = 4\n") {
+		t.Errorf("formatToolResults() = %q, want tool results section", got)
+	}
+}
@@ -0,0 +1,56 @@
+package rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"helloworld-ai/internal/llm"
+	"helloworld-ai/internal/tracing"
+)
+
+// slowChat answers after a delay so the generation span has a measurable duration.
+type slowChat struct {
+	delay time.Duration
+}
+
+func (c *slowChat) ChatWithMessages(context.Context, []llm.Message, llm.ChatParams) (string, error) {
+	time.Sleep(c.delay)
+	return "In the north bed [File: garden.md, Section: Beds]", nil
+}
+
+func TestGenerateStage_LatencyFromSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	s := &askState{
+		req:    AskRequest{Question: "Where are the tomatoes?"},
+		chunks: []chunkData{{text: "Tomatoes grow in the north bed.", vaultName: "personal", relPath: "garden.md", headingPath: "# Garden > ## Beds"}},
+	}
+	ctx := context.Background()
+	_, s.retrievalSpan = tracing.Start(ctx, "rag.retrieval")
+	time.Sleep(5 * time.Millisecond)
+
+	engine := &ragEngine{llmClient: &slowChat{delay: 5 * time.Millisecond}}
+	if err := engine.generateStage(ctx, s); err != nil {
+		t.Fatalf("generateStage() error = %v", err)
+	}
+
+	// The latency breakdown reports exactly the exported span durations
+	durations := make(map[string]int64)
+	for _, span := range recorder.Ended() {
+		durations[span.Name()] = span.EndTime().Sub(span.StartTime()).Milliseconds()
+	}
+	if got, ok := durations["rag.retrieval"]; !ok || got != s.retrievalMs || got < 5 {
+		t.Errorf("retrieval span = %d ms (recorded %v), retrievalMs = %d, want equal and >= 5", got, ok, s.retrievalMs)
+	}
+	if got, ok := durations["rag.generation"]; !ok || got != s.generationMs || got < 5 {
+		t.Errorf("generation span = %d ms (recorded %v), generationMs = %d, want equal and >= 5", got, ok, s.generationMs)
+	}
+}
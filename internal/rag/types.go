@@ -1,5 +1,7 @@
 package rag
 
+import "time"
+
 // AskRequest represents a RAG query request.
 type AskRequest struct {
 	// Question is the user's question to answer.
@@ -14,10 +16,48 @@ type AskRequest struct {
 	Detail string `json:"detail,omitempty"`
 	// Debug enables debug mode, returning detailed retrieval information.
 	Debug bool `json:"debug,omitempty"`
+	// IncludeCold also searches chunks moved to cold storage.
+	IncludeCold bool `json:"include_cold,omitempty"`
+	// Tags restricts the search to notes carrying all of these tags (or tags nested under them).
+	Tags []string `json:"tags,omitempty"`
+	// UpdatedBefore restricts the search to notes last changed before this time (zero: no bound).
+	UpdatedBefore time.Time `json:"updated_before,omitzero"`
+	// UpdatedAfter restricts the search to notes last changed at or after this time (zero: no bound).
+	UpdatedAfter time.Time `json:"updated_after,omitzero"`
+	// CreatedBefore restricts the search to notes whose frontmatter created date is before this time (zero: no bound).
+	CreatedBefore time.Time `json:"created_before,omitzero"`
+	// CreatedAfter restricts the search to notes whose frontmatter created date is at or after this time (zero: no bound).
+	CreatedAfter time.Time `json:"created_after,omitzero"`
+	// MinScore overrides the score thresholds for this request, within the server's floor
+	// (zero fields keep the defaults). Lower thresholds trade precision for recall.
+	MinScore ScoreThresholds `json:"min_score,omitzero"`
+	// Retrieval overrides the retrieval weights and candidate counts for this request
+	// (ignored unless the retrieval_options feature flag is on).
+	Retrieval RetrievalOverrides `json:"retrieval,omitzero"`
+	// NoCache bypasses the answer cache: the question is answered afresh and not stored.
+	NoCache bool `json:"no_cache,omitempty"`
+	// CapturePrompt adds the answer prompt and the raw model output to the debug info
+	// (requires Debug), for answer reports.
+	CapturePrompt bool `json:"capture_prompt,omitempty"`
+	// Generation overrides the model and sampling settings of the answer.
+	Generation GenerationOverrides `json:"generation,omitzero"`
+}
+
+// DocumentRequest represents a question about a document supplied with the request
+// (e.g. pasted text to summarize) rather than about the indexed notes.
+type DocumentRequest struct {
+	// Question is the instruction or question about the document.
+	Question string `json:"question"`
+	// Document is the full document text.
+	Document string `json:"document"`
+	// Name optionally identifies the document (e.g. the uploaded file name).
+	Name string `json:"name,omitempty"`
 }
 
 // Reference represents a reference to a chunk that was used in the answer.
 type Reference struct {
+	// ChunkID is the stable chunk identifier, used to report clicks on the reference.
+	ChunkID string `json:"chunk_id,omitempty"`
 	// Vault is the vault name (e.g., "personal", "work").
 	Vault string `json:"vault"`
 	// RelPath is the relative path to the note file.
@@ -26,6 +66,10 @@ type Reference struct {
 	HeadingPath string `json:"heading_path"`
 	// ChunkIndex is the chunk index within the note.
 	ChunkIndex int `json:"chunk_index"`
+	// Position is the chunk's 1-based place among the note's stored chunks (0 when unknown).
+	Position int `json:"position,omitempty"`
+	// TotalChunks is the number of stored chunks in the note (0 when unknown).
+	TotalChunks int `json:"total_chunks,omitempty"`
 }
 
 // AskResponse represents the response from a RAG query.
@@ -40,6 +84,52 @@ type AskResponse struct {
 	AbstainReason string `json:"abstain_reason,omitempty"`
 	// Debug contains debug information when debug mode is enabled.
 	Debug *DebugInfo `json:"debug,omitempty"`
+	// Meta records the model, prompt version, and retrieval configuration behind the answer.
+	Meta *ResponseMeta `json:"meta,omitempty"`
+	// Safety reports how the safety filter changed the answer (nil when it did not act).
+	Safety *SafetyResult `json:"safety,omitempty"`
+	// Suggestions are follow-up questions the retrieved notes can answer (empty when disabled).
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Cached is true when the answer came from the answer cache.
+	Cached bool `json:"cached,omitempty"`
+	// Truncated is true when the streamed answer was cut off by the generation timeout.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// SearchResponse represents the chunks retrieved for a question without generating an answer.
+type SearchResponse struct {
+	// Results are the selected chunks, best first (empty when nothing relevant was found).
+	Results []SearchResult `json:"results"`
+	// AbstainReason is why no chunks were selected (e.g., "no_relevant_context").
+	AbstainReason string `json:"abstain_reason,omitempty"`
+	// Debug contains debug information when debug mode is enabled.
+	Debug *DebugInfo `json:"debug,omitempty"`
+}
+
+// SearchResult is one retrieved chunk with its scores.
+type SearchResult struct {
+	// Reference identifies the chunk and its note.
+	Reference Reference `json:"reference"`
+	// Text is the full chunk text.
+	Text string `json:"text"`
+	// ScoreVector is the vector similarity score.
+	ScoreVector float64 `json:"score_vector"`
+	// ScoreLexical is the lexical score (0 when the chunk was not reranked).
+	ScoreLexical float64 `json:"score_lexical,omitempty"`
+	// ScoreFinal is the combined final score the results are ranked by.
+	ScoreFinal float64 `json:"score_final"`
+	// Rank is the 1-based rank of the chunk.
+	Rank int `json:"rank"`
+	// ModifiedAt is when the chunk's note was last changed (zero when unknown).
+	ModifiedAt time.Time `json:"modified_at,omitzero"`
+}
+
+// SafetyResult reports a safety filter action on an answer.
+type SafetyResult struct {
+	// Action is SafetyActionRedact or SafetyActionBlock.
+	Action string `json:"action"`
+	// Categories lists the sensitive categories found.
+	Categories []string `json:"categories"`
 }
 
 // DebugInfo contains detailed retrieval information for debugging and evaluation.
@@ -52,6 +142,166 @@ type DebugInfo struct {
 	Latency *LatencyBreakdown `json:"latency,omitempty"`
 	// IndexingCoverage contains indexing coverage statistics.
 	IndexingCoverage *IndexingCoverage `json:"indexing_coverage,omitempty"`
+	// RetrievalExpansion describes the second, broader retrieval pass (nil if the first pass was strong enough).
+	RetrievalExpansion *RetrievalExpansion `json:"retrieval_expansion,omitempty"`
+	// NotePrefilter describes the note-level first stage of two-stage retrieval (nil when disabled).
+	NotePrefilter *NotePrefilter `json:"note_prefilter,omitempty"`
+	// ToolResults lists the deterministic tool outputs injected into the prompt.
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+	// Conflicts lists the conflicting dates and numbers across notes flagged in the prompt.
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	// Features holds the feature flag values in effect for the request.
+	Features map[string]bool `json:"features,omitempty"`
+	// QuestionEmbeddingCached is true when the question embedding came from the cache.
+	QuestionEmbeddingCached bool `json:"question_embedding_cached,omitempty"`
+	// ScoreThresholds are the score thresholds applied to the retrieved chunks.
+	ScoreThresholds *ScoreThresholds `json:"score_thresholds,omitempty"`
+	// PromptTokens is the size of the answer prompt in chat model tokens (nil without a
+	// generated answer or when the tokenizer is unavailable).
+	PromptTokens *PromptTokens `json:"prompt_tokens,omitempty"`
+	// Answerability is the answerability judge's verdict (nil when the check is disabled,
+	// failed, or did not run).
+	Answerability *AnswerabilityCheck `json:"answerability,omitempty"`
+	// Judgement is the answer judge's verdict on the returned answer (nil when judging is
+	// disabled, failed, or did not run).
+	Judgement *AnswerJudgement `json:"judgement,omitempty"`
+	// Prompt is the answer prompt and raw model output (nil unless CapturePrompt was set and
+	// an answer was generated).
+	Prompt *CapturedPrompt `json:"prompt,omitempty"`
+}
+
+// CapturedPrompt is the answer prompt sent to the chat model and its unprocessed reply.
+type CapturedPrompt struct {
+	// System is the system prompt.
+	System string `json:"system"`
+	// User is the user message: the question followed by the context block.
+	User string `json:"user"`
+	// Output is the model's reply before any answer filtering.
+	Output string `json:"output"`
+}
+
+// AnswerabilityCheck reports the answerability judge's verdict in debug responses.
+type AnswerabilityCheck struct {
+	// Score is how well the judge thinks the context answers the question (0-1).
+	Score float32 `json:"score"`
+	// Threshold is the configured score below which the question is abstained.
+	Threshold float32 `json:"threshold"`
+	// Reason is the judge's one-line explanation, if it gave one.
+	Reason string `json:"reason,omitempty"`
+	// LatencyMs is the time spent in the judge call (milliseconds).
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// AnswerJudgement reports the answer judge's verdict in debug responses.
+type AnswerJudgement struct {
+	// Faithfulness is how fully the returned answer's claims are supported by the context (0-1).
+	Faithfulness float32 `json:"faithfulness"`
+	// Relevance is how directly the returned answer addresses the question (0-1).
+	Relevance float32 `json:"relevance"`
+	// Scores holds every criterion of the judge's rubric, normalized to 0-1.
+	Scores map[string]float32 `json:"scores,omitempty"`
+	// Threshold is the faithfulness below which answers are regenerated (0: scores only).
+	Threshold float32 `json:"threshold"`
+	// Reason is the judge's one-line explanation, if it gave one.
+	Reason string `json:"reason,omitempty"`
+	// Attempts is the number of answers generated, the first included.
+	Attempts int `json:"attempts"`
+	// Regenerated is true when a regenerated answer was returned in place of the first.
+	Regenerated bool `json:"regenerated,omitempty"`
+	// Abstained is true when the answer scored below an abstain threshold and was replaced
+	// with an unsupported_answer abstention.
+	Abstained bool `json:"abstained,omitempty"`
+	// PromptVersion identifies the judge rubric (judge.PromptVersion or the rubric file's version).
+	PromptVersion string `json:"prompt_version"`
+}
+
+// PromptTokens is the size of the answer prompt's parts in tokens of the chat model, as
+// counted by its tokenizer.
+type PromptTokens struct {
+	// SystemPrompt is the token count of the system prompt.
+	SystemPrompt int `json:"system_prompt"`
+	// Context is the token count of the context block (chunks, citation instructions, tool results).
+	Context int `json:"context"`
+	// Question is the token count of the question.
+	Question int `json:"question"`
+	// Total is the sum of the parts. The chat template adds a few tokens on top.
+	Total int `json:"total"`
+}
+
+// NotePrefilter describes the note-level first stage of two-stage retrieval.
+type NotePrefilter struct {
+	// TopM is the configured number of notes whose chunks are searched.
+	TopM int `json:"top_m"`
+	// Notes are the notes selected by centroid similarity, best first.
+	Notes []PrefilterNote `json:"notes"`
+	// ChunkResults is the number of chunk hits the second stage returned within the selected notes.
+	ChunkResults int `json:"chunk_results"`
+	// FallbackReason is set when the chunk search ran unrestricted ("no_note_centroids", "search_failed").
+	FallbackReason string `json:"fallback_reason,omitempty"`
+}
+
+// PrefilterNote is a note selected by the note-level prefilter.
+type PrefilterNote struct {
+	// Vault is the vault name.
+	Vault string `json:"vault"`
+	// RelPath is the relative path to the note file.
+	RelPath string `json:"rel_path"`
+	// Score is the cosine similarity between the question and the note centroid.
+	Score float64 `json:"score"`
+}
+
+// ToolResult is the output of a deterministic tool (date arithmetic, calculator, unit conversion)
+// run for the question and injected into the prompt.
+type ToolResult struct {
+	// Tool is the tool that produced the result ("date", "calculator", "unit_conversion").
+	Tool string `json:"tool"`
+	// Input is the part of the question (or context) the tool ran on.
+	Input string `json:"input"`
+	// Output is the computed result as shown to the LLM.
+	Output string `json:"output"`
+}
+
+// Conflict is a pair of statements from different notes that give a different date or
+// number for what appears to be the same fact.
+type Conflict struct {
+	// Kind is the kind of value that differs ("date" or "number").
+	Kind string `json:"kind"`
+	// Claims are the conflicting statements, one per note.
+	Claims []ConflictClaim `json:"claims"`
+}
+
+// ConflictClaim is one side of a Conflict.
+type ConflictClaim struct {
+	// Value is the date or number the statement gives.
+	Value string `json:"value"`
+	// VaultName is the vault of the note making the statement.
+	VaultName string `json:"vault_name"`
+	// RelPath is the note's path within the vault.
+	RelPath string `json:"rel_path"`
+	// HeadingPath is the section of the statement.
+	HeadingPath string `json:"heading_path"`
+	// Text is the statement itself.
+	Text string `json:"text"`
+}
+
+// RetrievalExpansion describes an automatic second retrieval pass after a weak first pass.
+type RetrievalExpansion struct {
+	// Reason is why the first pass was considered weak ("no_results", "below_threshold", "marginal_scores").
+	Reason string `json:"reason"`
+	// InitialCandidateK is the per-scope candidate K of the first pass.
+	InitialCandidateK int `json:"initial_candidate_k"`
+	// ExpandedCandidateK is the per-scope candidate K of the second pass.
+	ExpandedCandidateK int `json:"expanded_candidate_k"`
+	// FolderScopeRelaxed is true when the second pass searched all folders instead of the LLM-selected ones.
+	FolderScopeRelaxed bool `json:"folder_scope_relaxed"`
+	// NoteScopeRelaxed is true when the second pass searched all notes instead of the prefiltered ones.
+	NoteScopeRelaxed bool `json:"note_scope_relaxed"`
+	// InitialTopScore is the best final score of the first pass.
+	InitialTopScore float64 `json:"initial_top_score"`
+	// ExpandedTopScore is the best final score of the second pass.
+	ExpandedTopScore float64 `json:"expanded_top_score"`
+	// Used is true when the second pass scored higher and its results were used.
+	Used bool `json:"used"`
 }
 
 // LatencyBreakdown contains timing information for each phase of the RAG pipeline.
@@ -62,7 +312,7 @@ type LatencyBreakdown struct {
 	RetrievalMs int64 `json:"retrieval_ms"`
 	// GenerationMs is the time spent in LLM generation (milliseconds).
 	GenerationMs int64 `json:"generation_ms"`
-	// JudgeMs is the time spent in answer judging (milliseconds). Always 0 in Go API (judging happens in Python).
+	// JudgeMs is the time spent in answer judging (milliseconds, 0 when judging is disabled).
 	JudgeMs int64 `json:"judge_ms"`
 	// TotalMs is the total time for the entire RAG query (milliseconds).
 	TotalMs int64 `json:"total_ms"`
@@ -86,6 +336,66 @@ type RetrievedChunk struct {
 	Text string `json:"text"`
 	// Rank is the rank of this chunk in the retrieval results (1-based).
 	Rank int `json:"rank"`
+	// Explanation breaks down how the scores were computed (nil when the chunk was not reranked).
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+	// Provenance identifies the indexing run that produced the chunk (nil for chunks indexed before it was recorded).
+	Provenance *ChunkProvenance `json:"provenance,omitempty"`
+	// ClickThrough reports how often the chunk's reference was opened (nil when never shown or clicked).
+	ClickThrough *ClickThrough `json:"click_through,omitempty"`
+}
+
+// ClickThrough counts how often a chunk was returned as a reference and how often users
+// opened it, a relevance signal for ranking and evaluation.
+type ClickThrough struct {
+	// Shown is the number of answers that returned the chunk as a reference.
+	Shown int `json:"shown"`
+	// Clicks is the number of times the reference was opened.
+	Clicks int `json:"clicks"`
+	// Rate is Clicks / Shown (0 when never shown).
+	Rate float64 `json:"rate"`
+}
+
+// ChunkProvenance identifies the indexing run that produced a chunk.
+type ChunkProvenance struct {
+	// ChunkerVersion is the chunker version that split the note.
+	ChunkerVersion string `json:"chunker_version,omitempty"`
+	// EmbeddingModel is the model that embedded the chunk.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// EmbeddedAt is when the chunk was embedded (RFC 3339, UTC).
+	EmbeddedAt string `json:"embedded_at,omitempty"`
+	// RunID is the indexing run that wrote the chunk.
+	RunID string `json:"run_id,omitempty"`
+}
+
+// ScoreExplanation breaks a chunk's scores down into the components that produced them.
+type ScoreExplanation struct {
+	// MatchedTerms are the query terms (stopwords removed) found in the chunk text.
+	MatchedTerms []string `json:"matched_terms"`
+	// TermFrequencies is how often each matched term occurs in the chunk text.
+	TermFrequencies map[string]int `json:"term_frequencies,omitempty"`
+	// ChunkTokenCount is the number of tokens in the chunk text, used to normalize term matches.
+	ChunkTokenCount int `json:"chunk_token_count"`
+	// TermScore is the length-normalized term match component of the lexical score.
+	TermScore float64 `json:"term_score"`
+	// HeadingMatches are the query terms found in the chunk's heading path.
+	HeadingMatches []string `json:"heading_matches,omitempty"`
+	// HeadingBonus is the lexical bonus added for heading matches.
+	HeadingBonus float64 `json:"heading_bonus"`
+	// LexicalCapped is true when the lexical score was clamped to its maximum.
+	LexicalCapped bool `json:"lexical_capped"`
+	// FolderWeight is the multiplier applied to the vector score for the folder the chunk
+	// was found in (1.0 when no folder weighting applied).
+	FolderWeight float64 `json:"folder_weight"`
+	// HeadingExactMatch is true when the question names the chunk's heading, which ranks
+	// the chunk first regardless of its other scores.
+	HeadingExactMatch bool `json:"heading_exact_match,omitempty"`
+	// RerankerScore is the external reranker's relevance score, used in place of the lexical
+	// score in the final score (nil when no external reranker scored the chunk).
+	RerankerScore *float64 `json:"reranker_score,omitempty"`
+	// TagMatches are the chunk's note tags that share a word with the question.
+	TagMatches []string `json:"tag_matches,omitempty"`
+	// TagBoost is the boost added to the final score for TagMatches.
+	TagBoost float64 `json:"tag_boost,omitempty"`
 }
 
 // FolderSelection contains information about folder selection.
@@ -94,6 +404,8 @@ type FolderSelection struct {
 	SelectedFolders []string `json:"selected_folders"`
 	// AvailableFolders is the list of all available folders.
 	AvailableFolders []string `json:"available_folders,omitempty"`
+	// Method is how the folders were chosen: user, cache, heuristic, llm, or fallback.
+	Method string `json:"method,omitempty"`
 }
 
 // IndexingCoverage contains indexing coverage statistics.
@@ -0,0 +1,243 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// ErrTargetNotEmpty is returned by Restore when the database file or a collection already
+// holds data and RestoreOptions.Force is not set.
+var ErrTargetNotEmpty = errors.New("restore target is not empty")
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// DBPath is where the database is written.
+	DBPath string
+	// Force replaces an existing database file and deletes the snapshot's collections
+	// before restoring them.
+	Force bool
+}
+
+// Restore reads a snapshot written by Snapshot.WriteTo, upserts the points of every
+// collection into vectorStore, and writes the database to opts.DBPath. The API must not be
+// running against the database while it is restored. The database is moved into place last,
+// so an interrupted restore leaves no database that refers to missing points.
+func Restore(ctx context.Context, r io.Reader, vectorStore vectorstore.Store, opts RestoreOptions) (*Manifest, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareTargets(ctx, vectorStore, manifest, opts); err != nil {
+		return nil, err
+	}
+
+	collections := make(map[string]CollectionManifest, len(manifest.Collections))
+	for _, collection := range manifest.Collections {
+		collections[collection.File] = collection
+	}
+
+	tmpPath := opts.DBPath + ".restore"
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	restoredDB := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+
+		if header.Name == databaseFile {
+			if err := writeFile(tmpPath, tr); err != nil {
+				return nil, err
+			}
+			restoredDB = true
+			continue
+		}
+		collection, ok := collections[header.Name]
+		if !ok {
+			logger.WarnContext(ctx, "skipping unknown snapshot entry", "name", header.Name)
+			continue
+		}
+		if err := restoreCollection(ctx, tr, vectorStore, collection); err != nil {
+			return nil, err
+		}
+		logger.InfoContext(ctx, "restored collection", "collection", collection.Name, "points", collection.Points)
+	}
+	if !restoredDB {
+		return nil, fmt.Errorf("snapshot has no %s", databaseFile)
+	}
+
+	// A write-ahead log left by the replaced database would be replayed onto the restored one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(opts.DBPath + suffix); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", opts.DBPath+suffix, err)
+		}
+	}
+	if err := os.Rename(tmpPath, opts.DBPath); err != nil {
+		return nil, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	logger.InfoContext(ctx, "snapshot restored", "db_path", opts.DBPath, "chunks", manifest.Chunks, "created_at", manifest.CreatedAt)
+	return manifest, nil
+}
+
+// readManifest reads the manifest, which must be the first archive entry.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if header.Name != manifestFile {
+		return nil, fmt.Errorf("not a snapshot: first entry is %q, want %s", header.Name, manifestFile)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d (want %d)", manifest.Version, Version)
+	}
+	return &manifest, nil
+}
+
+// prepareTargets checks that the database file and the collections are empty, or with
+// Force deletes the collections, and creates the collections for the snapshot's points.
+func prepareTargets(ctx context.Context, vectorStore vectorstore.Store, manifest *Manifest, opts RestoreOptions) error {
+	if _, err := os.Stat(opts.DBPath); err == nil && !opts.Force {
+		return fmt.Errorf("%w: database %s exists", ErrTargetNotEmpty, opts.DBPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.DBPath), 0755); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	for _, collection := range manifest.Collections {
+		exists, err := vectorStore.CollectionExists(ctx, collection.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if !opts.Force {
+				info, err := vectorStore.GetCollectionInfo(ctx, collection.Name)
+				if err != nil {
+					return err
+				}
+				if info.PointsCount > 0 {
+					return fmt.Errorf("%w: collection %s has %d points", ErrTargetNotEmpty, collection.Name, info.PointsCount)
+				}
+			} else if err := vectorStore.DeleteCollection(ctx, collection.Name); err != nil {
+				return err
+			}
+		}
+		if collection.VectorSize == 0 {
+			continue
+		}
+		if err := vectorStore.EnsureCollection(ctx, collection.Name, collection.VectorSize); err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", collection.Name, err)
+		}
+	}
+	return nil
+}
+
+// restoreCollection upserts the points of one collection's JSONL file in batches.
+func restoreCollection(ctx context.Context, r io.Reader, vectorStore vectorstore.VectorStore, collection CollectionManifest) error {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	// Numbers are decoded as json.Number so integer payloads (vault_id) stay integers
+	decoder.UseNumber()
+
+	batch := make([]vectorstore.Point, 0, pointBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := vectorStore.Upsert(ctx, collection.Name, batch); err != nil {
+			return fmt.Errorf("failed to restore points into %s: %w", collection.Name, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		var record pointRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", collection.File, err)
+		}
+		meta, _ := normalizeNumbers(record.Payload).(map[string]any)
+		batch = append(batch, vectorstore.Point{ID: record.ID, Vec: record.Vector, Meta: meta})
+		if len(batch) == pointBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// normalizeNumbers replaces json.Number values with int64 or float64, recursing into
+// lists and objects.
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// writeFile copies r to a new file at path.
+func writeFile(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+// Package snapshot writes and restores full index snapshots: a copy of the SQLite database
+// and every vector point, so a machine move does not mean re-embedding every vault.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+// Version is the snapshot format version written to the manifest.
+const Version = 1
+
+// Archive entry names.
+const (
+	manifestFile = "manifest.json"
+	databaseFile = "index.db"
+	vectorsDir   = "vectors/"
+)
+
+// pointBatchSize is the number of points read from or written to the vector store per request.
+const pointBatchSize = 256
+
+// Collections names the vector collections the index uses, matching the API's configuration.
+type Collections struct {
+	// Chunks is the shared chunk collection (QDRANT_COLLECTION).
+	Chunks string
+	// PerVault stores hot chunks in each vault's own collection (QDRANT_COLLECTION_PER_VAULT).
+	PerVault bool
+	// Cold holds the chunks of cold-tier notes (empty if cold storage is disabled).
+	Cold string
+	// Notes holds one centroid per note for two-stage retrieval (empty if disabled).
+	Notes string
+}
+
+// Manifest describes the contents of a snapshot.
+type Manifest struct {
+	// Version is the snapshot format version.
+	Version int `json:"version"`
+	// CreatedAt is when the snapshot was taken.
+	CreatedAt time.Time `json:"created_at"`
+	// Chunks is the number of chunks in the database copy.
+	Chunks int `json:"chunks"`
+	// Collections lists the vector collections in the snapshot, ordered by name.
+	Collections []CollectionManifest `json:"collections"`
+}
+
+// CollectionManifest describes the points of one collection in a snapshot.
+type CollectionManifest struct {
+	// Name is the collection name.
+	Name string `json:"name"`
+	// File is the archive entry holding the points as JSONL.
+	File string `json:"file"`
+	// VectorSize is the dimension of the vectors (0 when the collection has no points).
+	VectorSize int `json:"vector_size"`
+	// Points is the number of points.
+	Points int `json:"points"`
+	// Missing counts chunks or notes whose point was not found in the vector store.
+	Missing int `json:"missing,omitempty"`
+}
+
+// pointRecord is one line of a collection's JSONL file.
+type pointRecord struct {
+	ID      string         `json:"id"`
+	Vector  []float32      `json:"vector"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+// Snapshotter takes snapshots of a live index.
+type Snapshotter struct {
+	db          *sql.DB
+	vectorStore vectorstore.VectorStore
+	collections Collections
+}
+
+// NewSnapshotter creates a Snapshotter reading the database and the given collections.
+func NewSnapshotter(db *sql.DB, vectorStore vectorstore.VectorStore, collections Collections) *Snapshotter {
+	return &Snapshotter{
+		db:          db,
+		vectorStore: vectorStore,
+		collections: collections,
+	}
+}
+
+// Snapshot is a snapshot staged in a temporary directory, ready to be written as a
+// gzip-compressed tarball. Close removes the staged files.
+type Snapshot struct {
+	Manifest Manifest
+	dir      string
+}
+
+// Create stages a snapshot: a consistent copy of the database (VACUUM INTO) and the points
+// of every chunk and note centroid, read from the collection each one is stored in. Nothing
+// is written to the caller until the snapshot is complete, so a failure leaves no partial
+// archive behind.
+func (s *Snapshotter) Create(ctx context.Context) (*Snapshot, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	dir, err := os.MkdirTemp("", "helloworld-ai-snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	snap := &Snapshot{
+		Manifest: Manifest{Version: Version, CreatedAt: time.Now().UTC()},
+		dir:      dir,
+	}
+
+	dbPath := filepath.Join(dir, databaseFile)
+	if err := storage.NewReplicator(s.db, dbPath).Snapshot(ctx); err != nil {
+		_ = snap.Close()
+		return nil, err
+	}
+	// The points to save are those of the chunks in the copy, so every restored chunk has
+	// its point even if the live index changed since the copy was taken
+	chunks, err := listChunks(ctx, dbPath)
+	if err != nil {
+		_ = snap.Close()
+		return nil, err
+	}
+	snap.Manifest.Chunks = len(chunks)
+
+	idsByCollection := s.pointIDs(chunks)
+	names := make([]string, 0, len(idsByCollection))
+	for name := range idsByCollection {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := os.Mkdir(filepath.Join(dir, vectorsDir), 0755); err != nil {
+		_ = snap.Close()
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	for _, name := range names {
+		collection, err := s.writeCollection(ctx, dir, name, idsByCollection[name])
+		if err != nil {
+			_ = snap.Close()
+			return nil, err
+		}
+		if collection.Missing > 0 {
+			logger.WarnContext(ctx, "some points are missing from the vector store", "collection", name, "missing", collection.Missing)
+		}
+		snap.Manifest.Collections = append(snap.Manifest.Collections, collection)
+	}
+
+	logger.InfoContext(ctx, "snapshot created", "chunks", snap.Manifest.Chunks, "collections", len(snap.Manifest.Collections))
+	return snap, nil
+}
+
+// listChunks lists the chunks in the database copy at path.
+func listChunks(ctx context.Context, path string) ([]storage.ChunkExportRecord, error) {
+	db, err := storage.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database copy: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	chunks, err := storage.NewChunkRepo(db).ListForExport(ctx, storage.ChunkExportFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// pointIDs groups the point IDs of chunks (and their notes' centroids) by collection.
+func (s *Snapshotter) pointIDs(chunks []storage.ChunkExportRecord) map[string][]string {
+	idsByCollection := make(map[string][]string)
+	seenNotes := make(map[string]bool)
+	for _, chunk := range chunks {
+		collection := s.collections.Chunks
+		switch {
+		case chunk.Tier == storage.TierCold && s.collections.Cold != "":
+			collection = s.collections.Cold
+		case s.collections.PerVault:
+			collection = vectorstore.VaultCollection(s.collections.Chunks, chunk.VaultID)
+		}
+		idsByCollection[collection] = append(idsByCollection[collection], chunk.ID)
+
+		if s.collections.Notes != "" && !seenNotes[chunk.NoteID] {
+			seenNotes[chunk.NoteID] = true
+			idsByCollection[s.collections.Notes] = append(idsByCollection[s.collections.Notes], chunk.NoteID)
+		}
+	}
+	return idsByCollection
+}
+
+// writeCollection retrieves the points with the given IDs in batches and writes them as JSONL.
+func (s *Snapshotter) writeCollection(ctx context.Context, dir, collection string, ids []string) (CollectionManifest, error) {
+	manifest := CollectionManifest{
+		Name: collection,
+		File: vectorsDir + collection + ".jsonl",
+	}
+
+	file, err := os.Create(filepath.Join(dir, manifest.File))
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create %s: %w", manifest.File, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	encoder := json.NewEncoder(file)
+	for start := 0; start < len(ids); start += pointBatchSize {
+		end := min(start+pointBatchSize, len(ids))
+		points, err := s.vectorStore.Retrieve(ctx, collection, ids[start:end])
+		if err != nil {
+			return manifest, fmt.Errorf("failed to retrieve points from %s: %w", collection, err)
+		}
+		for _, point := range points {
+			if manifest.VectorSize == 0 {
+				manifest.VectorSize = len(point.Vec)
+			}
+			if err := encoder.Encode(pointRecord{ID: point.ID, Vector: point.Vec, Payload: point.Meta}); err != nil {
+				return manifest, fmt.Errorf("failed to write %s: %w", manifest.File, err)
+			}
+		}
+		manifest.Points += len(points)
+		manifest.Missing += end - start - len(points)
+	}
+
+	if err := file.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to write %s: %w", manifest.File, err)
+	}
+	return manifest, nil
+}
+
+// WriteTo writes the snapshot to w as a gzip-compressed tarball: the manifest first, then
+// the database copy and one JSONL file of points per collection.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	gz := gzip.NewWriter(counter)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(s.Manifest, "", "  ")
+	if err != nil {
+		return counter.n, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := &tar.Header{Name: manifestFile, Mode: 0644, Size: int64(len(manifest)), ModTime: s.Manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return counter.n, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return counter.n, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	files := []string{databaseFile}
+	for _, collection := range s.Manifest.Collections {
+		files = append(files, collection.File)
+	}
+	for _, name := range files {
+		if err := s.addFile(tw, name); err != nil {
+			return counter.n, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return counter.n, fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return counter.n, fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	return counter.n, nil
+}
+
+// addFile copies a staged file into the archive.
+func (s *Snapshot) addFile(tw *tar.Writer, name string) error {
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", name, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: s.Manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close removes the staged snapshot files.
+func (s *Snapshot) Close() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to remove snapshot directory: %w", err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"helloworld-ai/internal/storage"
+	"helloworld-ai/internal/vectorstore"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := storage.Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := storage.NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	note := &storage.NoteRecord{VaultID: vault.ID, RelPath: "garden.md", Folder: "", Title: "Garden", Hash: "hash"}
+	if err := storage.NewNoteRepo(db).Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	chunkRepo := storage.NewChunkRepo(db)
+	for _, id := range []string{"c1", "c2", "c3"} {
+		if err := chunkRepo.Insert(ctx, &storage.ChunkRecord{ID: id, NoteID: note.ID, HeadingPath: "# Garden", Text: "text " + id}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	// c3 has no point, so it is counted as missing
+	store := vectorstore.NewMemoryStore()
+	for _, collection := range []string{"chunks", "centroids"} {
+		if err := store.EnsureCollection(ctx, collection, 2); err != nil {
+			t.Fatalf("EnsureCollection() error = %v", err)
+		}
+	}
+	chunkPoints := []vectorstore.Point{
+		{ID: "c1", Vec: []float32{1, 0}, Meta: map[string]any{"vault_id": vault.ID, "folder": "", "tags": []string{"plants"}}},
+		{ID: "c2", Vec: []float32{0.5, 0.25}, Meta: map[string]any{"vault_id": vault.ID, "folder": ""}},
+	}
+	if err := store.Upsert(ctx, "chunks", chunkPoints); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := store.Upsert(ctx, "centroids", []vectorstore.Point{{ID: note.ID, Vec: []float32{0.75, 0.125}}}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	snapshotter := NewSnapshotter(db, store, Collections{Chunks: "chunks", Notes: "centroids"})
+	snap, err := snapshotter.Create(ctx)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer func() {
+		_ = snap.Close()
+	}()
+
+	wantCollections := []CollectionManifest{
+		{Name: "centroids", File: "vectors/centroids.jsonl", VectorSize: 2, Points: 1},
+		{Name: "chunks", File: "vectors/chunks.jsonl", VectorSize: 2, Points: 2, Missing: 1},
+	}
+	if snap.Manifest.Chunks != 3 || !reflect.DeepEqual(snap.Manifest.Collections, wantCollections) {
+		t.Errorf("manifest = %+v, want 3 chunks and collections %+v", snap.Manifest, wantCollections)
+	}
+
+	var archive bytes.Buffer
+	if _, err := snap.WriteTo(&archive); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	restoredPath := filepath.Join(t.TempDir(), "restored", "index.db")
+	restoredStore := vectorstore.NewMemoryStore()
+	manifest, err := Restore(ctx, bytes.NewReader(archive.Bytes()), restoredStore, RestoreOptions{DBPath: restoredPath})
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if manifest.Chunks != 3 {
+		t.Errorf("restored manifest chunks = %d, want 3", manifest.Chunks)
+	}
+
+	restoredDB, err := storage.New(restoredPath)
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer func() {
+		_ = restoredDB.Close()
+	}()
+	chunks, err := storage.NewChunkRepo(restoredDB).ListForExport(ctx, storage.ChunkExportFilter{})
+	if err != nil {
+		t.Fatalf("ListForExport() error = %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("restored database has %d chunks, want 3", len(chunks))
+	}
+
+	points, err := restoredStore.Retrieve(ctx, "chunks", []string{"c1", "c2"})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("restored %d chunk points, want 2", len(points))
+	}
+	for _, point := range points {
+		if point.ID == "c1" {
+			if !reflect.DeepEqual(point.Vec, []float32{1, 0}) || point.Meta["vault_id"] != int64(vault.ID) || !reflect.DeepEqual(point.Meta["tags"], []any{"plants"}) {
+				t.Errorf("restored point = %+v, want the original vector, integer vault_id, and tags", point)
+			}
+		}
+	}
+	if centroids, err := restoredStore.Retrieve(ctx, "centroids", []string{note.ID}); err != nil || len(centroids) != 1 {
+		t.Errorf("restored centroids = %v (error %v), want the note's centroid", centroids, err)
+	}
+
+	// Restoring over existing data needs Force
+	_, err = Restore(ctx, bytes.NewReader(archive.Bytes()), restoredStore, RestoreOptions{DBPath: restoredPath})
+	if !errors.Is(err, ErrTargetNotEmpty) {
+		t.Errorf("Restore() over existing data error = %v, want ErrTargetNotEmpty", err)
+	}
+	if _, err := Restore(ctx, bytes.NewReader(archive.Bytes()), restoredStore, RestoreOptions{DBPath: restoredPath, Force: true}); err != nil {
+		t.Errorf("Restore() with Force error = %v", err)
+	}
+}
+
+func TestRestore_RejectsOtherArchives(t *testing.T) {
+	_, err := Restore(context.Background(), bytes.NewReader([]byte("not a snapshot")), vectorstore.NewMemoryStore(), RestoreOptions{DBPath: filepath.Join(t.TempDir(), "index.db")})
+	if err == nil {
+		t.Error("Restore() error = nil, want an error for data that is not a snapshot")
+	}
+}
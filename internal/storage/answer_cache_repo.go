@@ -0,0 +1,109 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_answer_cache_store.go -package=mocks helloworld-ai/internal/storage AnswerCacheStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// AnswerCacheStore defines the interface for the on-disk answer cache.
+type AnswerCacheStore interface {
+	// Get returns the cached answer for key. Returns ErrNotFound if none is stored.
+	Get(ctx context.Context, key string) (*AnswerCacheRecord, error)
+	// Put stores an answer, replacing any previous answer for the same key.
+	Put(ctx context.Context, record *AnswerCacheRecord) error
+	// DeleteStale deletes answers cached for another index version or expired at now,
+	// and returns how many were deleted.
+	DeleteStale(ctx context.Context, indexVersion int64, now time.Time) (int64, error)
+	// DeleteAll deletes every cached answer and returns how many were deleted.
+	DeleteAll(ctx context.Context) (int64, error)
+}
+
+// AnswerCacheRepo provides methods for answer cache operations.
+// It implements the AnswerCacheStore interface.
+type AnswerCacheRepo struct {
+	db *sql.DB
+}
+
+// NewAnswerCacheRepo creates a new AnswerCacheRepo.
+func NewAnswerCacheRepo(db *sql.DB) *AnswerCacheRepo {
+	return &AnswerCacheRepo{db: db}
+}
+
+// Get returns the cached answer for key. Returns ErrNotFound if none is stored.
+func (r *AnswerCacheRepo) Get(ctx context.Context, key string) (*AnswerCacheRecord, error) {
+	var record AnswerCacheRecord
+	var createdAtStr, expiresAtStr string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT cache_key, index_version, response, created_at, expires_at FROM answer_cache WHERE cache_key = ?",
+		key,
+	).Scan(&record.Key, &record.IndexVersion, &record.Response, &createdAtStr, &expiresAtStr)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cached answer: %w", err)
+	}
+
+	record.CreatedAt, err = parseTimestamp(createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	record.ExpiresAt, err = parseTimestamp(expiresAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expires_at: %w", err)
+	}
+	return &record, nil
+}
+
+// Put stores an answer, replacing any previous answer for the same key.
+func (r *AnswerCacheRepo) Put(ctx context.Context, record *AnswerCacheRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO answer_cache (cache_key, index_version, response, created_at, expires_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET
+			index_version = excluded.index_version,
+			response = excluded.response,
+			created_at = CURRENT_TIMESTAMP,
+			expires_at = excluded.expires_at`,
+		record.Key, record.IndexVersion, record.Response, record.ExpiresAt.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store cached answer: %w", err)
+	}
+	return nil
+}
+
+// DeleteStale deletes answers cached for another index version or expired at now,
+// and returns how many were deleted.
+func (r *AnswerCacheRepo) DeleteStale(ctx context.Context, indexVersion int64, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM answer_cache WHERE index_version != ? OR expires_at <= ?",
+		indexVersion, now.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale cached answers: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted cached answers: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteAll deletes every cached answer and returns how many were deleted.
+func (r *AnswerCacheRepo) DeleteAll(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM answer_cache")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cached answers: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted cached answers: %w", err)
+	}
+	return deleted, nil
+}
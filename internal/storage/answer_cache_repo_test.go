@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAnswerCacheRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	repo := NewAnswerCacheRepo(db)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if _, err := repo.Get(ctx, "missing"); err != ErrNotFound {
+		t.Fatalf("Get() on empty cache error = %v, want ErrNotFound", err)
+	}
+
+	records := []*AnswerCacheRecord{
+		{Key: "current", IndexVersion: 7, Response: `{"answer":"a"}`, ExpiresAt: now.Add(time.Hour)},
+		{Key: "old-index", IndexVersion: 6, Response: `{"answer":"b"}`, ExpiresAt: now.Add(time.Hour)},
+		{Key: "expired", IndexVersion: 7, Response: `{"answer":"c"}`, ExpiresAt: now.Add(-time.Minute)},
+	}
+	for _, record := range records {
+		if err := repo.Put(ctx, record); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	// Put replaces the answer stored under the same key
+	if err := repo.Put(ctx, &AnswerCacheRecord{Key: "current", IndexVersion: 7, Response: `{"answer":"a2"}`, ExpiresAt: now.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("Put() replace error = %v", err)
+	}
+	got, err := repo.Get(ctx, "current")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Response != `{"answer":"a2"}` || got.IndexVersion != 7 || !got.ExpiresAt.Equal(now.Add(2*time.Hour)) || got.CreatedAt.IsZero() {
+		t.Errorf("Get() = %+v, want replaced answer expiring in 2h", got)
+	}
+
+	deleted, err := repo.DeleteStale(ctx, 7, now)
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteStale() = %d, %v, want 2 deleted", deleted, err)
+	}
+	if _, err := repo.Get(ctx, "old-index"); err != ErrNotFound {
+		t.Errorf("Get(old-index) after DeleteStale error = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Get(ctx, "current"); err != nil {
+		t.Errorf("Get(current) after DeleteStale error = %v, want kept", err)
+	}
+
+	deleted, err = repo.DeleteAll(ctx)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteAll() = %d, %v, want 1 deleted", deleted, err)
+	}
+	if _, err := repo.Get(ctx, "current"); err != ErrNotFound {
+		t.Errorf("Get(current) after DeleteAll error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,144 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_chunk_click_store.go -package=mocks helloworld-ai/internal/storage ChunkClickStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ChunkClickStore defines the interface for reference click-through storage.
+type ChunkClickStore interface {
+	// RecordShown counts one impression for each chunk returned as a reference.
+	RecordShown(ctx context.Context, chunkIDs []string) error
+	// RecordClick counts one click on a chunk's reference.
+	RecordClick(ctx context.Context, chunkID string) error
+	// GetStats returns the click stats of the given chunks, keyed by chunk ID.
+	// Chunks never shown or clicked are omitted.
+	GetStats(ctx context.Context, chunkIDs []string) (map[string]ChunkClickStats, error)
+	// ListMostClicked returns up to limit chunks ordered by clicks, most clicked first.
+	ListMostClicked(ctx context.Context, limit int) ([]ChunkClickStats, error)
+}
+
+// ChunkClickRepo provides methods for reference click-through operations.
+// It implements the ChunkClickStore interface.
+//
+// Counts are keyed by chunk ID only. Chunk IDs are derived from the chunk content, so
+// counts survive re-indexing unchanged chunks and are not removed with their notes.
+// ChunkRepo.RenameIDs carries them over when a moved note's chunks get new IDs.
+type ChunkClickRepo struct {
+	db *sql.DB
+}
+
+// NewChunkClickRepo creates a new ChunkClickRepo.
+func NewChunkClickRepo(db *sql.DB) *ChunkClickRepo {
+	return &ChunkClickRepo{db: db}
+}
+
+// RecordShown counts one impression for each chunk returned as a reference.
+func (r *ChunkClickRepo) RecordShown(ctx context.Context, chunkIDs []string) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	values := make([]string, len(chunkIDs))
+	args := make([]interface{}, len(chunkIDs))
+	for i, id := range chunkIDs {
+		values[i] = "(?, 1)"
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO chunk_clicks (chunk_id, shown) VALUES %s ON CONFLICT(chunk_id) DO UPDATE SET shown = shown + 1",
+		strings.Join(values, ","),
+	)
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record shown references: %w", err)
+	}
+	return nil
+}
+
+// RecordClick counts one click on a chunk's reference.
+func (r *ChunkClickRepo) RecordClick(ctx context.Context, chunkID string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO chunk_clicks (chunk_id, clicks, last_clicked_at) VALUES (?, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT(chunk_id) DO UPDATE SET clicks = clicks + 1, last_clicked_at = CURRENT_TIMESTAMP`,
+		chunkID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record reference click: %w", err)
+	}
+	return nil
+}
+
+// GetStats returns the click stats of the given chunks, keyed by chunk ID.
+// Chunks never shown or clicked are omitted.
+func (r *ChunkClickRepo) GetStats(ctx context.Context, chunkIDs []string) (map[string]ChunkClickStats, error) {
+	stats := make(map[string]ChunkClickStats, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return stats, nil
+	}
+
+	placeholders := make([]string, len(chunkIDs))
+	args := make([]interface{}, len(chunkIDs))
+	for i, id := range chunkIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"SELECT chunk_id, shown, clicks, last_clicked_at FROM chunk_clicks WHERE chunk_id IN (%s)",
+		strings.Join(placeholders, ","),
+	)
+	list, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range list {
+		stats[s.ChunkID] = s
+	}
+	return stats, nil
+}
+
+// ListMostClicked returns up to limit chunks ordered by clicks, most clicked first.
+func (r *ChunkClickRepo) ListMostClicked(ctx context.Context, limit int) ([]ChunkClickStats, error) {
+	return r.query(ctx,
+		`SELECT chunk_id, shown, clicks, last_clicked_at FROM chunk_clicks
+		WHERE clicks > 0
+		ORDER BY clicks DESC, chunk_id
+		LIMIT ?`,
+		limit,
+	)
+}
+
+// query scans chunk click rows.
+func (r *ChunkClickRepo) query(ctx context.Context, query string, args ...interface{}) ([]ChunkClickStats, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk clicks: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var stats []ChunkClickStats
+	for rows.Next() {
+		var s ChunkClickStats
+		var lastClickedAt sql.NullString
+		if err := rows.Scan(&s.ChunkID, &s.Shown, &s.Clicks, &lastClickedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk clicks: %w", err)
+		}
+		if lastClickedAt.Valid {
+			t, err := parseTimestamp(lastClickedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse last_clicked_at: %w", err)
+			}
+			s.LastClickedAt = &t
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChunkClickRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	repo := NewChunkClickRepo(db)
+
+	for range 4 {
+		if err := repo.RecordShown(ctx, []string{"chunk-a", "chunk-b"}); err != nil {
+			t.Fatalf("RecordShown() error = %v", err)
+		}
+	}
+	for _, id := range []string{"chunk-a", "chunk-b", "chunk-a", "chunk-c"} {
+		if err := repo.RecordClick(ctx, id); err != nil {
+			t.Fatalf("RecordClick() error = %v", err)
+		}
+	}
+
+	stats, err := repo.GetStats(ctx, []string{"chunk-a", "chunk-b", "chunk-d"})
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("GetStats() returned %d chunks, want 2", len(stats))
+	}
+	a := stats["chunk-a"]
+	if a.Shown != 4 || a.Clicks != 2 || a.ClickThrough() != 0.5 || a.LastClickedAt == nil {
+		t.Errorf("GetStats()[chunk-a] = %+v, want shown 4, clicks 2", a)
+	}
+	if b := stats["chunk-b"]; b.ClickThrough() != 0.25 {
+		t.Errorf("chunk-b click-through = %v, want 0.25", b.ClickThrough())
+	}
+
+	mostClicked, err := repo.ListMostClicked(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListMostClicked() error = %v", err)
+	}
+	if len(mostClicked) != 2 || mostClicked[0].ChunkID != "chunk-a" || mostClicked[1].ChunkID != "chunk-b" {
+		t.Errorf("ListMostClicked() = %+v, want chunk-a then chunk-b", mostClicked)
+	}
+	// A click on a reference shown before tracking started has no impressions
+	if c := (ChunkClickStats{Clicks: 1}); c.ClickThrough() != 0 {
+		t.Errorf("ClickThrough() without impressions = %v, want 0", c.ClickThrough())
+	}
+}
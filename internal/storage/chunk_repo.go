@@ -4,8 +4,12 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // ChunkStore defines the interface for chunk storage operations.
@@ -17,12 +21,39 @@ type ChunkStore interface {
 	DeleteByNote(ctx context.Context, noteID string) error
 	// ListIDsByNote returns all chunk IDs for a given note, ordered by chunk_index.
 	ListIDsByNote(ctx context.Context, noteID string) ([]string, error)
+	// ListIndexesByNotes returns the chunk indexes of each given note, in ascending order.
+	ListIndexesByNotes(ctx context.Context, noteIDs []string) (map[string][]int, error)
 	// GetByID gets a chunk by its ID. Returns ErrNotFound if not found.
 	GetByID(ctx context.Context, id string) (*ChunkRecord, error)
 	// GetAllIDs returns all chunk IDs in the database.
 	GetAllIDs(ctx context.Context) ([]string, error)
 	// DeleteAll deletes all chunks from the database.
 	DeleteAll(ctx context.Context) error
+	// ListForExport returns chunks with their note and vault metadata, filtered by vault and folder.
+	ListForExport(ctx context.Context, filter ChunkExportFilter) ([]ChunkExportRecord, error)
+	// PruneTexts deletes stored chunk texts that no chunk references anymore.
+	PruneTexts(ctx context.Context) (int64, error)
+	// RestoreText stores text for an existing chunk whose text went missing.
+	// Returns ErrNotFound if the chunk does not exist.
+	RestoreText(ctx context.Context, id, text string) error
+	// RenameIDs changes chunk IDs from the keys of ids to their values, carrying click counts along.
+	RenameIDs(ctx context.Context, ids map[string]string) error
+	// ListByHeadingTerms returns chunks in the given vaults whose heading path contains all
+	// terms in order (case-insensitive), with their note and vault metadata.
+	ListByHeadingTerms(ctx context.Context, vaultIDs []int, terms []string) ([]ChunkExportRecord, error)
+	// ListByNote returns the chunks of a note with their texts, ordered by chunk_index.
+	ListByNote(ctx context.Context, noteID string) ([]ChunkRecord, error)
+	// ArchiveByNote replaces the note's previous chunk generation with its current chunks.
+	ArchiveByNote(ctx context.Context, noteID string) error
+	// ListPreviousByNote returns the chunk generation archived by ArchiveByNote, ordered by
+	// chunk_index (empty when the note was never re-indexed). The records have no ID.
+	ListPreviousByNote(ctx context.Context, noteID string) ([]ChunkRecord, error)
+}
+
+// TextHash returns the content address of a chunk text (SHA256 hex string).
+func TextHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
 
 // ChunkRepo provides methods for chunk operations.
@@ -44,14 +75,41 @@ func (r *ChunkRepo) DB() *sql.DB {
 
 // Insert inserts a single chunk into the database.
 // The chunk.ID must be set (UUID) before calling this method.
+// The text is stored once in chunk_texts keyed by its hash, so identical texts
+// across notes (templates, boilerplate) share a single row. chunk.TextHash is set.
 func (r *ChunkRepo) Insert(ctx context.Context, chunk *ChunkRecord) error {
-	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO chunks (id, note_id, chunk_index, heading_path, text) VALUES (?, ?, ?, ?, ?)",
-		chunk.ID, chunk.NoteID, chunk.ChunkIndex, chunk.HeadingPath, chunk.Text,
-	)
+	chunk.TextHash = TextHash(chunk.Text)
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin chunk insert: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO chunk_texts (hash, text) VALUES (?, ?)",
+		chunk.TextHash, chunk.Text,
+	); err != nil {
+		return fmt.Errorf("failed to insert chunk text: %w", err)
+	}
+	var embeddedAt any
+	if !chunk.EmbeddedAt.IsZero() {
+		embeddedAt = chunk.EmbeddedAt.UTC().Format(time.RFC3339)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO chunks (id, note_id, chunk_index, heading_path, text, text_hash, chunker_version, embedding_model, embedded_at, run_id, overlap_runes)
+		VALUES (?, ?, ?, ?, '', ?, ?, ?, ?, ?, ?)`,
+		chunk.ID, chunk.NoteID, chunk.ChunkIndex, chunk.HeadingPath, chunk.TextHash,
+		chunk.ChunkerVersion, chunk.EmbeddingModel, embeddedAt, chunk.RunID, chunk.OverlapRunes,
+	); err != nil {
 		return fmt.Errorf("failed to insert chunk: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk insert: %w", err)
+	}
 	return nil
 }
 
@@ -96,13 +154,61 @@ func (r *ChunkRepo) ListIDsByNote(ctx context.Context, noteID string) ([]string,
 	return ids, nil
 }
 
+// ListIndexesByNotes returns the chunk indexes of each given note, in ascending order.
+// Notes without chunks are absent from the map. Indexes can have gaps where chunks were
+// skipped during indexing, so a chunk's position is its place in the list, not its index.
+func (r *ChunkRepo) ListIndexesByNotes(ctx context.Context, noteIDs []string) (map[string][]int, error) {
+	indexes := make(map[string][]int)
+	if len(noteIDs) == 0 {
+		return indexes, nil
+	}
+
+	placeholders := make([]string, len(noteIDs))
+	args := make([]interface{}, len(noteIDs))
+	for i, noteID := range noteIDs {
+		placeholders[i] = "?"
+		args[i] = noteID
+	}
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT note_id, chunk_index FROM chunks WHERE note_id IN (%s) ORDER BY note_id, chunk_index", strings.Join(placeholders, ",")),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk indexes: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var noteID string
+		var chunkIndex int
+		if err := rows.Scan(&noteID, &chunkIndex); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk index: %w", err)
+		}
+		indexes[noteID] = append(indexes[noteID], chunkIndex)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return indexes, nil
+}
+
 // GetByID gets a chunk by its ID. Returns ErrNotFound if not found.
 func (r *ChunkRepo) GetByID(ctx context.Context, id string) (*ChunkRecord, error) {
 	var chunk ChunkRecord
+	var embeddedAt string
 	err := r.db.QueryRowContext(ctx,
-		"SELECT id, note_id, chunk_index, heading_path, text FROM chunks WHERE id = ?",
+		`SELECT c.id, c.note_id, c.chunk_index, c.heading_path, COALESCE(t.text, c.text), COALESCE(c.text_hash, ''),
+			COALESCE(c.chunker_version, ''), COALESCE(c.embedding_model, ''), COALESCE(c.embedded_at, ''), COALESCE(c.run_id, ''),
+			COALESCE(c.overlap_runes, 0)
+		FROM chunks c
+		LEFT JOIN chunk_texts t ON t.hash = c.text_hash
+		WHERE c.id = ?`,
 		id,
-	).Scan(&chunk.ID, &chunk.NoteID, &chunk.ChunkIndex, &chunk.HeadingPath, &chunk.Text)
+	).Scan(&chunk.ID, &chunk.NoteID, &chunk.ChunkIndex, &chunk.HeadingPath, &chunk.Text, &chunk.TextHash,
+		&chunk.ChunkerVersion, &chunk.EmbeddingModel, &embeddedAt, &chunk.RunID, &chunk.OverlapRunes)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -110,6 +216,11 @@ func (r *ChunkRepo) GetByID(ctx context.Context, id string) (*ChunkRecord, error
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunk: %w", err)
 	}
+	if embeddedAt != "" {
+		if chunk.EmbeddedAt, err = parseTimestamp(embeddedAt); err != nil {
+			return nil, fmt.Errorf("failed to parse embedded_at timestamp: %w", err)
+		}
+	}
 
 	return &chunk, nil
 }
@@ -140,11 +251,305 @@ func (r *ChunkRepo) GetAllIDs(ctx context.Context) ([]string, error) {
 	return ids, nil
 }
 
-// DeleteAll deletes all chunks from the database.
+// DeleteAll deletes all chunks and their stored texts from the database.
 func (r *ChunkRepo) DeleteAll(ctx context.Context) error {
 	_, err := r.db.ExecContext(ctx, "DELETE FROM chunks")
 	if err != nil {
 		return fmt.Errorf("failed to delete all chunks: %w", err)
 	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM chunk_history"); err != nil {
+		return fmt.Errorf("failed to delete chunk history: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM chunk_texts"); err != nil {
+		return fmt.Errorf("failed to delete all chunk texts: %w", err)
+	}
+	return nil
+}
+
+// PruneTexts deletes stored chunk texts that no chunk (current or archived) references anymore.
+// Deleting chunks leaves their texts behind because other chunks may share them;
+// this is run after an index pass to reclaim the space. Returns the number of texts removed.
+func (r *ChunkRepo) PruneTexts(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM chunk_texts
+		WHERE NOT EXISTS (SELECT 1 FROM chunks c WHERE c.text_hash = chunk_texts.hash)
+			AND NOT EXISTS (SELECT 1 FROM chunk_history h WHERE h.text_hash = chunk_texts.hash)`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune chunk texts: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pruned chunk texts: %w", err)
+	}
+	return removed, nil
+}
+
+// RenameIDs changes chunk IDs from the keys of ids to their values in one transaction, for
+// notes whose path (part of the stable chunk ID) changed. Click counts follow their chunks.
+// Returns ErrNotFound, changing nothing, if a chunk does not exist.
+func (r *ChunkRepo) RenameIDs(ctx context.Context, ids map[string]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin chunk rename: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for oldID, newID := range ids {
+		result, err := tx.ExecContext(ctx, "UPDATE chunks SET id = ? WHERE id = ?", newID, oldID)
+		if err != nil {
+			return fmt.Errorf("failed to rename chunk: %w", err)
+		}
+		renamed, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to count renamed chunks: %w", err)
+		}
+		if renamed == 0 {
+			return ErrNotFound
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE OR REPLACE chunk_clicks SET chunk_id = ? WHERE chunk_id = ?", newID, oldID); err != nil {
+			return fmt.Errorf("failed to rename chunk clicks: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk rename: %w", err)
+	}
 	return nil
 }
+
+// RestoreText stores text for an existing chunk whose text went missing, pointing the
+// chunk at the text's chunk_texts row. Returns ErrNotFound if the chunk does not exist.
+func (r *ChunkRepo) RestoreText(ctx context.Context, id, text string) error {
+	hash := TextHash(text)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin chunk text restore: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO chunk_texts (hash, text) VALUES (?, ?)",
+		hash, text,
+	); err != nil {
+		return fmt.Errorf("failed to insert chunk text: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE chunks SET text = '', text_hash = ? WHERE id = ?", hash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update chunk text: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count updated chunks: %w", err)
+	}
+	if updated == 0 {
+		return ErrNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk text restore: %w", err)
+	}
+	return nil
+}
+
+// chunkWithNoteQuery selects chunks joined with their note and vault, as scanned by queryChunksWithNotes.
+const chunkWithNoteQuery = `SELECT c.id, c.note_id, c.chunk_index, COALESCE(c.heading_path, ''), COALESCE(t.text, c.text),
+			COALESCE(c.text_hash, ''), v.id, v.name, n.rel_path, n.folder, COALESCE(n.title, ''), n.tier
+		FROM chunks c
+		LEFT JOIN chunk_texts t ON t.hash = c.text_hash
+		JOIN notes n ON n.id = c.note_id
+		JOIN vaults v ON v.id = n.vault_id`
+
+// ListForExport returns chunks with their note and vault metadata, filtered by vault and folder.
+// A folder filter matches the folder itself and all of its subfolders, compared literally
+// (no LIKE, so _ and % in folder names are not wildcards).
+// Results are ordered by vault name, note path, and chunk index so exports are stable.
+func (r *ChunkRepo) ListForExport(ctx context.Context, filter ChunkExportFilter) ([]ChunkExportRecord, error) {
+	query := chunkWithNoteQuery
+
+	var conditions []string
+	var args []any
+	if filter.VaultName != "" {
+		conditions = append(conditions, "v.name = ?")
+		args = append(args, filter.VaultName)
+	}
+	if folder := strings.Trim(filter.Folder, "/"); folder != "" {
+		conditions = append(conditions, "(n.folder = ? OR substr(n.folder, 1, length(?) + 1) = ? || '/')")
+		args = append(args, folder, folder, folder)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY v.name, n.rel_path, c.chunk_index"
+
+	records, err := r.queryChunksWithNotes(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks for export: %w", err)
+	}
+	return records, nil
+}
+
+// ListByHeadingTerms returns chunks in the given vaults whose heading path contains all
+// terms in order (case-insensitive for ASCII), ordered by vault name, note path, and chunk
+// index. Callers compare the heading segments themselves; this only narrows the candidates.
+// Returns an empty slice when vaultIDs or terms are empty.
+func (r *ChunkRepo) ListByHeadingTerms(ctx context.Context, vaultIDs []int, terms []string) ([]ChunkExportRecord, error) {
+	if len(vaultIDs) == 0 || len(terms) == 0 {
+		return []ChunkExportRecord{}, nil
+	}
+
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	escaped := make([]string, len(terms))
+	for i, term := range terms {
+		escaped[i] = escape.Replace(term)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(vaultIDs)), ",")
+	args := make([]any, 0, len(vaultIDs)+1)
+	for _, vaultID := range vaultIDs {
+		args = append(args, vaultID)
+	}
+	args = append(args, "%"+strings.Join(escaped, "%")+"%")
+
+	query := chunkWithNoteQuery + `
+		WHERE n.vault_id IN (` + placeholders + `) AND c.heading_path LIKE ? ESCAPE '\'
+		ORDER BY v.name, n.rel_path, c.chunk_index`
+	records, err := r.queryChunksWithNotes(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks by heading: %w", err)
+	}
+	return records, nil
+}
+
+// queryChunksWithNotes runs a chunkWithNoteQuery-based query and scans its rows.
+func (r *ChunkRepo) queryChunksWithNotes(ctx context.Context, query string, args ...any) ([]ChunkExportRecord, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var records []ChunkExportRecord
+	for rows.Next() {
+		var rec ChunkExportRecord
+		if err := rows.Scan(
+			&rec.ID, &rec.NoteID, &rec.ChunkIndex, &rec.HeadingPath, &rec.Text,
+			&rec.TextHash, &rec.VaultID, &rec.VaultName, &rec.RelPath, &rec.Folder, &rec.NoteTitle, &rec.Tier,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return records, nil
+}
+
+// ListByNote returns the chunks of a note with their texts, ordered by chunk_index.
+// Returns an empty slice if the note has no chunks (not an error).
+func (r *ChunkRepo) ListByNote(ctx context.Context, noteID string) ([]ChunkRecord, error) {
+	chunks, err := r.queryChunkRecords(ctx,
+		`SELECT c.id, c.note_id, c.chunk_index, c.heading_path, COALESCE(t.text, c.text), COALESCE(c.text_hash, ''),
+			COALESCE(c.chunker_version, ''), COALESCE(c.embedded_at, '')
+		FROM chunks c
+		LEFT JOIN chunk_texts t ON t.hash = c.text_hash
+		WHERE c.note_id = ?
+		ORDER BY c.chunk_index`,
+		noteID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks by note: %w", err)
+	}
+	return chunks, nil
+}
+
+// ArchiveByNote replaces the note's previous chunk generation with its current chunks.
+// It is called before re-indexing a note deletes them, so ListPreviousByNote can diff the
+// two generations. Only one generation is kept; texts stay shared through chunk_texts.
+func (r *ChunkRepo) ArchiveByNote(ctx context.Context, noteID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin chunk archive: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM chunk_history WHERE note_id = ?", noteID); err != nil {
+		return fmt.Errorf("failed to delete previous chunk generation: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO chunk_history (note_id, chunk_index, heading_path, text_hash, chunker_version, embedded_at)
+		SELECT note_id, chunk_index, heading_path, text_hash, chunker_version, embedded_at
+		FROM chunks WHERE note_id = ?`,
+		noteID,
+	); err != nil {
+		return fmt.Errorf("failed to archive chunks: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk archive: %w", err)
+	}
+	return nil
+}
+
+// ListPreviousByNote returns the chunk generation archived by ArchiveByNote, ordered by
+// chunk_index. Returns an empty slice if the note was never re-indexed. The records have no
+// ID: chunk IDs belong to the current generation's Qdrant points.
+func (r *ChunkRepo) ListPreviousByNote(ctx context.Context, noteID string) ([]ChunkRecord, error) {
+	chunks, err := r.queryChunkRecords(ctx,
+		`SELECT '', h.note_id, h.chunk_index, COALESCE(h.heading_path, ''), COALESCE(t.text, ''), COALESCE(h.text_hash, ''),
+			COALESCE(h.chunker_version, ''), COALESCE(h.embedded_at, '')
+		FROM chunk_history h
+		LEFT JOIN chunk_texts t ON t.hash = h.text_hash
+		WHERE h.note_id = ?
+		ORDER BY h.chunk_index`,
+		noteID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query previous chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// queryChunkRecords runs a query selecting id, note_id, chunk_index, heading_path, text,
+// text_hash, chunker_version, and embedded_at, and scans its rows.
+func (r *ChunkRepo) queryChunkRecords(ctx context.Context, query string, args ...any) ([]ChunkRecord, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	chunks := []ChunkRecord{}
+	for rows.Next() {
+		var chunk ChunkRecord
+		var embeddedAt string
+		if err := rows.Scan(&chunk.ID, &chunk.NoteID, &chunk.ChunkIndex, &chunk.HeadingPath, &chunk.Text,
+			&chunk.TextHash, &chunk.ChunkerVersion, &embeddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		if embeddedAt != "" {
+			if chunk.EmbeddedAt, err = parseTimestamp(embeddedAt); err != nil {
+				return nil, fmt.Errorf("failed to parse embedded_at timestamp: %w", err)
+			}
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return chunks, nil
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 )
 
 func TestNewChunkRepo(t *testing.T) {
@@ -381,3 +383,521 @@ func TestChunkRepo_ListIDsByNote_OrderedByIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestChunkRepo_SharedTexts(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	noteRepo := NewNoteRepo(db)
+	var noteIDs []string
+	for _, relPath := range []string{"a.md", "b.md"} {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Hash: "hash"}
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		noteIDs = append(noteIDs, note.ID)
+	}
+
+	repo := NewChunkRepo(db)
+	template := "## Daily template\n\n- [ ] Review inbox"
+	for i, noteID := range noteIDs {
+		chunk := &ChunkRecord{ID: fmt.Sprintf("chunk-%d", i), NoteID: noteID, Text: template}
+		if err := repo.Insert(ctx, chunk); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		if chunk.TextHash != TextHash(template) {
+			t.Errorf("Insert() TextHash = %q, want %q", chunk.TextHash, TextHash(template))
+		}
+	}
+
+	countTexts := func() int {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM chunk_texts").Scan(&n); err != nil {
+			t.Fatalf("count chunk_texts: %v", err)
+		}
+		return n
+	}
+	if got := countTexts(); got != 1 {
+		t.Errorf("chunk_texts rows = %d, want 1 for identical texts", got)
+	}
+
+	got, err := repo.GetByID(ctx, "chunk-1")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Text != template || got.TextHash != TextHash(template) {
+		t.Errorf("GetByID() = %+v, want the shared text", got)
+	}
+
+	// The text stays while any chunk references it
+	if err := repo.DeleteByNote(ctx, noteIDs[0]); err != nil {
+		t.Fatalf("DeleteByNote() error = %v", err)
+	}
+	if removed, err := repo.PruneTexts(ctx); err != nil || removed != 0 {
+		t.Errorf("PruneTexts() = %d, %v, want 0 while referenced", removed, err)
+	}
+	if err := repo.DeleteByNote(ctx, noteIDs[1]); err != nil {
+		t.Fatalf("DeleteByNote() error = %v", err)
+	}
+	if removed, err := repo.PruneTexts(ctx); err != nil || removed != 1 {
+		t.Errorf("PruneTexts() = %d, %v, want 1 once unreferenced", removed, err)
+	}
+	if got := countTexts(); got != 0 {
+		t.Errorf("chunk_texts rows = %d after prune, want 0", got)
+	}
+}
+
+func TestChunkRepo_Provenance(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "a.md", Hash: "hash"}
+	if err := NewNoteRepo(db).Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	repo := NewChunkRepo(db)
+	embeddedAt := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	chunks := []*ChunkRecord{
+		{ID: "with", NoteID: note.ID, Text: "one", ChunkerVersion: "goldmark-1", EmbeddingModel: "embed-small", EmbeddedAt: embeddedAt, RunID: "run-1"},
+		{ID: "without", NoteID: note.ID, ChunkIndex: 1, Text: "two"},
+		{ID: "overlap", NoteID: note.ID, ChunkIndex: 2, Text: "two\n\nthree", OverlapRunes: 5},
+	}
+	for _, chunk := range chunks {
+		if err := repo.Insert(ctx, chunk); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetByID(ctx, "with")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.ChunkerVersion != "goldmark-1" || got.EmbeddingModel != "embed-small" || !got.EmbeddedAt.Equal(embeddedAt) || got.RunID != "run-1" {
+		t.Errorf("GetByID() provenance = %q, %q, %v, %q, want the inserted values", got.ChunkerVersion, got.EmbeddingModel, got.EmbeddedAt, got.RunID)
+	}
+
+	got, err = repo.GetByID(ctx, "without")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.ChunkerVersion != "" || got.RunID != "" || !got.EmbeddedAt.IsZero() {
+		t.Errorf("GetByID() provenance = %+v, want empty for chunks without provenance", got)
+	}
+	if got.OverlapRunes != 0 {
+		t.Errorf("GetByID() OverlapRunes = %d, want 0", got.OverlapRunes)
+	}
+
+	got, err = repo.GetByID(ctx, "overlap")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.OverlapRunes != 5 {
+		t.Errorf("GetByID() OverlapRunes = %d, want 5", got.OverlapRunes)
+	}
+}
+
+func TestChunkRepo_ListByHeadingTerms(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	noteRepo := NewNoteRepo(db)
+	repo := NewChunkRepo(db)
+	chunks := []struct {
+		vaultID     int
+		relPath     string
+		headingPath string
+	}{
+		{personal.ID, "hash-tables.md", "Hash Tables > Designing a HashMap"},
+		{personal.ID, "hash-tables.md", "Hash Tables > Designing a HashMap > Resizing"},
+		{personal.ID, "hash-tables.md", "Hash Tables > Collisions"},
+		{personal.ID, "wild.md", "100% done_list"},
+		{work.ID, "design.md", "Designing a HashMap"},
+	}
+	for i, c := range chunks {
+		note := &NoteRecord{VaultID: c.vaultID, RelPath: c.relPath, Folder: "", Hash: "hash"}
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		chunk := &ChunkRecord{ID: fmt.Sprintf("chunk-%d", i), NoteID: note.ID, ChunkIndex: i, HeadingPath: c.headingPath, Text: fmt.Sprintf("text %d", i)}
+		if err := repo.Insert(ctx, chunk); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		vaultIDs []int
+		terms    []string
+		wantIDs  []string
+	}{
+		{name: "terms in order, case-insensitive", vaultIDs: []int{personal.ID}, terms: []string{"designing", "hashmap"}, wantIDs: []string{"chunk-0", "chunk-1"}},
+		{name: "terms out of order", vaultIDs: []int{personal.ID}, terms: []string{"hashmap", "designing"}},
+		{name: "across vaults", vaultIDs: []int{personal.ID, work.ID}, terms: []string{"designing", "hashmap"}, wantIDs: []string{"chunk-0", "chunk-1", "chunk-4"}},
+		{name: "wildcards are literal", vaultIDs: []int{personal.ID}, terms: []string{"0%", "e_l"}, wantIDs: []string{"chunk-3"}},
+		{name: "wildcard does not match other text", vaultIDs: []int{personal.ID}, terms: []string{"s_c"}},
+		{name: "no vaults", terms: []string{"designing"}},
+		{name: "no terms", vaultIDs: []int{personal.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := repo.ListByHeadingTerms(ctx, tt.vaultIDs, tt.terms)
+			if err != nil {
+				t.Fatalf("ListByHeadingTerms() error = %v", err)
+			}
+			var gotIDs []string
+			for _, rec := range records {
+				gotIDs = append(gotIDs, rec.ID)
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("ListByHeadingTerms() IDs = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+
+	records, _ := repo.ListByHeadingTerms(ctx, []int{work.ID}, []string{"hashmap"})
+	if len(records) != 1 || records[0].VaultName != "work" || records[0].RelPath != "design.md" || records[0].Text != "text 4" {
+		t.Errorf("ListByHeadingTerms() records = %+v, want the work chunk with note metadata", records)
+	}
+}
+
+func TestChunkRepo_ListForExport(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	noteRepo := NewNoteRepo(db)
+	repo := NewChunkRepo(db)
+	notes := []struct {
+		vaultID int
+		relPath string
+		folder  string
+	}{
+		{personal.ID, "my_notes/a.md", "my_notes"},
+		{personal.ID, "my_notes/drafts/b.md", "my_notes/drafts"},
+		// "_" and "%" would match these as LIKE wildcards
+		{personal.ID, "myXnotes/sub/c.md", "myXnotes/sub"},
+		{personal.ID, "my_notes2/d.md", "my_notes2"},
+		{work.ID, "my_notes/e.md", "my_notes"},
+	}
+	for i, n := range notes {
+		note := &NoteRecord{VaultID: n.vaultID, RelPath: n.relPath, Folder: n.folder, Hash: "hash"}
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		chunk := &ChunkRecord{ID: fmt.Sprintf("chunk-%d", i), NoteID: note.ID, ChunkIndex: 0, Text: fmt.Sprintf("text %d", i)}
+		if err := repo.Insert(ctx, chunk); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filter  ChunkExportFilter
+		wantIDs []string
+	}{
+		{name: "all", wantIDs: []string{"chunk-2", "chunk-0", "chunk-1", "chunk-3", "chunk-4"}},
+		{name: "vault", filter: ChunkExportFilter{VaultName: "work"}, wantIDs: []string{"chunk-4"}},
+		{name: "folder and subfolders", filter: ChunkExportFilter{VaultName: "personal", Folder: "my_notes"}, wantIDs: []string{"chunk-0", "chunk-1"}},
+		{name: "trailing slash", filter: ChunkExportFilter{VaultName: "personal", Folder: "my_notes/"}, wantIDs: []string{"chunk-0", "chunk-1"}},
+		{name: "subfolder", filter: ChunkExportFilter{Folder: "my_notes/drafts"}, wantIDs: []string{"chunk-1"}},
+		{name: "percent is literal", filter: ChunkExportFilter{Folder: "my%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := repo.ListForExport(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListForExport() error = %v", err)
+			}
+			var gotIDs []string
+			for _, rec := range records {
+				gotIDs = append(gotIDs, rec.ID)
+			}
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("ListForExport() IDs = %v, want %v", gotIDs, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestChunkRepo_RestoreText(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "a.md", Hash: "hash"}
+	if err := NewNoteRepo(db).Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	repo := NewChunkRepo(db)
+	if err := repo.Insert(ctx, &ChunkRecord{ID: "chunk-0", NoteID: note.ID, Text: "original"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	// Simulate a lost text row
+	if _, err := db.Exec("DELETE FROM chunk_texts"); err != nil {
+		t.Fatalf("delete chunk_texts: %v", err)
+	}
+	if got, err := repo.GetByID(ctx, "chunk-0"); err != nil || got.Text != "" {
+		t.Fatalf("GetByID() = %+v, %v, want empty text", got, err)
+	}
+
+	if err := repo.RestoreText(ctx, "chunk-0", "recovered"); err != nil {
+		t.Fatalf("RestoreText() error = %v", err)
+	}
+	got, err := repo.GetByID(ctx, "chunk-0")
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Text != "recovered" || got.TextHash != TextHash("recovered") {
+		t.Errorf("GetByID() = %+v, want the restored text", got)
+	}
+
+	if err := repo.RestoreText(ctx, "missing", "text"); err != ErrNotFound {
+		t.Errorf("RestoreText() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestChunkRepo_RenameIDs(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "a.md", Hash: "hash"}
+	if err := NewNoteRepo(db).Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	repo := NewChunkRepo(db)
+	for i, id := range []string{"old-0", "old-1"} {
+		if err := repo.Insert(ctx, &ChunkRecord{ID: id, NoteID: note.ID, ChunkIndex: i, Text: id}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+	clicks := NewChunkClickRepo(db)
+	if err := clicks.RecordClick(ctx, "old-1"); err != nil {
+		t.Fatalf("RecordClick() error = %v", err)
+	}
+
+	// A missing chunk fails the whole rename
+	if err := repo.RenameIDs(ctx, map[string]string{"old-0": "new-0", "missing": "new-2"}); err != ErrNotFound {
+		t.Errorf("RenameIDs() with a missing chunk error = %v, want ErrNotFound", err)
+	}
+	if ids, _ := repo.ListIDsByNote(ctx, note.ID); len(ids) != 2 || ids[0] != "old-0" {
+		t.Errorf("ListIDsByNote() after failed rename = %v, want the old IDs", ids)
+	}
+
+	if err := repo.RenameIDs(ctx, map[string]string{"old-0": "new-0", "old-1": "new-1"}); err != nil {
+		t.Fatalf("RenameIDs() error = %v", err)
+	}
+	if ids, _ := repo.ListIDsByNote(ctx, note.ID); len(ids) != 2 || ids[0] != "new-0" || ids[1] != "new-1" {
+		t.Errorf("ListIDsByNote() after rename = %v, want new-0, new-1", ids)
+	}
+	if got, err := repo.GetByID(ctx, "new-1"); err != nil || got.Text != "old-1" || got.ChunkIndex != 1 {
+		t.Errorf("GetByID(new-1) = %+v, %v, want the renamed chunk with its text", got, err)
+	}
+	stats, err := clicks.GetStats(ctx, []string{"old-1", "new-1"})
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if _, ok := stats["old-1"]; ok || stats["new-1"].Clicks != 1 {
+		t.Errorf("click stats after rename = %+v, want the click under new-1", stats)
+	}
+}
+
+func TestChunkRepo_ListIndexesByNotes(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	noteRepo := NewNoteRepo(db)
+	repo := NewChunkRepo(db)
+	// The second note skipped chunk 1 during indexing
+	chunkIndexes := map[string][]int{"a.md": {2, 0, 1}, "b.md": {0, 2}, "c.md": nil}
+	noteIDs := make(map[string]string)
+	for relPath, indexes := range chunkIndexes {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Hash: "hash"}
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		noteIDs[relPath] = note.ID
+		for _, index := range indexes {
+			chunk := &ChunkRecord{ID: fmt.Sprintf("%s-%d", relPath, index), NoteID: note.ID, ChunkIndex: index, Text: fmt.Sprintf("text %d", index)}
+			if err := repo.Insert(ctx, chunk); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+		}
+	}
+
+	got, err := repo.ListIndexesByNotes(ctx, []string{noteIDs["a.md"], noteIDs["b.md"], noteIDs["c.md"]})
+	if err != nil {
+		t.Fatalf("ListIndexesByNotes() error = %v", err)
+	}
+	if fmt.Sprint(got[noteIDs["a.md"]]) != "[0 1 2]" || fmt.Sprint(got[noteIDs["b.md"]]) != "[0 2]" {
+		t.Errorf("ListIndexesByNotes() = %v, want sorted indexes per note", got)
+	}
+	if _, ok := got[noteIDs["c.md"]]; ok || len(got) != 2 {
+		t.Errorf("ListIndexesByNotes() = %v, want notes without chunks absent", got)
+	}
+
+	if got, err := repo.ListIndexesByNotes(ctx, nil); err != nil || len(got) != 0 {
+		t.Errorf("ListIndexesByNotes(nil) = %v, %v, want an empty map", got, err)
+	}
+}
+
+func TestChunkRepo_ArchiveByNote(t *testing.T) {
+	ctx := context.Background()
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "a.md", Hash: "hash"}
+	if err := NewNoteRepo(db).Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	repo := NewChunkRepo(db)
+	insert := func(id string, index int, heading, text string) {
+		t.Helper()
+		if err := repo.Insert(ctx, &ChunkRecord{ID: id, NoteID: note.ID, ChunkIndex: index, HeadingPath: heading, Text: text, ChunkerVersion: "v1"}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	if previous, err := repo.ListPreviousByNote(ctx, note.ID); err != nil || len(previous) != 0 {
+		t.Fatalf("ListPreviousByNote() before archiving = %v, %v, want none", previous, err)
+	}
+
+	// Re-index: archive the first generation, then replace it
+	insert("old-1", 1, "# A > ## Two", "second section")
+	insert("old-0", 0, "# A", "first section")
+	if err := repo.ArchiveByNote(ctx, note.ID); err != nil {
+		t.Fatalf("ArchiveByNote() error = %v", err)
+	}
+	if err := repo.DeleteByNote(ctx, note.ID); err != nil {
+		t.Fatalf("DeleteByNote() error = %v", err)
+	}
+	insert("new-0", 0, "# A", "first section, edited")
+
+	// Archived texts survive pruning
+	if _, err := repo.PruneTexts(ctx); err != nil {
+		t.Fatalf("PruneTexts() error = %v", err)
+	}
+	previous, err := repo.ListPreviousByNote(ctx, note.ID)
+	if err != nil {
+		t.Fatalf("ListPreviousByNote() error = %v", err)
+	}
+	if len(previous) != 2 || previous[0].Text != "first section" || previous[1].HeadingPath != "# A > ## Two" || previous[1].Text != "second section" || previous[0].ChunkerVersion != "v1" {
+		t.Errorf("ListPreviousByNote() = %+v, want both old chunks in order with texts", previous)
+	}
+	current, err := repo.ListByNote(ctx, note.ID)
+	if err != nil {
+		t.Fatalf("ListByNote() error = %v", err)
+	}
+	if len(current) != 1 || current[0].ID != "new-0" || current[0].Text != "first section, edited" {
+		t.Errorf("ListByNote() = %+v, want the new chunk", current)
+	}
+
+	// Only one generation is kept
+	if err := repo.ArchiveByNote(ctx, note.ID); err != nil {
+		t.Fatalf("ArchiveByNote() error = %v", err)
+	}
+	if previous, err := repo.ListPreviousByNote(ctx, note.ID); err != nil || len(previous) != 1 || previous[0].Text != "first section, edited" {
+		t.Errorf("ListPreviousByNote() after second archive = %+v, %v, want the replaced generation", previous, err)
+	}
+}
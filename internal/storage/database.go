@@ -2,6 +2,8 @@ package storage
 
 import (
 	"database/sql"
+	"fmt"
+	"os"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -10,24 +12,52 @@ import (
 // New opens a SQLite database connection at the given path.
 // It enables foreign keys and sets connection pool settings.
 func New(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	// Enable foreign keys (disabled by default in SQLite) through the DSN so every pooled
+	// connection cascades deletes, not just the first one
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
 
-	// Enable foreign keys (disabled by default in SQLite)
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// Verify connection
+	if err := db.Ping(); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
 
+	return db, nil
+}
+
+// NewReadOnly opens an existing SQLite database read-only, for API replicas that serve
+// questions from the index written by the primary instance. The database must already be
+// migrated: a replica never creates or alters tables.
+func NewReadOnly(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("database not found: %w", err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+
 	// Set connection pool settings
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	// Verify connection
-	if err := db.Ping(); err != nil {
+	// The vaults table is created by the first migration; without it the primary has not run yet
+	var name string
+	err = db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'vaults'").Scan(&name)
+	if err == sql.ErrNoRows {
+		_ = db.Close()
+		return nil, fmt.Errorf("database %s is not migrated: start the primary instance first", path)
+	}
+	if err != nil {
 		_ = db.Close()
 		return nil, err
 	}
@@ -64,6 +94,123 @@ func Migrate(db *sql.DB) error {
 			text TEXT NOT NULL,
 			FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
 		);`,
+		`CREATE TABLE IF NOT EXISTS index_timings (
+			vault_id INTEGER NOT NULL,
+			rel_path TEXT NOT NULL,
+			read_ms INTEGER NOT NULL,
+			chunk_ms INTEGER NOT NULL,
+			embed_ms INTEGER NOT NULL,
+			upsert_ms INTEGER NOT NULL,
+			total_ms INTEGER NOT NULL,
+			size_bytes INTEGER NOT NULL,
+			chunk_count INTEGER NOT NULL,
+			indexed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (vault_id, rel_path),
+			FOREIGN KEY (vault_id) REFERENCES vaults(id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS index_checksums (
+			vault_id INTEGER PRIMARY KEY,
+			checksum TEXT NOT NULL,
+			note_count INTEGER NOT NULL,
+			chunk_count INTEGER NOT NULL,
+			computed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (vault_id) REFERENCES vaults(id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS chunk_texts (
+			hash TEXT PRIMARY KEY,
+			text TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS note_retrievals (
+			note_id TEXT NOT NULL,
+			retrieved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		"CREATE INDEX IF NOT EXISTS idx_note_retrievals_retrieved_at ON note_retrievals(retrieved_at)",
+		`CREATE TABLE IF NOT EXISTS note_tags (
+			note_id TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (note_id, tag),
+			FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+		);`,
+		"CREATE INDEX IF NOT EXISTS idx_note_tags_tag ON note_tags(tag)",
+		`CREATE TABLE IF NOT EXISTS chunk_clicks (
+			chunk_id TEXT PRIMARY KEY,
+			shown INTEGER NOT NULL DEFAULT 0,
+			clicks INTEGER NOT NULL DEFAULT 0,
+			last_clicked_at DATETIME
+		);`,
+		// AUTOINCREMENT keeps sequence numbers increasing even after old events are pruned
+		`CREATE TABLE IF NOT EXISTS index_events (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			vault_id INTEGER NOT NULL DEFAULT 0,
+			note_id TEXT NOT NULL DEFAULT '',
+			rel_path TEXT NOT NULL DEFAULT '',
+			previous_rel_path TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		// The chunks a note had before its last re-index, kept for chunk diffs
+		`CREATE TABLE IF NOT EXISTS chunk_history (
+			note_id TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			heading_path TEXT,
+			text_hash TEXT,
+			chunker_version TEXT,
+			embedded_at DATETIME,
+			PRIMARY KEY (note_id, chunk_index),
+			FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS answer_cache (
+			cache_key TEXT PRIMARY KEY,
+			index_version INTEGER NOT NULL,
+			response TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS jobs (
+			id TEXT PRIMARY KEY,
+			type TEXT NOT NULL,
+			state TEXT NOT NULL,
+			params TEXT NOT NULL DEFAULT '{}',
+			progress REAL NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			started_at DATETIME,
+			finished_at DATETIME
+		);`,
+		"CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs(state)",
+		// Chunks left out of the index, replaced each time their note is indexed
+		`CREATE TABLE IF NOT EXISTS index_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			note_id TEXT NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+		);`,
+		"CREATE INDEX IF NOT EXISTS idx_index_failures_note_id ON index_failures(note_id)",
+		// API users, identified by the SHA256 hash of their token, and the vaults each may read
+		`CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_vaults (
+			user_id INTEGER NOT NULL,
+			vault_id INTEGER NOT NULL,
+			PRIMARY KEY (user_id, vault_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (vault_id) REFERENCES vaults(id)
+		);`,
+		// Folder prefixes removed from the index that index runs skip until they are included again
+		`CREATE TABLE IF NOT EXISTS excluded_folders (
+			vault_id INTEGER NOT NULL,
+			prefix TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (vault_id, prefix),
+			FOREIGN KEY (vault_id) REFERENCES vaults(id)
+		);`,
 	}
 
 	for _, stmt := range schema {
@@ -72,5 +219,124 @@ func Migrate(db *sql.DB) error {
 		}
 	}
 
+	// Columns added after the initial schema. CREATE TABLE IF NOT EXISTS does not
+	// alter existing tables, so these are applied individually when missing.
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"notes", "tier", "TEXT NOT NULL DEFAULT 'hot'"},
+		{"notes", "last_retrieved_at", "DATETIME"},
+		{"notes", "aliases", "TEXT"},
+		{"notes", "frontmatter_created", "DATETIME"},
+		{"notes", "frontmatter_modified", "DATETIME"},
+		{"notes", "file_modified", "DATETIME"},
+		{"chunks", "text_hash", "TEXT"},
+		{"chunks", "chunker_version", "TEXT"},
+		{"chunks", "embedding_model", "TEXT"},
+		{"chunks", "embedded_at", "DATETIME"},
+		{"chunks", "run_id", "TEXT"},
+		{"chunks", "overlap_runes", "INTEGER NOT NULL DEFAULT 0"},
+	}
+
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_chunks_text_hash ON chunks(text_hash)"); err != nil {
+		return err
+	}
+
+	return migrateChunkTexts(db)
+}
+
+// migrateChunkTexts moves text stored inline on chunks written before chunk_texts existed
+// into the content-addressed table.
+func migrateChunkTexts(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, text FROM chunks WHERE text_hash IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to query inline chunk texts: %w", err)
+	}
+	type inlineChunk struct {
+		id   string
+		text string
+	}
+	var pending []inlineChunk
+	for rows.Next() {
+		var c inlineChunk
+		if err := rows.Scan(&c.id, &c.text); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan inline chunk text: %w", err)
+		}
+		pending = append(pending, c)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin chunk text migration: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, c := range pending {
+		hash := TextHash(c.text)
+		if _, err := tx.Exec("INSERT OR IGNORE INTO chunk_texts (hash, text) VALUES (?, ?)", hash, c.text); err != nil {
+			return fmt.Errorf("failed to store chunk text: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE chunks SET text_hash = ?, text = '' WHERE id = ?", hash, c.id); err != nil {
+			return fmt.Errorf("failed to update chunk text hash: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk text migration: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already present.
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan column info for %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
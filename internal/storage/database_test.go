@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 )
@@ -71,15 +72,24 @@ func TestNew_EnablesForeignKeys(t *testing.T) {
 		_ = db.Close()
 	}()
 
-	// Check that foreign keys are enabled
-	var fkEnabled int
-	err = db.QueryRow("PRAGMA foreign_keys").Scan(&fkEnabled)
-	if err != nil {
-		t.Fatalf("Failed to check foreign keys: %v", err)
-	}
+	// Check that foreign keys are enabled on every pooled connection, not just the first
+	ctx := context.Background()
+	for i := range 3 {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn() error = %v", err)
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
 
-	if fkEnabled != 1 {
-		t.Error("New() should enable foreign keys")
+		var fkEnabled int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fkEnabled); err != nil {
+			t.Fatalf("Failed to check foreign keys: %v", err)
+		}
+		if fkEnabled != 1 {
+			t.Errorf("connection %d: New() should enable foreign keys", i)
+		}
 	}
 }
 
@@ -245,3 +255,91 @@ func TestNew_Ping(t *testing.T) {
 		t.Errorf("Ping() error = %v", err)
 	}
 }
+
+func TestMigrate_MovesInlineChunkTexts(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	// Schema as it was before chunk_texts: text stored on each chunk
+	legacy := []string{
+		`CREATE TABLE vaults (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE, root_path TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE notes (id TEXT PRIMARY KEY, vault_id INTEGER NOT NULL, rel_path TEXT NOT NULL, folder TEXT NOT NULL, title TEXT, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP, hash TEXT NOT NULL)`,
+		`CREATE TABLE chunks (id TEXT PRIMARY KEY, note_id TEXT NOT NULL, chunk_index INTEGER NOT NULL, heading_path TEXT, text TEXT NOT NULL)`,
+		`INSERT INTO vaults (id, name, root_path) VALUES (1, 'personal', '/tmp')`,
+		`INSERT INTO notes (id, vault_id, rel_path, folder, hash) VALUES ('n1', 1, 'a.md', '', 'h')`,
+		`INSERT INTO chunks (id, note_id, chunk_index, heading_path, text) VALUES ('c1', 'n1', 0, '', 'same'), ('c2', 'n1', 1, '', 'same')`,
+	}
+	for _, stmt := range legacy {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("legacy schema: %v", err)
+		}
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	var texts, inline int
+	if err := db.QueryRow("SELECT COUNT(*) FROM chunk_texts").Scan(&texts); err != nil {
+		t.Fatalf("count chunk_texts: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM chunks WHERE text != '' OR text_hash IS NULL").Scan(&inline); err != nil {
+		t.Fatalf("count inline chunks: %v", err)
+	}
+	if texts != 1 || inline != 0 {
+		t.Errorf("after Migrate() chunk_texts = %d, inline chunks = %d, want 1 and 0", texts, inline)
+	}
+
+	chunk, err := NewChunkRepo(db).GetByID(context.Background(), "c2")
+	if err != nil || chunk.Text != "same" {
+		t.Errorf("GetByID() = %+v, %v, want migrated text", chunk, err)
+	}
+}
+
+func TestNewReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	if _, err := NewReadOnly(dbPath); err == nil {
+		t.Error("NewReadOnly() expected error for a missing database")
+	}
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if _, err := NewReadOnly(dbPath); err == nil {
+		t.Error("NewReadOnly() expected error for a database that is not migrated")
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if _, err := NewVaultRepo(db).GetOrCreateByName(context.Background(), "personal", "/vaults/personal"); err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	readOnly, err := NewReadOnly(dbPath)
+	if err != nil {
+		t.Fatalf("NewReadOnly() error = %v", err)
+	}
+	defer func() {
+		_ = readOnly.Close()
+	}()
+
+	repo := NewVaultRepo(readOnly)
+	vault, err := repo.GetByName(context.Background(), "personal")
+	if err != nil || vault.RootPath != "/vaults/personal" {
+		t.Errorf("GetByName() = %+v, %v, want the personal vault", vault, err)
+	}
+	if _, err := repo.GetOrCreateByName(context.Background(), "work", "/vaults/work"); err == nil {
+		t.Error("GetOrCreateByName() expected error on a read-only database")
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_excluded_folder_store.go -package=mocks helloworld-ai/internal/storage ExcludedFolderStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ExcludedFolderStore defines the interface for storing folder prefixes excluded from the index.
+type ExcludedFolderStore interface {
+	// Add excludes a folder prefix of a vault. Adding an excluded prefix again is a no-op.
+	Add(ctx context.Context, vaultID int, prefix string) error
+	// Remove includes a folder prefix again, returning ErrNotFound if it was not excluded.
+	Remove(ctx context.Context, vaultID int, prefix string) error
+	// List returns the excluded prefixes of the given vault (all vaults if 0), ordered by vault and prefix.
+	List(ctx context.Context, vaultID int) ([]ExcludedFolderRecord, error)
+}
+
+// ExcludedFolderRepo provides methods for excluded folder operations.
+// It implements the ExcludedFolderStore interface.
+type ExcludedFolderRepo struct {
+	db *sql.DB
+}
+
+// NewExcludedFolderRepo creates a new ExcludedFolderRepo.
+func NewExcludedFolderRepo(db *sql.DB) *ExcludedFolderRepo {
+	return &ExcludedFolderRepo{db: db}
+}
+
+// Add excludes a folder prefix of a vault. Adding an excluded prefix again is a no-op.
+func (r *ExcludedFolderRepo) Add(ctx context.Context, vaultID int, prefix string) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO excluded_folders (vault_id, prefix) VALUES (?, ?) ON CONFLICT (vault_id, prefix) DO NOTHING",
+		vaultID, prefix,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to exclude folder: %w", err)
+	}
+	return nil
+}
+
+// Remove includes a folder prefix again, returning ErrNotFound if it was not excluded.
+func (r *ExcludedFolderRepo) Remove(ctx context.Context, vaultID int, prefix string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM excluded_folders WHERE vault_id = ? AND prefix = ?", vaultID, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to include folder: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns the excluded prefixes of the given vault (all vaults if 0), ordered by vault and prefix.
+func (r *ExcludedFolderRepo) List(ctx context.Context, vaultID int) ([]ExcludedFolderRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT vault_id, prefix, created_at FROM excluded_folders
+		WHERE ? = 0 OR vault_id = ?
+		ORDER BY vault_id, prefix`,
+		vaultID, vaultID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query excluded folders: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var folders []ExcludedFolderRecord
+	for rows.Next() {
+		var f ExcludedFolderRecord
+		var createdAt string
+		if err := rows.Scan(&f.VaultID, &f.Prefix, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan excluded folder: %w", err)
+		}
+		if f.CreatedAt, err = parseTimestamp(createdAt); err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+		}
+		folders = append(folders, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return folders, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExcludedFolderRepo(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	repo := NewExcludedFolderRepo(db)
+
+	for _, f := range []struct {
+		vaultID int
+		prefix  string
+	}{
+		{personal.ID, "Journal"},
+		{personal.ID, "Archive"},
+		{work.ID, "Archive"},
+		// Excluding a folder twice keeps one entry
+		{personal.ID, "Archive"},
+	} {
+		if err := repo.Add(ctx, f.vaultID, f.prefix); err != nil {
+			t.Fatalf("Add(%d, %q) error = %v", f.vaultID, f.prefix, err)
+		}
+	}
+
+	all, err := repo.List(ctx, 0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 3 || all[0].Prefix != "Archive" || all[1].Prefix != "Journal" || all[2].VaultID != work.ID || all[0].CreatedAt.IsZero() {
+		t.Errorf("List(0) = %+v, want personal Archive, Journal, then work Archive", all)
+	}
+
+	if err := repo.Remove(ctx, personal.ID, "Archive"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := repo.Remove(ctx, personal.ID, "Archive"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove() of an included folder error = %v, want ErrNotFound", err)
+	}
+	personalFolders, err := repo.List(ctx, personal.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(personalFolders) != 1 || personalFolders[0].Prefix != "Journal" {
+		t.Errorf("List(personal) = %+v, want only Journal", personalFolders)
+	}
+}
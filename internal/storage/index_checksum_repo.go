@@ -0,0 +1,122 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_index_checksum_store.go -package=mocks helloworld-ai/internal/storage IndexChecksumStore
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// IndexChecksumStore defines the interface for per-vault index checksum storage.
+type IndexChecksumStore interface {
+	// Compute calculates the checksum of a vault's current notes and chunks.
+	Compute(ctx context.Context, vaultID int) (*IndexChecksumRecord, error)
+	// Save stores the checksum for a vault, replacing any previous one.
+	Save(ctx context.Context, checksum *IndexChecksumRecord) error
+	// Get returns the stored checksum for a vault. Returns ErrNotFound if none was saved.
+	Get(ctx context.Context, vaultID int) (*IndexChecksumRecord, error)
+}
+
+// IndexChecksumRepo provides methods for index checksum operations.
+// It implements the IndexChecksumStore interface.
+type IndexChecksumRepo struct {
+	db *sql.DB
+}
+
+// NewIndexChecksumRepo creates a new IndexChecksumRepo.
+func NewIndexChecksumRepo(db *sql.DB) *IndexChecksumRepo {
+	return &IndexChecksumRepo{db: db}
+}
+
+// Compute calculates the checksum of a vault's current notes and chunks.
+// Notes are hashed in rel_path order, each followed by its chunk IDs in chunk_index order,
+// so any added, removed, or edited note or chunk changes the checksum.
+func (r *IndexChecksumRepo) Compute(ctx context.Context, vaultID int) (*IndexChecksumRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT n.id, n.rel_path, n.hash, c.id
+		FROM notes n
+		LEFT JOIN chunks c ON c.note_id = n.id
+		WHERE n.vault_id = ?
+		ORDER BY n.rel_path, c.chunk_index, c.id`,
+		vaultID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes for checksum: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	hash := sha256.New()
+	record := &IndexChecksumRecord{VaultID: vaultID}
+	var lastNoteID string
+	for rows.Next() {
+		var noteID, relPath, noteHash string
+		var chunkID sql.NullString
+		if err := rows.Scan(&noteID, &relPath, &noteHash, &chunkID); err != nil {
+			return nil, fmt.Errorf("failed to scan checksum row: %w", err)
+		}
+		if noteID != lastNoteID {
+			_, _ = fmt.Fprintf(hash, "note\t%s\t%s\n", relPath, noteHash)
+			record.NoteCount++
+			lastNoteID = noteID
+		}
+		if chunkID.Valid {
+			_, _ = fmt.Fprintf(hash, "chunk\t%s\n", chunkID.String)
+			record.ChunkCount++
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	record.Checksum = hex.EncodeToString(hash.Sum(nil))
+	record.ComputedAt = time.Now().UTC()
+	return record, nil
+}
+
+// Save stores the checksum for a vault, replacing any previous one.
+func (r *IndexChecksumRepo) Save(ctx context.Context, checksum *IndexChecksumRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO index_checksums (vault_id, checksum, note_count, chunk_count, computed_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(vault_id) DO UPDATE SET
+			checksum = excluded.checksum,
+			note_count = excluded.note_count,
+			chunk_count = excluded.chunk_count,
+			computed_at = CURRENT_TIMESTAMP`,
+		checksum.VaultID, checksum.Checksum, checksum.NoteCount, checksum.ChunkCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save index checksum: %w", err)
+	}
+	return nil
+}
+
+// Get returns the stored checksum for a vault. Returns ErrNotFound if none was saved.
+func (r *IndexChecksumRepo) Get(ctx context.Context, vaultID int) (*IndexChecksumRecord, error) {
+	var record IndexChecksumRecord
+	var computedAtStr string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT vault_id, checksum, note_count, chunk_count, computed_at FROM index_checksums WHERE vault_id = ?",
+		vaultID,
+	).Scan(&record.VaultID, &record.Checksum, &record.NoteCount, &record.ChunkCount, &computedAtStr)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index checksum: %w", err)
+	}
+
+	record.ComputedAt, err = parseTimestamp(computedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse computed_at: %w", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIndexChecksumRepo_ComputeSaveGet(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	repo := NewIndexChecksumRepo(db)
+	if _, err := repo.Get(ctx, vault.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() before Save error = %v, want ErrNotFound", err)
+	}
+
+	empty, err := repo.Compute(ctx, vault.ID)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+
+	noteRepo := NewNoteRepo(db)
+	chunkRepo := NewChunkRepo(db)
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "a.md", Title: "A", Hash: "hash-a"}
+	if err := noteRepo.Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	for i, id := range []string{"chunk-1", "chunk-2"} {
+		if err := chunkRepo.Insert(ctx, &ChunkRecord{ID: id, NoteID: note.ID, ChunkIndex: i, Text: "text"}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	indexed, err := repo.Compute(ctx, vault.ID)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if indexed.Checksum == empty.Checksum {
+		t.Error("Compute() checksum should change when notes are added")
+	}
+	if indexed.NoteCount != 1 || indexed.ChunkCount != 2 {
+		t.Errorf("Compute() counts = %d notes/%d chunks, want 1/2", indexed.NoteCount, indexed.ChunkCount)
+	}
+
+	again, err := repo.Compute(ctx, vault.ID)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if again.Checksum != indexed.Checksum {
+		t.Error("Compute() should be deterministic for unchanged data")
+	}
+
+	if err := repo.Save(ctx, indexed); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	stored, err := repo.Get(ctx, vault.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Checksum != indexed.Checksum || stored.ChunkCount != 2 {
+		t.Errorf("Get() = %+v, want saved checksum", stored)
+	}
+
+	// Tampering with a chunk changes the checksum
+	if _, err := db.ExecContext(ctx, "DELETE FROM chunks WHERE id = ?", "chunk-2"); err != nil {
+		t.Fatalf("failed to delete chunk: %v", err)
+	}
+	tampered, err := repo.Compute(ctx, vault.ID)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	if tampered.Checksum == stored.Checksum {
+		t.Error("Compute() checksum should change when a chunk is removed")
+	}
+}
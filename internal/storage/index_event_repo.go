@@ -0,0 +1,117 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_index_event_store.go -package=mocks helloworld-ai/internal/storage IndexEventStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// IndexEventStore defines the interface for the index change event log.
+type IndexEventStore interface {
+	// Record appends an event, setting its Seq.
+	Record(ctx context.Context, event *IndexEventRecord) error
+	// ListSince returns up to limit events with a sequence number above since, oldest first.
+	ListSince(ctx context.Context, since int64, limit int) ([]IndexEventRecord, error)
+	// Bounds returns the oldest retained and the latest sequence numbers (0 when none).
+	Bounds(ctx context.Context) (oldest, latest int64, err error)
+	// Prune deletes all but the newest keep events and returns how many were deleted.
+	Prune(ctx context.Context, keep int) (int64, error)
+}
+
+// IndexEventRepo provides methods for index event operations.
+// It implements the IndexEventStore interface.
+type IndexEventRepo struct {
+	db *sql.DB
+}
+
+// NewIndexEventRepo creates a new IndexEventRepo.
+func NewIndexEventRepo(db *sql.DB) *IndexEventRepo {
+	return &IndexEventRepo{db: db}
+}
+
+// Record appends an event, setting its Seq.
+func (r *IndexEventRepo) Record(ctx context.Context, event *IndexEventRecord) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO index_events (type, vault_id, note_id, rel_path, previous_rel_path, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		event.Type, event.VaultID, event.NoteID, event.RelPath, event.PreviousRelPath,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record index event: %w", err)
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to read index event sequence: %w", err)
+	}
+	event.Seq = seq
+	return nil
+}
+
+// ListSince returns up to limit events with a sequence number above since, oldest first.
+func (r *IndexEventRepo) ListSince(ctx context.Context, since int64, limit int) ([]IndexEventRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT seq, type, vault_id, note_id, rel_path, previous_rel_path, created_at
+		FROM index_events
+		WHERE seq > ?
+		ORDER BY seq
+		LIMIT ?`,
+		since, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index events: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var events []IndexEventRecord
+	for rows.Next() {
+		var event IndexEventRecord
+		var createdAtStr string
+		if err := rows.Scan(&event.Seq, &event.Type, &event.VaultID, &event.NoteID, &event.RelPath, &event.PreviousRelPath, &createdAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan index event: %w", err)
+		}
+		event.CreatedAt, err = parseTimestamp(createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at timestamp: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return events, nil
+}
+
+// Bounds returns the oldest retained and the latest sequence numbers (0 when none).
+// The latest number survives pruning, so it only resets with the database.
+func (r *IndexEventRepo) Bounds(ctx context.Context) (oldest, latest int64, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT
+			COALESCE((SELECT MIN(seq) FROM index_events), 0),
+			COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'index_events'), 0)`,
+	).Scan(&oldest, &latest)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query index event bounds: %w", err)
+	}
+	return oldest, latest, nil
+}
+
+// Prune deletes all but the newest keep events and returns how many were deleted.
+func (r *IndexEventRepo) Prune(ctx context.Context, keep int) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM index_events WHERE seq <= (SELECT MAX(seq) FROM index_events) - ?`,
+		keep,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune index events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pruned index events: %w", err)
+	}
+	return deleted, nil
+}
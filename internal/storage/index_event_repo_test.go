@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIndexEventRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	repo := NewIndexEventRepo(db)
+
+	if oldest, latest, err := repo.Bounds(ctx); err != nil || oldest != 0 || latest != 0 {
+		t.Fatalf("Bounds() on empty log = %d, %d, %v, want 0, 0, nil", oldest, latest, err)
+	}
+
+	events := []*IndexEventRecord{
+		{Type: IndexEventNoteAdded, VaultID: 1, NoteID: "n1", RelPath: "garden.md"},
+		{Type: IndexEventNoteMoved, VaultID: 1, NoteID: "n1", RelPath: "home/garden.md", PreviousRelPath: "garden.md"},
+		{Type: IndexEventNoteDeleted, VaultID: 1, NoteID: "n1", RelPath: "home/garden.md"},
+		{Type: IndexEventReindexCompleted},
+	}
+	for i, event := range events {
+		if err := repo.Record(ctx, event); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		if event.Seq != int64(i+1) {
+			t.Errorf("Record() seq = %d, want %d", event.Seq, i+1)
+		}
+	}
+
+	got, err := repo.ListSince(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("ListSince() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Seq != 2 || got[1].Seq != 3 {
+		t.Fatalf("ListSince(1, 2) = %+v, want events 2 and 3", got)
+	}
+	if got[0].PreviousRelPath != "garden.md" || got[0].RelPath != "home/garden.md" || got[0].CreatedAt.IsZero() {
+		t.Errorf("ListSince() moved event = %+v, want both paths and a timestamp", got[0])
+	}
+
+	// Pruning keeps the newest events, and sequence numbers keep increasing afterwards
+	deleted, err := repo.Prune(ctx, 2)
+	if err != nil || deleted != 2 {
+		t.Fatalf("Prune(2) = %d, %v, want 2 deleted", deleted, err)
+	}
+	if _, err := repo.Prune(ctx, 2); err != nil {
+		t.Fatalf("Prune() again error = %v", err)
+	}
+	next := &IndexEventRecord{Type: IndexEventNoteAdded, VaultID: 2, NoteID: "n2", RelPath: "work.md"}
+	if err := repo.Record(ctx, next); err != nil || next.Seq != 5 {
+		t.Fatalf("Record() after prune seq = %d, %v, want 5", next.Seq, err)
+	}
+	if oldest, latest, err := repo.Bounds(ctx); err != nil || oldest != 3 || latest != 5 {
+		t.Errorf("Bounds() = %d, %d, %v, want 3, 5, nil", oldest, latest, err)
+	}
+}
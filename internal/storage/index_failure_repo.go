@@ -0,0 +1,127 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_index_failure_store.go -package=mocks helloworld-ai/internal/storage IndexFailureStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// IndexFailureStore defines the interface for the dead-letter records of chunks that were
+// skipped or failed during indexing.
+type IndexFailureStore interface {
+	// ReplaceForNote replaces the failures recorded for a note with failures (none clears them).
+	ReplaceForNote(ctx context.Context, noteID string, failures []IndexFailureRecord) error
+	// List returns up to limit failures, newest first.
+	List(ctx context.Context, limit int) ([]IndexFailureRecord, error)
+	// CountByReason returns the number of recorded failures per reason.
+	CountByReason(ctx context.Context) (map[string]int, error)
+}
+
+// IndexFailureRepo provides methods for index failure operations.
+// It implements the IndexFailureStore interface.
+type IndexFailureRepo struct {
+	db *sql.DB
+}
+
+// NewIndexFailureRepo creates a new IndexFailureRepo.
+func NewIndexFailureRepo(db *sql.DB) *IndexFailureRepo {
+	return &IndexFailureRepo{db: db}
+}
+
+// ReplaceForNote replaces the failures recorded for a note with failures (none clears them).
+// The NoteID of each failure is ignored in favour of noteID.
+func (r *IndexFailureRepo) ReplaceForNote(ctx context.Context, noteID string, failures []IndexFailureRecord) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM index_failures WHERE note_id = ?", noteID); err != nil {
+		return fmt.Errorf("failed to delete index failures: %w", err)
+	}
+	for _, failure := range failures {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO index_failures (note_id, chunk_index, reason, error, created_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			noteID, failure.ChunkIndex, failure.Reason, failure.Error,
+		); err != nil {
+			return fmt.Errorf("failed to record index failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit failures, newest first.
+func (r *IndexFailureRepo) List(ctx context.Context, limit int) ([]IndexFailureRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT f.id, f.note_id, n.vault_id, n.rel_path, f.chunk_index, f.reason, f.error, f.created_at
+		FROM index_failures f
+		JOIN notes n ON n.id = f.note_id
+		ORDER BY f.id DESC
+		LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index failures: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var failures []IndexFailureRecord
+	for rows.Next() {
+		var failure IndexFailureRecord
+		var createdAtStr string
+		if err := rows.Scan(
+			&failure.ID, &failure.NoteID, &failure.VaultID, &failure.RelPath,
+			&failure.ChunkIndex, &failure.Reason, &failure.Error, &createdAtStr,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan index failure: %w", err)
+		}
+		failure.CreatedAt, err = parseTimestamp(createdAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at timestamp: %w", err)
+		}
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return failures, nil
+}
+
+// CountByReason returns the number of recorded failures per reason.
+func (r *IndexFailureRepo) CountByReason(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT reason, COUNT(*) FROM index_failures GROUP BY reason")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count index failures: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan index failure count: %w", err)
+		}
+		counts[reason] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return counts, nil
+}
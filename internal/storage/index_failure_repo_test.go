@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIndexFailureRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	noteRepo := NewNoteRepo(db)
+	notes := []*NoteRecord{
+		{VaultID: vault.ID, RelPath: "table.md", Hash: "a"},
+		{VaultID: vault.ID, RelPath: "paste.md", Hash: "b"},
+	}
+	for _, note := range notes {
+		if err := noteRepo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	repo := NewIndexFailureRepo(db)
+	if err := repo.ReplaceForNote(ctx, notes[0].ID, []IndexFailureRecord{
+		{ChunkIndex: 2, Reason: IndexFailureContextSize, Error: "too large"},
+		{ChunkIndex: 5, Reason: IndexFailureContextSize, Error: "too large"},
+	}); err != nil {
+		t.Fatalf("ReplaceForNote() error = %v", err)
+	}
+	if err := repo.ReplaceForNote(ctx, notes[1].ID, []IndexFailureRecord{
+		{ChunkIndex: 0, Reason: IndexFailureEmbedding, Error: "connection refused"},
+	}); err != nil {
+		t.Fatalf("ReplaceForNote() error = %v", err)
+	}
+
+	failures, err := repo.List(ctx, 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(failures) != 3 {
+		t.Fatalf("List() returned %d records, want 3", len(failures))
+	}
+	if got := failures[0]; got.RelPath != "paste.md" || got.VaultID != vault.ID || got.Reason != IndexFailureEmbedding || got.Error != "connection refused" || got.CreatedAt.IsZero() {
+		t.Errorf("List()[0] = %+v, want the paste.md embedding error", got)
+	}
+	counts, err := repo.CountByReason(ctx)
+	if err != nil {
+		t.Fatalf("CountByReason() error = %v", err)
+	}
+	if counts[IndexFailureContextSize] != 2 || counts[IndexFailureEmbedding] != 1 {
+		t.Errorf("CountByReason() = %v, want 2 context_size and 1 embedding_error", counts)
+	}
+
+	// Re-indexing a note replaces its failures, and deleting it removes them
+	if err := repo.ReplaceForNote(ctx, notes[0].ID, nil); err != nil {
+		t.Fatalf("ReplaceForNote() error = %v", err)
+	}
+	if err := noteRepo.Delete(ctx, notes[1].ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	failures, err = repo.List(ctx, 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("List() = %+v, want no failures", failures)
+	}
+}
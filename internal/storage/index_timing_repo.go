@@ -0,0 +1,90 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_index_timing_store.go -package=mocks helloworld-ai/internal/storage IndexTimingStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// IndexTimingStore defines the interface for per-file indexing timing storage.
+type IndexTimingStore interface {
+	// Record stores the timing of the latest indexing run for a file, replacing any previous entry.
+	Record(ctx context.Context, timing *IndexTimingRecord) error
+	// ListSlowest returns up to limit files ordered by total indexing time, slowest first.
+	ListSlowest(ctx context.Context, limit int) ([]IndexTimingRecord, error)
+}
+
+// IndexTimingRepo provides methods for index timing operations.
+// It implements the IndexTimingStore interface.
+type IndexTimingRepo struct {
+	db *sql.DB
+}
+
+// NewIndexTimingRepo creates a new IndexTimingRepo.
+func NewIndexTimingRepo(db *sql.DB) *IndexTimingRepo {
+	return &IndexTimingRepo{db: db}
+}
+
+// Record stores the timing of the latest indexing run for a file, replacing any previous entry.
+func (r *IndexTimingRepo) Record(ctx context.Context, timing *IndexTimingRecord) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO index_timings (vault_id, rel_path, read_ms, chunk_ms, embed_ms, upsert_ms, total_ms, size_bytes, chunk_count, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(vault_id, rel_path) DO UPDATE SET
+			read_ms = excluded.read_ms,
+			chunk_ms = excluded.chunk_ms,
+			embed_ms = excluded.embed_ms,
+			upsert_ms = excluded.upsert_ms,
+			total_ms = excluded.total_ms,
+			size_bytes = excluded.size_bytes,
+			chunk_count = excluded.chunk_count,
+			indexed_at = CURRENT_TIMESTAMP`,
+		timing.VaultID, timing.RelPath, timing.ReadMs, timing.ChunkMs, timing.EmbedMs,
+		timing.UpsertMs, timing.TotalMs, timing.SizeBytes, timing.ChunkCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record index timing: %w", err)
+	}
+	return nil
+}
+
+// ListSlowest returns up to limit files ordered by total indexing time, slowest first.
+func (r *IndexTimingRepo) ListSlowest(ctx context.Context, limit int) ([]IndexTimingRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT vault_id, rel_path, read_ms, chunk_ms, embed_ms, upsert_ms, total_ms, size_bytes, chunk_count, indexed_at
+		FROM index_timings
+		ORDER BY total_ms DESC, rel_path
+		LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index timings: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var timings []IndexTimingRecord
+	for rows.Next() {
+		var timing IndexTimingRecord
+		var indexedAtStr string
+		if err := rows.Scan(
+			&timing.VaultID, &timing.RelPath, &timing.ReadMs, &timing.ChunkMs, &timing.EmbedMs,
+			&timing.UpsertMs, &timing.TotalMs, &timing.SizeBytes, &timing.ChunkCount, &indexedAtStr,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan index timing: %w", err)
+		}
+		timing.IndexedAt, err = parseTimestamp(indexedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse indexed_at timestamp: %w", err)
+		}
+		timings = append(timings, timing)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return timings, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIndexTimingRepo_RecordAndListSlowest(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	repo := NewIndexTimingRepo(db)
+	timings := []*IndexTimingRecord{
+		{VaultID: vault.ID, RelPath: "fast.md", ReadMs: 1, ChunkMs: 1, EmbedMs: 5, UpsertMs: 2, TotalMs: 9},
+		{VaultID: vault.ID, RelPath: "table.md", ReadMs: 2, ChunkMs: 900, EmbedMs: 100, UpsertMs: 10, TotalMs: 1012},
+		{VaultID: vault.ID, RelPath: "paste.md", ReadMs: 1, ChunkMs: 20, EmbedMs: 400, UpsertMs: 30, TotalMs: 451},
+	}
+	for _, timing := range timings {
+		if err := repo.Record(ctx, timing); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	// Re-recording a file replaces its previous timing
+	if err := repo.Record(ctx, &IndexTimingRecord{VaultID: vault.ID, RelPath: "fast.md", EmbedMs: 3, TotalMs: 3}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	slowest, err := repo.ListSlowest(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListSlowest() error = %v", err)
+	}
+	if len(slowest) != 2 {
+		t.Fatalf("ListSlowest() returned %d records, want 2", len(slowest))
+	}
+	if slowest[0].RelPath != "table.md" || slowest[0].Bottleneck() != "chunk" {
+		t.Errorf("ListSlowest()[0] = %s (%s), want table.md (chunk)", slowest[0].RelPath, slowest[0].Bottleneck())
+	}
+	if slowest[1].RelPath != "paste.md" || slowest[1].Bottleneck() != "embed" {
+		t.Errorf("ListSlowest()[1] = %s (%s), want paste.md (embed)", slowest[1].RelPath, slowest[1].Bottleneck())
+	}
+	if slowest[0].IndexedAt.IsZero() {
+		t.Error("ListSlowest() IndexedAt should be set")
+	}
+}
@@ -0,0 +1,230 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_job_store.go -package=mocks helloworld-ai/internal/storage JobStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// jobColumns are the columns scanned by scanJob, in order.
+const jobColumns = "id, type, state, params, progress, error, created_at, started_at, finished_at"
+
+// JobStore defines the interface for the background job table.
+type JobStore interface {
+	// Create queues a job, setting its ID, state, and creation time.
+	Create(ctx context.Context, job *JobRecord) error
+	// Get returns a job by ID. Returns ErrNotFound if it does not exist.
+	Get(ctx context.Context, id string) (*JobRecord, error)
+	// ListActive returns the queued and running jobs, oldest first.
+	ListActive(ctx context.Context) ([]JobRecord, error)
+	// ClaimNext marks the oldest queued job as running and returns it.
+	// Returns ErrNotFound if no job is queued.
+	ClaimNext(ctx context.Context) (*JobRecord, error)
+	// UpdateProgress records the fraction of a running job that is done.
+	UpdateProgress(ctx context.Context, id string, progress float64) error
+	// Finish marks a job as succeeded, or as failed with errMsg when it is not empty.
+	Finish(ctx context.Context, id string, errMsg string) error
+	// FailRunning marks every running job as failed with errMsg and returns how many were.
+	// Called on startup, when no worker can still be running them.
+	FailRunning(ctx context.Context, errMsg string) (int64, error)
+}
+
+// JobRepo provides methods for background job operations.
+// It implements the JobStore interface.
+type JobRepo struct {
+	db *sql.DB
+}
+
+// NewJobRepo creates a new JobRepo.
+func NewJobRepo(db *sql.DB) *JobRepo {
+	return &JobRepo{db: db}
+}
+
+// Create queues a job, setting its ID, state, and creation time.
+func (r *JobRepo) Create(ctx context.Context, job *JobRecord) error {
+	job.ID = uuid.New().String()
+	job.State = JobQueued
+	if job.Params == "" {
+		job.Params = "{}"
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, type, state, params, progress, error, created_at)
+		VALUES (?, ?, ?, ?, 0, '', CURRENT_TIMESTAMP)`,
+		job.ID, job.Type, job.State, job.Params,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	created, err := r.Get(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	*job = *created
+	return nil
+}
+
+// Get returns a job by ID. Returns ErrNotFound if it does not exist.
+func (r *JobRepo) Get(ctx context.Context, id string) (*JobRecord, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id)
+	job, err := scanJob(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListActive returns the queued and running jobs, oldest first.
+func (r *JobRepo) ListActive(ctx context.Context) ([]JobRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+jobColumns+" FROM jobs WHERE state IN (?, ?) ORDER BY created_at, rowid",
+		JobQueued, JobRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active jobs: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var jobs []JobRecord
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimNext marks the oldest queued job as running and returns it.
+// Returns ErrNotFound if no job is queued.
+func (r *JobRepo) ClaimNext(ctx context.Context) (*JobRecord, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id FROM jobs WHERE state = ? ORDER BY created_at, rowid LIMIT 1",
+		JobQueued,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queued jobs: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET state = ?, started_at = CURRENT_TIMESTAMP WHERE id = ? AND state = ?",
+		JobRunning, id, JobQueued,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count claimed jobs: %w", err)
+	}
+	if claimed == 0 {
+		// Another worker claimed it first
+		return nil, ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+// UpdateProgress records the fraction of a running job that is done.
+func (r *JobRepo) UpdateProgress(ctx context.Context, id string, progress float64) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET progress = ? WHERE id = ? AND state = ?",
+		min(max(progress, 0), 1), id, JobRunning,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job progress: %w", err)
+	}
+	return nil
+}
+
+// Finish marks a job as succeeded, or as failed with errMsg when it is not empty.
+// A succeeded job's progress is set to 1.
+func (r *JobRepo) Finish(ctx context.Context, id string, errMsg string) error {
+	var err error
+	if errMsg == "" {
+		_, err = r.db.ExecContext(ctx,
+			"UPDATE jobs SET state = ?, progress = 1, error = '', finished_at = CURRENT_TIMESTAMP WHERE id = ?",
+			JobSucceeded, id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			"UPDATE jobs SET state = ?, error = ?, finished_at = CURRENT_TIMESTAMP WHERE id = ?",
+			JobFailed, errMsg, id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to finish job: %w", err)
+	}
+	return nil
+}
+
+// FailRunning marks every running job as failed with errMsg and returns how many were.
+func (r *JobRepo) FailRunning(ctx context.Context, errMsg string) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE jobs SET state = ?, error = ?, finished_at = CURRENT_TIMESTAMP WHERE state = ?",
+		JobFailed, errMsg, JobRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail running jobs: %w", err)
+	}
+	failed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failed jobs: %w", err)
+	}
+	return failed, nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanJob scans the jobColumns of a row. Returns sql.ErrNoRows unwrapped.
+func scanJob(row rowScanner) (*JobRecord, error) {
+	var job JobRecord
+	var createdAtStr string
+	var startedAt, finishedAt sql.NullString
+	err := row.Scan(&job.ID, &job.Type, &job.State, &job.Params, &job.Progress, &job.Error, &createdAtStr, &startedAt, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan job: %w", err)
+	}
+
+	job.CreatedAt, err = parseTimestamp(createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	if startedAt.Valid {
+		t, err := parseTimestamp(startedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse started_at: %w", err)
+		}
+		job.StartedAt = &t
+	}
+	if finishedAt.Valid {
+		t, err := parseTimestamp(finishedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse finished_at: %w", err)
+		}
+		job.FinishedAt = &t
+	}
+	return &job, nil
+}
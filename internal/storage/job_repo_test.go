@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestJobRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	repo := NewJobRepo(db)
+
+	if _, err := repo.Get(ctx, "missing"); err != ErrNotFound {
+		t.Fatalf("Get() on missing job error = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.ClaimNext(ctx); err != ErrNotFound {
+		t.Fatalf("ClaimNext() on empty queue error = %v, want ErrNotFound", err)
+	}
+
+	first := &JobRecord{Type: "reindex", Params: `{"force":true}`}
+	second := &JobRecord{Type: "clear_index"}
+	for _, job := range []*JobRecord{first, second} {
+		if err := repo.Create(ctx, job); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if first.ID == "" || first.ID == second.ID || first.State != JobQueued || first.CreatedAt.IsZero() {
+		t.Fatalf("Create() job = %+v, want a new queued job with a creation time", first)
+	}
+	if second.Params != "{}" {
+		t.Errorf("Create() params = %q, want {} for no params", second.Params)
+	}
+
+	// Jobs are claimed oldest first
+	claimed, err := repo.ClaimNext(ctx)
+	if err != nil {
+		t.Fatalf("ClaimNext() error = %v", err)
+	}
+	if claimed.ID != first.ID || claimed.State != JobRunning || claimed.StartedAt == nil || claimed.Params != `{"force":true}` {
+		t.Fatalf("ClaimNext() = %+v, want the first job running", claimed)
+	}
+
+	if err := repo.UpdateProgress(ctx, first.ID, 0.5); err != nil {
+		t.Fatalf("UpdateProgress() error = %v", err)
+	}
+	active, err := repo.ListActive(ctx)
+	if err != nil {
+		t.Fatalf("ListActive() error = %v", err)
+	}
+	if len(active) != 2 || active[0].ID != first.ID || active[0].Progress != 0.5 || active[1].State != JobQueued {
+		t.Fatalf("ListActive() = %+v, want the running job at 0.5 then the queued one", active)
+	}
+
+	if err := repo.Finish(ctx, first.ID, ""); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	done, err := repo.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if done.State != JobSucceeded || done.Progress != 1 || done.FinishedAt == nil {
+		t.Errorf("Get() after Finish = %+v, want succeeded with progress 1", done)
+	}
+
+	// A restart fails jobs that were running, and leaves queued jobs to run
+	if _, err := repo.ClaimNext(ctx); err != nil {
+		t.Fatalf("ClaimNext() error = %v", err)
+	}
+	failed, err := repo.FailRunning(ctx, "interrupted")
+	if err != nil || failed != 1 {
+		t.Fatalf("FailRunning() = %d, %v, want 1 job failed", failed, err)
+	}
+	interrupted, _ := repo.Get(ctx, second.ID)
+	if interrupted.State != JobFailed || interrupted.Error != "interrupted" || interrupted.FinishedAt == nil {
+		t.Errorf("Get() after FailRunning = %+v, want failed with the error", interrupted)
+	}
+	if active, _ := repo.ListActive(ctx); len(active) != 0 {
+		t.Errorf("ListActive() = %+v, want no active jobs", active)
+	}
+}
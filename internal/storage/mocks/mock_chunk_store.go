@@ -41,6 +41,20 @@ func (m *MockChunkStore) EXPECT() *MockChunkStoreMockRecorder {
 	return m.recorder
 }
 
+// ArchiveByNote mocks base method.
+func (m *MockChunkStore) ArchiveByNote(ctx context.Context, noteID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveByNote", ctx, noteID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ArchiveByNote indicates an expected call of ArchiveByNote.
+func (mr *MockChunkStoreMockRecorder) ArchiveByNote(ctx, noteID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveByNote", reflect.TypeOf((*MockChunkStore)(nil).ArchiveByNote), ctx, noteID)
+}
+
 // DeleteAll mocks base method.
 func (m *MockChunkStore) DeleteAll(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -113,6 +127,51 @@ func (mr *MockChunkStoreMockRecorder) Insert(ctx, chunk any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockChunkStore)(nil).Insert), ctx, chunk)
 }
 
+// ListByHeadingTerms mocks base method.
+func (m *MockChunkStore) ListByHeadingTerms(ctx context.Context, vaultIDs []int, terms []string) ([]storage.ChunkExportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByHeadingTerms", ctx, vaultIDs, terms)
+	ret0, _ := ret[0].([]storage.ChunkExportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByHeadingTerms indicates an expected call of ListByHeadingTerms.
+func (mr *MockChunkStoreMockRecorder) ListByHeadingTerms(ctx, vaultIDs, terms any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByHeadingTerms", reflect.TypeOf((*MockChunkStore)(nil).ListByHeadingTerms), ctx, vaultIDs, terms)
+}
+
+// ListByNote mocks base method.
+func (m *MockChunkStore) ListByNote(ctx context.Context, noteID string) ([]storage.ChunkRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByNote", ctx, noteID)
+	ret0, _ := ret[0].([]storage.ChunkRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByNote indicates an expected call of ListByNote.
+func (mr *MockChunkStoreMockRecorder) ListByNote(ctx, noteID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByNote", reflect.TypeOf((*MockChunkStore)(nil).ListByNote), ctx, noteID)
+}
+
+// ListForExport mocks base method.
+func (m *MockChunkStore) ListForExport(ctx context.Context, filter storage.ChunkExportFilter) ([]storage.ChunkExportRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListForExport", ctx, filter)
+	ret0, _ := ret[0].([]storage.ChunkExportRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListForExport indicates an expected call of ListForExport.
+func (mr *MockChunkStoreMockRecorder) ListForExport(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListForExport", reflect.TypeOf((*MockChunkStore)(nil).ListForExport), ctx, filter)
+}
+
 // ListIDsByNote mocks base method.
 func (m *MockChunkStore) ListIDsByNote(ctx context.Context, noteID string) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -127,3 +186,76 @@ func (mr *MockChunkStoreMockRecorder) ListIDsByNote(ctx, noteID any) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIDsByNote", reflect.TypeOf((*MockChunkStore)(nil).ListIDsByNote), ctx, noteID)
 }
+
+// ListIndexesByNotes mocks base method.
+func (m *MockChunkStore) ListIndexesByNotes(ctx context.Context, noteIDs []string) (map[string][]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIndexesByNotes", ctx, noteIDs)
+	ret0, _ := ret[0].(map[string][]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIndexesByNotes indicates an expected call of ListIndexesByNotes.
+func (mr *MockChunkStoreMockRecorder) ListIndexesByNotes(ctx, noteIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIndexesByNotes", reflect.TypeOf((*MockChunkStore)(nil).ListIndexesByNotes), ctx, noteIDs)
+}
+
+// ListPreviousByNote mocks base method.
+func (m *MockChunkStore) ListPreviousByNote(ctx context.Context, noteID string) ([]storage.ChunkRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPreviousByNote", ctx, noteID)
+	ret0, _ := ret[0].([]storage.ChunkRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPreviousByNote indicates an expected call of ListPreviousByNote.
+func (mr *MockChunkStoreMockRecorder) ListPreviousByNote(ctx, noteID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPreviousByNote", reflect.TypeOf((*MockChunkStore)(nil).ListPreviousByNote), ctx, noteID)
+}
+
+// PruneTexts mocks base method.
+func (m *MockChunkStore) PruneTexts(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneTexts", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneTexts indicates an expected call of PruneTexts.
+func (mr *MockChunkStoreMockRecorder) PruneTexts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneTexts", reflect.TypeOf((*MockChunkStore)(nil).PruneTexts), ctx)
+}
+
+// RenameIDs mocks base method.
+func (m *MockChunkStore) RenameIDs(ctx context.Context, ids map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameIDs", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameIDs indicates an expected call of RenameIDs.
+func (mr *MockChunkStoreMockRecorder) RenameIDs(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameIDs", reflect.TypeOf((*MockChunkStore)(nil).RenameIDs), ctx, ids)
+}
+
+// RestoreText mocks base method.
+func (m *MockChunkStore) RestoreText(ctx context.Context, id, text string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreText", ctx, id, text)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreText indicates an expected call of RestoreText.
+func (mr *MockChunkStoreMockRecorder) RestoreText(ctx, id, text any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreText", reflect.TypeOf((*MockChunkStore)(nil).RestoreText), ctx, id, text)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	storage "helloworld-ai/internal/storage"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,6 +42,35 @@ func (m *MockNoteStore) EXPECT() *MockNoteStoreMockRecorder {
 	return m.recorder
 }
 
+// CountByVault mocks base method.
+func (m *MockNoteStore) CountByVault(ctx context.Context) (map[int]storage.VaultCounts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByVault", ctx)
+	ret0, _ := ret[0].(map[int]storage.VaultCounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByVault indicates an expected call of CountByVault.
+func (mr *MockNoteStoreMockRecorder) CountByVault(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByVault", reflect.TypeOf((*MockNoteStore)(nil).CountByVault), ctx)
+}
+
+// Delete mocks base method.
+func (m *MockNoteStore) Delete(ctx context.Context, noteID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, noteID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockNoteStoreMockRecorder) Delete(ctx, noteID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNoteStore)(nil).Delete), ctx, noteID)
+}
+
 // DeleteAll mocks base method.
 func (m *MockNoteStore) DeleteAll(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -55,6 +85,51 @@ func (mr *MockNoteStoreMockRecorder) DeleteAll(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAll", reflect.TypeOf((*MockNoteStore)(nil).DeleteAll), ctx)
 }
 
+// FolderChunkStats mocks base method.
+func (m *MockNoteStore) FolderChunkStats(ctx context.Context, vaultID int, prefix string, since time.Time) (storage.FolderChunkStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FolderChunkStats", ctx, vaultID, prefix, since)
+	ret0, _ := ret[0].(storage.FolderChunkStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FolderChunkStats indicates an expected call of FolderChunkStats.
+func (mr *MockNoteStoreMockRecorder) FolderChunkStats(ctx, vaultID, prefix, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FolderChunkStats", reflect.TypeOf((*MockNoteStore)(nil).FolderChunkStats), ctx, vaultID, prefix, since)
+}
+
+// GetAllIDs mocks base method.
+func (m *MockNoteStore) GetAllIDs(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllIDs", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllIDs indicates an expected call of GetAllIDs.
+func (mr *MockNoteStoreMockRecorder) GetAllIDs(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllIDs", reflect.TypeOf((*MockNoteStore)(nil).GetAllIDs), ctx)
+}
+
+// GetByID mocks base method.
+func (m *MockNoteStore) GetByID(ctx context.Context, id string) (*storage.NoteRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(*storage.NoteRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockNoteStoreMockRecorder) GetByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockNoteStore)(nil).GetByID), ctx, id)
+}
+
 // GetByVaultAndPath mocks base method.
 func (m *MockNoteStore) GetByVaultAndPath(ctx context.Context, vaultID int, relPath string) (*storage.NoteRecord, error) {
 	m.ctrl.T.Helper()
@@ -70,6 +145,97 @@ func (mr *MockNoteStoreMockRecorder) GetByVaultAndPath(ctx, vaultID, relPath any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByVaultAndPath", reflect.TypeOf((*MockNoteStore)(nil).GetByVaultAndPath), ctx, vaultID, relPath)
 }
 
+// ListByVault mocks base method.
+func (m *MockNoteStore) ListByVault(ctx context.Context, vaultID int) ([]storage.NoteRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByVault", ctx, vaultID)
+	ret0, _ := ret[0].([]storage.NoteRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByVault indicates an expected call of ListByVault.
+func (mr *MockNoteStoreMockRecorder) ListByVault(ctx, vaultID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByVault", reflect.TypeOf((*MockNoteStore)(nil).ListByVault), ctx, vaultID)
+}
+
+// ListColdCandidates mocks base method.
+func (m *MockNoteStore) ListColdCandidates(ctx context.Context, cutoff time.Time) ([]storage.NoteRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListColdCandidates", ctx, cutoff)
+	ret0, _ := ret[0].([]storage.NoteRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListColdCandidates indicates an expected call of ListColdCandidates.
+func (mr *MockNoteStoreMockRecorder) ListColdCandidates(ctx, cutoff any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListColdCandidates", reflect.TypeOf((*MockNoteStore)(nil).ListColdCandidates), ctx, cutoff)
+}
+
+// ListFolderStats mocks base method.
+func (m *MockNoteStore) ListFolderStats(ctx context.Context, vaultIDs []int, opts storage.FolderListOptions) ([]storage.FolderStat, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFolderStats", ctx, vaultIDs, opts)
+	ret0, _ := ret[0].([]storage.FolderStat)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListFolderStats indicates an expected call of ListFolderStats.
+func (mr *MockNoteStoreMockRecorder) ListFolderStats(ctx, vaultIDs, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFolderStats", reflect.TypeOf((*MockNoteStore)(nil).ListFolderStats), ctx, vaultIDs, opts)
+}
+
+// ListIDsByFilter mocks base method.
+func (m *MockNoteStore) ListIDsByFilter(ctx context.Context, vaultIDs []int, filter storage.NoteFilter) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIDsByFilter", ctx, vaultIDs, filter)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIDsByFilter indicates an expected call of ListIDsByFilter.
+func (mr *MockNoteStoreMockRecorder) ListIDsByFilter(ctx, vaultIDs, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIDsByFilter", reflect.TypeOf((*MockNoteStore)(nil).ListIDsByFilter), ctx, vaultIDs, filter)
+}
+
+// ListModifiedBetween mocks base method.
+func (m *MockNoteStore) ListModifiedBetween(ctx context.Context, vaultIDs []int, from, to time.Time, limit int) ([]storage.ModifiedNote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModifiedBetween", ctx, vaultIDs, from, to, limit)
+	ret0, _ := ret[0].([]storage.ModifiedNote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListModifiedBetween indicates an expected call of ListModifiedBetween.
+func (mr *MockNoteStoreMockRecorder) ListModifiedBetween(ctx, vaultIDs, from, to, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModifiedBetween", reflect.TypeOf((*MockNoteStore)(nil).ListModifiedBetween), ctx, vaultIDs, from, to, limit)
+}
+
+// ListMostRetrieved mocks base method.
+func (m *MockNoteStore) ListMostRetrieved(ctx context.Context, from, to time.Time, limit int) ([]storage.NoteRetrievalCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListMostRetrieved", ctx, from, to, limit)
+	ret0, _ := ret[0].([]storage.NoteRetrievalCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListMostRetrieved indicates an expected call of ListMostRetrieved.
+func (mr *MockNoteStoreMockRecorder) ListMostRetrieved(ctx, from, to, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMostRetrieved", reflect.TypeOf((*MockNoteStore)(nil).ListMostRetrieved), ctx, from, to, limit)
+}
+
 // ListUniqueFolders mocks base method.
 func (m *MockNoteStore) ListUniqueFolders(ctx context.Context, vaultIDs []int) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +251,120 @@ func (mr *MockNoteStoreMockRecorder) ListUniqueFolders(ctx, vaultIDs any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUniqueFolders", reflect.TypeOf((*MockNoteStore)(nil).ListUniqueFolders), ctx, vaultIDs)
 }
 
+// ListUpdatedBetween mocks base method.
+func (m *MockNoteStore) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]storage.NoteRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUpdatedBetween", ctx, from, to)
+	ret0, _ := ret[0].([]storage.NoteRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUpdatedBetween indicates an expected call of ListUpdatedBetween.
+func (mr *MockNoteStoreMockRecorder) ListUpdatedBetween(ctx, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUpdatedBetween", reflect.TypeOf((*MockNoteStore)(nil).ListUpdatedBetween), ctx, from, to)
+}
+
+// MarkRetrieved mocks base method.
+func (m *MockNoteStore) MarkRetrieved(ctx context.Context, noteIDs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRetrieved", ctx, noteIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkRetrieved indicates an expected call of MarkRetrieved.
+func (mr *MockNoteStoreMockRecorder) MarkRetrieved(ctx, noteIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRetrieved", reflect.TypeOf((*MockNoteStore)(nil).MarkRetrieved), ctx, noteIDs)
+}
+
+// ModifiedTimes mocks base method.
+func (m *MockNoteStore) ModifiedTimes(ctx context.Context, noteIDs []string) (map[string]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifiedTimes", ctx, noteIDs)
+	ret0, _ := ret[0].(map[string]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModifiedTimes indicates an expected call of ModifiedTimes.
+func (mr *MockNoteStoreMockRecorder) ModifiedTimes(ctx, noteIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifiedTimes", reflect.TypeOf((*MockNoteStore)(nil).ModifiedTimes), ctx, noteIDs)
+}
+
+// Move mocks base method.
+func (m *MockNoteStore) Move(ctx context.Context, noteID, relPath, folder string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", ctx, noteID, relPath, folder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockNoteStoreMockRecorder) Move(ctx, noteID, relPath, folder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockNoteStore)(nil).Move), ctx, noteID, relPath, folder)
+}
+
+// SetFileModified mocks base method.
+func (m *MockNoteStore) SetFileModified(ctx context.Context, noteID string, modified time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFileModified", ctx, noteID, modified)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFileModified indicates an expected call of SetFileModified.
+func (mr *MockNoteStoreMockRecorder) SetFileModified(ctx, noteID, modified any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFileModified", reflect.TypeOf((*MockNoteStore)(nil).SetFileModified), ctx, noteID, modified)
+}
+
+// SetMetadata mocks base method.
+func (m *MockNoteStore) SetMetadata(ctx context.Context, noteID string, meta storage.NoteMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMetadata", ctx, noteID, meta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMetadata indicates an expected call of SetMetadata.
+func (mr *MockNoteStoreMockRecorder) SetMetadata(ctx, noteID, meta any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMetadata", reflect.TypeOf((*MockNoteStore)(nil).SetMetadata), ctx, noteID, meta)
+}
+
+// SetTags mocks base method.
+func (m *MockNoteStore) SetTags(ctx context.Context, noteID string, tags []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTags", ctx, noteID, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTags indicates an expected call of SetTags.
+func (mr *MockNoteStoreMockRecorder) SetTags(ctx, noteID, tags any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTags", reflect.TypeOf((*MockNoteStore)(nil).SetTags), ctx, noteID, tags)
+}
+
+// SetTier mocks base method.
+func (m *MockNoteStore) SetTier(ctx context.Context, noteID, tier string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTier", ctx, noteID, tier)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTier indicates an expected call of SetTier.
+func (mr *MockNoteStoreMockRecorder) SetTier(ctx, noteID, tier any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTier", reflect.TypeOf((*MockNoteStore)(nil).SetTier), ctx, noteID, tier)
+}
+
 // Upsert mocks base method.
 func (m *MockNoteStore) Upsert(ctx context.Context, note *storage.NoteRecord) error {
 	m.ctrl.T.Helper()
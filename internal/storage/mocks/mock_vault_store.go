@@ -41,6 +41,21 @@ func (m *MockVaultStore) EXPECT() *MockVaultStoreMockRecorder {
 	return m.recorder
 }
 
+// GetByName mocks base method.
+func (m *MockVaultStore) GetByName(ctx context.Context, name string) (storage.VaultRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByName", ctx, name)
+	ret0, _ := ret[0].(storage.VaultRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByName indicates an expected call of GetByName.
+func (mr *MockVaultStoreMockRecorder) GetByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockVaultStore)(nil).GetByName), ctx, name)
+}
+
 // GetOrCreateByName mocks base method.
 func (m *MockVaultStore) GetOrCreateByName(ctx context.Context, name, rootPath string) (storage.VaultRecord, error) {
 	m.ctrl.T.Helper()
@@ -2,7 +2,7 @@ package storage
 
 import "time"
 
-// VaultRecord represents a vault (personal or work) in the database.
+// VaultRecord represents a configured vault in the database.
 type VaultRecord struct {
 	ID        int       `db:"id"`
 	Name      string    `db:"name"`
@@ -19,6 +19,93 @@ type NoteRecord struct {
 	Title     string    `db:"title"`    // Extracted title from markdown
 	UpdatedAt time.Time `db:"updated_at"`
 	Hash      string    `db:"hash"` // SHA256 hex string of file content
+	Tier      string    `db:"tier"` // Storage tier: TierHot or TierCold
+	// FileModified is the file's modification time when it was indexed (zero if unknown).
+	// Written by Upsert; the other queries leave it zero.
+	FileModified time.Time `db:"file_modified"`
+}
+
+const (
+	// TierHot marks notes whose chunks live in the primary collection.
+	TierHot = "hot"
+	// TierCold marks notes whose chunks were moved to the cold collection.
+	TierCold = "cold"
+)
+
+// ModifiedNote is a note and when it was last changed: its frontmatter modified date when
+// set, otherwise its file modification time, otherwise the time its change was indexed.
+type ModifiedNote struct {
+	Note       NoteRecord
+	ModifiedAt time.Time
+}
+
+// NoteRetrievalCount is a note and how many answers it contributed chunks to in a period.
+type NoteRetrievalCount struct {
+	Note  NoteRecord
+	Count int
+}
+
+// FolderStat describes a folder and how many notes it (and its subfolders) contains.
+type FolderStat struct {
+	Path      string // Format "<vaultID>/folder" (root is "<vaultID>/")
+	VaultID   int
+	Depth     int // Number of path components (0 for the vault root)
+	NoteCount int // Notes in this folder and all of its subfolders
+	// Retrievals counts the logged retrievals of those notes (see MarkRetrieved)
+	Retrievals int
+}
+
+// VaultCounts is the indexed content of a vault.
+type VaultCounts struct {
+	NoteCount  int
+	ChunkCount int
+}
+
+// FolderListOptions controls folder listing depth and pagination.
+type FolderListOptions struct {
+	// MaxDepth collapses deeper folders into their ancestor at this depth (0 = unlimited).
+	MaxDepth int
+	// Offset skips this many folders (ordered by vault ID and path).
+	Offset int
+	// Limit caps the number of folders returned (0 = unlimited).
+	Limit int
+}
+
+// FolderChunkStats summarizes the indexed notes and chunks under a folder prefix.
+type FolderChunkStats struct {
+	NoteCount     int
+	ChunkCount    int
+	TextChars     int        // Total characters of chunk text
+	LastIndexedAt *time.Time // Latest note index time (nil when the folder has no notes)
+	Retrievals    int        // Answers the folder's notes contributed to since the cutoff
+}
+
+// NoteMetadata is the metadata of a note read from its YAML frontmatter.
+type NoteMetadata struct {
+	// Aliases are the alternative names of the note (`aliases`).
+	Aliases []string
+	// Created is when the note was created (`created` or `date`; zero if not set).
+	Created time.Time
+	// Modified is when the note was last changed (`modified` or `updated`; zero if not set).
+	Modified time.Time
+}
+
+// NoteFilter selects notes by tag and change time. Zero fields do not filter.
+type NoteFilter struct {
+	// Tags must all be present on a note; a tag also matches its nested tags ("project" matches "project/alpha").
+	Tags []string
+	// UpdatedBefore keeps notes last changed before this time. A note's frontmatter
+	// modified date takes precedence over its file modification time, and both over the
+	// time its change was indexed.
+	UpdatedBefore time.Time
+	// UpdatedAfter keeps notes last changed at or after this time (see UpdatedBefore).
+	UpdatedAfter time.Time
+	// CreatedBefore keeps notes whose frontmatter created date is before this time.
+	// Notes without a created date are excluded.
+	CreatedBefore time.Time
+	// CreatedAfter keeps notes whose frontmatter created date is at or after this time.
+	// Notes without a created date are excluded.
+	CreatedAfter time.Time
 }
 
 // ChunkRecord represents a chunk of text from a note, indexed for vector search.
@@ -27,7 +114,144 @@ type ChunkRecord struct {
 	NoteID      string `db:"note_id"`      // UUID (foreign key to notes.id)
 	ChunkIndex  int    `db:"chunk_index"`  // Index within note (starts at 0)
 	HeadingPath string `db:"heading_path"` // Format: "# Heading1 > ## Heading2"
-	Text        string `db:"text"`         // Chunk text content
+	Text        string `db:"text"`         // Chunk text content (stored once per distinct text in chunk_texts)
+	TextHash    string `db:"text_hash"`    // SHA256 hex string of Text, key into chunk_texts
+	// OverlapRunes is the length of the leading text repeated from the previous chunk (0: none)
+	OverlapRunes int `db:"overlap_runes"`
+
+	// Provenance: which indexing run produced the chunk (empty for chunks indexed before it was recorded)
+	ChunkerVersion string    `db:"chunker_version"` // Chunker version that split the note
+	EmbeddingModel string    `db:"embedding_model"` // Model that embedded the chunk
+	EmbeddedAt     time.Time `db:"embedded_at"`     // When the chunk was embedded (UTC)
+	RunID          string    `db:"run_id"`          // Indexing run that wrote the chunk
+}
+
+// ChunkExportRecord is a chunk joined with the note and vault it belongs to, as exported to a corpus.
+type ChunkExportRecord struct {
+	ChunkRecord
+	VaultID   int
+	VaultName string
+	RelPath   string
+	Folder    string
+	NoteTitle string
+	Tier      string
+}
+
+// ChunkExportFilter narrows a chunk export to a vault and/or folder.
+type ChunkExportFilter struct {
+	// VaultName limits the export to one vault (empty = all vaults).
+	VaultName string
+	// Folder limits the export to a folder and its subfolders (empty = all folders).
+	Folder string
+}
+
+// Index event types recorded in the index_events table.
+const (
+	// IndexEventNoteAdded is recorded when a note is indexed for the first time.
+	IndexEventNoteAdded = "note_added"
+	// IndexEventNoteUpdated is recorded when a changed note is re-indexed.
+	IndexEventNoteUpdated = "note_updated"
+	// IndexEventNoteMoved is recorded when a note keeps its content under a new path.
+	IndexEventNoteMoved = "note_moved"
+	// IndexEventNoteDeleted is recorded when a note is removed from the index.
+	IndexEventNoteDeleted = "note_deleted"
+	// IndexEventIndexCleared is recorded when every note is removed at once (force reindex).
+	IndexEventIndexCleared = "index_cleared"
+	// IndexEventReindexCompleted is recorded when an index pass or rebuild finishes.
+	IndexEventReindexCompleted = "reindex_completed"
+)
+
+// IndexEventRecord is one change to the index. Seq increases with every event, so clients
+// can ask for the changes since the last sequence number they saw.
+type IndexEventRecord struct {
+	Seq             int64     `db:"seq"`
+	Type            string    `db:"type"`
+	VaultID         int       `db:"vault_id"` // 0 for events covering all vaults
+	NoteID          string    `db:"note_id"`
+	RelPath         string    `db:"rel_path"`
+	PreviousRelPath string    `db:"previous_rel_path"` // Only set for IndexEventNoteMoved
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// Index failure reasons, recorded for chunks that were left out of the index.
+const (
+	// IndexFailureContextSize is recorded for a chunk too large for the embedding model's
+	// context, which is skipped while the rest of the note is indexed.
+	IndexFailureContextSize = "context_size"
+	// IndexFailureEmbedding is recorded for the chunks of a batch whose embedding request
+	// failed, which fails the note.
+	IndexFailureEmbedding = "embedding_error"
+)
+
+// IndexFailureRecord is a chunk that was skipped or failed during the last indexing run of
+// its note. VaultID and RelPath are read from the note, so they follow note moves.
+type IndexFailureRecord struct {
+	ID         int64     `db:"id"`
+	NoteID     string    `db:"note_id"`
+	VaultID    int       `db:"vault_id"`
+	RelPath    string    `db:"rel_path"`
+	ChunkIndex int       `db:"chunk_index"`
+	Reason     string    `db:"reason"` // IndexFailureContextSize or IndexFailureEmbedding
+	Error      string    `db:"error"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// AnswerCacheRecord is a cached answer. IndexVersion is the latest index event sequence
+// number when the answer was generated, so entries from before an index change can be
+// recognized as stale.
+type AnswerCacheRecord struct {
+	Key          string    `db:"cache_key"` // Hash of the normalized question and request options
+	IndexVersion int64     `db:"index_version"`
+	Response     string    `db:"response"` // JSON-encoded answer
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// IndexTimingRecord holds the per-phase durations of the most recent indexing run for a file.
+type IndexTimingRecord struct {
+	VaultID    int       `db:"vault_id"`
+	RelPath    string    `db:"rel_path"`
+	ReadMs     int64     `db:"read_ms"`     // Reading the file and checking its hash
+	ChunkMs    int64     `db:"chunk_ms"`    // Markdown chunking
+	EmbedMs    int64     `db:"embed_ms"`    // Embedding generation
+	UpsertMs   int64     `db:"upsert_ms"`   // SQLite and Qdrant writes
+	TotalMs    int64     `db:"total_ms"`    // Wall time for the whole file
+	SizeBytes  int64     `db:"size_bytes"`  // File size in bytes
+	ChunkCount int       `db:"chunk_count"` // Number of chunks produced
+	IndexedAt  time.Time `db:"indexed_at"`
+}
+
+// Bottleneck returns the name of the phase that took the longest ("read", "chunk", "embed", or "upsert").
+func (t IndexTimingRecord) Bottleneck() string {
+	phase, longest := "read", t.ReadMs
+	if t.ChunkMs > longest {
+		phase, longest = "chunk", t.ChunkMs
+	}
+	if t.EmbedMs > longest {
+		phase, longest = "embed", t.EmbedMs
+	}
+	if t.UpsertMs > longest {
+		phase = "upsert"
+	}
+	return phase
+}
+
+// ChunkClickStats counts how often a chunk was cited as a reference and how often users
+// opened that reference.
+type ChunkClickStats struct {
+	ChunkID       string     `db:"chunk_id"`
+	Shown         int        `db:"shown"`           // Answers that returned the chunk as a reference
+	Clicks        int        `db:"clicks"`          // Times the reference was opened
+	LastClickedAt *time.Time `db:"last_clicked_at"` // Nil if never clicked
+}
+
+// ClickThrough returns clicks per time shown (0 if never shown). Clicks on references
+// shown before tracking started can push it above 1.
+func (s ChunkClickStats) ClickThrough() float64 {
+	if s.Shown == 0 {
+		return 0
+	}
+	return float64(s.Clicks) / float64(s.Shown)
 }
 
 // Legacy type aliases for backward compatibility during migration
@@ -35,3 +259,53 @@ type ChunkRecord struct {
 type Vault = VaultRecord
 type Note = NoteRecord
 type Chunk = ChunkRecord
+
+// IndexChecksumRecord is a checksum over a vault's note hashes and chunk IDs, used to detect
+// index changes made outside the indexer.
+type IndexChecksumRecord struct {
+	VaultID    int       `db:"vault_id"`
+	Checksum   string    `db:"checksum"`    // SHA256 hex over notes (rel_path, hash) and their chunk IDs in order
+	NoteCount  int       `db:"note_count"`  // Notes covered by the checksum
+	ChunkCount int       `db:"chunk_count"` // Chunks covered by the checksum
+	ComputedAt time.Time `db:"computed_at"`
+}
+
+// Job states recorded in the jobs table.
+const (
+	// JobQueued is a job waiting for the worker.
+	JobQueued = "queued"
+	// JobRunning is a job the worker has claimed.
+	JobRunning = "running"
+	// JobSucceeded is a job that finished without error.
+	JobSucceeded = "succeeded"
+	// JobFailed is a job that returned an error or was interrupted by a restart.
+	JobFailed = "failed"
+)
+
+// JobRecord is a long-running operation queued for the background worker.
+type JobRecord struct {
+	ID         string     `db:"id"`
+	Type       string     `db:"type"`
+	State      string     `db:"state"`
+	Params     string     `db:"params"`   // JSON-encoded parameters for the job type
+	Progress   float64    `db:"progress"` // Fraction done, 0 to 1
+	Error      string     `db:"error"`    // Set when the job failed
+	CreatedAt  time.Time  `db:"created_at"`
+	StartedAt  *time.Time `db:"started_at"`  // Nil while queued
+	FinishedAt *time.Time `db:"finished_at"` // Nil until the job succeeds or fails
+}
+
+// UserRecord is an API user and the vaults they may read.
+type UserRecord struct {
+	ID        int       `db:"id"`
+	Name      string    `db:"name"`
+	VaultIDs  []int     // From user_vaults, ascending
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ExcludedFolderRecord is a folder prefix of a vault that index runs skip.
+type ExcludedFolderRecord struct {
+	VaultID   int       `db:"vault_id"`
+	Prefix    string    `db:"prefix"` // Relative to the vault root, covering its subfolders
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -5,8 +5,10 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,14 +25,54 @@ type NoteStore interface {
 	// GetByVaultAndPath gets a note by vault ID and relative path.
 	// Returns nil and ErrNotFound if not found.
 	GetByVaultAndPath(ctx context.Context, vaultID int, relPath string) (*NoteRecord, error)
+	// GetByID gets a note by its ID. Returns nil and ErrNotFound if not found.
+	GetByID(ctx context.Context, id string) (*NoteRecord, error)
 	// Upsert inserts a new note or updates an existing one.
 	Upsert(ctx context.Context, note *NoteRecord) error
 	// DeleteAll deletes all notes from the database.
 	DeleteAll(ctx context.Context) error
+	// GetAllIDs returns all note IDs.
+	GetAllIDs(ctx context.Context) ([]string, error)
 	// ListUniqueFolders returns all unique folder paths, optionally filtered by vault IDs.
 	// If vaultIDs is empty, returns folders from all vaults.
 	// Returns strings in format "<vaultID>/folder" including all nested folders with full path.
 	ListUniqueFolders(ctx context.Context, vaultIDs []int) ([]string, error)
+	// CountByVault returns the indexed note and chunk counts of every vault with notes, by vault ID.
+	CountByVault(ctx context.Context) (map[int]VaultCounts, error)
+	// ListFolderStats returns folders (collapsed to opts.MaxDepth) with subtree note counts,
+	// optionally filtered by vault IDs and paginated. Also returns the total number of folders.
+	ListFolderStats(ctx context.Context, vaultIDs []int, opts FolderListOptions) ([]FolderStat, int, error)
+	// MarkRetrieved records that the given notes contributed chunks to an answer.
+	MarkRetrieved(ctx context.Context, noteIDs []string) error
+	// ListColdCandidates returns hot notes not updated or retrieved since cutoff.
+	ListColdCandidates(ctx context.Context, cutoff time.Time) ([]NoteRecord, error)
+	// SetTier updates the storage tier of a note.
+	SetTier(ctx context.Context, noteID, tier string) error
+	// SetFileModified records the file modification time of a note without marking it changed.
+	SetFileModified(ctx context.Context, noteID string, modified time.Time) error
+	// ListByVault returns all notes of a vault, ordered by path.
+	ListByVault(ctx context.Context, vaultID int) ([]NoteRecord, error)
+	// Move changes a note's path and folder, keeping its ID, hash, and chunks.
+	Move(ctx context.Context, noteID, relPath, folder string) error
+	// Delete removes a note with its chunks and retrieval log entries.
+	Delete(ctx context.Context, noteID string) error
+	// ListUpdatedBetween returns notes created or changed in [from, to), ordered by vault and path.
+	ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]NoteRecord, error)
+	// ListModifiedBetween returns up to limit notes in the given vaults (all vaults if empty)
+	// last changed in [from, to), most recently changed first.
+	ListModifiedBetween(ctx context.Context, vaultIDs []int, from, to time.Time, limit int) ([]ModifiedNote, error)
+	// ModifiedTimes returns when each of the given notes was last changed, keyed by note ID.
+	ModifiedTimes(ctx context.Context, noteIDs []string) (map[string]time.Time, error)
+	// ListMostRetrieved returns the notes that contributed to the most answers in [from, to).
+	ListMostRetrieved(ctx context.Context, from, to time.Time, limit int) ([]NoteRetrievalCount, error)
+	// FolderChunkStats summarizes the notes, chunks, and retrievals since a cutoff under a folder prefix.
+	FolderChunkStats(ctx context.Context, vaultID int, prefix string, since time.Time) (FolderChunkStats, error)
+	// SetTags replaces the tags of a note.
+	SetTags(ctx context.Context, noteID string, tags []string) error
+	// SetMetadata replaces the frontmatter metadata (aliases, created and modified dates) of a note.
+	SetMetadata(ctx context.Context, noteID string, meta NoteMetadata) error
+	// ListIDsByFilter returns the IDs of notes in the given vaults (all vaults if empty) matching filter.
+	ListIDsByFilter(ctx context.Context, vaultIDs []int, filter NoteFilter) ([]string, error)
 }
 
 // NoteRepo provides methods for note operations.
@@ -53,13 +95,24 @@ func (r *NoteRepo) DB() *sql.DB {
 // GetByVaultAndPath gets a note by vault ID and relative path.
 // Returns nil and ErrNotFound if not found.
 func (r *NoteRepo) GetByVaultAndPath(ctx context.Context, vaultID int, relPath string) (*NoteRecord, error) {
+	return r.getNote(ctx, "vault_id = ? AND rel_path = ?", vaultID, relPath)
+}
+
+// GetByID gets a note by its ID.
+// Returns nil and ErrNotFound if not found.
+func (r *NoteRepo) GetByID(ctx context.Context, id string) (*NoteRecord, error) {
+	return r.getNote(ctx, "id = ?", id)
+}
+
+// getNote gets the note matching the where clause.
+func (r *NoteRepo) getNote(ctx context.Context, where string, args ...any) (*NoteRecord, error) {
 	var note NoteRecord
 	var updatedAtStr string
 
 	err := r.db.QueryRowContext(ctx,
-		"SELECT id, vault_id, rel_path, folder, title, updated_at, hash FROM notes WHERE vault_id = ? AND rel_path = ?",
-		vaultID, relPath,
-	).Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash)
+		"SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier FROM notes WHERE "+where,
+		args...,
+	).Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -69,21 +122,32 @@ func (r *NoteRepo) GetByVaultAndPath(ctx context.Context, vaultID int, relPath s
 	}
 
 	// Parse updated_at DATETIME string
-	note.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+	note.UpdatedAt, err = parseTimestamp(updatedAtStr)
 	if err != nil {
-		// Try alternative format (SQLite might use different format)
-		note.UpdatedAt, err = time.Parse(time.RFC3339, updatedAtStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
-		}
+		return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
 	}
 
 	return &note, nil
 }
 
+// noteModifiedAt is the time a note was last changed: its frontmatter modified date when
+// set, otherwise its file modification time, otherwise the time its change was indexed.
+const noteModifiedAt = "COALESCE(frontmatter_modified, file_modified, updated_at)"
+
+// parseTimestamp parses a SQLite DATETIME string.
+func parseTimestamp(value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05", value)
+	if err != nil {
+		// Try alternative format (SQLite might use different format)
+		return time.Parse(time.RFC3339, value)
+	}
+	return t, nil
+}
+
 // Upsert inserts a new note or updates an existing one.
 // If the note doesn't exist (by vault_id and rel_path), generates a new UUID.
-// If it exists, updates title, updated_at, and hash while preserving the ID.
+// If it exists, updates title, updated_at, hash, and file_modified while preserving the ID.
+// A changed note is considered touched, so it is returned to the hot tier.
 func (r *NoteRepo) Upsert(ctx context.Context, note *NoteRecord) error {
 	// Check if note exists to determine if we need to generate UUID
 	existing, err := r.GetByVaultAndPath(ctx, note.VaultID, note.RelPath)
@@ -99,13 +163,18 @@ func (r *NoteRepo) Upsert(ctx context.Context, note *NoteRecord) error {
 		note.ID = existing.ID
 	}
 
+	var fileModified any
+	if !note.FileModified.IsZero() {
+		fileModified = note.FileModified.UTC().Format("2006-01-02 15:04:05")
+	}
+
 	// Use SQLite INSERT ... ON CONFLICT syntax for upsert
 	_, err = r.db.ExecContext(ctx,
-		`INSERT INTO notes (id, vault_id, rel_path, folder, title, updated_at, hash) 
-		 VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)
+		`INSERT INTO notes (id, vault_id, rel_path, folder, title, updated_at, hash, file_modified) 
+		 VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?)
 		 ON CONFLICT (vault_id, rel_path) DO UPDATE SET 
-		 title = excluded.title, updated_at = CURRENT_TIMESTAMP, hash = excluded.hash`,
-		note.ID, note.VaultID, note.RelPath, note.Folder, note.Title, note.Hash,
+		 title = excluded.title, updated_at = CURRENT_TIMESTAMP, hash = excluded.hash, file_modified = excluded.file_modified, tier = 'hot'`,
+		note.ID, note.VaultID, note.RelPath, note.Folder, note.Title, note.Hash, fileModified,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to upsert note: %w", err)
@@ -120,9 +189,70 @@ func (r *NoteRepo) DeleteAll(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete all notes: %w", err)
 	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM note_retrievals"); err != nil {
+		return fmt.Errorf("failed to delete note retrievals: %w", err)
+	}
 	return nil
 }
 
+// Delete removes a note with its chunks (by cascade) and retrieval log entries.
+// Returns ErrNotFound if the note does not exist.
+func (r *NoteRepo) Delete(ctx context.Context, noteID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM notes WHERE id = ?", noteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_retrievals WHERE note_id = ?", noteID); err != nil {
+		return fmt.Errorf("failed to delete note retrievals: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetAllIDs returns all note IDs.
+func (r *NoteRepo) GetAllIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id FROM notes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note IDs: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan note ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return ids, nil
+}
+
 // ListUniqueFolders returns all unique folder paths, optionally filtered by vault IDs.
 // If vaultIDs is empty, returns folders from all vaults.
 // Returns strings in format "<vaultID>/folder" including all nested folders with full path.
@@ -194,3 +324,664 @@ func (r *NoteRepo) ListUniqueFolders(ctx context.Context, vaultIDs []int) ([]str
 
 	return folders, nil
 }
+
+// CountByVault returns the indexed note and chunk counts of every vault with notes, by
+// vault ID. Vaults without notes are absent from the map.
+func (r *NoteRepo) CountByVault(ctx context.Context) (map[int]VaultCounts, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT n.vault_id, COUNT(*), COALESCE(SUM(c.chunks), 0)
+		 FROM notes n
+		 LEFT JOIN (SELECT note_id, COUNT(*) AS chunks FROM chunks GROUP BY note_id) c ON c.note_id = n.id
+		 GROUP BY n.vault_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notes by vault: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[int]VaultCounts)
+	for rows.Next() {
+		var vaultID int
+		var count VaultCounts
+		if err := rows.Scan(&vaultID, &count.NoteCount, &count.ChunkCount); err != nil {
+			return nil, fmt.Errorf("failed to scan vault counts: %w", err)
+		}
+		counts[vaultID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return counts, nil
+}
+
+// folderStatsQuery counts notes and their logged retrievals per folder; %s is an optional
+// WHERE clause on notes n.
+const folderStatsQuery = `SELECT n.vault_id, n.folder, COUNT(*), COALESCE(SUM(r.retrievals), 0)
+	FROM notes n
+	LEFT JOIN (SELECT note_id, COUNT(*) AS retrievals FROM note_retrievals GROUP BY note_id) r ON r.note_id = n.id
+	%s
+	GROUP BY n.vault_id, n.folder`
+
+// ListFolderStats returns folders with subtree note and retrieval counts, optionally filtered
+// by vault IDs. Folders deeper than opts.MaxDepth are collapsed into their ancestor at that
+// depth, so vaults with thousands of nested folders produce a bounded list. Results are
+// ordered by path and paginated with opts.Offset and opts.Limit; the total count ignores
+// pagination.
+func (r *NoteRepo) ListFolderStats(ctx context.Context, vaultIDs []int, opts FolderListOptions) ([]FolderStat, int, error) {
+	var query string
+	var args []interface{}
+
+	if len(vaultIDs) > 0 {
+		placeholders := make([]string, len(vaultIDs))
+		for i, vaultID := range vaultIDs {
+			placeholders[i] = "?"
+			args = append(args, vaultID)
+		}
+		query = fmt.Sprintf(folderStatsQuery, "WHERE n.vault_id IN ("+strings.Join(placeholders, ",")+")")
+	} else {
+		query = fmt.Sprintf(folderStatsQuery, "")
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query folder stats: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	statsByPath := make(map[string]*FolderStat)
+	addNotes := func(vaultID int, folder string, depth, count, retrievals int) {
+		path := fmt.Sprintf("%d/%s", vaultID, folder)
+		stat, ok := statsByPath[path]
+		if !ok {
+			stat = &FolderStat{Path: path, VaultID: vaultID, Depth: depth}
+			statsByPath[path] = stat
+		}
+		stat.NoteCount += count
+		stat.Retrievals += retrievals
+	}
+
+	for rows.Next() {
+		var vaultID, count, retrievals int
+		var folder string
+		if err := rows.Scan(&vaultID, &folder, &count, &retrievals); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan folder stats: %w", err)
+		}
+
+		if folder == "" {
+			addNotes(vaultID, "", 0, count, retrievals)
+			continue
+		}
+
+		// Credit the notes to the folder and every ancestor, stopping at MaxDepth
+		parts := strings.Split(folder, "/")
+		if opts.MaxDepth > 0 && len(parts) > opts.MaxDepth {
+			parts = parts[:opts.MaxDepth]
+		}
+		for depth := 1; depth <= len(parts); depth++ {
+			addNotes(vaultID, strings.Join(parts[:depth], "/"), depth, count, retrievals)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	stats := make([]FolderStat, 0, len(statsByPath))
+	for _, stat := range statsByPath {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].VaultID != stats[j].VaultID {
+			return stats[i].VaultID < stats[j].VaultID
+		}
+		return stats[i].Path < stats[j].Path
+	})
+
+	total := len(stats)
+	if opts.Offset > 0 {
+		if opts.Offset >= total {
+			return []FolderStat{}, total, nil
+		}
+		stats = stats[opts.Offset:]
+	}
+	if opts.Limit > 0 && len(stats) > opts.Limit {
+		stats = stats[:opts.Limit]
+	}
+
+	return stats, total, nil
+}
+
+// MarkRetrieved records that the given notes contributed chunks to an answer.
+// Retrieval keeps notes in the hot tier when the cold storage policy runs, and each
+// retrieval is logged (note ID and time only) for the most-asked topics of the digest.
+func (r *NoteRepo) MarkRetrieved(ctx context.Context, noteIDs []string) error {
+	if len(noteIDs) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(noteIDs))
+	values := make([]string, len(noteIDs))
+	args := make([]interface{}, len(noteIDs))
+	for i, id := range noteIDs {
+		placeholders[i] = "?"
+		values[i] = "(?)"
+		args[i] = id
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := fmt.Sprintf("UPDATE notes SET last_retrieved_at = CURRENT_TIMESTAMP WHERE id IN (%s)", strings.Join(placeholders, ","))
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark notes retrieved: %w", err)
+	}
+	query = fmt.Sprintf("INSERT INTO note_retrievals (note_id) VALUES %s", strings.Join(values, ","))
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to log note retrievals: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit retrievals: %w", err)
+	}
+	return nil
+}
+
+// ListColdCandidates returns hot notes whose content has not changed since cutoff
+// and that have not been retrieved since cutoff (or were never retrieved).
+func (r *NoteRepo) ListColdCandidates(ctx context.Context, cutoff time.Time) ([]NoteRecord, error) {
+	cutoffStr := cutoff.UTC().Format("2006-01-02 15:04:05")
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier FROM notes
+		 WHERE tier = ? AND updated_at < ? AND (last_retrieved_at IS NULL OR last_retrieved_at < ?)
+		 ORDER BY vault_id, rel_path`,
+		TierHot, cutoffStr, cutoffStr,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cold candidates: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var notes []NoteRecord
+	for rows.Next() {
+		var note NoteRecord
+		var updatedAtStr string
+		if err := rows.Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		note.UpdatedAt, err = parseTimestamp(updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
+
+// SetFileModified records the file modification time of a note.
+// Unlike Upsert it leaves updated_at and the tier alone, so an unchanged note stays unchanged.
+func (r *NoteRepo) SetFileModified(ctx context.Context, noteID string, modified time.Time) error {
+	fileModified := modified.UTC().Format("2006-01-02 15:04:05")
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE notes SET file_modified = ? WHERE id = ? AND file_modified IS NOT ?",
+		fileModified, noteID, fileModified,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set note file modification time: %w", err)
+	}
+	return nil
+}
+
+// SetTier updates the storage tier of a note.
+func (r *NoteRepo) SetTier(ctx context.Context, noteID, tier string) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE notes SET tier = ? WHERE id = ?", tier, noteID)
+	if err != nil {
+		return fmt.Errorf("failed to set note tier: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListByVault returns all notes of a vault, ordered by path.
+func (r *NoteRepo) ListByVault(ctx context.Context, vaultID int) ([]NoteRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier FROM notes
+		 WHERE vault_id = ? ORDER BY rel_path`,
+		vaultID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var notes []NoteRecord
+	for rows.Next() {
+		var note NoteRecord
+		var updatedAtStr string
+		if err := rows.Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		note.UpdatedAt, err = parseTimestamp(updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
+
+// Move changes a note's path and folder, keeping its ID, hash, and chunks.
+// updated_at is left alone because the content did not change.
+func (r *NoteRepo) Move(ctx context.Context, noteID, relPath, folder string) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE notes SET rel_path = ?, folder = ? WHERE id = ?",
+		relPath, folder, noteID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move note: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListUpdatedBetween returns notes created or changed in [from, to), ordered by vault and path.
+func (r *NoteRepo) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]NoteRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier FROM notes
+		 WHERE updated_at >= ? AND updated_at < ?
+		 ORDER BY vault_id, rel_path`,
+		from.UTC().Format("2006-01-02 15:04:05"), to.UTC().Format("2006-01-02 15:04:05"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query updated notes: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var notes []NoteRecord
+	for rows.Next() {
+		var note NoteRecord
+		var updatedAtStr string
+		if err := rows.Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		note.UpdatedAt, err = parseTimestamp(updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return notes, nil
+}
+
+// ListModifiedBetween returns up to limit notes in the given vaults (all vaults if empty)
+// last changed in [from, to), most recently changed first (ties by vault and path). A note
+// was last changed at its frontmatter modified date when set, otherwise at its file
+// modification time, otherwise when its change was indexed.
+func (r *NoteRepo) ListModifiedBetween(ctx context.Context, vaultIDs []int, from, to time.Time, limit int) ([]ModifiedNote, error) {
+	conditions := []string{noteModifiedAt + " >= ?", noteModifiedAt + " < ?"}
+	args := []interface{}{from.UTC().Format("2006-01-02 15:04:05"), to.UTC().Format("2006-01-02 15:04:05")}
+	if len(vaultIDs) > 0 {
+		placeholders := make([]string, len(vaultIDs))
+		for i, vaultID := range vaultIDs {
+			placeholders[i] = "?"
+			args = append(args, vaultID)
+		}
+		conditions = append(conditions, fmt.Sprintf("vault_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier, `+noteModifiedAt+` AS modified_at FROM notes
+		 WHERE `+strings.Join(conditions, " AND ")+`
+		 ORDER BY modified_at DESC, vault_id, rel_path
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query modified notes: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var notes []ModifiedNote
+	for rows.Next() {
+		var modified ModifiedNote
+		var updatedAtStr, modifiedAtStr string
+		note := &modified.Note
+		if err := rows.Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier, &modifiedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		if note.UpdatedAt, err = parseTimestamp(updatedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		if modified.ModifiedAt, err = parseTimestamp(modifiedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to parse modified timestamp: %w", err)
+		}
+		notes = append(notes, modified)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return notes, nil
+}
+
+// ModifiedTimes returns when each of the given notes was last changed (see noteModifiedAt),
+// keyed by note ID. Unknown IDs are omitted.
+func (r *NoteRepo) ModifiedTimes(ctx context.Context, noteIDs []string) (map[string]time.Time, error) {
+	modified := make(map[string]time.Time, len(noteIDs))
+	if len(noteIDs) == 0 {
+		return modified, nil
+	}
+
+	placeholders := make([]string, len(noteIDs))
+	args := make([]interface{}, len(noteIDs))
+	for i, id := range noteIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT id, %s FROM notes WHERE id IN (%s)", noteModifiedAt, strings.Join(placeholders, ",")),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note modification times: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var id, modifiedAtStr string
+		if err := rows.Scan(&id, &modifiedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to scan note modification time: %w", err)
+		}
+		if modified[id], err = parseTimestamp(modifiedAtStr); err != nil {
+			return nil, fmt.Errorf("failed to parse modified timestamp: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return modified, nil
+}
+
+// ListMostRetrieved returns the notes that contributed to the most answers in [from, to),
+// most retrieved first (ties by vault and path). Notes deleted since are skipped.
+func (r *NoteRepo) ListMostRetrieved(ctx context.Context, from, to time.Time, limit int) ([]NoteRetrievalCount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT n.id, n.vault_id, n.rel_path, n.folder, n.title, n.updated_at, n.hash, n.tier, COUNT(*) AS retrievals
+		 FROM note_retrievals r JOIN notes n ON n.id = r.note_id
+		 WHERE r.retrieved_at >= ? AND r.retrieved_at < ?
+		 GROUP BY n.id
+		 ORDER BY retrievals DESC, n.vault_id, n.rel_path
+		 LIMIT ?`,
+		from.UTC().Format("2006-01-02 15:04:05"), to.UTC().Format("2006-01-02 15:04:05"), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query note retrievals: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var counts []NoteRetrievalCount
+	for rows.Next() {
+		var count NoteRetrievalCount
+		var updatedAtStr string
+		note := &count.Note
+		if err := rows.Scan(&note.ID, &note.VaultID, &note.RelPath, &note.Folder, &note.Title, &updatedAtStr, &note.Hash, &note.Tier, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan note retrieval count: %w", err)
+		}
+		note.UpdatedAt, err = parseTimestamp(updatedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return counts, nil
+}
+
+// FolderChunkStats summarizes the notes of a vault under a folder prefix: note and chunk
+// counts, total chunk text length, latest index time, and retrievals since the cutoff.
+// The prefix matches whole folder names ("Archive" covers "Archive/2020" but not "Archived").
+func (r *NoteRepo) FolderChunkStats(ctx context.Context, vaultID int, prefix string, since time.Time) (FolderChunkStats, error) {
+	const inFolder = "n.vault_id = ? AND (n.folder = ? OR substr(n.folder, 1, length(?) + 1) = ? || '/')"
+	args := []interface{}{vaultID, prefix, prefix, prefix}
+
+	var stats FolderChunkStats
+	var lastIndexed sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*), MAX(n.updated_at) FROM notes n WHERE "+inFolder,
+		args...,
+	).Scan(&stats.NoteCount, &lastIndexed)
+	if err != nil {
+		return FolderChunkStats{}, fmt.Errorf("failed to query folder notes: %w", err)
+	}
+	if lastIndexed.Valid {
+		lastIndexedAt, err := parseTimestamp(lastIndexed.String)
+		if err != nil {
+			return FolderChunkStats{}, fmt.Errorf("failed to parse updated_at timestamp: %w", err)
+		}
+		stats.LastIndexedAt = &lastIndexedAt
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(LENGTH(COALESCE(t.text, c.text))), 0)
+		 FROM chunks c
+		 JOIN notes n ON n.id = c.note_id
+		 LEFT JOIN chunk_texts t ON t.hash = c.text_hash
+		 WHERE `+inFolder,
+		args...,
+	).Scan(&stats.ChunkCount, &stats.TextChars)
+	if err != nil {
+		return FolderChunkStats{}, fmt.Errorf("failed to query folder chunks: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM note_retrievals r JOIN notes n ON n.id = r.note_id WHERE r.retrieved_at >= ? AND "+inFolder,
+		append([]interface{}{since.UTC().Format("2006-01-02 15:04:05")}, args...)...,
+	).Scan(&stats.Retrievals)
+	if err != nil {
+		return FolderChunkStats{}, fmt.Errorf("failed to query folder retrievals: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SetTags replaces the tags of a note.
+func (r *NoteRepo) SetTags(ctx context.Context, noteID string, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM note_tags WHERE note_id = ?", noteID); err != nil {
+		return fmt.Errorf("failed to delete note tags: %w", err)
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO note_tags (note_id, tag) VALUES (?, ?)", noteID, tag); err != nil {
+			return fmt.Errorf("failed to insert note tag: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit note tags: %w", err)
+	}
+	return nil
+}
+
+// SetMetadata replaces the frontmatter metadata of a note. Aliases are stored as a JSON
+// array; zero dates are stored as NULL.
+func (r *NoteRepo) SetMetadata(ctx context.Context, noteID string, meta NoteMetadata) error {
+	var aliases, created, modified any
+	if len(meta.Aliases) > 0 {
+		data, err := json.Marshal(meta.Aliases)
+		if err != nil {
+			return fmt.Errorf("failed to marshal note aliases: %w", err)
+		}
+		aliases = string(data)
+	}
+	if !meta.Created.IsZero() {
+		created = meta.Created.UTC().Format("2006-01-02 15:04:05")
+	}
+	if !meta.Modified.IsZero() {
+		modified = meta.Modified.UTC().Format("2006-01-02 15:04:05")
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE notes SET aliases = ?, frontmatter_created = ?, frontmatter_modified = ? WHERE id = ?",
+		aliases, created, modified, noteID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set note metadata: %w", err)
+	}
+	return nil
+}
+
+// GetMetadata returns the frontmatter metadata stored for a note.
+// Returns ErrNotFound if the note does not exist.
+func (r *NoteRepo) GetMetadata(ctx context.Context, noteID string) (NoteMetadata, error) {
+	var aliases, created, modified sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT aliases, frontmatter_created, frontmatter_modified FROM notes WHERE id = ?",
+		noteID,
+	).Scan(&aliases, &created, &modified)
+	if err == sql.ErrNoRows {
+		return NoteMetadata{}, ErrNotFound
+	}
+	if err != nil {
+		return NoteMetadata{}, fmt.Errorf("failed to query note metadata: %w", err)
+	}
+
+	var meta NoteMetadata
+	if aliases.Valid {
+		if err := json.Unmarshal([]byte(aliases.String), &meta.Aliases); err != nil {
+			return NoteMetadata{}, fmt.Errorf("failed to parse note aliases: %w", err)
+		}
+	}
+	if created.Valid {
+		if meta.Created, err = parseTimestamp(created.String); err != nil {
+			return NoteMetadata{}, fmt.Errorf("failed to parse created date: %w", err)
+		}
+	}
+	if modified.Valid {
+		if meta.Modified, err = parseTimestamp(modified.String); err != nil {
+			return NoteMetadata{}, fmt.Errorf("failed to parse modified date: %w", err)
+		}
+	}
+	return meta, nil
+}
+
+// ListIDsByFilter returns the IDs of notes in the given vaults (all vaults if empty) that
+// carry every tag in filter (or a tag nested under it) and were last changed and created
+// within its time bounds, ordered by ID. The frontmatter modified date, when set, is the
+// time a note was last changed, then the file modification time.
+func (r *NoteRepo) ListIDsByFilter(ctx context.Context, vaultIDs []int, filter NoteFilter) ([]string, error) {
+	var conditions []string
+	var args []interface{}
+
+	if len(vaultIDs) > 0 {
+		placeholders := make([]string, len(vaultIDs))
+		for i, vaultID := range vaultIDs {
+			placeholders[i] = "?"
+			args = append(args, vaultID)
+		}
+		conditions = append(conditions, fmt.Sprintf("vault_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM note_tags t WHERE t.note_id = notes.id AND (t.tag = ? OR substr(t.tag, 1, length(?) + 1) = ? || '/'))")
+		args = append(args, tag, tag, tag)
+	}
+	if !filter.UpdatedBefore.IsZero() {
+		conditions = append(conditions, noteModifiedAt+" < ?")
+		args = append(args, filter.UpdatedBefore.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.UpdatedAfter.IsZero() {
+		conditions = append(conditions, noteModifiedAt+" >= ?")
+		args = append(args, filter.UpdatedAfter.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "frontmatter_created < ?")
+		args = append(args, filter.CreatedBefore.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "frontmatter_created >= ?")
+		args = append(args, filter.CreatedAfter.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	query := "SELECT id FROM notes"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notes by filter: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan note ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notes: %w", err)
+	}
+	return ids, nil
+}
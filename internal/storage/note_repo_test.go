@@ -2,7 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -30,6 +34,38 @@ func TestNewNoteRepo(t *testing.T) {
 	}
 }
 
+func TestNoteRepo_GetByID(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "test", "/tmp/test")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	repo := NewNoteRepo(db)
+	note := &NoteRecord{VaultID: vault.ID, RelPath: "garden.md", Title: "Garden", Hash: "h1"}
+	if err := repo.Upsert(ctx, note); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, note.ID)
+	if err != nil || got.VaultID != vault.ID || got.RelPath != "garden.md" {
+		t.Errorf("GetByID() = %+v, %v; want the garden note", got, err)
+	}
+	if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestNoteRepo_GetByVaultAndPath(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := tmpDir + "/test.db"
@@ -459,3 +495,605 @@ func TestNoteRepo_ListUniqueFolders(t *testing.T) {
 		})
 	}
 }
+
+func TestNoteRepo_ListFolderStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	vault, err := NewVaultRepo(db).GetOrCreateByName(context.Background(), "vault1", "/tmp/vault1")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	repo := NewNoteRepo(db)
+	notes := []*NoteRecord{
+		{VaultID: vault.ID, RelPath: "root.md", Folder: "", Hash: "h1"},
+		{VaultID: vault.ID, RelPath: "projects/a.md", Folder: "projects", Hash: "h2"},
+		{VaultID: vault.ID, RelPath: "projects/go/tips/b.md", Folder: "projects/go/tips", Hash: "h3"},
+		{VaultID: vault.ID, RelPath: "projects/go/tips/deep/c.md", Folder: "projects/go/tips/deep", Hash: "h4"},
+		{VaultID: vault.ID, RelPath: "projects/rust/d.md", Folder: "projects/rust", Hash: "h5"},
+	}
+	for _, note := range notes {
+		if err := repo.Upsert(context.Background(), note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	// Depth 2 collapses projects/go/tips and projects/go/tips/deep into projects/go
+	stats, total, err := repo.ListFolderStats(context.Background(), nil, FolderListOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("ListFolderStats() error = %v", err)
+	}
+	want := map[string]int{
+		fmt.Sprintf("%d/", vault.ID):              1,
+		fmt.Sprintf("%d/projects", vault.ID):      4,
+		fmt.Sprintf("%d/projects/go", vault.ID):   2,
+		fmt.Sprintf("%d/projects/rust", vault.ID): 1,
+	}
+	if total != len(want) || len(stats) != len(want) {
+		t.Fatalf("ListFolderStats() returned %d folders (total %d), want %d", len(stats), total, len(want))
+	}
+	for _, stat := range stats {
+		if count, ok := want[stat.Path]; !ok || count != stat.NoteCount {
+			t.Errorf("ListFolderStats() %s note count = %d, want %d", stat.Path, stat.NoteCount, count)
+		}
+	}
+
+	// Unlimited depth lists every nested folder
+	_, total, err = repo.ListFolderStats(context.Background(), nil, FolderListOptions{})
+	if err != nil {
+		t.Fatalf("ListFolderStats() error = %v", err)
+	}
+	if total != 6 {
+		t.Errorf("ListFolderStats() unlimited depth total = %d, want 6", total)
+	}
+
+	// Pagination
+	page, total, err := repo.ListFolderStats(context.Background(), nil, FolderListOptions{MaxDepth: 2, Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListFolderStats() error = %v", err)
+	}
+	if total != 4 || len(page) != 2 || page[0].Path != fmt.Sprintf("%d/projects", vault.ID) {
+		t.Errorf("ListFolderStats() page = %+v (total %d), want 2 folders starting at projects", page, total)
+	}
+
+	// Retrievals are credited to the folder and its ancestors
+	if err := repo.MarkRetrieved(context.Background(), []string{notes[2].ID, notes[2].ID, notes[4].ID}); err != nil {
+		t.Fatalf("MarkRetrieved() error = %v", err)
+	}
+	stats, _, err = repo.ListFolderStats(context.Background(), nil, FolderListOptions{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("ListFolderStats() error = %v", err)
+	}
+	wantRetrievals := map[string]int{
+		fmt.Sprintf("%d/", vault.ID):              0,
+		fmt.Sprintf("%d/projects", vault.ID):      3,
+		fmt.Sprintf("%d/projects/go", vault.ID):   2,
+		fmt.Sprintf("%d/projects/rust", vault.ID): 1,
+	}
+	for _, stat := range stats {
+		if stat.Retrievals != wantRetrievals[stat.Path] {
+			t.Errorf("ListFolderStats() %s retrievals = %d, want %d", stat.Path, stat.Retrievals, wantRetrievals[stat.Path])
+		}
+	}
+}
+
+func TestNoteRepo_DigestQueries(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	repo := NewNoteRepo(db)
+	for _, relPath := range []string{"garden.md", "recipes.md", "old.md"} {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Title: relPath, Hash: "h-" + relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+	if _, err := db.Exec("UPDATE notes SET updated_at = '2020-01-01 00:00:00' WHERE rel_path = 'old.md'"); err != nil {
+		t.Fatalf("failed to age note: %v", err)
+	}
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+	updated, err := repo.ListUpdatedBetween(ctx, from, to)
+	if err != nil {
+		t.Fatalf("ListUpdatedBetween() error = %v", err)
+	}
+	if len(updated) != 2 || updated[0].RelPath != "garden.md" || updated[1].RelPath != "recipes.md" {
+		t.Errorf("ListUpdatedBetween() = %+v, want garden.md and recipes.md", updated)
+	}
+
+	garden, _ := repo.GetByVaultAndPath(ctx, vault.ID, "garden.md")
+	recipes, _ := repo.GetByVaultAndPath(ctx, vault.ID, "recipes.md")
+	for _, ids := range [][]string{{garden.ID, recipes.ID}, {garden.ID}, {garden.ID}} {
+		if err := repo.MarkRetrieved(ctx, ids); err != nil {
+			t.Fatalf("MarkRetrieved() error = %v", err)
+		}
+	}
+
+	top, err := repo.ListMostRetrieved(ctx, from, to, 1)
+	if err != nil {
+		t.Fatalf("ListMostRetrieved() error = %v", err)
+	}
+	if len(top) != 1 || top[0].Note.ID != garden.ID || top[0].Count != 3 {
+		t.Errorf("ListMostRetrieved(limit 1) = %+v, want garden.md with 3 retrievals", top)
+	}
+	if past, _ := repo.ListMostRetrieved(ctx, from.Add(-48*time.Hour), from, 10); len(past) != 0 {
+		t.Errorf("ListMostRetrieved() outside the period = %+v, want none", past)
+	}
+}
+
+func TestNoteRepo_ListModifiedBetween(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	repo := NewNoteRepo(db)
+
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	notes := []struct {
+		vaultID      int
+		relPath      string
+		fileModified time.Time
+	}{
+		{personal.ID, "garden.md", day.Add(9 * time.Hour)},
+		{personal.ID, "recipes.md", day.Add(18 * time.Hour)},
+		{personal.ID, "old.md", day.AddDate(0, -1, 0)},
+		{work.ID, "standup.md", day.Add(12 * time.Hour)},
+		// Indexed before file times were recorded: dated by when it was indexed
+		{personal.ID, "unknown.md", time.Time{}},
+	}
+	ids := map[string]string{}
+	for _, n := range notes {
+		note := &NoteRecord{VaultID: n.vaultID, RelPath: n.relPath, Title: n.relPath, Hash: "h-" + n.relPath, FileModified: n.fileModified}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		ids[n.relPath] = note.ID
+	}
+	// The frontmatter modified date takes precedence over the file time
+	if err := repo.SetMetadata(ctx, ids["old.md"], NoteMetadata{Modified: day.Add(6 * time.Hour)}); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+
+	relPaths := func(modified []ModifiedNote) string {
+		paths := make([]string, 0, len(modified))
+		for _, m := range modified {
+			paths = append(paths, m.Note.RelPath)
+		}
+		return strings.Join(paths, ",")
+	}
+
+	tests := []struct {
+		name     string
+		vaultIDs []int
+		limit    int
+		want     string
+	}{
+		{name: "most recent first", limit: 10, want: "recipes.md,standup.md,garden.md,old.md"},
+		{name: "vault filter", vaultIDs: []int{personal.ID}, limit: 10, want: "recipes.md,garden.md,old.md"},
+		{name: "limit", limit: 2, want: "recipes.md,standup.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.ListModifiedBetween(ctx, tt.vaultIDs, day, day.AddDate(0, 0, 1), tt.limit)
+			if err != nil {
+				t.Fatalf("ListModifiedBetween() error = %v", err)
+			}
+			if relPaths(got) != tt.want {
+				t.Errorf("ListModifiedBetween() = %s, want %s", relPaths(got), tt.want)
+			}
+		})
+	}
+
+	got, err := repo.ListModifiedBetween(ctx, nil, day, day.AddDate(0, 0, 1), 1)
+	if err != nil {
+		t.Fatalf("ListModifiedBetween() error = %v", err)
+	}
+	if len(got) != 1 || !got[0].ModifiedAt.Equal(day.Add(18*time.Hour)) {
+		t.Errorf("ListModifiedBetween() = %+v, want recipes.md modified at 18:00", got)
+	}
+	recent, err := repo.ListModifiedBetween(ctx, nil, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListModifiedBetween() error = %v", err)
+	}
+	if relPaths(recent) != "unknown.md" {
+		t.Errorf("ListModifiedBetween(now) = %s, want unknown.md", relPaths(recent))
+	}
+
+	// A file time recorded later dates the note without marking it changed
+	if err := repo.SetFileModified(ctx, ids["unknown.md"], day.Add(20*time.Hour)); err != nil {
+		t.Fatalf("SetFileModified() error = %v", err)
+	}
+	got, err = repo.ListModifiedBetween(ctx, nil, day, day.AddDate(0, 0, 1), 1)
+	if err != nil {
+		t.Fatalf("ListModifiedBetween() error = %v", err)
+	}
+	if relPaths(got) != "unknown.md" {
+		t.Errorf("ListModifiedBetween() after SetFileModified() = %s, want unknown.md", relPaths(got))
+	}
+	updated, err := repo.ListUpdatedBetween(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListUpdatedBetween() error = %v", err)
+	}
+	if len(updated) != len(notes) {
+		t.Errorf("ListUpdatedBetween() = %d notes, want %d (updated_at untouched)", len(updated), len(notes))
+	}
+}
+
+func TestNoteRepo_ModifiedTimes(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, _ := NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	repo := NewNoteRepo(db)
+
+	ids := map[string]string{}
+	for _, relPath := range []string{"garden.md", "recipes.md"} {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Title: relPath, Hash: "h-" + relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		ids[relPath] = note.ID
+	}
+	// The frontmatter modified date takes precedence over the indexing time
+	modified := time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	if err := repo.SetMetadata(ctx, ids["garden.md"], NoteMetadata{Modified: modified}); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+
+	times, err := repo.ModifiedTimes(ctx, []string{ids["garden.md"], ids["recipes.md"], "missing"})
+	if err != nil {
+		t.Fatalf("ModifiedTimes() error = %v", err)
+	}
+	if len(times) != 2 {
+		t.Fatalf("ModifiedTimes() = %v, want garden.md and recipes.md", times)
+	}
+	if !times[ids["garden.md"]].Equal(modified) {
+		t.Errorf("ModifiedTimes()[garden.md] = %v, want its frontmatter date %v", times[ids["garden.md"]], modified)
+	}
+	if since := time.Since(times[ids["recipes.md"]]); since < -time.Minute || since > time.Hour {
+		t.Errorf("ModifiedTimes()[recipes.md] = %v, want when it was indexed", times[ids["recipes.md"]])
+	}
+
+	empty, err := repo.ModifiedTimes(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ModifiedTimes(nil) = %v, %v, want empty", empty, err)
+	}
+}
+
+func TestNoteRepo_Delete(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	repo := NewNoteRepo(db)
+	chunkRepo := NewChunkRepo(db)
+	var notes []*NoteRecord
+	for _, relPath := range []string{"drafts/scratch.md", "garden.md"} {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Title: relPath, Hash: "h-" + relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		if err := chunkRepo.Insert(ctx, &ChunkRecord{ID: "chunk-" + relPath, NoteID: note.ID, Text: "text of " + relPath}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := repo.MarkRetrieved(ctx, []string{notes[0].ID, notes[1].ID}); err != nil {
+		t.Fatalf("MarkRetrieved() error = %v", err)
+	}
+
+	if err := repo.Delete(ctx, notes[0].ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := repo.GetByVaultAndPath(ctx, vault.ID, "drafts/scratch.md"); err != ErrNotFound {
+		t.Errorf("GetByVaultAndPath() after Delete() error = %v, want ErrNotFound", err)
+	}
+	if ids, _ := chunkRepo.ListIDsByNote(ctx, notes[0].ID); len(ids) != 0 {
+		t.Errorf("chunks after Delete() = %v, want none", ids)
+	}
+	var retrievals int
+	if err := db.QueryRow("SELECT COUNT(*) FROM note_retrievals WHERE note_id = ?", notes[0].ID).Scan(&retrievals); err != nil || retrievals != 0 {
+		t.Errorf("retrievals after Delete() = %d (err %v), want 0", retrievals, err)
+	}
+	// Other notes are untouched
+	if ids, _ := chunkRepo.ListIDsByNote(ctx, notes[1].ID); len(ids) != 1 {
+		t.Errorf("chunks of the other note = %v, want 1", ids)
+	}
+
+	if err := repo.Delete(ctx, notes[0].ID); err != ErrNotFound {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNoteRepo_FolderChunkStats(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	repo := NewNoteRepo(db)
+	chunkRepo := NewChunkRepo(db)
+	notes := map[string]*NoteRecord{}
+	for _, relPath := range []string{"Projects/plan.md", "Projects/2024/launch.md", "Projects Archive/old.md"} {
+		note := &NoteRecord{VaultID: vault.ID, RelPath: relPath, Folder: path.Dir(relPath), Title: relPath, Hash: "h-" + relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		for i, text := range []string{"12345678", "1234"} {
+			chunk := &ChunkRecord{ID: fmt.Sprintf("%s-%d", relPath, i), NoteID: note.ID, ChunkIndex: i, Text: text}
+			if err := chunkRepo.Insert(ctx, chunk); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+		}
+		notes[relPath] = note
+	}
+	for _, ids := range [][]string{{notes["Projects/plan.md"].ID, notes["Projects Archive/old.md"].ID}, {notes["Projects/2024/launch.md"].ID}} {
+		if err := repo.MarkRetrieved(ctx, ids); err != nil {
+			t.Fatalf("MarkRetrieved() error = %v", err)
+		}
+	}
+
+	// "Projects" covers its subfolders but not "Projects Archive"
+	stats, err := repo.FolderChunkStats(ctx, vault.ID, "Projects", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("FolderChunkStats() error = %v", err)
+	}
+	if stats.NoteCount != 2 || stats.ChunkCount != 4 || stats.TextChars != 24 || stats.Retrievals != 2 {
+		t.Errorf("FolderChunkStats(Projects) = %+v, want 2 notes, 4 chunks, 24 chars, 2 retrievals", stats)
+	}
+	if stats.LastIndexedAt == nil || time.Since(*stats.LastIndexedAt) > time.Hour {
+		t.Errorf("LastIndexedAt = %v, want about now", stats.LastIndexedAt)
+	}
+
+	// Retrievals before the cutoff are not counted
+	if stats, _ := repo.FolderChunkStats(ctx, vault.ID, "Projects", time.Now().Add(time.Hour)); stats.Retrievals != 0 {
+		t.Errorf("FolderChunkStats() retrievals after cutoff = %d, want 0", stats.Retrievals)
+	}
+
+	stats, err = repo.FolderChunkStats(ctx, vault.ID, "Missing", time.Now())
+	if err != nil {
+		t.Fatalf("FolderChunkStats(Missing) error = %v", err)
+	}
+	if stats.NoteCount != 0 || stats.ChunkCount != 0 || stats.LastIndexedAt != nil {
+		t.Errorf("FolderChunkStats(Missing) = %+v, want empty", stats)
+	}
+}
+
+func TestNoteRepo_CountByVault(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	empty, _ := vaultRepo.GetOrCreateByName(ctx, "empty", "/tmp/empty")
+	repo := NewNoteRepo(db)
+	chunkRepo := NewChunkRepo(db)
+
+	notes := []struct {
+		vaultID int
+		relPath string
+		chunks  int
+	}{
+		{personal.ID, "garden.md", 3},
+		{personal.ID, "recipes/soup.md", 1},
+		{personal.ID, "empty.md", 0},
+		{work.ID, "standup.md", 2},
+	}
+	for _, n := range notes {
+		note := &NoteRecord{VaultID: n.vaultID, RelPath: n.relPath, Folder: path.Dir(n.relPath), Title: n.relPath, Hash: "h-" + n.relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		for i := range n.chunks {
+			chunk := &ChunkRecord{ID: fmt.Sprintf("%s-%d", n.relPath, i), NoteID: note.ID, ChunkIndex: i, Text: "text"}
+			if err := chunkRepo.Insert(ctx, chunk); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+		}
+	}
+
+	counts, err := repo.CountByVault(ctx)
+	if err != nil {
+		t.Fatalf("CountByVault() error = %v", err)
+	}
+	want := map[int]VaultCounts{
+		personal.ID: {NoteCount: 3, ChunkCount: 4},
+		work.ID:     {NoteCount: 1, ChunkCount: 2},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountByVault() = %v, want %v", counts, want)
+	}
+	if _, ok := counts[empty.ID]; ok {
+		t.Errorf("CountByVault() includes vault %d without notes", empty.ID)
+	}
+}
+
+func TestNoteRepo_ListIDsByFilter(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	personal, _ := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	work, _ := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work")
+	repo := NewNoteRepo(db)
+
+	notes := []struct {
+		vaultID int
+		relPath string
+		tags    []string
+	}{
+		{personal.ID, "golang.md", []string{"golang", "project/alpha"}},
+		{personal.ID, "rust.md", []string{"rust"}},
+		{work.ID, "service.md", []string{"golang"}},
+	}
+	ids := map[string]string{}
+	for _, n := range notes {
+		note := &NoteRecord{VaultID: n.vaultID, RelPath: n.relPath, Folder: "", Title: n.relPath, Hash: "h-" + n.relPath}
+		if err := repo.Upsert(ctx, note); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		if err := repo.SetTags(ctx, note.ID, n.tags); err != nil {
+			t.Fatalf("SetTags() error = %v", err)
+		}
+		ids[n.relPath] = note.ID
+	}
+	// Replacing tags drops the old ones
+	if err := repo.SetTags(ctx, ids["rust.md"], []string{"rust", "systems"}); err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	// rust.md last changed a year ago
+	if _, err := db.ExecContext(ctx, "UPDATE notes SET updated_at = ? WHERE id = ?", time.Now().AddDate(-1, 0, 0).UTC().Format("2006-01-02 15:04:05"), ids["rust.md"]); err != nil {
+		t.Fatalf("failed to backdate note: %v", err)
+	}
+	// service.md was indexed just now but its frontmatter says it last changed two years ago
+	created := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := repo.SetMetadata(ctx, ids["service.md"], NoteMetadata{Aliases: []string{"svc"}, Created: created, Modified: time.Now().AddDate(-2, 0, 0)}); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+	meta, err := repo.GetMetadata(ctx, ids["service.md"])
+	if err != nil {
+		t.Fatalf("GetMetadata() error = %v", err)
+	}
+	if len(meta.Aliases) != 1 || meta.Aliases[0] != "svc" || !meta.Created.Equal(created) || meta.Modified.IsZero() {
+		t.Errorf("GetMetadata() = %+v, want stored aliases and dates", meta)
+	}
+
+	sorted := func(relPaths ...string) []string {
+		out := make([]string, 0, len(relPaths))
+		for _, relPath := range relPaths {
+			out = append(out, ids[relPath])
+		}
+		sort.Strings(out)
+		return out
+	}
+	monthAgo := time.Now().AddDate(0, -1, 0)
+
+	tests := []struct {
+		name     string
+		vaultIDs []int
+		filter   NoteFilter
+		want     []string
+	}{
+		{name: "tag in all vaults", filter: NoteFilter{Tags: []string{"golang"}}, want: sorted("golang.md", "service.md")},
+		{name: "tag in one vault", vaultIDs: []int{work.ID}, filter: NoteFilter{Tags: []string{"golang"}}, want: sorted("service.md")},
+		{name: "parent tag matches nested tags", filter: NoteFilter{Tags: []string{"project"}}, want: sorted("golang.md")},
+		{name: "tag prefix is not a parent tag", filter: NoteFilter{Tags: []string{"proj"}}},
+		{name: "all tags required", filter: NoteFilter{Tags: []string{"golang", "rust"}}},
+		{name: "replaced tags", filter: NoteFilter{Tags: []string{"systems"}}, want: sorted("rust.md")},
+		{name: "updated before", filter: NoteFilter{UpdatedBefore: monthAgo}, want: sorted("rust.md", "service.md")},
+		{name: "updated after", filter: NoteFilter{UpdatedAfter: monthAgo}, want: sorted("golang.md")},
+		{name: "created after", filter: NoteFilter{CreatedAfter: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, want: sorted("service.md")},
+		{name: "created before", filter: NoteFilter{CreatedBefore: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{name: "no filter", vaultIDs: []int{personal.ID}, want: sorted("golang.md", "rust.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.ListIDsByFilter(ctx, tt.vaultIDs, tt.filter)
+			if err != nil {
+				t.Fatalf("ListIDsByFilter() error = %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListIDsByFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Deleting a note removes its tags
+	if err := repo.Delete(ctx, ids["service.md"]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM note_tags WHERE note_id = ?", ids["service.md"]).Scan(&count); err != nil || count != 0 {
+		t.Errorf("tags left after Delete() = %d, %v, want 0", count, err)
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// Replicator keeps a warm standby copy of the database in another file, ideally on another
+// disk, so the index metadata survives the loss of the disk the live database is on. The
+// standby is a complete SQLite database that can replace the live file after a failure.
+type Replicator struct {
+	db   *sql.DB
+	path string
+}
+
+// NewReplicator creates a Replicator that copies db to the file at path.
+func NewReplicator(db *sql.DB, path string) *Replicator {
+	return &Replicator{
+		db:   db,
+		path: path,
+	}
+}
+
+// Snapshot writes a consistent copy of the database to the standby path. The copy is built
+// in a temporary file with VACUUM INTO and renamed over the standby, so a failed or
+// interrupted snapshot leaves the previous standby intact.
+func (r *Replicator) Snapshot(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create replica directory: %w", err)
+	}
+
+	// VACUUM INTO refuses to overwrite, so clear what an interrupted snapshot left behind
+	tmpPath := r.path + ".tmp"
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale replica snapshot: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "VACUUM INTO ?", tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace replica: %w", err)
+	}
+	return nil
+}
+
+// Run snapshots the database immediately and then every interval until ctx is cancelled.
+// Failures are logged and retried on the next tick.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := r.Snapshot(ctx); err != nil {
+			logger.WarnContext(ctx, "database replica snapshot failed", "path", r.path, "error", err)
+		} else {
+			logger.InfoContext(ctx, "database replica updated", "path", r.path, "duration_ms", time.Since(start).Milliseconds())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnableWAL switches the database to write-ahead logging, which external replication
+// tools such as Litestream require. The journal mode is stored in the database file.
+func EnableWAL(db *sql.DB) error {
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode = WAL;").Scan(&mode); err != nil {
+		return fmt.Errorf("failed to enable WAL mode: %w", err)
+	}
+	if mode != "wal" {
+		return fmt.Errorf("failed to enable WAL mode: journal mode is %s", mode)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplicator_Snapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if err := EnableWAL(db); err != nil {
+		t.Fatalf("EnableWAL() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	if _, err := vaultRepo.GetOrCreateByName(ctx, "personal", "/tmp/personal"); err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	replicaPath := filepath.Join(tmpDir, "standby", "replica.db")
+	replicator := NewReplicator(db, replicaPath)
+	// A snapshot interrupted before the rename must not block the next one
+	if err := os.MkdirAll(filepath.Dir(replicaPath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(replicaPath+".tmp", []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to write stale snapshot: %v", err)
+	}
+	if err := replicator.Snapshot(ctx); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	// Later changes reach the standby with the next snapshot
+	if _, err := vaultRepo.GetOrCreateByName(ctx, "work", "/tmp/work"); err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	if err := replicator.Snapshot(ctx); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if _, err := os.Stat(replicaPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary snapshot left behind, Stat() error = %v", err)
+	}
+
+	replica, err := New(replicaPath)
+	if err != nil {
+		t.Fatalf("New() replica error = %v", err)
+	}
+	defer func() {
+		_ = replica.Close()
+	}()
+	vaults, err := NewVaultRepo(replica).ListAll(ctx)
+	if err != nil {
+		t.Fatalf("ListAll() on replica error = %v", err)
+	}
+	if len(vaults) != 2 {
+		t.Errorf("replica vaults = %+v, want personal and work", vaults)
+	}
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// ShadowIndex builds a replacement copy of the notes and chunks tables in a separate SQLite
+// file, then swaps it into the live database in a single transaction. Readers of the live
+// database keep seeing the previous index until the swap commits.
+type ShadowIndex struct {
+	db     *sql.DB
+	path   string
+	shadow *sql.DB
+}
+
+// NewShadowIndex creates a ShadowIndex for the live database db, building into the file at path.
+func NewShadowIndex(db *sql.DB, path string) *ShadowIndex {
+	return &ShadowIndex{
+		db:   db,
+		path: path,
+	}
+}
+
+// Open recreates the shadow database and returns it. Vaults are copied from the live
+// database so notes built in the shadow keep the same vault IDs.
+func (s *ShadowIndex) Open(ctx context.Context) (*sql.DB, error) {
+	if err := s.Discard(); err != nil {
+		return nil, err
+	}
+
+	shadow, err := New(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shadow database: %w", err)
+	}
+	if err := Migrate(shadow); err != nil {
+		_ = shadow.Close()
+		return nil, fmt.Errorf("failed to migrate shadow database: %w", err)
+	}
+
+	vaults, err := NewVaultRepo(s.db).ListAll(ctx)
+	if err != nil {
+		_ = shadow.Close()
+		return nil, fmt.Errorf("failed to list vaults: %w", err)
+	}
+	for _, vault := range vaults {
+		if _, err := shadow.ExecContext(ctx,
+			"INSERT INTO vaults (id, name, root_path) VALUES (?, ?, ?)",
+			vault.ID, vault.Name, vault.RootPath,
+		); err != nil {
+			_ = shadow.Close()
+			return nil, fmt.Errorf("failed to copy vault %s: %w", vault.Name, err)
+		}
+	}
+
+	s.shadow = shadow
+	return shadow, nil
+}
+
+// Swap replaces the live notes and chunks with the shadow's in one transaction and then
+// discards the shadow. Retrieval timestamps carry over to notes with the same path, so
+// cold storage decisions survive a rebuild.
+func (s *ShadowIndex) Swap(ctx context.Context) error {
+	if s.shadow == nil {
+		return fmt.Errorf("shadow index is not open")
+	}
+	// Flush the shadow to disk so the live connection sees all of it
+	if err := s.shadow.Close(); err != nil {
+		return fmt.Errorf("failed to close shadow database: %w", err)
+	}
+	s.shadow = nil
+
+	// ATTACH is per connection, so the whole swap runs on one
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS shadow", s.path); err != nil {
+		return fmt.Errorf("failed to attach shadow database: %w", err)
+	}
+	attached := true
+	defer func() {
+		if attached {
+			_, _ = conn.ExecContext(context.Background(), "DETACH DATABASE shadow")
+		}
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin swap transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	statements := []string{
+		// Rebuilt notes get new IDs, so retrieval history follows them by path
+		`UPDATE main.note_retrievals SET note_id = COALESCE((
+			SELECT s.id FROM shadow.notes s JOIN main.notes n ON n.vault_id = s.vault_id AND n.rel_path = s.rel_path
+			WHERE n.id = main.note_retrievals.note_id
+		), note_id)`,
+		`UPDATE shadow.notes SET last_retrieved_at = (
+			SELECT n.last_retrieved_at FROM main.notes n
+			WHERE n.vault_id = shadow.notes.vault_id AND n.rel_path = shadow.notes.rel_path
+		)`,
+		"DELETE FROM main.chunks",
+		"DELETE FROM main.chunk_texts",
+		"DELETE FROM main.notes",
+		`INSERT INTO main.notes (id, vault_id, rel_path, folder, title, updated_at, hash, tier, last_retrieved_at, aliases, frontmatter_created, frontmatter_modified, file_modified)
+		SELECT id, vault_id, rel_path, folder, title, updated_at, hash, tier, last_retrieved_at, aliases, frontmatter_created, frontmatter_modified, file_modified FROM shadow.notes`,
+		"INSERT INTO main.note_tags (note_id, tag) SELECT note_id, tag FROM shadow.note_tags",
+		"INSERT INTO main.chunk_texts (hash, text) SELECT hash, text FROM shadow.chunk_texts",
+		`INSERT INTO main.chunks (id, note_id, chunk_index, heading_path, text, text_hash, chunker_version, embedding_model, embedded_at, run_id, overlap_runes)
+		SELECT id, note_id, chunk_index, heading_path, text, text_hash, chunker_version, embedding_model, embedded_at, run_id, overlap_runes FROM shadow.chunks`,
+		`INSERT INTO main.index_failures (note_id, chunk_index, reason, error, created_at)
+		SELECT note_id, chunk_index, reason, error, created_at FROM shadow.index_failures`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to swap shadow index: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit swap transaction: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "DETACH DATABASE shadow"); err != nil {
+		return fmt.Errorf("failed to detach shadow database: %w", err)
+	}
+	attached = false
+	return s.Discard()
+}
+
+// Discard closes and removes the shadow database. It is safe to call when none is open.
+func (s *ShadowIndex) Discard() error {
+	if s.shadow != nil {
+		_ = s.shadow.Close()
+		s.shadow = nil
+	}
+	for _, path := range []string{s.path, s.path + "-journal", s.path + "-wal", s.path + "-shm"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove shadow database %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShadowIndex_Swap(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vault, err := NewVaultRepo(db).GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	// Live index: one note that has been retrieved
+	liveNotes := NewNoteRepo(db)
+	if err := liveNotes.Upsert(ctx, &NoteRecord{ID: "old-note", VaultID: vault.ID, RelPath: "a.md", Hash: "old"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := NewChunkRepo(db).Insert(ctx, &ChunkRecord{ID: "old-chunk", NoteID: "old-note", Text: "old"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if err := liveNotes.MarkRetrieved(ctx, []string{"old-note"}); err != nil {
+		t.Fatalf("MarkRetrieved() error = %v", err)
+	}
+
+	shadowPath := filepath.Join(tmpDir, "test.db.rebuild")
+	shadowIndex := NewShadowIndex(db, shadowPath)
+	shadowDB, err := shadowIndex.Open(ctx)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := NewNoteRepo(shadowDB).Upsert(ctx, &NoteRecord{ID: "new-note", VaultID: vault.ID, RelPath: "a.md", Hash: "new"}); err != nil {
+		t.Fatalf("shadow Upsert() error = %v", err)
+	}
+	if err := NewChunkRepo(shadowDB).Insert(ctx, &ChunkRecord{ID: "new-chunk", NoteID: "new-note", Text: "new"}); err != nil {
+		t.Fatalf("shadow Insert() error = %v", err)
+	}
+	if err := NewNoteRepo(shadowDB).SetTags(ctx, "new-note", []string{"golang"}); err != nil {
+		t.Fatalf("shadow SetTags() error = %v", err)
+	}
+
+	// The live index is untouched until the swap
+	if ids, _ := NewChunkRepo(db).GetAllIDs(ctx); len(ids) != 1 || ids[0] != "old-chunk" {
+		t.Fatalf("live chunk IDs before swap = %v, want [old-chunk]", ids)
+	}
+
+	if err := shadowIndex.Swap(ctx); err != nil {
+		t.Fatalf("Swap() error = %v", err)
+	}
+
+	if ids, _ := NewChunkRepo(db).GetAllIDs(ctx); len(ids) != 1 || ids[0] != "new-chunk" {
+		t.Errorf("live chunk IDs after swap = %v, want [new-chunk]", ids)
+	}
+	note, err := liveNotes.GetByVaultAndPath(ctx, vault.ID, "a.md")
+	if err != nil || note.ID != "new-note" {
+		t.Fatalf("GetByVaultAndPath() = %+v, %v, want new-note", note, err)
+	}
+	if ids, err := liveNotes.ListIDsByFilter(ctx, nil, NoteFilter{Tags: []string{"golang"}}); err != nil || len(ids) != 1 || ids[0] != "new-note" {
+		t.Errorf("ListIDsByFilter() after swap = %v, %v, want [new-note]", ids, err)
+	}
+	var lastRetrieved sql.NullTime
+	if err := db.QueryRowContext(ctx, "SELECT last_retrieved_at FROM notes WHERE id = ?", "new-note").Scan(&lastRetrieved); err != nil {
+		t.Fatalf("failed to read last_retrieved_at: %v", err)
+	}
+	if !lastRetrieved.Valid {
+		t.Error("last_retrieved_at was not carried over to the rebuilt note")
+	}
+	if _, err := os.Stat(shadowPath); !os.IsNotExist(err) {
+		t.Errorf("shadow database should be removed after swap, stat error = %v", err)
+	}
+}
+
+func TestShadowIndex_SwapWithoutOpen(t *testing.T) {
+	if err := NewShadowIndex(nil, filepath.Join(t.TempDir(), "shadow.db")).Swap(context.Background()); err == nil {
+		t.Error("Swap() expected error when the shadow index was never opened")
+	}
+}
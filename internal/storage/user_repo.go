@@ -0,0 +1,230 @@
+package storage
+
+//go:generate go run go.uber.org/mock/mockgen@latest -destination=mocks/mock_user_store.go -package=mocks helloworld-ai/internal/storage UserStore
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadyExists is returned when a record with the same unique name already exists.
+var ErrAlreadyExists = errors.New("record already exists")
+
+// UserStore defines the interface for API users and their vault permissions.
+type UserStore interface {
+	// Create adds a user with the hash of their token (HashToken) and the vaults they may
+	// read, setting the user's ID and creation time. Returns ErrAlreadyExists if the name is taken.
+	Create(ctx context.Context, user *UserRecord, tokenHash string) error
+	// GetByTokenHash returns the user whose token has the given hash, with their vaults.
+	// Returns ErrNotFound if no user has it.
+	GetByTokenHash(ctx context.Context, tokenHash string) (*UserRecord, error)
+	// List returns all users with their vaults, ordered by name.
+	List(ctx context.Context) ([]UserRecord, error)
+	// SetVaults replaces the vaults a user may read. Returns ErrNotFound if the user does not exist.
+	SetVaults(ctx context.Context, name string, vaultIDs []int) error
+	// Delete removes a user and their vault permissions. Returns ErrNotFound if the user does not exist.
+	Delete(ctx context.Context, name string) error
+}
+
+// UserRepo provides methods for API user operations.
+// It implements the UserStore interface.
+type UserRepo struct {
+	db *sql.DB
+}
+
+// NewUserRepo creates a new UserRepo.
+func NewUserRepo(db *sql.DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+// HashToken returns the SHA256 hex digest under which a user token is stored, so the
+// database never holds usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create adds a user with the hash of their token and the vaults they may read.
+func (r *UserRepo) Create(ctx context.Context, user *UserRecord, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE name = ?", user.Name).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user name: %w", err)
+	}
+	if exists > 0 {
+		return ErrAlreadyExists
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO users (name, token_hash, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
+		user.Name, tokenHash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get user ID: %w", err)
+	}
+	if err := insertUserVaults(ctx, tx, int(id), user.VaultIDs); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT created_at FROM users WHERE id = ?", id).Scan(&user.CreatedAt); err != nil {
+		return fmt.Errorf("failed to read created user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+	user.ID = int(id)
+	return nil
+}
+
+// GetByTokenHash returns the user whose token has the given hash, with their vaults.
+func (r *UserRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*UserRecord, error) {
+	var user UserRecord
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, name, created_at FROM users WHERE token_hash = ?",
+		tokenHash,
+	).Scan(&user.ID, &user.Name, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	vaults, err := r.listVaults(ctx, "WHERE user_id = ?", user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.VaultIDs = vaults[user.ID]
+	return &user, nil
+}
+
+// List returns all users with their vaults, ordered by name.
+func (r *UserRepo) List(ctx context.Context) ([]UserRecord, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, created_at FROM users ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var users []UserRecord
+	for rows.Next() {
+		var user UserRecord
+		if err := rows.Scan(&user.ID, &user.Name, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	vaults, err := r.listVaults(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].VaultIDs = vaults[users[i].ID]
+	}
+	return users, nil
+}
+
+// SetVaults replaces the vaults a user may read.
+func (r *UserRepo) SetVaults(ctx context.Context, name string, vaultIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM users WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_vaults WHERE user_id = ?", id); err != nil {
+		return fmt.Errorf("failed to clear user vaults: %w", err)
+	}
+	if err := insertUserVaults(ctx, tx, id, vaultIDs); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user vaults: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a user; their vault permissions go with them (ON DELETE CASCADE).
+func (r *UserRepo) Delete(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count deleted users: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// listVaults returns the vault IDs of users matching the where clause, keyed by user ID
+// and ascending.
+func (r *UserRepo) listVaults(ctx context.Context, where string, args ...any) (map[int][]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id, vault_id FROM user_vaults "+where+" ORDER BY user_id, vault_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user vaults: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	vaults := make(map[int][]int)
+	for rows.Next() {
+		var userID, vaultID int
+		if err := rows.Scan(&userID, &vaultID); err != nil {
+			return nil, fmt.Errorf("failed to scan user vault: %w", err)
+		}
+		vaults[userID] = append(vaults[userID], vaultID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return vaults, nil
+}
+
+// insertUserVaults grants a user the given vaults within tx.
+func insertUserVaults(ctx context.Context, tx *sql.Tx, userID int, vaultIDs []int) error {
+	for _, vaultID := range vaultIDs {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO user_vaults (user_id, vault_id) VALUES (?, ?)",
+			userID, vaultID,
+		); err != nil {
+			return fmt.Errorf("failed to grant vault %d: %w", vaultID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUserRepo(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/test.db"
+
+	db, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	vaultRepo := NewVaultRepo(db)
+	personal, err := vaultRepo.GetOrCreateByName(ctx, "personal", "/vaults/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	work, err := vaultRepo.GetOrCreateByName(ctx, "work", "/vaults/work")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+	repo := NewUserRepo(db)
+
+	alice := &UserRecord{Name: "alice", VaultIDs: []int{work.ID, personal.ID}}
+	if err := repo.Create(ctx, alice, HashToken("alice-token")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if alice.ID == 0 || alice.CreatedAt.IsZero() {
+		t.Errorf("Create() did not set the ID and creation time: %+v", alice)
+	}
+	if err := repo.Create(ctx, &UserRecord{Name: "bob"}, HashToken("bob-token")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, &UserRecord{Name: "alice"}, HashToken("other-token")); err != ErrAlreadyExists {
+		t.Errorf("Create() with a taken name error = %v, want ErrAlreadyExists", err)
+	}
+
+	got, err := repo.GetByTokenHash(ctx, HashToken("alice-token"))
+	if err != nil {
+		t.Fatalf("GetByTokenHash() error = %v", err)
+	}
+	if got.Name != "alice" || !reflect.DeepEqual(got.VaultIDs, []int{personal.ID, work.ID}) {
+		t.Errorf("GetByTokenHash() = %+v, want alice with both vaults", got)
+	}
+	if _, err := repo.GetByTokenHash(ctx, HashToken("alice")); err != ErrNotFound {
+		t.Errorf("GetByTokenHash() with an unknown token error = %v, want ErrNotFound", err)
+	}
+
+	if err := repo.SetVaults(ctx, "alice", []int{personal.ID}); err != nil {
+		t.Fatalf("SetVaults() error = %v", err)
+	}
+	if err := repo.SetVaults(ctx, "carol", nil); err != ErrNotFound {
+		t.Errorf("SetVaults() for a missing user error = %v, want ErrNotFound", err)
+	}
+	users, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(users) != 2 || users[0].Name != "alice" || !reflect.DeepEqual(users[0].VaultIDs, []int{personal.ID}) || users[1].VaultIDs != nil {
+		t.Errorf("List() = %+v, want alice with the personal vault and bob without vaults", users)
+	}
+
+	if err := repo.Delete(ctx, "alice"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Delete(ctx, "alice"); err != ErrNotFound {
+		t.Errorf("Delete() twice error = %v, want ErrNotFound", err)
+	}
+	var grants int
+	if err := db.QueryRow("SELECT COUNT(*) FROM user_vaults").Scan(&grants); err != nil || grants != 0 {
+		t.Errorf("user_vaults rows after delete = %d (%v), want 0", grants, err)
+	}
+}
@@ -14,6 +14,8 @@ import (
 type VaultStore interface {
 	// GetOrCreateByName gets an existing vault by name, or creates it if it doesn't exist.
 	GetOrCreateByName(ctx context.Context, name, rootPath string) (VaultRecord, error)
+	// GetByName gets an existing vault by name, returning ErrNotFound if it doesn't exist.
+	GetByName(ctx context.Context, name string) (VaultRecord, error)
 	// ListAll returns all vaults ordered by name.
 	ListAll(ctx context.Context) ([]VaultRecord, error)
 }
@@ -91,6 +93,23 @@ func (r *VaultRepo) GetOrCreateByName(ctx context.Context, name, rootPath string
 	return vault, nil
 }
 
+// GetByName gets an existing vault by name, returning ErrNotFound if it doesn't exist.
+// Unlike GetOrCreateByName it never writes, so it works on a read-only database.
+func (r *VaultRepo) GetByName(ctx context.Context, name string) (VaultRecord, error) {
+	var vault VaultRecord
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, name, root_path, created_at FROM vaults WHERE name = ?",
+		name,
+	).Scan(&vault.ID, &vault.Name, &vault.RootPath, &vault.CreatedAt)
+	if err == sql.ErrNoRows {
+		return VaultRecord{}, ErrNotFound
+	}
+	if err != nil {
+		return VaultRecord{}, fmt.Errorf("failed to query vault by name: %w", err)
+	}
+	return vault, nil
+}
+
 // ListAll returns all vaults ordered by name.
 func (r *VaultRepo) ListAll(ctx context.Context) ([]VaultRecord, error) {
 	rows, err := r.db.QueryContext(ctx,
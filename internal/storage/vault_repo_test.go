@@ -303,3 +303,35 @@ func TestVaultRepo_GetOrCreateByName_UniqueNames(t *testing.T) {
 		t.Errorf("GetOrCreateByName() should return different vault for different name")
 	}
 }
+
+func TestVaultRepo_GetByName(t *testing.T) {
+	db, err := New(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	ctx := context.Background()
+	repo := NewVaultRepo(db)
+	created, err := repo.GetOrCreateByName(ctx, "personal", "/tmp/personal")
+	if err != nil {
+		t.Fatalf("GetOrCreateByName() error = %v", err)
+	}
+
+	vault, err := repo.GetByName(ctx, "personal")
+	if err != nil {
+		t.Fatalf("GetByName() error = %v", err)
+	}
+	if vault.ID != created.ID || vault.RootPath != "/tmp/personal" {
+		t.Errorf("GetByName() = %+v, want %+v", vault, created)
+	}
+
+	if _, err := repo.GetByName(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("GetByName(missing) error = %v, want ErrNotFound", err)
+	}
+}
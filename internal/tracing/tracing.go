@@ -0,0 +1,114 @@
+// Package tracing sets up OpenTelemetry tracing and provides the spans used to time the
+// RAG pipeline. Span durations are measured here rather than read back from the SDK, so the
+// same timings feed both exported traces and the latency breakdown in debug responses, even
+// when no exporter is configured.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer that creates every span in the service.
+const instrumentationName = "helloworld-ai"
+
+// Setup installs a global tracer provider that exports spans over OTLP/HTTP. The exporter
+// reads its endpoint, headers, and timeout from the standard OTEL_EXPORTER_OTLP_* variables.
+// The returned shutdown flushes pending spans and should be called before exit.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the service tracer from the global provider (a no-op until Setup runs).
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Span is a timed trace span. Its duration is measured locally and used for the exported
+// span's timestamps, so Elapsed and End agree with the trace backend.
+type Span struct {
+	span  trace.Span
+	start time.Time
+	end   time.Time
+}
+
+// Start starts a span named name as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *Span) {
+	start := time.Now()
+	ctx, span := Tracer().Start(ctx, name, trace.WithTimestamp(start), trace.WithAttributes(attrs...))
+	return ctx, &Span{span: span, start: start}
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...attribute.KeyValue) {
+	s.span.SetAttributes(attrs...)
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// TraceID returns the ID of the trace the span belongs to, or "" when tracing is disabled.
+func (s *Span) TraceID() string {
+	spanContext := s.span.SpanContext()
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// Elapsed returns the time since the span started, or its duration once ended.
+func (s *Span) Elapsed() time.Duration {
+	if !s.end.IsZero() {
+		return s.end.Sub(s.start)
+	}
+	return time.Since(s.start)
+}
+
+// End ends the span and returns its duration. Later calls return the same duration
+// without ending the span again.
+func (s *Span) End() time.Duration {
+	if s.end.IsZero() {
+		s.end = time.Now()
+		s.span.End(trace.WithTimestamp(s.end))
+	}
+	return s.end.Sub(s.start)
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	time.Sleep(2 * time.Millisecond)
+	child.RecordError(errors.New("search failed"))
+	duration := child.End()
+	if again := child.End(); again != duration {
+		t.Errorf("second End() = %v, want the first duration %v", again, duration)
+	}
+	if child.Elapsed() != duration {
+		t.Errorf("Elapsed() after End() = %v, want %v", child.Elapsed(), duration)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d ended spans, want 2 (End must not end a span twice)", len(spans))
+	}
+	recorded := spans[0]
+	if recorded.Name() != "child" || recorded.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("first span = %q with parent %v, want child of parent", recorded.Name(), recorded.Parent().SpanID())
+	}
+	if got := recorded.EndTime().Sub(recorded.StartTime()); got != duration {
+		t.Errorf("exported duration = %v, want the measured %v", got, duration)
+	}
+	if recorded.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error after RecordError", recorded.Status().Code)
+	}
+}
+
+func TestSpan_NoProvider(t *testing.T) {
+	// Without Setup the spans are no-ops but still measure their duration
+	_, span := Start(context.Background(), "untraced")
+	time.Sleep(time.Millisecond)
+	if span.End() < time.Millisecond {
+		t.Error("End() should measure the span even when tracing is disabled")
+	}
+}
@@ -2,38 +2,85 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 
 	"helloworld-ai/internal/storage"
 )
 
 // Manager manages vault configuration and provides vault lookup and path resolution.
 type Manager struct {
-	vaultRepo storage.VaultStore
-	vaults    map[string]storage.VaultRecord // Cache vaults by name
+	vaultRepo   storage.VaultStore
+	vaults      map[string]storage.VaultRecord // Cache vaults by name
+	symlinkMode string                         // SymlinksFollow or SymlinksSkip
 }
 
-// NewManager creates a new vault manager and initializes personal and work vaults.
-func NewManager(ctx context.Context, vaultRepo storage.VaultStore, personalPath, workPath string) (*Manager, error) {
-	m := &Manager{
-		vaultRepo: vaultRepo,
-		vaults:    make(map[string]storage.VaultRecord),
+// Config names a vault and its root directory.
+type Config struct {
+	// Name identifies the vault in requests, metadata, and the vaults table (e.g. "personal").
+	Name string `json:"name"`
+	// Path is the vault's root directory.
+	Path string `json:"path"`
+}
+
+// NewManager creates a new vault manager and initializes the configured vaults, creating
+// records for new names and updating the root path of existing ones.
+// symlinkMode controls how scanning treats symlinks (SymlinksFollow or SymlinksSkip).
+func NewManager(ctx context.Context, vaultRepo storage.VaultStore, vaults []Config, symlinkMode string) (*Manager, error) {
+	return newManager(vaultRepo, vaults, symlinkMode, func(vault Config) (storage.VaultRecord, error) {
+		record, err := vaultRepo.GetOrCreateByName(ctx, vault.Name, vault.Path)
+		if err != nil {
+			return storage.VaultRecord{}, fmt.Errorf("failed to create vault %s: %w", vault.Name, err)
+		}
+		return record, nil
+	})
+}
+
+// NewReadOnlyManager creates a vault manager for a read-only API replica. It looks up the
+// records the primary instance created and never writes them; a configured vault without a
+// record is an error. The configured path is used as the root, since a replica may mount
+// the vaults elsewhere than the primary.
+func NewReadOnlyManager(ctx context.Context, vaultRepo storage.VaultStore, vaults []Config, symlinkMode string) (*Manager, error) {
+	return newManager(vaultRepo, vaults, symlinkMode, func(vault Config) (storage.VaultRecord, error) {
+		record, err := vaultRepo.GetByName(ctx, vault.Name)
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.VaultRecord{}, fmt.Errorf("vault %s is not in the database: configure it on the primary instance first", vault.Name)
+		}
+		if err != nil {
+			return storage.VaultRecord{}, fmt.Errorf("failed to look up vault %s: %w", vault.Name, err)
+		}
+		record.RootPath = vault.Path
+		return record, nil
+	})
+}
+
+// newManager validates the configuration and resolves each vault's record with load.
+func newManager(vaultRepo storage.VaultStore, vaults []Config, symlinkMode string, load func(Config) (storage.VaultRecord, error)) (*Manager, error) {
+	if symlinkMode != SymlinksFollow && symlinkMode != SymlinksSkip {
+		return nil, fmt.Errorf("invalid symlink mode: %s", symlinkMode)
+	}
+	if len(vaults) == 0 {
+		return nil, fmt.Errorf("no vaults configured")
 	}
 
-	// Initialize personal vault
-	personalVault, err := vaultRepo.GetOrCreateByName(ctx, "personal", personalPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create vault personal: %w", err)
+	m := &Manager{
+		vaultRepo:   vaultRepo,
+		vaults:      make(map[string]storage.VaultRecord),
+		symlinkMode: symlinkMode,
 	}
-	m.vaults["personal"] = personalVault
 
-	// Initialize work vault
-	workVault, err := vaultRepo.GetOrCreateByName(ctx, "work", workPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create vault work: %w", err)
+	for _, vault := range vaults {
+		if _, ok := m.vaults[vault.Name]; ok {
+			return nil, fmt.Errorf("duplicate vault: %s", vault.Name)
+		}
+		record, err := load(vault)
+		if err != nil {
+			return nil, err
+		}
+		m.vaults[vault.Name] = record
 	}
-	m.vaults["work"] = workVault
 
 	return m, nil
 }
@@ -47,15 +94,35 @@ func (m *Manager) VaultByName(name string) (storage.VaultRecord, error) {
 	return vault, nil
 }
 
-// AbsPath returns the absolute path for a file given its vault ID and relative path.
-func (m *Manager) AbsPath(vaultID int, relPath string) string {
-	// Find vault by ID
+// VaultByID returns the vault record with the given ID.
+func (m *Manager) VaultByID(id int) (storage.VaultRecord, error) {
 	for _, vault := range m.vaults {
-		if vault.ID == vaultID {
-			return filepath.Join(vault.RootPath, relPath)
+		if vault.ID == id {
+			return vault, nil
 		}
 	}
-	// If vault not found, return empty string (should not happen in practice)
-	return ""
+	return storage.VaultRecord{}, fmt.Errorf("vault not found: %d", id)
+}
+
+// ListVaults returns all configured vaults ordered by ID.
+func (m *Manager) ListVaults() []storage.VaultRecord {
+	vaults := make([]storage.VaultRecord, 0, len(m.vaults))
+	for _, vault := range m.vaults {
+		vaults = append(vaults, vault)
+	}
+	sort.Slice(vaults, func(i, j int) bool {
+		return vaults[i].ID < vaults[j].ID
+	})
+	return vaults
+}
+
+// AbsPath returns the absolute path for a file given its vault ID and relative path.
+func (m *Manager) AbsPath(vaultID int, relPath string) string {
+	vault, err := m.VaultByID(vaultID)
+	if err != nil {
+		// Should not happen in practice
+		return ""
+	}
+	return filepath.Join(vault.RootPath, relPath)
 }
 
@@ -24,7 +24,7 @@ func TestNewManager(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", "/tmp/work").
 		Return(storage.VaultRecord{ID: 2, Name: "work", RootPath: "/tmp/work"}, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, "/tmp/personal", "/tmp/work")
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/tmp/personal"}, {Name: "work", Path: "/tmp/work"}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -44,7 +44,7 @@ func TestNewManager_Error(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "personal", "/tmp/personal").
 		Return(storage.VaultRecord{}, storage.ErrNotFound)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, "/tmp/personal", "/tmp/work")
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/tmp/personal"}, {Name: "work", Path: "/tmp/work"}}, SymlinksFollow)
 	if err == nil {
 		t.Error("NewManager() expected error, got nil")
 	}
@@ -70,7 +70,7 @@ func TestManager_VaultByName(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", "/tmp/work").
 		Return(workVault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, "/tmp/personal", "/tmp/work")
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/tmp/personal"}, {Name: "work", Path: "/tmp/work"}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -136,7 +136,7 @@ func TestManager_AbsPath(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", "/tmp/work").
 		Return(workVault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, "/tmp/personal", "/tmp/work")
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/tmp/personal"}, {Name: "work", Path: "/tmp/work"}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -183,3 +183,85 @@ func TestManager_AbsPath(t *testing.T) {
 	}
 }
 
+
+func TestNewManager_ConfiguredVaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := mocks.NewMockVaultStore(ctrl)
+
+	configs := []Config{
+		{Name: "notes", Path: "/vaults/notes"},
+		{Name: "team_wiki", Path: "/vaults/wiki"},
+		{Name: "archive", Path: "/vaults/archive"},
+	}
+	for i, cfg := range configs {
+		mockVaultRepo.EXPECT().
+			GetOrCreateByName(gomock.Any(), cfg.Name, cfg.Path).
+			Return(storage.VaultRecord{ID: 3 - i, Name: cfg.Name, RootPath: cfg.Path}, nil)
+	}
+
+	manager, err := NewManager(context.Background(), mockVaultRepo, configs, SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	vaults := manager.ListVaults()
+	if len(vaults) != 3 || vaults[0].Name != "archive" || vaults[1].Name != "team_wiki" || vaults[2].Name != "notes" {
+		t.Errorf("ListVaults() = %+v, want archive, team_wiki, notes (by ID)", vaults)
+	}
+
+	vault, err := manager.VaultByID(2)
+	if err != nil || vault.Name != "team_wiki" {
+		t.Errorf("VaultByID(2) = %+v, %v, want team_wiki", vault, err)
+	}
+	if _, err := manager.VaultByID(99); err == nil {
+		t.Error("VaultByID(99) expected error for an unknown vault")
+	}
+	if got := manager.AbsPath(2, "a.md"); got != "/vaults/wiki/a.md" {
+		t.Errorf("AbsPath(2, a.md) = %q, want /vaults/wiki/a.md", got)
+	}
+}
+
+func TestNewManager_InvalidVaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := mocks.NewMockVaultStore(ctrl)
+	mockVaultRepo.EXPECT().
+		GetOrCreateByName(gomock.Any(), "work", "/a").
+		Return(storage.VaultRecord{ID: 1, Name: "work", RootPath: "/a"}, nil)
+
+	if _, err := NewManager(context.Background(), mockVaultRepo, nil, SymlinksFollow); err == nil {
+		t.Error("NewManager() expected error without vaults")
+	}
+	duplicate := []Config{{Name: "work", Path: "/a"}, {Name: "work", Path: "/b"}}
+	if _, err := NewManager(context.Background(), mockVaultRepo, duplicate, SymlinksFollow); err == nil {
+		t.Error("NewManager() expected error for a duplicate vault name")
+	}
+}
+
+func TestNewReadOnlyManager(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := mocks.NewMockVaultStore(ctrl)
+	mockVaultRepo.EXPECT().
+		GetByName(gomock.Any(), "personal").
+		Return(storage.VaultRecord{ID: 1, Name: "personal", RootPath: "/primary/personal"}, nil)
+
+	manager, err := NewReadOnlyManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/replica/personal"}}, SymlinksFollow)
+	if err != nil {
+		t.Fatalf("NewReadOnlyManager() error = %v", err)
+	}
+	if got := manager.AbsPath(1, "a.md"); got != "/replica/personal/a.md" {
+		t.Errorf("AbsPath(1, a.md) = %q, want the replica's configured path", got)
+	}
+
+	mockVaultRepo.EXPECT().
+		GetByName(gomock.Any(), "work").
+		Return(storage.VaultRecord{}, storage.ErrNotFound)
+	if _, err := NewReadOnlyManager(context.Background(), mockVaultRepo, []Config{{Name: "work", Path: "/replica/work"}}, SymlinksFollow); err == nil {
+		t.Error("NewReadOnlyManager() expected error for a vault the primary has not created")
+	}
+}
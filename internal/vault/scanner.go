@@ -5,9 +5,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/storage"
+)
+
+// Symlink handling modes for vault scanning.
+const (
+	// SymlinksFollow follows symlinked files and directories. Each real directory is scanned
+	// at most once per vault, so symlink loops and directories linked twice are not rescanned.
+	SymlinksFollow = "follow"
+	// SymlinksSkip ignores symlinked files and directories.
+	SymlinksSkip = "skip"
 )
 
-// ScannedFile represents a markdown file found during vault scanning.
+// ScannedFile represents a markdown note or canvas file found during vault scanning.
 type ScannedFile struct {
 	VaultID int    // Vault ID from database
 	RelPath string // Relative path from vault root (e.g., "projects/meeting-notes.md")
@@ -15,7 +27,7 @@ type ScannedFile struct {
 	AbsPath string // Absolute file path
 }
 
-// ScanAll scans all vaults and returns a list of all markdown files found.
+// ScanAll scans all vaults and returns a list of all markdown and canvas files found.
 func (m *Manager) ScanAll(ctx context.Context) ([]ScannedFile, error) {
 	var scannedFiles []ScannedFile
 
@@ -28,64 +40,135 @@ func (m *Manager) ScanAll(ctx context.Context) ([]ScannedFile, error) {
 		default:
 		}
 
-		// Walk vault root directory
-		err := filepath.Walk(vault.RootPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				// Log error but continue scanning
-				return fmt.Errorf("failed to access path %s: %w", path, err)
-			}
+		files, err := m.scanVault(ctx, vault)
+		scannedFiles = append(scannedFiles, files...)
+		if err != nil {
+			return scannedFiles, err
+		}
+	}
 
-			// Skip directories
-			if info.IsDir() {
-				// Skip .obsidian directory (Obsidian configuration)
-				if info.Name() == ".obsidian" {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+	return scannedFiles, nil
+}
 
-			// Filter for markdown files
-			if filepath.Ext(path) != ".md" {
-				return nil
-			}
+// ScanVault scans a single vault and returns the markdown and canvas files found in it.
+func (m *Manager) ScanVault(ctx context.Context, vaultID int) ([]ScannedFile, error) {
+	vault, err := m.VaultByID(vaultID)
+	if err != nil {
+		return nil, err
+	}
+	return m.scanVault(ctx, vault)
+}
+
+// scanVault walks a vault's root directory. Files found before an error are returned with it.
+func (m *Manager) scanVault(ctx context.Context, vault storage.VaultRecord) ([]ScannedFile, error) {
+	// visited holds the real path of every directory scanned
+	s := &vaultScan{
+		manager: m,
+		vaultID: vault.ID,
+		root:    vault.RootPath,
+		visited: make(map[string]bool),
+	}
+	if err := s.walkDir(ctx, vault.RootPath); err != nil {
+		return s.files, fmt.Errorf("failed to scan vault %s: %w", vault.Name, err)
+	}
+	return s.files, nil
+}
+
+// vaultScan holds the state of a single vault walk.
+type vaultScan struct {
+	manager *Manager
+	vaultID int
+	root    string
+	visited map[string]bool
+	files   []ScannedFile
+}
+
+// walkDir scans a directory (reached directly or through a symlink) in lexical order.
+// Paths stay relative to the vault root as seen through links, not the link targets.
+func (s *vaultScan) walkDir(ctx context.Context, dir string) error {
+	logger := contextutil.LoggerFromContext(ctx)
 
-			// Compute relative path from vault root
-			relPath, err := filepath.Rel(vault.RootPath, path)
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to access path %s: %w", dir, err)
+	}
+	if s.visited[realDir] {
+		logger.WarnContext(ctx, "directory already scanned, skipping symlink loop or duplicate link", "path", dir, "target", realDir)
+		return nil
+	}
+	s.visited[realDir] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to access path %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		isDir := entry.IsDir()
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			if s.manager.symlinkMode == SymlinksSkip {
+				logger.DebugContext(ctx, "skipping symlink", "path", path)
+				continue
+			}
+			// Stat follows the link; broken links are skipped rather than failing the scan
+			info, err := os.Stat(path)
 			if err != nil {
-				return fmt.Errorf("failed to compute relative path for %s: %w", path, err)
+				logger.WarnContext(ctx, "skipping broken symlink", "path", path, "error", err)
+				continue
 			}
+			isDir = info.IsDir()
+		}
 
-			// Normalize relative path (use forward slashes for consistency)
-			relPath = filepath.ToSlash(relPath)
-
-			// Compute folder per Section 0.6
-			folder := filepath.Dir(relPath)
-			if folder == "." || folder == "" {
-				// Root-level file
-				folder = ""
-			} else {
-				// Normalize folder path
-				folder = filepath.ToSlash(folder)
+		if isDir {
+			// Skip .obsidian directory (Obsidian configuration)
+			if entry.Name() == ".obsidian" {
+				continue
 			}
-
-			// Create ScannedFile
-			scannedFile := ScannedFile{
-				VaultID: vault.ID,
-				RelPath: relPath,
-				Folder:  folder,
-				AbsPath: path,
+			if err := s.walkDir(ctx, path); err != nil {
+				return err
 			}
+			continue
+		}
 
-			scannedFiles = append(scannedFiles, scannedFile)
-			return nil
-		})
-
-		if err != nil {
-			// Log error but continue with other vaults
-			return scannedFiles, fmt.Errorf("failed to scan vault %s: %w", vault.Name, err)
+		// Filter for markdown notes and Obsidian canvases
+		if ext := filepath.Ext(path); ext != ".md" && ext != ".canvas" {
+			continue
+		}
+		if err := s.addFile(path); err != nil {
+			return err
 		}
 	}
-
-	return scannedFiles, nil
+	return nil
 }
 
+// addFile records a markdown or canvas file found at path.
+func (s *vaultScan) addFile(path string) error {
+	// Compute relative path from vault root
+	relPath, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return fmt.Errorf("failed to compute relative path for %s: %w", path, err)
+	}
+
+	// Normalize relative path (use forward slashes for consistency)
+	relPath = filepath.ToSlash(relPath)
+
+	// Compute folder per Section 0.6
+	folder := filepath.Dir(relPath)
+	if folder == "." || folder == "" {
+		// Root-level file
+		folder = ""
+	} else {
+		// Normalize folder path
+		folder = filepath.ToSlash(folder)
+	}
+
+	s.files = append(s.files, ScannedFile{
+		VaultID: s.vaultID,
+		RelPath: relPath,
+		Folder:  folder,
+		AbsPath: path,
+	})
+	return nil
+}
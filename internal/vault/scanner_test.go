@@ -72,7 +72,7 @@ func TestManager_ScanAll(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", workDir).
 		Return(workVault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, personalDir, workDir)
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: personalDir}, {Name: "work", Path: workDir}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -144,7 +144,7 @@ func TestManager_ScanAll_SkipsObsidian(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", vaultDir).
 		Return(vault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, vaultDir, vaultDir)
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: vaultDir}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -180,6 +180,7 @@ func TestManager_ScanAll_OnlyMarkdown(t *testing.T) {
 		ext  string
 	}{
 		{"note.md", ".md"},
+		{"board.canvas", ".canvas"},
 		{"document.txt", ".txt"},
 		{"image.png", ".png"},
 		{"code.go", ".go"},
@@ -207,7 +208,7 @@ func TestManager_ScanAll_OnlyMarkdown(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", vaultDir).
 		Return(vault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, vaultDir, vaultDir)
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: vaultDir}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -217,14 +218,14 @@ func TestManager_ScanAll_OnlyMarkdown(t *testing.T) {
 		t.Fatalf("ScanAll() error = %v", err)
 	}
 
-	// Should only find .md files
-	if len(scannedFiles) != 2 { // One for personal, one for work
-		t.Errorf("ScanAll() found %d files, want 2", len(scannedFiles))
+	// Should only find .md and .canvas files
+	if len(scannedFiles) != 4 { // Two for personal, two for work
+		t.Errorf("ScanAll() found %d files, want 4", len(scannedFiles))
 	}
 
 	for _, file := range scannedFiles {
-		if filepath.Ext(file.RelPath) != ".md" {
-			t.Errorf("ScanAll() should only return .md files, found: %s", file.RelPath)
+		if ext := filepath.Ext(file.RelPath); ext != ".md" && ext != ".canvas" {
+			t.Errorf("ScanAll() should only return .md and .canvas files, found: %s", file.RelPath)
 		}
 	}
 }
@@ -252,7 +253,7 @@ func TestManager_ScanAll_ContextCancellation(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", vaultDir).
 		Return(vault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, vaultDir, vaultDir)
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: vaultDir}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -299,7 +300,7 @@ func TestScannedFile_Fields(t *testing.T) {
 		GetOrCreateByName(gomock.Any(), "work", vaultDir).
 		Return(vault, nil)
 
-	manager, err := NewManager(context.Background(), mockVaultRepo, vaultDir, vaultDir)
+	manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: vaultDir}}, SymlinksFollow)
 	if err != nil {
 		t.Fatalf("NewManager() error = %v", err)
 	}
@@ -335,3 +336,102 @@ func TestScannedFile_Fields(t *testing.T) {
 	}
 }
 
+
+func TestManager_ScanAll_Symlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	vaultDir := filepath.Join(tmpDir, "vault")
+	sharedDir := filepath.Join(tmpDir, "shared")
+
+	for _, dir := range []string{filepath.Join(vaultDir, "notes"), sharedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(vaultDir, "notes", "local.md"), []byte("# Local"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sharedDir, "shared.md"), []byte("# Shared"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Shared folder outside the vault, a loop back to the vault root, and a broken link
+	links := map[string]string{
+		filepath.Join(vaultDir, "shared"):        sharedDir,
+		filepath.Join(vaultDir, "notes", "loop"): vaultDir,
+		filepath.Join(vaultDir, "broken.md"):     filepath.Join(tmpDir, "missing.md"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		symlinkMode string
+		want        []string
+	}{
+		{
+			name:        "follow",
+			symlinkMode: SymlinksFollow,
+			want:        []string{"notes/local.md", "shared/shared.md"},
+		},
+		{
+			name:        "skip",
+			symlinkMode: SymlinksSkip,
+			want:        []string{"notes/local.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockVaultRepo := mocks.NewMockVaultStore(ctrl)
+			mockVaultRepo.EXPECT().
+				GetOrCreateByName(gomock.Any(), "personal", vaultDir).
+				Return(storage.VaultRecord{ID: 1, Name: "personal", RootPath: vaultDir}, nil)
+			mockVaultRepo.EXPECT().
+				GetOrCreateByName(gomock.Any(), "work", sharedDir).
+				Return(storage.VaultRecord{ID: 2, Name: "work", RootPath: sharedDir}, nil)
+
+			manager, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: vaultDir}, {Name: "work", Path: sharedDir}}, tt.symlinkMode)
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+
+			files, err := manager.ScanAll(context.Background())
+			if err != nil {
+				t.Fatalf("ScanAll() error = %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				if file.VaultID == 1 {
+					got = append(got, file.RelPath)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ScanAll() personal paths = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("ScanAll() personal paths = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestNewManager_InvalidSymlinkMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVaultRepo := mocks.NewMockVaultStore(ctrl)
+
+	if _, err := NewManager(context.Background(), mockVaultRepo, []Config{{Name: "personal", Path: "/tmp/personal"}, {Name: "work", Path: "/tmp/work"}}, "sometimes"); err == nil {
+		t.Error("NewManager() expected error for invalid symlink mode")
+	}
+}
@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// newFileMode is the mode of files WriteFileAtomic creates, as a text editor would.
+const newFileMode os.FileMode = 0644
+
+// WriteFileAtomic writes data to absPath through a temporary file and a rename, so sync
+// tools and the scanner never see a partial file. The temporary name is hidden and not .md,
+// so it is never indexed. Missing folders are created. A replaced file keeps its permission
+// bits; new files get 0644 rather than the 0600 of the temporary file.
+func WriteFileAtomic(absPath string, data []byte) error {
+	dir := filepath.Dir(absPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	mode := newFileMode
+	if info, err := os.Stat(absPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, ".hwai-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), absPath)
+}
@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	newPath := filepath.Join(dir, "inbox", "new.md")
+	if err := WriteFileAtomic(newPath, []byte("# New\n")); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	info, err := os.Stat(newPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("new file mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// A replaced file keeps its permission bits
+	privatePath := filepath.Join(dir, "private.md")
+	if err := os.WriteFile(privatePath, []byte("# Old\n"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Chmod(privatePath, 0640); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	if err := WriteFileAtomic(privatePath, []byte("# Updated\n")); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	data, err := os.ReadFile(privatePath)
+	if err != nil || string(data) != "# Updated\n" {
+		t.Errorf("replaced file = %q (err %v), want the new content", data, err)
+	}
+	if info, err := os.Stat(privatePath); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("replaced file mode = %v (err %v), want 0640", info.Mode().Perm(), err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("vault entries = %v, want inbox and private.md", entries)
+	}
+}
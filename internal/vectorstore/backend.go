@@ -0,0 +1,70 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Vector store backends (VECTOR_STORE_BACKEND).
+const (
+	// BackendQdrant stores vectors in a Qdrant server.
+	BackendQdrant = "qdrant"
+	// BackendMemory keeps vectors in process memory with exact search. The index is lost on
+	// restart, so it suits tests and small vaults that re-embed quickly.
+	BackendMemory = "memory"
+	// BackendPgvector stores vectors in PostgreSQL with the pgvector extension.
+	BackendPgvector = "pgvector"
+)
+
+// ParseBackend validates a backend name (case-insensitive). Empty selects Qdrant.
+func ParseBackend(name string) (string, error) {
+	switch backend := strings.ToLower(strings.TrimSpace(name)); backend {
+	case "":
+		return BackendQdrant, nil
+	case BackendQdrant, BackendMemory, BackendPgvector:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("invalid vector store backend %q (must be %s, %s, or %s)", name, BackendQdrant, BackendMemory, BackendPgvector)
+	}
+}
+
+// Store is a VectorStore that also creates and describes its collections, supports
+// aliases for rebuilds, and holds a connection to close on shutdown. Every backend
+// implements it.
+type Store interface {
+	VectorStore
+	AliasStore
+	GetCollectionInfo(ctx context.Context, collection string) (*CollectionInfo, error)
+	Close() error
+}
+
+// BackendOptions configures Open.
+type BackendOptions struct {
+	// Backend is one of the Backend* names (empty uses BackendQdrant).
+	Backend string
+	// QdrantURL is the Qdrant HTTP URL, used by BackendQdrant.
+	QdrantURL string
+	// PgvectorDSN is the PostgreSQL connection string, used by BackendPgvector.
+	PgvectorDSN string
+	// UpsertBatchSize caps the points per upsert request (<= 0 uses DefaultUpsertBatchSize).
+	UpsertBatchSize int
+	// Distance is one of the Distance* metrics (empty uses DistanceCosine).
+	Distance string
+}
+
+// Open creates the vector store selected by opts.Backend.
+func Open(opts BackendOptions) (Store, error) {
+	backend, err := ParseBackend(opts.Backend)
+	if err != nil {
+		return nil, err
+	}
+	switch backend {
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendPgvector:
+		return NewPgvectorStore(opts.PgvectorDSN, opts.UpsertBatchSize, opts.Distance)
+	default:
+		return NewQdrantStore(opts.QdrantURL, opts.UpsertBatchSize, opts.Distance)
+	}
+}
@@ -0,0 +1,45 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: BackendQdrant},
+		{name: "Memory", want: BackendMemory},
+		{name: " pgvector ", want: BackendPgvector},
+		{name: "milvus", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBackend(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBackend(%q) = %q, %v, want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	store, err := Open(BackendOptions{Backend: BackendMemory})
+	if err != nil {
+		t.Fatalf("Open(memory) error = %v", err)
+	}
+	if _, ok := store.(*MemoryStore); !ok {
+		t.Errorf("Open(memory) = %T, want *MemoryStore", store)
+	}
+	if err := store.EnsureCollection(context.Background(), "notes", 2); err != nil {
+		t.Errorf("EnsureCollection() error = %v", err)
+	}
+
+	if _, err := Open(BackendOptions{Backend: "milvus"}); err == nil {
+		t.Error("Open() expected error for an unknown backend")
+	}
+	if _, err := Open(BackendOptions{Backend: BackendPgvector}); err == nil {
+		t.Error("Open(pgvector) expected error without a connection string")
+	}
+}
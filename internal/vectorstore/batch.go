@@ -0,0 +1,111 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultUpsertBatchSize is the number of points sent per upsert request when no batch
+// size is configured.
+const DefaultUpsertBatchSize = 64
+
+// Upsert batches are retried a few times with a short linear backoff before they are
+// reported as failed.
+const (
+	maxUpsertAttempts  = 3
+	upsertRetryBackoff = 200 * time.Millisecond
+)
+
+// BatchFailure is an upsert batch that still failed after retries.
+type BatchFailure struct {
+	// Start is the index of the batch's first point in the upserted slice.
+	Start int
+	// PointIDs are the IDs of the points in the batch.
+	PointIDs []string
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// UpsertBatchError reports the batches of an upsert that failed. Points in the other
+// batches were written, so callers can keep them and retry only what failed.
+type UpsertBatchError struct {
+	// Total is the number of points in the upsert.
+	Total int
+	// Failed lists the failed batches in order.
+	Failed []BatchFailure
+}
+
+// Error summarizes the failed batches and the first batch's error.
+func (e *UpsertBatchError) Error() string {
+	return fmt.Sprintf("%d of %d points failed to upsert in %d batch(es): %v",
+		len(e.FailedIDs()), e.Total, len(e.Failed), e.Failed[0].Err)
+}
+
+// Unwrap returns the errors of the failed batches.
+func (e *UpsertBatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, failure := range e.Failed {
+		errs = append(errs, failure.Err)
+	}
+	return errs
+}
+
+// Partial reports whether some points were written despite the failures.
+func (e *UpsertBatchError) Partial() bool {
+	return len(e.FailedIDs()) < e.Total
+}
+
+// FailedIDs returns the IDs of all points that were not written.
+func (e *UpsertBatchError) FailedIDs() []string {
+	var ids []string
+	for _, failure := range e.Failed {
+		ids = append(ids, failure.PointIDs...)
+	}
+	return ids
+}
+
+// upsertInBatches calls upsert with consecutive batches of at most batchSize points,
+// retrying each failed batch up to maxUpsertAttempts times. A failed batch does not stop
+// later batches; failures are returned together as an *UpsertBatchError. Cancellation
+// of ctx stops immediately and returns the context error.
+func upsertInBatches(ctx context.Context, points []Point, batchSize int, upsert func(ctx context.Context, batch []Point) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultUpsertBatchSize
+	}
+
+	var failed []BatchFailure
+	for start := 0; start < len(points); start += batchSize {
+		end := min(start+batchSize, len(points))
+		batch := points[start:end]
+
+		var err error
+		for attempt := 1; attempt <= maxUpsertAttempts; attempt++ {
+			if err = upsert(ctx, batch); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if attempt < maxUpsertAttempts {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Duration(attempt) * upsertRetryBackoff):
+				}
+			}
+		}
+		if err != nil {
+			ids := make([]string, 0, len(batch))
+			for _, point := range batch {
+				ids = append(ids, point.ID)
+			}
+			failed = append(failed, BatchFailure{Start: start, PointIDs: ids, Err: err})
+		}
+	}
+
+	if len(failed) > 0 {
+		return &UpsertBatchError{Total: len(points), Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUpsertInBatches(t *testing.T) {
+	points := make([]Point, 7)
+	for i := range points {
+		points[i] = Point{ID: fmt.Sprintf("p%d", i)}
+	}
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name string
+		// failures is how many times each batch (by first point ID) fails before succeeding
+		failures      map[string]int
+		wantCalls     int
+		wantFailedIDs []string
+	}{
+		{name: "all batches succeed", wantCalls: 3},
+		{name: "transient failure is retried", failures: map[string]int{"p3": 2}, wantCalls: 5},
+		{
+			name:          "failed batch does not stop later batches",
+			failures:      map[string]int{"p3": maxUpsertAttempts},
+			wantCalls:     2 + maxUpsertAttempts,
+			wantFailedIDs: []string{"p3", "p4", "p5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var written []string
+			err := upsertInBatches(context.Background(), points, 3, func(_ context.Context, batch []Point) error {
+				calls++
+				if len(batch) > 3 {
+					t.Errorf("batch of %d points, want at most 3", len(batch))
+				}
+				if tt.failures[batch[0].ID] > 0 {
+					tt.failures[batch[0].ID]--
+					return errUnavailable
+				}
+				for _, point := range batch {
+					written = append(written, point.ID)
+				}
+				return nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("upsert calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantFailedIDs == nil {
+				if err != nil {
+					t.Fatalf("upsertInBatches() error = %v", err)
+				}
+				if len(written) != len(points) {
+					t.Errorf("written = %v, want all points", written)
+				}
+				return
+			}
+
+			var batchErr *UpsertBatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("upsertInBatches() error = %v, want *UpsertBatchError", err)
+			}
+			if fmt.Sprint(batchErr.FailedIDs()) != fmt.Sprint(tt.wantFailedIDs) || batchErr.Failed[0].Start != 3 {
+				t.Errorf("failed batches = %+v, want %v starting at 3", batchErr.Failed, tt.wantFailedIDs)
+			}
+			if !batchErr.Partial() || batchErr.Total != len(points) || len(written) != len(points)-len(tt.wantFailedIDs) {
+				t.Errorf("written = %v, total %d, want the other batches written", written, batchErr.Total)
+			}
+			if !errors.Is(err, errUnavailable) {
+				t.Errorf("errors.Is(err, batch error) = false, want the batch error unwrapped")
+			}
+		})
+	}
+}
+
+func TestUpsertInBatches_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := upsertInBatches(ctx, []Point{{ID: "a"}, {ID: "b"}}, 1, func(context.Context, []Point) error {
+		calls++
+		cancel()
+		return context.Canceled
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("upsertInBatches() = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
@@ -0,0 +1,10 @@
+package vectorstore
+
+import "fmt"
+
+// VaultCollection returns the name of a vault's own chunk collection, used instead of the
+// shared collection when chunks are stored per vault (QDRANT_COLLECTION_PER_VAULT). Vault IDs
+// are stable, so the name survives vault renames and only uses characters every backend accepts.
+func VaultCollection(collection string, vaultID int) string {
+	return fmt.Sprintf("%s_vault_%d", collection, vaultID)
+}
@@ -0,0 +1,73 @@
+package vectorstore
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// Distance metrics a collection can be created with (QDRANT_DISTANCE).
+const (
+	DistanceCosine = "cosine"
+	DistanceDot    = "dot"
+	DistanceEuclid = "euclid"
+)
+
+// ParseDistance validates a distance metric name (case-insensitive). Empty selects cosine.
+func ParseDistance(name string) (string, error) {
+	switch distance := strings.ToLower(strings.TrimSpace(name)); distance {
+	case "":
+		return DistanceCosine, nil
+	case DistanceCosine, DistanceDot, DistanceEuclid:
+		return distance, nil
+	default:
+		return "", fmt.Errorf("invalid distance metric %q (must be %s, %s, or %s)", name, DistanceCosine, DistanceDot, DistanceEuclid)
+	}
+}
+
+// qdrantDistance maps a distance metric name to its Qdrant enum.
+func qdrantDistance(distance string) qdrant.Distance {
+	switch distance {
+	case DistanceDot:
+		return qdrant.Distance_Dot
+	case DistanceEuclid:
+		return qdrant.Distance_Euclid
+	default:
+		return qdrant.Distance_Cosine
+	}
+}
+
+// normalizesVectors reports whether vectors are normalized client-side for distance.
+// Qdrant normalizes cosine vectors itself. Dot and euclid collections get unit vectors,
+// so scores stay in the cosine range the retrieval thresholds are tuned for.
+func normalizesVectors(distance string) bool {
+	return distance == DistanceDot || distance == DistanceEuclid
+}
+
+// normalizeVector returns vec scaled to unit length. Zero vectors are returned unchanged.
+func normalizeVector(vec []float32) []float32 {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vec
+	}
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(vec))
+	for i, v := range vec {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// similarityScore turns a Qdrant score into a similarity where higher is better. Euclid
+// scores are distances; between unit vectors, 1 - d²/2 equals their cosine similarity.
+func similarityScore(distance string, score float32) float32 {
+	if distance == DistanceEuclid {
+		return 1 - score*score/2
+	}
+	return score
+}
@@ -0,0 +1,54 @@
+package vectorstore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "", want: DistanceCosine},
+		{name: "Dot", want: DistanceDot},
+		{name: " euclid ", want: DistanceEuclid},
+		{name: "manhattan", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDistance(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDistance(%q) = %q, %v, want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if qdrantDistance(DistanceDot) != qdrant.Distance_Dot || qdrantDistance(DistanceCosine) != qdrant.Distance_Cosine {
+		t.Error("qdrantDistance() maps metrics to the wrong Qdrant distance")
+	}
+}
+
+func TestNormalizeVector(t *testing.T) {
+	vec := []float32{3, 4}
+	got := normalizeVector(vec)
+	if math.Abs(float64(got[0])-0.6) > 1e-6 || math.Abs(float64(got[1])-0.8) > 1e-6 {
+		t.Errorf("normalizeVector() = %v, want [0.6 0.8]", got)
+	}
+	if vec[0] != 3 {
+		t.Error("normalizeVector() modified its input")
+	}
+	if zero := normalizeVector([]float32{0, 0}); zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("normalizeVector() of zero vector = %v, want it unchanged", zero)
+	}
+}
+
+func TestSimilarityScore(t *testing.T) {
+	// Unit vectors at 60 degrees: cosine 0.5, euclidean distance 1
+	if got := similarityScore(DistanceEuclid, 1); math.Abs(float64(got)-0.5) > 1e-6 {
+		t.Errorf("similarityScore(euclid, 1) = %v, want 0.5", got)
+	}
+	if got := similarityScore(DistanceDot, 0.7); got != 0.7 {
+		t.Errorf("similarityScore(dot, 0.7) = %v, want 0.7", got)
+	}
+}
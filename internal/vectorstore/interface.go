@@ -29,6 +29,31 @@ type VectorStore interface {
 	// Delete removes points by their IDs.
 	Delete(ctx context.Context, collection string, ids []string) error
 
+	// SetPayload sets the given metadata keys on existing points, leaving their vectors
+	// and other keys unchanged. IDs that do not exist are ignored.
+	SetPayload(ctx context.Context, collection string, ids []string, payload map[string]any) error
+
+	// Retrieve returns points (vectors and metadata) by their IDs.
+	// IDs that do not exist in the collection are omitted from the result.
+	Retrieve(ctx context.Context, collection string, ids []string) ([]Point, error)
+
 	// CollectionExists checks if a collection exists.
 	CollectionExists(ctx context.Context, collection string) (bool, error)
 }
+
+// AliasStore is implemented by vector stores that support collection aliases, which let an
+// index be rebuilt in a new collection and swapped in without queries seeing a partial index.
+// Point operations and searches accept an alias wherever a collection name is expected.
+type AliasStore interface {
+	// EnsureCollection creates the collection if needed and validates its vector size.
+	EnsureCollection(ctx context.Context, collection string, vectorSize int) error
+
+	// DeleteCollection removes a collection and all of its points.
+	DeleteCollection(ctx context.Context, collection string) error
+
+	// AliasTarget returns the collection an alias points to, or "" if no such alias exists.
+	AliasTarget(ctx context.Context, alias string) (string, error)
+
+	// SwitchAlias atomically points alias at collection, replacing any previous target.
+	SwitchAlias(ctx context.Context, alias, collection string) error
+}
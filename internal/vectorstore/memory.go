@@ -0,0 +1,327 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is an in-memory VectorStore used in test mode so the API can run without Qdrant.
+// It performs exact cosine similarity search and mirrors the QdrantStore filter semantics.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]Point
+	aliases     map[string]string // alias name to collection name
+}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		collections: make(map[string]map[string]Point),
+		aliases:     make(map[string]string),
+	}
+}
+
+// Close is a no-op; it lets the store be closed on shutdown like QdrantStore.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// EnsureCollection creates the collection if it does not exist.
+// The vector size is not enforced because all vectors come from the same embedder.
+func (s *MemoryStore) EnsureCollection(ctx context.Context, collection string, vectorSize int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection = s.resolve(collection)
+	if _, ok := s.collections[collection]; !ok {
+		s.collections[collection] = make(map[string]Point)
+	}
+	return nil
+}
+
+// Upsert inserts or updates points in the collection, creating it if needed.
+func (s *MemoryStore) Upsert(ctx context.Context, collection string, points []Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collection = s.resolve(collection)
+	coll, ok := s.collections[collection]
+	if !ok {
+		coll = make(map[string]Point)
+		s.collections[collection] = coll
+	}
+
+	for _, p := range points {
+		vec := make([]float32, len(p.Vec))
+		copy(vec, p.Vec)
+		coll[p.ID] = Point{ID: p.ID, Vec: vec, Meta: normalizeMeta(p.Meta)}
+	}
+	return nil
+}
+
+// Search performs an exact cosine similarity search with optional filters.
+func (s *MemoryStore) Search(ctx context.Context, collection string, query []float32, k int, filters map[string]any) ([]SearchResult, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	coll, ok := s.collections[s.resolve(collection)]
+	if !ok {
+		return nil, fmt.Errorf("failed to search points: collection %s not found", collection)
+	}
+
+	results := make([]SearchResult, 0, len(coll))
+	for _, p := range coll {
+		if !matchesFilters(p.Meta, filters) {
+			continue
+		}
+		results = append(results, SearchResult{
+			PointID: p.ID,
+			Score:   cosineSimilarity(query, p.Vec),
+			Meta:    copyMeta(p.Meta),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].PointID < results[j].PointID
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Delete removes points by their IDs. Missing IDs are ignored.
+func (s *MemoryStore) Delete(ctx context.Context, collection string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	coll, ok := s.collections[s.resolve(collection)]
+	if !ok {
+		return nil
+	}
+	for _, id := range ids {
+		delete(coll, id)
+	}
+	return nil
+}
+
+// SetPayload sets the given metadata keys on existing points. IDs that do not exist are ignored.
+func (s *MemoryStore) SetPayload(ctx context.Context, collection string, ids []string, payload map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	coll, ok := s.collections[s.resolve(collection)]
+	if !ok {
+		return nil
+	}
+	for _, id := range ids {
+		p, ok := coll[id]
+		if !ok {
+			continue
+		}
+		meta := make(map[string]any, len(p.Meta)+len(payload))
+		for k, v := range p.Meta {
+			meta[k] = v
+		}
+		for k, v := range payload {
+			meta[k] = v
+		}
+		p.Meta = meta
+		coll[id] = p
+	}
+	return nil
+}
+
+// Retrieve returns points by their IDs. IDs that do not exist are omitted.
+func (s *MemoryStore) Retrieve(ctx context.Context, collection string, ids []string) ([]Point, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	coll := s.collections[s.resolve(collection)]
+	points := make([]Point, 0, len(ids))
+	for _, id := range ids {
+		p, ok := coll[id]
+		if !ok {
+			continue
+		}
+		vec := make([]float32, len(p.Vec))
+		copy(vec, p.Vec)
+		points = append(points, Point{ID: p.ID, Vec: vec, Meta: copyMeta(p.Meta)})
+	}
+	return points, nil
+}
+
+// CollectionExists checks if a collection exists.
+func (s *MemoryStore) CollectionExists(ctx context.Context, collection string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.collections[s.resolve(collection)]
+	return ok, nil
+}
+
+// GetCollectionInfo returns the point count of a collection. The vector size is taken from
+// the stored vectors since EnsureCollection does not record it.
+func (s *MemoryStore) GetCollectionInfo(ctx context.Context, collection string) (*CollectionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points, ok := s.collections[s.resolve(collection)]
+	if !ok {
+		return nil, fmt.Errorf("failed to get collection info: collection %s not found", collection)
+	}
+
+	info := &CollectionInfo{PointsCount: len(points), Status: "green"}
+	for _, point := range points {
+		info.VectorSize = len(point.Vec)
+		break
+	}
+	return info, nil
+}
+
+// DeleteCollection removes a collection and all of its points. Missing collections are ignored.
+func (s *MemoryStore) DeleteCollection(ctx context.Context, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.collections, collection)
+	return nil
+}
+
+// AliasTarget returns the collection an alias points to, or "" if no such alias exists.
+func (s *MemoryStore) AliasTarget(ctx context.Context, alias string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.aliases[alias], nil
+}
+
+// SwitchAlias points alias at collection, replacing any previous target.
+// Like Qdrant, an alias cannot share its name with a collection.
+func (s *MemoryStore) SwitchAlias(ctx context.Context, alias, collection string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[alias]; ok {
+		return fmt.Errorf("failed to switch alias: collection %s already exists", alias)
+	}
+	if _, ok := s.collections[collection]; !ok {
+		return fmt.Errorf("failed to switch alias: collection %s not found", collection)
+	}
+	s.aliases[alias] = collection
+	return nil
+}
+
+// resolve returns the collection an alias points to, or name itself if it is not an alias.
+// Callers must hold s.mu.
+func (s *MemoryStore) resolve(name string) string {
+	if target, ok := s.aliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// matchesFilters applies the vault_id (exact) and folder (prefix, empty means root) filters.
+func matchesFilters(meta map[string]any, filters map[string]any) bool {
+	if vaultID, ok := filters["vault_id"]; ok {
+		want, ok := toInt64(vaultID)
+		if ok && want != 0 {
+			got, _ := toInt64(meta["vault_id"])
+			if got != want {
+				return false
+			}
+		}
+	}
+
+	if folder, ok := filters["folder"]; ok {
+		want := fmt.Sprintf("%v", folder)
+		got := fmt.Sprintf("%v", meta["folder"])
+		if want == "" {
+			if got != "" {
+				return false
+			}
+		} else if !strings.HasPrefix(got, want) {
+			return false
+		}
+	}
+
+	if noteIDs, ok := filters["note_id"].([]string); ok && len(noteIDs) > 0 {
+		got := fmt.Sprintf("%v", meta["note_id"])
+		if !slices.Contains(noteIDs, got) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeMeta copies metadata, converting integers to int64 as Qdrant does on read.
+func normalizeMeta(meta map[string]any) map[string]any {
+	result := make(map[string]any, len(meta))
+	for k, v := range meta {
+		if n, ok := toInt64(v); ok {
+			if _, isString := v.(string); !isString {
+				result[k] = n
+				continue
+			}
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// copyMeta returns a shallow copy of metadata so callers cannot mutate stored points.
+func copyMeta(meta map[string]any) map[string]any {
+	result := make(map[string]any, len(meta))
+	for k, v := range meta {
+		result[k] = v
+	}
+	return result
+}
+
+// toInt64 converts integer-like values (including numeric strings) to int64.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case string:
+		var parsed int64
+		if _, err := fmt.Sscanf(n, "%d", &parsed); err == nil {
+			return parsed, true
+		}
+	}
+	return 0, false
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if either is empty or zero.
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
@@ -0,0 +1,113 @@
+package vectorstore
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryStore_SearchWithFilters(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.EnsureCollection(ctx, "notes", 2); err != nil {
+		t.Fatalf("EnsureCollection() error = %v", err)
+	}
+	points := []Point{
+		{ID: "a", Vec: []float32{1, 0}, Meta: map[string]any{"vault_id": 1, "folder": "projects/go"}},
+		{ID: "b", Vec: []float32{0.9, 0.1}, Meta: map[string]any{"vault_id": 2, "folder": "projects"}},
+		{ID: "c", Vec: []float32{0, 1}, Meta: map[string]any{"vault_id": 1, "folder": ""}},
+	}
+	if err := store.Upsert(ctx, "notes", points); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filters map[string]any
+		wantIDs []string
+	}{
+		{name: "no filters ranks by similarity", filters: nil, wantIDs: []string{"a", "b", "c"}},
+		{name: "vault filter", filters: map[string]any{"vault_id": 1}, wantIDs: []string{"a", "c"}},
+		{name: "folder prefix filter", filters: map[string]any{"folder": "projects"}, wantIDs: []string{"a", "b"}},
+		{name: "empty folder matches root only", filters: map[string]any{"folder": ""}, wantIDs: []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := store.Search(ctx, "notes", []float32{1, 0}, 10, tt.filters)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != len(tt.wantIDs) {
+				t.Fatalf("Search() returned %d results, want %d", len(results), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if results[i].PointID != id {
+					t.Errorf("Search()[%d] = %s, want %s", i, results[i].PointID, id)
+				}
+			}
+		})
+	}
+
+	// Stored integers come back as int64, matching Qdrant payloads
+	if _, ok := points[0].Meta["vault_id"].(int); !ok {
+		t.Error("Upsert() should not mutate caller metadata")
+	}
+	retrieved, err := store.Retrieve(ctx, "notes", []string{"a", "missing"})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(retrieved) != 1 {
+		t.Fatalf("Retrieve() returned %d points, want 1", len(retrieved))
+	}
+	if _, ok := retrieved[0].Meta["vault_id"].(int64); !ok {
+		t.Errorf("Retrieve() vault_id type = %T, want int64", retrieved[0].Meta["vault_id"])
+	}
+
+	if err := store.Delete(ctx, "notes", []string{"a"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Search(ctx, "missing", []float32{1, 0}, 1, nil); err == nil {
+		t.Error("Search() expected error for missing collection")
+	}
+}
+
+func TestMemoryStore_Aliases(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for _, collection := range []string{"notes_1", "notes_2"} {
+		if err := store.Upsert(ctx, collection, []Point{{ID: collection, Vec: []float32{1, 0}}}); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	if err := store.SwitchAlias(ctx, "notes", "notes_1"); err != nil {
+		t.Fatalf("SwitchAlias() error = %v", err)
+	}
+	results, err := store.Search(ctx, "notes", []float32{1, 0}, 10, nil)
+	if err != nil || len(results) != 1 || results[0].PointID != "notes_1" {
+		t.Fatalf("Search(alias) = %v, %v, want notes_1", results, err)
+	}
+
+	if err := store.SwitchAlias(ctx, "notes", "notes_2"); err != nil {
+		t.Fatalf("SwitchAlias() error = %v", err)
+	}
+	if target, _ := store.AliasTarget(ctx, "notes"); target != "notes_2" {
+		t.Errorf("AliasTarget() = %q, want notes_2", target)
+	}
+	results, err = store.Search(ctx, "notes", []float32{1, 0}, 10, nil)
+	if err != nil || len(results) != 1 || results[0].PointID != "notes_2" {
+		t.Errorf("Search(alias) after switch = %v, %v, want notes_2", results, err)
+	}
+
+	if err := store.SwitchAlias(ctx, "notes_1", "notes_2"); err == nil {
+		t.Error("SwitchAlias() expected error when a collection has the alias name")
+	}
+	if err := store.DeleteCollection(ctx, "notes_1"); err != nil {
+		t.Fatalf("DeleteCollection() error = %v", err)
+	}
+	if exists, _ := store.CollectionExists(ctx, "notes_1"); exists {
+		t.Error("CollectionExists() = true after DeleteCollection")
+	}
+}
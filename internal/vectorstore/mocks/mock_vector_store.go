@@ -70,6 +70,21 @@ func (mr *MockVectorStoreMockRecorder) Delete(ctx, collection, ids any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockVectorStore)(nil).Delete), ctx, collection, ids)
 }
 
+// Retrieve mocks base method.
+func (m *MockVectorStore) Retrieve(ctx context.Context, collection string, ids []string) ([]vectorstore.Point, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Retrieve", ctx, collection, ids)
+	ret0, _ := ret[0].([]vectorstore.Point)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Retrieve indicates an expected call of Retrieve.
+func (mr *MockVectorStoreMockRecorder) Retrieve(ctx, collection, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retrieve", reflect.TypeOf((*MockVectorStore)(nil).Retrieve), ctx, collection, ids)
+}
+
 // Search mocks base method.
 func (m *MockVectorStore) Search(ctx context.Context, collection string, query []float32, k int, filters map[string]any) ([]vectorstore.SearchResult, error) {
 	m.ctrl.T.Helper()
@@ -85,6 +100,20 @@ func (mr *MockVectorStoreMockRecorder) Search(ctx, collection, query, k, filters
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockVectorStore)(nil).Search), ctx, collection, query, k, filters)
 }
 
+// SetPayload mocks base method.
+func (m *MockVectorStore) SetPayload(ctx context.Context, collection string, ids []string, payload map[string]any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPayload", ctx, collection, ids, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPayload indicates an expected call of SetPayload.
+func (mr *MockVectorStoreMockRecorder) SetPayload(ctx, collection, ids, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPayload", reflect.TypeOf((*MockVectorStore)(nil).SetPayload), ctx, collection, ids, payload)
+}
+
 // Upsert mocks base method.
 func (m *MockVectorStore) Upsert(ctx context.Context, collection string, points []vectorstore.Point) error {
 	m.ctrl.T.Helper()
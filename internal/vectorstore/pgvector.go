@@ -0,0 +1,587 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"helloworld-ai/internal/contextutil"
+)
+
+// pgvectorDriver is the database/sql driver PgvectorStore connects with. It is registered
+// by the pgx stdlib package, which is linked in with the pgvector build tag
+// (pgvector_driver.go) so default builds do not depend on it.
+const pgvectorDriver = "pgx"
+
+// validCollectionName limits collection names to what can be embedded in a table name.
+var validCollectionName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// PgvectorStore implements VectorStore on PostgreSQL with the pgvector extension. Each
+// collection is a vectors_<collection> table with an HNSW index for the store's metric;
+// the vector_collections and vector_aliases tables record collection sizes and aliases.
+type PgvectorStore struct {
+	db *sql.DB
+	// upsertBatchSize is the maximum number of points written per statement.
+	upsertBatchSize int
+	// distance is the metric collections are indexed and searched with.
+	distance string
+}
+
+// NewPgvectorStore connects to PostgreSQL and creates the pgvector extension and the
+// collection and alias tables if needed.
+// upsertBatchSize caps the points per upsert statement (<= 0 uses DefaultUpsertBatchSize).
+// distance is one of the Distance* metrics (empty uses DistanceCosine).
+func NewPgvectorStore(dsn string, upsertBatchSize int, distance string) (*PgvectorStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("pgvector connection string is required")
+	}
+	distance, err := ParseDistance(distance)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(sql.Drivers(), pgvectorDriver) {
+		return nil, fmt.Errorf("pgvector support is not built in (rebuild with -tags pgvector)")
+	}
+
+	db, err := sql.Open(pgvectorDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	for _, stmt := range []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		`CREATE TABLE IF NOT EXISTS vector_collections (
+			name TEXT PRIMARY KEY,
+			vector_size INTEGER NOT NULL,
+			distance TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS vector_aliases (
+			alias TEXT PRIMARY KEY,
+			collection TEXT NOT NULL REFERENCES vector_collections(name)
+		)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to prepare pgvector schema: %w", err)
+		}
+	}
+
+	if upsertBatchSize <= 0 {
+		upsertBatchSize = DefaultUpsertBatchSize
+	}
+	return &PgvectorStore{
+		db:              db,
+		upsertBatchSize: upsertBatchSize,
+		distance:        distance,
+	}, nil
+}
+
+// Close closes the PostgreSQL connection pool.
+func (s *PgvectorStore) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close PostgreSQL connection: %w", err)
+	}
+	return nil
+}
+
+// Upsert inserts or updates points in the collection, in batches of upsertBatchSize with
+// the same retries and partial-failure reporting as QdrantStore.
+func (s *PgvectorStore) Upsert(ctx context.Context, collection string, points []Point) error {
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return err
+	}
+	return upsertInBatches(ctx, points, s.upsertBatchSize, func(ctx context.Context, batch []Point) error {
+		return s.upsertBatch(ctx, table, batch)
+	})
+}
+
+// upsertBatch writes one batch of points in a single statement.
+func (s *PgvectorStore) upsertBatch(ctx context.Context, table string, points []Point) error {
+	values := make([]string, 0, len(points))
+	args := make([]any, 0, 3*len(points))
+	for _, p := range points {
+		payload, err := json.Marshal(p.Meta)
+		if err != nil {
+			return fmt.Errorf("failed to encode payload of point %s: %w", p.ID, err)
+		}
+		vec := p.Vec
+		if normalizesVectors(s.distance) {
+			vec = normalizeVector(vec)
+		}
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d::vector, $%d::jsonb)", n+1, n+2, n+3))
+		args = append(args, p.ID, vectorLiteral(vec), string(payload))
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, embedding, payload) VALUES %s
+		ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding, payload = EXCLUDED.payload`,
+		table, strings.Join(values, ", "))
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to upsert points: %w", err)
+	}
+	return nil
+}
+
+// Search returns the k points nearest to query that match filters, with the same filter
+// semantics and higher-is-better scores as QdrantStore.
+func (s *PgvectorStore) Search(ctx context.Context, collection string, query []float32, k int, filters map[string]any) ([]SearchResult, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be greater than 0")
+	}
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if normalizesVectors(s.distance) {
+		query = normalizeVector(query)
+	}
+	where, args := pgvectorFilter(filters, []any{vectorLiteral(query)})
+	args = append(args, k)
+	sqlQuery := fmt.Sprintf(`SELECT id, embedding %s $1::vector AS distance, payload FROM %s%s ORDER BY distance LIMIT $%d`,
+		pgvectorOperator(s.distance), table, where, len(args))
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to search points", "collection", collection, "k", k, "error", err)
+		return nil, fmt.Errorf("failed to search points: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	results := make([]SearchResult, 0, k)
+	for rows.Next() {
+		var id, payload string
+		var distance float64
+		if err := rows.Scan(&id, &distance, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		meta, err := decodePayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, SearchResult{
+			PointID: id,
+			Score:   pgvectorScore(s.distance, float32(distance)),
+			Meta:    meta,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search points: %w", err)
+	}
+
+	logger.InfoContext(ctx, "search completed", "collection", collection, "k", k, "results", len(results))
+	return results, nil
+}
+
+// Delete removes points by their IDs. Missing IDs are ignored.
+func (s *PgvectorStore) Delete(ctx context.Context, collection string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	in, args := placeholders(ids, nil)
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, table, in), args...); err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
+
+// SetPayload merges the given metadata keys into existing points without touching their
+// vectors. IDs that do not exist are ignored.
+func (s *PgvectorStore) SetPayload(ctx context.Context, collection string, ids []string, payload map[string]any) error {
+	if len(ids) == 0 || len(payload) == 0 {
+		return nil
+	}
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	in, args := placeholders(ids, []any{string(encoded)})
+	query := fmt.Sprintf(`UPDATE %s SET payload = payload || $1::jsonb WHERE id IN (%s)`, table, in)
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to set payload: %w", err)
+	}
+	return nil
+}
+
+// Retrieve returns points (vectors and metadata) by their IDs. IDs that do not exist are
+// omitted.
+func (s *PgvectorStore) Retrieve(ctx context.Context, collection string, ids []string) ([]Point, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	in, args := placeholders(ids, nil)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, embedding::text, payload FROM %s WHERE id IN (%s)`, table, in), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve points: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	points := make([]Point, 0, len(ids))
+	for rows.Next() {
+		var id, embedding, payload string
+		if err := rows.Scan(&id, &embedding, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan point: %w", err)
+		}
+		vec, err := parseVector(embedding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vector of point %s: %w", id, err)
+		}
+		meta, err := decodePayload(payload)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, Point{ID: id, Vec: vec, Meta: meta})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve points: %w", err)
+	}
+	return points, nil
+}
+
+// CollectionExists reports whether a collection, or an alias of one, exists.
+func (s *PgvectorStore) CollectionExists(ctx context.Context, collection string) (bool, error) {
+	if _, _, err := s.collectionConfig(ctx, collection); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	return true, nil
+}
+
+// EnsureCollection creates the collection's table and index if needed. An existing
+// collection (or the collection behind an alias) must have the same vector size and metric.
+func (s *PgvectorStore) EnsureCollection(ctx context.Context, collection string, vectorSize int) error {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	size, distance, err := s.collectionConfig(ctx, collection)
+	switch {
+	case err == nil:
+		if size != vectorSize {
+			return fmt.Errorf("collection vector size mismatch: expected %d, got %d", vectorSize, size)
+		}
+		if distance != s.distance {
+			return fmt.Errorf("collection distance mismatch: expected %s, got %s (recreate the collection to change the metric)", s.distance, distance)
+		}
+		logger.InfoContext(ctx, "collection validated", "collection", collection, "vector_size", vectorSize, "distance", s.distance)
+		return nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	if !validCollectionName.MatchString(collection) {
+		return fmt.Errorf("invalid collection name %q (letters, digits, and underscores only)", collection)
+	}
+	logger.InfoContext(ctx, "creating collection", "collection", collection, "vector_size", vectorSize, "distance", s.distance)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	table := pgvectorTable(collection)
+	for _, stmt := range []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, embedding vector(%d) NOT NULL, payload JSONB NOT NULL)`, table, vectorSize),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw (embedding %s)`,
+			quoteIdentifier("vectors_"+collection+"_embedding_idx"), table, pgvectorOpsClass(s.distance)),
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO vector_collections (name, vector_size, distance) VALUES ($1, $2, $3)`,
+		collection, vectorSize, s.distance); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	logger.InfoContext(ctx, "collection created", "collection", collection, "vector_size", vectorSize)
+	return nil
+}
+
+// GetCollectionInfo returns the vector size and point count of a collection. An alias
+// reports the collection it points to.
+func (s *PgvectorStore) GetCollectionInfo(ctx context.Context, collection string) (*CollectionInfo, error) {
+	size, _, err := s.collectionConfig(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	table, err := s.table(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT count(*) FROM %s`, table)).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to get collection info: %w", err)
+	}
+	return &CollectionInfo{VectorSize: size, PointsCount: count, Status: "green"}, nil
+}
+
+// DeleteCollection removes a collection, its points, and any aliases pointing to it.
+// Missing collections are ignored.
+func (s *PgvectorStore) DeleteCollection(ctx context.Context, collection string) error {
+	if !validCollectionName.MatchString(collection) {
+		return fmt.Errorf("invalid collection name %q", collection)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	for _, stmt := range []string{
+		`DELETE FROM vector_aliases WHERE collection = $1`,
+		`DELETE FROM vector_collections WHERE name = $1`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, collection); err != nil {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, pgvectorTable(collection))); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// AliasTarget returns the collection an alias points to, or "" if no such alias exists.
+func (s *PgvectorStore) AliasTarget(ctx context.Context, alias string) (string, error) {
+	var target string
+	err := s.db.QueryRowContext(ctx, `SELECT collection FROM vector_aliases WHERE alias = $1`, alias).Scan(&target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list aliases: %w", err)
+	}
+	return target, nil
+}
+
+// SwitchAlias atomically points alias at collection, replacing any previous target.
+// Like Qdrant, an alias cannot share its name with a collection.
+func (s *PgvectorStore) SwitchAlias(ctx context.Context, alias, collection string) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM vector_collections WHERE name = $1)`, alias).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to switch alias: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("failed to switch alias: collection %s already exists", alias)
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO vector_aliases (alias, collection) VALUES ($1, $2)
+		ON CONFLICT (alias) DO UPDATE SET collection = EXCLUDED.collection`, alias, collection)
+	if err != nil {
+		return fmt.Errorf("failed to switch alias: %w", err)
+	}
+	return nil
+}
+
+// collectionConfig returns the vector size and metric of a collection or of the collection
+// an alias points to. A missing collection returns sql.ErrNoRows.
+func (s *PgvectorStore) collectionConfig(ctx context.Context, collection string) (int, string, error) {
+	var size int
+	var distance string
+	err := s.db.QueryRowContext(ctx, `SELECT vector_size, distance FROM vector_collections
+		WHERE name = COALESCE((SELECT collection FROM vector_aliases WHERE alias = $1), $1)`, collection).Scan(&size, &distance)
+	return size, distance, err
+}
+
+// table returns the quoted table name of a collection, resolving aliases.
+func (s *PgvectorStore) table(ctx context.Context, collection string) (string, error) {
+	target, err := s.AliasTarget(ctx, collection)
+	if err != nil {
+		return "", err
+	}
+	if target != "" {
+		collection = target
+	}
+	if !validCollectionName.MatchString(collection) {
+		return "", fmt.Errorf("invalid collection name %q", collection)
+	}
+	return pgvectorTable(collection), nil
+}
+
+// pgvectorTable returns the quoted name of a collection's table.
+func pgvectorTable(collection string) string {
+	return quoteIdentifier("vectors_" + collection)
+}
+
+// quoteIdentifier quotes a PostgreSQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// pgvectorOperator returns the pgvector distance operator for a metric.
+func pgvectorOperator(distance string) string {
+	switch distance {
+	case DistanceDot:
+		return "<#>"
+	case DistanceEuclid:
+		return "<->"
+	default:
+		return "<=>"
+	}
+}
+
+// pgvectorOpsClass returns the HNSW operator class that indexes a metric's operator.
+func pgvectorOpsClass(distance string) string {
+	switch distance {
+	case DistanceDot:
+		return "vector_ip_ops"
+	case DistanceEuclid:
+		return "vector_l2_ops"
+	default:
+		return "vector_cosine_ops"
+	}
+}
+
+// pgvectorScore turns a pgvector distance into the similarity QdrantStore would report:
+// cosine distance is 1 - similarity, <#> is the negated inner product, and euclid
+// distances of unit vectors convert like Qdrant's.
+func pgvectorScore(distance string, d float32) float32 {
+	switch distance {
+	case DistanceDot:
+		return -d
+	case DistanceEuclid:
+		return similarityScore(DistanceEuclid, d)
+	default:
+		return 1 - d
+	}
+}
+
+// pgvectorFilter builds a WHERE clause for the vault_id (exact), folder (prefix, empty
+// means root), and note_id (any of) filters, appending its arguments to args.
+func pgvectorFilter(filters map[string]any, args []any) (string, []any) {
+	var conditions []string
+	if vaultID, ok := filters["vault_id"]; ok {
+		if id, ok := toInt64(vaultID); ok && id != 0 {
+			args = append(args, id)
+			conditions = append(conditions, fmt.Sprintf("(payload->>'vault_id')::bigint = $%d", len(args)))
+		}
+	}
+	if folder, ok := filters["folder"]; ok {
+		if folderStr := fmt.Sprintf("%v", folder); folderStr == "" {
+			conditions = append(conditions, "COALESCE(payload->>'folder', '') = ''")
+		} else {
+			args = append(args, escapeLike(folderStr)+"%")
+			conditions = append(conditions, fmt.Sprintf(`payload->>'folder' LIKE $%d ESCAPE '\'`, len(args)))
+		}
+	}
+	if noteIDs, ok := filters["note_id"].([]string); ok && len(noteIDs) > 0 {
+		var in string
+		in, args = placeholders(noteIDs, args)
+		conditions = append(conditions, fmt.Sprintf("payload->>'note_id' IN (%s)", in))
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// placeholders appends values to args and returns their comma-separated placeholders.
+func placeholders(values []string, args []any) (string, []any) {
+	marks := make([]string, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+		marks = append(marks, "$"+strconv.Itoa(len(args)))
+	}
+	return strings.Join(marks, ", "), args
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// vectorLiteral formats a vector in pgvector's text format, e.g. "[0.1,0.2]".
+func vectorLiteral(vec []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vec {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// parseVector parses a vector in pgvector's text format.
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return nil, fmt.Errorf("invalid vector %q", s)
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		return []float32{}, nil
+	}
+	parts := strings.Split(s, ",")
+	vec := make([]float32, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", part, err)
+		}
+		vec = append(vec, float32(v))
+	}
+	return vec, nil
+}
+
+// decodePayload decodes a JSONB payload, returning whole numbers as int64 like QdrantStore
+// so callers see the same types from every backend.
+func decodePayload(payload string) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(payload)))
+	decoder.UseNumber()
+	meta := make(map[string]any)
+	if err := decoder.Decode(&meta); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	for k, v := range meta {
+		number, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		if n, err := number.Int64(); err == nil {
+			meta[k] = n
+		} else if f, err := number.Float64(); err == nil {
+			meta[k] = f
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,7 @@
+//go:build pgvector
+
+package vectorstore
+
+// The pgx database/sql driver is only linked into builds that can use the pgvector backend:
+// go build -tags pgvector ./...
+import _ "github.com/jackc/pgx/v5/stdlib"
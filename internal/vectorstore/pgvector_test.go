@@ -0,0 +1,98 @@
+package vectorstore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVectorLiteral(t *testing.T) {
+	vec := []float32{0.25, -1, 3.5e-7}
+	literal := vectorLiteral(vec)
+	if literal != "[0.25,-1,3.5e-07]" {
+		t.Errorf("vectorLiteral() = %q", literal)
+	}
+	parsed, err := parseVector(literal)
+	if err != nil {
+		t.Fatalf("parseVector() error = %v", err)
+	}
+	if !reflect.DeepEqual(parsed, vec) {
+		t.Errorf("parseVector(vectorLiteral(%v)) = %v", vec, parsed)
+	}
+
+	for _, invalid := range []string{"", "0.1,0.2", "[0.1,x]"} {
+		if _, err := parseVector(invalid); err == nil {
+			t.Errorf("parseVector(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestPgvectorFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filters   map[string]any
+		wantWhere string
+		wantArgs  []any
+	}{
+		{name: "no filters", wantArgs: []any{"q"}},
+		{
+			name:      "vault and folder prefix",
+			filters:   map[string]any{"vault_id": 2, "folder": "projects/100%_done"},
+			wantWhere: ` WHERE (payload->>'vault_id')::bigint = $2 AND payload->>'folder' LIKE $3 ESCAPE '\'`,
+			wantArgs:  []any{"q", int64(2), `projects/100\%\_done%`},
+		},
+		{
+			name:      "root folder and notes",
+			filters:   map[string]any{"vault_id": "0", "folder": "", "note_id": []string{"n1", "n2"}},
+			wantWhere: ` WHERE COALESCE(payload->>'folder', '') = '' AND payload->>'note_id' IN ($2, $3)`,
+			wantArgs:  []any{"q", "n1", "n2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := pgvectorFilter(tt.filters, []any{"q"})
+			if where != tt.wantWhere {
+				t.Errorf("pgvectorFilter() where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("pgvectorFilter() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPgvectorScore(t *testing.T) {
+	// Every metric reports the cosine similarity of unit vectors 60 degrees apart as 0.5
+	tests := []struct {
+		distance string
+		d        float32
+	}{
+		{distance: DistanceCosine, d: 0.5},
+		{distance: DistanceDot, d: -0.5},
+		{distance: DistanceEuclid, d: 1},
+	}
+	for _, tt := range tests {
+		if got := pgvectorScore(tt.distance, tt.d); got != 0.5 {
+			t.Errorf("pgvectorScore(%s, %v) = %v, want 0.5", tt.distance, tt.d, got)
+		}
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	meta, err := decodePayload(`{"vault_id": 3, "folder": "projects", "score": 0.5, "tags": ["go"]}`)
+	if err != nil {
+		t.Fatalf("decodePayload() error = %v", err)
+	}
+	want := map[string]any{"vault_id": int64(3), "folder": "projects", "score": 0.5, "tags": []any{"go"}}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("decodePayload() = %#v, want %#v", meta, want)
+	}
+}
+
+func TestNewPgvectorStore_WithoutDriver(t *testing.T) {
+	if _, err := NewPgvectorStore("postgres://localhost/notes", 0, ""); err == nil {
+		t.Error("NewPgvectorStore() expected error when the pgx driver is not built in")
+	}
+	if _, err := NewPgvectorStore("postgres://localhost/notes", 0, "manhattan"); err == nil {
+		t.Error("NewPgvectorStore() expected error for an invalid distance")
+	}
+}
@@ -8,23 +8,35 @@ import (
 	"strings"
 
 	"github.com/qdrant/go-client/qdrant"
+	"go.opentelemetry.io/otel/attribute"
 
 	"helloworld-ai/internal/contextutil"
+	"helloworld-ai/internal/tracing"
 )
 
 // QdrantStore implements VectorStore using Qdrant.
 type QdrantStore struct {
 	client *qdrant.Client
+	// upsertBatchSize is the maximum number of points sent per upsert request.
+	upsertBatchSize int
+	// distance is the metric collections are created with and validated against.
+	distance string
 }
 
 // NewQdrantStore creates a new Qdrant vector store client.
 // urlStr should be in the format "http://host:port" (e.g., "http://localhost:6333").
 // The gRPC port (typically 6334) will be derived from the HTTP port.
-func NewQdrantStore(urlStr string) (*QdrantStore, error) {
+// upsertBatchSize caps the points per upsert request (<= 0 uses DefaultUpsertBatchSize).
+// distance is one of the Distance* metrics (empty uses DistanceCosine).
+func NewQdrantStore(urlStr string, upsertBatchSize int, distance string) (*QdrantStore, error) {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Qdrant URL: %w", err)
 	}
+	distance, err = ParseDistance(distance)
+	if err != nil {
+		return nil, err
+	}
 
 	host := parsedURL.Hostname()
 	if host == "" {
@@ -49,12 +61,28 @@ func NewQdrantStore(urlStr string) (*QdrantStore, error) {
 		return nil, fmt.Errorf("failed to create Qdrant client: %w", err)
 	}
 
+	if upsertBatchSize <= 0 {
+		upsertBatchSize = DefaultUpsertBatchSize
+	}
+
 	return &QdrantStore{
-		client: client,
+		client:          client,
+		upsertBatchSize: upsertBatchSize,
+		distance:        distance,
 	}, nil
 }
 
+// Close closes the connection to Qdrant.
+func (s *QdrantStore) Close() error {
+	if err := s.client.Close(); err != nil {
+		return fmt.Errorf("failed to close Qdrant client: %w", err)
+	}
+	return nil
+}
+
 // Upsert inserts or updates points in the collection.
+// Points are sent in batches of upsertBatchSize, and each failed batch is retried. Batches
+// that still fail are returned as an *UpsertBatchError; the other batches are written.
 func (s *QdrantStore) Upsert(ctx context.Context, collection string, points []Point) error {
 	logger := contextutil.LoggerFromContext(ctx)
 
@@ -62,11 +90,29 @@ func (s *QdrantStore) Upsert(ctx context.Context, collection string, points []Po
 		return nil
 	}
 
+	err := upsertInBatches(ctx, points, s.upsertBatchSize, func(ctx context.Context, batch []Point) error {
+		return s.upsertBatch(ctx, collection, batch)
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to upsert points", "collection", collection, "count", len(points), "error", err)
+		return fmt.Errorf("failed to upsert points: %w", err)
+	}
+
+	logger.InfoContext(ctx, "upserted points", "collection", collection, "count", len(points))
+	return nil
+}
+
+// upsertBatch sends one upsert request.
+func (s *QdrantStore) upsertBatch(ctx context.Context, collection string, points []Point) error {
 	qdrantPoints := make([]*qdrant.PointStruct, 0, len(points))
 	for _, point := range points {
+		vec := point.Vec
+		if normalizesVectors(s.distance) {
+			vec = normalizeVector(vec)
+		}
 		qdrantPoint := &qdrant.PointStruct{
 			Id:      qdrant.NewID(point.ID),
-			Vectors: qdrant.NewVectors(point.Vec...),
+			Vectors: qdrant.NewVectors(vec...),
 		}
 
 		if len(point.Meta) > 0 {
@@ -80,17 +126,26 @@ func (s *QdrantStore) Upsert(ctx context.Context, collection string, points []Po
 		CollectionName: collection,
 		Points:         qdrantPoints,
 	})
-	if err != nil {
-		logger.ErrorContext(ctx, "failed to upsert points", "collection", collection, "count", len(points), "error", err)
-		return fmt.Errorf("failed to upsert points: %w", err)
-	}
-
-	logger.InfoContext(ctx, "upserted points", "collection", collection, "count", len(points))
-	return nil
+	return err
 }
 
 // Search performs a similarity search with optional filters.
 func (s *QdrantStore) Search(ctx context.Context, collection string, query []float32, k int, filters map[string]any) ([]SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "qdrant.search",
+		attribute.String("db.collection.name", collection),
+		attribute.Int("qdrant.k", k),
+		attribute.Int("qdrant.filters", len(filters)),
+	)
+	defer span.End()
+
+	results, err := s.search(ctx, collection, query, k, filters)
+	span.RecordError(err)
+	span.SetAttributes(attribute.Int("qdrant.results", len(results)))
+	return results, err
+}
+
+// search builds the Qdrant filter and query for Search and converts the results.
+func (s *QdrantStore) search(ctx context.Context, collection string, query []float32, k int, filters map[string]any) ([]SearchResult, error) {
 	logger := contextutil.LoggerFromContext(ctx)
 
 	if k <= 0 {
@@ -148,6 +203,11 @@ func (s *QdrantStore) Search(ctx context.Context, collection string, query []flo
 			}
 		}
 
+		// Handle note_id filter (match any of the given note IDs)
+		if noteIDs, ok := filters["note_id"].([]string); ok && len(noteIDs) > 0 {
+			mustConditions = append(mustConditions, qdrant.NewMatchKeywords("note_id", noteIDs...))
+		}
+
 		if len(mustConditions) > 0 {
 			qdrantFilter = &qdrant.Filter{
 				Must: mustConditions,
@@ -155,6 +215,9 @@ func (s *QdrantStore) Search(ctx context.Context, collection string, query []flo
 		}
 	}
 
+	if normalizesVectors(s.distance) {
+		query = normalizeVector(query)
+	}
 	limit := uint64(k)
 	queryReq := &qdrant.QueryPoints{
 		CollectionName: collection,
@@ -182,7 +245,7 @@ func (s *QdrantStore) Search(ctx context.Context, collection string, query []flo
 			pointID = strings.ReplaceAll(uuidStr, "-", "")
 		}
 
-		score := result.Score
+		score := similarityScore(s.distance, result.Score)
 
 		meta := make(map[string]any)
 		if result.Payload != nil {
@@ -226,6 +289,91 @@ func (s *QdrantStore) Delete(ctx context.Context, collection string, ids []strin
 	return nil
 }
 
+// SetPayload sets the given metadata keys on existing points without touching their vectors.
+func (s *QdrantStore) SetPayload(ctx context.Context, collection string, ids []string, payload map[string]any) error {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if len(ids) == 0 || len(payload) == 0 {
+		return nil
+	}
+
+	qdrantIDs := make([]*qdrant.PointId, 0, len(ids))
+	for _, id := range ids {
+		qdrantIDs = append(qdrantIDs, qdrant.NewID(id))
+	}
+
+	_, err := s.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: collection,
+		Payload:        qdrant.NewValueMap(payload),
+		PointsSelector: qdrant.NewPointsSelector(qdrantIDs...),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to set payload", "collection", collection, "count", len(ids), "error", err)
+		return fmt.Errorf("failed to set payload: %w", err)
+	}
+
+	logger.DebugContext(ctx, "updated point payloads", "collection", collection, "count", len(ids))
+	return nil
+}
+
+// Retrieve returns points (vectors and metadata) by their IDs.
+func (s *QdrantStore) Retrieve(ctx context.Context, collection string, ids []string) ([]Point, error) {
+	logger := contextutil.LoggerFromContext(ctx)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	qdrantIDs := make([]*qdrant.PointId, 0, len(ids))
+	for _, id := range ids {
+		qdrantIDs = append(qdrantIDs, qdrant.NewID(id))
+	}
+
+	retrieved, err := s.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: collection,
+		Ids:            qdrantIDs,
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to retrieve points", "collection", collection, "count", len(ids), "error", err)
+		return nil, fmt.Errorf("failed to retrieve points: %w", err)
+	}
+
+	points := make([]Point, 0, len(retrieved))
+	for _, rp := range retrieved {
+		pointID := ""
+		if rp.Id != nil {
+			// Normalize UUIDs to the 32 hex char format used in SQLite
+			pointID = strings.ReplaceAll(rp.Id.GetUuid(), "-", "")
+		}
+
+		var vec []float32
+		if output := rp.Vectors.GetVector(); output != nil {
+			if dense := output.GetDense(); dense != nil {
+				vec = dense.GetData()
+			} else {
+				// Older Qdrant servers only populate the legacy data field
+				vec = output.Data
+			}
+		}
+
+		meta := make(map[string]any)
+		if rp.Payload != nil {
+			meta = convertPayloadToMap(rp.Payload)
+		}
+
+		points = append(points, Point{
+			ID:   pointID,
+			Vec:  vec,
+			Meta: meta,
+		})
+	}
+
+	logger.DebugContext(ctx, "retrieved points", "collection", collection, "requested", len(ids), "found", len(points))
+	return points, nil
+}
+
 // CollectionExists checks if a collection exists.
 func (s *QdrantStore) CollectionExists(ctx context.Context, collection string) (bool, error) {
 	exists, err := s.client.CollectionExists(ctx, collection)
@@ -236,23 +384,32 @@ func (s *QdrantStore) CollectionExists(ctx context.Context, collection string) (
 }
 
 // EnsureCollection ensures a collection exists with the specified vector size.
-// If the collection exists, validates that the vector size matches.
-// If it doesn't exist, creates it with the specified vector size.
+// If the collection exists, validates that the vector size and distance metric match.
+// If it doesn't exist, creates it with the specified vector size and the store's metric.
 func (s *QdrantStore) EnsureCollection(ctx context.Context, collection string, vectorSize int) error {
 	logger := contextutil.LoggerFromContext(ctx)
 
+	// After a blue/green rebuild the configured name is an alias; validate the collection behind it
+	target, err := s.AliasTarget(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if target != "" {
+		collection = target
+	}
+
 	exists, err := s.CollectionExists(ctx, collection)
 	if err != nil {
 		return fmt.Errorf("failed to check collection existence: %w", err)
 	}
 
 	if !exists {
-		logger.InfoContext(ctx, "creating collection", "collection", collection, "vector_size", vectorSize)
+		logger.InfoContext(ctx, "creating collection", "collection", collection, "vector_size", vectorSize, "distance", s.distance)
 		err := s.client.CreateCollection(ctx, &qdrant.CreateCollection{
 			CollectionName: collection,
 			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
 				Size:     uint64(vectorSize),
-				Distance: qdrant.Distance_Cosine,
+				Distance: qdrantDistance(s.distance),
 			}),
 		})
 		if err != nil {
@@ -293,12 +450,26 @@ func (s *QdrantStore) EnsureCollection(ctx context.Context, collection string, v
 		return fmt.Errorf("collection vector size mismatch: expected %d, got %d", vectorSize, actualSize)
 	}
 
-	logger.InfoContext(ctx, "collection validated", "collection", collection, "vector_size", vectorSize)
+	// Scores from a collection built for another metric would not mean what retrieval expects
+	if want := qdrantDistance(s.distance); params.Distance != want {
+		return fmt.Errorf("collection distance mismatch: expected %s, got %s (recreate the collection to change the metric)", want, params.Distance)
+	}
+
+	logger.InfoContext(ctx, "collection validated", "collection", collection, "vector_size", vectorSize, "distance", s.distance)
 	return nil
 }
 
 // GetCollectionInfo returns information about a collection including point count.
+// An alias reports the collection it points to.
 func (s *QdrantStore) GetCollectionInfo(ctx context.Context, collection string) (*CollectionInfo, error) {
+	target, err := s.AliasTarget(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	if target != "" {
+		collection = target
+	}
+
 	info, err := s.client.GetCollectionInfo(ctx, collection)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collection info: %w", err)
@@ -375,3 +546,44 @@ func convertValue(v *qdrant.Value) any {
 		return nil
 	}
 }
+
+// DeleteCollection removes a collection and all of its points.
+func (s *QdrantStore) DeleteCollection(ctx context.Context, collection string) error {
+	if err := s.client.DeleteCollection(ctx, collection); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// AliasTarget returns the collection an alias points to, or "" if no such alias exists.
+func (s *QdrantStore) AliasTarget(ctx context.Context, alias string) (string, error) {
+	aliases, err := s.client.ListAliases(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list aliases: %w", err)
+	}
+	for _, description := range aliases {
+		if description.GetAliasName() == alias {
+			return description.GetCollectionName(), nil
+		}
+	}
+	return "", nil
+}
+
+// SwitchAlias atomically points alias at collection, replacing any previous target.
+// The delete and create run as one alias update, so searches never see the alias missing.
+func (s *QdrantStore) SwitchAlias(ctx context.Context, alias, collection string) error {
+	target, err := s.AliasTarget(ctx, alias)
+	if err != nil {
+		return err
+	}
+
+	var actions []*qdrant.AliasOperations
+	if target != "" {
+		actions = append(actions, qdrant.NewAliasDelete(alias))
+	}
+	actions = append(actions, qdrant.NewAliasCreate(alias, collection))
+	if err := s.client.UpdateAliases(ctx, actions); err != nil {
+		return fmt.Errorf("failed to switch alias: %w", err)
+	}
+	return nil
+}
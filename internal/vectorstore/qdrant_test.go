@@ -100,12 +100,20 @@ func TestNewQdrantStore_URLParsing(t *testing.T) {
 // TestNewQdrantStore_InvalidURL tests that invalid URLs return errors.
 // This test creates a real client but only for the error case.
 func TestNewQdrantStore_InvalidURL(t *testing.T) {
-	_, err := NewQdrantStore("://invalid")
+	_, err := NewQdrantStore("://invalid", 0, "")
 	if err == nil {
 		t.Error("NewQdrantStore() with invalid URL should return error")
 	}
 }
 
+// TestNewQdrantStore_InvalidDistance tests that unknown metrics are rejected before a client is created.
+func TestNewQdrantStore_InvalidDistance(t *testing.T) {
+	_, err := NewQdrantStore("http://localhost:6333", 0, "manhattan")
+	if err == nil {
+		t.Error("NewQdrantStore() with invalid distance should return error")
+	}
+}
+
 func TestNewQdrantStore_PortDerivation(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -206,3 +214,16 @@ func TestConvertPayloadToMap(t *testing.T) {
 		t.Errorf("convertPayloadToMap() with nil should return empty map, got %d items", len(result))
 	}
 }
+
+func TestQdrantStore_Retrieve_EmptyIDs(t *testing.T) {
+	// Retrieve should return early without touching the client
+	store := &QdrantStore{}
+
+	points, err := store.Retrieve(context.Background(), "test-collection", []string{})
+	if err != nil {
+		t.Errorf("Retrieve() with empty IDs should return early without error, got: %v", err)
+	}
+	if len(points) != 0 {
+		t.Errorf("Retrieve() with empty IDs returned %d points, want 0", len(points))
+	}
+}